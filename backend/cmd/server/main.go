@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"time"
@@ -17,9 +18,17 @@ import (
 
 	"github.com/opendefender/openrisk/config"
 	"github.com/opendefender/openrisk/database"
+	"github.com/opendefender/openrisk/internal/adapters/attack"
+	"github.com/opendefender/openrisk/internal/adapters/blobstore"
+	"github.com/opendefender/openrisk/internal/adapters/inventory"
+	"github.com/opendefender/openrisk/internal/adapters/mailer"
+	"github.com/opendefender/openrisk/internal/adapters/osv"
+	"github.com/opendefender/openrisk/internal/adapters/taxii"
 	"github.com/opendefender/openrisk/internal/adapters/thehive"
+	"github.com/opendefender/openrisk/internal/adapters/vulnfeeds"
 	"github.com/opendefender/openrisk/internal/cache"
 	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
 	"github.com/opendefender/openrisk/internal/handlers"
 	"github.com/opendefender/openrisk/internal/middleware"
 	"github.com/opendefender/openrisk/internal/migrations"
@@ -110,6 +119,35 @@ func main() {
 		&domain.Risk{},
 		&domain.Mitigation{},
 		&domain.Asset{},
+		&domain.AssetRelationship{},
+		&domain.BusinessService{},
+		&domain.AssetDiscoveryRun{},
+		&domain.SBOMDocument{},
+		&domain.SoftwareComponent{},
+		&domain.Vulnerability{},
+		&domain.VulnerablePackage{},
+		&domain.ComponentVulnerability{},
+		&domain.LicensePolicy{},
+		&domain.CVERecord{},
+		&domain.VulnFeedImport{},
+		&domain.EnrichmentPolicy{},
+		&domain.RiskEnrichment{},
+		&domain.CVSSMappingPolicy{},
+		&domain.AttackObject{},
+		&domain.AttackMitigates{},
+		&domain.AttackBundleImport{},
+		&domain.Threat{},
+		&domain.RiskThreat{},
+		&domain.TAXIICollection{},
+		&domain.ThreatFeed{},
+		&domain.ControlCatalog{},
+		&domain.Control{},
+		&domain.ControlImplementation{},
+		&domain.Incident{},
+		&domain.IncidentRisk{},
+		&domain.LossEvent{},
+		&domain.RecalibrationPolicy{},
+		&domain.RiskRecalibration{},
 		&domain.RiskHistory{},
 		&domain.CustomField{},
 		&domain.CustomFieldTemplate{},
@@ -121,10 +159,40 @@ func main() {
 		&domain.MarketplaceApp{},
 		&domain.ConnectorUpdate{},
 		&domain.MarketplaceLog{},
+		&domain.ApprovalWorkflow{},
+		&domain.ApprovalRequest{},
+		&domain.ApprovalTask{},
+		&domain.ApprovalVote{},
+		&domain.ApprovalDelegation{},
+		&domain.RiskException{},
+		&domain.RiskExceptionReminder{},
+		&domain.ReviewCadencePolicy{},
+		&domain.ReviewTask{},
+		&domain.ReviewCalendarFeed{},
+		&domain.Job{},
+		&domain.ScheduledJob{},
+		&domain.ScheduledJobRun{},
+		&domain.ImportBatch{},
+		&domain.ImportRecord{},
+		&domain.SavedView{},
+		&domain.Dashboard{},
+		&domain.DashboardWidget{},
+		&domain.Report{},
+		&domain.ReportSchedule{},
+		&domain.RiskAuditReport{},
+		&domain.RiskVersion{},
+		&domain.DeletedItem{},
 	); err != nil {
 		log.Fatalf("Database Migration Failed: %v", err)
 	}
 
+	// Risks created before versioning get a baseline version for as-of queries
+	if n, err := services.NewRiskVersionService(database.DB).Backfill(); err != nil {
+		log.Printf("Risk versions: backfill failed: %v", err)
+	} else if n > 0 {
+		log.Printf("Risk versions: recorded a baseline for %d risks", n)
+	}
+
 	// Création du compte Admin par défaut si la DB est vide
 	// Cela garantit que l'app est utilisable immédiatement après déploiement.
 	handlers.SeedAdminUser()
@@ -148,12 +216,55 @@ func main() {
 	// Ils respectent les interfaces définies dans core/ports
 	theHiveAdapter := thehive.NewTheHiveAdapter(cfg.Integrations.TheHive)
 
+	// Cluster-wide scheduler: each periodic worker runs on one replica per tick,
+	// whichever wins the job's lease in the scheduled_jobs table
+	scheduler := services.NewSchedulerService(database.DB)
+	schedule := func(name, expr, description string, fn services.ScheduledTaskFunc) {
+		if err := scheduler.Register(name, expr, fn, services.ScheduleOptions{Description: description}); err != nil {
+			log.Printf("Scheduler: %v", err)
+		}
+	}
+
 	// Initialisation du Moteur de Synchro (Background Worker)
 	// Il tourne indépendamment de l'API HTTP
 	syncEngine := workers.NewSyncEngine(theHiveAdapter)
-	syncEngine.Start(context.Background())
+	// Synchronised incidents are kept, linked to the risks they realized
+	incidentService := services.NewIncidentService(database.DB)
+	syncEngine.IncidentRecorder = incidentService
+	schedule("thehive_sync", "@every 1m", "Synchronise incidents from TheHive", syncEngine.RunOnce)
+
+	log.Println("OpenDefender SyncEngine scheduled")
 
-	log.Println("OpenDefender SyncEngine started in background")
+	// Database connectivity and connection pool usage, recorded in the scheduler run history
+	poolMonitor := cache.NewPoolMonitor(cache.NewPoolHealthCheck(database.DB), time.Minute)
+	poolMonitor.OnUnhealthy(func(result cache.HealthCheckResult) {
+		log.Printf("Warning: database pool unhealthy: %s", result.Message)
+	})
+	schedule("db_pool_health", "@every 5m", "Check database connectivity and connection pool usage", poolMonitor.Check)
+
+	// Durable job queue shared by every replica (bulk operations, exports, marketplace syncs)
+	jobQueue := services.NewJobQueueService(database.DB)
+
+	// Artifact storage for exports: local filesystem by default, any S3-compatible store otherwise
+	blobStore, err := newBlobStore(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Storage initialization failed: %v", err)
+	}
+	// Download links are signed: without a key every export download would be refused
+	if cfg.Storage.URLSigningKey == "" {
+		log.Fatal("Storage initialization failed: set EXPORT_URL_SIGNING_KEY or JWT_SECRET to sign export download links")
+	}
+	bulkOpService := services.NewBulkOperationService(jobQueue, blobStore, []byte(cfg.Storage.URLSigningKey))
+	bulkOpHandler := handlers.NewBulkOperationHandler(bulkOpService)
+	importService := services.NewImportService(jobQueue, blobStore)
+	importHandler := handlers.NewImportHandler(importService)
+
+	// Scheduled reports are emailed over SMTP when SMTP_HOST is set
+	mailSender, err := newMailer(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Mailer initialization failed: %v", err)
+	}
+	reportService := services.NewReportService(jobQueue, blobStore, mailSender)
 
 	// =========================================================================
 	// 4. WEB SERVER SETUP (Fiber)
@@ -164,6 +275,7 @@ func main() {
 		DisableStartupMessage: true, // Plus propre dans les logs de prod
 		ReadTimeout:           10 * time.Second,
 		WriteTimeout:          10 * time.Second,
+		BodyLimit:             32 * 1024 * 1024, // Spreadsheet imports
 		// Custom Error Handler pour toujours renvoyer du JSON
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
@@ -219,6 +331,24 @@ func main() {
 	api.Post("/auth/register", authHandler.Register)
 	api.Post("/auth/refresh", authHandler.RefreshToken)
 
+	// --- Review Calendar Feeds (authenticated by the secret token in the URL) ---
+	reviewService := services.NewReviewSchedulerService(database.DB)
+	reviewHandler := handlers.NewReviewScheduleHandler(reviewService)
+	api.Get("/calendar/reviews/:token", reviewHandler.GetCalendarFeed)
+
+	// --- Export Downloads (authenticated by the signed, expiring URL) ---
+	api.Get("/exports/:id/download", bulkOpHandler.DownloadExport)
+
+	// --- TAXII 2.1 Server (authenticated by the token of a collection) ---
+	threatIntelService := services.NewThreatIntelService(database.DB, taxii.NewPoller())
+	controlService := services.NewControlService(database.DB)
+	taxiiHandler := handlers.NewTAXIIHandler(threatIntelService)
+	api.Get("/taxii2", taxiiHandler.Discovery)
+	api.Get("/taxii2/api", taxiiHandler.APIRoot)
+	api.Get("/taxii2/api/collections", taxiiHandler.Collections)
+	api.Get("/taxii2/api/collections/:id", taxiiHandler.Collection)
+	api.Get("/taxii2/api/collections/:id/objects", taxiiHandler.Objects)
+
 	// --- OAuth2 Routes ---
 	api.Get("/auth/oauth2/login/:provider", handlers.OAuth2Login)
 	api.Get("/auth/oauth2/callback/:provider", handlers.OAuth2Callback)
@@ -240,12 +370,22 @@ func main() {
 			Action:   domain.PermissionRead,
 		}),
 		cacheableHandlers.CacheRiskListGET(handlers.GetRisks))
+	riskRead := middleware.RequirePermissions(permissionService, domain.Permission{
+		Resource: domain.PermissionResourceRisk,
+		Action:   domain.PermissionRead,
+	})
+	protected.Get("/risks/filter/fields", riskRead, handlers.GetRiskFilterFields)
+	protected.Post("/risks/filter/validate", riskRead, handlers.ValidateRiskFilter)
+	riskVersionHandler := handlers.NewRiskVersionHandler(services.NewRiskVersionService(database.DB))
+	protected.Get("/risks/diff", riskRead, riskVersionHandler.DiffRegister)
+	protected.Get("/risks/:id/versions", riskRead, riskVersionHandler.ListRiskVersions)
+	// Not cached: writes do not invalidate it, and the ETag must come from the current row
 	protected.Get("/risks/:id",
 		middleware.RequirePermissions(permissionService, domain.Permission{
 			Resource: domain.PermissionResourceRisk,
 			Action:   domain.PermissionRead,
 		}),
-		cacheableHandlers.CacheRiskGetByIDGET(handlers.GetRisk))
+		handlers.GetRisk)
 
 	// Gestion des Risques (Écriture = Analyst & Admin uniquement)
 	// Respect du principe "Simplicité & Sécurité" + Fine-grained Permission Checks
@@ -270,19 +410,18 @@ func main() {
 	protected.Post("/risks/:id/mitigations", writerRole, handlers.AddMitigation)
 	protected.Patch("/mitigations/:mitigationId/toggle", writerRole, handlers.ToggleMitigationStatus)
 	protected.Patch("/mitigations/:mitigationId", writerRole, handlers.UpdateMitigation)
+	protected.Delete("/mitigations/:mitigationId", writerRole, handlers.DeleteMitigation)
 	// Sub-actions (checklist) for mitigations
 	protected.Post("/mitigations/:id/subactions", writerRole, handlers.CreateMitigationSubAction)
 	protected.Patch("/mitigations/:id/subactions/:subactionId/toggle", writerRole, handlers.ToggleMitigationSubAction)
 	protected.Delete("/mitigations/:id/subactions/:subactionId", writerRole, handlers.DeleteMitigationSubAction)
 
 	api.Get("/users/me", authHandler.GetProfile)
-	api.Get("/assets", middleware.Protected(), handlers.GetAssets)
-	api.Post("/assets", middleware.Protected(), handlers.CreateAsset)
 	api.Get("/stats/risk-matrix", cacheableHandlers.CacheDashboardMatrixGET(handlers.GetRiskMatrixData))
 	api.Get("/stats/risk-distribution", cacheableHandlers.CacheDashboardStatsGET(handlers.GetRiskDistribution))
 	api.Get("/stats/mitigation-metrics", cacheableHandlers.CacheDashboardStatsGET(handlers.GetMitigationMetrics))
 	api.Get("/stats/top-vulnerabilities", cacheableHandlers.CacheDashboardStatsGET(handlers.GetTopVulnerabilities))
-	api.Get("/export/pdf", handlers.ExportRisksPDF)
+	protected.Get("/export/pdf", riskRead, handlers.ExportRisksPDF)
 	api.Get("/stats/trends", middleware.Protected(), cacheableHandlers.CacheDashboardTimelineGET(handlers.GetGlobalRiskTrend))
 	api.Get("/mitigations/recommended", handlers.GetRecommendedMitigations)
 	api.Get("/gamification/me", middleware.Protected(), handlers.GetMyGamificationProfile)
@@ -336,11 +475,29 @@ func main() {
 	protected.Get("/custom-fields/scope/:scope", customFieldHandler.ListCustomFieldsByScope)
 	protected.Post("/custom-fields/templates/:id/apply", customFieldHandler.ApplyTemplate)
 
+	// --- Background Jobs (Protected routes) ---
+	// Bulk operations, exports and marketplace syncs run on the durable job queue
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	protected.Get("/jobs", jobHandler.ListJobs)
+	protected.Get("/jobs/:id", jobHandler.GetJob)
+	protected.Post("/jobs/:id/cancel", jobHandler.CancelJob)
+	protected.Post("/jobs/:id/retry", jobHandler.RetryJob)
+
 	// --- Bulk Operations (Protected routes) ---
-	bulkOpHandler := handlers.NewBulkOperationHandler()
 	protected.Post("/bulk-operations", bulkOpHandler.CreateBulkOperation)
 	protected.Get("/bulk-operations", bulkOpHandler.ListBulkOperations)
+	protected.Get("/bulk-operations/export-columns", bulkOpHandler.ListExportColumns)
 	protected.Get("/bulk-operations/:id", bulkOpHandler.GetBulkOperation)
+	protected.Get("/bulk-operations/:id/export-result", bulkOpHandler.GetExportResult)
+
+	// --- Spreadsheet Imports (upload -> dry-run -> commit job -> rollback) ---
+	protected.Get("/imports/fields/:entity", importHandler.ListFields)
+	protected.Post("/imports", writerRole, importHandler.Upload)
+	protected.Get("/imports", importHandler.ListImports)
+	protected.Get("/imports/:id", importHandler.GetImport)
+	protected.Post("/imports/:id/dry-run", writerRole, importHandler.DryRun)
+	protected.Post("/imports/:id/commit", writerRole, importHandler.CommitImport)
+	protected.Post("/imports/:id/rollback", writerRole, importHandler.RollbackImport)
 
 	// --- Risk Timeline (Protected routes) ---
 	timelineHandler := handlers.NewRiskTimelineHandler()
@@ -360,23 +517,348 @@ func main() {
 	protected.Get("/analytics/mitigations/metrics", analyticsHandler.GetMitigationMetrics)
 	protected.Get("/analytics/frameworks", analyticsHandler.GetFrameworkAnalytics)
 	protected.Get("/analytics/dashboard", analyticsHandler.GetDashboardSnapshot)
+	protected.Get("/analytics/losses", analyticsHandler.GetLossAnalytics)
 	protected.Get("/analytics/export", analyticsHandler.GetExportData)
 
+	// --- Saved Views & Dashboards (private, team or tenant wide; portable as JSON) ---
+	viewHandler := handlers.NewViewHandler(services.NewViewService(database.DB))
+	protected.Get("/views", riskRead, viewHandler.ListViews)
+	protected.Post("/views", riskRead, viewHandler.CreateView)
+	protected.Post("/views/export", riskRead, viewHandler.ExportViews)
+	protected.Post("/views/import", riskRead, viewHandler.ImportViews)
+	protected.Get("/views/:id", riskRead, viewHandler.GetView)
+	protected.Put("/views/:id", riskRead, viewHandler.UpdateView)
+	protected.Delete("/views/:id", riskRead, viewHandler.DeleteView)
+	protected.Get("/dashboards", riskRead, viewHandler.ListDashboards)
+	protected.Post("/dashboards", riskRead, viewHandler.CreateDashboard)
+	protected.Get("/dashboards/default", riskRead, viewHandler.GetDefaultDashboard)
+	protected.Get("/dashboards/:id", riskRead, viewHandler.GetDashboard)
+	protected.Put("/dashboards/:id", riskRead, viewHandler.UpdateDashboard)
+	protected.Delete("/dashboards/:id", riskRead, viewHandler.DeleteDashboard)
+	protected.Get("/dashboards/:id/data", riskRead, viewHandler.GetDashboardData)
+
+	// --- Approval Workflows (Protected routes) ---
+	// Approval chains are configured by admins; any authenticated user may vote on tasks assigned to them
+	approvalService := services.NewApprovalWorkflowService(database.DB)
+	schedule("approval_escalation", "@every 15m", "Escalate overdue approval tasks", func(ctx context.Context) error {
+		_, err := approvalService.ProcessTimeouts(time.Now())
+		return err
+	})
+	approvalHandler := handlers.NewApprovalWorkflowHandler(approvalService)
+	protected.Post("/risk-management/approval-workflows", adminRole, approvalHandler.CreateWorkflow)
+	protected.Get("/risk-management/approval-workflows", approvalHandler.ListWorkflows)
+	protected.Get("/risk-management/approval-workflows/:id", approvalHandler.GetWorkflow)
+	protected.Patch("/risk-management/approval-workflows/:id", adminRole, approvalHandler.UpdateWorkflow)
+	protected.Delete("/risk-management/approval-workflows/:id", adminRole, approvalHandler.DeleteWorkflow)
+	protected.Get("/risk-management/approvals", approvalHandler.ListRequests)
+	protected.Get("/risk-management/approvals/mine", approvalHandler.ListMyTasks)
+	protected.Get("/risk-management/approvals/:id", approvalHandler.GetRequest)
+	protected.Post("/risk-management/approvals/:id/cancel", writerRole, approvalHandler.CancelRequest)
+	protected.Post("/risk-management/approval-tasks/:id/vote", approvalHandler.Vote)
+	protected.Post("/risk-management/approval-delegations", approvalHandler.CreateDelegation)
+	protected.Get("/risk-management/approval-delegations", approvalHandler.ListDelegations)
+	protected.Delete("/risk-management/approval-delegations/:id", approvalHandler.RevokeDelegation)
+
+	// Decisions and treatment plans go through the approval chain matching them; direct
+	// approval is admin-only and refused once a chain governs the decision
+	riskMgmtHandler := handlers.NewRiskManagementHandler(services.NewRiskManagementService(database.DB))
+	protected.Post("/risk-management/treatment-plans", writerRole, riskMgmtHandler.CreateTreatmentPlan)
+	protected.Post("/risk-management/decisions", writerRole, riskMgmtHandler.RecordDecision)
+	protected.Get("/risk-management/decisions/:id", riskMgmtHandler.GetDecision)
+	protected.Post("/risk-management/decisions/:id/approve", adminRole, riskMgmtHandler.ApproveDecision)
+
+	// --- Risk Exceptions (Protected routes) ---
+	// Exceptions are requested by analysts; single-step approval and revocation are admin-only
+	exceptionService := services.NewRiskExceptionService(database.DB)
+	schedule("exception_lifecycle", "@hourly", "Expire risk exceptions and send expiry reminders", func(ctx context.Context) error {
+		now := time.Now()
+		if _, err := exceptionService.ProcessExpirations(now); err != nil {
+			return err
+		}
+		_, err := exceptionService.ProcessReminders(now)
+		return err
+	})
+	exceptionHandler := handlers.NewRiskExceptionHandler(exceptionService)
+	protected.Get("/exceptions", exceptionHandler.ListExceptions)
+	protected.Post("/exceptions", writerRole, exceptionHandler.CreateException)
+	protected.Get("/exceptions/register", exceptionHandler.GetExceptionRegister)
+	protected.Get("/exceptions/reminders", exceptionHandler.ListMyReminders)
+	protected.Post("/exceptions/reminders/:id/ack", exceptionHandler.AcknowledgeReminder)
+	protected.Get("/exceptions/:id", exceptionHandler.GetException)
+	protected.Post("/exceptions/:id/approve", adminRole, exceptionHandler.ApproveException)
+	protected.Post("/exceptions/:id/reject", adminRole, exceptionHandler.RejectException)
+	protected.Post("/exceptions/:id/recertify", writerRole, exceptionHandler.RecertifyException)
+	protected.Post("/exceptions/:id/revoke", adminRole, exceptionHandler.RevokeException)
+
+	// --- Risk Review Scheduling (Protected routes) ---
+	schedule("review_tasks", "@hourly", "Open and flag risk review tasks", func(ctx context.Context) error {
+		_, err := reviewService.GenerateTasks(time.Now())
+		return err
+	})
+	protected.Get("/reviews/policy", reviewHandler.GetPolicy)
+	protected.Put("/reviews/policy", adminRole, reviewHandler.UpdatePolicy)
+	protected.Get("/reviews/schedule", reviewHandler.GetSchedule)
+	protected.Get("/reviews/stale", reviewHandler.GetStaleRisks)
+	protected.Get("/reviews/tasks", reviewHandler.ListTasks)
+	protected.Post("/reviews/calendar-feeds", reviewHandler.CreateCalendarFeed)
+	protected.Get("/reviews/calendar-feeds", reviewHandler.ListCalendarFeeds)
+	protected.Delete("/reviews/calendar-feeds/:id", reviewHandler.RevokeCalendarFeed)
+
 	// --- Time Series Analytics (Protected routes) ---
 	handlers.RegisterTimeSeriesRoutes(app, database.DB)
 	protected.Get("/threats", threatHandler.GetThreats)
 	protected.Get("/threats/stats", threatHandler.GetThreatStats)
 
+	// --- MITRE ATT&CK (Protected routes) ---
+	// Risks link the techniques that realize them and mitigations the ATT&CK
+	// mitigations or D3FEND countermeasures they implement; the catalog is
+	// reloaded daily from ATTACK_BUNDLE when the file changed
+	attackService := services.NewAttackService(database.DB)
+	if path := cfg.Attack.Bundle; path != "" {
+		schedule("attack_bundle", "@daily", "Load the MITRE ATT&CK Enterprise bundle when it changed", func(ctx context.Context) error {
+			bundle, err := attack.LoadFile(path)
+			if err != nil {
+				return err
+			}
+			last, err := attackService.LatestImport()
+			if err != nil || (last != nil && last.Checksum == bundle.Checksum) {
+				return err
+			}
+			bundle.ImportedBy = "scheduler"
+			_, err = attackService.ImportBundle(ctx, bundle.AttackBundleImport, bundle.Objects, bundle.Mitigates)
+			return err
+		})
+	}
+	attackHandler := handlers.NewAttackHandler(attackService)
+	protected.Get("/threats/coverage", attackHandler.GetCoverage)
+	protected.Get("/threats/coverage/layer", attackHandler.GetCoverageLayer)
+	protected.Post("/attack/bundle", adminRole, attackHandler.ImportBundle)
+	protected.Get("/attack/imports", attackHandler.GetImports)
+	protected.Get("/attack/tactics", attackHandler.GetTactics)
+	protected.Get("/attack/techniques", attackHandler.GetTechniques)
+	protected.Get("/attack/mitigations", attackHandler.GetMitigations)
+	protected.Get("/attack/objects/:id", attackHandler.GetObject)
+
+	// Threat Intelligence (STIX 2.1 bundles, TAXII 2.1 collections and feeds)
+	schedule("threat_feeds", "@every 15m", "Poll TAXII threat feeds", threatIntelService.PollAll)
+	threatIntelHandler := handlers.NewThreatIntelHandler(threatIntelService)
+	protected.Post("/stix/bundle", writerRole, threatIntelHandler.ImportBundle)
+	protected.Get("/stix/bundle", threatIntelHandler.ExportBundle)
+	protected.Get("/threat-intel/threats", threatIntelHandler.GetThreats)
+	protected.Get("/threat-intel/threats/:id", threatIntelHandler.GetThreat)
+	protected.Get("/risks/:id/threats", riskRead, threatIntelHandler.GetRiskThreats)
+	protected.Post("/risks/:id/threats", writerRole, threatIntelHandler.LinkRiskThreat)
+	protected.Delete("/risks/:id/threats/:threatId", writerRole, threatIntelHandler.UnlinkRiskThreat)
+	protected.Get("/taxii-collections", adminRole, threatIntelHandler.GetCollections)
+	protected.Post("/taxii-collections", adminRole, threatIntelHandler.CreateCollection)
+	protected.Delete("/taxii-collections/:id", adminRole, threatIntelHandler.DeleteCollection)
+	protected.Get("/threat-feeds", adminRole, threatIntelHandler.GetFeeds)
+	protected.Post("/threat-feeds", adminRole, threatIntelHandler.CreateFeed)
+	protected.Delete("/threat-feeds/:id", adminRole, threatIntelHandler.DeleteFeed)
+	protected.Post("/threat-feeds/:id/poll", adminRole, threatIntelHandler.PollFeed)
+
+	// OSCAL control catalog, SSP fragments and POA&M
+	oscalHandler := handlers.NewOSCALHandler(controlService)
+	protected.Post("/oscal/import", adminRole, oscalHandler.ImportDocument)
+	protected.Get("/oscal/ssp", oscalHandler.ExportSSP)
+	protected.Get("/oscal/poam", oscalHandler.ExportPOAM)
+	protected.Get("/controls/catalogs", oscalHandler.GetCatalogs)
+	protected.Get("/controls", oscalHandler.GetControls)
+	protected.Get("/controls/:id", oscalHandler.GetControl)
+	protected.Put("/controls/:id/implementation", writerRole, oscalHandler.SetImplementation)
+
 	// --- Reports Management (Protected routes) ---
-	reportHandler := handlers.NewReportHandler(database.DB)
-	protected.Get("/reports", reportHandler.GetReports)
-	protected.Get("/reports/:id", reportHandler.GetReport)
-	protected.Get("/reports/stats", reportHandler.GetReportStats)
+	// Reports are rendered from persisted snapshots, so regenerating one reproduces its figures
+	reportHandler := handlers.NewReportHandler(reportService)
+	schedule("report_schedules", "@every 1m", "Generate and email scheduled reports", reportService.RunDueSchedules)
+	protected.Get("/reports", riskRead, reportHandler.GetReports)
+	protected.Post("/reports", riskRead, writerRole, reportHandler.GenerateReport)
+	protected.Get("/reports/stats", riskRead, reportHandler.GetReportStats)
+	protected.Get("/reports/templates", riskRead, reportHandler.GetReportTemplates)
+	protected.Get("/reports/schedules", riskRead, reportHandler.ListReportSchedules)
+	protected.Post("/reports/schedules", writerRole, reportHandler.CreateReportSchedule)
+	protected.Get("/reports/schedules/:id", riskRead, reportHandler.GetReportSchedule)
+	protected.Put("/reports/schedules/:id", writerRole, reportHandler.UpdateReportSchedule)
+	protected.Delete("/reports/schedules/:id", writerRole, reportHandler.DeleteReportSchedule)
+	protected.Post("/reports/schedules/:id/run", writerRole, reportHandler.RunReportSchedule)
+	protected.Get("/reports/:id", riskRead, reportHandler.GetReport)
+	protected.Get("/reports/:id/download", riskRead, reportHandler.DownloadReport)
+	protected.Post("/reports/:id/regenerate", riskRead, writerRole, reportHandler.RegenerateReport)
+	protected.Post("/reports/:id/deliver", writerRole, reportHandler.DeliverReport)
+	protected.Delete("/reports/:id", writerRole, reportHandler.DeleteReport)
+
+	// --- Branding (Protected routes) ---
+	// Cover page logo and colors of the board PDF export
+	brandingHandler := handlers.NewBrandingHandler(services.NewBrandingService(database.DB))
+	protected.Get("/branding", brandingHandler.GetBranding)
+	protected.Put("/branding", adminRole, brandingHandler.UpdateBranding)
+
+	// --- Asset Inventory (Protected routes) ---
+	// Assets with their hierarchy and relationships, and business services with their aggregate risk.
+	// Risk scores follow the criticality of the services depending on their assets, rescored hourly.
+	impactService := services.NewImpactService(database.DB)
+	schedule("risk_impact_rescore", "@hourly", "Rescore risks from the effective criticality of their assets", func(ctx context.Context) error {
+		_, err := impactService.RescoreRisks(ctx)
+		return err
+	})
+	assetHandler := handlers.NewAssetHandler(services.NewAssetService(database.DB), impactService)
+	protected.Get("/assets", assetHandler.GetAssets)
+	protected.Post("/assets", writerRole, assetHandler.CreateAsset)
+	protected.Get("/assets/:id", assetHandler.GetAsset)
+	protected.Patch("/assets/:id", writerRole, assetHandler.UpdateAsset)
+	protected.Delete("/assets/:id", writerRole, assetHandler.DeleteAsset)
+	protected.Get("/assets/:id/tree", assetHandler.GetAssetTree)
+	protected.Get("/assets/:id/blast-radius", assetHandler.GetAssetBlastRadius)
+	protected.Get("/risks/:id/blast-radius", riskRead, assetHandler.GetRiskBlastRadius)
+	protected.Get("/assets/:id/relationships", assetHandler.GetAssetRelationships)
+	protected.Post("/assets/:id/relationships", writerRole, assetHandler.CreateAssetRelationship)
+	protected.Delete("/assets/:id/relationships/:relationshipId", writerRole, assetHandler.DeleteAssetRelationship)
+	protected.Get("/business-services", assetHandler.GetBusinessServices)
+	protected.Post("/business-services", writerRole, assetHandler.CreateBusinessService)
+	protected.Get("/business-services/:id", assetHandler.GetBusinessService)
+	protected.Patch("/business-services/:id", writerRole, assetHandler.UpdateBusinessService)
+	protected.Delete("/business-services/:id", writerRole, assetHandler.DeleteBusinessService)
+	protected.Post("/business-services/:id/assets", writerRole, assetHandler.AddBusinessServiceAssets)
+	protected.Delete("/business-services/:id/assets/:assetId", writerRole, assetHandler.RemoveBusinessServiceAsset)
+
+	// --- Asset Discovery (Protected routes) ---
+	// Cloud and CMDB inventories reconciled with the assets of their source;
+	// the ServiceNow CMDB is also synced daily when configured
+	var cmdb ports.AssetInventoryProvider
+	if sn := cfg.Integrations.ServiceNow; sn.URL != "" {
+		cmdb = inventory.NewServiceNowClient(sn.URL, sn.Username, sn.Password, sn.Table)
+	}
+	discoveryService := services.NewAssetDiscoveryService(database.DB, cmdb)
+	if cmdb != nil {
+		schedule("servicenow_cmdb_sync", "@daily", "Reconcile assets with the ServiceNow CMDB", func(ctx context.Context) error {
+			_, err := discoveryService.SyncCMDB(ctx, false)
+			return err
+		})
+	}
+	discoveryHandler := handlers.NewAssetDiscoveryHandler(discoveryService)
+	protected.Get("/asset-discovery/runs", discoveryHandler.ListRuns)
+	protected.Get("/asset-discovery/runs/:id", discoveryHandler.GetRun)
+	protected.Post("/asset-discovery/servicenow/sync", adminRole, discoveryHandler.SyncCMDB)
+	protected.Post("/asset-discovery/:format", writerRole, discoveryHandler.Ingest)
+
+	// --- SBOM & Vulnerability Feed (Protected routes) ---
+	// Components of assets from their SBOMs, matched against the OSV feed
+	// (loaded daily from VULN_FEED_DIR/osv when set) and the license policies
+	sbomService := services.NewSBOMService(database.DB)
+	if dir := cfg.VulnFeeds.Dir; dir != "" {
+		schedule("osv_feed_load", "@daily", "Load OSV dumps and match asset components", func(ctx context.Context) error {
+			vulns, err := osv.LoadPath(filepath.Join(dir, "osv"))
+			if err != nil {
+				return err
+			}
+			_, err = sbomService.ImportVulnerabilities(domain.WithActor(ctx, domain.SystemActor), vulns)
+			return err
+		})
+	}
+	sbomHandler := handlers.NewSBOMHandler(sbomService)
+	protected.Post("/assets/:id/sbom", writerRole, sbomHandler.UploadSBOM)
+	protected.Get("/assets/:id/sboms", sbomHandler.GetAssetSBOMs)
+	protected.Get("/assets/:id/components", sbomHandler.GetAssetComponents)
+	protected.Get("/assets/:id/vulnerabilities", sbomHandler.GetAssetVulnerabilities)
+	protected.Get("/software-components", sbomHandler.SearchComponents)
+	protected.Get("/vulnerabilities/:id", sbomHandler.GetVulnerability)
+	protected.Post("/vulnerability-feeds/osv", adminRole, sbomHandler.ImportOSV)
+	protected.Get("/license-policies", sbomHandler.GetLicensePolicies)
+	protected.Post("/license-policies", adminRole, sbomHandler.CreateLicensePolicy)
+	protected.Delete("/license-policies/:id", adminRole, sbomHandler.DeleteLicensePolicy)
+
+	// --- Vulnerability Enrichment (Protected routes) ---
+	// CVEs referenced by risks enriched from the NVD, KEV and EPSS feeds
+	// (loaded daily from VULN_FEED_DIR/{nvd,kev,epss} when set); the tenant
+	// policy decides whether their probability follows
+	enrichmentService := services.NewVulnEnrichmentService(database.DB)
+	schedule("vuln_enrichment", "@daily", "Load the NVD, KEV and EPSS feeds and enrich risks referencing CVEs", func(ctx context.Context) error {
+		ctx = domain.WithActor(ctx, domain.SystemActor)
+		if dir := cfg.VulnFeeds.Dir; dir != "" {
+			feeds, err := vulnfeeds.LoadDir(dir)
+			if err != nil {
+				return err
+			}
+			for _, feed := range feeds {
+				if _, err := enrichmentService.ImportFeed(ctx, feed.VulnFeedImport, feed.Records); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := enrichmentService.EnrichAll(ctx)
+		return err
+	})
+	enrichmentHandler := handlers.NewVulnEnrichmentHandler(enrichmentService)
+	protected.Get("/vulnerability-feeds/imports", enrichmentHandler.GetFeedImports)
+	protected.Post("/vulnerability-feeds/:source", adminRole, enrichmentHandler.ImportFeed)
+	protected.Get("/cves/:id", enrichmentHandler.GetCVE)
+	protected.Get("/risks/:id/enrichment", riskRead, enrichmentHandler.GetRiskEnrichment)
+	protected.Post("/risks/:id/enrich", writerRole, enrichmentHandler.EnrichRisk)
+	protected.Post("/vulnerability-enrichment/run", adminRole, enrichmentHandler.RunEnrichment)
+	protected.Get("/vulnerability-enrichment/policy", enrichmentHandler.GetPolicy)
+	protected.Put("/vulnerability-enrichment/policy", adminRole, enrichmentHandler.UpdatePolicy)
+
+	// --- CVSS (Protected routes) ---
+	// Risks carrying a CVSS vector take their impact and probability from the
+	// tenant mapping; their impact follows the criticality of their assets
+	cvssService := services.NewCVSSService(database.DB)
+	schedule("cvss_rederive", "@hourly", "Derive again the impact of risks with a CVSS vector from their assets", func(ctx context.Context) error {
+		_, err := cvssService.RederiveImpacts(ctx)
+		return err
+	})
+	cvssHandler := handlers.NewCVSSHandler(cvssService)
+	protected.Post("/cvss/calculate", cvssHandler.Calculate)
+	protected.Get("/cvss/policy", cvssHandler.GetPolicy)
+	protected.Put("/cvss/policy", adminRole, cvssHandler.UpdatePolicy)
+
+	// --- Incidents & Loss Events (Protected routes) ---
+	// Incidents are linked to the risks they realized; risks realized
+	// repeatedly have their probability raised by the tenant policy
+	incidentHandler := handlers.NewIncidentHandler(incidentService)
+	protected.Get("/incidents", incidentHandler.GetIncidents)
+	protected.Post("/incidents", writerRole, incidentHandler.CreateIncident)
+	protected.Get("/incidents/recalibration/policy", incidentHandler.GetRecalibrationPolicy)
+	protected.Put("/incidents/recalibration/policy", adminRole, incidentHandler.UpdateRecalibrationPolicy)
+	protected.Post("/incidents/recalibration/run", adminRole, incidentHandler.RunRecalibration)
+	protected.Get("/incidents/:id", incidentHandler.GetIncident)
+	protected.Post("/incidents/:id/risks", writerRole, incidentHandler.LinkRisks)
+	protected.Delete("/incidents/:id/risks/:riskId", writerRole, incidentHandler.UnlinkRisk)
+	protected.Post("/incidents/:id/loss-events", writerRole, incidentHandler.CreateLossEvent)
+	protected.Put("/loss-events/:id", writerRole, incidentHandler.UpdateLossEvent)
+	protected.Delete("/loss-events/:id", writerRole, incidentHandler.DeleteLossEvent)
+	protected.Get("/risks/:id/incidents", riskRead, incidentHandler.GetRiskIncidents)
+
+	// --- Recycle Bin (Protected routes) ---
+	// Deleted items are restored by writers and purged by admins; expired items are purged daily
+	recycleBinService := services.NewRecycleBinService(database.DB, time.Duration(cfg.RecycleBin.RetentionDays)*24*time.Hour)
+	schedule("recycle_bin_purge", "@daily", "Purge recycle bin items past the retention window", func(ctx context.Context) error {
+		_, err := recycleBinService.PurgeExpired(ctx, time.Now())
+		return err
+	})
+	recycleBinHandler := handlers.NewRecycleBinHandler(recycleBinService)
+	protected.Get("/recycle-bin/:type", writerRole, recycleBinHandler.ListDeletedItems)
+	protected.Post("/recycle-bin/:type/:id/restore", writerRole, recycleBinHandler.RestoreDeletedItem)
+	protected.Delete("/recycle-bin/:type/:id", adminRole, recycleBinHandler.PurgeDeletedItem)
 
 	// --- Marketplace Management (Protected routes) ---
 	// Marketplace can be browsed by all authenticated users
 	// Installation requires analyst or admin role
 	marketplaceService := services.NewMarketplaceService(database.DB, log.New(os.Stderr, "[Marketplace] ", log.LstdFlags))
+	marketplaceService.UseJobQueue(jobQueue)
+	if err := marketplaceService.ResumeAutoSync(context.Background()); err != nil {
+		log.Printf("Marketplace: failed to resume auto-sync schedules: %v", err)
+	}
+	jobQueue.Start(context.Background(), 4, 2*time.Second)
+
+	// --- Scheduler Administration (Admin only) ---
+	schedulerHandler := handlers.NewSchedulerHandler(scheduler)
+	protected.Get("/scheduler/jobs", adminRole, schedulerHandler.ListJobs)
+	protected.Get("/scheduler/jobs/:name", adminRole, schedulerHandler.GetJob)
+	protected.Get("/scheduler/jobs/:name/runs", adminRole, schedulerHandler.ListRuns)
+	protected.Post("/scheduler/jobs/:name/trigger", adminRole, schedulerHandler.TriggerJob)
+	protected.Post("/scheduler/jobs/:name/pause", adminRole, schedulerHandler.PauseJob)
+	protected.Post("/scheduler/jobs/:name/resume", adminRole, schedulerHandler.ResumeJob)
+	scheduler.Start(context.Background(), 15*time.Second)
 	marketplaceHandler := handlers.NewMarketplaceHandler(marketplaceService)
 
 	// Public marketplace endpoints (all authenticated users can browse)
@@ -475,6 +957,23 @@ func main() {
 	log.Println("Server exited properly")
 }
 
+// newBlobStore selects the artifact store from the storage configuration
+func newBlobStore(cfg config.StorageConfig) (ports.BlobStore, error) {
+	if cfg.Driver == "s3" {
+		return blobstore.NewS3Store(cfg)
+	}
+	return blobstore.NewLocalStore(cfg.LocalPath)
+}
+
+// newMailer returns the SMTP mailer, or nil when email is not configured
+func newMailer(cfg config.MailConfig) (ports.Mailer, error) {
+	m, err := mailer.NewSMTPMailer(cfg)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // parseEnvInt safely parses environment variables to integers
 func parseEnvInt(key string, defaultVal int) int {
 	if val := os.Getenv(key); val != "" {
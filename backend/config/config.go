@@ -26,12 +26,71 @@ type Config struct {
 	Database DatabaseConfig
 	// Modules externes
 	Integrations IntegrationsConfig
+	// Stockage des artefacts (exports, rapports)
+	Storage StorageConfig
+	// Envoi des emails (rapports planifiés)
+	Mail MailConfig
+	// Corbeille des éléments supprimés
+	RecycleBin RecycleBinConfig
+	// Flux de vulnérabilités chargés hors ligne
+	VulnFeeds VulnFeedsConfig
+	// Référentiel MITRE ATT&CK
+	Attack AttackConfig
+}
+
+// AttackConfig : bundle STIX ATT&CK Enterprise (enterprise-attack.json,
+// éventuellement gzippé), rechargé chaque jour lorsqu'il a changé
+type AttackConfig struct {
+	Bundle string
+}
+
+// VulnFeedsConfig : répertoire miroir des flux de vulnérabilités, chargé
+// chaque jour ; les dumps OSV sont lus dans son sous-répertoire osv/, les
+// flux NVD, KEV et EPSS dans nvd/, kev/ et epss/
+type VulnFeedsConfig struct {
+	Dir string
+}
+
+// RecycleBinConfig : durée de rétention des éléments supprimés avant purge
+// automatique ; 0 ou moins désactive la purge automatique
+type RecycleBinConfig struct {
+	RetentionDays int
+}
+
+// MailConfig configure le serveur SMTP ; sans SMTP_HOST aucun email n'est envoyé
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+}
+
+// StorageConfig sélectionne le stockage des artefacts : "local" (par défaut) ou "s3"
+type StorageConfig struct {
+	Driver        string
+	LocalPath     string
+	S3Endpoint    string // ex: https://s3.eu-west-1.amazonaws.com ou http://minio:9000
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	URLSigningKey string // Signe les URLs de téléchargement (JWT_SECRET par défaut)
 }
 
 type IntegrationsConfig struct {
 	TheHive  ExternalService `mapstructure:"thehive"`
 	OpenCTI  ExternalService `mapstructure:"opencti"`
 	OpenRMF  ExternalService `mapstructure:"openrmf"`
+	// CMDB ServiceNow, synchronisée chaque jour si l'URL est renseignée
+	ServiceNow ServiceNowConfig `mapstructure:"servicenow"`
+}
+
+type ServiceNowConfig struct {
+	URL      string // https://<instance>.service-now.com
+	Username string
+	Password string
+	Table    string // Table CMDB lue (cmdb_ci par défaut)
 }
 
 type ExternalService struct {
@@ -48,6 +107,16 @@ func LoadConfig() *Config {
 	// Dans un environnement de dev, le port DB est souvent le 5432 par défaut
 	dbPort := 5432 
 
+	smtpPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil {
+		smtpPort = 587
+	}
+
+	retentionDays, err := strconv.Atoi(os.Getenv("RECYCLE_BIN_RETENTION_DAYS"))
+	if err != nil {
+		retentionDays = 30
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port: port,
@@ -60,5 +129,46 @@ func LoadConfig() *Config {
 			Password: os.Getenv("DB_PASSWORD"),
 			DBName: os.Getenv("DB_NAME"),
 		},
+		Storage: StorageConfig{
+			Driver:        getEnvDefault("BLOB_STORE", "local"),
+			LocalPath:     getEnvDefault("BLOB_LOCAL_PATH", "./data/blobs"),
+			S3Endpoint:    os.Getenv("S3_ENDPOINT"),
+			S3Region:      getEnvDefault("S3_REGION", "us-east-1"),
+			S3Bucket:      os.Getenv("S3_BUCKET"),
+			S3AccessKey:   os.Getenv("S3_ACCESS_KEY"),
+			S3SecretKey:   os.Getenv("S3_SECRET_KEY"),
+			URLSigningKey: getEnvDefault("EXPORT_URL_SIGNING_KEY", os.Getenv("JWT_SECRET")),
+		},
+		Mail: MailConfig{
+			SMTPHost:     os.Getenv("SMTP_HOST"),
+			SMTPPort:     smtpPort,
+			SMTPUsername: os.Getenv("SMTP_USERNAME"),
+			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+			From:         getEnvDefault("SMTP_FROM", "openrisk@localhost"),
+		},
+		RecycleBin: RecycleBinConfig{
+			RetentionDays: retentionDays,
+		},
+		VulnFeeds: VulnFeedsConfig{
+			Dir: os.Getenv("VULN_FEED_DIR"),
+		},
+		Attack: AttackConfig{
+			Bundle: os.Getenv("ATTACK_BUNDLE"),
+		},
+		Integrations: IntegrationsConfig{
+			ServiceNow: ServiceNowConfig{
+				URL:      os.Getenv("SERVICENOW_URL"),
+				Username: os.Getenv("SERVICENOW_USERNAME"),
+				Password: os.Getenv("SERVICENOW_PASSWORD"),
+				Table:    getEnvDefault("SERVICENOW_CMDB_TABLE", "cmdb_ci"),
+			},
+		},
+	}
+}
+
+func getEnvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
\ No newline at end of file
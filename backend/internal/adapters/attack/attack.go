@@ -0,0 +1,198 @@
+// Package attack reads the MITRE ATT&CK Enterprise STIX 2.1 bundle
+// (enterprise-attack.json from the mitre-attack/attack-stix-data repository,
+// gzipped or not) and writes ATT&CK Navigator layers.
+package attack
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// ErrNotAttackBundle is returned for a STIX bundle without ATT&CK technique
+var ErrNotAttackBundle = errors.New("not an ATT&CK STIX bundle: no technique found")
+
+// Bundle is a loaded ATT&CK bundle: its import record, the techniques,
+// tactics and mitigations it defines and which mitigation mitigates which
+// technique
+type Bundle struct {
+	domain.AttackBundleImport
+	Objects   []domain.AttackObject
+	Mitigates []domain.AttackMitigates
+}
+
+type stixBundle struct {
+	Type    string       `json:"type"`
+	Objects []stixObject `json:"objects"`
+}
+
+type stixObject struct {
+	Type               string `json:"type"`
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	Revoked            bool   `json:"revoked"`
+	ExternalReferences []struct {
+		SourceName string `json:"source_name"`
+		ExternalID string `json:"external_id"`
+		URL        string `json:"url"`
+	} `json:"external_references"`
+	KillChainPhases []struct {
+		KillChainName string `json:"kill_chain_name"`
+		PhaseName     string `json:"phase_name"`
+	} `json:"kill_chain_phases"`
+	Platforms        []string `json:"x_mitre_platforms"`
+	Deprecated       bool     `json:"x_mitre_deprecated"`
+	ShortName        string   `json:"x_mitre_shortname"`
+	Version          string   `json:"x_mitre_version"`
+	RelationshipType string   `json:"relationship_type"`
+	SourceRef        string   `json:"source_ref"`
+	TargetRef        string   `json:"target_ref"`
+}
+
+// attackReference returns the ATT&CK ID and page of an object
+func (o *stixObject) attackReference() (string, string) {
+	for _, ref := range o.ExternalReferences {
+		if ref.SourceName == "mitre-attack" && ref.ExternalID != "" {
+			return ref.ExternalID, ref.URL
+		}
+	}
+	return "", ""
+}
+
+// Read reads an ATT&CK STIX bundle. Techniques and mitigations that are
+// revoked or deprecated are kept, marked so, for existing links to resolve.
+func Read(r io.Reader) (*Bundle, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	var raw stixBundle
+	if err := json.NewDecoder(io.TeeReader(r, hash)).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid STIX bundle: %w", err)
+	}
+	if raw.Type != "bundle" {
+		return nil, fmt.Errorf("invalid STIX bundle: type %q", raw.Type)
+	}
+
+	bundle := &Bundle{}
+	bundle.Checksum = hex.EncodeToString(hash.Sum(nil))
+	attackIDs := map[string]string{} // STIX ID to ATT&CK ID
+	for i := range raw.Objects {
+		o := &raw.Objects[i]
+		id, url := o.attackReference()
+		object := domain.AttackObject{
+			ID:          id,
+			STIXID:      o.ID,
+			Name:        o.Name,
+			Description: o.Description,
+			URL:         url,
+			Deprecated:  o.Revoked || o.Deprecated,
+		}
+		switch o.Type {
+		case "x-mitre-collection":
+			bundle.Version = o.Version
+			continue
+		case "attack-pattern":
+			if !domain.IsAttackTechniqueID(id) {
+				continue
+			}
+			object.Type = domain.AttackTechnique
+			for _, phase := range o.KillChainPhases {
+				if phase.KillChainName == "mitre-attack" {
+					object.Tactics = append(object.Tactics, phase.PhaseName)
+				}
+			}
+			object.Platforms = o.Platforms
+			if parent, _, ok := strings.Cut(id, "."); ok {
+				object.ParentID = parent
+			}
+			bundle.Techniques++
+		case "x-mitre-tactic":
+			if id == "" {
+				continue
+			}
+			object.Type = domain.AttackTactic
+			object.ShortName = o.ShortName
+			bundle.Tactics++
+		case "course-of-action":
+			// Bundles before ATT&CK v5 also hold per-technique courses of
+			// action, named after the technique: only Mxxxx are mitigations
+			if !domain.IsAttackMitigationID(id) {
+				continue
+			}
+			object.Type = domain.AttackMitigation
+			bundle.Mitigations++
+		default:
+			continue
+		}
+		attackIDs[o.ID] = id
+		bundle.Objects = append(bundle.Objects, object)
+	}
+	if bundle.Techniques == 0 {
+		return nil, ErrNotAttackBundle
+	}
+
+	seen := map[domain.AttackMitigates]bool{}
+	for i := range raw.Objects {
+		o := &raw.Objects[i]
+		if o.Type != "relationship" || o.RelationshipType != "mitigates" || o.Revoked || o.Deprecated {
+			continue
+		}
+		link := domain.AttackMitigates{MitigationID: attackIDs[o.SourceRef], TechniqueID: attackIDs[o.TargetRef]}
+		if !domain.IsAttackMitigationID(link.MitigationID) || !domain.IsAttackTechniqueID(link.TechniqueID) || seen[link] {
+			continue
+		}
+		seen[link] = true
+		bundle.Mitigates = append(bundle.Mitigates, link)
+	}
+	sort.Slice(bundle.Mitigates, func(i, j int) bool {
+		a, b := bundle.Mitigates[i], bundle.Mitigates[j]
+		if a.MitigationID != b.MitigationID {
+			return a.MitigationID < b.MitigationID
+		}
+		return a.TechniqueID < b.TechniqueID
+	})
+	return bundle, nil
+}
+
+// LoadFile reads the bundle at path
+func LoadFile(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ATT&CK bundle: %w", err)
+	}
+	defer f.Close()
+	bundle, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	bundle.FileName = filepath.Base(path)
+	return bundle, nil
+}
+
+// decompress transparently gunzips gzipped input
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		return gz, nil
+	}
+	return buffered, nil
+}
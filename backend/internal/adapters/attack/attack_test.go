@@ -0,0 +1,143 @@
+package attack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const enterpriseBundle = `{
+  "type": "bundle", "id": "bundle--1", "spec_version": "2.1",
+  "objects": [
+    {"type": "x-mitre-collection", "id": "x-mitre-collection--1", "name": "Enterprise ATT&CK", "x_mitre_version": "15.1"},
+    {"type": "x-mitre-tactic", "id": "x-mitre-tactic--exec", "name": "Execution", "x_mitre_shortname": "execution",
+      "external_references": [{"source_name": "mitre-attack", "external_id": "TA0002", "url": "https://attack.mitre.org/tactics/TA0002"}]},
+    {"type": "attack-pattern", "id": "attack-pattern--cmd", "name": "Command and Scripting Interpreter",
+      "kill_chain_phases": [{"kill_chain_name": "mitre-attack", "phase_name": "execution"}],
+      "x_mitre_platforms": ["Linux", "Windows"],
+      "external_references": [{"source_name": "mitre-attack", "external_id": "T1059", "url": "https://attack.mitre.org/techniques/T1059"}, {"source_name": "capec", "external_id": "CAPEC-88"}]},
+    {"type": "attack-pattern", "id": "attack-pattern--ps", "name": "PowerShell",
+      "kill_chain_phases": [{"kill_chain_name": "mitre-attack", "phase_name": "execution"}],
+      "external_references": [{"source_name": "mitre-attack", "external_id": "T1059.001"}]},
+    {"type": "attack-pattern", "id": "attack-pattern--old", "name": "Old technique", "revoked": true,
+      "external_references": [{"source_name": "mitre-attack", "external_id": "T1086"}]},
+    {"type": "attack-pattern", "id": "attack-pattern--capec", "name": "Not ATT&CK",
+      "external_references": [{"source_name": "capec", "external_id": "CAPEC-1"}]},
+    {"type": "course-of-action", "id": "course-of-action--exec", "name": "Execution Prevention",
+      "external_references": [{"source_name": "mitre-attack", "external_id": "M1038"}]},
+    {"type": "course-of-action", "id": "course-of-action--legacy", "name": "PowerShell Mitigation",
+      "external_references": [{"source_name": "mitre-attack", "external_id": "T1086"}]},
+    {"type": "relationship", "id": "relationship--1", "relationship_type": "mitigates", "source_ref": "course-of-action--exec", "target_ref": "attack-pattern--ps"},
+    {"type": "relationship", "id": "relationship--2", "relationship_type": "mitigates", "source_ref": "course-of-action--exec", "target_ref": "attack-pattern--cmd"},
+    {"type": "relationship", "id": "relationship--3", "relationship_type": "mitigates", "source_ref": "course-of-action--legacy", "target_ref": "attack-pattern--old"},
+    {"type": "relationship", "id": "relationship--4", "relationship_type": "subtechnique-of", "source_ref": "attack-pattern--ps", "target_ref": "attack-pattern--cmd"}
+  ]
+}`
+
+func TestRead(t *testing.T) {
+	bundle, err := Read(strings.NewReader(enterpriseBundle))
+	require.NoError(t, err)
+
+	assert.Equal(t, "15.1", bundle.Version)
+	assert.Len(t, bundle.Checksum, 64)
+	assert.Equal(t, 3, bundle.Techniques)
+	assert.Equal(t, 1, bundle.Tactics)
+	assert.Equal(t, 1, bundle.Mitigations, "only Mxxxx courses of action are mitigations")
+
+	objects := map[string]domain.AttackObject{}
+	for _, o := range bundle.Objects {
+		objects[o.ID] = o
+	}
+	require.Len(t, objects, 5)
+	assert.Equal(t, domain.AttackTechnique, objects["T1059"].Type)
+	assert.Equal(t, []string{"execution"}, []string(objects["T1059"].Tactics))
+	assert.Equal(t, []string{"Linux", "Windows"}, []string(objects["T1059"].Platforms))
+	assert.Equal(t, "https://attack.mitre.org/techniques/T1059", objects["T1059"].URL)
+	assert.Equal(t, "T1059", objects["T1059.001"].ParentID)
+	assert.True(t, objects["T1086"].Deprecated, "revoked techniques are kept, marked deprecated")
+	assert.Equal(t, "execution", objects["TA0002"].ShortName)
+	assert.Equal(t, "attack-pattern--ps", objects["T1059.001"].STIXID)
+
+	assert.Equal(t, []domain.AttackMitigates{
+		{MitigationID: "M1038", TechniqueID: "T1059"},
+		{MitigationID: "M1038", TechniqueID: "T1059.001"},
+	}, bundle.Mitigates)
+}
+
+func TestReadGzipAndFile(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(enterpriseBundle))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	path := filepath.Join(t.TempDir(), "enterprise-attack.json.gz")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	bundle, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "enterprise-attack.json.gz", bundle.FileName)
+	assert.Equal(t, 3, bundle.Techniques)
+
+	plain, err := Read(strings.NewReader(enterpriseBundle))
+	require.NoError(t, err)
+	assert.Equal(t, plain.Checksum, bundle.Checksum, "the checksum is of the decompressed bundle")
+}
+
+func TestReadRejectsOtherBundles(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"type": "bundle", "objects": [{"type": "indicator", "id": "indicator--1"}]}`))
+	assert.ErrorIs(t, err, ErrNotAttackBundle)
+
+	_, err = Read(strings.NewReader(`{"type": "report"}`))
+	assert.Error(t, err)
+
+	_, err = Read(strings.NewReader(`not json`))
+	assert.Error(t, err)
+}
+
+func TestNavigatorLayer(t *testing.T) {
+	coverage := []domain.TechniqueCoverage{
+		{TechniqueID: "T1566", Status: domain.CoverageExposed, OpenRisks: 3, RiskTitles: []string{"Phishing", "BEC"}},
+		{TechniqueID: "T1059.001", Status: domain.CoverageMitigated, OpenRisks: 1, DoneMitigations: 2},
+		{TechniqueID: "T1078", Status: domain.CoverageCovered, DoneMitigations: 1},
+	}
+	layer := NavigatorLayer("Coverage", "15.1", coverage)
+
+	assert.Equal(t, "15", layer.Versions.Attack)
+	assert.Equal(t, LayerFormatVersion, layer.Versions.Layer)
+	assert.Equal(t, "enterprise-attack", layer.Domain)
+	assert.Equal(t, 3, layer.Gradient.MaxValue)
+	require.Len(t, layer.Techniques, 4, "the parent of a sub-technique is added to expand it")
+
+	assert.Equal(t, "T1566", layer.Techniques[0].TechniqueID)
+	assert.Equal(t, 3, *layer.Techniques[0].Score)
+	assert.Equal(t, "#e60d0d", layer.Techniques[0].Color)
+	assert.Equal(t, "Phishing\nBEC", layer.Techniques[0].Comment)
+	assert.Equal(t, "#31a354", layer.Techniques[2].Color)
+	assert.Equal(t, 0, *layer.Techniques[2].Score)
+	assert.Equal(t, LayerTechnique{TechniqueID: "T1059", Enabled: true, ShowSubtechniques: true}, layer.Techniques[3])
+
+	raw, err := json.Marshal(layer)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	for _, key := range []string{"name", "versions", "domain", "techniques", "gradient", "legendItems"} {
+		assert.Contains(t, decoded, key)
+	}
+	assert.Equal(t, "T1059.001", decoded["techniques"].([]interface{})[1].(map[string]interface{})["techniqueID"])
+}
+
+func TestNavigatorLayerEmpty(t *testing.T) {
+	layer := NavigatorLayer("Coverage", "", nil)
+	raw, err := json.Marshal(layer)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"techniques":[]`)
+	assert.Equal(t, 1, layer.Gradient.MaxValue)
+}
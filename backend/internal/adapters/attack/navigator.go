@@ -0,0 +1,145 @@
+package attack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// Layer format and Navigator version the layers are written for
+const (
+	LayerFormatVersion = "4.5"
+	NavigatorVersion   = "4.9.1"
+)
+
+// Colors of the coverage statuses in layers
+var coverageColors = map[string]string{
+	domain.CoverageExposed:   "#e60d0d",
+	domain.CoverageMitigated: "#ffb000",
+	domain.CoverageCovered:   "#31a354",
+	domain.CoveragePlanned:   "#9ecae1",
+}
+
+// Layer is an ATT&CK Navigator layer
+type Layer struct {
+	Name                          string           `json:"name"`
+	Versions                      LayerVersions    `json:"versions"`
+	Domain                        string           `json:"domain"`
+	Description                   string           `json:"description"`
+	Sorting                       int              `json:"sorting"`
+	HideDisabled                  bool             `json:"hideDisabled"`
+	Techniques                    []LayerTechnique `json:"techniques"`
+	Gradient                      LayerGradient    `json:"gradient"`
+	LegendItems                   []LegendItem     `json:"legendItems"`
+	Metadata                      []LayerMetadata  `json:"metadata,omitempty"`
+	ShowTacticRowBackground       bool             `json:"showTacticRowBackground"`
+	SelectTechniquesAcrossTactics bool             `json:"selectTechniquesAcrossTactics"`
+	SelectSubtechniquesWithParent bool             `json:"selectSubtechniquesWithParent"`
+}
+
+// LayerVersions are the ATT&CK, Navigator and layer format versions
+type LayerVersions struct {
+	Attack    string `json:"attack,omitempty"`
+	Navigator string `json:"navigator"`
+	Layer     string `json:"layer"`
+}
+
+// LayerTechnique annotates a technique in all its tactics
+type LayerTechnique struct {
+	TechniqueID       string          `json:"techniqueID"`
+	Score             *int            `json:"score,omitempty"`
+	Color             string          `json:"color,omitempty"`
+	Comment           string          `json:"comment,omitempty"`
+	Enabled           bool            `json:"enabled"`
+	Metadata          []LayerMetadata `json:"metadata,omitempty"`
+	ShowSubtechniques bool            `json:"showSubtechniques"`
+}
+
+// LayerGradient colors techniques by score when they have no color
+type LayerGradient struct {
+	Colors   []string `json:"colors"`
+	MinValue int      `json:"minValue"`
+	MaxValue int      `json:"maxValue"`
+}
+
+// LegendItem explains a color of the layer
+type LegendItem struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// LayerMetadata is a name and value shown with a technique or the layer
+type LayerMetadata struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NavigatorLayer writes the coverage of techniques as a Navigator layer:
+// techniques are colored by status and scored by their number of open risks.
+// Parents of annotated sub-techniques are expanded.
+func NavigatorLayer(name, attackVersion string, coverage []domain.TechniqueCoverage) *Layer {
+	layer := &Layer{
+		Name:        name,
+		Versions:    LayerVersions{Attack: majorVersion(attackVersion), Navigator: NavigatorVersion, Layer: LayerFormatVersion},
+		Domain:      "enterprise-attack",
+		Description: "Techniques realizing open risks and countered by mitigations, from OpenRisk",
+		Sorting:     3, // By score, descending
+		Gradient:    LayerGradient{Colors: []string{"#ffffff", "#e60d0d"}, MinValue: 0, MaxValue: 1},
+		LegendItems: []LegendItem{
+			{Label: "Open risks, no mitigation done", Color: coverageColors[domain.CoverageExposed]},
+			{Label: "Open risks, mitigations done", Color: coverageColors[domain.CoverageMitigated]},
+			{Label: "Mitigations done, no open risk", Color: coverageColors[domain.CoverageCovered]},
+			{Label: "Mitigations planned", Color: coverageColors[domain.CoveragePlanned]},
+		},
+		SelectTechniquesAcrossTactics: true,
+		Techniques:                    []LayerTechnique{},
+	}
+
+	annotated := map[string]bool{}
+	parents := map[string]bool{}
+	for _, c := range coverage {
+		score := c.OpenRisks
+		layer.Gradient.MaxValue = max(layer.Gradient.MaxValue, score)
+		technique := LayerTechnique{
+			TechniqueID: c.TechniqueID,
+			Score:       &score,
+			Color:       coverageColors[c.Status],
+			Comment:     strings.Join(c.RiskTitles, "\n"),
+			Enabled:     true,
+			Metadata: []LayerMetadata{
+				{Name: "status", Value: c.Status},
+				{Name: "open risks", Value: fmt.Sprint(c.OpenRisks)},
+				{Name: "mitigations done", Value: fmt.Sprint(c.DoneMitigations)},
+				{Name: "mitigations planned", Value: fmt.Sprint(c.PlannedMitigations)},
+			},
+		}
+		annotated[c.TechniqueID] = true
+		if parent, _, ok := strings.Cut(c.TechniqueID, "."); ok {
+			parents[parent] = true
+		}
+		layer.Techniques = append(layer.Techniques, technique)
+	}
+	for i := range layer.Techniques {
+		layer.Techniques[i].ShowSubtechniques = parents[layer.Techniques[i].TechniqueID]
+	}
+	var missing []string
+	for parent := range parents {
+		if !annotated[parent] {
+			missing = append(missing, parent)
+		}
+	}
+	sort.Strings(missing)
+	for _, parent := range missing {
+		layer.Techniques = append(layer.Techniques, LayerTechnique{TechniqueID: parent, Enabled: true, ShowSubtechniques: true})
+	}
+	return layer
+}
+
+// majorVersion returns the major ATT&CK version the Navigator expects ("15"
+// for release 15.1)
+func majorVersion(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
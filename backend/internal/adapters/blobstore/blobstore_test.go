@@ -0,0 +1,72 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opendefender/openrisk/config"
+	"github.com/opendefender/openrisk/internal/core/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "exports/a.csv", strings.NewReader("id,title\n"), 9, "text/csv"))
+
+	body, err := store.Get(ctx, "exports/a.csv")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "id,title\n", string(data))
+
+	require.NoError(t, store.Delete(ctx, "exports/a.csv"))
+	_, err = store.Get(ctx, "exports/a.csv")
+	assert.ErrorIs(t, err, ports.ErrBlobNotFound)
+
+	assert.Error(t, store.Put(ctx, "../escape.csv", strings.NewReader("x"), 1, "text/csv"))
+}
+
+func TestS3StoreSignsPathStyleRequests(t *testing.T) {
+	var got *http.Request
+	var uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			uploaded = string(body)
+		}
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(config.StorageConfig{
+		S3Endpoint:  server.URL,
+		S3Region:    "us-east-1",
+		S3Bucket:    "openrisk",
+		S3AccessKey: "AKIDEXAMPLE",
+		S3SecretKey: "secret",
+	})
+	require.NoError(t, err)
+	store.now = func() time.Time { return time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, store.Put(context.Background(), "exports/risks 1.csv", strings.NewReader("a,b"), 3, "text/csv"))
+	assert.Equal(t, "/openrisk/exports/risks%201.csv", got.URL.EscapedPath())
+	assert.Equal(t, "a,b", uploaded)
+	assert.Equal(t, "20260304T100000Z", got.Header.Get("x-amz-date"))
+	assert.True(t, strings.HasPrefix(got.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260304/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="))
+
+	_, err = store.Get(context.Background(), "exports/missing.csv")
+	assert.ErrorIs(t, err, ports.ErrBlobNotFound)
+}
@@ -0,0 +1,217 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/ports"
+)
+
+// Asset types of common cloud resources; others keep the short name of
+// their provider type
+var awsTypes = map[string]string{
+	"aws::ec2::instance":                        "Server",
+	"aws::rds::dbinstance":                      "Database",
+	"aws::rds::dbcluster":                       "Database",
+	"aws::dynamodb::table":                      "Database",
+	"aws::s3::bucket":                           "Storage",
+	"aws::ec2::volume":                          "Storage",
+	"aws::lambda::function":                     "Function",
+	"aws::eks::cluster":                         "Container",
+	"aws::ecs::cluster":                         "Container",
+	"aws::ec2::vpc":                             "Network",
+	"aws::ec2::subnet":                          "Network",
+	"aws::ec2::securitygroup":                   "Network",
+	"aws::elasticloadbalancingv2::loadbalancer": "Network",
+	"aws::iam::role":                            "Identity",
+	"aws::iam::user":                            "Identity",
+}
+
+var azureTypes = map[string]string{
+	"microsoft.compute/virtualmachines":                "Server",
+	"microsoft.sql/servers":                            "Database",
+	"microsoft.sql/servers/databases":                  "Database",
+	"microsoft.documentdb/databaseaccounts":            "Database",
+	"microsoft.storage/storageaccounts":                "Storage",
+	"microsoft.compute/disks":                          "Storage",
+	"microsoft.web/sites":                              "Application",
+	"microsoft.containerservice/managedclusters":       "Container",
+	"microsoft.network/virtualnetworks":                "Network",
+	"microsoft.network/networksecuritygroups":          "Network",
+	"microsoft.network/loadbalancers":                  "Network",
+	"microsoft.keyvault/vaults":                        "Secrets",
+	"microsoft.managedidentity/userassignedidentities": "Identity",
+}
+
+var gcpTypes = map[string]string{
+	"compute.googleapis.com/instance":             "Server",
+	"sqladmin.googleapis.com/instance":            "Database",
+	"bigquery.googleapis.com/dataset":             "Database",
+	"spanner.googleapis.com/instance":             "Database",
+	"storage.googleapis.com/bucket":               "Storage",
+	"compute.googleapis.com/disk":                 "Storage",
+	"cloudfunctions.googleapis.com/cloudfunction": "Function",
+	"run.googleapis.com/service":                  "Application",
+	"container.googleapis.com/cluster":            "Container",
+	"compute.googleapis.com/network":              "Network",
+	"compute.googleapis.com/subnetwork":           "Network",
+	"compute.googleapis.com/firewall":             "Network",
+	"iam.googleapis.com/serviceaccount":           "Identity",
+	"secretmanager.googleapis.com/secret":         "Secrets",
+}
+
+// awsConfigItem is a configuration item of an AWS Config snapshot
+type awsConfigItem struct {
+	ResourceType     string            `json:"resourceType"`
+	ResourceID       string            `json:"resourceId"`
+	ResourceName     string            `json:"resourceName"`
+	ARN              string            `json:"ARN"`
+	AccountID        string            `json:"awsAccountId"`
+	Region           string            `json:"awsRegion"`
+	Status           string            `json:"configurationItemStatus"`
+	Tags             map[string]string `json:"tags"`
+	AvailabilityZone string            `json:"availabilityZone"`
+}
+
+// parseAWSConfig reads an AWS Config snapshot ({"configurationItems": [...]})
+// or a list of configuration items. Deleted resources are retired.
+func parseAWSConfig(data []byte) ([]ports.DiscoveredAsset, error) {
+	items, err := decodeObjects(data, "configurationItems", "ConfigurationItems")
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ports.DiscoveredAsset, 0, len(items))
+	for i, raw := range items {
+		var item awsConfigItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, fmt.Errorf("invalid AWS Config item %d: %w", i+1, err)
+		}
+		id := item.ARN
+		if id == "" {
+			id = item.ResourceID
+		}
+		name := item.ResourceName
+		if name == "" {
+			name, _ = lookupTag(item.Tags, "name")
+		}
+		description := strings.TrimSpace(strings.Join(nonEmpty(item.ResourceType, item.AccountID, item.Region), " "))
+		record := newRecord(SourceAWS, id, name, mapType(awsTypes, item.ResourceType), description, item.Tags)
+		record.Retired = strings.HasPrefix(item.Status, "ResourceDeleted")
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// azureResource is a row of an Azure Resource Graph query on Resources
+type azureResource struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Type           string            `json:"type"`
+	Location       string            `json:"location"`
+	ResourceGroup  string            `json:"resourceGroup"`
+	SubscriptionID string            `json:"subscriptionId"`
+	Tags           map[string]string `json:"tags"`
+	Properties     struct {
+		ProvisioningState string `json:"provisioningState"`
+	} `json:"properties"`
+}
+
+// parseAzureGraph reads the result of an Azure Resource Graph query, as
+// returned by the API or az graph query ({"data": [...]}), or its rows.
+// Resources being deleted are retired.
+func parseAzureGraph(data []byte) ([]ports.DiscoveredAsset, error) {
+	items, err := decodeObjects(data, "data", "value")
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ports.DiscoveredAsset, 0, len(items))
+	for i, raw := range items {
+		var resource azureResource
+		if err := json.Unmarshal(raw, &resource); err != nil {
+			return nil, fmt.Errorf("invalid Azure resource %d: %w", i+1, err)
+		}
+		// Resource IDs are case-insensitive
+		id := strings.ToLower(resource.ID)
+		description := strings.Join(nonEmpty(resource.Type, resource.ResourceGroup, resource.Location), " ")
+		record := newRecord(SourceAzure, id, resource.Name, mapType(azureTypes, resource.Type), description, resource.Tags)
+		switch strings.ToLower(resource.Properties.ProvisioningState) {
+		case "deleting", "deleted":
+			record.Retired = true
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// gcpAsset is an asset of a Cloud Asset Inventory export (asset_type and
+// resource.data) or search result (assetType, displayName and labels)
+type gcpAsset struct {
+	Name          string            `json:"name"`
+	AssetType     string            `json:"assetType"`
+	AssetTypeFile string            `json:"asset_type"`
+	DisplayName   string            `json:"displayName"`
+	Labels        map[string]string `json:"labels"`
+	Location      string            `json:"location"`
+	Project       string            `json:"project"`
+	State         string            `json:"state"`
+	Deleted       bool              `json:"deleted"`
+	Resource      struct {
+		Location string `json:"location"`
+		Data     struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"data"`
+	} `json:"resource"`
+}
+
+// parseGCPAssets reads a Cloud Asset Inventory export (newline-delimited
+// JSON) or the JSON array of gcloud asset search-all-resources. Deleted
+// assets are retired.
+func parseGCPAssets(data []byte) ([]ports.DiscoveredAsset, error) {
+	items, err := decodeObjects(data, "assets", "results")
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ports.DiscoveredAsset, 0, len(items))
+	for i, raw := range items {
+		var asset gcpAsset
+		if err := json.Unmarshal(raw, &asset); err != nil {
+			return nil, fmt.Errorf("invalid GCP asset %d: %w", i+1, err)
+		}
+		assetType := asset.AssetType
+		if assetType == "" {
+			assetType = asset.AssetTypeFile
+		}
+		labels := asset.Labels
+		if len(labels) == 0 {
+			labels = asset.Resource.Data.Labels
+		}
+		name := asset.DisplayName
+		if name == "" {
+			name = asset.Resource.Data.Name
+		}
+		if name == "" {
+			name = asset.Name[strings.LastIndex(asset.Name, "/")+1:]
+		}
+		location := asset.Location
+		if location == "" {
+			location = asset.Resource.Location
+		}
+		description := strings.Join(nonEmpty(assetType, strings.TrimPrefix(asset.Project, "projects/"), location), " ")
+		record := newRecord(SourceGCP, asset.Name, name, mapType(gcpTypes, assetType), description, labels)
+		record.Retired = asset.Deleted || strings.EqualFold(asset.State, "DELETED")
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func nonEmpty(values ...string) []string {
+	out := values[:0:0]
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
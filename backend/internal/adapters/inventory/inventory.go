@@ -0,0 +1,326 @@
+// Package inventory reads cloud and CMDB inventories (AWS Config snapshots,
+// Azure Resource Graph and GCP Cloud Asset Inventory exports, ServiceNow CMDB
+// tables) and maps their resources to assets.
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+)
+
+// Format is the kind of inventory export
+type Format string
+
+const (
+	FormatAWSConfig  Format = "aws-config"
+	FormatAzureGraph Format = "azure-resource-graph"
+	FormatGCPAssets  Format = "gcp-asset-inventory"
+	FormatServiceNow Format = "servicenow-cmdb"
+)
+
+const (
+	maxInventorySize = 64 << 20
+	maxAssetNameSize = 255
+	maxAssetTagValue = 200
+)
+
+// Formats lists the supported inventory formats
+var Formats = []Format{FormatAWSConfig, FormatAzureGraph, FormatGCPAssets, FormatServiceNow}
+
+// Asset sources, recorded in Asset.Source
+const (
+	SourceAWS        = "AWS"
+	SourceAzure      = "AZURE"
+	SourceGCP        = "GCP"
+	SourceServiceNow = "SERVICENOW"
+)
+
+// ErrUnsupportedFormat is returned for unknown inventory formats
+var ErrUnsupportedFormat = errors.New("unsupported inventory format")
+
+// ParseFormat reads a format as written in URLs: "aws-config", "aws",
+// "azure", "gcp", "servicenow"...
+func ParseFormat(raw string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "aws-config", "aws_config", "aws":
+		return FormatAWSConfig, nil
+	case "azure-resource-graph", "azure_resource_graph", "azure":
+		return FormatAzureGraph, nil
+	case "gcp-asset-inventory", "gcp_asset_inventory", "gcp":
+		return FormatGCPAssets, nil
+	case "servicenow-cmdb", "servicenow_cmdb", "servicenow":
+		return FormatServiceNow, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, raw)
+}
+
+// Source returns the asset source of the format
+func (f Format) Source() string {
+	switch f {
+	case FormatAWSConfig:
+		return SourceAWS
+	case FormatAzureGraph:
+		return SourceAzure
+	case FormatGCPAssets:
+		return SourceGCP
+	case FormatServiceNow:
+		return SourceServiceNow
+	}
+	return ""
+}
+
+// Parse reads an inventory export. Resources without an identifier are
+// skipped; a resource listed twice keeps its last record.
+func Parse(format Format, r io.Reader) ([]ports.DiscoveredAsset, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxInventorySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+	if len(data) > maxInventorySize {
+		return nil, fmt.Errorf("inventory exceeds %d MB", maxInventorySize>>20)
+	}
+
+	var records []ports.DiscoveredAsset
+	switch format {
+	case FormatAWSConfig:
+		records, err = parseAWSConfig(data)
+	case FormatAzureGraph:
+		records, err = parseAzureGraph(data)
+	case FormatGCPAssets:
+		records, err = parseGCPAssets(data)
+	case FormatServiceNow:
+		records, err = parseServiceNow(data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dedupe(records), nil
+}
+
+// dedupe keeps the last record of each external ID, in first-seen order
+func dedupe(records []ports.DiscoveredAsset) []ports.DiscoveredAsset {
+	index := make(map[string]int, len(records))
+	out := make([]ports.DiscoveredAsset, 0, len(records))
+	for _, record := range records {
+		id := record.Asset.ExternalID
+		if id == "" {
+			continue
+		}
+		if i, ok := index[id]; ok {
+			out[i] = record
+			continue
+		}
+		index[id] = len(out)
+		out = append(out, record)
+	}
+	return out
+}
+
+// newRecord builds the record of a resource, inferring its owner and
+// criticality from its tags
+func newRecord(source, externalID, name, assetType, description string, tags map[string]string) ports.DiscoveredAsset {
+	if name == "" {
+		name = externalID
+	}
+	if len(name) > maxAssetNameSize {
+		name = name[:maxAssetNameSize]
+	}
+	return ports.DiscoveredAsset{Asset: domain.Asset{
+		Name:        name,
+		Type:        assetType,
+		Owner:       InferOwner(tags),
+		Criticality: InferCriticality(tags),
+		Description: description,
+		Tags:        formatTags(tags),
+		Source:      source,
+		ExternalID:  externalID,
+	}}
+}
+
+// formatTags renders tags as sorted "key=value" strings
+func formatTags(tags map[string]string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if len(v) > maxAssetTagValue {
+			v = v[:maxAssetTagValue]
+		}
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// tagKey normalises a tag key: "Business-Criticality", "business_criticality"
+// and "businessCriticality" are the same key
+func tagKey(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// lookupTag returns the value of the first of keys present in tags
+func lookupTag(tags map[string]string, keys ...string) (string, bool) {
+	normalised := make(map[string]string, len(tags))
+	for k, v := range tags {
+		normalised[tagKey(k)] = strings.TrimSpace(v)
+	}
+	for _, key := range keys {
+		if v, ok := normalised[key]; ok && v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// InferOwner reads the owner of a resource from its tags (owner, owner-email,
+// contact, team...); empty when none is set
+func InferOwner(tags map[string]string) string {
+	owner, _ := lookupTag(tags, "owner", "owneremail", "ownedby", "businessowner", "technicalowner", "contact", "team")
+	return owner
+}
+
+// InferCriticality reads the criticality of a resource from its tags, in
+// order: an explicit criticality (critical, high, "1 - most critical"...), a
+// tier (0 and 1 are critical), then the environment (production is high,
+// staging medium, development and test low). Empty when no tag tells it.
+func InferCriticality(tags map[string]string) domain.AssetCriticality {
+	if v, ok := lookupTag(tags, "criticality", "businesscriticality", "assetcriticality", "impact"); ok {
+		if c := parseCriticality(v); c != "" {
+			return c
+		}
+	}
+	if v, ok := lookupTag(tags, "tier", "servicetier", "businesstier"); ok {
+		switch digits := strings.TrimFunc(v, func(r rune) bool { return !unicode.IsDigit(r) }); digits {
+		case "0", "1":
+			return domain.CriticalityCritical
+		case "2":
+			return domain.CriticalityHigh
+		case "3":
+			return domain.CriticalityMedium
+		case "":
+		default:
+			return domain.CriticalityLow
+		}
+	}
+	if v, ok := lookupTag(tags, "environment", "env", "stage"); ok {
+		switch strings.ToLower(v) {
+		case "prod", "production", "prd", "live":
+			return domain.CriticalityHigh
+		case "staging", "stage", "stg", "preprod", "pre-production", "uat", "acceptance":
+			return domain.CriticalityMedium
+		case "dev", "development", "test", "testing", "qa", "sandbox", "demo", "lab":
+			return domain.CriticalityLow
+		}
+	}
+	return ""
+}
+
+// parseCriticality reads a criticality value: a level name, or a ServiceNow
+// style rank from "1 - most critical" to "4 - not critical"
+func parseCriticality(value string) domain.AssetCriticality {
+	v := strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case strings.HasPrefix(v, "1"):
+		return domain.CriticalityCritical
+	case strings.HasPrefix(v, "2"):
+		return domain.CriticalityHigh
+	case strings.HasPrefix(v, "3"):
+		return domain.CriticalityMedium
+	case strings.HasPrefix(v, "4"), strings.HasPrefix(v, "5"):
+		return domain.CriticalityLow
+	case strings.Contains(v, "not critical"), strings.Contains(v, "non critical"), strings.Contains(v, "non-critical"):
+		return domain.CriticalityLow
+	case strings.Contains(v, "critical"):
+		return domain.CriticalityCritical
+	case strings.Contains(v, "high"):
+		return domain.CriticalityHigh
+	case strings.Contains(v, "medium"), strings.Contains(v, "moderate"):
+		return domain.CriticalityMedium
+	case strings.Contains(v, "low"):
+		return domain.CriticalityLow
+	}
+	return ""
+}
+
+// mapType maps a provider resource type to an asset type, falling back to
+// the last segment of the provider type
+func mapType(types map[string]string, resourceType string) string {
+	if t, ok := types[strings.ToLower(resourceType)]; ok {
+		return t
+	}
+	short := resourceType
+	if i := strings.LastIndexAny(short, ":/."); i >= 0 && i < len(short)-1 {
+		short = short[i+1:]
+	}
+	return short
+}
+
+// decodeObjects reads a JSON array of objects, an object holding the array
+// under one of keys, or newline-delimited JSON objects
+func decodeObjects(data []byte, keys ...string) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if trimmed[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("invalid inventory JSON: %w", err)
+		}
+		return items, nil
+	}
+
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &wrapper); err == nil {
+		for _, key := range keys {
+			if raw, ok := wrapper[key]; ok {
+				var items []json.RawMessage
+				if err := json.Unmarshal(raw, &items); err != nil {
+					return nil, fmt.Errorf("invalid inventory JSON in %q: %w", key, err)
+				}
+				return items, nil
+			}
+		}
+		// A single object is a one-line newline-delimited export
+		return []json.RawMessage{trimmed}, nil
+	}
+
+	var items []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxInventorySize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if !json.Valid(text) {
+			return nil, fmt.Errorf("invalid inventory JSON on line %d", line)
+		}
+		items = append(items, append(json.RawMessage(nil), text...))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,240 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	for raw, want := range map[string]Format{
+		"aws":                  FormatAWSConfig,
+		"AWS-Config":           FormatAWSConfig,
+		"azure":                FormatAzureGraph,
+		"gcp_asset_inventory":  FormatGCPAssets,
+		"servicenow":           FormatServiceNow,
+		"azure-resource-graph": FormatAzureGraph,
+	} {
+		got, err := ParseFormat(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+
+	_, err := ParseFormat("oracle")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestParseAWSConfig(t *testing.T) {
+	snapshot := `{"fileVersion": "1.0", "configurationItems": [
+		{"resourceType": "AWS::EC2::Instance", "resourceId": "i-0abc", "ARN": "arn:aws:ec2:eu-west-1:123:instance/i-0abc",
+		 "awsAccountId": "123", "awsRegion": "eu-west-1", "configurationItemStatus": "OK",
+		 "tags": {"Name": "web-1", "Owner": "platform@example.com", "Environment": "production"}},
+		{"resourceType": "AWS::S3::Bucket", "resourceId": "logs", "resourceName": "logs",
+		 "configurationItemStatus": "ResourceDeleted", "tags": {"criticality": "low"}},
+		{"resourceType": "AWS::SQS::Queue", "resourceId": "jobs", "configurationItemStatus": "OK"}
+	]}`
+
+	records, err := Parse(FormatAWSConfig, strings.NewReader(snapshot))
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	web := records[0]
+	assert.Equal(t, "arn:aws:ec2:eu-west-1:123:instance/i-0abc", web.Asset.ExternalID)
+	assert.Equal(t, "web-1", web.Asset.Name)
+	assert.Equal(t, "Server", web.Asset.Type)
+	assert.Equal(t, "platform@example.com", web.Asset.Owner)
+	assert.Equal(t, domain.CriticalityHigh, web.Asset.Criticality)
+	assert.Equal(t, SourceAWS, web.Asset.Source)
+	assert.Equal(t, []string{"Environment=production", "Name=web-1", "Owner=platform@example.com"}, []string(web.Asset.Tags))
+	assert.False(t, web.Retired)
+
+	assert.Equal(t, "logs", records[1].Asset.ExternalID)
+	assert.Equal(t, "Storage", records[1].Asset.Type)
+	assert.True(t, records[1].Retired)
+
+	// Unknown types keep their short name; no tag means no criticality
+	assert.Equal(t, "Queue", records[2].Asset.Type)
+	assert.Equal(t, "jobs", records[2].Asset.Name)
+	assert.Empty(t, records[2].Asset.Criticality)
+}
+
+func TestParseAzureGraph(t *testing.T) {
+	result := `{"count": 2, "data": [
+		{"id": "/subscriptions/S1/resourceGroups/RG/providers/Microsoft.Compute/virtualMachines/vm1", "name": "vm1",
+		 "type": "microsoft.compute/virtualmachines", "location": "westeurope", "resourceGroup": "rg",
+		 "tags": {"business-criticality": "Critical", "team": "payments"}, "properties": {"provisioningState": "Succeeded"}},
+		{"id": "/subscriptions/S1/resourceGroups/RG/providers/Microsoft.Sql/servers/db1", "name": "db1",
+		 "type": "microsoft.sql/servers", "tags": {"env": "dev"}, "properties": {"provisioningState": "Deleting"}}
+	]}`
+
+	records, err := Parse(FormatAzureGraph, strings.NewReader(result))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	vm := records[0]
+	assert.Equal(t, "/subscriptions/s1/resourcegroups/rg/providers/microsoft.compute/virtualmachines/vm1", vm.Asset.ExternalID)
+	assert.Equal(t, "Server", vm.Asset.Type)
+	assert.Equal(t, "payments", vm.Asset.Owner)
+	assert.Equal(t, domain.CriticalityCritical, vm.Asset.Criticality)
+	assert.False(t, vm.Retired)
+
+	assert.Equal(t, "Database", records[1].Asset.Type)
+	assert.Equal(t, domain.CriticalityLow, records[1].Asset.Criticality)
+	assert.True(t, records[1].Retired)
+}
+
+func TestParseGCPAssets(t *testing.T) {
+	// Newline-delimited Cloud Asset Inventory export
+	export := `{"name": "//compute.googleapis.com/projects/p1/zones/europe-west1-b/instances/api-1", "asset_type": "compute.googleapis.com/Instance", "resource": {"location": "europe-west1-b", "data": {"name": "api-1", "labels": {"tier": "1"}}}}
+{"name": "//storage.googleapis.com/old-bucket", "asset_type": "storage.googleapis.com/Bucket", "deleted": true}
+`
+	records, err := Parse(FormatGCPAssets, strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "api-1", records[0].Asset.Name)
+	assert.Equal(t, "Server", records[0].Asset.Type)
+	assert.Equal(t, domain.CriticalityCritical, records[0].Asset.Criticality)
+	assert.False(t, records[0].Retired)
+	assert.Equal(t, "old-bucket", records[1].Asset.Name)
+	assert.True(t, records[1].Retired)
+
+	// gcloud asset search-all-resources output
+	search := `[{"name": "//sqladmin.googleapis.com/projects/p1/instances/orders", "assetType": "sqladmin.googleapis.com/Instance",
+		"displayName": "orders", "project": "projects/42", "location": "europe-west1", "state": "RUNNABLE", "labels": {"environment": "staging"}}]`
+	records, err = Parse(FormatGCPAssets, strings.NewReader(search))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Database", records[0].Asset.Type)
+	assert.Equal(t, domain.CriticalityMedium, records[0].Asset.Criticality)
+}
+
+func TestParseServiceNow(t *testing.T) {
+	export := `{"records": [
+		{"sys_id": "a1", "name": "erp-db", "sys_class_name": "cmdb_ci_db_ora_instance", "owned_by": "Jane Doe",
+		 "busines_criticality": "1 - most critical", "install_status": "1"},
+		{"sys_id": "b2", "name": "old-app", "sys_class_name": "cmdb_ci_appl", "install_status": "7"},
+		{"sys_id": "a1", "name": "erp-db-renamed", "sys_class_name": "cmdb_ci_db_ora_instance", "install_status": "1"}
+	]}`
+
+	records, err := Parse(FormatServiceNow, strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, records, 2, "a record listed twice keeps its last version")
+
+	assert.Equal(t, "erp-db-renamed", records[0].Asset.Name)
+	assert.Equal(t, "Database", records[0].Asset.Type)
+	assert.Equal(t, SourceServiceNow, records[0].Asset.Source)
+	assert.True(t, records[1].Retired)
+}
+
+func TestParseRejectsInvalidJSON(t *testing.T) {
+	_, err := Parse(FormatAWSConfig, strings.NewReader(`[{"resourceId": `))
+	assert.Error(t, err)
+	_, err = Parse(Format("oracle"), strings.NewReader(`[]`))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestInferCriticality(t *testing.T) {
+	tests := []struct {
+		tags map[string]string
+		want domain.AssetCriticality
+	}{
+		{map[string]string{"Criticality": "HIGH"}, domain.CriticalityHigh},
+		{map[string]string{"business_criticality": "2 - somewhat critical"}, domain.CriticalityHigh},
+		{map[string]string{"businessCriticality": "not critical"}, domain.CriticalityLow},
+		{map[string]string{"tier": "tier-0"}, domain.CriticalityCritical},
+		{map[string]string{"tier": "3"}, domain.CriticalityMedium},
+		{map[string]string{"Environment": "prod"}, domain.CriticalityHigh},
+		{map[string]string{"env": "sandbox"}, domain.CriticalityLow},
+		// An explicit criticality wins over the environment
+		{map[string]string{"env": "prod", "criticality": "low"}, domain.CriticalityLow},
+		{map[string]string{"env": "unknown"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, InferCriticality(tt.tags), "%v", tt.tags)
+	}
+}
+
+func TestInferOwner(t *testing.T) {
+	assert.Equal(t, "ops@example.com", InferOwner(map[string]string{"Owner-Email": "ops@example.com"}))
+	assert.Equal(t, "alice", InferOwner(map[string]string{"team": "payments", "owner": "alice"}))
+	assert.Empty(t, InferOwner(map[string]string{"env": "prod"}))
+}
+
+// serviceNowStandIn serves a CMDB table through a Table API stand-in
+func serviceNowStandIn(t *testing.T, cis []map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "svc" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": {"message": "User Not Authenticated"}}`)
+			return
+		}
+		if r.URL.Path != "/api/now/table/cmdb_ci_server" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "all", r.URL.Query().Get("sysparm_display_value"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("sysparm_limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("sysparm_offset"))
+		page := []map[string]interface{}{}
+		for i := offset; i < offset+limit && i < len(cis); i++ {
+			page = append(page, cis[i])
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"result": page}))
+	}))
+}
+
+func TestServiceNowClientFetchAssets(t *testing.T) {
+	var cis []map[string]interface{}
+	for i := 0; i < 5; i++ {
+		cis = append(cis, map[string]interface{}{
+			"sys_id":         map[string]string{"value": fmt.Sprintf("ci-%d", i), "display_value": fmt.Sprintf("ci-%d", i)},
+			"name":           map[string]string{"value": fmt.Sprintf("srv-%d", i), "display_value": fmt.Sprintf("srv-%d", i)},
+			"sys_class_name": map[string]string{"value": "cmdb_ci_linux_server", "display_value": "Linux Server"},
+			"owned_by":       map[string]string{"value": "6816f79cc0a8016401c5a33be04be441", "display_value": "Fred Luddy"},
+			"install_status": map[string]string{"value": "1", "display_value": "Installed"},
+		})
+	}
+	cis[4]["install_status"] = map[string]string{"value": "7", "display_value": "Retired"}
+	cis[3]["busines_criticality"] = map[string]string{"value": "1 - most critical", "display_value": "1 - most critical"}
+
+	server := serviceNowStandIn(t, cis)
+	defer server.Close()
+
+	client := NewServiceNowClient(server.URL+"/", "svc", "secret", "cmdb_ci_server")
+	client.PageSize = 2
+	assert.Equal(t, SourceServiceNow, client.Source())
+
+	records, err := client.FetchAssets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 5, "every page is read")
+
+	assert.Equal(t, "ci-0", records[0].Asset.ExternalID)
+	assert.Equal(t, "srv-0", records[0].Asset.Name)
+	assert.Equal(t, "Server", records[0].Asset.Type)
+	assert.Equal(t, "Fred Luddy", records[0].Asset.Owner, "references read as their display value")
+	assert.Equal(t, domain.CriticalityCritical, records[3].Asset.Criticality)
+	assert.True(t, records[4].Retired)
+	assert.False(t, records[0].Retired)
+}
+
+func TestServiceNowClientAuthFailure(t *testing.T) {
+	server := serviceNowStandIn(t, nil)
+	defer server.Close()
+
+	client := NewServiceNowClient(server.URL, "svc", "wrong", "cmdb_ci_server")
+	_, err := client.FetchAssets(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
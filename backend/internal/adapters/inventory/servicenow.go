@@ -0,0 +1,255 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/core/ports"
+)
+
+// Asset types of common CMDB classes; others keep their class name
+var serviceNowTypes = map[string]string{
+	"cmdb_ci_server":                "Server",
+	"cmdb_ci_linux_server":          "Server",
+	"cmdb_ci_win_server":            "Server",
+	"cmdb_ci_unix_server":           "Server",
+	"cmdb_ci_vm_instance":           "Server",
+	"cmdb_ci_computer":              "Laptop",
+	"cmdb_ci_database":              "Database",
+	"cmdb_ci_db_instance":           "Database",
+	"cmdb_ci_db_mssql_instance":     "Database",
+	"cmdb_ci_db_ora_instance":       "Database",
+	"cmdb_ci_appl":                  "Application",
+	"cmdb_ci_app_server":            "Application",
+	"cmdb_ci_netgear":               "Network",
+	"cmdb_ci_ip_router":             "Network",
+	"cmdb_ci_ip_switch":             "Network",
+	"cmdb_ci_ip_firewall":           "Network",
+	"cmdb_ci_storage_device":        "Storage",
+	"cmdb_ci_cloud_service_account": "SaaS",
+}
+
+// snValue is a CMDB field, exported either as a string or, with
+// sysparm_display_value=all, as {"display_value", "value"}
+type snValue struct {
+	Value   string
+	Display string
+}
+
+func (v *snValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v.Value, v.Display = s, s
+		return nil
+	}
+	var obj struct {
+		Value   interface{} `json:"value"`
+		Display interface{} `json:"display_value"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		// Numbers, booleans and null read as their text
+		v.Value = strings.Trim(string(data), `"`)
+		if v.Value == "null" {
+			v.Value = ""
+		}
+		v.Display = v.Value
+		return nil
+	}
+	v.Value = fmt.Sprint(valueOrEmpty(obj.Value))
+	v.Display = fmt.Sprint(valueOrEmpty(obj.Display))
+	if v.Display == "" {
+		v.Display = v.Value
+	}
+	return nil
+}
+
+func valueOrEmpty(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// text returns the display value, readable for references like owned_by
+func (v snValue) text() string {
+	return strings.TrimSpace(v.Display)
+}
+
+// serviceNowCI is a configuration item of a CMDB table
+type serviceNowCI struct {
+	SysID               snValue `json:"sys_id"`
+	Name                snValue `json:"name"`
+	Class               snValue `json:"sys_class_name"`
+	ShortDescription    snValue `json:"short_description"`
+	OwnedBy             snValue `json:"owned_by"`
+	ManagedBy           snValue `json:"managed_by"`
+	SupportGroup        snValue `json:"support_group"`
+	BusinessCriticality snValue `json:"busines_criticality"` // Spelled so in the CMDB schema
+	Criticality         snValue `json:"business_criticality"`
+	Environment         snValue `json:"environment"`
+	InstallStatus       snValue `json:"install_status"`
+	OperationalStatus   snValue `json:"operational_status"`
+}
+
+// parseServiceNow reads a CMDB table export: the JSON export
+// ({"records": [...]}) or a Table API response ({"result": [...]})
+func parseServiceNow(data []byte) ([]ports.DiscoveredAsset, error) {
+	items, err := decodeObjects(data, "records", "result")
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ports.DiscoveredAsset, 0, len(items))
+	for i, raw := range items {
+		var ci serviceNowCI
+		if err := json.Unmarshal(raw, &ci); err != nil {
+			return nil, fmt.Errorf("invalid CMDB record %d: %w", i+1, err)
+		}
+		records = append(records, ci.record())
+	}
+	return records, nil
+}
+
+func (ci serviceNowCI) record() ports.DiscoveredAsset {
+	class := strings.TrimSpace(ci.Class.Value)
+	assetType := class
+	if t, ok := serviceNowTypes[strings.ToLower(class)]; ok {
+		assetType = t
+	}
+	tags := map[string]string{}
+	for key, value := range map[string]snValue{
+		"class":                {Value: class, Display: class},
+		"business_criticality": firstValue(ci.BusinessCriticality, ci.Criticality),
+		"environment":          ci.Environment,
+		"support_group":        ci.SupportGroup,
+	} {
+		if text := value.text(); text != "" {
+			tags[key] = text
+		}
+	}
+
+	record := newRecord(SourceServiceNow, strings.TrimSpace(ci.SysID.Value), ci.Name.text(), assetType, ci.ShortDescription.text(), tags)
+	for _, owner := range []snValue{ci.OwnedBy, ci.ManagedBy, ci.SupportGroup} {
+		if owner.text() != "" {
+			record.Asset.Owner = owner.text()
+			break
+		}
+	}
+	// Retired is install_status 7 or operational_status 6
+	record.Retired = serviceNowStatusRetired(ci.InstallStatus, "7") || serviceNowStatusRetired(ci.OperationalStatus, "6")
+	return record
+}
+
+// serviceNowStatusRetired tells whether a status field, by value or display
+// value, is the retired state
+func serviceNowStatusRetired(status snValue, retiredValue string) bool {
+	return strings.TrimSpace(status.Value) == retiredValue || strings.EqualFold(status.text(), "retired")
+}
+
+func firstValue(values ...snValue) snValue {
+	for _, v := range values {
+		if v.text() != "" {
+			return v
+		}
+	}
+	return snValue{}
+}
+
+// ServiceNowClient reads configuration items from the Table API of a
+// ServiceNow instance, page by page
+type ServiceNowClient struct {
+	BaseURL  string // https://<instance>.service-now.com
+	Username string
+	Password string
+	Table    string // cmdb_ci when empty
+	PageSize int
+	Client   *http.Client
+}
+
+// NewServiceNowClient creates a Table API client for a CMDB table
+func NewServiceNowClient(baseURL, username, password, table string) *ServiceNowClient {
+	if table == "" {
+		table = "cmdb_ci"
+	}
+	return &ServiceNowClient{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Username: username,
+		Password: password,
+		Table:    table,
+		PageSize: 1000,
+		Client:   &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Source returns the asset source of the CMDB
+// Implements the AssetInventoryProvider interface
+func (c *ServiceNowClient) Source() string {
+	return SourceServiceNow
+}
+
+// FetchAssets reads every configuration item of the table
+// Implements the AssetInventoryProvider interface
+func (c *ServiceNowClient) FetchAssets(ctx context.Context) ([]ports.DiscoveredAsset, error) {
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = 1000
+	}
+	var records []ports.DiscoveredAsset
+	for offset := 0; ; offset += pageSize {
+		page, err := c.fetchPage(ctx, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, ci := range page {
+			records = append(records, ci.record())
+		}
+		if len(page) < pageSize {
+			return dedupe(records), nil
+		}
+	}
+}
+
+func (c *ServiceNowClient) fetchPage(ctx context.Context, offset, limit int) ([]serviceNowCI, error) {
+	query := url.Values{}
+	query.Set("sysparm_display_value", "all")
+	query.Set("sysparm_exclude_reference_link", "true")
+	query.Set("sysparm_limit", strconv.Itoa(limit))
+	query.Set("sysparm_offset", strconv.Itoa(offset))
+	query.Set("sysparm_query", "ORDERBYsys_id")
+	endpoint := fmt.Sprintf("%s/api/now/table/%s?%s", c.BaseURL, url.PathEscape(c.Table), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ServiceNow request: %w", err)
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	req.Header.Set("Accept", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ServiceNow request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ServiceNow returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var page struct {
+		Result []serviceNowCI `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxInventorySize)).Decode(&page); err != nil {
+		return nil, fmt.Errorf("invalid ServiceNow response: %w", err)
+	}
+	return page.Result, nil
+}
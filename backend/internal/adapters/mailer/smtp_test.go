@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/core/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMessage(t *testing.T) {
+	data, err := BuildMessage("OpenRisk <reports@example.com>", ports.EmailMessage{
+		To:          []string{"ciso@example.com", " Board <board@example.com> "},
+		Subject:     "Board pack – March",
+		Body:        "Please find the board pack attached.",
+		Attachments: []ports.Attachment{{Filename: "board.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}},
+	}, time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "ciso@example.com, board@example.com", msg.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Board pack – March", subject)
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := reader.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", body.Header.Get("Content-Type"))
+
+	attachment, err := reader.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "board.pdf", attachment.FileName())
+	raw, _ := io.ReadAll(attachment)
+	assert.Contains(t, string(raw), "JVBERi0xLjQ=")
+}
+
+func TestBuildMessageRejectsHeaderInjection(t *testing.T) {
+	_, err := BuildMessage("reports@example.com", ports.EmailMessage{To: []string{"a@example.com"}, Subject: "x\r\nBcc: evil@example.com"}, time.Now())
+	assert.Error(t, err)
+
+	_, err = BuildMessage("reports@example.com", ports.EmailMessage{To: []string{"not an address"}}, time.Now())
+	assert.Error(t, err)
+
+	_, err = BuildMessage("reports@example.com", ports.EmailMessage{}, time.Now())
+	assert.ErrorIs(t, err, ErrNoRecipients)
+}
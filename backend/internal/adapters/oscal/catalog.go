@@ -0,0 +1,237 @@
+package oscal
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// Catalog is an OSCAL catalog of controls
+type Catalog struct {
+	XMLName    struct{}    `json:"-" xml:"http://csrc.nist.gov/ns/oscal/1.0 catalog"`
+	UUID       string      `json:"uuid" xml:"uuid,attr"`
+	Metadata   Metadata    `json:"metadata" xml:"metadata"`
+	Controls   []Control   `json:"controls,omitempty" xml:"control"`
+	Groups     []Group     `json:"groups,omitempty" xml:"group"`
+	BackMatter *BackMatter `json:"back-matter,omitempty" xml:"back-matter"`
+}
+
+// Group is a family of controls
+type Group struct {
+	ID       string     `json:"id,omitempty" xml:"id,attr,omitempty"`
+	Class    string     `json:"class,omitempty" xml:"class,attr,omitempty"`
+	Title    string     `json:"title" xml:"title"`
+	Props    []Property `json:"props,omitempty" xml:"prop"`
+	Parts    []Part     `json:"parts,omitempty" xml:"part"`
+	Groups   []Group    `json:"groups,omitempty" xml:"group"`
+	Controls []Control  `json:"controls,omitempty" xml:"control"`
+}
+
+// Control is a control; its controls are its enhancements
+type Control struct {
+	ID       string     `json:"id" xml:"id,attr"`
+	Class    string     `json:"class,omitempty" xml:"class,attr,omitempty"`
+	Title    string     `json:"title" xml:"title"`
+	Props    []Property `json:"props,omitempty" xml:"prop"`
+	Links    []Link     `json:"links,omitempty" xml:"link"`
+	Parts    []Part     `json:"parts,omitempty" xml:"part"`
+	Controls []Control  `json:"controls,omitempty" xml:"control"`
+}
+
+// Profile selects controls of catalogs or of other profiles
+type Profile struct {
+	XMLName    struct{}    `json:"-" xml:"http://csrc.nist.gov/ns/oscal/1.0 profile"`
+	UUID       string      `json:"uuid" xml:"uuid,attr"`
+	Metadata   Metadata    `json:"metadata" xml:"metadata"`
+	Imports    []Import    `json:"imports" xml:"import"`
+	BackMatter *BackMatter `json:"back-matter,omitempty" xml:"back-matter"`
+}
+
+// Import selects controls of a catalog or profile
+type Import struct {
+	Href            string             `json:"href" xml:"href,attr"`
+	IncludeAll      *struct{}          `json:"include-all,omitempty" xml:"include-all"`
+	IncludeControls []ControlSelection `json:"include-controls,omitempty" xml:"include-controls"`
+	ExcludeControls []ControlSelection `json:"exclude-controls,omitempty" xml:"exclude-controls"`
+}
+
+// ControlSelection selects controls by ID or by pattern on their ID, with
+// their enhancements when WithChildControls is yes
+type ControlSelection struct {
+	WithChildControls string     `json:"with-child-controls,omitempty" xml:"with-child-controls,attr,omitempty"`
+	WithIDs           []string   `json:"with-ids,omitempty" xml:"with-id"`
+	Matching          []Matching `json:"matching,omitempty" xml:"matching"`
+}
+
+// Matching is a glob pattern on control IDs
+type Matching struct {
+	Pattern string `json:"pattern" xml:"pattern,attr"`
+}
+
+// CatalogControls flattens the controls of a catalog, in catalog order, with
+// the title of their innermost group as family and of the catalog as
+// framework
+func CatalogControls(catalog *Catalog) []domain.Control {
+	var controls []domain.Control
+	var walkControls func(items []Control, family, parent string)
+	walkControls = func(items []Control, family, parent string) {
+		for _, c := range items {
+			controls = append(controls, domain.Control{
+				ControlID: c.ID,
+				Label:     firstLabel(c.Props, c.ID),
+				Name:      c.Title,
+				Class:     c.Class,
+				Framework: catalog.Metadata.Title,
+				Family:    family,
+				ParentID:  parent,
+				Statement: partProse(c.Parts, "statement"),
+				Guidance:  partProse(c.Parts, "guidance"),
+				Position:  len(controls),
+				Withdrawn: propValue(c.Props, "status") == "withdrawn",
+			})
+			walkControls(c.Controls, family, c.ID)
+		}
+	}
+	var walkGroups func(groups []Group)
+	walkGroups = func(groups []Group) {
+		for _, g := range groups {
+			walkControls(g.Controls, g.Title, "")
+			walkGroups(g.Groups)
+		}
+	}
+	walkControls(catalog.Controls, "", "")
+	walkGroups(catalog.Groups)
+	return controls
+}
+
+// SelectControls returns the IDs of the controls an import selects among
+// the controls it refers to, in their order
+func SelectControls(imp Import, controls []domain.Control) []string {
+	children := map[string][]string{}
+	for _, c := range controls {
+		if c.ParentID != "" {
+			children[c.ParentID] = append(children[c.ParentID], c.ControlID)
+		}
+	}
+	matches := func(selections []ControlSelection) map[string]bool {
+		selected := map[string]bool{}
+		var add func(id string, withChildren bool)
+		add = func(id string, withChildren bool) {
+			selected[id] = true
+			if withChildren {
+				for _, child := range children[id] {
+					add(child, true)
+				}
+			}
+		}
+		for _, s := range selections {
+			withChildren := s.WithChildControls == "yes"
+			for _, id := range s.WithIDs {
+				add(strings.TrimSpace(id), withChildren)
+			}
+			for _, m := range s.Matching {
+				for _, c := range controls {
+					if ok, _ := path.Match(m.Pattern, c.ControlID); ok {
+						add(c.ControlID, withChildren)
+					}
+				}
+			}
+		}
+		return selected
+	}
+
+	included := matches(imp.IncludeControls)
+	excluded := matches(imp.ExcludeControls)
+	var ids []string
+	for _, c := range controls {
+		if (imp.IncludeAll != nil || included[c.ControlID]) && !excluded[c.ControlID] {
+			ids = append(ids, c.ControlID)
+		}
+	}
+	return ids
+}
+
+// ImportRefs returns what identifies the document an import refers to: its
+// href and, for a back matter resource (#uuid), the resource UUID and the
+// hrefs of its links
+func ImportRefs(profile *Profile, imp Import) []string {
+	ref, internal := strings.CutPrefix(imp.Href, "#")
+	if !internal {
+		return []string{imp.Href}
+	}
+	refs := []string{ref}
+	if profile.BackMatter != nil {
+		for _, r := range profile.BackMatter.Resources {
+			if r.UUID == ref {
+				for _, link := range r.RLinks {
+					refs = append(refs, link.Href)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// LastModified parses the last modification date of a document
+func LastModified(m Metadata) *time.Time {
+	t, err := time.Parse(time.RFC3339Nano, m.LastModified)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+func firstLabel(props []Property, fallback string) string {
+	label := ""
+	for _, p := range props {
+		if p.Name != "label" || p.NS != "" {
+			continue
+		}
+		if p.Class == "" {
+			return p.Value
+		}
+		if label == "" {
+			label = p.Value
+		}
+	}
+	if label == "" {
+		return strings.ToUpper(fallback)
+	}
+	return label
+}
+
+func propValue(props []Property, name string) string {
+	for _, p := range props {
+		if p.Name == name && p.NS == "" {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// partProse returns the prose of the parts named name with the prose of
+// their items, each item on a line of its own led by its label
+func partProse(parts []Part, name string) string {
+	var lines []string
+	var walk func(parts []Part, depth int)
+	walk = func(parts []Part, depth int) {
+		for _, p := range parts {
+			if depth == 0 && p.Name != name {
+				continue
+			}
+			text := p.Prose
+			if label := propValue(p.Props, "label"); label != "" && depth > 0 {
+				text = label + " " + text
+			}
+			if text = strings.TrimSpace(text); text != "" {
+				lines = append(lines, strings.Repeat("  ", max(depth-1, 0))+text)
+			}
+			walk(p.Parts, depth+1)
+		}
+	}
+	walk(parts, 0)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,178 @@
+package oscal
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Markup is OSCAL multiline markup, kept as plain text with paragraphs
+// separated by blank lines. In XML each paragraph is a p element.
+type Markup string
+
+// MarshalXML writes the paragraphs of the markup
+func (m Markup) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeParagraphs(e, string(m)); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML reads the text of the blocks of the markup as paragraphs
+func (m *Markup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var paragraphs []string
+	err := decodeProse(d, func(xml.StartElement) (bool, error) { return false, nil }, &paragraphs)
+	*m = Markup(strings.Join(paragraphs, "\n\n"))
+	return err
+}
+
+func encodeParagraphs(e *xml.Encoder, text string) error {
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph == "" {
+			continue
+		}
+		if err := e.EncodeElement(paragraph, xml.StartElement{Name: xml.Name{Local: "p"}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeProse reads the blocks of prose up to the end of the current
+// element as paragraphs; the children child claims are decoded by it instead.
+// Parameter insertions are kept as the {{ insert: param, id }} of OSCAL JSON.
+func decodeProse(d *xml.Decoder, child func(xml.StartElement) (bool, error), paragraphs *[]string) error {
+	var current strings.Builder
+	flush := func() {
+		if text := strings.Join(strings.Fields(current.String()), " "); text != "" {
+			*paragraphs = append(*paragraphs, text)
+		}
+		current.Reset()
+	}
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				claimed, err := child(t)
+				if err != nil {
+					return err
+				}
+				if claimed {
+					continue
+				}
+				flush()
+			}
+			if t.Name.Local == "insert" {
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "id-ref" {
+						current.WriteString("{{ insert: param, " + attr.Value + " }}")
+					}
+				}
+			}
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				flush()
+				return nil
+			}
+			depth--
+			if depth == 0 {
+				flush()
+			}
+		case xml.CharData:
+			current.Write(t)
+		}
+	}
+}
+
+// Part is a part of a control: its statement, guidance, objectives. In XML
+// its prose is the p elements among its children.
+type Part struct {
+	ID    string     `json:"id,omitempty"`
+	Name  string     `json:"name"`
+	NS    string     `json:"ns,omitempty"`
+	Class string     `json:"class,omitempty"`
+	Title string     `json:"title,omitempty"`
+	Props []Property `json:"props,omitempty"`
+	Prose string     `json:"prose,omitempty"`
+	Parts []Part     `json:"parts,omitempty"`
+	Links []Link     `json:"links,omitempty"`
+}
+
+// MarshalXML writes the part with its prose as p elements
+func (p Part) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	for _, attr := range [][2]string{{"id", p.ID}, {"name", p.Name}, {"ns", p.NS}, {"class", p.Class}} {
+		if attr[1] != "" {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attr[0]}, Value: attr[1]})
+		}
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if p.Title != "" {
+		if err := e.EncodeElement(p.Title, xml.StartElement{Name: xml.Name{Local: "title"}}); err != nil {
+			return err
+		}
+	}
+	for _, prop := range p.Props {
+		if err := e.EncodeElement(prop, xml.StartElement{Name: xml.Name{Local: "prop"}}); err != nil {
+			return err
+		}
+	}
+	if err := encodeParagraphs(e, p.Prose); err != nil {
+		return err
+	}
+	for _, part := range p.Parts {
+		if err := e.EncodeElement(part, xml.StartElement{Name: xml.Name{Local: "part"}}); err != nil {
+			return err
+		}
+	}
+	for _, link := range p.Links {
+		if err := e.EncodeElement(link, xml.StartElement{Name: xml.Name{Local: "link"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML reads the part, its prose from the blocks among its children
+func (p *Part) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "id":
+			p.ID = attr.Value
+		case "name":
+			p.Name = attr.Value
+		case "ns":
+			p.NS = attr.Value
+		case "class":
+			p.Class = attr.Value
+		}
+	}
+	var paragraphs []string
+	err := decodeProse(d, func(child xml.StartElement) (bool, error) {
+		switch child.Name.Local {
+		case "title":
+			return true, d.DecodeElement(&p.Title, &child)
+		case "prop":
+			p.Props = append(p.Props, Property{})
+			return true, d.DecodeElement(&p.Props[len(p.Props)-1], &child)
+		case "part":
+			p.Parts = append(p.Parts, Part{})
+			return true, d.DecodeElement(&p.Parts[len(p.Parts)-1], &child)
+		case "link":
+			p.Links = append(p.Links, Link{})
+			return true, d.DecodeElement(&p.Links[len(p.Links)-1], &child)
+		}
+		return false, nil
+	}, &paragraphs)
+	p.Prose = strings.Join(paragraphs, "\n\n")
+	return err
+}
@@ -0,0 +1,260 @@
+// Package oscal reads NIST OSCAL catalogs and profiles into the control
+// catalog and writes system security plan fragments and plans of action and
+// milestones, in OSCAL JSON and XML.
+package oscal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// Version is the OSCAL version documents are written in
+const Version = "1.1.2"
+
+// Namespace is the namespace of OSCAL XML documents
+const Namespace = "http://csrc.nist.gov/ns/oscal/1.0"
+
+// PropNamespace qualifies the properties OpenRisk adds to documents
+const PropNamespace = "https://github.com/opendefender/openrisk/ns/oscal"
+
+// Formats documents are read and written in
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+)
+
+var (
+	ErrNotOSCAL         = errors.New("not an OSCAL document")
+	ErrUnsupportedModel = errors.New("unsupported OSCAL model")
+)
+
+// namespace derives the UUIDs of the objects OpenRisk writes without an ID
+// of their own, so that writing them again gives the same UUIDs
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/opendefender/openrisk/oscal"))
+
+// Document is an OSCAL document: one of its models is set
+type Document struct {
+	Catalog *Catalog `json:"catalog,omitempty"`
+	Profile *Profile `json:"profile,omitempty"`
+	SSP     *SSP     `json:"system-security-plan,omitempty"`
+	POAM    *POAM    `json:"plan-of-action-and-milestones,omitempty"`
+}
+
+// Model returns the name of the document's model
+func (d *Document) Model() string {
+	switch {
+	case d.Catalog != nil:
+		return "catalog"
+	case d.Profile != nil:
+		return "profile"
+	case d.SSP != nil:
+		return "system-security-plan"
+	case d.POAM != nil:
+		return "plan-of-action-and-milestones"
+	}
+	return ""
+}
+
+// Metadata describes a document
+type Metadata struct {
+	Title              string             `json:"title" xml:"title"`
+	Published          string             `json:"published,omitempty" xml:"published,omitempty"`
+	LastModified       string             `json:"last-modified" xml:"last-modified"`
+	Version            string             `json:"version" xml:"version"`
+	OSCALVersion       string             `json:"oscal-version" xml:"oscal-version"`
+	Props              []Property         `json:"props,omitempty" xml:"prop"`
+	Links              []Link             `json:"links,omitempty" xml:"link"`
+	Roles              []Role             `json:"roles,omitempty" xml:"role"`
+	Parties            []Party            `json:"parties,omitempty" xml:"party"`
+	ResponsibleParties []ResponsibleParty `json:"responsible-parties,omitempty" xml:"responsible-party"`
+	Remarks            Markup             `json:"remarks,omitempty" xml:"remarks,omitempty"`
+}
+
+// Property is a name and value pair, in the OSCAL namespace unless NS is set
+type Property struct {
+	Name    string `json:"name" xml:"name,attr"`
+	UUID    string `json:"uuid,omitempty" xml:"uuid,attr,omitempty"`
+	NS      string `json:"ns,omitempty" xml:"ns,attr,omitempty"`
+	Value   string `json:"value" xml:"value,attr"`
+	Class   string `json:"class,omitempty" xml:"class,attr,omitempty"`
+	Remarks Markup `json:"remarks,omitempty" xml:"remarks,omitempty"`
+}
+
+// Link points to a resource, in the back matter when its href is #uuid
+type Link struct {
+	Href      string `json:"href" xml:"href,attr"`
+	Rel       string `json:"rel,omitempty" xml:"rel,attr,omitempty"`
+	MediaType string `json:"media-type,omitempty" xml:"media-type,attr,omitempty"`
+	Text      string `json:"text,omitempty" xml:"text,omitempty"`
+}
+
+// Role is a role parties take
+type Role struct {
+	ID          string `json:"id" xml:"id,attr"`
+	Title       string `json:"title" xml:"title"`
+	Description Markup `json:"description,omitempty" xml:"description,omitempty"`
+}
+
+// Party is a person or organization
+type Party struct {
+	UUID string `json:"uuid" xml:"uuid,attr"`
+	Type string `json:"type" xml:"type,attr"` // person, organization
+	Name string `json:"name,omitempty" xml:"name,omitempty"`
+}
+
+// ResponsibleParty assigns parties to a role
+type ResponsibleParty struct {
+	RoleID     string   `json:"role-id" xml:"role-id,attr"`
+	PartyUUIDs []string `json:"party-uuids" xml:"party-uuid"`
+}
+
+// BackMatter holds the resources documents refer to
+type BackMatter struct {
+	Resources []Resource `json:"resources,omitempty" xml:"resource"`
+}
+
+// Resource is a resource of the back matter
+type Resource struct {
+	UUID        string     `json:"uuid" xml:"uuid,attr"`
+	Title       string     `json:"title,omitempty" xml:"title,omitempty"`
+	Description Markup     `json:"description,omitempty" xml:"description,omitempty"`
+	Props       []Property `json:"props,omitempty" xml:"prop"`
+	RLinks      []RLink    `json:"rlinks,omitempty" xml:"rlink"`
+	Remarks     Markup     `json:"remarks,omitempty" xml:"remarks,omitempty"`
+}
+
+// RLink locates a resource
+type RLink struct {
+	Href      string `json:"href" xml:"href,attr"`
+	MediaType string `json:"media-type,omitempty" xml:"media-type,attr,omitempty"`
+}
+
+// Read reads an OSCAL document in JSON or XML
+func Read(r io.Reader) (*Document, string, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: empty document", ErrNotOSCAL)
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == 0xEF || b == 0xBB || b == 0xBF {
+			continue
+		}
+		if err := br.UnreadByte(); err != nil {
+			return nil, "", err
+		}
+		if b == '<' {
+			doc, err := readXML(br)
+			return doc, FormatXML, err
+		}
+		doc, err := readJSON(br)
+		return doc, FormatJSON, err
+	}
+}
+
+func readJSON(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotOSCAL, err)
+	}
+	if doc.Model() == "" {
+		return nil, fmt.Errorf("%w: no catalog, profile, system-security-plan or plan-of-action-and-milestones", ErrNotOSCAL)
+	}
+	return &doc, nil
+}
+
+func readXML(r io.Reader) (*Document, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotOSCAL, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Space != Namespace {
+			return nil, fmt.Errorf("%w: root element %s is not in the OSCAL namespace", ErrNotOSCAL, start.Name.Local)
+		}
+		var doc Document
+		switch start.Name.Local {
+		case "catalog":
+			doc.Catalog = &Catalog{}
+			err = decoder.DecodeElement(doc.Catalog, &start)
+		case "profile":
+			doc.Profile = &Profile{}
+			err = decoder.DecodeElement(doc.Profile, &start)
+		case "system-security-plan":
+			doc.SSP = &SSP{}
+			err = decoder.DecodeElement(doc.SSP, &start)
+		case "plan-of-action-and-milestones":
+			doc.POAM = &POAM{}
+			err = decoder.DecodeElement(doc.POAM, &start)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedModel, start.Name.Local)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotOSCAL, err)
+		}
+		return &doc, nil
+	}
+}
+
+// Write writes a document in a format
+func Write(w io.Writer, doc *Document, format string) error {
+	if format == FormatXML {
+		return WriteXML(w, doc)
+	}
+	return WriteJSON(w, doc)
+}
+
+// WriteJSON writes a document in OSCAL JSON
+func WriteJSON(w io.Writer, doc *Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(doc)
+}
+
+// WriteXML writes a document in OSCAL XML
+func WriteXML(w io.Writer, doc *Document) error {
+	var root interface{}
+	switch {
+	case doc.Catalog != nil:
+		root = doc.Catalog
+	case doc.Profile != nil:
+		root = doc.Profile
+	case doc.SSP != nil:
+		root = doc.SSP
+	case doc.POAM != nil:
+		root = doc.POAM
+	default:
+		return ErrUnsupportedModel
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("failed to write OSCAL XML: %w", err)
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ContentType returns the media type of documents written in a format
+func ContentType(format string) string {
+	if format == FormatXML {
+		return "application/oscal+xml"
+	}
+	return "application/oscal+json"
+}
@@ -0,0 +1,336 @@
+package oscal
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const catalogJSON = `{
+  "catalog": {
+    "uuid": "74c8ba1e-5cd4-4ad1-bbfd-d888e2f6c724",
+    "metadata": {"title": "Example Catalog", "last-modified": "2024-02-01T00:00:00Z", "version": "5.1.1", "oscal-version": "1.1.2"},
+    "groups": [
+      {"id": "ac", "class": "family", "title": "Access Control", "controls": [
+        {"id": "ac-1", "class": "SP800-53", "title": "Policy and Procedures",
+          "props": [{"name": "label", "value": "AC-1"}, {"name": "sort-id", "value": "ac-01"}],
+          "parts": [
+            {"id": "ac-1_smt", "name": "statement", "parts": [
+              {"id": "ac-1_smt.a", "name": "item", "props": [{"name": "label", "value": "a."}], "prose": "Develop an access control policy for {{ insert: param, ac-1_prm_1 }}."}
+            ]},
+            {"id": "ac-1_gdn", "name": "guidance", "prose": "Policies address access control."}
+          ]},
+        {"id": "ac-2", "title": "Account Management",
+          "props": [{"name": "label", "value": "AC-2"}],
+          "parts": [{"id": "ac-2_smt", "name": "statement", "prose": "Manage system accounts."}],
+          "controls": [
+            {"id": "ac-2.1", "title": "Automated System Account Management", "props": [{"name": "label", "value": "AC-2(1)"}]},
+            {"id": "ac-2.2", "title": "Withdrawn", "props": [{"name": "label", "value": "AC-2(2)"}, {"name": "status", "value": "withdrawn"}]}
+          ]}
+      ]},
+      {"id": "au", "class": "family", "title": "Audit and Accountability", "controls": [
+        {"id": "au-2", "title": "Event Logging", "props": [{"name": "label", "value": "AU-2"}]},
+        {"id": "au-3", "title": "Content of Audit Records", "props": [{"name": "label", "value": "AU-3"}]}
+      ]}
+    ]
+  }
+}`
+
+const catalogXML = `<?xml version="1.0" encoding="UTF-8"?>
+<catalog xmlns="http://csrc.nist.gov/ns/oscal/1.0" uuid="74c8ba1e-5cd4-4ad1-bbfd-d888e2f6c724">
+  <metadata>
+    <title>Example Catalog</title>
+    <last-modified>2024-02-01T00:00:00Z</last-modified>
+    <version>5.1.1</version>
+    <oscal-version>1.1.2</oscal-version>
+  </metadata>
+  <group id="ac" class="family">
+    <title>Access Control</title>
+    <control id="ac-1" class="SP800-53">
+      <title>Policy and Procedures</title>
+      <prop name="label" value="AC-1"/>
+      <prop name="sort-id" value="ac-01"/>
+      <part id="ac-1_smt" name="statement">
+        <part id="ac-1_smt.a" name="item">
+          <prop name="label" value="a."/>
+          <p>Develop an access control policy for <insert type="param" id-ref="ac-1_prm_1"/>.</p>
+        </part>
+      </part>
+      <part id="ac-1_gdn" name="guidance">
+        <p>Policies address
+          access control.</p>
+      </part>
+    </control>
+    <control id="ac-2">
+      <title>Account Management</title>
+      <prop name="label" value="AC-2"/>
+      <part id="ac-2_smt" name="statement"><p>Manage system accounts.</p></part>
+      <control id="ac-2.1">
+        <title>Automated System Account Management</title>
+        <prop name="label" value="AC-2(1)"/>
+      </control>
+      <control id="ac-2.2">
+        <title>Withdrawn</title>
+        <prop name="label" value="AC-2(2)"/>
+        <prop name="status" value="withdrawn"/>
+      </control>
+    </control>
+  </group>
+  <group id="au" class="family">
+    <title>Audit and Accountability</title>
+    <control id="au-2"><title>Event Logging</title><prop name="label" value="AU-2"/></control>
+    <control id="au-3"><title>Content of Audit Records</title><prop name="label" value="AU-3"/></control>
+  </group>
+</catalog>`
+
+const profileXML = `<profile xmlns="http://csrc.nist.gov/ns/oscal/1.0" uuid="2b1b7a3c-1f4e-4d6e-9a53-6f1d1b6e8c11">
+  <metadata>
+    <title>Example Baseline</title>
+    <last-modified>2024-03-01T00:00:00Z</last-modified>
+    <version>1.0</version>
+    <oscal-version>1.1.2</oscal-version>
+  </metadata>
+  <import href="#9c2c4a1d-7a4f-4b0c-8d1e-3f5a6b7c8d9e">
+    <include-controls with-child-controls="yes">
+      <with-id>ac-2</with-id>
+    </include-controls>
+    <include-controls>
+      <matching pattern="au-*"/>
+    </include-controls>
+    <exclude-controls>
+      <with-id>ac-2.2</with-id>
+    </exclude-controls>
+  </import>
+  <back-matter>
+    <resource uuid="9c2c4a1d-7a4f-4b0c-8d1e-3f5a6b7c8d9e">
+      <rlink href="https://example.org/catalogs/example_catalog.json"/>
+    </resource>
+  </back-matter>
+</profile>`
+
+func readDocument(t *testing.T, data string) *Document {
+	t.Helper()
+	doc, _, err := Read(strings.NewReader(data))
+	require.NoError(t, err)
+	return doc
+}
+
+func TestCatalogControls(t *testing.T) {
+	doc := readDocument(t, catalogJSON)
+	require.Equal(t, "catalog", doc.Model())
+	require.NoError(t, Validate([]byte(catalogJSON)))
+
+	controls := CatalogControls(doc.Catalog)
+	require.Len(t, controls, 6)
+	ids := make([]string, 0, len(controls))
+	for _, c := range controls {
+		ids = append(ids, c.ControlID)
+	}
+	assert.Equal(t, []string{"ac-1", "ac-2", "ac-2.1", "ac-2.2", "au-2", "au-3"}, ids, "catalog order, enhancements after their control")
+
+	ac1 := controls[0]
+	assert.Equal(t, "AC-1", ac1.Label)
+	assert.Equal(t, "Access Control", ac1.Family)
+	assert.Equal(t, "Example Catalog", ac1.Framework)
+	assert.Equal(t, "SP800-53", ac1.Class)
+	assert.Equal(t, "a. Develop an access control policy for {{ insert: param, ac-1_prm_1 }}.", ac1.Statement)
+	assert.Equal(t, "Policies address access control.", ac1.Guidance)
+
+	assert.Equal(t, "ac-2", controls[2].ParentID)
+	assert.Equal(t, "AC-2(1)", controls[2].Label)
+	assert.True(t, controls[3].Withdrawn)
+	assert.Equal(t, 4, controls[4].Position)
+	assert.Equal(t, "Audit and Accountability", controls[4].Family)
+
+	t.Run("XML reads the same", func(t *testing.T) {
+		xmlDoc, format, err := Read(strings.NewReader(catalogXML))
+		require.NoError(t, err)
+		assert.Equal(t, FormatXML, format)
+		assert.Equal(t, doc.Catalog.UUID, xmlDoc.Catalog.UUID)
+		assert.Equal(t, controls, CatalogControls(xmlDoc.Catalog))
+	})
+}
+
+func TestSelectControls(t *testing.T) {
+	controls := CatalogControls(readDocument(t, catalogJSON).Catalog)
+	profile := readDocument(t, profileXML).Profile
+	require.Len(t, profile.Imports, 1)
+	imp := profile.Imports[0]
+
+	assert.Equal(t, []string{"ac-2", "ac-2.1", "au-2", "au-3"}, SelectControls(imp, controls))
+	assert.Equal(t, []string{"9c2c4a1d-7a4f-4b0c-8d1e-3f5a6b7c8d9e", "https://example.org/catalogs/example_catalog.json"}, ImportRefs(profile, imp))
+
+	all := Import{Href: "catalog.json", IncludeAll: &struct{}{}, ExcludeControls: []ControlSelection{{Matching: []Matching{{Pattern: "ac-2*"}}}}}
+	assert.Equal(t, []string{"ac-1", "au-2", "au-3"}, SelectControls(all, controls))
+	assert.Equal(t, []string{"catalog.json"}, ImportRefs(profile, all))
+
+	var out bytes.Buffer
+	require.NoError(t, WriteJSON(&out, &Document{Profile: profile}))
+	require.NoError(t, Validate(out.Bytes()))
+}
+
+// roundTrip checks that a document written in JSON conforms to the schema and
+// reads back from XML as the same JSON
+func roundTrip(t *testing.T, doc *Document) []byte {
+	t.Helper()
+	var original bytes.Buffer
+	require.NoError(t, WriteJSON(&original, doc))
+	require.NoError(t, Validate(original.Bytes()), original.String())
+
+	var asXML bytes.Buffer
+	require.NoError(t, WriteXML(&asXML, doc))
+	fromXML, format, err := Read(&asXML)
+	require.NoError(t, err)
+	require.Equal(t, FormatXML, format)
+
+	var again bytes.Buffer
+	require.NoError(t, WriteJSON(&again, fromXML))
+	assert.JSONEq(t, original.String(), again.String())
+	require.NoError(t, Validate(again.Bytes()))
+
+	fromJSON, format, err := Read(bytes.NewReader(original.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, FormatJSON, format)
+	assert.Equal(t, doc.Model(), fromJSON.Model())
+	return original.Bytes()
+}
+
+func TestSSPRoundTrip(t *testing.T) {
+	controls := CatalogControls(readDocument(t, catalogJSON).Catalog)
+	for i := range controls {
+		controls[i].ID = uuid.New()
+	}
+	evidenceID := uuid.New()
+	in := SSPInput{
+		SystemID:   uuid.New(),
+		SystemName: "Payments",
+		Profile: domain.ControlCatalog{
+			OSCALUUID: "2b1b7a3c-1f4e-4d6e-9a53-6f1d1b6e8c11", Title: "Example Baseline", Version: "1.0", FileName: "baseline profile.xml",
+		},
+		Controls: controls,
+		Implementations: map[uuid.UUID]domain.ControlImplementation{
+			controls[1].ID: {Status: domain.ControlPartial, Description: "Accounts are reviewed quarterly.\n\nAutomation is planned.", ResponsibleRole: "System Owner"},
+			controls[4].ID: {Status: domain.ControlImplemented, ResponsibleRole: "SOC"},
+		},
+		Evidence: map[uuid.UUID][]domain.RiskComplianceEvidence{
+			controls[1].ID: {{ID: evidenceID, EvidenceTitle: "Account review Q1", EvidenceType: "REPORT", IsVerified: true,
+				EvidenceDate: time.Date(2024, 4, 2, 9, 0, 0, 0, time.UTC), FilePath: "evidence/review q1.pdf", ComplianceFramework: "NIST 800-53"}},
+			controls[2].ID: {{ID: evidenceID, EvidenceTitle: "Account review Q1"}},
+		},
+		GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	doc := BuildSSP(in)
+	roundTrip(t, doc)
+
+	ssp := doc.SSP
+	assert.Equal(t, "#2b1b7a3c-1f4e-4d6e-9a53-6f1d1b6e8c11", ssp.ImportProfile.Href)
+	require.Len(t, ssp.ControlImplementation.ImplementedRequirements, 6)
+	ac2 := ssp.ControlImplementation.ImplementedRequirements[1]
+	assert.Equal(t, "ac-2", ac2.ControlID)
+	assert.Equal(t, []ResponsibleRole{{RoleID: "system-owner"}}, ac2.ResponsibleRoles)
+	require.NotNil(t, ac2.ByComponents[0].ImplementationStatus)
+	assert.Equal(t, "partial", ac2.ByComponents[0].ImplementationStatus.State)
+	assert.Equal(t, "#"+evidenceID.String(), ac2.ByComponents[0].Links[0].Href)
+	assert.Nil(t, ssp.ControlImplementation.ImplementedRequirements[0].ByComponents[0].ImplementationStatus, "not assessed")
+	assert.Equal(t, []Role{{ID: "soc", Title: "SOC"}, {ID: "system-owner", Title: "System Owner"}}, ssp.Metadata.Roles)
+	require.Len(t, ssp.BackMatter.Resources, 2, "the profile, and the evidence once")
+	assert.Equal(t, "evidence/review%20q1.pdf", ssp.BackMatter.Resources[1].RLinks[0].Href)
+
+	again := BuildSSP(in)
+	assert.Equal(t, ac2.UUID, again.SSP.ControlImplementation.ImplementedRequirements[1].UUID, "stable requirement UUIDs")
+}
+
+func TestPOAMRoundTrip(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 9, 30, 0, 0, 0, 0, time.UTC)
+	phishing := domain.Risk{
+		ID: uuid.New(), Title: "Phishing", Description: "Credential theft <by> email", Impact: 4, Probability: 3, Score: 12,
+		Status: domain.StatusActive, Owner: "ciso@example.com",
+		Mitigations: []domain.Mitigation{
+			{ID: uuid.New(), Title: "Awareness training", Status: domain.MitigationDone},
+			{ID: uuid.New(), Title: "Hardware keys", Status: domain.MitigationPlanned},
+		},
+	}
+	legacy := domain.Risk{ID: uuid.New(), Title: "Legacy ERP", Impact: 3, Probability: 2, Score: 6, Status: domain.StatusAccepted}
+	plans := []domain.RiskTreatmentPlan{
+		{ID: uuid.New(), TreatmentType: "MITIGATE", TreatmentName: "Roll out MFA", Description: "MFA for all staff",
+			ImplementationStart: start, ImplementationEnd: end, Status: "IN_PROGRESS", ApprovalStatus: "APPROVED",
+			Actions: []domain.RiskTreatmentAction{
+				{ID: uuid.New(), ActionName: "Buy keys", Status: "COMPLETED", Priority: "HIGH", DueDate: start.AddDate(0, 1, 0)},
+				{ID: uuid.New(), ActionName: "Enroll staff", Status: "IN_PROGRESS", StartDate: start.AddDate(0, 1, 0), DueDate: end},
+			}},
+		{ID: uuid.New(), TreatmentType: "MITIGATE", TreatmentName: "Mail filtering", Status: "PLANNED", ApprovalStatus: "PENDING",
+			ImplementationEnd: end.AddDate(0, 3, 0)},
+	}
+
+	doc := BuildPOAM(POAMInput{
+		SystemID:   uuid.New(),
+		SystemName: "Payments",
+		Risks: []POAMRisk{
+			{Risk: phishing, Plans: plans},
+			{Risk: legacy, Deviation: true},
+		},
+		GeneratedAt: start,
+	})
+	roundTrip(t, doc)
+
+	poam := doc.POAM
+	require.Len(t, poam.Risks, 2)
+	require.Len(t, poam.POAMItems, 2)
+	risk := poam.Risks[0]
+	assert.Equal(t, phishing.ID.String(), risk.UUID)
+	assert.Equal(t, "remediating", risk.Status)
+	assert.Equal(t, "2024-09-30T00:00:00Z", risk.Deadline, "earliest end of an unfinished plan")
+	require.Len(t, risk.MitigatingFactors, 1)
+	assert.Equal(t, Markup("Awareness training"), risk.MitigatingFactors[0].Description)
+	require.Len(t, risk.Remediations, 2)
+	assert.Equal(t, "planned", risk.Remediations[0].Lifecycle)
+	assert.Equal(t, "recommendation", risk.Remediations[1].Lifecycle)
+	require.Len(t, risk.Remediations[0].Tasks, 3, "the plan milestone and its actions")
+	assert.Equal(t, "milestone", risk.Remediations[0].Tasks[0].Type)
+	assert.NotNil(t, risk.Remediations[0].Tasks[0].Timing.WithinDateRange)
+	assert.NotNil(t, risk.Remediations[0].Tasks[1].Timing.OnDate, "due date without start")
+	assert.Equal(t, "deviation-approved", poam.Risks[1].Status)
+	assert.Equal(t, []RelatedRisk{{RiskUUID: legacy.ID.String()}}, poam.POAMItems[1].RelatedRisks)
+
+	t.Run("no open risks", func(t *testing.T) {
+		empty := BuildPOAM(POAMInput{SystemID: uuid.New(), SystemName: "Payments", GeneratedAt: start})
+		var out bytes.Buffer
+		require.NoError(t, WriteJSON(&out, empty))
+		assert.ErrorIs(t, Validate(out.Bytes()), ErrSchema, "a plan needs an item")
+	})
+}
+
+func TestValidateRejects(t *testing.T) {
+	doc := BuildPOAM(POAMInput{SystemID: uuid.New(), SystemName: "Payments", GeneratedAt: time.Now(), Risks: []POAMRisk{
+		{Risk: domain.Risk{ID: uuid.New(), Title: "Phishing", Impact: 2, Probability: 2, Score: 4}},
+	}})
+	doc.POAM.Risks[0].Status = "pending"
+	doc.POAM.Metadata.LastModified = "yesterday"
+	doc.POAM.UUID = "not-a-uuid"
+	var out bytes.Buffer
+	require.NoError(t, WriteJSON(&out, doc))
+
+	err := Validate(out.Bytes())
+	var schemaErr *SchemaError
+	require.True(t, errors.As(err, &schemaErr), "%v", err)
+	assert.ErrorIs(t, err, ErrSchema)
+	joined := strings.Join(schemaErr.Violations, "\n")
+	assert.Contains(t, joined, "/plan-of-action-and-milestones/uuid")
+	assert.Contains(t, joined, "/plan-of-action-and-milestones/metadata/last-modified")
+	assert.Contains(t, joined, "/plan-of-action-and-milestones/risks/0/status")
+
+	assert.Error(t, Validate([]byte(`{"catalog": {"uuid": "74c8ba1e-5cd4-4ad1-bbfd-d888e2f6c724"}}`)), "metadata is required")
+	assert.Error(t, Validate([]byte(`{"assessment-plan": {}}`)), "unknown model")
+
+	_, _, err = Read(strings.NewReader(`<catalog uuid="x"><metadata/></catalog>`))
+	assert.ErrorIs(t, err, ErrNotOSCAL, "XML outside the OSCAL namespace")
+}
@@ -0,0 +1,279 @@
+package oscal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// POAM is a plan of action and milestones
+type POAM struct {
+	XMLName    struct{}    `json:"-" xml:"http://csrc.nist.gov/ns/oscal/1.0 plan-of-action-and-milestones"`
+	UUID       string      `json:"uuid" xml:"uuid,attr"`
+	Metadata   Metadata    `json:"metadata" xml:"metadata"`
+	SystemID   *SystemID   `json:"system-id,omitempty" xml:"system-id"`
+	Risks      []Risk      `json:"risks,omitempty" xml:"risk"`
+	POAMItems  []POAMItem  `json:"poam-items,omitempty" xml:"poam-item"`
+	BackMatter *BackMatter `json:"back-matter,omitempty" xml:"back-matter"`
+}
+
+// Risk is an identified risk
+type Risk struct {
+	UUID              string             `json:"uuid" xml:"uuid,attr"`
+	Title             string             `json:"title" xml:"title"`
+	Description       Markup             `json:"description" xml:"description"`
+	Statement         Markup             `json:"statement" xml:"statement"`
+	Props             []Property         `json:"props,omitempty" xml:"prop"`
+	Status            string             `json:"status" xml:"status"` // open, investigating, remediating, deviation-requested, deviation-approved, closed
+	Characterizations []Characterization `json:"characterizations,omitempty" xml:"characterization"`
+	MitigatingFactors []MitigatingFactor `json:"mitigating-factors,omitempty" xml:"mitigating-factor"`
+	Deadline          string             `json:"deadline,omitempty" xml:"deadline,omitempty"`
+	Remediations      []Response         `json:"remediations,omitempty" xml:"response"`
+}
+
+// Characterization rates a risk, as told by its origin
+type Characterization struct {
+	Origin Origin  `json:"origin" xml:"origin"`
+	Facets []Facet `json:"facets" xml:"facet"`
+}
+
+// Origin tells who made an observation or rating
+type Origin struct {
+	Actors []Actor `json:"actors" xml:"actor"`
+}
+
+// Actor is a party or tool at the origin of a rating
+type Actor struct {
+	Type      string `json:"type" xml:"type,attr"` // tool, assessment-platform, party
+	ActorUUID string `json:"actor-uuid" xml:"actor-uuid,attr"`
+}
+
+// Facet is a rating of a risk in a rating system
+type Facet struct {
+	Name   string `json:"name" xml:"name,attr"`
+	System string `json:"system" xml:"system,attr"`
+	Value  string `json:"value" xml:"value,attr"`
+}
+
+// MitigatingFactor is a measure reducing a risk
+type MitigatingFactor struct {
+	UUID        string     `json:"uuid" xml:"uuid,attr"`
+	Description Markup     `json:"description" xml:"description"`
+	Props       []Property `json:"props,omitempty" xml:"prop"`
+}
+
+// Response is a remediation of a risk with its tasks
+type Response struct {
+	UUID        string     `json:"uuid" xml:"uuid,attr"`
+	Lifecycle   string     `json:"lifecycle" xml:"lifecycle,attr"` // recommendation, planned, completed
+	Title       string     `json:"title" xml:"title"`
+	Description Markup     `json:"description" xml:"description"`
+	Props       []Property `json:"props,omitempty" xml:"prop"`
+	Tasks       []Task     `json:"tasks,omitempty" xml:"task"`
+}
+
+// Task is an action or milestone of a response
+type Task struct {
+	UUID        string     `json:"uuid" xml:"uuid,attr"`
+	Type        string     `json:"type" xml:"type,attr"` // action, milestone
+	Title       string     `json:"title" xml:"title"`
+	Description Markup     `json:"description,omitempty" xml:"description,omitempty"`
+	Props       []Property `json:"props,omitempty" xml:"prop"`
+	Timing      *Timing    `json:"timing,omitempty" xml:"timing"`
+}
+
+// Timing is when a task is due
+type Timing struct {
+	OnDate          *OnDate          `json:"on-date,omitempty" xml:"on-date"`
+	WithinDateRange *WithinDateRange `json:"within-date-range,omitempty" xml:"within-date-range"`
+}
+
+// OnDate is a date a task is due
+type OnDate struct {
+	Date string `json:"date" xml:"date,attr"`
+}
+
+// WithinDateRange is the period a task is carried out in
+type WithinDateRange struct {
+	Start string `json:"start" xml:"start,attr"`
+	End   string `json:"end" xml:"end,attr"`
+}
+
+// POAMItem is an entry of the plan, for a risk
+type POAMItem struct {
+	UUID         string        `json:"uuid" xml:"uuid,attr"`
+	Title        string        `json:"title" xml:"title"`
+	Description  Markup        `json:"description" xml:"description"`
+	Props        []Property    `json:"props,omitempty" xml:"prop"`
+	RelatedRisks []RelatedRisk `json:"related-risks,omitempty" xml:"related-risk"`
+}
+
+// RelatedRisk points to a risk of the plan
+type RelatedRisk struct {
+	RiskUUID string `json:"risk-uuid" xml:"risk-uuid,attr"`
+}
+
+// POAMRisk is an open risk with its treatment plans and actions; Deviation
+// is set when an exception accepting it is active
+type POAMRisk struct {
+	Risk      domain.Risk
+	Plans     []domain.RiskTreatmentPlan
+	Deviation bool
+}
+
+// POAMInput is what a plan of action and milestones is built from
+type POAMInput struct {
+	SystemID    uuid.UUID
+	SystemName  string
+	Risks       []POAMRisk
+	GeneratedAt time.Time
+}
+
+// BuildPOAM builds a plan of action and milestones: an item per open risk,
+// rated by OpenRisk, with its done mitigations as mitigating factors and its
+// treatment plans as remediations whose tasks are the plan actions. OSCAL
+// requires an item at least, so callers need open risks.
+func BuildPOAM(in POAMInput) *Document {
+	generated := in.GeneratedAt.UTC()
+	party := uuid.NewSHA1(namespace, []byte("party|openrisk")).String()
+	metadata := newMetadata(fmt.Sprintf("%s Plan of Action and Milestones", in.SystemName), generated)
+	metadata.Parties = []Party{{UUID: party, Type: "organization", Name: "OpenRisk"}}
+
+	poam := &POAM{
+		UUID:     uuid.NewString(),
+		Metadata: metadata,
+		SystemID: &SystemID{IdentifierType: "https://ietf.org/rfc/rfc4122", ID: uuid.NewSHA1(namespace, []byte("system|"+in.SystemID.String())).String()},
+	}
+	for _, entry := range in.Risks {
+		r := &entry.Risk
+		risk := Risk{
+			UUID:        r.ID.String(),
+			Title:       r.Title,
+			Description: Markup(firstNonEmpty(strings.TrimSpace(r.Description), r.Title)),
+			Statement:   Markup(fmt.Sprintf("Impact %d and probability %d give a score of %.2f.", r.Impact, r.Probability, r.Score)),
+			Props:       []Property{{Name: "openrisk-status", NS: PropNamespace, Value: string(r.Status)}},
+			Status:      poamStatus(entry),
+			Characterizations: []Characterization{{
+				Origin: Origin{Actors: []Actor{{Type: "party", ActorUUID: party}}},
+				Facets: []Facet{
+					{Name: "impact", System: PropNamespace, Value: fmt.Sprint(r.Impact)},
+					{Name: "likelihood", System: PropNamespace, Value: fmt.Sprint(r.Probability)},
+					{Name: "score", System: PropNamespace, Value: fmt.Sprintf("%.2f", r.Score)},
+				},
+			}},
+		}
+		if r.Owner != "" {
+			risk.Props = append(risk.Props, Property{Name: "owner", NS: PropNamespace, Value: r.Owner})
+		}
+		for _, m := range r.Mitigations {
+			if m.Status != domain.MitigationDone {
+				continue
+			}
+			risk.MitigatingFactors = append(risk.MitigatingFactors, MitigatingFactor{
+				UUID:        m.ID.String(),
+				Description: Markup(m.Title),
+			})
+		}
+
+		var deadline time.Time
+		for _, plan := range entry.Plans {
+			risk.Remediations = append(risk.Remediations, remediation(plan))
+			if !plan.ImplementationEnd.IsZero() && (deadline.IsZero() || plan.ImplementationEnd.Before(deadline)) && !planDone(plan.Status) {
+				deadline = plan.ImplementationEnd
+			}
+		}
+		if !deadline.IsZero() {
+			risk.Deadline = timestamp(deadline)
+		}
+
+		poam.Risks = append(poam.Risks, risk)
+		poam.POAMItems = append(poam.POAMItems, POAMItem{
+			UUID:         uuid.NewSHA1(namespace, []byte("poam-item|"+r.ID.String())).String(),
+			Title:        r.Title,
+			Description:  risk.Description,
+			RelatedRisks: []RelatedRisk{{RiskUUID: risk.UUID}},
+		})
+	}
+	return &Document{POAM: poam}
+}
+
+// poamStatus maps a risk to the OSCAL risk status: deviation-approved under
+// an active exception, remediating once a plan or action is in progress
+func poamStatus(entry POAMRisk) string {
+	if entry.Deviation {
+		return "deviation-approved"
+	}
+	for _, plan := range entry.Plans {
+		if strings.EqualFold(plan.Status, "IN_PROGRESS") {
+			return "remediating"
+		}
+		for _, action := range plan.Actions {
+			if strings.EqualFold(action.Status, "IN_PROGRESS") {
+				return "remediating"
+			}
+		}
+	}
+	return "open"
+}
+
+func remediation(plan domain.RiskTreatmentPlan) Response {
+	lifecycle := "planned"
+	switch {
+	case planDone(plan.Status):
+		lifecycle = "completed"
+	case strings.EqualFold(plan.ApprovalStatus, "PENDING"):
+		lifecycle = "recommendation"
+	}
+	response := Response{
+		UUID:        plan.ID.String(),
+		Lifecycle:   lifecycle,
+		Title:       plan.TreatmentName,
+		Description: Markup(firstNonEmpty(strings.TrimSpace(plan.Description), plan.TreatmentName)),
+		Props: []Property{
+			{Name: "treatment-type", NS: PropNamespace, Value: firstNonEmpty(plan.TreatmentType, "unknown")},
+			{Name: "status", NS: PropNamespace, Value: firstNonEmpty(plan.Status, "PLANNED")},
+		},
+	}
+	if timing := taskTiming(plan.ImplementationStart, plan.ImplementationEnd); timing != nil {
+		response.Tasks = append(response.Tasks, Task{
+			UUID:   uuid.NewSHA1(namespace, []byte("milestone|"+plan.ID.String())).String(),
+			Type:   "milestone",
+			Title:  "Complete " + plan.TreatmentName,
+			Timing: timing,
+		})
+	}
+	for _, action := range plan.Actions {
+		response.Tasks = append(response.Tasks, Task{
+			UUID:        action.ID.String(),
+			Type:        "action",
+			Title:       action.ActionName,
+			Description: Markup(action.ActionDescription),
+			Props: []Property{
+				{Name: "status", NS: PropNamespace, Value: firstNonEmpty(action.Status, "NOT_STARTED")},
+				{Name: "priority", NS: PropNamespace, Value: firstNonEmpty(action.Priority, "MEDIUM")},
+			},
+			Timing: taskTiming(action.StartDate, action.DueDate),
+		})
+	}
+	return response
+}
+
+// taskTiming is the range from start to end, the end date alone when there
+// is no start, nil without an end
+func taskTiming(start, end time.Time) *Timing {
+	switch {
+	case end.IsZero():
+		return nil
+	case start.IsZero() || start.After(end):
+		return &Timing{OnDate: &OnDate{Date: timestamp(end)}}
+	}
+	return &Timing{WithinDateRange: &WithinDateRange{Start: timestamp(start), End: timestamp(end)}}
+}
+
+func planDone(status string) bool {
+	return strings.EqualFold(status, "COMPLETED") || strings.EqualFold(status, "DONE")
+}
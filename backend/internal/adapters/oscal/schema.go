@@ -0,0 +1,236 @@
+package oscal
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The schema is an excerpt of the OSCAL 1.1.2 complete JSON schema, limited
+// to the assemblies OpenRisk reads and writes, as the released schemas can't
+// be fetched at build time. Its definitions keep the names of the released
+// ones so that they can be swapped for the full schema.
+//
+//go:embed schema/oscal_subset_schema.json
+var schemaJSON []byte
+
+// ErrSchema is returned when a document doesn't conform to the OSCAL schema
+var ErrSchema = errors.New("document does not conform to the OSCAL schema")
+
+// SchemaError lists the violations of the schema by a document, each with
+// the JSON pointer of the offending value
+type SchemaError struct {
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrSchema, strings.Join(e.Violations, "; "))
+}
+
+func (e *SchemaError) Unwrap() error { return ErrSchema }
+
+// schemaNode is the subset of JSON schema (draft-07) the OSCAL schemas use
+// for the assemblies above
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	MinProperties        *int                   `json:"minProperties"`
+	MaxProperties        *int                   `json:"maxProperties"`
+	Items                *schemaNode            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	Enum                 []string               `json:"enum"`
+	Pattern              string                 `json:"pattern"`
+	Format               string                 `json:"format"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+
+	pattern *regexp.Regexp
+}
+
+var (
+	schemaOnce sync.Once
+	schemaRoot *schemaNode
+	schemaErr  error
+)
+
+func loadSchema() (*schemaNode, error) {
+	schemaOnce.Do(func() {
+		var root schemaNode
+		if schemaErr = json.Unmarshal(schemaJSON, &root); schemaErr != nil {
+			return
+		}
+		var compile func(n *schemaNode) error
+		compile = func(n *schemaNode) error {
+			if n == nil {
+				return nil
+			}
+			if n.Pattern != "" {
+				re, err := regexp.Compile(n.Pattern)
+				if err != nil {
+					return fmt.Errorf("pattern %q: %w", n.Pattern, err)
+				}
+				n.pattern = re
+			}
+			for _, child := range n.Properties {
+				if err := compile(child); err != nil {
+					return err
+				}
+			}
+			for _, child := range n.Definitions {
+				if err := compile(child); err != nil {
+					return err
+				}
+			}
+			return compile(n.Items)
+		}
+		if schemaErr = compile(&root); schemaErr == nil {
+			schemaRoot = &root
+		}
+	})
+	return schemaRoot, schemaErr
+}
+
+// Validate checks an OSCAL JSON document against the schema. The violations
+// are reported in a *SchemaError.
+func Validate(data []byte) error {
+	root, err := loadSchema()
+	if err != nil {
+		return fmt.Errorf("load OSCAL schema: %w", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var doc any
+	if err := d.Decode(&doc); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotOSCAL, err)
+	}
+	v := validator{definitions: root.Definitions}
+	v.check(root, doc, "")
+	if len(v.violations) > 0 {
+		return &SchemaError{Violations: v.violations}
+	}
+	return nil
+}
+
+type validator struct {
+	definitions map[string]*schemaNode
+	violations  []string
+}
+
+func (v *validator) fail(at, format string, args ...any) {
+	if at == "" {
+		at = "/"
+	}
+	v.violations = append(v.violations, at+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) check(n *schemaNode, value any, at string) {
+	if n.Ref != "" {
+		name, ok := strings.CutPrefix(n.Ref, "#/definitions/")
+		def := v.definitions[name]
+		if !ok || def == nil {
+			v.fail(at, "unresolved reference %s", n.Ref)
+			return
+		}
+		v.check(def, value, at)
+		return
+	}
+
+	switch n.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			v.fail(at, "expected an object")
+			return
+		}
+		v.checkObject(n, object, at)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			v.fail(at, "expected an array")
+			return
+		}
+		if n.MinItems != nil && len(array) < *n.MinItems {
+			v.fail(at, "expected at least %d items, got %d", *n.MinItems, len(array))
+		}
+		if n.Items != nil {
+			for i, item := range array {
+				v.check(n.Items, item, fmt.Sprintf("%s/%d", at, i))
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.fail(at, "expected a string")
+			return
+		}
+		v.checkString(n, s, at)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.fail(at, "expected a boolean")
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			v.fail(at, "expected a number")
+			return
+		}
+		if _, err := number.Int64(); n.Type == "integer" && err != nil {
+			v.fail(at, "expected an integer")
+		}
+	}
+}
+
+func (v *validator) checkObject(n *schemaNode, object map[string]any, at string) {
+	if n.MinProperties != nil && len(object) < *n.MinProperties {
+		v.fail(at, "expected at least %d properties", *n.MinProperties)
+	}
+	if n.MaxProperties != nil && len(object) > *n.MaxProperties {
+		v.fail(at, "expected at most %d properties", *n.MaxProperties)
+	}
+	for _, name := range n.Required {
+		if _, ok := object[name]; !ok {
+			v.fail(at, "missing required property %q", name)
+		}
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		child := at + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+		if property, ok := n.Properties[name]; ok {
+			v.check(property, object[name], child)
+		} else if n.AdditionalProperties != nil && !*n.AdditionalProperties {
+			v.fail(child, "unexpected property")
+		}
+	}
+}
+
+func (v *validator) checkString(n *schemaNode, s, at string) {
+	if len(n.Enum) > 0 && !slices.Contains(n.Enum, s) {
+		v.fail(at, "%q is not one of %s", s, strings.Join(n.Enum, ", "))
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		v.fail(at, "%q does not match %s", s, n.Pattern)
+	}
+	switch n.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			v.fail(at, "%q is not a date and time", s)
+		}
+	case "uri-reference":
+		if strings.ContainsAny(s, " \t\n") {
+			v.fail(at, "%q is not a URI reference", s)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package oscal
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// SSP is a system security plan
+type SSP struct {
+	XMLName               struct{}              `json:"-" xml:"http://csrc.nist.gov/ns/oscal/1.0 system-security-plan"`
+	UUID                  string                `json:"uuid" xml:"uuid,attr"`
+	Metadata              Metadata              `json:"metadata" xml:"metadata"`
+	ImportProfile         ImportProfile         `json:"import-profile" xml:"import-profile"`
+	SystemCharacteristics SystemCharacteristics `json:"system-characteristics" xml:"system-characteristics"`
+	SystemImplementation  SystemImplementation  `json:"system-implementation" xml:"system-implementation"`
+	ControlImplementation ControlImplementation `json:"control-implementation" xml:"control-implementation"`
+	BackMatter            *BackMatter           `json:"back-matter,omitempty" xml:"back-matter"`
+}
+
+// ImportProfile points to the profile a plan implements
+type ImportProfile struct {
+	Href string `json:"href" xml:"href,attr"`
+}
+
+// SystemCharacteristics describes the system of a plan
+type SystemCharacteristics struct {
+	SystemIDs             []SystemID        `json:"system-ids" xml:"system-id"`
+	SystemName            string            `json:"system-name" xml:"system-name"`
+	Description           Markup            `json:"description" xml:"description"`
+	Props                 []Property        `json:"props,omitempty" xml:"prop"`
+	SystemInformation     SystemInformation `json:"system-information" xml:"system-information"`
+	Status                Status            `json:"status" xml:"status"`
+	AuthorizationBoundary Boundary          `json:"authorization-boundary" xml:"authorization-boundary"`
+}
+
+// SystemID identifies a system
+type SystemID struct {
+	IdentifierType string `json:"identifier-type,omitempty" xml:"identifier-type,attr,omitempty"`
+	ID             string `json:"id" xml:",chardata"`
+}
+
+// SystemInformation lists the information types a system processes
+type SystemInformation struct {
+	InformationTypes []InformationType `json:"information-types" xml:"information-type"`
+}
+
+// InformationType is a type of information a system processes
+type InformationType struct {
+	UUID        string `json:"uuid,omitempty" xml:"uuid,attr,omitempty"`
+	Title       string `json:"title" xml:"title"`
+	Description Markup `json:"description" xml:"description"`
+}
+
+// Status is the operational state of a system or component
+type Status struct {
+	State   string `json:"state" xml:"state,attr"`
+	Remarks Markup `json:"remarks,omitempty" xml:"remarks,omitempty"`
+}
+
+// Boundary describes the authorization boundary of a system
+type Boundary struct {
+	Description Markup `json:"description" xml:"description"`
+}
+
+// SystemImplementation lists the users and components of a system
+type SystemImplementation struct {
+	Users      []User      `json:"users" xml:"user"`
+	Components []Component `json:"components" xml:"component"`
+}
+
+// User is a type of user of a system
+type User struct {
+	UUID    string   `json:"uuid" xml:"uuid,attr"`
+	Title   string   `json:"title,omitempty" xml:"title,omitempty"`
+	RoleIDs []string `json:"role-ids,omitempty" xml:"role-id"`
+}
+
+// Component is a component of a system
+type Component struct {
+	UUID        string `json:"uuid" xml:"uuid,attr"`
+	Type        string `json:"type" xml:"type,attr"`
+	Title       string `json:"title" xml:"title"`
+	Description Markup `json:"description" xml:"description"`
+	Status      Status `json:"status" xml:"status"`
+}
+
+// ControlImplementation describes how the controls of a plan are implemented
+type ControlImplementation struct {
+	Description             Markup                   `json:"description" xml:"description"`
+	ImplementedRequirements []ImplementedRequirement `json:"implemented-requirements" xml:"implemented-requirement"`
+}
+
+// ImplementedRequirement describes the implementation of a control
+type ImplementedRequirement struct {
+	UUID             string            `json:"uuid" xml:"uuid,attr"`
+	ControlID        string            `json:"control-id" xml:"control-id,attr"`
+	Props            []Property        `json:"props,omitempty" xml:"prop"`
+	ResponsibleRoles []ResponsibleRole `json:"responsible-roles,omitempty" xml:"responsible-role"`
+	ByComponents     []ByComponent     `json:"by-components,omitempty" xml:"by-component"`
+}
+
+// ResponsibleRole assigns a role
+type ResponsibleRole struct {
+	RoleID string `json:"role-id" xml:"role-id,attr"`
+}
+
+// ByComponent describes how a component implements a control
+type ByComponent struct {
+	ComponentUUID        string                `json:"component-uuid" xml:"component-uuid,attr"`
+	UUID                 string                `json:"uuid" xml:"uuid,attr"`
+	Description          Markup                `json:"description" xml:"description"`
+	Links                []Link                `json:"links,omitempty" xml:"link"`
+	ImplementationStatus *ImplementationStatus `json:"implementation-status,omitempty" xml:"implementation-status"`
+}
+
+// ImplementationStatus is the implementation state of a control
+type ImplementationStatus struct {
+	State   string `json:"state" xml:"state,attr"`
+	Remarks Markup `json:"remarks,omitempty" xml:"remarks,omitempty"`
+}
+
+// SSPInput is what a system security plan fragment is built from: the
+// controls of a profile or catalog with how the tenant implements them and
+// the compliance evidence referencing them
+type SSPInput struct {
+	SystemID        uuid.UUID
+	SystemName      string
+	Profile         domain.ControlCatalog
+	Controls        []domain.Control
+	Implementations map[uuid.UUID]domain.ControlImplementation
+	Evidence        map[uuid.UUID][]domain.RiskComplianceEvidence
+	GeneratedAt     time.Time
+}
+
+// BuildSSP builds a system security plan fragment: the implementation of
+// each control, by the system as a whole, with its evidence as back matter
+// resources. The system characteristics are left to the full plan.
+func BuildSSP(in SSPInput) *Document {
+	generated := in.GeneratedAt.UTC()
+	system := uuid.NewSHA1(namespace, []byte("system|"+in.SystemID.String())).String()
+	roles := map[string]Role{}
+	resources := []Resource{profileResource(in.Profile)}
+	seen := map[uuid.UUID]bool{}
+
+	var requirements []ImplementedRequirement
+	for _, control := range in.Controls {
+		requirement := ImplementedRequirement{
+			UUID:      uuid.NewSHA1(namespace, []byte("requirement|"+in.SystemID.String()+"|"+control.ID.String())).String(),
+			ControlID: control.ControlID,
+		}
+		byComponent := ByComponent{
+			ComponentUUID: system,
+			UUID:          uuid.NewSHA1(namespace, []byte("by-component|"+in.SystemID.String()+"|"+control.ID.String())).String(),
+			Description:   "No implementation statement recorded in OpenRisk.",
+		}
+		if impl, ok := in.Implementations[control.ID]; ok {
+			byComponent.ImplementationStatus = &ImplementationStatus{State: impl.Status}
+			if strings.TrimSpace(impl.Description) != "" {
+				byComponent.Description = Markup(impl.Description)
+			}
+			if role := token(impl.ResponsibleRole); role != "" {
+				roles[role] = Role{ID: role, Title: impl.ResponsibleRole}
+				requirement.ResponsibleRoles = []ResponsibleRole{{RoleID: role}}
+			}
+		}
+		for _, evidence := range in.Evidence[control.ID] {
+			if !seen[evidence.ID] {
+				seen[evidence.ID] = true
+				resources = append(resources, evidenceResource(evidence))
+			}
+			byComponent.Links = append(byComponent.Links, Link{Href: "#" + evidence.ID.String(), Rel: "evidence", Text: evidence.EvidenceTitle})
+		}
+		requirement.ByComponents = []ByComponent{byComponent}
+		requirements = append(requirements, requirement)
+	}
+
+	metadata := newMetadata(fmt.Sprintf("%s System Security Plan: control implementation", in.SystemName), generated)
+	for _, id := range slices.Sorted(maps.Keys(roles)) {
+		metadata.Roles = append(metadata.Roles, roles[id])
+	}
+
+	return &Document{SSP: &SSP{
+		UUID:          uuid.NewString(),
+		Metadata:      metadata,
+		ImportProfile: ImportProfile{Href: "#" + resources[0].UUID},
+		SystemCharacteristics: SystemCharacteristics{
+			SystemIDs:   []SystemID{{IdentifierType: "https://ietf.org/rfc/rfc4122", ID: system}},
+			SystemName:  in.SystemName,
+			Description: Markup(fmt.Sprintf("Control implementation of %s as recorded in OpenRisk. This fragment is meant to be merged into the full system security plan.", in.SystemName)),
+			SystemInformation: SystemInformation{InformationTypes: []InformationType{{
+				UUID:        uuid.NewSHA1(namespace, []byte("information-type|"+in.SystemID.String())).String(),
+				Title:       "Risk and compliance records",
+				Description: "Risks, treatment plans and compliance evidence of the system.",
+			}}},
+			Status:                Status{State: "operational"},
+			AuthorizationBoundary: Boundary{Description: "Described in the full system security plan."},
+		},
+		SystemImplementation: SystemImplementation{
+			Users: []User{{UUID: uuid.NewSHA1(namespace, []byte("user|"+in.SystemID.String())).String(), Title: "OpenRisk users"}},
+			Components: []Component{{
+				UUID:        system,
+				Type:        "this-system",
+				Title:       in.SystemName,
+				Description: "The system as a whole.",
+				Status:      Status{State: "operational"},
+			}},
+		},
+		ControlImplementation: ControlImplementation{
+			Description:             Markup(fmt.Sprintf("Implementation of the controls of %s.", in.Profile.Title)),
+			ImplementedRequirements: requirements,
+		},
+		BackMatter: &BackMatter{Resources: resources},
+	}}
+}
+
+func profileResource(profile domain.ControlCatalog) Resource {
+	resource := Resource{
+		UUID:  profile.OSCALUUID,
+		Title: profile.Title,
+		Props: []Property{{Name: "version", NS: PropNamespace, Value: firstNonEmpty(profile.Version, "unknown")}},
+	}
+	if profile.FileName != "" {
+		resource.RLinks = []RLink{{Href: (&url.URL{Path: profile.FileName}).String()}}
+	}
+	return resource
+}
+
+func evidenceResource(evidence domain.RiskComplianceEvidence) Resource {
+	resource := Resource{
+		UUID:        evidence.ID.String(),
+		Title:       evidence.EvidenceTitle,
+		Description: Markup(evidence.EvidenceDescription),
+		Props: []Property{
+			{Name: "evidence-type", NS: PropNamespace, Value: firstNonEmpty(evidence.EvidenceType, "unknown")},
+			{Name: "status", NS: PropNamespace, Value: firstNonEmpty(evidence.Status, "PENDING")},
+			{Name: "verified", NS: PropNamespace, Value: fmt.Sprint(evidence.IsVerified)},
+		},
+	}
+	if evidence.ComplianceFramework != "" {
+		resource.Props = append(resource.Props, Property{Name: "framework", NS: PropNamespace, Value: evidence.ComplianceFramework})
+	}
+	if !evidence.EvidenceDate.IsZero() {
+		resource.Props = append(resource.Props, Property{Name: "collected", NS: PropNamespace, Value: timestamp(evidence.EvidenceDate)})
+	}
+	if evidence.FilePath != "" {
+		resource.RLinks = []RLink{{Href: (&url.URL{Path: evidence.FilePath}).String()}}
+	}
+	return resource
+}
+
+func newMetadata(title string, generated time.Time) Metadata {
+	return Metadata{
+		Title:        title,
+		LastModified: timestamp(generated),
+		Version:      generated.Format("2006.01.02-150405"),
+		OSCALVersion: Version,
+	}
+}
+
+// timestamp writes an OSCAL date and time, in UTC
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+var nonToken = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// token turns a name into an OSCAL token (a role ID)
+func token(name string) string {
+	t := strings.Trim(nonToken.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-"), "-.")
+	if t != "" && (t[0] >= '0' && t[0] <= '9') {
+		t = "role-" + t
+	}
+	return t
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
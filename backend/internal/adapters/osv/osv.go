@@ -0,0 +1,276 @@
+// Package osv loads vulnerability advisories from OSV dumps: the all.zip
+// archives of https://osv-vulnerabilities.storage.googleapis.com, single
+// advisory files, JSON arrays of advisories, or a directory of those.
+package osv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// Source of the vulnerabilities loaded from OSV
+const Source = "OSV"
+
+const (
+	maxDumpSize     = 1 << 30
+	maxAdvisorySize = 16 << 20
+)
+
+type advisory struct {
+	ID        string     `json:"id"`
+	Aliases   []string   `json:"aliases"`
+	Summary   string     `json:"summary"`
+	Details   string     `json:"details"`
+	Published *time.Time `json:"published"`
+	Modified  *time.Time `json:"modified"`
+	Withdrawn *time.Time `json:"withdrawn"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions          []string `json:"versions"`
+		EcosystemSpecific struct {
+			Severity string `json:"severity"`
+		} `json:"ecosystem_specific"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// LoadPath loads the advisories of a dump file or of every .json and .zip
+// file of a directory
+func LoadPath(path string) ([]domain.Vulnerability, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OSV dump: %w", err)
+	}
+	if !info.IsDir() {
+		return loadFile(path)
+	}
+	var vulns []domain.Vulnerability
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if d.IsDir() || (ext != ".json" && ext != ".zip") {
+			return nil
+		}
+		loaded, err := loadFile(p)
+		if err != nil {
+			return err
+		}
+		vulns = append(vulns, loaded...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vulns, nil
+}
+
+func loadFile(path string) ([]domain.Vulnerability, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OSV dump: %w", err)
+	}
+	defer f.Close()
+	vulns, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return vulns, nil
+}
+
+// Read loads the advisories of a zip archive, an advisory, or a JSON array
+// of advisories. Withdrawn advisories are kept without their packages, so
+// that they no longer match.
+func Read(r io.Reader) ([]domain.Vulnerability, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxDumpSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OSV dump: %w", err)
+	}
+	if len(data) > maxDumpSize {
+		return nil, fmt.Errorf("OSV dump exceeds %d MB", maxDumpSize>>20)
+	}
+
+	var advisories []advisory
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		advisories, err = readZip(data)
+	} else {
+		advisories, err = readJSON(bytes.TrimSpace(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	vulns := make([]domain.Vulnerability, 0, len(advisories))
+	for _, a := range advisories {
+		if a.ID == "" {
+			continue
+		}
+		vulns = append(vulns, a.vulnerability())
+	}
+	return vulns, nil
+}
+
+func readJSON(data []byte) ([]advisory, error) {
+	if len(data) > 0 && data[0] == '[' {
+		var advisories []advisory
+		if err := json.Unmarshal(data, &advisories); err != nil {
+			return nil, fmt.Errorf("invalid OSV JSON: %w", err)
+		}
+		return advisories, nil
+	}
+	var a advisory
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("invalid OSV JSON: %w", err)
+	}
+	return []advisory{a}, nil
+}
+
+func readZip(data []byte) ([]advisory, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSV archive: %w", err)
+	}
+	advisories := make([]advisory, 0, len(archive.File))
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(file.Name), ".json") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid OSV archive entry %s: %w", file.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxAdvisorySize))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid OSV archive entry %s: %w", file.Name, err)
+		}
+		parsed, err := readJSON(bytes.TrimSpace(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		advisories = append(advisories, parsed...)
+	}
+	return advisories, nil
+}
+
+func (a advisory) vulnerability() domain.Vulnerability {
+	v := domain.Vulnerability{
+		ID:        a.ID,
+		Aliases:   a.Aliases,
+		Summary:   a.Summary,
+		Details:   a.Details,
+		Source:    Source,
+		Published: a.Published,
+		Modified:  a.Modified,
+		Withdrawn: a.Withdrawn,
+	}
+	if v.Summary == "" {
+		v.Summary = firstLine(a.Details)
+	}
+	for _, s := range a.Severity {
+		if strings.HasPrefix(s.Type, "CVSS_V3") || (s.Type == "CVSS_V4" && v.CVSSVector == "") {
+			v.CVSSVector = s.Score
+		}
+	}
+	v.Severity = normaliseSeverity(a.DatabaseSpecific.Severity)
+	if a.Withdrawn != nil {
+		return v
+	}
+
+	for _, affected := range a.Affected {
+		if v.Severity == "" {
+			v.Severity = normaliseSeverity(affected.EcosystemSpecific.Severity)
+		}
+		if affected.Package.Name == "" {
+			continue
+		}
+		var ranges []domain.VersionRange
+		for _, r := range affected.Ranges {
+			// Git ranges are commits, not versions
+			if strings.EqualFold(r.Type, "GIT") {
+				continue
+			}
+			var current *domain.VersionRange
+			for _, e := range r.Events {
+				switch {
+				case e.Introduced != "":
+					ranges = append(ranges, domain.VersionRange{Introduced: e.Introduced})
+					current = &ranges[len(ranges)-1]
+				case current != nil && e.Fixed != "":
+					current.Fixed = e.Fixed
+					current = nil
+				case current != nil && e.LastAffected != "":
+					current.LastAffected = e.LastAffected
+					current = nil
+				}
+			}
+		}
+		if len(ranges) == 0 && len(affected.Versions) == 0 {
+			continue
+		}
+		pkg := domain.VulnerablePackage{
+			VulnerabilityID: a.ID,
+			Ecosystem:       affected.Package.Ecosystem,
+			Name:            affected.Package.Name,
+			Versions:        affected.Versions,
+		}
+		if len(ranges) > 0 {
+			pkg.Ranges, _ = json.Marshal(ranges)
+		}
+		v.Packages = append(v.Packages, pkg)
+	}
+	return v
+}
+
+// normaliseSeverity maps feed severities (GitHub's MODERATE...) to LOW,
+// MEDIUM, HIGH or CRITICAL
+func normaliseSeverity(severity string) string {
+	switch strings.ToUpper(strings.TrimSpace(severity)) {
+	case "CRITICAL":
+		return "CRITICAL"
+	case "HIGH", "IMPORTANT":
+		return "HIGH"
+	case "MODERATE", "MEDIUM":
+		return "MEDIUM"
+	case "LOW", "NEGLIGIBLE", "UNIMPORTANT":
+		return "LOW"
+	}
+	return ""
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > 255 {
+		s = s[:255]
+	}
+	return s
+}
@@ -0,0 +1,94 @@
+package osv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const log4shell = `{
+  "id": "GHSA-jfh8-c2jp-5v3q",
+  "aliases": ["CVE-2021-44228"],
+  "summary": "Remote code injection in Log4j",
+  "modified": "2024-01-01T00:00:00Z",
+  "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H"}],
+  "affected": [{
+    "package": {"ecosystem": "Maven", "name": "org.apache.logging.log4j:log4j-core"},
+    "ranges": [
+      {"type": "ECOSYSTEM", "events": [{"introduced": "2.0-beta9"}, {"fixed": "2.3.1"}, {"introduced": "2.4"}, {"fixed": "2.12.2"}, {"introduced": "2.13.0"}, {"fixed": "2.15.0"}]},
+      {"type": "GIT", "repo": "https://github.com/apache/logging-log4j2", "events": [{"introduced": "0"}, {"fixed": "abc"}]}
+    ]
+  }],
+  "database_specific": {"severity": "CRITICAL"}
+}`
+
+func TestReadAdvisory(t *testing.T) {
+	vulns, err := Read(strings.NewReader(log4shell))
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+
+	v := vulns[0]
+	assert.Equal(t, "GHSA-jfh8-c2jp-5v3q", v.ID)
+	assert.Equal(t, "CVE-2021-44228", v.CVE())
+	assert.Equal(t, "CRITICAL", v.Severity)
+	assert.Equal(t, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", v.CVSSVector)
+	assert.Equal(t, Source, v.Source)
+	require.Len(t, v.Packages, 1)
+
+	var ranges []domain.VersionRange
+	require.NoError(t, json.Unmarshal(v.Packages[0].Ranges, &ranges))
+	assert.Equal(t, []domain.VersionRange{
+		{Introduced: "2.0-beta9", Fixed: "2.3.1"},
+		{Introduced: "2.4", Fixed: "2.12.2"},
+		{Introduced: "2.13.0", Fixed: "2.15.0"},
+	}, ranges, "git ranges are dropped")
+}
+
+func TestReadZipAndWithdrawn(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"GHSA-jfh8-c2jp-5v3q.json": log4shell,
+		"PYSEC-2020-1.json":        `{"id": "PYSEC-2020-1", "details": "Withdrawn advisory\nmore", "withdrawn": "2021-01-01T00:00:00Z", "affected": [{"package": {"ecosystem": "PyPI", "name": "foo"}, "versions": ["1.0"]}]}`,
+		"README.txt":               "not an advisory",
+	} {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+
+	vulns, err := Read(&buf)
+	require.NoError(t, err)
+	require.Len(t, vulns, 2)
+	for _, v := range vulns {
+		if v.ID == "PYSEC-2020-1" {
+			assert.NotNil(t, v.Withdrawn)
+			assert.Empty(t, v.Packages, "withdrawn advisories no longer match")
+			assert.Equal(t, "Withdrawn advisory", v.Summary)
+		}
+	}
+}
+
+func TestLoadPathDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "log4shell.json"), []byte(log4shell), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "more.json"), []byte(`[{"id": "OSV-1", "affected": [{"package": {"ecosystem": "npm", "name": "lodash"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"last_affected": "4.17.20"}]}]}]}]`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.md"), []byte("ignored"), 0o600))
+
+	vulns, err := LoadPath(dir)
+	require.NoError(t, err)
+	assert.Len(t, vulns, 2)
+
+	_, err = LoadPath(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
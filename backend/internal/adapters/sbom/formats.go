@@ -0,0 +1,303 @@
+package sbom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// cdxComponent is a component of a CycloneDX BOM, JSON or XML
+type cdxComponent struct {
+	Type       string         `json:"type" xml:"type,attr"`
+	Group      string         `json:"group" xml:"group"`
+	Name       string         `json:"name" xml:"name"`
+	Version    string         `json:"version" xml:"version"`
+	PURL       string         `json:"purl" xml:"purl"`
+	Publisher  string         `json:"publisher" xml:"publisher"`
+	Supplier   cdxSupplier    `json:"supplier" xml:"supplier"`
+	Licenses   cdxLicenses    `json:"licenses" xml:"licenses"`
+	Components []cdxComponent `json:"components" xml:"components>component"`
+}
+
+type cdxSupplier struct {
+	Name string `json:"name" xml:"name"`
+}
+
+// cdxLicenses is a license choice: licenses by id or name, or an expression
+type cdxLicenses []string
+
+func (l *cdxLicenses) UnmarshalJSON(data []byte) error {
+	var choices []struct {
+		License struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"license"`
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(data, &choices); err != nil {
+		return err
+	}
+	for _, c := range choices {
+		*l = append(*l, firstNonEmpty(c.Expression, c.License.ID, c.License.Name))
+	}
+	return nil
+}
+
+func (l *cdxLicenses) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var choices struct {
+		Licenses []struct {
+			ID   string `xml:"id"`
+			Name string `xml:"name"`
+		} `xml:"license"`
+		Expressions []string `xml:"expression"`
+	}
+	if err := d.DecodeElement(&choices, &start); err != nil {
+		return err
+	}
+	for _, c := range choices.Licenses {
+		*l = append(*l, firstNonEmpty(c.ID, c.Name))
+	}
+	*l = append(*l, choices.Expressions...)
+	return nil
+}
+
+type cdxBOM struct {
+	BOMFormat   string `json:"bomFormat"`
+	SpecVersion string `json:"specVersion"`
+	Metadata    struct {
+		Component cdxComponent `json:"component" xml:"component"`
+	} `json:"metadata" xml:"metadata"`
+	Components []cdxComponent `json:"components" xml:"components>component"`
+}
+
+type spdxDocument struct {
+	SPDXVersion string   `json:"spdxVersion"`
+	Name        string   `json:"name"`
+	Describes   []string `json:"documentDescribes"`
+	Relations   []struct {
+		Element string `json:"spdxElementId"`
+		Type    string `json:"relationshipType"`
+		Related string `json:"relatedSpdxElement"`
+	} `json:"relationships"`
+	Packages []struct {
+		SPDXID           string `json:"SPDXID"`
+		Name             string `json:"name"`
+		VersionInfo      string `json:"versionInfo"`
+		Supplier         string `json:"supplier"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+		ExternalRefs     []struct {
+			Category string `json:"referenceCategory"`
+			Type     string `json:"referenceType"`
+			Locator  string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+// parseJSON reads a CycloneDX or SPDX JSON document
+func parseJSON(data []byte) (*Document, error) {
+	var probe struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("invalid SBOM JSON: %w", err)
+	}
+	switch {
+	case strings.EqualFold(probe.BOMFormat, "CycloneDX"):
+		var bom cdxBOM
+		if err := json.Unmarshal(data, &bom); err != nil {
+			return nil, fmt.Errorf("invalid CycloneDX document: %w", err)
+		}
+		return cycloneDXDocument(bom, "json"), nil
+	case probe.SPDXVersion != "":
+		var spdx spdxDocument
+		if err := json.Unmarshal(data, &spdx); err != nil {
+			return nil, fmt.Errorf("invalid SPDX document: %w", err)
+		}
+		return spdxJSONDocument(spdx), nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// parseCycloneDXXML reads a CycloneDX XML document; the spec version is the
+// one of its namespace (http://cyclonedx.org/schema/bom/1.5)
+func parseCycloneDXXML(data []byte) (*Document, error) {
+	var bom struct {
+		XMLName xml.Name
+		cdxBOM
+	}
+	if err := xml.Unmarshal(data, &bom); err != nil {
+		return nil, fmt.Errorf("invalid CycloneDX XML: %w", err)
+	}
+	if bom.XMLName.Local != "bom" || !strings.Contains(bom.XMLName.Space, "cyclonedx.org") {
+		return nil, ErrUnsupportedFormat
+	}
+	bom.SpecVersion = bom.XMLName.Space[strings.LastIndexByte(bom.XMLName.Space, '/')+1:]
+	return cycloneDXDocument(bom.cdxBOM, "xml"), nil
+}
+
+func cycloneDXDocument(bom cdxBOM, encoding string) *Document {
+	doc := &Document{SBOMDocument: domain.SBOMDocument{
+		Format:      domain.SBOMCycloneDX,
+		Encoding:    encoding,
+		SpecVersion: bom.SpecVersion,
+		Name:        bom.Metadata.Component.Name,
+		Version:     bom.Metadata.Component.Version,
+	}}
+	// Components nest: an application lists the libraries it bundles
+	var walk func([]cdxComponent)
+	walk = func(components []cdxComponent) {
+		for _, c := range components {
+			supplier := firstNonEmpty(c.Supplier.Name, c.Publisher)
+			doc.Components = append(doc.Components, newComponent(c.Name, c.Group, c.Version, c.PURL, supplier, c.Licenses))
+			walk(c.Components)
+		}
+	}
+	walk(bom.Components)
+	return doc
+}
+
+func spdxJSONDocument(spdx spdxDocument) *Document {
+	doc := &Document{SBOMDocument: domain.SBOMDocument{
+		Format:      domain.SBOMSPDX,
+		Encoding:    "json",
+		SpecVersion: strings.TrimPrefix(spdx.SPDXVersion, "SPDX-"),
+		Name:        spdx.Name,
+	}}
+	described := map[string]bool{}
+	for _, id := range spdx.Describes {
+		described[id] = true
+	}
+	for _, r := range spdx.Relations {
+		if r.Element == "SPDXRef-DOCUMENT" && r.Type == "DESCRIBES" {
+			described[r.Related] = true
+		}
+	}
+	for _, p := range spdx.Packages {
+		purl := ""
+		for _, ref := range p.ExternalRefs {
+			if strings.EqualFold(ref.Type, "purl") {
+				purl = ref.Locator
+				break
+			}
+		}
+		// The package the document describes is the product itself
+		if described[p.SPDXID] {
+			doc.Version = p.VersionInfo
+			continue
+		}
+		licenses := []string{firstNonEmpty(spdxLicense(p.LicenseConcluded), spdxLicense(p.LicenseDeclared))}
+		doc.Components = append(doc.Components, newComponent(p.Name, "", p.VersionInfo, purl, spdxSupplier(p.Supplier), licenses))
+	}
+	return doc
+}
+
+// parseSPDXTagValue reads an SPDX tag-value document
+func parseSPDXTagValue(data []byte) (*Document, error) {
+	doc := &Document{SBOMDocument: domain.SBOMDocument{Format: domain.SBOMSPDX, Encoding: "tag-value"}}
+	type pkg struct {
+		id, name, version, purl, supplier, concluded, declared string
+	}
+	var packages []*pkg
+	var current *pkg
+	described := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		tag, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(tag) {
+		case "SPDXVersion":
+			doc.SpecVersion = strings.TrimPrefix(value, "SPDX-")
+		case "DocumentName":
+			doc.Name = value
+		case "Relationship":
+			// SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package
+			if fields := strings.Fields(value); len(fields) == 3 && fields[0] == "SPDXRef-DOCUMENT" && fields[1] == "DESCRIBES" {
+				described[fields[2]] = true
+			}
+		case "PackageName":
+			current = &pkg{name: value}
+			packages = append(packages, current)
+		case "SPDXID":
+			if current != nil {
+				current.id = value
+			}
+		case "PackageVersion":
+			if current != nil {
+				current.version = value
+			}
+		case "PackageSupplier":
+			if current != nil {
+				current.supplier = value
+			}
+		case "PackageLicenseConcluded":
+			if current != nil {
+				current.concluded = value
+			}
+		case "PackageLicenseDeclared":
+			if current != nil {
+				current.declared = value
+			}
+		case "ExternalRef":
+			// PACKAGE-MANAGER purl pkg:maven/...
+			if fields := strings.Fields(value); current != nil && len(fields) == 3 && strings.EqualFold(fields[1], "purl") {
+				current.purl = fields[2]
+			}
+		case "FileName":
+			// Files follow the packages; their fields are not the package's
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read SPDX document: %w", err)
+	}
+	for _, p := range packages {
+		if described[p.id] {
+			doc.Version = p.version
+			continue
+		}
+		licenses := []string{firstNonEmpty(spdxLicense(p.concluded), spdxLicense(p.declared))}
+		doc.Components = append(doc.Components, newComponent(p.name, "", p.version, p.purl, spdxSupplier(p.supplier), licenses))
+	}
+	return doc, nil
+}
+
+// spdxLicense drops the NOASSERTION and NONE placeholders
+func spdxLicense(value string) string {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "NOASSERTION", "NONE":
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// spdxSupplier reads "Organization: Apache" as "Apache"
+func spdxSupplier(value string) string {
+	if spdxLicense(value) == "" {
+		return ""
+	}
+	if _, name, ok := strings.Cut(value, ":"); ok {
+		return strings.TrimSpace(name)
+	}
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
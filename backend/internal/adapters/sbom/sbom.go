@@ -0,0 +1,211 @@
+// Package sbom reads software bills of materials (CycloneDX JSON and XML,
+// SPDX JSON and tag-value) into the components an asset ships.
+package sbom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+const maxSBOMSize = 64 << 20
+
+// ErrUnsupportedFormat is returned for documents that are neither CycloneDX
+// nor SPDX
+var ErrUnsupportedFormat = errors.New("unsupported SBOM format: expected CycloneDX (JSON or XML) or SPDX (JSON or tag-value)")
+
+// Document is a parsed SBOM: its description and components, the latter not
+// yet attached to an asset
+type Document struct {
+	domain.SBOMDocument
+	Components []domain.SoftwareComponent
+}
+
+// Parse detects the format of an SBOM and reads it. Components listed twice
+// (same purl, or same name and version) are kept once.
+func Parse(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSBOMSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SBOM: %w", err)
+	}
+	if len(data) > maxSBOMSize {
+		return nil, fmt.Errorf("SBOM exceeds %d MB", maxSBOMSize>>20)
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\xef\xbb\xbf"))
+
+	var doc *Document
+	switch {
+	case len(data) == 0:
+		return nil, ErrUnsupportedFormat
+	case data[0] == '{':
+		doc, err = parseJSON(data)
+	case data[0] == '<':
+		doc, err = parseCycloneDXXML(data)
+	case bytes.HasPrefix(data, []byte("SPDXVersion:")), bytes.Contains(data[:min(len(data), 4096)], []byte("\nSPDXVersion:")):
+		doc, err = parseSPDXTagValue(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc.Components = dedupe(doc.Components)
+	doc.ComponentCount = len(doc.Components)
+	return doc, nil
+}
+
+// newComponent builds a component, completing its ecosystem and package name
+// from its purl
+func newComponent(name, group, version, purl, supplier string, licenses []string) domain.SoftwareComponent {
+	c := domain.SoftwareComponent{
+		Name:     strings.TrimSpace(name),
+		Group:    strings.TrimSpace(group),
+		Version:  strings.TrimSpace(version),
+		PURL:     strings.TrimSpace(purl),
+		Supplier: strings.TrimSpace(supplier),
+		Licenses: cleanLicenses(licenses),
+	}
+	if p, err := ParsePURL(c.PURL); err == nil {
+		c.Ecosystem = p.Ecosystem()
+		c.PackageName = p.PackageName()
+		if c.Name == "" {
+			c.Name = p.Name
+		}
+		if c.Group == "" {
+			c.Group = p.Namespace
+		}
+		if c.Version == "" {
+			c.Version = p.Version
+		}
+	}
+	if c.PackageName == "" {
+		c.PackageName = c.Name
+		if c.Group != "" {
+			c.PackageName = c.Group + ":" + c.Name
+		}
+	}
+	return c
+}
+
+func cleanLicenses(licenses []string) []string {
+	var out []string
+	for _, l := range licenses {
+		l = strings.TrimSpace(l)
+		switch strings.ToUpper(l) {
+		case "", "NOASSERTION", "NONE":
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func dedupe(components []domain.SoftwareComponent) []domain.SoftwareComponent {
+	seen := make(map[string]bool, len(components))
+	out := components[:0]
+	for _, c := range components {
+		if c.Name == "" {
+			continue
+		}
+		key := c.PURL
+		if key == "" {
+			key = c.PackageName + "@" + c.Version
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+// PURL is a package URL: pkg:type/namespace/name@version?qualifiers#subpath
+type PURL struct {
+	Type      string
+	Namespace string
+	Name      string
+	Version   string
+}
+
+// ParsePURL reads a package URL
+func ParsePURL(raw string) (PURL, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(raw), "pkg:")
+	if !ok {
+		return PURL{}, fmt.Errorf("invalid purl %q", raw)
+	}
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	rest = strings.Trim(rest, "/")
+	var p PURL
+	if i := strings.LastIndexByte(rest, '@'); i > strings.LastIndexByte(rest, '/') {
+		p.Version, _ = url.PathUnescape(rest[i+1:])
+		rest = rest[:i]
+	}
+	segments := strings.Split(rest, "/")
+	if len(segments) < 2 || segments[0] == "" || segments[len(segments)-1] == "" {
+		return PURL{}, fmt.Errorf("invalid purl %q", raw)
+	}
+	p.Type = strings.ToLower(segments[0])
+	for i, s := range segments {
+		segments[i], _ = url.PathUnescape(s)
+	}
+	p.Name = segments[len(segments)-1]
+	p.Namespace = strings.Join(segments[1:len(segments)-1], "/")
+	return p, nil
+}
+
+// purlEcosystems maps purl types to OSV ecosystems
+var purlEcosystems = map[string]string{
+	"maven":     "Maven",
+	"npm":       "npm",
+	"pypi":      "PyPI",
+	"golang":    "Go",
+	"cargo":     "crates.io",
+	"nuget":     "NuGet",
+	"gem":       "RubyGems",
+	"composer":  "Packagist",
+	"hex":       "Hex",
+	"pub":       "Pub",
+	"swift":     "SwiftURL",
+	"cocoapods": "CocoaPods",
+	"deb":       "Debian",
+	"apk":       "Alpine",
+	"rpm":       "Red Hat",
+}
+
+// Ecosystem returns the OSV ecosystem of the package, or its purl type
+func (p PURL) Ecosystem() string {
+	if e, ok := purlEcosystems[p.Type]; ok {
+		if p.Type == "deb" && strings.EqualFold(p.Namespace, "ubuntu") {
+			return "Ubuntu"
+		}
+		return e
+	}
+	return p.Type
+}
+
+// PackageName returns the name of the package in its ecosystem, as OSV
+// advisories name it: "group:artifact" for Maven, "@scope/name" for npm,
+// the module path for Go, "vendor/package" for Composer
+func (p PURL) PackageName() string {
+	switch p.Type {
+	case "maven":
+		if p.Namespace != "" {
+			return p.Namespace + ":" + p.Name
+		}
+	case "pypi":
+		return strings.ReplaceAll(strings.ToLower(p.Name), "_", "-")
+	case "npm", "golang", "composer", "swift":
+		if p.Namespace != "" {
+			return p.Namespace + "/" + p.Name
+		}
+	}
+	return p.Name
+}
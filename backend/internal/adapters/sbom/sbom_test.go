@@ -0,0 +1,157 @@
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCycloneDXJSON(t *testing.T) {
+	bom := `{
+	  "bomFormat": "CycloneDX", "specVersion": "1.5",
+	  "metadata": {"component": {"type": "application", "name": "billing", "version": "3.2.0"}},
+	  "components": [
+	    {"type": "library", "group": "org.apache.logging.log4j", "name": "log4j-core", "version": "2.14.1",
+	     "purl": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1",
+	     "licenses": [{"license": {"id": "Apache-2.0"}}],
+	     "components": [{"type": "library", "name": "shaded", "version": "1.0", "purl": "pkg:npm/%40acme/shaded@1.0"}]},
+	    {"type": "library", "name": "requests", "version": "2.31.0", "purl": "pkg:pypi/Requests@2.31.0",
+	     "licenses": [{"expression": "Apache-2.0 OR MIT"}]},
+	    {"type": "library", "group": "org.apache.logging.log4j", "name": "log4j-core", "version": "2.14.1",
+	     "purl": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"}
+	  ]
+	}`
+
+	doc, err := Parse(strings.NewReader(bom))
+	require.NoError(t, err)
+	assert.Equal(t, domain.SBOMCycloneDX, doc.Format)
+	assert.Equal(t, "json", doc.Encoding)
+	assert.Equal(t, "1.5", doc.SpecVersion)
+	assert.Equal(t, "billing", doc.Name)
+	assert.Equal(t, "3.2.0", doc.Version)
+	require.Len(t, doc.Components, 3, "nested components are read, duplicates dropped")
+	assert.Equal(t, 3, doc.ComponentCount)
+
+	log4j := doc.Components[0]
+	assert.Equal(t, "log4j-core", log4j.Name)
+	assert.Equal(t, "Maven", log4j.Ecosystem)
+	assert.Equal(t, "org.apache.logging.log4j:log4j-core", log4j.PackageName)
+	assert.Equal(t, []string{"Apache-2.0"}, []string(log4j.Licenses))
+
+	assert.Equal(t, "@acme/shaded", doc.Components[1].PackageName)
+	assert.Equal(t, "npm", doc.Components[1].Ecosystem)
+
+	assert.Equal(t, "PyPI", doc.Components[2].Ecosystem)
+	assert.Equal(t, "requests", doc.Components[2].PackageName)
+	assert.Equal(t, []string{"Apache-2.0 OR MIT"}, []string(doc.Components[2].Licenses))
+}
+
+func TestParseCycloneDXXML(t *testing.T) {
+	bom := `<?xml version="1.0" encoding="UTF-8"?>
+<bom xmlns="http://cyclonedx.org/schema/bom/1.4" version="1">
+  <metadata><component type="application"><name>portal</name><version>1.0</version></component></metadata>
+  <components>
+    <component type="library">
+      <group>com.fasterxml.jackson.core</group>
+      <name>jackson-databind</name>
+      <version>2.9.10</version>
+      <purl>pkg:maven/com.fasterxml.jackson.core/jackson-databind@2.9.10</purl>
+      <licenses><license><id>Apache-2.0</id></license></licenses>
+    </component>
+    <component type="library">
+      <name>gpl-lib</name>
+      <version>0.3</version>
+      <licenses><expression>GPL-3.0-only</expression></licenses>
+    </component>
+  </components>
+</bom>`
+
+	doc, err := Parse(strings.NewReader(bom))
+	require.NoError(t, err)
+	assert.Equal(t, domain.SBOMCycloneDX, doc.Format)
+	assert.Equal(t, "xml", doc.Encoding)
+	assert.Equal(t, "1.4", doc.SpecVersion)
+	assert.Equal(t, "portal", doc.Name)
+	require.Len(t, doc.Components, 2)
+	assert.Equal(t, "com.fasterxml.jackson.core:jackson-databind", doc.Components[0].PackageName)
+	assert.Equal(t, []string{"Apache-2.0"}, []string(doc.Components[0].Licenses))
+	assert.Equal(t, "gpl-lib", doc.Components[1].PackageName)
+	assert.Equal(t, []string{"GPL-3.0-only"}, []string(doc.Components[1].Licenses))
+}
+
+func TestParseSPDXJSON(t *testing.T) {
+	spdx := `{
+	  "spdxVersion": "SPDX-2.3", "name": "web-frontend",
+	  "packages": [
+	    {"SPDXID": "SPDXRef-root", "name": "web-frontend", "versionInfo": "5.1.0"},
+	    {"SPDXID": "SPDXRef-lodash", "name": "lodash", "versionInfo": "4.17.15",
+	     "supplier": "Organization: OpenJS Foundation",
+	     "licenseConcluded": "NOASSERTION", "licenseDeclared": "MIT",
+	     "externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:npm/lodash@4.17.15"}]}
+	  ],
+	  "relationships": [{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-root"}]
+	}`
+
+	doc, err := Parse(strings.NewReader(spdx))
+	require.NoError(t, err)
+	assert.Equal(t, domain.SBOMSPDX, doc.Format)
+	assert.Equal(t, "2.3", doc.SpecVersion)
+	assert.Equal(t, "5.1.0", doc.Version, "the described package is the product")
+	require.Len(t, doc.Components, 1)
+	lodash := doc.Components[0]
+	assert.Equal(t, "npm", lodash.Ecosystem)
+	assert.Equal(t, "OpenJS Foundation", lodash.Supplier)
+	assert.Equal(t, []string{"MIT"}, []string(lodash.Licenses))
+}
+
+func TestParseSPDXTagValue(t *testing.T) {
+	spdx := `SPDXVersion: SPDX-2.2
+DataLicense: CC0-1.0
+SPDXID: SPDXRef-DOCUMENT
+DocumentName: agent
+Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-agent
+
+PackageName: agent
+SPDXID: SPDXRef-agent
+PackageVersion: 0.9.0
+
+PackageName: golang.org/x/net
+SPDXID: SPDXRef-net
+PackageVersion: v0.7.0
+PackageLicenseConcluded: BSD-3-Clause
+ExternalRef: PACKAGE-MANAGER purl pkg:golang/golang.org/x/net@v0.7.0
+
+FileName: ./main.go
+SPDXID: SPDXRef-file
+`
+	doc, err := Parse(strings.NewReader(spdx))
+	require.NoError(t, err)
+	assert.Equal(t, "tag-value", doc.Encoding)
+	assert.Equal(t, "agent", doc.Name)
+	assert.Equal(t, "0.9.0", doc.Version)
+	require.Len(t, doc.Components, 1)
+	assert.Equal(t, "Go", doc.Components[0].Ecosystem)
+	assert.Equal(t, "golang.org/x/net", doc.Components[0].PackageName)
+	assert.Equal(t, "v0.7.0", doc.Components[0].Version)
+}
+
+func TestParseRejectsUnknownDocuments(t *testing.T) {
+	for _, doc := range []string{``, `{"foo": 1}`, `<project/>`, `name,version`} {
+		_, err := Parse(strings.NewReader(doc))
+		assert.Error(t, err, doc)
+	}
+}
+
+func TestParsePURL(t *testing.T) {
+	p, err := ParsePURL("pkg:deb/ubuntu/openssl@3.0.2-0ubuntu1.10?arch=amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "Ubuntu", p.Ecosystem())
+	assert.Equal(t, "openssl", p.PackageName())
+	assert.Equal(t, "3.0.2-0ubuntu1.10", p.Version)
+
+	_, err = ParsePURL("maven/log4j")
+	assert.Error(t, err)
+}
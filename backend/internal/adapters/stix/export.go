@@ -0,0 +1,215 @@
+package stix
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// namespace derives the IDs of the objects OpenRisk writes without an ID of
+// their own, so that exporting them again gives the same IDs
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/opendefender/openrisk"))
+
+// IdentityID is the identity of OpenRisk, creator of the objects it writes
+var IdentityID = "identity--" + namespace.String()
+
+// ExportInput is what an export shares: risks with their mitigations, the
+// threats linked to each risk and the ATT&CK catalog entries of their
+// techniques. Objects marked above MaxTLP are left out.
+type ExportInput struct {
+	Risks      []domain.Risk
+	Threats    map[uuid.UUID][]domain.Threat
+	Techniques map[string]domain.AttackObject
+	MaxTLP     string
+}
+
+// Export writes the input as a STIX 2.1 bundle
+func Export(in ExportInput) (*Bundle, error) {
+	objects, err := ExportObjects(in)
+	if err != nil {
+		return nil, err
+	}
+	return &Bundle{Type: "bundle", ID: "bundle--" + uuid.NewString(), Objects: objects}, nil
+}
+
+// ExportObjects writes each risk as a vulnerability, its mitigations as
+// courses of action mitigating it, its techniques as attack patterns
+// targeting it and its threats as received, related to it. Objects are
+// marked with the TLP of what they stand for, relationships with the most
+// restrictive of their ends.
+func ExportObjects(in ExportInput) ([]json.RawMessage, error) {
+	w := &writer{seen: map[string]bool{}}
+	for i := range in.Risks {
+		risk := &in.Risks[i]
+		tlp := domain.EffectiveTLP(risk.TLP)
+		if !domain.TLPAllows(in.MaxTLP, tlp) {
+			continue
+		}
+		riskRef := domain.RiskSTIXID(risk.ID)
+		score := risk.Score
+		refs := []ExternalReference{{SourceName: "openrisk", ExternalID: risk.ID.String()}}
+		for _, cve := range domain.ExtractCVEs(append([]string{risk.ExternalID, risk.Title, risk.Description}, risk.Tags...)...) {
+			refs = append(refs, ExternalReference{SourceName: "cve", ExternalID: cve})
+		}
+		w.add(Object{
+			Type:               "vulnerability",
+			ID:                 riskRef,
+			Created:            timestamp(risk.CreatedAt),
+			Modified:           timestamp(risk.UpdatedAt),
+			Name:               risk.Title,
+			Description:        risk.Description,
+			Labels:             risk.Tags,
+			ExternalReferences: refs,
+			ObjectMarkingRefs:  []string{TLPMarkingID(tlp)},
+			XStatus:            string(risk.Status),
+			XScore:             &score,
+			XImpact:            risk.Impact,
+			XProbability:       risk.Probability,
+		})
+
+		for j := range risk.Mitigations {
+			m := &risk.Mitigations[j]
+			progress := m.Progress
+			ref := "course-of-action--" + m.ID.String()
+			var countermeasures []ExternalReference
+			for _, id := range m.Countermeasures {
+				source := "mitre-attack"
+				if domain.IsD3FENDID(id) {
+					source = "d3fend"
+				}
+				countermeasures = append(countermeasures, ExternalReference{SourceName: source, ExternalID: id})
+			}
+			w.add(Object{
+				Type:               "course-of-action",
+				ID:                 ref,
+				Created:            timestamp(m.CreatedAt),
+				Modified:           timestamp(m.UpdatedAt),
+				Name:               m.Title,
+				ExternalReferences: countermeasures,
+				ObjectMarkingRefs:  []string{TLPMarkingID(tlp)},
+				XStatus:            string(m.Status),
+				XProgress:          &progress,
+			})
+			w.relate("mitigates", ref, riskRef, tlp, m.UpdatedAt)
+		}
+
+		for _, id := range risk.Techniques {
+			// Written by OpenRisk under an ID of its own, the ATT&CK ID in
+			// the external reference correlates it with the catalog
+			technique, known := in.Techniques[id]
+			ref := "attack-pattern--" + uuid.NewSHA1(namespace, []byte(id)).String()
+			name := technique.Name
+			if !known {
+				name = id
+			}
+			w.add(Object{
+				Type:     "attack-pattern",
+				ID:       ref,
+				Created:  timestamp(risk.CreatedAt),
+				Modified: timestamp(risk.CreatedAt),
+				Name:     name,
+				ExternalReferences: []ExternalReference{{
+					SourceName: "mitre-attack", ExternalID: id, URL: technique.URL,
+				}},
+				ObjectMarkingRefs: []string{TLPMarkingID(domain.TLPClear)},
+			})
+			w.relate("targets", ref, riskRef, tlp, risk.UpdatedAt)
+		}
+
+		for _, threat := range in.Threats[risk.ID] {
+			threatTLP := domain.EffectiveTLP(threat.TLP)
+			if !domain.TLPAllows(in.MaxTLP, threatTLP) {
+				continue
+			}
+			if len(threat.Raw) > 0 {
+				w.addRaw(threat.STIXID, json.RawMessage(threat.Raw))
+			} else {
+				w.add(Object{
+					Type:              threat.Type,
+					ID:                threat.STIXID,
+					Created:           timestamp(threat.ReportedAt),
+					Modified:          timestamp(threat.ModifiedAt),
+					Name:              threat.Name,
+					Description:       threat.Description,
+					ObjectMarkingRefs: []string{TLPMarkingID(threatTLP)},
+				})
+			}
+			relationship := "related-to"
+			if threat.Type == domain.ThreatActor || threat.Type == domain.ThreatAttackPattern {
+				relationship = "targets"
+			}
+			if domain.TLPAllows(tlp, threatTLP) {
+				threatTLP = tlp
+			}
+			w.relate(relationship, threat.STIXID, riskRef, threatTLP, risk.UpdatedAt)
+		}
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	if len(w.objects) == 0 {
+		return []json.RawMessage{}, nil
+	}
+
+	identity, err := json.Marshal(Object{
+		Type:          "identity",
+		SpecVersion:   SpecVersion,
+		ID:            IdentityID,
+		Created:       "2024-01-01T00:00:00.000Z",
+		Modified:      "2024-01-01T00:00:00.000Z",
+		Name:          "OpenRisk",
+		IdentityClass: "system",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]json.RawMessage{identity}, w.objects...), nil
+}
+
+type writer struct {
+	seen    map[string]bool
+	objects []json.RawMessage
+	err     error
+}
+
+// add writes an object created by OpenRisk, once
+func (w *writer) add(o Object) {
+	if w.seen[o.ID] || w.err != nil {
+		return
+	}
+	o.SpecVersion = SpecVersion
+	o.CreatedByRef = IdentityID
+	data, err := json.Marshal(o)
+	if err != nil {
+		w.err = fmt.Errorf("failed to write %s: %w", o.ID, err)
+		return
+	}
+	w.addRaw(o.ID, data)
+}
+
+func (w *writer) addRaw(id string, data json.RawMessage) {
+	if w.seen[id] {
+		return
+	}
+	w.seen[id] = true
+	w.objects = append(w.objects, data)
+}
+
+// relate writes a relationship, its ID derived from its ends
+func (w *writer) relate(relationship, source, target, tlp string, at time.Time) {
+	key := relationship + "|" + source + "|" + target
+	w.add(Object{
+		Type:              "relationship",
+		ID:                "relationship--" + uuid.NewSHA1(namespace, []byte(key)).String(),
+		Created:           timestamp(at),
+		Modified:          timestamp(at),
+		RelationshipType:  relationship,
+		SourceRef:         source,
+		TargetRef:         target,
+		ObjectMarkingRefs: []string{TLPMarkingID(tlp)},
+	})
+}
@@ -0,0 +1,257 @@
+// Package stix reads STIX 2.1 bundles into threats and writes risks, their
+// mitigations and the threats and techniques linked to them as STIX 2.1
+// objects, honoring TLP markings.
+package stix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+	"gorm.io/datatypes"
+)
+
+// SpecVersion is the STIX version objects are written in
+const SpecVersion = "2.1"
+
+// ErrNotSTIXBundle is returned for JSON that is not a STIX bundle
+var ErrNotSTIXBundle = errors.New("not a STIX bundle")
+
+// TLP marking definitions: those of TLP 2.0, which objects are written with,
+// and the TLP 1.0 ones predefined by STIX 2.1
+var (
+	tlpMarkingIDs = map[string]string{
+		domain.TLPClear:       "marking-definition--94868c89-83c2-464b-929b-a1a8aa3c8487",
+		domain.TLPGreen:       "marking-definition--bab4a63c-aed9-4cf5-a766-dfca5abac2bb",
+		domain.TLPAmber:       "marking-definition--55d920b0-5e8b-4f79-9ee9-91f868d9b421",
+		domain.TLPAmberStrict: "marking-definition--939a9414-2ddd-4d32-a0cd-375ea402b003",
+		domain.TLPRed:         "marking-definition--e828b379-4e03-4974-9ac4-e53a884c97c1",
+	}
+	tlpV1MarkingIDs = map[string]string{
+		"marking-definition--613f2e26-407d-48c7-9eca-b8e91df99dc9": domain.TLPClear,
+		"marking-definition--34098fce-860f-48ae-8e50-ebd3cc5e41da": domain.TLPGreen,
+		"marking-definition--f88d31f6-486f-44da-b317-01333bde0b82": domain.TLPAmber,
+		"marking-definition--5e57c739-391a-4eb3-b6be-7d15ca92d5ed": domain.TLPRed,
+	}
+)
+
+// TLPMarkingID returns the TLP 2.0 marking definition of a marking
+func TLPMarkingID(tlp string) string {
+	return tlpMarkingIDs[domain.EffectiveTLP(tlp)]
+}
+
+// Bundle is a STIX 2.1 bundle
+type Bundle struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id"`
+	Objects []json.RawMessage `json:"objects"`
+}
+
+// ExternalReference points to an object in another source (cve, mitre-attack)
+type ExternalReference struct {
+	SourceName  string `json:"source_name"`
+	ExternalID  string `json:"external_id,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Object holds the properties of the STIX objects read and written; custom
+// properties of OpenRisk are prefixed x_openrisk_
+type Object struct {
+	Type               string              `json:"type"`
+	SpecVersion        string              `json:"spec_version,omitempty"`
+	ID                 string              `json:"id"`
+	Created            string              `json:"created,omitempty"`
+	Modified           string              `json:"modified,omitempty"`
+	CreatedByRef       string              `json:"created_by_ref,omitempty"`
+	Revoked            bool                `json:"revoked,omitempty"`
+	Confidence         int                 `json:"confidence,omitempty"`
+	Name               string              `json:"name,omitempty"`
+	Description        string              `json:"description,omitempty"`
+	Labels             []string            `json:"labels,omitempty"`
+	ExternalReferences []ExternalReference `json:"external_references,omitempty"`
+	ObjectMarkingRefs  []string            `json:"object_marking_refs,omitempty"`
+
+	// identity
+	IdentityClass string `json:"identity_class,omitempty"`
+	// indicator
+	IndicatorTypes []string `json:"indicator_types,omitempty"`
+	Pattern        string   `json:"pattern,omitempty"`
+	PatternType    string   `json:"pattern_type,omitempty"`
+	ValidFrom      string   `json:"valid_from,omitempty"`
+	ValidUntil     string   `json:"valid_until,omitempty"`
+	// threat-actor
+	ThreatActorTypes []string `json:"threat_actor_types,omitempty"`
+	Aliases          []string `json:"aliases,omitempty"`
+	// relationship
+	RelationshipType string `json:"relationship_type,omitempty"`
+	SourceRef        string `json:"source_ref,omitempty"`
+	TargetRef        string `json:"target_ref,omitempty"`
+	// marking-definition (TLP 1.0)
+	DefinitionType string `json:"definition_type,omitempty"`
+	Definition     *struct {
+		TLP string `json:"tlp,omitempty"`
+	} `json:"definition,omitempty"`
+
+	XStatus      string   `json:"x_openrisk_status,omitempty"`
+	XScore       *float64 `json:"x_openrisk_score,omitempty"`
+	XImpact      int      `json:"x_openrisk_impact,omitempty"`
+	XProbability int      `json:"x_openrisk_probability,omitempty"`
+	XProgress    *int     `json:"x_openrisk_progress,omitempty"`
+}
+
+// Read reads a STIX 2.1 bundle
+func Read(r io.Reader) (*ports.ThreatIntel, error) {
+	var bundle Bundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotSTIXBundle, err)
+	}
+	if bundle.Type != "bundle" {
+		return nil, fmt.Errorf("%w: type %q", ErrNotSTIXBundle, bundle.Type)
+	}
+	return Parse(bundle.Objects)
+}
+
+// Parse reads indicators, vulnerabilities, attack patterns and threat actors
+// as threats, with the relationships between objects. Their TLP marking is
+// the most restrictive of the TLP marking definitions they reference,
+// predefined or defined among the objects.
+func Parse(raw []json.RawMessage) (*ports.ThreatIntel, error) {
+	objects := make([]Object, len(raw))
+	markings := map[string]string{}
+	for id, tlp := range tlpV1MarkingIDs {
+		markings[id] = tlp
+	}
+	for tlp, id := range tlpMarkingIDs {
+		markings[id] = tlp
+	}
+	for i, data := range raw {
+		if err := json.Unmarshal(data, &objects[i]); err != nil {
+			return nil, fmt.Errorf("invalid STIX object %d: %w", i, err)
+		}
+		if o := &objects[i]; o.Type == "marking-definition" {
+			if tlp := markingTLP(o); tlp != "" {
+				markings[o.ID] = tlp
+			}
+		}
+	}
+
+	intel := &ports.ThreatIntel{}
+	for i := range objects {
+		o := &objects[i]
+		switch {
+		case o.Type == "relationship":
+			if o.SourceRef != "" && o.TargetRef != "" {
+				intel.Relationships = append(intel.Relationships, ports.ThreatRelationship{
+					Type: o.RelationshipType, SourceRef: o.SourceRef, TargetRef: o.TargetRef,
+				})
+			}
+		case domain.IsThreatType(o.Type):
+			threat := domain.Threat{
+				STIXID:      o.ID,
+				Type:        o.Type,
+				Name:        truncate(o.Name, 255),
+				Description: o.Description,
+				Pattern:     o.Pattern,
+				PatternType: truncate(o.PatternType, 30),
+				Labels:      append(append(append([]string{}, o.Labels...), o.IndicatorTypes...), o.ThreatActorTypes...),
+				Aliases:     o.Aliases,
+				Confidence:  o.Confidence,
+				Revoked:     o.Revoked,
+				ValidFrom:   parseTimestamp(o.ValidFrom),
+				ValidUntil:  parseTimestamp(o.ValidUntil),
+				Raw:         datatypes.JSON(raw[i]),
+			}
+			for _, ref := range o.ObjectMarkingRefs {
+				if tlp, ok := markings[ref]; ok && (threat.TLP == "" || !domain.TLPAllows(threat.TLP, tlp)) {
+					threat.TLP = tlp
+				}
+			}
+			threat.ExternalID = externalID(o)
+			if threat.Name == "" {
+				threat.Name = truncate(firstNonEmpty(threat.ExternalID, o.Pattern, o.ID), 255)
+			}
+			if created := parseTimestamp(o.Created); created != nil {
+				threat.ReportedAt = *created
+			}
+			threat.ModifiedAt = threat.ReportedAt
+			if modified := parseTimestamp(o.Modified); modified != nil {
+				threat.ModifiedAt = *modified
+			}
+			intel.Threats = append(intel.Threats, threat)
+		}
+	}
+	return intel, nil
+}
+
+// markingTLP returns the TLP marking a marking definition defines, if any
+func markingTLP(o *Object) string {
+	if o.DefinitionType == "tlp" && o.Definition != nil {
+		if tlp, err := domain.NormalizeTLP(o.Definition.TLP); err == nil {
+			return tlp
+		}
+	}
+	if strings.HasPrefix(strings.ToUpper(o.Name), "TLP:") {
+		if tlp, err := domain.NormalizeTLP(o.Name); err == nil {
+			return tlp
+		}
+	}
+	return ""
+}
+
+// externalID returns the CVE of a vulnerability or the ATT&CK ID of an attack
+// pattern
+func externalID(o *Object) string {
+	source := map[string]string{domain.ThreatVulnerability: "cve", domain.ThreatAttackPattern: "mitre-attack"}[o.Type]
+	for _, ref := range o.ExternalReferences {
+		if source != "" && ref.SourceName == source && ref.ExternalID != "" {
+			return truncate(strings.ToUpper(ref.ExternalID), 50)
+		}
+	}
+	if o.Type == domain.ThreatVulnerability {
+		if cves := domain.ExtractCVEs(o.Name); len(cves) > 0 {
+			return cves[0]
+		}
+	}
+	return ""
+}
+
+// parseTimestamp parses a STIX timestamp; nil when absent or invalid
+func parseTimestamp(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// timestamp writes a STIX timestamp, in UTC with milliseconds
+func timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
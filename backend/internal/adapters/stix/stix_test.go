@@ -0,0 +1,198 @@
+package stix
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const feedBundle = `{
+  "type": "bundle", "id": "bundle--1",
+  "objects": [
+    {"type": "marking-definition", "spec_version": "2.1", "id": "marking-definition--partner", "definition_type": "tlp", "definition": {"tlp": "amber"}},
+    {"type": "indicator", "spec_version": "2.1", "id": "indicator--c2", "created": "2024-03-01T10:00:00.000Z", "modified": "2024-03-02T10:00:00.000Z",
+      "name": "C2 server", "indicator_types": ["malicious-activity"], "pattern": "[ipv4-addr:value = '198.51.100.7']", "pattern_type": "stix",
+      "valid_from": "2024-03-01T00:00:00Z", "confidence": 80,
+      "object_marking_refs": ["marking-definition--34098fce-860f-48ae-8e50-ebd3cc5e41da", "marking-definition--partner"]},
+    {"type": "vulnerability", "spec_version": "2.1", "id": "vulnerability--log4shell", "created": "2021-12-10T00:00:00Z", "modified": "2021-12-10T00:00:00Z",
+      "name": "Log4Shell", "external_references": [{"source_name": "cve", "external_id": "cve-2021-44228"}],
+      "object_marking_refs": ["marking-definition--94868c89-83c2-464b-929b-a1a8aa3c8487"]},
+    {"type": "threat-actor", "spec_version": "2.1", "id": "threat-actor--apt", "created": "2024-01-01T00:00:00Z", "modified": "2024-01-01T00:00:00Z",
+      "name": "APT Example", "threat_actor_types": ["nation-state"], "aliases": ["Example Bear"],
+      "object_marking_refs": ["marking-definition--5e57c739-391a-4eb3-b6be-7d15ca92d5ed"]},
+    {"type": "attack-pattern", "spec_version": "2.1", "id": "attack-pattern--ps", "created": "2024-01-01T00:00:00Z", "modified": "2024-01-01T00:00:00Z",
+      "name": "PowerShell", "external_references": [{"source_name": "mitre-attack", "external_id": "T1059.001"}]},
+    {"type": "malware", "spec_version": "2.1", "id": "malware--ignored", "name": "Not a threat", "is_family": true},
+    {"type": "relationship", "spec_version": "2.1", "id": "relationship--1", "relationship_type": "targets", "source_ref": "threat-actor--apt", "target_ref": "vulnerability--log4shell"},
+    {"type": "relationship", "spec_version": "2.1", "id": "relationship--2", "relationship_type": "uses", "source_ref": "threat-actor--apt"}
+  ]
+}`
+
+func TestReadBundle(t *testing.T) {
+	intel, err := Read(strings.NewReader(feedBundle))
+	require.NoError(t, err)
+	require.Len(t, intel.Threats, 4)
+	require.Len(t, intel.Relationships, 1)
+	assert.Equal(t, "targets", intel.Relationships[0].Type)
+
+	byID := map[string]domain.Threat{}
+	for _, threat := range intel.Threats {
+		byID[threat.STIXID] = threat
+	}
+
+	indicator := byID["indicator--c2"]
+	assert.Equal(t, domain.ThreatIndicator, indicator.Type)
+	assert.Equal(t, domain.TLPAmber, indicator.TLP, "the most restrictive marking wins")
+	assert.Equal(t, "stix", indicator.PatternType)
+	assert.Equal(t, []string{"malicious-activity"}, []string(indicator.Labels))
+	assert.Equal(t, 80, indicator.Confidence)
+	require.NotNil(t, indicator.ValidFrom)
+	assert.Equal(t, time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), indicator.ModifiedAt)
+	assert.JSONEq(t, `"indicator--c2"`, mustField(t, indicator.Raw, "id"))
+
+	vulnerability := byID["vulnerability--log4shell"]
+	assert.Equal(t, "CVE-2021-44228", vulnerability.ExternalID)
+	assert.Equal(t, domain.TLPClear, vulnerability.TLP)
+
+	actor := byID["threat-actor--apt"]
+	assert.Equal(t, domain.TLPRed, actor.TLP, "TLP 1.0 RED marking")
+	assert.Equal(t, []string{"Example Bear"}, []string(actor.Aliases))
+
+	pattern := byID["attack-pattern--ps"]
+	assert.Equal(t, "T1059.001", pattern.ExternalID)
+	assert.Empty(t, pattern.TLP, "unmarked objects keep no marking")
+}
+
+func TestReadRejectsOtherJSON(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"type": "x-other", "objects": []}`))
+	assert.ErrorIs(t, err, ErrNotSTIXBundle)
+
+	_, err = Read(strings.NewReader(`not json`))
+	assert.ErrorIs(t, err, ErrNotSTIXBundle)
+}
+
+func exportInput() ExportInput {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	shared := domain.Risk{
+		ID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), Title: "Log4Shell on the payment API",
+		ExternalID: "CVE-2021-44228", Impact: 5, Probability: 4, Score: 20, TLP: domain.TLPGreen,
+		Techniques: []string{"T1190"}, Status: domain.StatusActive, CreatedAt: at, UpdatedAt: at,
+		Mitigations: []domain.Mitigation{{
+			ID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), Title: "Patch log4j",
+			Countermeasures: []string{"M1051"}, Progress: 50, CreatedAt: at, UpdatedAt: at,
+		}},
+	}
+	secret := domain.Risk{
+		ID: uuid.MustParse("33333333-3333-3333-3333-333333333333"), Title: "Merger leak",
+		TLP: domain.TLPRed, CreatedAt: at, UpdatedAt: at,
+	}
+	actor := domain.Threat{
+		STIXID: "threat-actor--apt", Type: domain.ThreatActor, Name: "APT Example", TLP: domain.TLPAmber,
+		Raw: []byte(`{"type":"threat-actor","spec_version":"2.1","id":"threat-actor--apt","name":"APT Example"}`),
+	}
+	return ExportInput{
+		Risks:      []domain.Risk{shared, secret},
+		Threats:    map[uuid.UUID][]domain.Threat{shared.ID: {actor}},
+		Techniques: map[string]domain.AttackObject{"T1190": {ID: "T1190", Name: "Exploit Public-Facing Application"}},
+		MaxTLP:     domain.TLPAmber,
+	}
+}
+
+func TestExportHonorsTLP(t *testing.T) {
+	bundle, err := Export(exportInput())
+	require.NoError(t, err)
+	assert.Equal(t, "bundle", bundle.Type)
+
+	objects := map[string]Object{}
+	for _, raw := range bundle.Objects {
+		var o Object
+		require.NoError(t, json.Unmarshal(raw, &o))
+		objects[o.ID] = o
+	}
+	assert.Equal(t, "identity", mustObject(t, bundle.Objects[0]).Type)
+
+	risk, ok := objects["vulnerability--11111111-1111-1111-1111-111111111111"]
+	require.True(t, ok)
+	assert.Equal(t, []string{TLPMarkingID(domain.TLPGreen)}, risk.ObjectMarkingRefs)
+	assert.Contains(t, risk.ExternalReferences, ExternalReference{SourceName: "cve", ExternalID: "CVE-2021-44228"})
+	assert.Equal(t, 5, risk.XImpact)
+	assert.NotContains(t, objects, "vulnerability--33333333-3333-3333-3333-333333333333", "RED risks stay above an AMBER ceiling")
+
+	assert.Contains(t, objects, "course-of-action--22222222-2222-2222-2222-222222222222")
+	assert.Contains(t, objects, "threat-actor--apt")
+
+	relationships := map[string]Object{}
+	for _, o := range objects {
+		if o.Type == "relationship" {
+			relationships[o.RelationshipType+"|"+o.SourceRef] = o
+		}
+	}
+	require.Len(t, relationships, 3)
+	actorLink := relationships["targets|threat-actor--apt"]
+	assert.Equal(t, []string{TLPMarkingID(domain.TLPAmber)}, actorLink.ObjectMarkingRefs, "a relationship is marked as its most restrictive end")
+
+	exported, err := Export(ExportInput{Risks: exportInput().Risks, MaxTLP: domain.TLPClear})
+	require.NoError(t, err)
+	assert.Empty(t, exported.Objects)
+}
+
+func TestExportIsStable(t *testing.T) {
+	first, err := ExportObjects(exportInput())
+	require.NoError(t, err)
+	second, err := ExportObjects(exportInput())
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	bundle, err := Export(exportInput())
+	require.NoError(t, err)
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	intel, err := Read(strings.NewReader(string(data)))
+	require.NoError(t, err)
+
+	types := map[string]string{}
+	for _, threat := range intel.Threats {
+		types[threat.STIXID] = threat.Type
+	}
+	risk := domain.RiskSTIXID(uuid.MustParse("11111111-1111-1111-1111-111111111111"))
+	assert.Equal(t, domain.ThreatVulnerability, types[risk])
+	assert.Equal(t, domain.ThreatActor, types["threat-actor--apt"])
+	id, ok := domain.RiskIDFromSTIXID(risk)
+	assert.True(t, ok)
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", id.String())
+
+	for _, threat := range intel.Threats {
+		if threat.STIXID == risk {
+			assert.Equal(t, domain.TLPGreen, threat.TLP)
+			assert.Equal(t, "CVE-2021-44228", threat.ExternalID)
+		}
+		if threat.Type == domain.ThreatAttackPattern {
+			assert.Equal(t, "T1190", threat.ExternalID)
+			assert.Equal(t, domain.TLPClear, threat.TLP)
+		}
+	}
+	assert.Len(t, intel.Relationships, 3)
+}
+
+func mustObject(t *testing.T, raw json.RawMessage) Object {
+	t.Helper()
+	var o Object
+	require.NoError(t, json.Unmarshal(raw, &o))
+	return o
+}
+
+func mustField(t *testing.T, raw []byte, field string) string {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(raw, &fields))
+	return string(fields[field])
+}
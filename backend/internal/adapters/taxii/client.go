@@ -0,0 +1,157 @@
+package taxii
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/adapters/stix"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+)
+
+// maxResponseSize bounds a TAXII response
+const maxResponseSize = 64 << 20
+
+// maxPages bounds the pages followed in one poll
+const maxPages = 1000
+
+// Client reads an API root of a TAXII 2.1 server. It authenticates with HTTP
+// Basic, or with a bearer token when only Password is set.
+type Client struct {
+	APIRoot  string // https://taxii.example.org/api1/
+	Username string
+	Password string
+	PageSize int
+	Client   *http.Client
+}
+
+// NewClient creates a client of an API root
+func NewClient(apiRoot, username, password string) *Client {
+	return &Client{
+		APIRoot:  strings.TrimRight(apiRoot, "/") + "/",
+		Username: username,
+		Password: password,
+		PageSize: 500,
+		Client:   &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Collections lists the collections of the API root
+func (c *Client) Collections(ctx context.Context) ([]Collection, error) {
+	var collections Collections
+	if _, err := c.get(ctx, c.APIRoot+"collections/", &collections); err != nil {
+		return nil, err
+	}
+	return collections.Collections, nil
+}
+
+// Objects reads the objects added to a collection after addedAfter, when
+// set, following the pages. It returns the date the last one was added,
+// from which to resume, or addedAfter when there is none.
+func (c *Client) Objects(ctx context.Context, collectionID string, addedAfter *time.Time) ([]json.RawMessage, *time.Time, error) {
+	endpoint := c.APIRoot + "collections/" + url.PathEscape(collectionID) + "/objects/"
+	query := url.Values{}
+	if addedAfter != nil {
+		query.Set("added_after", addedAfter.UTC().Format(time.RFC3339Nano))
+	}
+	if c.PageSize > 0 {
+		query.Set("limit", strconv.Itoa(c.PageSize))
+	}
+
+	var objects []json.RawMessage
+	last := addedAfter
+	for page := 0; page < maxPages; page++ {
+		var envelope Envelope
+		header, err := c.get(ctx, endpoint+"?"+query.Encode(), &envelope)
+		if err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, envelope.Objects...)
+		if added, err := time.Parse(time.RFC3339Nano, header.Get(HeaderDateAddedLast)); err == nil {
+			added = added.UTC()
+			last = &added
+		}
+		if !envelope.More {
+			return objects, last, nil
+		}
+		if envelope.Next != "" {
+			query.Set("next", envelope.Next)
+		} else if last != nil {
+			// Servers without next resume from the last date of addition
+			query.Set("added_after", last.Format(time.RFC3339Nano))
+		} else {
+			return nil, nil, fmt.Errorf("TAXII server announced more objects without a way to fetch them")
+		}
+	}
+	return nil, nil, fmt.Errorf("TAXII collection %s has more than %d pages", collectionID, maxPages)
+}
+
+func (c *Client) get(ctx context.Context, endpoint string, out interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build TAXII request: %w", err)
+	}
+	req.Header.Set("Accept", MediaType)
+	switch {
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	case c.Password != "":
+		req.Header.Set("Authorization", "Bearer "+c.Password)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("TAXII request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("TAXII server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(out); err != nil {
+		return nil, fmt.Errorf("invalid TAXII response: %w", err)
+	}
+	return resp.Header, nil
+}
+
+// Poller polls the TAXII collections of threat feeds
+type Poller struct {
+	PageSize int
+	Client   *http.Client
+}
+
+// NewPoller creates a poller of threat feeds
+func NewPoller() *Poller {
+	return &Poller{PageSize: 500, Client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// PollFeed reads the threats added to the feed's collection since its last
+// poll
+// Implements the ThreatFeedProvider interface
+func (p *Poller) PollFeed(ctx context.Context, feed domain.ThreatFeed) (*ports.ThreatIntel, *time.Time, error) {
+	client := NewClient(feed.APIRoot, feed.Username, feed.Password)
+	client.PageSize = p.PageSize
+	if p.Client != nil {
+		client.Client = p.Client
+	}
+	objects, last, err := client.Objects(ctx, feed.CollectionID, feed.AddedAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	intel, err := stix.Parse(objects)
+	if err != nil {
+		return nil, nil, err
+	}
+	return intel, last, nil
+}
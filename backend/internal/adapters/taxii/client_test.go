@@ -0,0 +1,92 @@
+package taxii
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taxiiServer serves a collection of three indicators, two per page
+func taxiiServer(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api1/collections/", func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "reader" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, MediaType, r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", MediaType)
+
+		if r.URL.Path == "/api1/collections/" {
+			_ = json.NewEncoder(w).Encode(Collections{Collections: []Collection{{ID: "feed", Title: "Feed", CanRead: true}}})
+			return
+		}
+		require.Equal(t, "/api1/collections/feed/objects/", r.URL.Path)
+		queries = append(queries, r.URL.RawQuery)
+
+		indicator := func(id string) json.RawMessage {
+			return json.RawMessage(`{"type":"indicator","spec_version":"2.1","id":"indicator--` + id + `","name":"` + id + `","pattern":"[x]","pattern_type":"stix","created":"2024-01-01T00:00:00Z","modified":"2024-01-01T00:00:00Z"}`)
+		}
+		if r.URL.Query().Get("next") == "" {
+			w.Header().Set(HeaderDateAddedLast, "2024-06-01T00:00:00.000Z")
+			_ = json.NewEncoder(w).Encode(Envelope{More: true, Next: "page2", Objects: []json.RawMessage{indicator("a"), indicator("b")}})
+			return
+		}
+		w.Header().Set(HeaderDateAddedLast, "2024-06-02T00:00:00.000Z")
+		_ = json.NewEncoder(w).Encode(Envelope{Objects: []json.RawMessage{indicator("c")}})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &queries
+}
+
+func TestClientObjectsFollowsPages(t *testing.T) {
+	server, queries := taxiiServer(t)
+	client := NewClient(server.URL+"/api1", "reader", "secret")
+	client.PageSize = 2
+
+	collections, err := client.Collections(context.Background())
+	require.NoError(t, err)
+	require.Len(t, collections, 1)
+	assert.Equal(t, "feed", collections[0].ID)
+
+	after := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	objects, last, err := client.Objects(context.Background(), "feed", &after)
+	require.NoError(t, err)
+	assert.Len(t, objects, 3)
+	require.NotNil(t, last)
+	assert.Equal(t, time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC), *last)
+
+	require.Len(t, *queries, 2)
+	assert.Contains(t, (*queries)[0], "added_after=2024-05-01T00%3A00%3A00Z")
+	assert.Contains(t, (*queries)[0], "limit=2")
+	assert.Contains(t, (*queries)[1], "next=page2")
+}
+
+func TestClientReportsErrors(t *testing.T) {
+	server, _ := taxiiServer(t)
+	_, _, err := NewClient(server.URL+"/api1", "reader", "wrong").Objects(context.Background(), "feed", nil)
+	assert.ErrorContains(t, err, "status 401")
+}
+
+func TestPollerPollFeed(t *testing.T) {
+	server, _ := taxiiServer(t)
+	intel, last, err := NewPoller().PollFeed(context.Background(), domain.ThreatFeed{
+		APIRoot: server.URL + "/api1/", CollectionID: "feed", Username: "reader", Password: "secret",
+	})
+	require.NoError(t, err)
+	require.Len(t, intel.Threats, 3)
+	assert.Equal(t, domain.ThreatIndicator, intel.Threats[0].Type)
+	assert.Equal(t, "indicator--c", intel.Threats[2].STIXID)
+	require.NotNil(t, last)
+	assert.Equal(t, 2024, last.Year())
+}
@@ -0,0 +1,63 @@
+// Package taxii holds the TAXII 2.1 resources OpenRisk serves and a client
+// polling the collections of other TAXII 2.1 servers.
+package taxii
+
+import (
+	"encoding/json"
+)
+
+// MediaType is the content type of TAXII 2.1 resources
+const MediaType = "application/taxii+json;version=2.1"
+
+// Headers of the objects resource telling which dates of addition the
+// returned objects span
+const (
+	HeaderDateAddedFirst = "X-TAXII-Date-Added-First"
+	HeaderDateAddedLast  = "X-TAXII-Date-Added-Last"
+)
+
+// Discovery is the server discovery resource
+type Discovery struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	APIRoots    []string `json:"api_roots"`
+}
+
+// APIRoot describes an API root
+type APIRoot struct {
+	Title            string   `json:"title"`
+	Description      string   `json:"description,omitempty"`
+	Versions         []string `json:"versions"`
+	MaxContentLength int      `json:"max_content_length"`
+}
+
+// Collection describes a collection
+type Collection struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	CanRead     bool     `json:"can_read"`
+	CanWrite    bool     `json:"can_write"`
+	MediaTypes  []string `json:"media_types,omitempty"`
+}
+
+// Collections lists the collections of an API root
+type Collections struct {
+	Collections []Collection `json:"collections"`
+}
+
+// Envelope carries a page of the objects of a collection; Next fetches the
+// following page while More is set
+type Envelope struct {
+	More    bool              `json:"more"`
+	Next    string            `json:"next,omitempty"`
+	Objects []json.RawMessage `json:"objects"`
+}
+
+// Error is a TAXII error message
+type Error struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	HTTPStatus  string `json:"http_status,omitempty"`
+}
@@ -0,0 +1,98 @@
+package vulnfeeds
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// ReadEPSS reads an EPSS score file (epss_scores-YYYY-MM-DD.csv, usually
+// gzipped): an optional "#model_version:...,score_date:..." line, then the
+// cve,epss,percentile columns
+func ReadEPSS(r io.Reader) (*Feed, error) {
+	content, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(content)
+	feed := &Feed{VulnFeedImport: domain.VulnFeedImport{Source: domain.FeedEPSS}}
+
+	var model, scoreDate string
+	if b, err := firstByte(buffered); err == nil && b == '#' {
+		line, err := buffered.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("invalid EPSS CSV: %w", err)
+		}
+		for _, part := range strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "#")), ",") {
+			key, value, _ := strings.Cut(part, ":")
+			switch strings.TrimSpace(key) {
+			case "model_version":
+				model = strings.TrimSpace(value)
+			case "score_date":
+				scoreDate = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid EPSS CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	cveCol, ok1 := columns["cve"]
+	epssCol, ok2 := columns["epss"]
+	percentileCol, ok3 := columns["percentile"]
+	if !ok1 || !ok2 || !ok3 {
+		return nil, errors.New("invalid EPSS CSV: expected cve, epss and percentile columns")
+	}
+	dateCol, hasDate := columns["date"] // Per-row date of API exports
+
+	scored := parseTime(scoreDate)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid EPSS CSV: %w", err)
+		}
+		if len(row) <= max(cveCol, epssCol, percentileCol) || row[cveCol] == "" {
+			continue
+		}
+		epss, err1 := strconv.ParseFloat(strings.TrimSpace(row[epssCol]), 64)
+		percentile, err2 := strconv.ParseFloat(strings.TrimSpace(row[percentileCol]), 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid EPSS CSV: line %d: invalid score", line)
+		}
+		date := scored
+		if hasDate && dateCol < len(row) {
+			if t := parseTime(row[dateCol]); t != nil {
+				date = t
+				if scoreDate == "" {
+					scoreDate = row[dateCol]
+				}
+			}
+		}
+		feed.Records = append(feed.Records, domain.CVERecord{
+			ID:             strings.ToUpper(strings.TrimSpace(row[cveCol])),
+			EPSS:           &epss,
+			EPSSPercentile: &percentile,
+			EPSSDate:       date,
+		})
+	}
+	feed.Version = strings.TrimSpace(model + " " + scoreDate)
+	feed.RecordCount = len(feed.Records)
+	return feed, nil
+}
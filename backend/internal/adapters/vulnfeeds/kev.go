@@ -0,0 +1,128 @@
+package vulnfeeds
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+type kevEntry struct {
+	CVEID                      string `json:"cveID"`
+	VulnerabilityName          string `json:"vulnerabilityName"`
+	DateAdded                  string `json:"dateAdded"`
+	DueDate                    string `json:"dueDate"`
+	KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse"`
+}
+
+// ReadKEV reads the CISA KEV catalog, as published in JSON
+// (known_exploited_vulnerabilities.json) or CSV. The catalog is complete:
+// CVEs it does not list are no longer known exploited.
+func ReadKEV(r io.Reader) (*Feed, error) {
+	content, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(content)
+	first, err := firstByte(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KEV catalog: %w", err)
+	}
+
+	feed := &Feed{VulnFeedImport: domain.VulnFeedImport{Source: domain.FeedKEV}}
+	var entries []kevEntry
+	if first == '{' {
+		var catalog struct {
+			CatalogVersion  string     `json:"catalogVersion"`
+			Vulnerabilities []kevEntry `json:"vulnerabilities"`
+		}
+		if err := json.NewDecoder(buffered).Decode(&catalog); err != nil {
+			return nil, fmt.Errorf("invalid KEV JSON: %w", err)
+		}
+		if catalog.Vulnerabilities == nil {
+			return nil, errors.New("invalid KEV JSON: no vulnerabilities")
+		}
+		feed.Version = catalog.CatalogVersion
+		entries = catalog.Vulnerabilities
+	} else {
+		entries, err = readKEVCSV(buffered)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range entries {
+		if e.CVEID == "" {
+			continue
+		}
+		feed.Records = append(feed.Records, domain.CVERecord{
+			ID:            strings.ToUpper(strings.TrimSpace(e.CVEID)),
+			KEV:           true,
+			KEVName:       e.VulnerabilityName,
+			KEVDateAdded:  parseTime(e.DateAdded),
+			KEVDueDate:    parseTime(e.DueDate),
+			KEVRansomware: strings.EqualFold(e.KnownRansomwareCampaignUse, "Known"),
+		})
+	}
+	feed.RecordCount = len(feed.Records)
+	return feed, nil
+}
+
+func readKEVCSV(r io.Reader) ([]kevEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid KEV CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := columns["cveID"]; !ok {
+		return nil, errors.New("invalid KEV CSV: missing cveID column")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var entries []kevEntry
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid KEV CSV: %w", err)
+		}
+		entries = append(entries, kevEntry{
+			CVEID:                      field(row, "cveID"),
+			VulnerabilityName:          field(row, "vulnerabilityName"),
+			DateAdded:                  field(row, "dateAdded"),
+			DueDate:                    field(row, "dueDate"),
+			KnownRansomwareCampaignUse: field(row, "knownRansomwareCampaignUse"),
+		})
+	}
+	return entries, nil
+}
+
+// firstByte returns the first non-blank byte without consuming it
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\n' && b != '\r' && b != '\t' {
+			return b, r.UnreadByte()
+		}
+	}
+}
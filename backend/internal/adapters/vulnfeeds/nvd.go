@@ -0,0 +1,173 @@
+package vulnfeeds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+type nvdCVSS struct {
+	Version      string  `json:"version"`
+	VectorString string  `json:"vectorString"`
+	BaseScore    float64 `json:"baseScore"`
+	BaseSeverity string  `json:"baseSeverity"`
+}
+
+type nvdMetric struct {
+	Type         string  `json:"type"` // Primary or Secondary
+	CVSSData     nvdCVSS `json:"cvssData"`
+	BaseSeverity string  `json:"baseSeverity"` // CVSS v2 keeps it outside cvssData
+}
+
+type nvdDescription struct {
+	Lang  string `json:"lang"`
+	Value string `json:"value"`
+}
+
+// nvdDocument is an NVD API 2.0 response or a legacy 1.1 JSON feed
+type nvdDocument struct {
+	Timestamp       string `json:"timestamp"`
+	Vulnerabilities []struct {
+		CVE struct {
+			ID           string           `json:"id"`
+			Published    string           `json:"published"`
+			LastModified string           `json:"lastModified"`
+			VulnStatus   string           `json:"vulnStatus"`
+			Descriptions []nvdDescription `json:"descriptions"`
+			Metrics      struct {
+				V40 []nvdMetric `json:"cvssMetricV40"`
+				V31 []nvdMetric `json:"cvssMetricV31"`
+				V30 []nvdMetric `json:"cvssMetricV30"`
+				V2  []nvdMetric `json:"cvssMetricV2"`
+			} `json:"metrics"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+
+	LegacyTimestamp string `json:"CVE_data_timestamp"`
+	Items           []struct {
+		CVE struct {
+			Meta struct {
+				ID string `json:"ID"`
+			} `json:"CVE_data_meta"`
+			Description struct {
+				Data []nvdDescription `json:"description_data"`
+			} `json:"description"`
+		} `json:"cve"`
+		Impact struct {
+			V3 struct {
+				CVSS nvdCVSS `json:"cvssV3"`
+			} `json:"baseMetricV3"`
+			V2 struct {
+				CVSS     nvdCVSS `json:"cvssV2"`
+				Severity string  `json:"severity"`
+			} `json:"baseMetricV2"`
+		} `json:"impact"`
+		PublishedDate    string `json:"publishedDate"`
+		LastModifiedDate string `json:"lastModifiedDate"`
+	} `json:"CVE_Items"`
+}
+
+// ReadNVD reads an NVD API 2.0 response or a legacy 1.1 feed. The CVSS
+// retained is the primary v3.1 one, else v4.0, v3.0 and v2. Rejected CVEs
+// are skipped.
+func ReadNVD(r io.Reader) (*Feed, error) {
+	content, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	var doc nvdDocument
+	if err := json.NewDecoder(content).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid NVD JSON: %w", err)
+	}
+	if doc.Vulnerabilities == nil && doc.Items == nil {
+		return nil, errors.New("invalid NVD JSON: no vulnerabilities nor CVE_Items")
+	}
+
+	feed := &Feed{VulnFeedImport: domain.VulnFeedImport{Source: domain.FeedNVD, Version: firstNonEmpty(doc.Timestamp, doc.LegacyTimestamp)}}
+	for _, v := range doc.Vulnerabilities {
+		cve := v.CVE
+		if cve.ID == "" || strings.EqualFold(cve.VulnStatus, "Rejected") {
+			continue
+		}
+		record := domain.CVERecord{
+			ID:           strings.ToUpper(cve.ID),
+			Description:  englishDescription(cve.Descriptions),
+			Published:    parseTime(cve.Published),
+			LastModified: parseTime(cve.LastModified),
+		}
+		for _, metrics := range [][]nvdMetric{cve.Metrics.V31, cve.Metrics.V40, cve.Metrics.V30, cve.Metrics.V2} {
+			if m, ok := primaryMetric(metrics); ok {
+				setCVSS(&record, m.CVSSData, m.BaseSeverity)
+				break
+			}
+		}
+		feed.Records = append(feed.Records, record)
+	}
+	for _, item := range doc.Items {
+		if item.CVE.Meta.ID == "" || strings.HasPrefix(englishDescription(item.CVE.Description.Data), "** REJECT **") {
+			continue
+		}
+		record := domain.CVERecord{
+			ID:           strings.ToUpper(item.CVE.Meta.ID),
+			Description:  englishDescription(item.CVE.Description.Data),
+			Published:    parseTime(item.PublishedDate),
+			LastModified: parseTime(item.LastModifiedDate),
+		}
+		switch {
+		case item.Impact.V3.CVSS.VectorString != "":
+			setCVSS(&record, item.Impact.V3.CVSS, "")
+		case item.Impact.V2.CVSS.VectorString != "":
+			setCVSS(&record, item.Impact.V2.CVSS, item.Impact.V2.Severity)
+		}
+		feed.Records = append(feed.Records, record)
+	}
+	feed.RecordCount = len(feed.Records)
+	return feed, nil
+}
+
+// primaryMetric returns the metric scored by NVD itself, else the first one
+func primaryMetric(metrics []nvdMetric) (nvdMetric, bool) {
+	for _, m := range metrics {
+		if strings.EqualFold(m.Type, "Primary") && m.CVSSData.VectorString != "" {
+			return m, true
+		}
+	}
+	for _, m := range metrics {
+		if m.CVSSData.VectorString != "" {
+			return m, true
+		}
+	}
+	return nvdMetric{}, false
+}
+
+func setCVSS(record *domain.CVERecord, cvss nvdCVSS, severity string) {
+	record.CVSSVector = cvss.VectorString
+	record.CVSSVersion = cvss.Version
+	record.CVSSScore = cvss.BaseScore
+	record.CVSSSeverity = strings.ToUpper(firstNonEmpty(cvss.BaseSeverity, severity))
+}
+
+func englishDescription(descriptions []nvdDescription) string {
+	for _, d := range descriptions {
+		if strings.EqualFold(d.Lang, "en") {
+			return d.Value
+		}
+	}
+	if len(descriptions) > 0 {
+		return descriptions[0].Value
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
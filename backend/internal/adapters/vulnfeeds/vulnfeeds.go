@@ -0,0 +1,154 @@
+// Package vulnfeeds reads the offline feeds enriching CVEs: NVD CVE JSON
+// (API 2.0 responses and legacy 1.1 yearly feeds), the CISA Known Exploited
+// Vulnerabilities catalog (JSON or CSV) and FIRST EPSS score CSVs. Files may
+// be gzipped.
+package vulnfeeds
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// ErrUnknownFeed is returned for a feed other than NVD, KEV or EPSS
+var ErrUnknownFeed = errors.New("unknown vulnerability feed (expected nvd, kev or epss)")
+
+// Feed is a loaded feed file: its import record and the CVE records it
+// fills, with only the columns of its source set
+type Feed struct {
+	domain.VulnFeedImport
+	Records []domain.CVERecord
+}
+
+// Read reads a feed file of a source (NVD, KEV or EPSS, case-insensitive)
+func Read(source string, r io.Reader) (*Feed, error) {
+	switch strings.ToUpper(source) {
+	case domain.FeedNVD:
+		return ReadNVD(r)
+	case domain.FeedKEV:
+		return ReadKEV(r)
+	case domain.FeedEPSS:
+		return ReadEPSS(r)
+	}
+	return nil, ErrUnknownFeed
+}
+
+// LoadDir loads a feed mirror: every file of nvd/ (yearly or paged
+// dumps), and the most recent file of kev/ and of epss/. Missing
+// subdirectories are skipped.
+func LoadDir(dir string) ([]*Feed, error) {
+	var feeds []*Feed
+	for _, source := range []string{domain.FeedNVD, domain.FeedKEV, domain.FeedEPSS} {
+		files, err := feedFiles(filepath.Join(dir, strings.ToLower(source)))
+		if err != nil {
+			return nil, err
+		}
+		if source != domain.FeedNVD && len(files) > 1 {
+			files = files[len(files)-1:]
+		}
+		for _, path := range files {
+			feed, err := loadFile(source, path)
+			if err != nil {
+				return nil, err
+			}
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds, nil
+}
+
+// feedFiles lists the .json, .csv and .gz files of a directory, oldest first
+func feedFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed directory: %w", err)
+	}
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	var files []file
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".json" && ext != ".csv" && ext != ".gz") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feed directory: %w", err)
+		}
+		files = append(files, file{filepath.Join(dir, entry.Name()), info.ModTime()})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].path < files[j].path
+	})
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
+
+func loadFile(source, path string) (*Feed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s feed: %w", source, err)
+	}
+	defer f.Close()
+	feed, err := Read(source, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	feed.FileName = filepath.Base(path)
+	return feed, nil
+}
+
+// decompress returns a reader of the content, gunzipped when gzipped
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		return gz, nil
+	}
+	return buffered, nil
+}
+
+// parseTime reads the timestamps of the feeds, with or without zone
+func parseTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05-0700",
+		"2006-01-02",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package vulnfeeds
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nvdAPI = `{
+  "resultsPerPage": 2, "format": "NVD_CVE", "version": "2.0", "timestamp": "2024-01-02T10:00:00.000",
+  "vulnerabilities": [
+    {"cve": {"id": "CVE-2021-44228", "published": "2021-12-10T10:15:09.143", "lastModified": "2023-11-07T03:39:36.747", "vulnStatus": "Analyzed",
+      "descriptions": [{"lang": "es", "value": "Log4j2..."}, {"lang": "en", "value": "Apache Log4j2 JNDI features do not protect against attacker controlled LDAP."}],
+      "metrics": {
+        "cvssMetricV31": [
+          {"source": "vendor", "type": "Secondary", "cvssData": {"version": "3.1", "vectorString": "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:C/C:H/I:H/A:H", "baseScore": 9.0, "baseSeverity": "CRITICAL"}},
+          {"source": "nvd@nist.gov", "type": "Primary", "cvssData": {"version": "3.1", "vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", "baseScore": 10.0, "baseSeverity": "CRITICAL"}}
+        ],
+        "cvssMetricV2": [{"type": "Primary", "cvssData": {"version": "2.0", "vectorString": "AV:N/AC:M/Au:N/C:C/I:C/A:C", "baseScore": 9.3}, "baseSeverity": "HIGH"}]
+      }}},
+    {"cve": {"id": "CVE-2020-0001", "vulnStatus": "Analyzed", "descriptions": [{"lang": "en", "value": "Old"}],
+      "metrics": {"cvssMetricV2": [{"type": "Primary", "cvssData": {"version": "2.0", "vectorString": "AV:L/AC:L/Au:N/C:P/I:P/A:P", "baseScore": 4.6}, "baseSeverity": "MEDIUM"}]}}},
+    {"cve": {"id": "CVE-2020-9999", "vulnStatus": "Rejected", "descriptions": [{"lang": "en", "value": "Rejected reason"}]}}
+  ]
+}`
+
+func TestReadNVDAPI(t *testing.T) {
+	feed, err := ReadNVD(strings.NewReader(nvdAPI))
+	require.NoError(t, err)
+	assert.Equal(t, domain.FeedNVD, feed.Source)
+	assert.Equal(t, "2024-01-02T10:00:00.000", feed.Version)
+	require.Len(t, feed.Records, 2, "rejected CVEs are skipped")
+	assert.Equal(t, 2, feed.RecordCount)
+
+	log4shell := feed.Records[0]
+	assert.Equal(t, "CVE-2021-44228", log4shell.ID)
+	assert.Equal(t, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", log4shell.CVSSVector, "the primary metric is retained")
+	assert.Equal(t, 10.0, log4shell.CVSSScore)
+	assert.Equal(t, "CRITICAL", log4shell.CVSSSeverity)
+	assert.Contains(t, log4shell.Description, "JNDI")
+	require.NotNil(t, log4shell.Published)
+	assert.Equal(t, time.Date(2021, 12, 10, 10, 15, 9, 143000000, time.UTC), *log4shell.Published)
+
+	old := feed.Records[1]
+	assert.Equal(t, "2.0", old.CVSSVersion)
+	assert.Equal(t, "MEDIUM", old.CVSSSeverity)
+}
+
+func TestReadNVDLegacyGzipped(t *testing.T) {
+	legacy := `{"CVE_data_type": "CVE", "CVE_data_timestamp": "2021-12-12T08:00Z", "CVE_Items": [
+	  {"cve": {"CVE_data_meta": {"ID": "CVE-2021-44228"}, "description": {"description_data": [{"lang": "en", "value": "Log4Shell"}]}},
+	   "impact": {"baseMetricV3": {"cvssV3": {"version": "3.1", "vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", "baseScore": 10.0, "baseSeverity": "CRITICAL"}}},
+	   "publishedDate": "2021-12-10T10:15Z", "lastModifiedDate": "2021-12-12T03:15Z"},
+	  {"cve": {"CVE_data_meta": {"ID": "CVE-2021-0002"}, "description": {"description_data": [{"lang": "en", "value": "** REJECT ** Duplicate"}]}}}
+	]}`
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(legacy))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	feed, err := Read("nvd", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-12-12T08:00Z", feed.Version)
+	require.Len(t, feed.Records, 1)
+	assert.Equal(t, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", feed.Records[0].CVSSVector)
+	require.NotNil(t, feed.Records[0].Published)
+	assert.Equal(t, time.Date(2021, 12, 10, 10, 15, 0, 0, time.UTC), *feed.Records[0].Published)
+}
+
+func TestReadKEV(t *testing.T) {
+	catalog := `{"title": "CISA Catalog of Known Exploited Vulnerabilities", "catalogVersion": "2024.01.02", "count": 2, "vulnerabilities": [
+	  {"cveID": "CVE-2021-44228", "vendorProject": "Apache", "product": "Log4j2", "vulnerabilityName": "Apache Log4j2 Remote Code Execution Vulnerability",
+	   "dateAdded": "2021-12-10", "dueDate": "2021-12-24", "knownRansomwareCampaignUse": "Known"},
+	  {"cveID": "CVE-2023-0001", "vulnerabilityName": "Other", "dateAdded": "2023-01-05", "dueDate": "2023-01-26", "knownRansomwareCampaignUse": "Unknown"}
+	]}`
+	feed, err := ReadKEV(strings.NewReader(catalog))
+	require.NoError(t, err)
+	assert.Equal(t, "2024.01.02", feed.Version)
+	require.Len(t, feed.Records, 2)
+	log4shell := feed.Records[0]
+	assert.True(t, log4shell.KEV)
+	assert.True(t, log4shell.KEVRansomware)
+	require.NotNil(t, log4shell.KEVDateAdded)
+	assert.Equal(t, "2021-12-10", log4shell.KEVDateAdded.Format("2006-01-02"))
+	assert.False(t, feed.Records[1].KEVRansomware)
+
+	csvCatalog := "\ufeffcveID,vendorProject,product,vulnerabilityName,dateAdded,shortDescription,requiredAction,dueDate,knownRansomwareCampaignUse,notes\n" +
+		"CVE-2021-44228,Apache,Log4j2,\"Apache Log4j2 Remote Code Execution Vulnerability\",2021-12-10,\"JNDI, LDAP\",Apply updates,2021-12-24,Known,\n"
+	feed, err = ReadKEV(strings.NewReader(csvCatalog))
+	require.NoError(t, err)
+	require.Len(t, feed.Records, 1)
+	assert.Equal(t, "Apache Log4j2 Remote Code Execution Vulnerability", feed.Records[0].KEVName)
+	assert.True(t, feed.Records[0].KEVRansomware)
+
+	_, err = ReadKEV(strings.NewReader("cve,epss\n"))
+	assert.Error(t, err)
+}
+
+func TestReadEPSS(t *testing.T) {
+	scores := "#model_version:v2023.03.01,score_date:2024-01-02T00:00:00+0000\n" +
+		"cve,epss,percentile\n" +
+		"CVE-2021-44228,0.97565,0.99996\n" +
+		"cve-1999-0001,0.01130,0.83212\n"
+	feed, err := ReadEPSS(strings.NewReader(scores))
+	require.NoError(t, err)
+	assert.Equal(t, "v2023.03.01 2024-01-02T00:00:00+0000", feed.Version)
+	require.Len(t, feed.Records, 2)
+	assert.Equal(t, "CVE-2021-44228", feed.Records[0].ID)
+	assert.InDelta(t, 0.97565, *feed.Records[0].EPSS, 1e-9)
+	assert.InDelta(t, 0.99996, *feed.Records[0].EPSSPercentile, 1e-9)
+	require.NotNil(t, feed.Records[0].EPSSDate)
+	assert.Equal(t, "2024-01-02", feed.Records[0].EPSSDate.Format("2006-01-02"))
+	assert.Equal(t, "CVE-1999-0001", feed.Records[1].ID)
+
+	_, err = ReadEPSS(strings.NewReader("cve,epss,percentile\nCVE-2021-1,high,0.5\n"))
+	assert.Error(t, err)
+	_, err = ReadEPSS(strings.NewReader("cve,score\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"nvd", "epss"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nvd", "nvdcve-2021.json"), []byte(nvdAPI), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nvd", "README.md"), []byte("ignored"), 0o600))
+	older := filepath.Join(dir, "epss", "epss_scores-2024-01-01.csv")
+	require.NoError(t, os.WriteFile(older, []byte("cve,epss,percentile\nCVE-2021-44228,0.5,0.9\n"), 0o600))
+	require.NoError(t, os.Chtimes(older, time.Now().Add(-24*time.Hour), time.Now().Add(-24*time.Hour)))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "epss", "epss_scores-2024-01-02.csv"), []byte("cve,epss,percentile\nCVE-2021-44228,0.9,0.99\nCVE-2021-1,0.1,0.2\n"), 0o600))
+
+	feeds, err := LoadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, feeds, 2, "no kev directory; only the latest EPSS file")
+	assert.Equal(t, domain.FeedNVD, feeds[0].Source)
+	assert.Equal(t, "nvdcve-2021.json", feeds[0].FileName)
+	assert.Equal(t, domain.FeedEPSS, feeds[1].Source)
+	assert.Equal(t, "epss_scores-2024-01-02.csv", feeds[1].FileName)
+	assert.Len(t, feeds[1].Records, 2)
+
+	_, err = Read("osv", strings.NewReader("{}"))
+	assert.ErrorIs(t, err, ErrUnknownFeed)
+}
@@ -198,10 +198,7 @@ go func() {
 for {
 select {
 case <-pm.ticker.C:
-result := pm.phc.PerformHealthCheck(ctx)
-if !result.Healthy && pm.onUnhealthy != nil {
-pm.onUnhealthy(result)
-}
+pm.Check(ctx)
 case <-pm.stop:
 pm.ticker.Stop()
 return
@@ -210,6 +207,21 @@ return
 }()
 }
 
+// Check performs a single health check, e.g. when driven by the cluster scheduler
+func (pm *PoolMonitor) Check(ctx context.Context) error {
+result := pm.phc.PerformHealthCheck(ctx)
+if !result.Healthy {
+if pm.onUnhealthy != nil {
+pm.onUnhealthy(result)
+}
+if result.Error != nil {
+return result.Error
+}
+return fmt.Errorf("connection pool unhealthy: %s", result.Message)
+}
+return nil
+}
+
 // Stop stops monitoring
 func (pm *PoolMonitor) Stop() {
 close(pm.stop)
@@ -0,0 +1,307 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ApprovalEntityType identifies what kind of record an approval chain governs
+type ApprovalEntityType string
+
+const (
+	ApprovalEntityDecision      ApprovalEntityType = "RISK_DECISION"
+	ApprovalEntityTreatmentPlan ApprovalEntityType = "TREATMENT_PLAN"
+	ApprovalEntityException     ApprovalEntityType = "RISK_EXCEPTION"
+)
+
+// ApprovalQuorum defines how many approvers of a step must approve
+type ApprovalQuorum string
+
+const (
+	QuorumAll      ApprovalQuorum = "ALL"      // Every resolved approver must approve
+	QuorumAny      ApprovalQuorum = "ANY"      // A single approval is enough
+	QuorumMajority ApprovalQuorum = "MAJORITY" // More than half of the approvers
+)
+
+// ApprovalRequestStatus represents the lifecycle of an approval request
+type ApprovalRequestStatus string
+
+const (
+	ApprovalRequestPending   ApprovalRequestStatus = "PENDING"
+	ApprovalRequestApproved  ApprovalRequestStatus = "APPROVED"
+	ApprovalRequestRejected  ApprovalRequestStatus = "REJECTED"
+	ApprovalRequestCancelled ApprovalRequestStatus = "CANCELLED"
+)
+
+// ApprovalTaskStatus represents the lifecycle of a single approval step
+type ApprovalTaskStatus string
+
+const (
+	ApprovalTaskWaiting  ApprovalTaskStatus = "WAITING" // Stage not reached yet
+	ApprovalTaskPending  ApprovalTaskStatus = "PENDING" // Awaiting votes
+	ApprovalTaskApproved ApprovalTaskStatus = "APPROVED"
+	ApprovalTaskRejected ApprovalTaskStatus = "REJECTED"
+	ApprovalTaskSkipped  ApprovalTaskStatus = "SKIPPED" // Request closed before the stage was reached
+)
+
+// ApprovalVoteDecision is the answer an approver gives on a task
+type ApprovalVoteDecision string
+
+const (
+	VoteApprove ApprovalVoteDecision = "APPROVE"
+	VoteReject  ApprovalVoteDecision = "REJECT"
+)
+
+// ApproverRoleRiskOwner is a pseudo role resolved to the risk owner of the register entry
+const ApproverRoleRiskOwner = "risk_owner"
+
+// riskLevelRank orders risk levels so workflows can apply from a minimum level upwards
+var riskLevelRank = map[string]int{
+	"LOW":      1,
+	"MEDIUM":   2,
+	"HIGH":     3,
+	"CRITICAL": 4,
+}
+
+// RiskLevelRank returns the ordinal of a risk level (0 when unknown)
+func RiskLevelRank(level string) int {
+	return riskLevelRank[strings.ToUpper(level)]
+}
+
+// ApprovalStepDefinition describes one step of an approval chain.
+// Steps sharing the same Stage run in parallel; stages run sequentially in ascending order.
+type ApprovalStepDefinition struct {
+	Name            string         `json:"name"`
+	Stage           int            `json:"stage"`
+	ApproverRoles   []string       `json:"approver_roles,omitempty"` // Role names, or "risk_owner"
+	ApproverUsers   []uuid.UUID    `json:"approver_users,omitempty"`
+	Quorum          ApprovalQuorum `json:"quorum"`
+	MinApprovals    int            `json:"min_approvals,omitempty"` // Overrides Quorum when > 0
+	TimeoutHours    int            `json:"timeout_hours,omitempty"` // 0 = no timeout
+	EscalateToRoles []string       `json:"escalate_to_roles,omitempty"`
+	EscalateToUsers []uuid.UUID    `json:"escalate_to_users,omitempty"`
+}
+
+// Validate checks that a step can be resolved to at least one approver
+func (s ApprovalStepDefinition) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("step name is required")
+	}
+	if s.Stage < 1 {
+		return fmt.Errorf("step %q: stage must be >= 1", s.Name)
+	}
+	if len(s.ApproverRoles) == 0 && len(s.ApproverUsers) == 0 {
+		return fmt.Errorf("step %q: at least one approver role or user is required", s.Name)
+	}
+	switch s.Quorum {
+	case QuorumAll, QuorumAny, QuorumMajority:
+	case "":
+		if s.MinApprovals <= 0 {
+			return fmt.Errorf("step %q: quorum or min_approvals is required", s.Name)
+		}
+	default:
+		return fmt.Errorf("step %q: invalid quorum %q", s.Name, s.Quorum)
+	}
+	if s.MinApprovals < 0 || s.TimeoutHours < 0 {
+		return fmt.Errorf("step %q: min_approvals and timeout_hours must be positive", s.Name)
+	}
+	return nil
+}
+
+// RequiredApprovals computes how many approvals a step needs given the number of eligible approvers
+func (s ApprovalStepDefinition) RequiredApprovals(eligible int) int {
+	if s.MinApprovals > 0 {
+		return s.MinApprovals
+	}
+	switch s.Quorum {
+	case QuorumAny:
+		return 1
+	case QuorumMajority:
+		return eligible/2 + 1
+	default:
+		if eligible == 0 {
+			return 1
+		}
+		return eligible
+	}
+}
+
+// ApprovalWorkflow is a per-tenant approval chain configuration for a type of decision
+type ApprovalWorkflow struct {
+	ID          uuid.UUID          `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    uuid.UUID          `gorm:"type:uuid;index" json:"tenant_id"`
+	Name        string             `gorm:"size:255;not null" json:"name"`
+	Description string             `gorm:"type:text" json:"description"`
+	EntityType  ApprovalEntityType `gorm:"size:50;not null;index" json:"entity_type"`
+
+	// Matching conditions (empty = any)
+	DecisionType string `gorm:"size:100;index" json:"decision_type,omitempty"` // e.g. RISK_ACCEPTANCE
+	MinRiskLevel string `gorm:"size:50" json:"min_risk_level,omitempty"`       // LOW, MEDIUM, HIGH, CRITICAL
+	Priority     int    `gorm:"default:0" json:"priority"`                     // Highest priority wins on overlap
+
+	Steps    datatypes.JSON `gorm:"type:jsonb;not null" json:"steps"` // []ApprovalStepDefinition
+	IsActive bool           `gorm:"default:true;index" json:"is_active"`
+
+	CreatedBy uuid.UUID      `gorm:"type:uuid" json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// StepDefinitions decodes the configured steps
+func (w *ApprovalWorkflow) StepDefinitions() ([]ApprovalStepDefinition, error) {
+	var steps []ApprovalStepDefinition
+	if len(w.Steps) == 0 {
+		return steps, nil
+	}
+	if err := json.Unmarshal(w.Steps, &steps); err != nil {
+		return nil, fmt.Errorf("invalid approval steps: %w", err)
+	}
+	return steps, nil
+}
+
+// Matches reports whether the workflow applies to a given decision
+func (w *ApprovalWorkflow) Matches(entityType ApprovalEntityType, decisionType string, riskLevel string) bool {
+	if !w.IsActive || w.EntityType != entityType {
+		return false
+	}
+	if w.DecisionType != "" && !strings.EqualFold(w.DecisionType, decisionType) {
+		return false
+	}
+	if w.MinRiskLevel != "" && RiskLevelRank(riskLevel) < RiskLevelRank(w.MinRiskLevel) {
+		return false
+	}
+	return true
+}
+
+// ApprovalRequest is a running instance of an approval workflow for a specific record
+type ApprovalRequest struct {
+	ID             uuid.UUID             `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID       uuid.UUID             `gorm:"type:uuid;index" json:"tenant_id"`
+	WorkflowID     uuid.UUID             `gorm:"type:uuid;index;not null" json:"workflow_id"`
+	EntityType     ApprovalEntityType    `gorm:"size:50;not null;index:idx_approval_entity" json:"entity_type"`
+	EntityID       uuid.UUID             `gorm:"type:uuid;not null;index:idx_approval_entity" json:"entity_id"`
+	RiskRegisterID uuid.UUID             `gorm:"type:uuid;index" json:"risk_register_id"`
+	DecisionType   string                `gorm:"size:100" json:"decision_type"`
+	RiskLevel      string                `gorm:"size:50" json:"risk_level"`
+	Status         ApprovalRequestStatus `gorm:"size:50;index;default:'PENDING'" json:"status"`
+	CurrentStage   int                   `json:"current_stage"`
+	RequestedBy    uuid.UUID             `gorm:"type:uuid;not null" json:"requested_by"`
+	CompletedAt    *time.Time            `json:"completed_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+
+	Tasks []ApprovalTask `gorm:"foreignKey:RequestID" json:"tasks,omitempty"`
+}
+
+// ApprovalTask is one step of an approval request with its resolved approvers
+type ApprovalTask struct {
+	ID                uuid.UUID          `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	RequestID         uuid.UUID          `gorm:"type:uuid;index;not null" json:"request_id"`
+	TenantID          uuid.UUID          `gorm:"type:uuid;index" json:"tenant_id"`
+	StepName          string             `gorm:"size:255;not null" json:"step_name"`
+	Stage             int                `gorm:"index" json:"stage"`
+	Approvers         pq.StringArray     `gorm:"type:text[]" json:"approvers"` // Resolved user IDs
+	RequiredApprovals int                `json:"required_approvals"`
+	TimeoutHours      int                `json:"timeout_hours"`
+	EscalateToRoles   pq.StringArray     `gorm:"type:text[]" json:"escalate_to_roles,omitempty"`
+	EscalateToUsers   pq.StringArray     `gorm:"type:text[]" json:"escalate_to_users,omitempty"`
+	Status            ApprovalTaskStatus `gorm:"size:50;index;default:'WAITING'" json:"status"`
+	ApprovalCount     int                `json:"approval_count"`
+	RejectionCount    int                `json:"rejection_count"`
+	DueAt             *time.Time         `gorm:"index" json:"due_at,omitempty"`
+	Escalated         bool               `gorm:"default:false" json:"escalated"`
+	EscalatedAt       *time.Time         `json:"escalated_at,omitempty"`
+	CompletedAt       *time.Time         `json:"completed_at,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+
+	Votes []ApprovalVote `gorm:"foreignKey:TaskID" json:"votes,omitempty"`
+}
+
+// HasApprover reports whether the user is one of the resolved approvers
+func (t *ApprovalTask) HasApprover(userID uuid.UUID) bool {
+	for _, a := range t.Approvers {
+		if a == userID.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluateApprovalTask derives the task status from the vote counts.
+// A task is rejected as soon as the remaining approvers can no longer reach the quorum.
+func EvaluateApprovalTask(required, eligible, approvals, rejections int) ApprovalTaskStatus {
+	if approvals >= required {
+		return ApprovalTaskApproved
+	}
+	if eligible-rejections < required {
+		return ApprovalTaskRejected
+	}
+	return ApprovalTaskPending
+}
+
+// ApprovalVote records one approver's answer on a task
+type ApprovalVote struct {
+	ID         uuid.UUID            `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TaskID     uuid.UUID            `gorm:"type:uuid;index;not null" json:"task_id"`
+	RequestID  uuid.UUID            `gorm:"type:uuid;index;not null" json:"request_id"`
+	ApproverID uuid.UUID            `gorm:"type:uuid;not null" json:"approver_id"`
+	OnBehalfOf *uuid.UUID           `gorm:"type:uuid" json:"on_behalf_of,omitempty"` // Set when voting as a delegate
+	Decision   ApprovalVoteDecision `gorm:"size:20;not null" json:"decision"`
+	Comment    string               `gorm:"type:text" json:"comment"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+// ApprovalDelegation lets an approver hand their approval rights to another user for a period
+type ApprovalDelegation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;index" json:"tenant_id"`
+	DelegatorID uuid.UUID  `gorm:"type:uuid;index;not null" json:"delegator_id"`
+	DelegateID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"delegate_id"`
+	ValidFrom   time.Time  `json:"valid_from"`
+	ValidUntil  time.Time  `json:"valid_until"`
+	Reason      string     `gorm:"type:text" json:"reason"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the delegation is in effect at the given time
+func (d *ApprovalDelegation) IsActive(at time.Time) bool {
+	if d.RevokedAt != nil {
+		return false
+	}
+	return !at.Before(d.ValidFrom) && at.Before(d.ValidUntil)
+}
+
+// TableName returns the table name for ApprovalWorkflow
+func (ApprovalWorkflow) TableName() string {
+	return "approval_workflows"
+}
+
+// TableName returns the table name for ApprovalRequest
+func (ApprovalRequest) TableName() string {
+	return "approval_requests"
+}
+
+// TableName returns the table name for ApprovalTask
+func (ApprovalTask) TableName() string {
+	return "approval_tasks"
+}
+
+// TableName returns the table name for ApprovalVote
+func (ApprovalVote) TableName() string {
+	return "approval_votes"
+}
+
+// TableName returns the table name for ApprovalDelegation
+func (ApprovalDelegation) TableName() string {
+	return "approval_delegations"
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -16,20 +17,90 @@ const (
 	CriticalityCritical AssetCriticality = "CRITICAL"
 )
 
+// IsValid : vérifie que la criticité fait partie des valeurs connues
+func (c AssetCriticality) IsValid() bool {
+	switch c {
+	case CriticalityLow, CriticalityMedium, CriticalityHigh, CriticalityCritical:
+		return true
+	}
+	return false
+}
+
 type Asset struct {
 	ID          uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	Name        string           `gorm:"not null" json:"name"`
 	Type        string           `json:"type"` // Server, Laptop, Database, SaaS
 	Criticality AssetCriticality `gorm:"default:'MEDIUM'" json:"criticality"`
 	Owner       string           `json:"owner"`
+	Description string           `gorm:"type:text" json:"description,omitempty"`
+	Tags        pq.StringArray   `gorm:"type:text[]" json:"tags,omitempty"`
+
+	// Criticalité effective : la plus haute entre la sienne et celle des
+	// services métier qui dépendent de l'asset, calculée pour le scoring
+	EffectiveCriticality AssetCriticality `gorm:"-" json:"effective_criticality,omitempty"`
+
+	// Hiérarchie : datacenter → cluster → host
+	ParentID *uuid.UUID `gorm:"type:uuid;index" json:"parent_id,omitempty"`
+	Children []*Asset   `gorm:"foreignKey:ParentID" json:"children,omitempty"`
 
 	// Relation Many-to-Many avec Risk
 	Risks []*Risk `gorm:"many2many:risk_assets;" json:"risks,omitempty"`
 
-	Source     string `gorm:"default:'MANUAL'" json:"source"` // MANUAL ou OPENASSET
+	Source     string `gorm:"default:'MANUAL'" json:"source"` // MANUAL, OPENASSET, AWS, AZURE, GCP ou SERVICENOW
 	ExternalID string `json:"external_id"`
 
+	// Date à laquelle l'inventaire source a signalé l'asset supprimé ou
+	// retiré ; les risques liés sont alors à revoir
+	RetiredAt *time.Time `gorm:"index" json:"retired_at,omitempty"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// AfterDelete : retire l'asset des risques liés et le place dans la corbeille.
+// Ses relations et son appartenance aux services métier sont conservées pour
+// la restauration, et supprimées à la purge.
+func (a *Asset) AfterDelete(tx *gorm.DB) (err error) {
+	return cascadeAssetDeletion(tx, a.ID)
+}
+
+// AssetRelationType : nature d'une relation orientée entre deux assets,
+// lue « source <type> cible » (ex. app depends_on database)
+type AssetRelationType string
+
+const (
+	RelationDependsOn       AssetRelationType = "depends_on"
+	RelationHosts           AssetRelationType = "hosts"
+	RelationConnectsTo      AssetRelationType = "connects_to"
+	RelationProcessesDataOf AssetRelationType = "processes_data_of"
+)
+
+// IsValid : vérifie que le type de relation est connu
+func (t AssetRelationType) IsValid() bool {
+	switch t {
+	case RelationDependsOn, RelationHosts, RelationConnectsTo, RelationProcessesDataOf:
+		return true
+	}
+	return false
+}
+
+// AssetRelationship : relation typée entre deux assets, unique par
+// (source, cible, type)
+type AssetRelationship struct {
+	ID          uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SourceID    uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_asset_relationships_edge" json:"source_id"`
+	TargetID    uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_asset_relationships_edge;index" json:"target_id"`
+	Type        AssetRelationType `gorm:"size:30;not null;uniqueIndex:idx_asset_relationships_edge" json:"type"`
+	Description string            `json:"description,omitempty"`
+
+	Source *Asset `gorm:"foreignKey:SourceID" json:"source,omitempty"`
+	Target *Asset `gorm:"foreignKey:TargetID" json:"target,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName : nom de la table des relations
+func (AssetRelationship) TableName() string {
+	return "asset_relationships"
+}
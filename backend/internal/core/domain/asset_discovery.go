@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// RiskChangeAssetRetired is the history change type recorded on the risks of
+// an asset that its inventory reports retired, which calls for their review
+const RiskChangeAssetRetired = "ASSET_RETIRED"
+
+// AssetDiscoveryStatus is the outcome of an inventory ingestion
+type AssetDiscoveryStatus string
+
+const (
+	AssetDiscoveryCompleted AssetDiscoveryStatus = "COMPLETED"
+	AssetDiscoveryFailed    AssetDiscoveryStatus = "FAILED"
+)
+
+// Actions of an asset discovery change
+const (
+	AssetDiscoveryCreate     = "CREATE"
+	AssetDiscoveryUpdate     = "UPDATE"
+	AssetDiscoveryRetire     = "RETIRE"
+	AssetDiscoveryReactivate = "REACTIVATE"
+)
+
+// AssetDiscoveryRun records the ingestion of a cloud or CMDB inventory and
+// its reconciliation with the assets of that source. A dry run computes the
+// changes without applying them.
+type AssetDiscoveryRun struct {
+	ID            uuid.UUID            `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Source        string               `gorm:"size:20;not null;index" json:"source"` // AWS, AZURE, GCP, SERVICENOW
+	Format        string               `gorm:"size:30;not null" json:"format"`
+	FileName      string               `gorm:"size:255" json:"file_name,omitempty"`
+	DryRun        bool                 `gorm:"not null;default:false" json:"dry_run"`
+	RetireMissing bool                 `gorm:"not null;default:false" json:"retire_missing"` // Assets of the source missing from the inventory were retired
+	Status        AssetDiscoveryStatus `gorm:"size:20;not null" json:"status"`
+
+	TotalCount     int `json:"total_count"`
+	CreatedCount   int `json:"created_count"`
+	UpdatedCount   int `json:"updated_count"`
+	UnchangedCount int `json:"unchanged_count"`
+	RetiredCount   int `json:"retired_count"`
+	ReviewCount    int `json:"review_count"` // Risks raised for review by retirements
+
+	Changes      datatypes.JSON `gorm:"type:jsonb" json:"changes,omitempty"` // []AssetDiscoveryChange, capped
+	ErrorMessage string         `gorm:"type:text" json:"error_message,omitempty"`
+
+	StartedBy  string     `gorm:"size:100" json:"started_by"`
+	StartedAt  time.Time  `gorm:"not null;index" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName returns the table name for AssetDiscoveryRun
+func (AssetDiscoveryRun) TableName() string {
+	return "asset_discovery_runs"
+}
+
+// AssetDiscoveryChange is a change made, or to be made by a dry run, to an
+// asset by a discovery run
+type AssetDiscoveryChange struct {
+	Action     string                       `json:"action"`
+	AssetID    *uuid.UUID                   `json:"asset_id,omitempty"`
+	ExternalID string                       `json:"external_id"`
+	Name       string                       `json:"name"`
+	Fields     map[string]ImportFieldChange `json:"fields,omitempty"`
+	RiskIDs    []uuid.UUID                  `json:"risk_ids,omitempty"` // Risks raised for review
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Kinds of MITRE ATT&CK objects kept from the Enterprise STIX bundle
+const (
+	AttackTechnique  = "technique"
+	AttackTactic     = "tactic"
+	AttackMitigation = "mitigation"
+)
+
+// Coverage status of a technique
+const (
+	CoverageExposed   = "EXPOSED"   // Open risks, no mitigation done
+	CoverageMitigated = "MITIGATED" // Open risks, with mitigations done
+	CoverageCovered   = "COVERED"   // Mitigations done, no open risk
+	CoveragePlanned   = "PLANNED"   // Mitigations planned only
+)
+
+var (
+	attackTechniqueID = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+	attackMitigation  = regexp.MustCompile(`^M\d{4}$`)
+	d3fendTechnique   = regexp.MustCompile(`^D3-[A-Z0-9]+$`)
+)
+
+// AttackObject is a technique, sub-technique, tactic or mitigation of the
+// ATT&CK catalog, keyed by its ATT&CK ID (T1059.001, TA0002, M1037)
+type AttackObject struct {
+	ID          string         `gorm:"primaryKey;size:20" json:"id"`
+	STIXID      string         `gorm:"column:stix_id;size:100;index" json:"stix_id"`
+	Type        string         `gorm:"size:20;index" json:"type"`
+	Name        string         `gorm:"size:255" json:"name"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	ShortName   string         `gorm:"size:100" json:"short_name,omitempty"`     // Tactic name in kill chains (initial-access)
+	Tactics     pq.StringArray `gorm:"type:text[]" json:"tactics,omitempty"`     // Short names of the tactics of a technique
+	Platforms   pq.StringArray `gorm:"type:text[]" json:"platforms,omitempty"`   // Platforms of a technique
+	ParentID    string         `gorm:"size:20;index" json:"parent_id,omitempty"` // Technique of a sub-technique
+	URL         string         `gorm:"size:255" json:"url,omitempty"`
+	Deprecated  bool           `gorm:"not null;default:false" json:"deprecated,omitempty"` // Revoked or deprecated
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TableName returns the table name for AttackObject
+func (AttackObject) TableName() string {
+	return "attack_objects"
+}
+
+// AttackMitigates records that an ATT&CK mitigation mitigates a technique
+type AttackMitigates struct {
+	MitigationID string `gorm:"primaryKey;size:20" json:"mitigation_id"`
+	TechniqueID  string `gorm:"primaryKey;size:20;index" json:"technique_id"`
+}
+
+// TableName returns the table name for AttackMitigates
+func (AttackMitigates) TableName() string {
+	return "attack_mitigates"
+}
+
+// AttackBundleImport records a load of the ATT&CK STIX bundle
+type AttackBundleImport struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Version     string    `gorm:"size:50" json:"version"` // ATT&CK release (15.1)
+	FileName    string    `gorm:"size:255" json:"file_name,omitempty"`
+	Checksum    string    `gorm:"size:64" json:"checksum"` // SHA-256 of the bundle
+	Techniques  int       `json:"techniques"`
+	Tactics     int       `json:"tactics"`
+	Mitigations int       `json:"mitigations"`
+	ImportedBy  string    `gorm:"size:100" json:"imported_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for AttackBundleImport
+func (AttackBundleImport) TableName() string {
+	return "attack_bundle_imports"
+}
+
+// IsAttackTechniqueID reports whether id is shaped like a technique or
+// sub-technique ID (T1059, T1059.001)
+func IsAttackTechniqueID(id string) bool {
+	return attackTechniqueID.MatchString(id)
+}
+
+// IsAttackMitigationID reports whether id is shaped like an ATT&CK
+// mitigation ID (M1037)
+func IsAttackMitigationID(id string) bool {
+	return attackMitigation.MatchString(id)
+}
+
+// IsD3FENDID reports whether id is shaped like a D3FEND countermeasure ID
+// (D3-MFA)
+func IsD3FENDID(id string) bool {
+	return d3fendTechnique.MatchString(id)
+}
+
+// NormalizeAttackIDs uppercases, trims, deduplicates and sorts ATT&CK or
+// D3FEND IDs
+func NormalizeAttackIDs(ids []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, id := range ids {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TechniqueCoverage sums up, for a technique, the open risks it realizes and
+// the mitigations that counter it
+type TechniqueCoverage struct {
+	TechniqueID        string      `json:"technique_id"`
+	Name               string      `json:"name,omitempty"`
+	Tactics            []string    `json:"tactics,omitempty"`
+	Status             string      `json:"status"`
+	OpenRisks          int         `json:"open_risks"`
+	RiskIDs            []uuid.UUID `json:"risk_ids,omitempty"`
+	RiskTitles         []string    `json:"risk_titles,omitempty"`
+	DoneMitigations    int         `json:"done_mitigations"`
+	PlannedMitigations int         `json:"planned_mitigations"`
+}
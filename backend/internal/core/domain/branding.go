@@ -0,0 +1,10 @@
+package domain
+
+// TenantBranding customises documents generated for a tenant, such as the
+// board PDF. It is stored under "branding" in the tenant's metadata.
+type TenantBranding struct {
+	DisplayName  string `json:"display_name,omitempty"`  // Shown on cover pages instead of the tenant name
+	PrimaryColor string `json:"primary_color,omitempty"` // #RRGGBB, headings and cover band
+	AccentColor  string `json:"accent_color,omitempty"`  // #RRGGBB, charts and highlights
+	Logo         string `json:"logo,omitempty"`          // PNG or JPEG data URI
+}
@@ -1,9 +1,13 @@
 package domain
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
 )
 
 // BulkOperationType represents the type of bulk operation
@@ -34,18 +38,32 @@ type BulkOperation struct {
 
 	// Filter & Scope
 	FilterQuery    map[string]interface{} `gorm:"type:jsonb" json:"filter_query,omitempty"` // Query to select resources
+	Filter         string                 `gorm:"type:text" json:"filter,omitempty"`        // Filter expression selecting the risks, legacy filter_query included
+	AsOf           *time.Time             `json:"as_of,omitempty"`                          // Exports only: the register as it was at that date
 	ResourceCount  int                    `json:"resource_count"`                           // Total resources to process
 	ProcessedCount int                    `json:"processed_count"`                          // Resources processed so far
+	SkippedCount   int                    `json:"skipped_count"`                            // Resources changed since the filter was evaluated
+
+	// Version of each risk the filter selected when the operation was created
+	// ([]ResourceVersion); updates, deletes and assignments skip the risks
+	// changed since
+	RiskVersions datatypes.JSON `gorm:"type:jsonb" json:"-"`
 
 	// Operation details
-	UpdateData   map[string]interface{} `gorm:"type:jsonb" json:"update_data,omitempty"` // Data to update (for update operations)
-	ExportFormat string                 `json:"export_format,omitempty"`                 // json, csv, pdf (for export)
+	UpdateData    map[string]interface{} `gorm:"type:jsonb" json:"update_data,omitempty"`     // Data to update (for update operations)
+	ExportFormat  string                 `json:"export_format,omitempty"`                     // csv, xlsx, json (for export)
+	ExportColumns pq.StringArray         `gorm:"type:text[]" json:"export_columns,omitempty"` // Selected columns; empty exports the defaults
 
 	// Results & Error handling
 	ResultURL    string `json:"result_url,omitempty"` // URL to download result (for exports)
+	ArtifactKey  string `json:"-"`                    // Blob store key of the export file
+	ArtifactSize int64  `json:"artifact_size,omitempty"`
 	ErrorMessage string `gorm:"type:text" json:"error_message,omitempty"`
 	ErrorCount   int    `json:"error_count"`
 
+	// Background job executing the operation
+	JobID *uuid.UUID `gorm:"type:uuid;index" json:"job_id,omitempty"`
+
 	// Metadata
 	CreatedBy     uuid.UUID  `gorm:"index" json:"created_by"`
 	CreatedAt     time.Time  `json:"created_at"`
@@ -54,6 +72,19 @@ type BulkOperation struct {
 	EstimatedTime *int       `json:"estimated_time_seconds,omitempty"` // Estimated seconds to completion
 }
 
+// SelectedVersions decodes the risks selected when the operation was created;
+// nil for operations that did not record them
+func (op *BulkOperation) SelectedVersions() ([]ResourceVersion, error) {
+	if len(op.RiskVersions) == 0 {
+		return nil, nil
+	}
+	var versions []ResourceVersion
+	if err := json.Unmarshal(op.RiskVersions, &versions); err != nil {
+		return nil, fmt.Errorf("invalid bulk operation risk versions: %w", err)
+	}
+	return versions, nil
+}
+
 // BulkOperationLog tracks individual resource processing in a bulk operation
 type BulkOperationLog struct {
 	ID              uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
@@ -70,10 +101,13 @@ type BulkOperationLog struct {
 // CreateBulkOperationRequest represents a request to create a bulk operation
 type CreateBulkOperationRequest struct {
 	OperationType BulkOperationType      `json:"operation_type" validate:"required,oneof=update delete export assign_mitigation"`
-	FilterQuery   map[string]interface{} `json:"filter_query"`            // MongoDB-like filter
-	UpdateData    map[string]interface{} `json:"update_data,omitempty"`   // For update operations
-	ExportFormat  string                 `json:"export_format,omitempty"` // For export operations
-	MitigationID  uuid.UUID              `json:"mitigation_id,omitempty"` // For assign operations
+	FilterQuery   map[string]interface{} `json:"filter_query"`             // Legacy key/value filter: status, min_score, max_score, tags
+	Filter        string                 `json:"filter,omitempty"`         // Filter expression, e.g. score >= 12 AND tags HAS "GDPR"
+	UpdateData    map[string]interface{} `json:"update_data,omitempty"`    // For update operations
+	ExportFormat  string                 `json:"export_format,omitempty"`  // For export operations: csv (default), xlsx, json
+	ExportColumns []string               `json:"export_columns,omitempty"` // For export operations; see GET /bulk-operations/export-columns
+	AsOf          string                 `json:"as_of,omitempty"`          // For export operations: date (YYYY-MM-DD) or timestamp to export the register as of
+	MitigationID  uuid.UUID              `json:"mitigation_id,omitempty"`  // For assign operations
 }
 
 // TableName returns the table name for BulkOperation
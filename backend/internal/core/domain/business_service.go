@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BusinessService is a service the business relies on (payments, e-commerce)
+// composed of assets. Its risk is the one of its assets and of their children
+// in the asset hierarchy.
+type BusinessService struct {
+	ID          uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name        string           `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Description string           `gorm:"type:text" json:"description,omitempty"`
+	Owner       string           `json:"owner"`
+	Criticality AssetCriticality `gorm:"size:20;not null;default:'MEDIUM'" json:"criticality"`
+
+	Assets []*Asset `gorm:"many2many:business_service_assets;" json:"assets,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for BusinessService
+func (BusinessService) TableName() string {
+	return "business_services"
+}
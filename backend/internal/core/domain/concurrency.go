@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrVersionConflict : la ressource a changé depuis la version lue par le client
+var ErrVersionConflict = errors.New("resource was modified since it was read")
+
+// ResourceVersion : version d'une ressource à un instant donné, ex. celle des
+// risques sélectionnés par le filtre d'une opération bulk
+type ResourceVersion struct {
+	ID      uuid.UUID `json:"id"`
+	Version int       `json:"version"`
+}
+
+// ETag renvoie l'entity tag HTTP d'une version
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// MatchesETag indique si l'en-tête If-Match accepte la version : "*" ou une
+// liste d'entity tags. Les tags faibles (W/"3") sont acceptés, un proxy qui
+// compresse la réponse pouvant affaiblir le tag.
+func MatchesETag(ifMatch string, version int64) bool {
+	current := ETag(version)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVersion renvoie ErrVersionConflict quand l'en-tête If-Match est
+// présent et n'accepte pas la version courante
+func CheckVersion(ifMatch string, version int64) error {
+	if strings.TrimSpace(ifMatch) == "" || MatchesETag(ifMatch, version) {
+		return nil
+	}
+	return fmt.Errorf("%w: current version is %d", ErrVersionConflict, version)
+}
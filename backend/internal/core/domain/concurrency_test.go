@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	assert.Equal(t, `"3"`, ETag(3))
+
+	assert.True(t, MatchesETag(`"3"`, 3))
+	assert.True(t, MatchesETag(`W/"3"`, 3), "weak tags are accepted")
+	assert.True(t, MatchesETag(`"1", "3"`, 3))
+	assert.True(t, MatchesETag(`*`, 3))
+	assert.False(t, MatchesETag(`"2"`, 3))
+	assert.False(t, MatchesETag(`3`, 3), "tags are quoted")
+
+	assert.NoError(t, CheckVersion("", 3), "no If-Match, no check")
+	assert.NoError(t, CheckVersion(`"3"`, 3))
+	err := CheckVersion(`"2"`, 3)
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+}
+
+func TestBulkOperationSelectedVersions(t *testing.T) {
+	op := BulkOperation{}
+	versions, err := op.SelectedVersions()
+	require.NoError(t, err)
+	assert.Nil(t, versions, "not recorded")
+
+	op.RiskVersions = []byte(`[]`)
+	versions, err = op.SelectedVersions()
+	require.NoError(t, err)
+	assert.NotNil(t, versions, "recorded, nothing selected")
+	assert.Empty(t, versions)
+
+	id := uuid.New()
+	op.RiskVersions = []byte(`[{"id":"` + id.String() + `","version":4}]`)
+	versions, err = op.SelectedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceVersion{{ID: id, Version: 4}}, versions)
+}
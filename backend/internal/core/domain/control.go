@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Kinds of control catalogs: an OSCAL catalog, or a profile selecting
+// controls of a catalog (a baseline)
+const (
+	ControlCatalogKind = "catalog"
+	ControlProfileKind = "profile"
+)
+
+// Implementation states of a control, those of OSCAL
+const (
+	ControlImplemented   = "implemented"
+	ControlPartial       = "partial"
+	ControlPlanned       = "planned"
+	ControlAlternative   = "alternative"
+	ControlNotApplicable = "not-applicable"
+)
+
+var ErrInvalidControlStatus = errors.New("invalid control implementation status: expected implemented, partial, planned, alternative or not-applicable")
+
+// IsControlStatus reports whether status is an implementation state
+func IsControlStatus(status string) bool {
+	switch status {
+	case ControlImplemented, ControlPartial, ControlPlanned, ControlAlternative, ControlNotApplicable:
+		return true
+	}
+	return false
+}
+
+// NormalizeControlRef normalizes a reference to a control as written in
+// evidence or labels ("AC-2(1)", "ac-2.1", "AC 2 (1)") into its OSCAL ID
+// (ac-2.1)
+func NormalizeControlRef(ref string) string {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	ref = strings.NewReplacer(" ", "", "(", ".", ")", "").Replace(ref)
+	return strings.Trim(ref, ".")
+}
+
+// ControlCatalog is an OSCAL catalog or profile loaded into the control
+// catalog, keyed by its OSCAL UUID. A profile keeps the IDs of the controls it
+// selects from its base catalog.
+type ControlCatalog struct {
+	ID            uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Kind          string         `gorm:"size:20;index" json:"kind"` // catalog, profile
+	OSCALUUID     string         `gorm:"column:oscal_uuid;size:36;uniqueIndex;not null" json:"oscal_uuid"`
+	Title         string         `gorm:"size:255" json:"title"`
+	Version       string         `gorm:"size:100" json:"version"`
+	OSCALVersion  string         `gorm:"column:oscal_version;size:20" json:"oscal_version"`
+	LastModified  *time.Time     `json:"last_modified,omitempty"`
+	BaseCatalogID *uuid.UUID     `gorm:"type:uuid;index" json:"base_catalog_id,omitempty"` // Catalog a profile selects from
+	ControlIDs    pq.StringArray `gorm:"type:text[]" json:"control_ids,omitempty"`         // Controls a profile selects
+	Controls      int            `json:"controls"`
+	FileName      string         `gorm:"size:255" json:"file_name,omitempty"`
+	Checksum      string         `gorm:"size:64" json:"checksum"` // SHA-256 of the document
+	ImportedBy    uuid.UUID      `gorm:"type:uuid" json:"imported_by"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// TableName returns the table name for ControlCatalog
+func (ControlCatalog) TableName() string {
+	return "control_catalogs"
+}
+
+// Control représente un contrôle de sécurité/conformité d'un catalogue
+// (Contrat avec OpenRMF, catalogues OSCAL)
+type Control struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	CatalogID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_controls_catalog_control;not null" json:"catalog_id"`
+	ControlID string    `gorm:"size:100;uniqueIndex:idx_controls_catalog_control;not null" json:"control_id"` // OSCAL ID (ac-2.1)
+	Label     string    `gorm:"size:100" json:"label"`                                                        // AC-2(1)
+	Name      string    `gorm:"size:500" json:"name"`
+	Class     string    `gorm:"size:100" json:"class,omitempty"`
+	Framework string    `gorm:"size:255" json:"framework"`              // Title of the catalog, Ex: NIST SP 800-53
+	Family    string    `gorm:"size:255;index" json:"family,omitempty"` // Group of the catalog (Access Control)
+	ParentID  string    `gorm:"size:100" json:"parent_id,omitempty"`    // Control of an enhancement
+	Statement string    `gorm:"type:text" json:"statement,omitempty"`
+	Guidance  string    `gorm:"type:text" json:"guidance,omitempty"`
+	Position  int       `json:"position"` // Order in the catalog
+	Withdrawn bool      `gorm:"not null;default:false" json:"withdrawn,omitempty"`
+	Status    string    `gorm:"-" json:"status,omitempty"` // Implementation state for the tenant
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for Control
+func (Control) TableName() string {
+	return "controls"
+}
+
+// ControlImplementation records how a tenant implements a control
+type ControlImplementation struct {
+	ID              uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID        uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_control_impl_tenant_control" json:"tenant_id"`
+	ControlID       uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_control_impl_tenant_control;not null" json:"control_id"`
+	Status          string    `gorm:"size:20;not null" json:"status"` // implemented, partial, planned, alternative, not-applicable
+	Description     string    `gorm:"type:text" json:"description,omitempty"`
+	ResponsibleRole string    `gorm:"size:100" json:"responsible_role,omitempty"`
+	UpdatedBy       uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for ControlImplementation
+func (ControlImplementation) TableName() string {
+	return "control_implementations"
+}
@@ -120,6 +120,11 @@ func (CustomField) TableName() string {
 	return "custom_fields"
 }
 
+// AfterDelete moves the custom field to the recycle bin
+func (f *CustomField) AfterDelete(tx *gorm.DB) error {
+	return RecordDeletion(tx, RecycleCustomField, f.ID, DeletedCascade{})
+}
+
 // TableName returns the table name for CustomFieldTemplate
 func (CustomFieldTemplate) TableName() string {
 	return "custom_field_templates"
@@ -0,0 +1,384 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CVSS versions accepted in vectors. 3.0 vectors are scored with the 3.1
+// formulas, which only differ in rounding and the modified impact.
+const (
+	CVSSVersion30 = "3.0"
+	CVSSVersion31 = "3.1"
+	CVSSVersion40 = "4.0"
+)
+
+// CVSS qualitative severity ratings, as NVD reports them
+const (
+	CVSSSeverityNone     = "NONE"
+	CVSSSeverityLow      = "LOW"
+	CVSSSeverityMedium   = "MEDIUM"
+	CVSSSeverityHigh     = "HIGH"
+	CVSSSeverityCritical = "CRITICAL"
+)
+
+// ErrInvalidCVSSVector is returned for a vector that does not parse or lacks
+// a base metric
+var ErrInvalidCVSSVector = errors.New("invalid CVSS vector")
+
+// cvssMetric is a metric of a vector: its abbreviation, whether the base
+// score requires it and its values, "X" (Not Defined) included when optional
+type cvssMetric struct {
+	name     string
+	required bool
+	values   []string
+}
+
+var cvss3Metrics = []cvssMetric{
+	{"AV", true, []string{"N", "A", "L", "P"}},
+	{"AC", true, []string{"L", "H"}},
+	{"PR", true, []string{"N", "L", "H"}},
+	{"UI", true, []string{"N", "R"}},
+	{"S", true, []string{"U", "C"}},
+	{"C", true, []string{"H", "L", "N"}},
+	{"I", true, []string{"H", "L", "N"}},
+	{"A", true, []string{"H", "L", "N"}},
+	{"E", false, []string{"X", "H", "F", "P", "U"}},
+	{"RL", false, []string{"X", "U", "W", "T", "O"}},
+	{"RC", false, []string{"X", "C", "R", "U"}},
+	{"CR", false, []string{"X", "H", "M", "L"}},
+	{"IR", false, []string{"X", "H", "M", "L"}},
+	{"AR", false, []string{"X", "H", "M", "L"}},
+	{"MAV", false, []string{"X", "N", "A", "L", "P"}},
+	{"MAC", false, []string{"X", "L", "H"}},
+	{"MPR", false, []string{"X", "N", "L", "H"}},
+	{"MUI", false, []string{"X", "N", "R"}},
+	{"MS", false, []string{"X", "U", "C"}},
+	{"MC", false, []string{"X", "H", "L", "N"}},
+	{"MI", false, []string{"X", "H", "L", "N"}},
+	{"MA", false, []string{"X", "H", "L", "N"}},
+}
+
+var cvss4Metrics = []cvssMetric{
+	{"AV", true, []string{"N", "A", "L", "P"}},
+	{"AC", true, []string{"L", "H"}},
+	{"AT", true, []string{"N", "P"}},
+	{"PR", true, []string{"N", "L", "H"}},
+	{"UI", true, []string{"N", "P", "A"}},
+	{"VC", true, []string{"H", "L", "N"}},
+	{"VI", true, []string{"H", "L", "N"}},
+	{"VA", true, []string{"H", "L", "N"}},
+	{"SC", true, []string{"H", "L", "N"}},
+	{"SI", true, []string{"H", "L", "N"}},
+	{"SA", true, []string{"H", "L", "N"}},
+	{"E", false, []string{"X", "A", "P", "U"}},
+	{"CR", false, []string{"X", "H", "M", "L"}},
+	{"IR", false, []string{"X", "H", "M", "L"}},
+	{"AR", false, []string{"X", "H", "M", "L"}},
+	{"MAV", false, []string{"X", "N", "A", "L", "P"}},
+	{"MAC", false, []string{"X", "L", "H"}},
+	{"MAT", false, []string{"X", "N", "P"}},
+	{"MPR", false, []string{"X", "N", "L", "H"}},
+	{"MUI", false, []string{"X", "N", "P", "A"}},
+	{"MVC", false, []string{"X", "H", "L", "N"}},
+	{"MVI", false, []string{"X", "H", "L", "N"}},
+	{"MVA", false, []string{"X", "H", "L", "N"}},
+	{"MSC", false, []string{"X", "H", "L", "N"}},
+	{"MSI", false, []string{"X", "S", "H", "L", "N"}},
+	{"MSA", false, []string{"X", "S", "H", "L", "N"}},
+	// Supplemental metrics do not change the scores
+	{"S", false, []string{"X", "N", "P"}},
+	{"AU", false, []string{"X", "N", "Y"}},
+	{"R", false, []string{"X", "A", "U", "I"}},
+	{"V", false, []string{"X", "D", "C"}},
+	{"RE", false, []string{"X", "L", "M", "H"}},
+	{"U", false, []string{"X", "Clear", "Green", "Amber", "Red"}},
+}
+
+// CVSSVector is a parsed CVSS v3.x or v4.0 vector
+type CVSSVector struct {
+	Version string
+	metrics map[string]string
+}
+
+// CVSSScores are the scores of a vector. For v4.0, which has no temporal
+// group, Temporal is the score with the threat metrics (CVSS-BT) and
+// Environmental the score with all metrics (CVSS-BTE).
+type CVSSScores struct {
+	Version               string  `json:"version"`
+	Vector                string  `json:"vector"`
+	Base                  float64 `json:"base_score"`
+	BaseSeverity          string  `json:"base_severity"`
+	Temporal              float64 `json:"temporal_score"`
+	TemporalSeverity      string  `json:"temporal_severity"`
+	Environmental         float64 `json:"environmental_score"`
+	EnvironmentalSeverity string  `json:"environmental_severity"`
+}
+
+// ParseCVSSVector parses and validates a "CVSS:3.1/AV:N/..." or
+// "CVSS:4.0/AV:N/..." vector. Metrics may come in any order but only once,
+// and every base metric is required.
+func ParseCVSSVector(vector string) (*CVSSVector, error) {
+	parts := strings.Split(strings.TrimSpace(vector), "/")
+	prefix, version, _ := strings.Cut(parts[0], ":")
+	if prefix != "CVSS" {
+		return nil, fmt.Errorf("%w: %q must start with CVSS:3.1/ or CVSS:4.0/", ErrInvalidCVSSVector, vector)
+	}
+	definitions := cvssMetricsFor(version)
+	if definitions == nil {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidCVSSVector, version)
+	}
+
+	v := &CVSSVector{Version: version, metrics: map[string]string{}}
+	for _, part := range parts[1:] {
+		name, value, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed metric %q", ErrInvalidCVSSVector, part)
+		}
+		metric := findCVSSMetric(definitions, name)
+		if metric == nil {
+			return nil, fmt.Errorf("%w: unknown metric %q for CVSS %s", ErrInvalidCVSSVector, name, version)
+		}
+		if _, dup := v.metrics[name]; dup {
+			return nil, fmt.Errorf("%w: metric %s given twice", ErrInvalidCVSSVector, name)
+		}
+		if !containsString(metric.values, value) {
+			return nil, fmt.Errorf("%w: invalid value %q for metric %s", ErrInvalidCVSSVector, value, name)
+		}
+		v.metrics[name] = value
+	}
+	for _, metric := range definitions {
+		if _, ok := v.metrics[metric.name]; metric.required && !ok {
+			return nil, fmt.Errorf("%w: missing base metric %s", ErrInvalidCVSSVector, metric.name)
+		}
+	}
+	return v, nil
+}
+
+func cvssMetricsFor(version string) []cvssMetric {
+	switch version {
+	case CVSSVersion30, CVSSVersion31:
+		return cvss3Metrics
+	case CVSSVersion40:
+		return cvss4Metrics
+	}
+	return nil
+}
+
+func findCVSSMetric(definitions []cvssMetric, name string) *cvssMetric {
+	for i := range definitions {
+		if definitions[i].name == name {
+			return &definitions[i]
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the vector in the specification's metric order, without
+// the metrics left Not Defined
+func (v *CVSSVector) String() string {
+	var b strings.Builder
+	b.WriteString("CVSS:" + v.Version)
+	for _, metric := range cvssMetricsFor(v.Version) {
+		if value := v.Metric(metric.name); value != "X" {
+			b.WriteString("/" + metric.name + ":" + value)
+		}
+	}
+	return b.String()
+}
+
+// Metric returns the value of a metric as written in the vector, "X" (Not
+// Defined) when absent
+func (v *CVSSVector) Metric(name string) string {
+	if value, ok := v.metrics[name]; ok {
+		return value
+	}
+	return "X"
+}
+
+// effective returns the modified metric of the environmental group when
+// defined, the base metric otherwise
+func (v *CVSSVector) effective(name string) string {
+	if modified := v.Metric("M" + name); modified != "X" {
+		return modified
+	}
+	return v.Metric(name)
+}
+
+// WithRequirements returns a copy of the vector whose undefined security
+// requirements (CR, IR, AR) are set to the given level (H, M or L)
+func (v *CVSSVector) WithRequirements(level string) *CVSSVector {
+	out := &CVSSVector{Version: v.Version, metrics: make(map[string]string, len(v.metrics)+3)}
+	for name, value := range v.metrics {
+		out.metrics[name] = value
+	}
+	if level == "" || level == "X" {
+		return out
+	}
+	for _, name := range []string{"CR", "IR", "AR"} {
+		if out.Metric(name) == "X" {
+			out.metrics[name] = level
+		}
+	}
+	return out
+}
+
+// only returns a copy of the vector restricted to the given metrics
+func (v *CVSSVector) only(names ...string) *CVSSVector {
+	out := &CVSSVector{Version: v.Version, metrics: map[string]string{}}
+	for _, name := range names {
+		if value, ok := v.metrics[name]; ok {
+			out.metrics[name] = value
+		}
+	}
+	return out
+}
+
+// Scores computes the base, temporal (threat) and environmental scores
+func (v *CVSSVector) Scores() CVSSScores {
+	scores := CVSSScores{Version: v.Version, Vector: v.String()}
+	if v.Version == CVSSVersion40 {
+		base := []string{"AV", "AC", "AT", "PR", "UI", "VC", "VI", "VA", "SC", "SI", "SA"}
+		scores.Base = v.only(base...).score4()
+		scores.Temporal = v.only(append(base, "E")...).score4()
+		scores.Environmental = v.score4()
+	} else {
+		scores.Base = v.baseScore3()
+		scores.Temporal = v.temporalScore3()
+		scores.Environmental = v.environmentalScore3()
+	}
+	scores.BaseSeverity = CVSSSeverity(scores.Base)
+	scores.TemporalSeverity = CVSSSeverity(scores.Temporal)
+	scores.EnvironmentalSeverity = CVSSSeverity(scores.Environmental)
+	return scores
+}
+
+// CVSSSeverity is the qualitative rating of a score
+func CVSSSeverity(score float64) string {
+	switch {
+	case score == 0:
+		return CVSSSeverityNone
+	case score < 4:
+		return CVSSSeverityLow
+	case score < 7:
+		return CVSSSeverityMedium
+	case score < 9:
+		return CVSSSeverityHigh
+	default:
+		return CVSSSeverityCritical
+	}
+}
+
+// CVSS v3.x metric weights, from the specification
+var (
+	cvss3AttackVector      = map[string]float64{"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2}
+	cvss3AttackComplexity  = map[string]float64{"L": 0.77, "H": 0.44}
+	cvss3UserInteraction   = map[string]float64{"N": 0.85, "R": 0.62}
+	cvss3CIA               = map[string]float64{"H": 0.56, "L": 0.22, "N": 0}
+	cvss3ExploitCodeMature = map[string]float64{"X": 1, "H": 1, "F": 0.97, "P": 0.94, "U": 0.91}
+	cvss3RemediationLevel  = map[string]float64{"X": 1, "U": 1, "W": 0.97, "T": 0.96, "O": 0.95}
+	cvss3ReportConfidence  = map[string]float64{"X": 1, "C": 1, "R": 0.96, "U": 0.92}
+	cvss3Requirement       = map[string]float64{"X": 1, "H": 1.5, "M": 1, "L": 0.5}
+)
+
+func cvss3PrivilegesRequired(value string, scopeChanged bool) float64 {
+	switch value {
+	case "L":
+		if scopeChanged {
+			return 0.68
+		}
+		return 0.62
+	case "H":
+		if scopeChanged {
+			return 0.5
+		}
+		return 0.27
+	}
+	return 0.85
+}
+
+// cvssRoundup is the specification's Roundup: the smallest number with one
+// decimal equal to or above the input, immune to floating point artefacts
+func cvssRoundup(x float64) float64 {
+	i := int64(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
+
+// exploitability3 is the exploitability sub-score of the base metrics or,
+// when modified is set, of the modified ones
+func (v *CVSSVector) exploitability3(modified bool) float64 {
+	metric := v.Metric
+	if modified {
+		metric = v.effective
+	}
+	return 8.22 * cvss3AttackVector[metric("AV")] * cvss3AttackComplexity[metric("AC")] *
+		cvss3PrivilegesRequired(metric("PR"), metric("S") == "C") * cvss3UserInteraction[metric("UI")]
+}
+
+func (v *CVSSVector) baseScore3() float64 {
+	iss := 1 - (1-cvss3CIA[v.Metric("C")])*(1-cvss3CIA[v.Metric("I")])*(1-cvss3CIA[v.Metric("A")])
+	scopeChanged := v.Metric("S") == "C"
+	impact := 6.42 * iss
+	if scopeChanged {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0
+	}
+	if scopeChanged {
+		return cvssRoundup(math.Min(1.08*(impact+v.exploitability3(false)), 10))
+	}
+	return cvssRoundup(math.Min(impact+v.exploitability3(false), 10))
+}
+
+func (v *CVSSVector) temporalMultiplier3() float64 {
+	return cvss3ExploitCodeMature[v.Metric("E")] * cvss3RemediationLevel[v.Metric("RL")] * cvss3ReportConfidence[v.Metric("RC")]
+}
+
+func (v *CVSSVector) temporalScore3() float64 {
+	return cvssRoundup(v.baseScore3() * v.temporalMultiplier3())
+}
+
+// modifiedISS3 is the modified impact sub-score: the impact on each of
+// confidentiality, integrity and availability weighted by its requirement
+func (v *CVSSVector) modifiedISS3() float64 {
+	miss := 1 - (1-cvss3Requirement[v.Metric("CR")]*cvss3CIA[v.effective("C")])*
+		(1-cvss3Requirement[v.Metric("IR")]*cvss3CIA[v.effective("I")])*
+		(1-cvss3Requirement[v.Metric("AR")]*cvss3CIA[v.effective("A")])
+	return math.Min(miss, 0.915)
+}
+
+func (v *CVSSVector) environmentalScore3() float64 {
+	miss := v.modifiedISS3()
+	scopeChanged := v.effective("S") == "C"
+	impact := 6.42 * miss
+	if scopeChanged {
+		if v.Version == CVSSVersion30 {
+			impact = 7.52*(miss-0.029) - 3.25*math.Pow(miss-0.02, 15)
+		} else {
+			impact = 7.52*(miss-0.029) - 3.25*math.Pow(miss*0.9731-0.02, 13)
+		}
+	}
+	if impact <= 0 {
+		return 0
+	}
+	exploitability := v.exploitability3(true)
+	if scopeChanged {
+		return cvssRoundup(cvssRoundup(math.Min(1.08*(impact+exploitability), 10)) * v.temporalMultiplier3())
+	}
+	return cvssRoundup(cvssRoundup(math.Min(impact+exploitability, 10)) * v.temporalMultiplier3())
+}
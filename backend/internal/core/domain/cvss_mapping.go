@@ -0,0 +1,223 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// CVSSThreshold maps a CVSS component score (0 to 10) at or above Score to
+// an impact or probability Level (1 to 5)
+type CVSSThreshold struct {
+	Score float64 `json:"score"`
+	Level int     `json:"level"`
+}
+
+// CVSSMappingPolicy sets, for a tenant, how the impact and exploitability
+// components of a CVSS vector translate into the impact and probability of
+// a risk
+type CVSSMappingPolicy struct {
+	ID                    uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID              uuid.UUID      `gorm:"type:uuid;uniqueIndex" json:"tenant_id"`
+	ImpactThresholds      datatypes.JSON `gorm:"type:jsonb" json:"impact_thresholds"`      // []CVSSThreshold
+	ProbabilityThresholds datatypes.JSON `gorm:"type:jsonb" json:"probability_thresholds"` // []CVSSThreshold
+	// The linked assets' criticality sets the security requirements (CR, IR,
+	// AR) the vector leaves undefined, unless this is set
+	IgnoreAssetCriticality bool `gorm:"not null;default:false" json:"ignore_asset_criticality"`
+
+	UpdatedBy uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for CVSSMappingPolicy
+func (CVSSMappingPolicy) TableName() string {
+	return "cvss_mapping_policies"
+}
+
+// DefaultCVSSThresholds is the mapping of both components for tenants
+// without a policy
+var DefaultCVSSThresholds = []CVSSThreshold{
+	{Score: 8, Level: 5},
+	{Score: 6, Level: 4},
+	{Score: 4, Level: 3},
+	{Score: 2, Level: 2},
+	{Score: 0, Level: 1},
+}
+
+// DefaultCVSSMappingPolicy is used for tenants without a configured policy
+func DefaultCVSSMappingPolicy(tenantID uuid.UUID) CVSSMappingPolicy {
+	thresholds, _ := json.Marshal(DefaultCVSSThresholds)
+	return CVSSMappingPolicy{
+		TenantID:              tenantID,
+		ImpactThresholds:      thresholds,
+		ProbabilityThresholds: thresholds,
+	}
+}
+
+func decodeCVSSThresholds(raw datatypes.JSON) ([]CVSSThreshold, error) {
+	var thresholds []CVSSThreshold
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &thresholds); err != nil {
+			return nil, err
+		}
+	}
+	if len(thresholds) == 0 {
+		thresholds = append(thresholds, DefaultCVSSThresholds...)
+	}
+	sort.SliceStable(thresholds, func(i, j int) bool { return thresholds[i].Score > thresholds[j].Score })
+	return thresholds, nil
+}
+
+// Validate checks the scores and levels of both mappings
+func (p *CVSSMappingPolicy) Validate() error {
+	for field, raw := range map[string]datatypes.JSON{"impact_thresholds": p.ImpactThresholds, "probability_thresholds": p.ProbabilityThresholds} {
+		thresholds, err := decodeCVSSThresholds(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", field, err)
+		}
+		for _, t := range thresholds {
+			if t.Score < 0 || t.Score > 10 {
+				return fmt.Errorf("%s: score %v must be between 0 and 10", field, t.Score)
+			}
+			if t.Level < 1 || t.Level > 5 {
+				return fmt.Errorf("%s: level for score %v must be between 1 and 5", field, t.Score)
+			}
+		}
+	}
+	return nil
+}
+
+// levelFor returns the level of the first threshold the score reaches, 1
+// below all of them
+func levelFor(raw datatypes.JSON, score float64) int {
+	thresholds, _ := decodeCVSSThresholds(raw)
+	for _, t := range thresholds {
+		if score >= t.Score {
+			return t.Level
+		}
+	}
+	return 1
+}
+
+// CVSSRequirementFor is the security requirement (CR, IR, AR) matching an
+// asset criticality
+func CVSSRequirementFor(criticality AssetCriticality) string {
+	switch criticality {
+	case CriticalityCritical, CriticalityHigh:
+		return "H"
+	case CriticalityMedium:
+		return "M"
+	case CriticalityLow:
+		return "L"
+	}
+	return "X"
+}
+
+// CVSSMapping is how a vector translated into the impact and probability of
+// a risk
+type CVSSMapping struct {
+	CVSSScores
+	Requirement      string  `json:"requirement,omitempty"` // CR, IR and AR taken from the assets
+	ImpactScore      float64 `json:"impact_score"`          // 0 to 10
+	Impact           int     `json:"impact"`
+	ExploitScore     float64 `json:"exploitability_score"` // 0 to 10
+	Probability      int     `json:"probability"`
+	AssetsConsidered int     `json:"assets_considered"`
+}
+
+// Map scores a vector and derives impact and probability from it. The
+// security requirements the vector leaves undefined come from the highest
+// criticality of the assets, unless the policy ignores it.
+func (p *CVSSMappingPolicy) Map(vector *CVSSVector, assets []*Asset) CVSSMapping {
+	var mapping CVSSMapping
+	if !p.IgnoreAssetCriticality {
+		var highest AssetCriticality
+		for _, a := range assets {
+			if a == nil {
+				continue
+			}
+			criticality := a.Criticality
+			if a.EffectiveCriticality != "" {
+				criticality = a.EffectiveCriticality
+			}
+			if assetCriticalityRank[criticality] > assetCriticalityRank[highest] {
+				highest = criticality
+			}
+			mapping.AssetsConsidered++
+		}
+		if requirement := CVSSRequirementFor(highest); requirement != "X" {
+			mapping.Requirement = requirement
+			vector = vector.WithRequirements(requirement)
+		}
+	}
+
+	mapping.CVSSScores = vector.Scores()
+	mapping.ImpactScore = math.Round(vector.ImpactComponent()*10) / 10
+	mapping.ExploitScore = math.Round(vector.ExploitabilityComponent()*10) / 10
+	mapping.Impact = levelFor(p.ImpactThresholds, mapping.ImpactScore)
+	mapping.Probability = levelFor(p.ProbabilityThresholds, mapping.ExploitScore)
+	return mapping
+}
+
+var assetCriticalityRank = map[AssetCriticality]int{
+	CriticalityLow:      1,
+	CriticalityMedium:   2,
+	CriticalityHigh:     3,
+	CriticalityCritical: 4,
+}
+
+// ImpactComponent scores from 0 to 10 the consequences described by the
+// vector: the v3.1 modified impact sub-score, weighted by the security
+// requirements, over its maximum. For v4.0 the impact on the subsequent
+// systems counts when higher than on the vulnerable one, and a safety impact
+// is the maximum.
+func (v *CVSSVector) ImpactComponent() float64 {
+	if v.Version != CVSSVersion40 {
+		return 10 * v.modifiedISS3() / 0.915
+	}
+	if v.Metric("MSI") == "S" || v.Metric("MSA") == "S" {
+		return 10
+	}
+	requirement := func(name string) float64 {
+		return cvss3Requirement[v.Metric(name)]
+	}
+	vulnerable := 1 - (1-requirement("CR")*cvss3CIA[v.m4("VC")])*
+		(1-requirement("IR")*cvss3CIA[v.m4("VI")])*
+		(1-requirement("AR")*cvss3CIA[v.m4("VA")])
+	subsequent := 1 - (1-cvss3CIA[v.m4("SC")])*(1-cvss3CIA[v.m4("SI")])*(1-cvss3CIA[v.m4("SA")])
+	return 10 * math.Min(math.Max(vulnerable, subsequent), 0.915) / 0.915
+}
+
+// cvss4UserInteraction weighs the v4.0 user interaction like v3.1 does,
+// active interaction being the least likely
+var cvss4UserInteraction = map[string]float64{"N": 0.85, "P": 0.62, "A": 0.45}
+
+// cvss4ExploitMaturity weighs the v4.0 exploit maturity like v3.1 does
+var cvss4ExploitMaturity = map[string]float64{"A": 1, "P": 0.94, "U": 0.91}
+
+// ExploitabilityComponent scores from 0 to 10 how likely exploitation is:
+// the v3.1 exploitability sub-score of the modified metrics over its maximum,
+// lowered by the exploit maturity. For v4.0, attack requirements weigh like a
+// high attack complexity.
+func (v *CVSSVector) ExploitabilityComponent() float64 {
+	const maxExploitability = 8.22 * 0.85 * 0.77 * 0.85 * 0.85
+	var exploitability float64
+	if v.Version != CVSSVersion40 {
+		exploitability = v.exploitability3(true) * cvss3ExploitCodeMature[v.Metric("E")]
+	} else {
+		complexity := cvss3AttackComplexity["L"]
+		if v.m4("AC") != "L" || v.m4("AT") != "N" {
+			complexity = cvss3AttackComplexity["H"]
+		}
+		exploitability = 8.22 * cvss3AttackVector[v.m4("AV")] * complexity *
+			cvss3PrivilegesRequired(v.m4("PR"), false) * cvss4UserInteraction[v.m4("UI")] *
+			cvss4ExploitMaturity[v.m4("E")]
+	}
+	return math.Min(10*exploitability/maxExploitability, 10)
+}
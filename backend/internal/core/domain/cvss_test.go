@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCVSSVector(t *testing.T) {
+	v, err := ParseCVSSVector("CVSS:3.1/C:H/AV:N/AC:L/PR:N/UI:N/S:U/I:H/A:H/E:X/CR:H")
+	require.NoError(t, err)
+	assert.Equal(t, CVSSVersion31, v.Version)
+	assert.Equal(t, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/CR:H", v.String(), "normalised order, undefined metrics dropped")
+	assert.Equal(t, "X", v.Metric("MAV"))
+
+	v, err = ParseCVSSVector("CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N/U:Amber")
+	require.NoError(t, err)
+	assert.Equal(t, CVSSVersion40, v.Version)
+
+	for _, invalid := range []string{
+		"",
+		"AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:2.0/AV:N/AC:L/Au:N/C:C/I:C/A:C",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H",
+		"CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:N/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/AT:N",
+		"CVSS:4.0/AV:N/AC:L/PR:N/UI:R/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N",
+		"CVSS:3.1/AV:N/AC",
+	} {
+		_, err := ParseCVSSVector(invalid)
+		assert.ErrorIs(t, err, ErrInvalidCVSSVector, invalid)
+	}
+}
+
+func TestCVSS31Scores(t *testing.T) {
+	cases := []struct {
+		vector                        string
+		base, temporal, environmental float64
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8, 9.8, 9.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10, 10, 10},
+		{"CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", 7.8, 7.8, 7.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1, 6.1, 6.1},
+		{"CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N", 5.9, 5.9, 5.9},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0, 0, 0},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/E:P/RL:O/RC:C", 9.8, 8.8, 8.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/MAV:L/MPR:L", 9.8, 9.8, 7.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N/CR:H", 5.3, 5.3, 6.1},
+	}
+	for _, c := range cases {
+		v, err := ParseCVSSVector(c.vector)
+		require.NoError(t, err)
+		scores := v.Scores()
+		assert.Equal(t, c.base, scores.Base, c.vector)
+		assert.Equal(t, c.temporal, scores.Temporal, c.vector)
+		assert.Equal(t, c.environmental, scores.Environmental, c.vector)
+	}
+
+	v, _ := ParseCVSSVector("CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N")
+	assert.Equal(t, CVSSSeverityMedium, v.Scores().BaseSeverity)
+}
+
+func TestCVSS40Scores(t *testing.T) {
+	cases := []struct {
+		vector string
+		score  float64
+	}{
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", 9.3},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:H/SI:H/SA:H", 10},
+		{"CVSS:4.0/AV:L/AC:L/AT:N/PR:L/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", 8.5},
+		{"CVSS:4.0/AV:L/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", 8.6},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:N/VI:N/VA:N/SC:N/SI:N/SA:N", 0},
+	}
+	for _, c := range cases {
+		v, err := ParseCVSSVector(c.vector)
+		require.NoError(t, err)
+		assert.Equal(t, c.score, v.Scores().Base, c.vector)
+	}
+
+	// Threat and environmental metrics only count past the base score
+	v, err := ParseCVSSVector("CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N/E:U/CR:L/IR:L/AR:L")
+	require.NoError(t, err)
+	scores := v.Scores()
+	assert.Equal(t, 9.3, scores.Base)
+	assert.Less(t, scores.Temporal, scores.Base)
+	assert.Less(t, scores.Environmental, scores.Temporal)
+}
+
+func TestCVSSMappingPolicyMap(t *testing.T) {
+	policy := DefaultCVSSMappingPolicy(uuid.Nil)
+	require.NoError(t, policy.Validate())
+
+	critical, _ := ParseCVSSVector("CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H")
+	mapping := policy.Map(critical, nil)
+	assert.Equal(t, 5, mapping.Impact)
+	assert.Equal(t, 5, mapping.Probability)
+	assert.Equal(t, 10.0, mapping.ExploitScore)
+	assert.Empty(t, mapping.Requirement)
+
+	local, _ := ParseCVSSVector("CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:L/I:L/A:N")
+	mapping = policy.Map(local, nil)
+	assert.Equal(t, 3, mapping.Impact)
+	assert.Equal(t, 3, mapping.Probability)
+
+	// The most critical asset sets the security requirements
+	assets := []*Asset{{Criticality: CriticalityLow}, {Criticality: CriticalityMedium, EffectiveCriticality: CriticalityCritical}}
+	mapping = policy.Map(local, assets)
+	assert.Equal(t, "H", mapping.Requirement)
+	assert.Equal(t, 2, mapping.AssetsConsidered)
+	assert.Equal(t, 4, mapping.Impact)
+	assert.Contains(t, mapping.Vector, "/CR:H/IR:H/AR:H")
+
+	// A requirement set in the vector is kept
+	lowered, _ := ParseCVSSVector("CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:L/I:L/A:N/CR:L")
+	mapping = policy.Map(lowered, assets)
+	assert.Contains(t, mapping.Vector, "/CR:L/IR:H/AR:H")
+	assert.Equal(t, 3, mapping.Impact)
+
+	policy.IgnoreAssetCriticality = true
+	assert.Equal(t, 3, policy.Map(local, assets).Impact)
+
+	safety, _ := ParseCVSSVector("CVSS:4.0/AV:A/AC:L/AT:P/PR:N/UI:A/VC:N/VI:L/VA:N/SC:N/SI:N/SA:N/MSI:S")
+	mapping = policy.Map(safety, nil)
+	assert.Equal(t, 5, mapping.Impact)
+	assert.Equal(t, 2, mapping.Probability)
+
+	policy.ImpactThresholds, _ = json.Marshal([]CVSSThreshold{{Score: 9, Level: 5}, {Score: 0, Level: 2}})
+	assert.Equal(t, 2, policy.Map(local, nil).Impact)
+	policy.ProbabilityThresholds = []byte(`[{"score": 11, "level": 3}]`)
+	assert.Error(t, policy.Validate())
+	policy.ProbabilityThresholds = []byte(`[{"score": 5, "level": 0}]`)
+	assert.Error(t, policy.Validate())
+}
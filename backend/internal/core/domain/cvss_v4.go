@@ -0,0 +1,342 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CVSS v4.0 is not a formula: each vector falls in a macrovector, a tuple of
+// six equivalence classes (EQ1 to EQ6) whose score was set by experts, then
+// is placed within the macrovector by its distance to the most severe
+// vectors of the class. This follows the FIRST reference implementation.
+
+// m4 returns the value of a metric used for scoring: the modified metric
+// when defined, with the defaults of the specification for Not Defined
+// exploit maturity (Attacked) and security requirements (High)
+func (v *CVSSVector) m4(name string) string {
+	switch name {
+	case "E":
+		if e := v.Metric("E"); e != "X" {
+			return e
+		}
+		return "A"
+	case "CR", "IR", "AR":
+		if r := v.Metric(name); r != "X" {
+			return r
+		}
+		return "H"
+	}
+	return v.effective(name)
+}
+
+// macroVector returns the equivalence class of each of EQ1 to EQ6
+func (v *CVSSVector) macroVector() [6]int {
+	var eq [6]int
+	av, pr, ui := v.m4("AV"), v.m4("PR"), v.m4("UI")
+	switch {
+	case av == "N" && pr == "N" && ui == "N":
+		eq[0] = 0
+	case (av == "N" || pr == "N" || ui == "N") && av != "P":
+		eq[0] = 1
+	default:
+		eq[0] = 2
+	}
+
+	if v.m4("AC") != "L" || v.m4("AT") != "N" {
+		eq[1] = 1
+	}
+
+	vc, vi, va := v.m4("VC"), v.m4("VI"), v.m4("VA")
+	switch {
+	case vc == "H" && vi == "H":
+		eq[2] = 0
+	case vc == "H" || vi == "H" || va == "H":
+		eq[2] = 1
+	default:
+		eq[2] = 2
+	}
+
+	switch {
+	case v.Metric("MSI") == "S" || v.Metric("MSA") == "S":
+		eq[3] = 0
+	case v.m4("SC") == "H" || v.m4("SI") == "H" || v.m4("SA") == "H":
+		eq[3] = 1
+	default:
+		eq[3] = 2
+	}
+
+	switch v.m4("E") {
+	case "P":
+		eq[4] = 1
+	case "U":
+		eq[4] = 2
+	}
+
+	if !((v.m4("CR") == "H" && vc == "H") || (v.m4("IR") == "H" && vi == "H") || (v.m4("AR") == "H" && va == "H")) {
+		eq[5] = 1
+	}
+	return eq
+}
+
+func macroVectorKey(eq [6]int) string {
+	return fmt.Sprintf("%d%d%d%d%d%d", eq[0], eq[1], eq[2], eq[3], eq[4], eq[5])
+}
+
+// cvss4Level is the severity distance of each metric value from the most
+// severe one, in steps of 0.1
+var cvss4Level = map[string]map[string]float64{
+	"AV": {"N": 0, "A": 0.1, "L": 0.2, "P": 0.3},
+	"PR": {"N": 0, "L": 0.1, "H": 0.2},
+	"UI": {"N": 0, "P": 0.1, "A": 0.2},
+	"AC": {"L": 0, "H": 0.1},
+	"AT": {"N": 0, "P": 0.1},
+	"VC": {"H": 0, "L": 0.1, "N": 0.2},
+	"VI": {"H": 0, "L": 0.1, "N": 0.2},
+	"VA": {"H": 0, "L": 0.1, "N": 0.2},
+	"SC": {"H": 0.1, "L": 0.2, "N": 0.3},
+	"SI": {"S": 0, "H": 0.1, "L": 0.2, "N": 0.3},
+	"SA": {"S": 0, "H": 0.1, "L": 0.2, "N": 0.3},
+	"CR": {"H": 0, "M": 0.1, "L": 0.2},
+	"IR": {"H": 0, "M": 0.1, "L": 0.2},
+	"AR": {"H": 0, "M": 0.1, "L": 0.2},
+}
+
+// cvss4MaxComposed lists, for each class of each EQ, the most severe vectors
+// of the class. EQ3 and EQ6 are combined, indexed by EQ3 then EQ6.
+var (
+	cvss4MaxEQ1 = [][]string{
+		{"AV:N/PR:N/UI:N"},
+		{"AV:A/PR:N/UI:N", "AV:N/PR:L/UI:N", "AV:N/PR:N/UI:P"},
+		{"AV:P/PR:N/UI:N", "AV:A/PR:L/UI:P"},
+	}
+	cvss4MaxEQ2 = [][]string{
+		{"AC:L/AT:N"},
+		{"AC:H/AT:N", "AC:L/AT:P"},
+	}
+	cvss4MaxEQ3EQ6 = [][][]string{
+		{
+			{"VC:H/VI:H/VA:H/CR:H/IR:H/AR:H"},
+			{"VC:H/VI:H/VA:L/CR:M/IR:M/AR:H", "VC:H/VI:H/VA:H/CR:M/IR:M/AR:M"},
+		},
+		{
+			{"VC:L/VI:H/VA:H/CR:H/IR:H/AR:H", "VC:H/VI:L/VA:H/CR:H/IR:H/AR:H"},
+			{"VC:L/VI:H/VA:L/CR:H/IR:M/AR:H", "VC:L/VI:H/VA:H/CR:H/IR:M/AR:M", "VC:H/VI:L/VA:H/CR:M/IR:H/AR:M", "VC:H/VI:L/VA:L/CR:M/IR:H/AR:H", "VC:L/VI:L/VA:H/CR:H/IR:H/AR:M"},
+		},
+		{
+			nil,
+			{"VC:L/VI:L/VA:L/CR:H/IR:H/AR:H"},
+		},
+	}
+	cvss4MaxEQ4 = [][]string{
+		{"SC:H/SI:S/SA:S"},
+		{"SC:H/SI:H/SA:H"},
+		{"SC:L/SI:L/SA:L"},
+	}
+)
+
+// cvss4MaxSeverity is, for each class of each EQ, the severity distance
+// between its most and least severe vectors, in steps of 0.1
+var (
+	cvss4MaxSeverityEQ1    = []float64{1, 4, 5}
+	cvss4MaxSeverityEQ2    = []float64{1, 2}
+	cvss4MaxSeverityEQ3EQ6 = [][]float64{{7, 6}, {8, 8}, {0, 10}}
+	cvss4MaxSeverityEQ4    = []float64{6, 5, 4}
+)
+
+// score4 computes the CVSS v4.0 score of the vector with all its metrics
+func (v *CVSSVector) score4() float64 {
+	impact := []string{"VC", "VI", "VA", "SC", "SI", "SA"}
+	none := true
+	for _, name := range impact {
+		if v.m4(name) != "N" {
+			none = false
+		}
+	}
+	if none {
+		return 0
+	}
+
+	eq := v.macroVector()
+	value, ok := cvss4MacroVectorScores[macroVectorKey(eq)]
+	if !ok {
+		return 0
+	}
+
+	// Score of the next less severe macrovector of each EQ, NaN if none
+	lower := func(index int) float64 {
+		next := eq
+		next[index]++
+		if score, ok := cvss4MacroVectorScores[macroVectorKey(next)]; ok {
+			return score
+		}
+		return math.NaN()
+	}
+	lowerEQ3EQ6 := math.NaN()
+	switch {
+	case eq[2] == 0 && eq[5] == 0:
+		left, right := eq, eq
+		left[5] = 1
+		right[2] = 1
+		l, lok := cvss4MacroVectorScores[macroVectorKey(left)]
+		r, rok := cvss4MacroVectorScores[macroVectorKey(right)]
+		switch {
+		case lok && rok:
+			lowerEQ3EQ6 = math.Max(l, r)
+		case lok:
+			lowerEQ3EQ6 = l
+		case rok:
+			lowerEQ3EQ6 = r
+		}
+	case eq[2] == 0 && eq[5] == 1, eq[2] == 1 && eq[5] == 0:
+		next := eq
+		next[2], next[5] = 1, 1
+		if score, ok := cvss4MacroVectorScores[macroVectorKey(next)]; ok {
+			lowerEQ3EQ6 = score
+		}
+	case eq[2] == 1 && eq[5] == 1:
+		next := eq
+		next[2] = 2
+		if score, ok := cvss4MacroVectorScores[macroVectorKey(next)]; ok {
+			lowerEQ3EQ6 = score
+		}
+	}
+
+	// The first most severe vector of the macrovector that the vector does
+	// not exceed on any metric
+	distance := func(max map[string]string, name string) float64 {
+		return cvss4Level[name][v.m4(name)] - cvss4Level[name][max[name]]
+	}
+	var maxVector map[string]string
+	for _, eq1 := range cvss4MaxEQ1[eq[0]] {
+		for _, eq2 := range cvss4MaxEQ2[eq[1]] {
+			for _, eq3eq6 := range cvss4MaxEQ3EQ6[eq[2]][eq[5]] {
+				for _, eq4 := range cvss4MaxEQ4[eq[3]] {
+					candidate := parseCVSSMetrics(eq1 + "/" + eq2 + "/" + eq3eq6 + "/" + eq4)
+					exceeds := false
+					for name := range cvss4Level {
+						if distance(candidate, name) < 0 {
+							exceeds = true
+							break
+						}
+					}
+					if !exceeds {
+						maxVector = candidate
+						break
+					}
+				}
+				if maxVector != nil {
+					break
+				}
+			}
+			if maxVector != nil {
+				break
+			}
+		}
+		if maxVector != nil {
+			break
+		}
+	}
+	if maxVector == nil {
+		return roundCVSS4(value)
+	}
+	sum := func(names ...string) float64 {
+		var total float64
+		for _, name := range names {
+			total += distance(maxVector, name)
+		}
+		return total
+	}
+
+	type eqDistance struct {
+		available, current, maxSeverity float64
+	}
+	distances := []eqDistance{
+		{value - lower(0), sum("AV", "PR", "UI"), cvss4MaxSeverityEQ1[eq[0]]},
+		{value - lower(1), sum("AC", "AT"), cvss4MaxSeverityEQ2[eq[1]]},
+		{value - lowerEQ3EQ6, sum("VC", "VI", "VA", "CR", "IR", "AR"), cvss4MaxSeverityEQ3EQ6[eq[2]][eq[5]]},
+		{value - lower(3), sum("SC", "SI", "SA"), cvss4MaxSeverityEQ4[eq[3]]},
+		// Exploit maturity has a single value per class: no distance
+		{value - lower(4), 0, 1},
+	}
+	var total float64
+	var existing int
+	for _, d := range distances {
+		if math.IsNaN(d.available) {
+			continue
+		}
+		existing++
+		total += d.available * (d.current / (d.maxSeverity * 0.1))
+	}
+	if existing > 0 {
+		value -= total / float64(existing)
+	}
+	return roundCVSS4(math.Min(math.Max(value, 0), 10))
+}
+
+func roundCVSS4(value float64) float64 {
+	return math.Round((value+1e-6)*10) / 10
+}
+
+func parseCVSSMetrics(vector string) map[string]string {
+	metrics := map[string]string{}
+	for _, part := range strings.Split(vector, "/") {
+		if name, value, ok := strings.Cut(part, ":"); ok {
+			metrics[name] = value
+		}
+	}
+	return metrics
+}
+
+// cvss4MacroVectorScores is the score of each macrovector, keyed by its
+// EQ1 to EQ6 classes
+var cvss4MacroVectorScores = map[string]float64{
+	"000000": 10, "000001": 9.9, "000010": 9.8, "000011": 9.5, "000020": 9.5, "000021": 9.2,
+	"000100": 10, "000101": 9.6, "000110": 9.3, "000111": 8.7, "000120": 9.1, "000121": 8.1,
+	"000200": 9.3, "000201": 9, "000210": 8.9, "000211": 8, "000220": 8.1, "000221": 6.8,
+	"001000": 9.8, "001001": 9.5, "001010": 9.5, "001011": 9.2, "001020": 9, "001021": 8.4,
+	"001100": 9.3, "001101": 9.2, "001110": 8.9, "001111": 8.1, "001120": 8.1, "001121": 6.5,
+	"001200": 8.8, "001201": 8, "001210": 7.8, "001211": 7, "001220": 6.9, "001221": 4.8,
+	"002001": 9.2, "002011": 8.2, "002021": 7.2, "002101": 7.9, "002111": 6.9, "002121": 5,
+	"002201": 6.9, "002211": 5.5, "002221": 2.7,
+	"010000": 9.9, "010001": 9.7, "010010": 9.5, "010011": 9.2, "010020": 9.2, "010021": 8.5,
+	"010100": 9.5, "010101": 9.1, "010110": 9, "010111": 8.3, "010120": 8.4, "010121": 7.1,
+	"010200": 9.2, "010201": 8.1, "010210": 8.2, "010211": 7.1, "010220": 7.2, "010221": 5.3,
+	"011000": 9.5, "011001": 9.3, "011010": 9.2, "011011": 8.5, "011020": 8.5, "011021": 7.3,
+	"011100": 9.2, "011101": 8.2, "011110": 8, "011111": 7.2, "011120": 7, "011121": 5.9,
+	"011200": 8.4, "011201": 7, "011210": 7.1, "011211": 5.2, "011220": 5, "011221": 3,
+	"012001": 8.6, "012011": 7.5, "012021": 5.2, "012101": 7.1, "012111": 5.2, "012121": 2.9,
+	"012201": 6.3, "012211": 2.9, "012221": 1.7,
+	"100000": 9.8, "100001": 9.5, "100010": 9.4, "100011": 8.7, "100020": 9.1, "100021": 8.1,
+	"100100": 9.4, "100101": 8.9, "100110": 8.6, "100111": 7.4, "100120": 7.7, "100121": 6.4,
+	"100200": 8.7, "100201": 7.5, "100210": 7.4, "100211": 6.3, "100220": 6.3, "100221": 4.9,
+	"101000": 9.4, "101001": 8.9, "101010": 8.8, "101011": 7.7, "101020": 7.6, "101021": 6.7,
+	"101100": 8.6, "101101": 7.6, "101110": 7.4, "101111": 5.8, "101120": 5.9, "101121": 5,
+	"101200": 7.2, "101201": 5.7, "101210": 5.7, "101211": 5.2, "101220": 5.2, "101221": 2.5,
+	"102001": 8.3, "102011": 7, "102021": 5.4, "102101": 6.5, "102111": 5.8, "102121": 2.6,
+	"102201": 5.3, "102211": 2.1, "102221": 1.3,
+	"110000": 9.5, "110001": 9, "110010": 8.8, "110011": 7.6, "110020": 7.6, "110021": 7,
+	"110100": 9, "110101": 7.7, "110110": 7.5, "110111": 6.2, "110120": 6.1, "110121": 5.3,
+	"110200": 7.7, "110201": 6.6, "110210": 6.8, "110211": 5.9, "110220": 5.2, "110221": 3,
+	"111000": 8.9, "111001": 7.8, "111010": 7.6, "111011": 6.7, "111020": 6.2, "111021": 5.8,
+	"111100": 7.4, "111101": 5.9, "111110": 5.7, "111111": 5.7, "111120": 4.7, "111121": 2.3,
+	"111200": 6.1, "111201": 5.2, "111210": 5.7, "111211": 2.9, "111220": 2.4, "111221": 1.6,
+	"112001": 7.1, "112011": 5.9, "112021": 3, "112101": 5.8, "112111": 2.6, "112121": 1.5,
+	"112201": 2.3, "112211": 1.3, "112221": 0.6,
+	"200000": 9.3, "200001": 8.7, "200010": 8.6, "200011": 7.2, "200020": 7.5, "200021": 5.8,
+	"200100": 8.6, "200101": 7.4, "200110": 7.4, "200111": 6.1, "200120": 5.6, "200121": 3.4,
+	"200200": 7, "200201": 5.4, "200210": 5.2, "200211": 4, "200220": 4, "200221": 2.2,
+	"201000": 8.5, "201001": 7.5, "201010": 7.4, "201011": 5.5, "201020": 6.2, "201021": 5.1,
+	"201100": 7.2, "201101": 5.7, "201110": 5.5, "201111": 4.1, "201120": 4.6, "201121": 1.9,
+	"201200": 5.3, "201201": 3.6, "201210": 3.4, "201211": 1.9, "201220": 1.9, "201221": 0.8,
+	"202001": 6.4, "202011": 5.1, "202021": 2, "202101": 4.7, "202111": 2.1, "202121": 1.1,
+	"202201": 2.4, "202211": 0.9, "202221": 0.4,
+	"210000": 8.8, "210001": 7.5, "210010": 7.3, "210011": 5.3, "210020": 6, "210021": 5,
+	"210100": 7.3, "210101": 5.5, "210110": 5.9, "210111": 4, "210120": 4.1, "210121": 2,
+	"210200": 5.4, "210201": 4.3, "210210": 4.5, "210211": 2.2, "210220": 2, "210221": 1.1,
+	"211000": 7.5, "211001": 5.5, "211010": 5.8, "211011": 4.5, "211020": 4, "211021": 2.1,
+	"211100": 6.1, "211101": 5.1, "211110": 4.8, "211111": 1.8, "211120": 2, "211121": 0.9,
+	"211200": 4.6, "211201": 1.8, "211210": 1.7, "211211": 0.7, "211220": 0.8, "211221": 0.2,
+	"212001": 5.3, "212011": 2.4, "212021": 1.4, "212101": 2.4, "212111": 1.2, "212121": 0.5,
+	"212201": 1, "212211": 0.3, "212221": 0.1,
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// WidgetType is the kind of data a dashboard widget renders
+type WidgetType string
+
+const (
+	WidgetRiskMatrix        WidgetType = "risk_matrix"        // Risk counts per impact x probability cell
+	WidgetTrend             WidgetType = "trend"              // Daily risk count and average score
+	WidgetTopRisks          WidgetType = "top_risks"          // Highest scored risks
+	WidgetSLABreaches       WidgetType = "sla_breaches"       // Overdue reviews and mitigations
+	WidgetFrameworkCoverage WidgetType = "framework_coverage" // Risks per framework
+)
+
+// ValidWidgetTypes lists the widget types dashboards accept
+var ValidWidgetTypes = []WidgetType{WidgetRiskMatrix, WidgetTrend, WidgetTopRisks, WidgetSLABreaches, WidgetFrameworkCoverage}
+
+// Dashboard is a user-configured page of widgets. Its filter applies to every
+// widget, on top of each widget's own filter.
+type Dashboard struct {
+	ID          uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    uuid.UUID         `gorm:"type:uuid;index" json:"tenant_id"`
+	OwnerID     uuid.UUID         `gorm:"type:uuid;not null;index" json:"owner_id"`
+	Name        string            `gorm:"size:255;not null" json:"name"`
+	Description string            `gorm:"type:text" json:"description"`
+	Filter      string            `gorm:"type:text" json:"filter"`
+	Visibility  ViewVisibility    `gorm:"size:10;default:'private';index" json:"visibility"`
+	TeamID      *uuid.UUID        `gorm:"type:uuid;index" json:"team_id,omitempty"`
+	IsDefault   bool              `gorm:"default:false" json:"is_default"` // The owner's home dashboard
+	Widgets     []DashboardWidget `gorm:"foreignKey:DashboardID;constraint:OnDelete:CASCADE" json:"widgets"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// DashboardWidget is one widget of a dashboard with its grid placement
+type DashboardWidget struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DashboardID uuid.UUID      `gorm:"type:uuid;not null;index" json:"dashboard_id"`
+	Type        WidgetType     `gorm:"size:30;not null" json:"type"`
+	Title       string         `gorm:"size:255" json:"title"`
+	Filter      string         `gorm:"type:text" json:"filter"`
+	Options     datatypes.JSON `gorm:"type:jsonb" json:"options,omitempty"` // Type specific: {"limit": 10}, {"days": 90}
+	Position    int            `json:"position"`
+	X           int            `json:"x"`
+	Y           int            `json:"y"`
+	Width       int            `gorm:"default:4" json:"width"`
+	Height      int            `gorm:"default:3" json:"height"`
+	RefreshRate int            `json:"refresh_rate"` // Seconds between client refreshes, 0 for manual
+}
+
+// TableName returns the table name for Dashboard
+func (Dashboard) TableName() string {
+	return "dashboards"
+}
+
+// TableName returns the table name for DashboardWidget
+func (DashboardWidget) TableName() string {
+	return "dashboard_widgets"
+}
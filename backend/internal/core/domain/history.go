@@ -1,11 +1,28 @@
 package domain
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 )
 
+// Types de changement enregistrés par les hooks ; les workflows en ajoutent
+// d'autres (EXCEPTION_APPROVED, EXCEPTION_EXPIRED...)
+const (
+	RiskChangeCreate  = "CREATE"
+	RiskChangeUpdate  = "UPDATE"
+	RiskChangeDelete  = "DELETE"
+	RiskChangeRestore = "RESTORE"
+)
+
+// SystemActor : auteur des changements faits hors requête authentifiée
+// (SyncEngine, tâches planifiées)
+const SystemActor = "System"
+
 // RiskHistory : Trace l'évolution d'un risque dans le temps
 type RiskHistory struct {
 	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
@@ -17,8 +34,67 @@ type RiskHistory struct {
 	Probability int        `json:"probability"`
 	Status      RiskStatus `json:"status"`
 
+	// Ce qui a changé : version produite (voir RiskVersion) et diff champ par champ
+	Version int            `json:"version,omitempty"`
+	Changes datatypes.JSON `gorm:"type:jsonb" json:"changes,omitempty"` // []RiskFieldChange
+
 	// Qui et Quand
 	ChangedBy  string    `json:"changed_by"`  // User ID ou "System" (SyncEngine)
-	ChangeType string    `json:"change_type"` // CREATE, UPDATE, MITIGATE
+	ChangeType string    `json:"change_type"` // CREATE, UPDATE, DELETE, RESTORE, EXCEPTION_*...
 	CreatedAt  time.Time `json:"created_at"`  // Timestamp du changement
 }
+
+// RiskFieldChange : valeur d'un champ avant et après un changement ; nil si absent
+type RiskFieldChange struct {
+	Field string      `json:"field"` // title, tags, custom_fields.<clé>, assets, mitigations[<titre>].status...
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// FieldChanges décode le diff du changement (vide pour l'historique antérieur)
+func (h *RiskHistory) FieldChanges() ([]RiskFieldChange, error) {
+	var changes []RiskFieldChange
+	if len(h.Changes) == 0 {
+		return changes, nil
+	}
+	if err := json.Unmarshal(h.Changes, &changes); err != nil {
+		return nil, fmt.Errorf("invalid risk history changes: %w", err)
+	}
+	return changes, nil
+}
+
+// SetFieldChanges encode le diff du changement
+func (h *RiskHistory) SetFieldChanges(changes []RiskFieldChange) error {
+	if len(changes) == 0 {
+		h.Changes = nil
+		return nil
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode risk history changes: %w", err)
+	}
+	h.Changes = raw
+	return nil
+}
+
+type actorKey struct{}
+
+// WithActor attache l'auteur des changements au contexte ; les hooks des
+// risques et mitigations l'enregistrent dans l'historique quand la
+// transaction porte ce contexte (db.WithContext)
+func WithActor(ctx context.Context, actor string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom renvoie l'auteur attaché au contexte, SystemActor par défaut
+func ActorFrom(ctx context.Context) string {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+			return actor
+		}
+	}
+	return SystemActor
+}
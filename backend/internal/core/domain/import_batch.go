@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+)
+
+// ImportEntity is the kind of record an import batch creates or updates
+type ImportEntity string
+
+const (
+	ImportEntityRisk       ImportEntity = "risk"
+	ImportEntityAsset      ImportEntity = "asset"
+	ImportEntityMitigation ImportEntity = "mitigation"
+)
+
+// ImportBatchStatus tracks an import through upload, dry-run, commit and rollback
+type ImportBatchStatus string
+
+const (
+	ImportStatusUploaded   ImportBatchStatus = "UPLOADED"    // File stored, mapping suggested
+	ImportStatusValidated  ImportBatchStatus = "VALIDATED"   // Dry-run computed with the confirmed mapping
+	ImportStatusCommitting ImportBatchStatus = "COMMITTING"  // Commit job queued or running
+	ImportStatusCommitted  ImportBatchStatus = "COMMITTED"   // Rows applied, rollback journal recorded
+	ImportStatusFailed     ImportBatchStatus = "FAILED"      // Commit failed; nothing was applied
+	ImportStatusRolledBack ImportBatchStatus = "ROLLED_BACK" // Every change of the batch was reverted
+)
+
+// ImportMatchMode decides how rows are matched against existing records
+type ImportMatchMode string
+
+const (
+	ImportMatchAuto       ImportMatchMode = "auto"        // External ID when the row has one, title/name otherwise
+	ImportMatchExternalID ImportMatchMode = "external_id" // Only rows with a known external ID update records
+	ImportMatchTitle      ImportMatchMode = "title"       // Match on title (risks, mitigations) or name (assets)
+	ImportMatchNone       ImportMatchMode = "none"        // Every row creates a record
+)
+
+// ImportRowAction is what committing a row does
+type ImportRowAction string
+
+const (
+	ImportActionCreate    ImportRowAction = "CREATE"
+	ImportActionUpdate    ImportRowAction = "UPDATE"
+	ImportActionUnchanged ImportRowAction = "UNCHANGED"
+	ImportActionError     ImportRowAction = "ERROR"
+)
+
+// ImportBatch is one uploaded spreadsheet and the state of its import
+type ImportBatch struct {
+	ID       uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID uuid.UUID         `gorm:"type:uuid;index" json:"tenant_id"`
+	Entity   ImportEntity      `gorm:"type:varchar(20);not null" json:"entity"`
+	Status   ImportBatchStatus `gorm:"type:varchar(20);default:'UPLOADED';index" json:"status"`
+	FileName string            `gorm:"size:255" json:"file_name"`
+	Format   string            `gorm:"size:10" json:"format"` // csv, xlsx
+	FileKey  string            `json:"-"`                     // Blob store key of the uploaded file
+	FileSize int64             `json:"file_size"`
+	Headers  pq.StringArray    `gorm:"type:text[]" json:"headers"`
+
+	// Confirmed (or suggested, before the dry-run) header -> field mapping
+	Mapping     datatypes.JSON  `gorm:"type:jsonb" json:"mapping"`
+	MatchBy     ImportMatchMode `gorm:"type:varchar(20);default:'auto'" json:"match_by"`
+	SkipInvalid bool            `gorm:"default:false" json:"skip_invalid"` // Commit valid rows even when others fail validation
+
+	// Dry-run results
+	TotalRows      int            `json:"total_rows"`
+	CreateCount    int            `json:"create_count"`
+	UpdateCount    int            `json:"update_count"`
+	UnchangedCount int            `json:"unchanged_count"`
+	ErrorCount     int            `json:"error_count"`
+	Preview        datatypes.JSON `gorm:"type:jsonb" json:"preview,omitempty"` // []ImportRowResult, errors first, capped
+
+	JobID        *uuid.UUID `gorm:"type:uuid;index" json:"job_id,omitempty"`
+	ErrorMessage string     `gorm:"type:text" json:"error_message,omitempty"`
+
+	CreatedBy    uuid.UUID  `gorm:"type:uuid;index" json:"created_by"`
+	ValidatedAt  *time.Time `json:"validated_at,omitempty"`
+	CommittedAt  *time.Time `json:"committed_at,omitempty"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+	RolledBackBy *uuid.UUID `gorm:"type:uuid" json:"rolled_back_by,omitempty"`
+	// Records changed since the import, which the rollback left as they were
+	RollbackSkippedCount int       `json:"rollback_skipped_count"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// ImportFieldChange is the old and new value of a field in the dry-run diff
+type ImportFieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ImportRowResult is the dry-run outcome of one spreadsheet row
+type ImportRowResult struct {
+	Row      int                          `json:"row"` // Spreadsheet line number, the header being line 1
+	Action   ImportRowAction              `json:"action"`
+	Key      string                       `json:"key"` // Title or name identifying the row
+	EntityID *uuid.UUID                   `json:"entity_id,omitempty"`
+	Changes  map[string]ImportFieldChange `json:"changes,omitempty"`
+	Errors   []string                     `json:"errors,omitempty"`
+}
+
+// ImportRecord journals a change applied by a committed batch so the whole
+// batch can be rolled back
+type ImportRecord struct {
+	ID        uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	BatchID   uuid.UUID       `gorm:"type:uuid;not null;index" json:"batch_id"`
+	RowNumber int             `json:"row"`
+	Entity    ImportEntity    `gorm:"type:varchar(20)" json:"entity"`
+	EntityID  uuid.UUID       `gorm:"type:uuid;index" json:"entity_id"`
+	Action    ImportRowAction `gorm:"type:varchar(20)" json:"action"`
+	// Column values the update overwrote; empty for creates
+	Before datatypes.JSON `gorm:"type:jsonb" json:"before,omitempty"`
+	// The record's updated_at right after the import changed it; a rollback leaves
+	// records updated since then alone and flags them RollbackSkipped
+	EntityUpdatedAt *time.Time `json:"entity_updated_at,omitempty"`
+	RollbackSkipped bool       `gorm:"default:false" json:"rollback_skipped"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// CanValidate reports whether the mapping can still be changed and the dry-run re-run
+func (b *ImportBatch) CanValidate() bool {
+	return b.Status == ImportStatusUploaded || b.Status == ImportStatusValidated || b.Status == ImportStatusFailed
+}
+
+// TableName returns the table name for ImportBatch
+func (ImportBatch) TableName() string {
+	return "import_batches"
+}
+
+// TableName returns the table name for ImportRecord
+func (ImportRecord) TableName() string {
+	return "import_records"
+}
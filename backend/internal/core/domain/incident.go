@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// RiskChangeRecalibrated is the history change type recorded on a risk whose
+// probability was raised because it materialized repeatedly
+const RiskChangeRecalibrated = "PROBABILITY_RECALIBRATED"
+
+// IncidentSourceManual is the source of incidents recorded through the API
+const IncidentSourceManual = "MANUAL"
+
+// ErrInvalidLossEvent is returned for a loss event with negative amounts or
+// dates out of order
+var ErrInvalidLossEvent = errors.New("invalid loss event")
+
+// Incident représente une alerte ou un cas (Contrat avec TheHive), persisté
+// avec les risques qu'il a réalisés et ses pertes. Source et ExternalID
+// l'identifient dans l'outil tiers ; CreatedAt est sa date d'ouverture.
+type Incident struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Title       string     `gorm:"size:255;not null" json:"title"`
+	Status      string     `gorm:"size:50;index" json:"status"`
+	Severity    string     `gorm:"size:20;index" json:"severity"` // LOW, MEDIUM, HIGH, CRITICAL
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	Description string     `gorm:"type:text" json:"description"`
+	Source      string     `gorm:"size:50;not null;default:'MANUAL';index:idx_incidents_source_external_id" json:"source"`
+	ExternalID  string     `gorm:"size:255;index:idx_incidents_source_external_id" json:"external_id,omitempty"`
+
+	Risks      []IncidentRisk `gorm:"foreignKey:IncidentID" json:"risks,omitempty"`
+	LossEvents []LossEvent    `gorm:"foreignKey:IncidentID" json:"loss_events,omitempty"`
+}
+
+// TableName returns the table name for Incident
+func (Incident) TableName() string {
+	return "incidents"
+}
+
+// TotalLoss sums the loss events of the incident
+func (i *Incident) TotalLoss() float64 {
+	total := 0.0
+	for _, e := range i.LossEvents {
+		total += e.TotalLoss()
+	}
+	return total
+}
+
+// IncidentRisk links an incident to a risk it realized
+type IncidentRisk struct {
+	IncidentID uuid.UUID `gorm:"type:uuid;primaryKey" json:"incident_id"`
+	RiskID     uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"risk_id"`
+	LinkedBy   string    `gorm:"size:100" json:"linked_by"` // Actor, System for the SyncEngine
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for IncidentRisk
+func (IncidentRisk) TableName() string {
+	return "incident_risks"
+}
+
+// LossEvent records what an incident cost, in the tenant's currency: the
+// direct loss (stolen funds, fines, lost revenue), the indirect loss
+// (reputation, churn, productivity) and the cost of recovery
+type LossEvent struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	IncidentID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"incident_id"`
+	Description  string     `gorm:"type:text" json:"description"`
+	DirectLoss   float64    `gorm:"type:numeric(14,2);not null;default:0" json:"direct_loss"`
+	IndirectLoss float64    `gorm:"type:numeric(14,2);not null;default:0" json:"indirect_loss"`
+	RecoveryCost float64    `gorm:"type:numeric(14,2);not null;default:0" json:"recovery_cost"`
+	OccurredAt   time.Time  `gorm:"not null;index" json:"occurred_at"`
+	DiscoveredAt *time.Time `json:"discovered_at,omitempty"`
+	RecoveredAt  *time.Time `json:"recovered_at,omitempty"`
+	RecordedBy   string     `gorm:"size:100" json:"recorded_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for LossEvent
+func (LossEvent) TableName() string {
+	return "loss_events"
+}
+
+// TotalLoss sums the direct and indirect loss and the recovery cost
+func (e *LossEvent) TotalLoss() float64 {
+	return e.DirectLoss + e.IndirectLoss + e.RecoveryCost
+}
+
+// Validate checks the amounts and that the loss was discovered and recovered
+// after it occurred
+func (e *LossEvent) Validate() error {
+	if e.DirectLoss < 0 || e.IndirectLoss < 0 || e.RecoveryCost < 0 {
+		return fmt.Errorf("%w: amounts must not be negative", ErrInvalidLossEvent)
+	}
+	if e.OccurredAt.IsZero() {
+		return fmt.Errorf("%w: occurred_at is required", ErrInvalidLossEvent)
+	}
+	if e.DiscoveredAt != nil && e.DiscoveredAt.Before(e.OccurredAt) {
+		return fmt.Errorf("%w: discovered_at is before occurred_at", ErrInvalidLossEvent)
+	}
+	if e.RecoveredAt != nil && e.RecoveredAt.Before(e.OccurredAt) {
+		return fmt.Errorf("%w: recovered_at is before occurred_at", ErrInvalidLossEvent)
+	}
+	return nil
+}
+
+// MaterializationThreshold raises to Probability the risks realized by at
+// least Occurrences incidents within the policy window
+type MaterializationThreshold struct {
+	Occurrences int `json:"occurrences"`
+	Probability int `json:"probability"`
+}
+
+// RecalibrationPolicy sets, for a tenant, how incidents raise the
+// probability of the risks they realize. Probability is never lowered.
+type RecalibrationPolicy struct {
+	ID         uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID   uuid.UUID      `gorm:"type:uuid;uniqueIndex" json:"tenant_id"`
+	Enabled    bool           `gorm:"not null;default:false" json:"enabled"`
+	WindowDays int            `gorm:"not null;default:365" json:"window_days"` // Incidents opened earlier are not counted
+	Thresholds datatypes.JSON `gorm:"type:jsonb" json:"thresholds"`            // []MaterializationThreshold
+
+	UpdatedBy uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for RecalibrationPolicy
+func (RecalibrationPolicy) TableName() string {
+	return "recalibration_policies"
+}
+
+// DefaultMaterializationThresholds is the mapping of tenants without a policy
+var DefaultMaterializationThresholds = []MaterializationThreshold{
+	{Occurrences: 3, Probability: 5},
+	{Occurrences: 2, Probability: 4},
+}
+
+// DefaultRecalibrationPolicy is used for tenants without a configured
+// policy: a risk realized twice in a year becomes likely, three times almost
+// certain
+func DefaultRecalibrationPolicy(tenantID uuid.UUID) RecalibrationPolicy {
+	thresholds, _ := json.Marshal(DefaultMaterializationThresholds)
+	return RecalibrationPolicy{
+		TenantID:   tenantID,
+		Enabled:    true,
+		WindowDays: 365,
+		Thresholds: thresholds,
+	}
+}
+
+// Mapping returns the thresholds, most occurrences first
+func (p *RecalibrationPolicy) Mapping() []MaterializationThreshold {
+	var thresholds []MaterializationThreshold
+	if len(p.Thresholds) > 0 {
+		_ = json.Unmarshal(p.Thresholds, &thresholds)
+	}
+	sort.SliceStable(thresholds, func(i, j int) bool { return thresholds[i].Occurrences > thresholds[j].Occurrences })
+	return thresholds
+}
+
+// Window returns the period over which incidents are counted
+func (p *RecalibrationPolicy) Window() time.Duration {
+	return time.Duration(p.WindowDays) * 24 * time.Hour
+}
+
+// Validate checks the window and the thresholds of the policy
+func (p *RecalibrationPolicy) Validate() error {
+	if p.WindowDays < 1 || p.WindowDays > 3650 {
+		return fmt.Errorf("window_days must be between 1 and 3650")
+	}
+	var thresholds []MaterializationThreshold
+	if len(p.Thresholds) > 0 {
+		if err := json.Unmarshal(p.Thresholds, &thresholds); err != nil {
+			return fmt.Errorf("invalid thresholds: %w", err)
+		}
+	}
+	for _, t := range thresholds {
+		if t.Occurrences < 1 {
+			return fmt.Errorf("occurrences %d must be at least 1", t.Occurrences)
+		}
+		if t.Probability < 1 || t.Probability > 5 {
+			return fmt.Errorf("probability for %d occurrences must be between 1 and 5", t.Occurrences)
+		}
+	}
+	return nil
+}
+
+// ProbabilityFor maps the number of incidents that realized a risk within
+// the window to a probability and the rule that gave it; 0 when no rule
+// applies or the policy is disabled
+func (p *RecalibrationPolicy) ProbabilityFor(occurrences int) (int, string) {
+	if !p.Enabled {
+		return 0, ""
+	}
+	for _, t := range p.Mapping() {
+		if occurrences >= t.Occurrences {
+			return t.Probability, fmt.Sprintf("materialized %d times in %d days (>= %d): probability %d", occurrences, p.WindowDays, t.Occurrences, t.Probability)
+		}
+	}
+	return 0, ""
+}
+
+// RiskRecalibration is the provenance of a probability raised by incidents:
+// the incident that triggered it, how many realized the risk within the
+// window and the change made
+type RiskRecalibration struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	RiskID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"risk_id"`
+	IncidentID      *uuid.UUID `gorm:"type:uuid;index" json:"incident_id,omitempty"` // Nil for a recalibration run
+	TenantID        uuid.UUID  `gorm:"type:uuid;index" json:"tenant_id"`             // Tenant whose policy applied
+	Occurrences     int        `json:"occurrences"`
+	WindowDays      int        `json:"window_days"`
+	ProbabilityFrom int        `json:"probability_from"`
+	ProbabilityTo   int        `json:"probability_to"`
+	Reason          string     `gorm:"type:text" json:"reason"`
+	CreatedAt       time.Time  `gorm:"index" json:"created_at"`
+}
+
+// TableName returns the table name for RiskRecalibration
+func (RiskRecalibration) TableName() string {
+	return "risk_recalibrations"
+}
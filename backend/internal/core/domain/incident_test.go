@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecalibrationPolicyProbabilityFor(t *testing.T) {
+	policy := DefaultRecalibrationPolicy(uuid.New())
+
+	p, rule := policy.ProbabilityFor(1)
+	assert.Zero(t, p, "a single occurrence is what the risk predicted")
+	assert.Empty(t, rule)
+
+	p, rule = policy.ProbabilityFor(2)
+	assert.Equal(t, 4, p)
+	assert.Equal(t, "materialized 2 times in 365 days (>= 2): probability 4", rule)
+
+	p, _ = policy.ProbabilityFor(7)
+	assert.Equal(t, 5, p)
+
+	policy.Enabled = false
+	p, _ = policy.ProbabilityFor(7)
+	assert.Zero(t, p, "a disabled policy never recalibrates")
+}
+
+func TestRecalibrationPolicyValidate(t *testing.T) {
+	policy := DefaultRecalibrationPolicy(uuid.Nil)
+	assert.NoError(t, policy.Validate())
+
+	policy.WindowDays = 0
+	assert.Error(t, policy.Validate())
+
+	policy.WindowDays = 90
+	policy.Thresholds, _ = json.Marshal([]MaterializationThreshold{{Occurrences: 0, Probability: 3}})
+	assert.Error(t, policy.Validate())
+
+	policy.Thresholds, _ = json.Marshal([]MaterializationThreshold{{Occurrences: 2, Probability: 6}})
+	assert.Error(t, policy.Validate())
+}
+
+func TestLossEventValidate(t *testing.T) {
+	occurred := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	before := occurred.Add(-time.Hour)
+	after := occurred.AddDate(0, 0, 10)
+
+	event := LossEvent{DirectLoss: 1000, IndirectLoss: 250.5, RecoveryCost: 49.5, OccurredAt: occurred, RecoveredAt: &after}
+	assert.NoError(t, event.Validate())
+	assert.Equal(t, 1300.0, event.TotalLoss())
+
+	invalid := []LossEvent{
+		{DirectLoss: -1, OccurredAt: occurred},
+		{DirectLoss: 10},
+		{DirectLoss: 10, OccurredAt: occurred, DiscoveredAt: &before},
+		{DirectLoss: 10, OccurredAt: occurred, RecoveredAt: &before},
+	}
+	for _, e := range invalid {
+		assert.True(t, errors.Is(e.Validate(), ErrInvalidLossEvent))
+	}
+
+	incident := Incident{LossEvents: []LossEvent{event, {RecoveryCost: 200}}}
+	assert.Equal(t, 1500.0, incident.TotalLoss())
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// JobStatus represents the lifecycle of a background job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "QUEUED"
+	JobRunning   JobStatus = "RUNNING"
+	JobSucceeded JobStatus = "SUCCEEDED"
+	JobFailed    JobStatus = "FAILED"
+	JobCancelled JobStatus = "CANCELLED"
+)
+
+// DefaultJobMaxAttempts is used when a job is enqueued without an explicit attempt limit
+const DefaultJobMaxAttempts = 5
+
+// Job is a unit of background work persisted in the jobs table.
+// Workers claim queued jobs with SELECT ... FOR UPDATE SKIP LOCKED and hold
+// them under a lease (LockedUntil); a job whose lease expires is handed to
+// another worker, so work survives process restarts.
+type Job struct {
+	ID       uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID uuid.UUID      `gorm:"type:uuid;index;uniqueIndex:idx_jobs_idempotency" json:"tenant_id"`
+	Type     string         `gorm:"size:100;not null;index" json:"type"`
+	Payload  datatypes.JSON `gorm:"type:jsonb" json:"payload,omitempty"`
+	Status   JobStatus      `gorm:"size:20;not null;default:'QUEUED';index" json:"status"`
+	Priority int            `gorm:"default:0" json:"priority"` // Higher runs first
+
+	// IdempotencyKey deduplicates enqueues; enqueueing the same key twice returns the first job
+	IdempotencyKey *string `gorm:"size:255;uniqueIndex:idx_jobs_idempotency" json:"idempotency_key,omitempty"`
+
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"default:5" json:"max_attempts"`
+	RunAt       time.Time  `gorm:"index" json:"run_at"` // Not picked up before this time (retry backoff, scheduled jobs)
+	LockedBy    string     `gorm:"size:100" json:"locked_by,omitempty"`
+	LockedUntil *time.Time `gorm:"index" json:"locked_until,omitempty"` // Visibility timeout of the current attempt
+
+	Progress        int            `gorm:"default:0" json:"progress"` // 0-100
+	ProgressMessage string         `gorm:"size:255" json:"progress_message,omitempty"`
+	LastError       string         `gorm:"type:text" json:"last_error,omitempty"`
+	Result          datatypes.JSON `gorm:"type:jsonb" json:"result,omitempty"`
+	CancelRequested bool           `gorm:"default:false" json:"cancel_requested"`
+
+	CreatedBy  uuid.UUID  `gorm:"type:uuid;index" json:"created_by"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// IsTerminal reports whether the job will not run again
+func (j *Job) IsTerminal() bool {
+	switch j.Status {
+	case JobSucceeded, JobFailed, JobCancelled:
+		return true
+	}
+	return false
+}
+
+// JobBackoff returns the delay before retrying a job that failed its n-th attempt:
+// base doubled for every previous attempt, capped at max.
+func JobBackoff(attempt int, base, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// TableName returns the table name for Job
+func (Job) TableName() string {
+	return "jobs"
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobBackoff(t *testing.T) {
+	base := 10 * time.Second
+	max := 5 * time.Minute
+
+	assert.Equal(t, 10*time.Second, JobBackoff(0, base, max))
+	assert.Equal(t, 10*time.Second, JobBackoff(1, base, max))
+	assert.Equal(t, 20*time.Second, JobBackoff(2, base, max))
+	assert.Equal(t, 80*time.Second, JobBackoff(4, base, max))
+	assert.Equal(t, max, JobBackoff(6, base, max))
+	assert.Equal(t, max, JobBackoff(60, base, max))
+}
+
+func TestJobIsTerminal(t *testing.T) {
+	for status, terminal := range map[JobStatus]bool{
+		JobQueued:    false,
+		JobRunning:   false,
+		JobSucceeded: true,
+		JobFailed:    true,
+		JobCancelled: true,
+	} {
+		job := Job{Status: status}
+		assert.Equal(t, terminal, job.IsTerminal(), status)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +30,10 @@ type Mitigation struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// Incrémentée à chaque sauvegarde par le hook AfterSave, jamais écrite par
+	// les sauvegardes elles-mêmes ; sert d'ETag
+	Version int `gorm:"->;not null;default:0" json:"version,omitempty"`
+
 	// Recommendation Engine
 	Cost           int `gorm:"default:1" json:"cost"`            // Catégorie de coût: 1 (Faible) à 3 (Élevé)
 	MitigationTime int `gorm:"default:1" json:"mitigation_time"` // Temps estimé en Jours
@@ -39,8 +44,36 @@ type Mitigation struct {
 	// Relation avec le Risque (pour la lecture)
 	Risk *Risk `json:"risk,omitempty"` // Preload
 
+	// Techniques MITRE ATT&CK contrées et contre-mesures mises en œuvre :
+	// mitigations ATT&CK (M1032) ou D3FEND (D3-MFA). Sans technique, la
+	// mitigation contre celles de son risque
+	Techniques      pq.StringArray `gorm:"type:text[]" json:"techniques,omitempty"`
+	Countermeasures pq.StringArray `gorm:"type:text[]" json:"countermeasures,omitempty"`
+
 	// Checklist / Sub-actions
 	SubActions []MitigationSubAction `gorm:"foreignKey:MitigationID" json:"sub_actions,omitempty"`
 
 	gorm.Model
 }
+
+// AfterSave : incrémente la version de la mitigation ; les mitigations font
+// partie des versions du risque
+func (m *Mitigation) AfterSave(tx *gorm.DB) (err error) {
+	if m.ID != uuid.Nil {
+		if err := tx.Table("mitigations").Where("id = ?", m.ID).
+			UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+			return err
+		}
+		m.Version++
+	}
+	return RecordRiskVersion(tx, m.RiskID)
+}
+
+// AfterDelete : versionne le risque sans la mitigation supprimée et place
+// la mitigation dans la corbeille (sauf suppression en cascade d'un risque)
+func (m *Mitigation) AfterDelete(tx *gorm.DB) (err error) {
+	if err := RecordRiskVersion(tx, m.RiskID); err != nil {
+		return err
+	}
+	return RecordDeletion(tx, RecycleMitigation, m.ID, DeletedCascade{})
+}
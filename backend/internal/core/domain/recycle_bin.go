@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecycleResource is a soft-deleted resource type kept in the recycle bin
+type RecycleResource string
+
+const (
+	RecycleRisk        RecycleResource = "risk"
+	RecycleAsset       RecycleResource = "asset"
+	RecycleMitigation  RecycleResource = "mitigation"
+	RecycleTeam        RecycleResource = "team"
+	RecycleCustomField RecycleResource = "custom_field"
+)
+
+// RecycleResources lists the resource types of the recycle bin
+var RecycleResources = []RecycleResource{RecycleRisk, RecycleAsset, RecycleMitigation, RecycleTeam, RecycleCustomField}
+
+// Audit actions of the recycle bin; the audited resource is the RecycleResource
+const (
+	ActionSoftDelete AuditLogAction = "soft_delete"
+	ActionRestore    AuditLogAction = "restore"
+	ActionPurge      AuditLogAction = "purge"
+)
+
+// Table and display column of each resource type
+var recycleTables = map[RecycleResource][2]string{
+	RecycleRisk:        {"risks", "title"},
+	RecycleAsset:       {"assets", "name"},
+	RecycleMitigation:  {"mitigations", "title"},
+	RecycleTeam:        {"teams", "name"},
+	RecycleCustomField: {"custom_fields", "name"},
+}
+
+// Table returns the table of the resource type, empty when unknown
+func (r RecycleResource) Table() string {
+	return recycleTables[r][0]
+}
+
+// DeletedItem is a soft-deleted resource in the recycle bin: who deleted it,
+// when, and what was removed along with it so a restore can put it back.
+// Cascaded items (e.g. the mitigations of a deleted risk) have no entry of
+// their own.
+type DeletedItem struct {
+	ID           uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ResourceType RecycleResource `gorm:"size:30;not null;uniqueIndex:idx_deleted_items_resource" json:"resource_type"`
+	ResourceID   uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_deleted_items_resource" json:"resource_id"`
+	Name         string          `gorm:"size:255" json:"name"`
+	DeletedBy    string          `gorm:"size:100" json:"deleted_by"` // User ID or "System"
+	DeletedAt    time.Time       `gorm:"not null;index" json:"deleted_at"`
+	Cascade      datatypes.JSON  `gorm:"type:jsonb" json:"cascade,omitempty"` // DeletedCascade
+
+	PurgeAt *time.Time `gorm:"-" json:"purge_at,omitempty"` // End of the retention window
+}
+
+// TableName returns the table name for DeletedItem
+func (DeletedItem) TableName() string {
+	return "deleted_items"
+}
+
+// DeletedCascade is what a deletion removed along with the resource
+type DeletedCascade struct {
+	MitigationIDs []uuid.UUID `json:"mitigation_ids,omitempty"` // Soft-deleted mitigations of a risk
+	AssetIDs      []uuid.UUID `json:"asset_ids,omitempty"`      // Assets unlinked from a risk
+	RiskIDs       []uuid.UUID `json:"risk_ids,omitempty"`       // Risks unlinked from an asset
+	MemberIDs     []uuid.UUID `json:"member_ids,omitempty"`     // Soft-deleted team memberships
+}
+
+// Cascaded decodes what was removed along with the item
+func (d *DeletedItem) Cascaded() (DeletedCascade, error) {
+	var cascade DeletedCascade
+	if len(d.Cascade) == 0 {
+		return cascade, nil
+	}
+	if err := json.Unmarshal(d.Cascade, &cascade); err != nil {
+		return cascade, fmt.Errorf("invalid deleted item cascade: %w", err)
+	}
+	return cascade, nil
+}
+
+// RecordDeletion puts a soft-deleted resource in the recycle bin and audits
+// the deletion, attributed to the actor of the transaction's context
+func RecordDeletion(tx *gorm.DB, resource RecycleResource, id uuid.UUID, cascade DeletedCascade) error {
+	if id == uuid.Nil {
+		return nil
+	}
+	table := recycleTables[resource]
+	var names []string
+	if err := tx.Table(table[0]).Where("id = ?", id).Limit(1).Pluck(table[1], &names).Error; err != nil {
+		return fmt.Errorf("failed to load deleted %s: %w", resource, err)
+	}
+
+	raw, err := json.Marshal(cascade)
+	if err != nil {
+		return fmt.Errorf("failed to encode deleted item cascade: %w", err)
+	}
+	item := DeletedItem{
+		ResourceType: resource,
+		ResourceID:   id,
+		DeletedBy:    ActorFrom(tx.Statement.Context),
+		DeletedAt:    time.Now().UTC(),
+		Cascade:      raw,
+	}
+	if len(names) > 0 {
+		item.Name = names[0]
+	}
+	// Deleting an item again refreshes its entry
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "resource_type"}, {Name: "resource_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "deleted_by", "deleted_at", "cascade"}),
+	}).Create(&item).Error; err != nil {
+		return fmt.Errorf("failed to record deletion: %w", err)
+	}
+	return AuditRecycle(tx, ActionSoftDelete, resource, id, item.DeletedBy, item.Name)
+}
+
+// AuditRecycle audits a deletion, restore or purge
+func AuditRecycle(tx *gorm.DB, action AuditLogAction, resource RecycleResource, id uuid.UUID, actor, name string) error {
+	entry := AuditLog{
+		Action:       action,
+		Resource:     AuditLogResource(resource),
+		ResourceID:   &id,
+		Result:       ResultSuccess,
+		ErrorMessage: fmt.Sprintf("%s %s %q", action, resource, name),
+		Timestamp:    time.Now(),
+	}
+	if userID, err := uuid.Parse(actor); err == nil {
+		entry.UserID = &userID
+	}
+	if err := tx.Session(&gorm.Session{NewDB: true}).Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to audit %s: %w", action, err)
+	}
+	return nil
+}
+
+// cascadeRiskDeletion soft-deletes the mitigations of a deleted risk and
+// unlinks its assets, then puts the risk in the recycle bin
+func cascadeRiskDeletion(tx *gorm.DB, riskID uuid.UUID) error {
+	if riskID == uuid.Nil {
+		return nil
+	}
+	var cascade DeletedCascade
+	if err := tx.Model(&Mitigation{}).Where("risk_id = ?", riskID).Pluck("id", &cascade.MitigationIDs).Error; err != nil {
+		return fmt.Errorf("failed to list mitigations: %w", err)
+	}
+	if len(cascade.MitigationIDs) > 0 {
+		if err := tx.Where("id IN ?", cascade.MitigationIDs).Delete(&Mitigation{}).Error; err != nil {
+			return fmt.Errorf("failed to delete mitigations: %w", err)
+		}
+	}
+	if err := tx.Table("risk_assets").Where("risk_id = ?", riskID).Pluck("asset_id", &cascade.AssetIDs).Error; err != nil {
+		return fmt.Errorf("failed to list asset links: %w", err)
+	}
+	if err := tx.Exec("DELETE FROM risk_assets WHERE risk_id = ?", riskID).Error; err != nil {
+		return fmt.Errorf("failed to unlink assets: %w", err)
+	}
+	return RecordDeletion(tx, RecycleRisk, riskID, cascade)
+}
+
+// cascadeAssetDeletion unlinks a deleted asset from its risks, versioning
+// them, then puts the asset in the recycle bin
+func cascadeAssetDeletion(tx *gorm.DB, assetID uuid.UUID) error {
+	if assetID == uuid.Nil {
+		return nil
+	}
+	var cascade DeletedCascade
+	if err := tx.Table("risk_assets").Where("asset_id = ?", assetID).Pluck("risk_id", &cascade.RiskIDs).Error; err != nil {
+		return fmt.Errorf("failed to list risk links: %w", err)
+	}
+	if err := tx.Exec("DELETE FROM risk_assets WHERE asset_id = ?", assetID).Error; err != nil {
+		return fmt.Errorf("failed to unlink risks: %w", err)
+	}
+	for _, riskID := range cascade.RiskIDs {
+		if err := RecordRiskVersion(tx, riskID); err != nil {
+			return err
+		}
+	}
+	return RecordDeletion(tx, RecycleAsset, assetID, cascade)
+}
+
+// cascadeTeamDeletion soft-deletes the memberships of a deleted team, then
+// puts the team in the recycle bin
+func cascadeTeamDeletion(tx *gorm.DB, teamID uuid.UUID) error {
+	if teamID == uuid.Nil {
+		return nil
+	}
+	var cascade DeletedCascade
+	if err := tx.Model(&TeamMember{}).Where("team_id = ?", teamID).Pluck("id", &cascade.MemberIDs).Error; err != nil {
+		return fmt.Errorf("failed to list team members: %w", err)
+	}
+	if len(cascade.MemberIDs) > 0 {
+		if err := tx.Where("id IN ?", cascade.MemberIDs).Delete(&TeamMember{}).Error; err != nil {
+			return fmt.Errorf("failed to delete team members: %w", err)
+		}
+	}
+	return RecordDeletion(tx, RecycleTeam, teamID, cascade)
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ReportTemplate is the layout and content of a generated report
+type ReportTemplate string
+
+const (
+	ReportExecutiveSummary ReportTemplate = "executive_summary" // Key figures, severity mix and top risks
+	ReportRiskRegister     ReportTemplate = "risk_register"     // Every selected risk
+	ReportTreatmentStatus  ReportTemplate = "treatment_status"  // Mitigation progress and overdue actions
+	ReportComplianceGap    ReportTemplate = "compliance_gap"    // Untreated risks per framework
+	ReportBoardPack        ReportTemplate = "board_pack"        // Executive summary, treatment and compliance in one document
+)
+
+// ValidReportTemplates lists the templates reports can be generated from
+var ValidReportTemplates = []ReportTemplate{ReportExecutiveSummary, ReportRiskRegister, ReportTreatmentStatus, ReportComplianceGap, ReportBoardPack}
+
+// ReportFormat is the file format of a report artifact
+type ReportFormat string
+
+const (
+	ReportFormatPDF  ReportFormat = "pdf"
+	ReportFormatDOCX ReportFormat = "docx"
+	ReportFormatHTML ReportFormat = "html"
+)
+
+// ReportStatus is the lifecycle of a report
+type ReportStatus string
+
+const (
+	ReportStatusPending    ReportStatus = "PENDING"
+	ReportStatusGenerating ReportStatus = "GENERATING"
+	ReportStatusCompleted  ReportStatus = "COMPLETED"
+	ReportStatusFailed     ReportStatus = "FAILED"
+)
+
+// ReportParameters select what a report covers
+type ReportParameters struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Filter      string    `json:"filter,omitempty"`    // Risk filter expression
+	Framework   string    `json:"framework,omitempty"` // Restricts the report to one framework
+}
+
+// Report is a generated report artifact. Its content comes from a
+// RiskAuditReport snapshot, so regenerating it reproduces the same figures.
+type Report struct {
+	ID            uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID      uuid.UUID      `gorm:"type:uuid;index" json:"tenant_id"`
+	Title         string         `gorm:"size:255;not null" json:"title"`
+	Type          ReportTemplate `gorm:"size:50;not null;index" json:"type"`
+	Format        ReportFormat   `gorm:"size:10;not null" json:"format"`
+	Status        ReportStatus   `gorm:"size:20;default:'PENDING';index" json:"status"`
+	Parameters    datatypes.JSON `gorm:"type:jsonb" json:"parameters"` // ReportParameters
+	SnapshotID    *uuid.UUID     `gorm:"type:uuid;index" json:"snapshot_id,omitempty"`
+	ScheduleID    *uuid.UUID     `gorm:"type:uuid;index" json:"schedule_id,omitempty"`
+	JobID         *uuid.UUID     `gorm:"type:uuid" json:"job_id,omitempty"`
+	ArtifactKey   string         `gorm:"size:500" json:"-"`
+	Size          int64          `json:"size"` // Bytes
+	Recipients    pq.StringArray `gorm:"type:text[]" json:"recipients,omitempty"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+	DeliveryError string         `gorm:"type:text" json:"delivery_error,omitempty"`
+	ErrorMessage  string         `gorm:"type:text" json:"error_message,omitempty"`
+	GeneratedBy   uuid.UUID      `gorm:"type:uuid;index" json:"generated_by"`
+	GeneratedAt   *time.Time     `json:"generated_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ReportPeriod is a reporting period relative to a schedule's run time
+type ReportPeriod string
+
+const (
+	ReportPeriodLastWeek    ReportPeriod = "last_week"    // The previous Monday to Sunday
+	ReportPeriodLastMonth   ReportPeriod = "last_month"   // The previous calendar month
+	ReportPeriodLastQuarter ReportPeriod = "last_quarter" // The previous calendar quarter
+	ReportPeriodLast30Days  ReportPeriod = "last_30_days" // The 30 days before the run
+)
+
+// ReportSchedule generates a report on a cron schedule and emails it to a
+// distribution list
+type ReportSchedule struct {
+	ID           uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID     uuid.UUID      `gorm:"type:uuid;index" json:"tenant_id"`
+	Name         string         `gorm:"size:255;not null" json:"name"`
+	Template     ReportTemplate `gorm:"size:50;not null" json:"template"`
+	Format       ReportFormat   `gorm:"size:10;not null" json:"format"`
+	Schedule     string         `gorm:"size:100;not null" json:"schedule"` // Cron expression or descriptor such as @monthly
+	Period       ReportPeriod   `gorm:"size:20;not null" json:"period"`
+	Filter       string         `gorm:"type:text" json:"filter"`
+	Framework    string         `gorm:"size:100" json:"framework"`
+	Recipients   pq.StringArray `gorm:"type:text[]" json:"recipients"`
+	Enabled      bool           `gorm:"index" json:"enabled"`
+	NextRunAt    time.Time      `gorm:"index" json:"next_run_at"`
+	LastRunAt    *time.Time     `json:"last_run_at,omitempty"`
+	LastReportID *uuid.UUID     `gorm:"type:uuid" json:"last_report_id,omitempty"`
+	CreatedBy    uuid.UUID      `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName returns the table name for Report
+func (Report) TableName() string {
+	return "reports"
+}
+
+// TableName returns the table name for ReportSchedule
+func (ReportSchedule) TableName() string {
+	return "report_schedules"
+}
@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReviewFrequency is how often a risk must be reviewed
+type ReviewFrequency string
+
+const (
+	ReviewWeekly     ReviewFrequency = "WEEKLY"
+	ReviewMonthly    ReviewFrequency = "MONTHLY"
+	ReviewQuarterly  ReviewFrequency = "QUARTERLY"
+	ReviewSemiAnnual ReviewFrequency = "SEMI_ANNUAL"
+	ReviewAnnual     ReviewFrequency = "ANNUAL"
+)
+
+// ParseReviewFrequency normalises a frequency as entered on treatment plans
+// ("monthly", "Semi-Annual", ...). It returns false for unknown values.
+func ParseReviewFrequency(value string) (ReviewFrequency, bool) {
+	normalised := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(strings.TrimSpace(value)))
+	switch ReviewFrequency(normalised) {
+	case ReviewWeekly, ReviewMonthly, ReviewQuarterly, ReviewSemiAnnual, ReviewAnnual:
+		return ReviewFrequency(normalised), true
+	case "BIANNUAL", "SEMIANNUAL":
+		return ReviewSemiAnnual, true
+	case "YEARLY":
+		return ReviewAnnual, true
+	}
+	return "", false
+}
+
+// Next returns the review date following from
+func (f ReviewFrequency) Next(from time.Time) time.Time {
+	switch f {
+	case ReviewWeekly:
+		return from.AddDate(0, 0, 7)
+	case ReviewMonthly:
+		return from.AddDate(0, 1, 0)
+	case ReviewSemiAnnual:
+		return from.AddDate(0, 6, 0)
+	case ReviewAnnual:
+		return from.AddDate(1, 0, 0)
+	default:
+		return from.AddDate(0, 3, 0)
+	}
+}
+
+// ReviewCadencePolicy sets the review frequency per risk level for a tenant.
+// A treatment plan's ReviewFrequency overrides the policy for its risk.
+type ReviewCadencePolicy struct {
+	ID       uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID uuid.UUID       `gorm:"type:uuid;uniqueIndex" json:"tenant_id"`
+	Critical ReviewFrequency `gorm:"size:20;default:'MONTHLY'" json:"critical"`
+	High     ReviewFrequency `gorm:"size:20;default:'QUARTERLY'" json:"high"`
+	Medium   ReviewFrequency `gorm:"size:20;default:'SEMI_ANNUAL'" json:"medium"`
+	Low      ReviewFrequency `gorm:"size:20;default:'ANNUAL'" json:"low"`
+
+	LeadDays  int `gorm:"default:14" json:"lead_days"` // Review tasks are opened this many days before the due date
+	GraceDays int `gorm:"default:0" json:"grace_days"` // A risk is stale once its review is overdue by more than this
+
+	UpdatedBy uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultReviewCadencePolicy is used for tenants without a configured policy
+func DefaultReviewCadencePolicy(tenantID uuid.UUID) ReviewCadencePolicy {
+	return ReviewCadencePolicy{
+		TenantID: tenantID,
+		Critical: ReviewMonthly,
+		High:     ReviewQuarterly,
+		Medium:   ReviewSemiAnnual,
+		Low:      ReviewAnnual,
+		LeadDays: 14,
+	}
+}
+
+// FrequencyFor returns the review frequency for a risk level (unknown levels use the HIGH cadence)
+func (p *ReviewCadencePolicy) FrequencyFor(level string) ReviewFrequency {
+	switch strings.ToUpper(level) {
+	case "CRITICAL":
+		return p.Critical
+	case "MEDIUM":
+		return p.Medium
+	case "LOW":
+		return p.Low
+	default:
+		return p.High
+	}
+}
+
+// ReviewTaskStatus represents the lifecycle of a scheduled review
+type ReviewTaskStatus string
+
+const (
+	ReviewTaskOpen      ReviewTaskStatus = "OPEN"
+	ReviewTaskOverdue   ReviewTaskStatus = "OVERDUE"
+	ReviewTaskCompleted ReviewTaskStatus = "COMPLETED"
+	ReviewTaskCancelled ReviewTaskStatus = "CANCELLED"
+)
+
+// ReviewTask is a scheduled review of a risk assigned to its owner.
+// It is completed by recording a RiskMonitoringReview for the risk.
+type ReviewTask struct {
+	ID             uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID       uuid.UUID        `gorm:"type:uuid;index" json:"tenant_id"`
+	RiskRegisterID uuid.UUID        `gorm:"type:uuid;not null;index" json:"risk_register_id"`
+	RiskID         uuid.UUID        `gorm:"type:uuid;index" json:"risk_id"`
+	Title          string           `gorm:"size:255" json:"title"`
+	AssigneeID     uuid.UUID        `gorm:"type:uuid;index" json:"assignee_id"`
+	RiskLevel      string           `gorm:"size:50" json:"risk_level"`
+	Frequency      ReviewFrequency  `gorm:"size:20" json:"frequency"`
+	DueDate        time.Time        `gorm:"index" json:"due_date"`
+	Status         ReviewTaskStatus `gorm:"size:20;index;default:'OPEN'" json:"status"`
+	ReviewID       *uuid.UUID       `gorm:"type:uuid" json:"review_id,omitempty"` // Monitoring review that completed the task
+	CompletedBy    *uuid.UUID       `gorm:"type:uuid" json:"completed_by,omitempty"`
+	CompletedAt    *time.Time       `json:"completed_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// CalendarFeedOwner identifies whose reviews a calendar feed publishes
+type CalendarFeedOwner string
+
+const (
+	CalendarFeedUser CalendarFeedOwner = "USER"
+	CalendarFeedTeam CalendarFeedOwner = "TEAM"
+)
+
+// ReviewCalendarFeed is a secret, revocable URL serving review tasks as iCalendar,
+// so calendar clients can subscribe without a bearer token.
+type ReviewCalendarFeed struct {
+	ID        uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID  uuid.UUID         `gorm:"type:uuid;index" json:"tenant_id"`
+	OwnerType CalendarFeedOwner `gorm:"size:10;not null" json:"owner_type"`
+	OwnerID   uuid.UUID         `gorm:"type:uuid;not null;index" json:"owner_id"`
+	TokenHash string            `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	CreatedBy uuid.UUID         `gorm:"type:uuid" json:"created_by"`
+	RevokedAt *time.Time        `json:"revoked_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	DeletedAt gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// TableName returns the table name for ReviewCadencePolicy
+func (ReviewCadencePolicy) TableName() string {
+	return "review_cadence_policies"
+}
+
+// TableName returns the table name for ReviewTask
+func (ReviewTask) TableName() string {
+	return "review_tasks"
+}
+
+// TableName returns the table name for ReviewCalendarFeed
+func (ReviewCalendarFeed) TableName() string {
+	return "review_calendar_feeds"
+}
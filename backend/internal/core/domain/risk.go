@@ -37,6 +37,15 @@ type Risk struct {
 	Source     string `gorm:"default:'MANUAL'" json:"source"` // "MANUAL", "THEHIVE", "OPENRMF"
 	ExternalID string `gorm:"index" json:"external_id"`       // ID dans l'outil tiers
 
+	// Vecteur CVSS v3.1 ou v4.0, normalisé : quand il est renseigné, Impact et
+	// Probability en sont dérivés par la politique du tenant (voir CVSSMappingPolicy)
+	CVSSVector string  `gorm:"column:cvss_vector;size:200" json:"cvss_vector,omitempty"`
+	CVSSScore  float64 `gorm:"column:cvss_score;type:numeric(3,1);default:0" json:"cvss_score,omitempty"` // Score environnemental
+
+	// Perte annuelle attendue (ALE), dans la devise du tenant : la prédiction
+	// comparée aux pertes des incidents qui réalisent le risque
+	ExpectedLoss float64 `gorm:"column:expected_loss;type:numeric(14,2);default:0" json:"expected_loss,omitempty"`
+
 	// Audit
 	// Flexible custom fields
 	CustomFields datatypes.JSON `gorm:"type:jsonb" json:"custom_fields,omitempty"`
@@ -44,12 +53,23 @@ type Risk struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// Numéro de la dernière version (voir RiskVersion), tenu à jour par les
+	// hooks et jamais écrit par les sauvegardes ; sert d'ETag
+	Version int `gorm:"->;not null;default:0" json:"version,omitempty"`
+
 	Mitigations []Mitigation `gorm:"foreignKey:RiskID" json:"mitigations,omitempty"`
 
 	Assets []*Asset `gorm:"many2many:risk_assets;" json:"assets,omitempty"`
 
 	// Framework classifications (ISO27001, NIST, CIS, OWASP...)
 	Frameworks pq.StringArray `gorm:"type:text[]" json:"frameworks,omitempty"`
+
+	// Techniques MITRE ATT&CK qui réalisent le risque (T1566, T1059.001)
+	Techniques pq.StringArray `gorm:"type:text[]" json:"techniques,omitempty"`
+
+	// Marquage TLP sous lequel le risque est partagé en STIX ; vide vaut
+	// DefaultTLP (AMBER)
+	TLP string `gorm:"column:tlp;size:20" json:"tlp,omitempty"`
 }
 
 func (r *Risk) BeforeSave(tx *gorm.DB) (err error) {
@@ -61,20 +81,19 @@ func (r *Risk) BeforeSave(tx *gorm.DB) (err error) {
 	return
 }
 
-// AfterSave : Gère la logique après la sauvegarde (enregistrement de l'historique)
-// Ce hook est essentiel pour les fonctionnalités de Timeline et de Trends.
+// AfterSave : Gère la logique après la sauvegarde (version et historique)
+// Ce hook est essentiel pour les fonctionnalités de Timeline et de Trends :
+// chaque changement effectif produit une version et une entrée d'historique
+// avec le diff champ par champ et l'auteur du contexte (voir WithActor).
 func (r *Risk) AfterSave(tx *gorm.DB) (err error) {
-	// Always create a history snapshot after save for timeline and trends.
-	history := RiskHistory{
-		RiskID:      r.ID,
-		Score:       r.Score,
-		Impact:      r.Impact,
-		Probability: r.Probability,
-		Status:      r.Status,
-		ChangedBy:   r.Owner,
-		ChangeType:  "UPDATE",
-		CreatedAt:   time.Now(),
-	}
+	return RecordRiskVersion(tx, r.ID)
+}
 
-	return tx.Create(&history).Error
+// AfterDelete : versionne et historise la suppression, puis place le risque
+// dans la corbeille avec ses mitigations et ses liens vers les assets
+func (r *Risk) AfterDelete(tx *gorm.DB) (err error) {
+	if err := RecordRiskVersion(tx, r.ID); err != nil {
+		return err
+	}
+	return cascadeRiskDeletion(tx, r.ID)
 }
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// RiskExceptionStatus represents the lifecycle of a risk acceptance / exception
+type RiskExceptionStatus string
+
+const (
+	ExceptionPendingApproval RiskExceptionStatus = "PENDING_APPROVAL"
+	ExceptionActive          RiskExceptionStatus = "ACTIVE"
+	ExceptionExpired         RiskExceptionStatus = "EXPIRED"
+	ExceptionRevoked         RiskExceptionStatus = "REVOKED"
+	ExceptionRejected        RiskExceptionStatus = "REJECTED"
+)
+
+// DefaultExceptionReminderDays are the days before expiry at which owners are reminded
+var DefaultExceptionReminderDays = []int64{30, 7, 1}
+
+// CompensatingControl describes a control that offsets an accepted risk while the exception is active
+type CompensatingControl struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ControlID   string `json:"control_id,omitempty"` // Reference in a framework (e.g. ISO27001 A.8.16)
+	Owner       string `json:"owner,omitempty"`
+}
+
+// RiskException is a time-boxed acceptance of a risk, scoped to specific assets and
+// backed by compensating controls. Expired exceptions return the risk to its prior status.
+type RiskException struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID   uuid.UUID  `gorm:"type:uuid;index" json:"tenant_id"`
+	RiskID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"risk_id"`
+	DecisionID *uuid.UUID `gorm:"type:uuid" json:"decision_id,omitempty"` // Originating RiskDecision, if any
+
+	Title                string         `gorm:"size:255;not null" json:"title"`
+	Justification        string         `gorm:"type:text;not null" json:"justification"`
+	CompensatingControls datatypes.JSON `gorm:"type:jsonb" json:"compensating_controls"` // []CompensatingControl
+	Owner                string         `gorm:"size:255;index" json:"owner"`             // Email ou UserID, receives reminders
+
+	Status       RiskExceptionStatus `gorm:"size:50;index;default:'PENDING_APPROVAL'" json:"status"`
+	ValidFrom    time.Time           `json:"valid_from"`
+	ExpiresAt    time.Time           `gorm:"index" json:"expires_at"`
+	ReminderDays pq.Int64Array       `gorm:"type:integer[]" json:"reminder_days"`
+
+	// Re-certification: a proposed new expiry awaiting approval
+	ProposedExpiresAt     *time.Time `json:"proposed_expires_at,omitempty"`
+	RecertificationReason string     `gorm:"type:text" json:"recertification_reason,omitempty"`
+	RecertificationCount  int        `gorm:"default:0" json:"recertification_count"`
+	LastRecertifiedAt     *time.Time `json:"last_recertified_at,omitempty"`
+
+	// Risk status before the exception was applied, restored on expiry or revocation
+	PreviousRiskStatus RiskStatus `gorm:"size:50" json:"previous_risk_status,omitempty"`
+
+	RequestedBy uuid.UUID  `gorm:"type:uuid" json:"requested_by"`
+	ApprovedBy  *uuid.UUID `gorm:"type:uuid" json:"approved_by,omitempty"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"` // Expired, revoked or rejected
+	CloseReason string     `gorm:"type:text" json:"close_reason,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Risk   *Risk    `gorm:"foreignKey:RiskID" json:"risk,omitempty"`
+	Assets []*Asset `gorm:"many2many:risk_exception_assets;" json:"assets,omitempty"`
+}
+
+// Controls decodes the compensating controls
+func (e *RiskException) Controls() ([]CompensatingControl, error) {
+	var controls []CompensatingControl
+	if len(e.CompensatingControls) == 0 {
+		return controls, nil
+	}
+	if err := json.Unmarshal(e.CompensatingControls, &controls); err != nil {
+		return nil, err
+	}
+	return controls, nil
+}
+
+// DaysRemaining returns the number of started days left before expiry (0 once expired)
+func (e *RiskException) DaysRemaining(now time.Time) int {
+	left := e.ExpiresAt.Sub(now)
+	if left <= 0 {
+		return 0
+	}
+	return int(math.Ceil(left.Hours() / 24))
+}
+
+// IsExpired reports whether the exception has passed its expiry date
+func (e *RiskException) IsExpired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// DueExceptionReminder returns the reminder threshold (in days) that applies at now, and whether
+// it still has to be sent. Only the most urgent reached threshold is considered, so a late
+// scheduler run does not send a burst of stale reminders.
+func DueExceptionReminder(expiresAt, now time.Time, thresholds []int64, sent map[int64]bool) (int64, bool) {
+	left := expiresAt.Sub(now)
+	if left <= 0 {
+		return 0, false
+	}
+
+	var due int64 = -1
+	for _, days := range thresholds {
+		if days <= 0 || left > time.Duration(days)*24*time.Hour {
+			continue
+		}
+		if due == -1 || days < due {
+			due = days
+		}
+	}
+	if due == -1 || sent[due] {
+		return 0, false
+	}
+	return due, true
+}
+
+// RiskExceptionReminder records a reminder sent to an exception owner before expiry
+type RiskExceptionReminder struct {
+	ID               uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID         uuid.UUID  `gorm:"type:uuid;index" json:"tenant_id"`
+	ExceptionID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"exception_id"`
+	Recipient        string     `gorm:"size:255;index" json:"recipient"`
+	DaysBeforeExpiry int64      `json:"days_before_expiry"`
+	ExpiresAt        time.Time  `json:"expires_at"` // Expiry the reminder was sent for; re-certification resets reminders
+	SentAt           time.Time  `json:"sent_at"`
+	AcknowledgedAt   *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// TableName returns the table name for RiskException
+func (RiskException) TableName() string {
+	return "risk_exceptions"
+}
+
+// TableName returns the table name for RiskExceptionReminder
+func (RiskExceptionReminder) TableName() string {
+	return "risk_exception_reminders"
+}
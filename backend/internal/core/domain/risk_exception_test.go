@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDueExceptionReminder(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	thresholds := []int64{30, 7, 1}
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		sent      map[int64]bool
+		wantDays  int64
+		wantDue   bool
+	}{
+		{"too early", now.AddDate(0, 0, 45), nil, 0, false},
+		{"first threshold reached", now.AddDate(0, 0, 30), nil, 30, true},
+		{"first threshold already sent", now.AddDate(0, 0, 20), map[int64]bool{30: true}, 0, false},
+		{"week before expiry", now.AddDate(0, 0, 5), map[int64]bool{30: true}, 7, true},
+		{"late run only sends the most urgent", now.Add(12 * time.Hour), nil, 1, true},
+		{"expired", now.Add(-time.Hour), nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, due := DueExceptionReminder(tt.expiresAt, now, thresholds, tt.sent)
+			assert.Equal(t, tt.wantDue, due)
+			assert.Equal(t, tt.wantDays, days)
+		})
+	}
+}
+
+func TestRiskExceptionDaysRemaining(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	exception := RiskException{ExpiresAt: now.Add(36 * time.Hour)}
+	assert.Equal(t, 2, exception.DaysRemaining(now))
+	assert.False(t, exception.IsExpired(now))
+
+	exception.ExpiresAt = now
+	assert.Equal(t, 0, exception.DaysRemaining(now))
+	assert.True(t, exception.IsExpired(now))
+}
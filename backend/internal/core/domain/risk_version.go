@@ -0,0 +1,329 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RiskVersionChange tells how a version came to be
+type RiskVersionChange string
+
+const (
+	RiskVersionCreate RiskVersionChange = "CREATE"
+	RiskVersionUpdate RiskVersionChange = "UPDATE"
+	RiskVersionDelete RiskVersionChange = "DELETE"
+	// Recorded for risks that existed before versioning: their state at that
+	// time, assumed valid since their creation
+	RiskVersionBaseline RiskVersionChange = "BASELINE"
+)
+
+// RiskVersion is an append-only snapshot of a risk with its mitigations and
+// asset links. A version describes the risk from ValidFrom until ValidTo, when
+// the next version replaced it, so the register as of a date is the set of
+// versions valid at that date.
+type RiskVersion struct {
+	ID         uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	RiskID     uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_risk_versions_risk_version" json:"risk_id"`
+	Version    int               `gorm:"not null;uniqueIndex:idx_risk_versions_risk_version" json:"version"`
+	ChangeType RiskVersionChange `gorm:"size:20;not null" json:"change_type"`
+	Deleted    bool              `gorm:"not null;default:false" json:"deleted"`
+	ValidFrom  time.Time         `gorm:"not null;index" json:"valid_from"`
+	ValidTo    *time.Time        `gorm:"index" json:"valid_to,omitempty"` // Nil for the current version
+
+	// The risk as serialized by the API, with its mitigations and assets
+	Snapshot datatypes.JSON `gorm:"type:jsonb;not null" json:"snapshot"`
+	Checksum string         `gorm:"size:64;not null" json:"-"` // SHA-256 of Snapshot and Deleted, to skip unchanged saves
+}
+
+// TableName returns the table name for RiskVersion
+func (RiskVersion) TableName() string {
+	return "risk_versions"
+}
+
+// Risk decodes the snapshot
+func (v *RiskVersion) Risk() (*Risk, error) {
+	var risk Risk
+	if err := json.Unmarshal(v.Snapshot, &risk); err != nil {
+		return nil, fmt.Errorf("invalid risk version snapshot: %w", err)
+	}
+	risk.Version = v.Version
+	return &risk, nil
+}
+
+// RecordRiskVersion appends the current state of a risk, with its
+// mitigations and asset links, to its versions and records the change in its
+// history, attributed to the actor of the transaction's context (see
+// WithActor). Nothing is recorded when that state is the one of the latest
+// version, so several hooks of one save record a single version.
+func RecordRiskVersion(tx *gorm.DB, riskID uuid.UUID) error {
+	return RecordRiskChange(tx, riskID, "")
+}
+
+// RecordRiskChange is RecordRiskVersion with the change type of the history
+// entry given, e.g. EXCEPTION_APPROVED. When empty it is CREATE, UPDATE,
+// DELETE or RESTORE depending on the previous version.
+func RecordRiskChange(tx *gorm.DB, riskID uuid.UUID, changeType string) error {
+	if riskID == uuid.Nil {
+		return nil
+	}
+	version, risk, err := snapshotRiskVersion(tx, riskID)
+	if err != nil || version == nil {
+		return err
+	}
+
+	var latest RiskVersion
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("risk_id = ?", riskID).Order("version DESC").Take(&latest).Error
+	previous := &Risk{}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		version.Version = 1
+		version.ChangeType = RiskVersionCreate
+	case err != nil:
+		return fmt.Errorf("failed to load latest risk version: %w", err)
+	case latest.Checksum == version.Checksum:
+		return nil
+	default:
+		version.Version = latest.Version + 1
+		version.ChangeType = RiskVersionUpdate
+		if previous, err = latest.Risk(); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	version.ValidFrom = now
+	if risk.DeletedAt.Valid {
+		version.ChangeType = RiskVersionDelete
+	}
+	if latest.ID != uuid.Nil {
+		if err := tx.Model(&RiskVersion{}).Where("id = ?", latest.ID).Update("valid_to", now).Error; err != nil {
+			return fmt.Errorf("failed to close risk version: %w", err)
+		}
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return err
+	}
+	if err := setRiskVersion(tx, riskID, version.Version); err != nil {
+		return err
+	}
+
+	if changeType == "" {
+		changeType = string(version.ChangeType)
+		if latest.Deleted && !version.Deleted {
+			changeType = RiskChangeRestore
+		}
+	}
+	history := RiskHistory{
+		RiskID:      riskID,
+		Version:     version.Version,
+		Score:       risk.Score,
+		Impact:      risk.Impact,
+		Probability: risk.Probability,
+		Status:      risk.Status,
+		ChangedBy:   ActorFrom(tx.Statement.Context),
+		ChangeType:  changeType,
+		CreatedAt:   now,
+	}
+	if err := history.SetFieldChanges(DiffRisks(previous, risk)); err != nil {
+		return err
+	}
+	return tx.Create(&history).Error
+}
+
+// BaselineRiskVersion records the first version of a risk created before
+// versioning. Its earlier states are unknown, so the current one is assumed
+// valid since the risk's creation, until its deletion for deleted risks.
+func BaselineRiskVersion(tx *gorm.DB, riskID uuid.UUID) error {
+	version, risk, err := snapshotRiskVersion(tx, riskID)
+	if err != nil || version == nil {
+		return err
+	}
+	version.Version = 1
+	version.ChangeType = RiskVersionBaseline
+	version.ValidFrom = risk.CreatedAt.UTC()
+	if !risk.DeletedAt.Valid {
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return setRiskVersion(tx, riskID, version.Version)
+	}
+
+	deletedAt := risk.DeletedAt.Time.UTC()
+	version.ValidTo = &deletedAt
+	deleted := *version
+	deleted.ID = uuid.Nil
+	deleted.Version = 2
+	deleted.ChangeType = RiskVersionDelete
+	deleted.Deleted = true
+	deleted.ValidFrom = deletedAt
+	deleted.ValidTo = nil
+	if err := tx.Create([]*RiskVersion{version, &deleted}).Error; err != nil {
+		return err
+	}
+	return setRiskVersion(tx, riskID, deleted.Version)
+}
+
+// snapshotRiskVersion builds an unsaved version of a risk as stored, deleted or
+// not; nil when the risk does not exist
+func snapshotRiskVersion(tx *gorm.DB, riskID uuid.UUID) (*RiskVersion, *Risk, error) {
+	var risk Risk
+	if err := tx.Unscoped().Take(&risk, "id = ?", riskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to load risk: %w", err)
+	}
+	if err := tx.Where("risk_id = ?", riskID).Order("created_at ASC, id ASC").Find(&risk.Mitigations).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load mitigations: %w", err)
+	}
+	if err := tx.Joins("JOIN risk_assets ON risk_assets.asset_id = assets.id").
+		Where("risk_assets.risk_id = ?", riskID).Order("assets.name ASC, assets.id ASC").Find(&risk.Assets).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+
+	// The version counters are not part of the state: a version numbers its
+	// snapshot (see Risk), and saving a mitigation unchanged bumps its counter
+	risk.Version = 0
+	for i := range risk.Mitigations {
+		risk.Mitigations[i].Version = 0
+	}
+	snapshot, err := json.Marshal(&risk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode risk version: %w", err)
+	}
+	// Soft deletion leaves the serialized risk unchanged, so it is hashed too
+	sum := sha256.Sum256(append(snapshot, fmt.Sprint(risk.DeletedAt.Valid)...))
+	return &RiskVersion{
+		RiskID:   riskID,
+		Deleted:  risk.DeletedAt.Valid,
+		Snapshot: snapshot,
+		Checksum: hex.EncodeToString(sum[:]),
+	}, &risk, nil
+}
+
+// setRiskVersion mirrors the latest version number on the risk, used as its
+// ETag. The risk's hooks are skipped: this is not a change of the risk.
+func setRiskVersion(tx *gorm.DB, riskID uuid.UUID, version int) error {
+	if err := tx.Table("risks").Where("id = ?", riskID).UpdateColumn("version", version).Error; err != nil {
+		return fmt.Errorf("failed to update risk version: %w", err)
+	}
+	return nil
+}
+
+// DiffRisks lists the fields that differ between two versions of a risk.
+// Mitigations are compared by ID and assets by name, retired ones marked so.
+func DiffRisks(before, after *Risk) []RiskFieldChange {
+	var changes []RiskFieldChange
+	add := func(field string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, RiskFieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("status", before.Status, after.Status)
+	add("impact", before.Impact, after.Impact)
+	add("probability", before.Probability, after.Probability)
+	add("score", before.Score, after.Score)
+	add("owner", before.Owner, after.Owner)
+	add("source", before.Source, after.Source)
+	add("external_id", before.ExternalID, after.ExternalID)
+	add("cvss_vector", before.CVSSVector, after.CVSSVector)
+	add("cvss_score", before.CVSSScore, after.CVSSScore)
+	add("expected_loss", before.ExpectedLoss, after.ExpectedLoss)
+	add("tlp", before.TLP, after.TLP)
+	add("tags", sortedStrings(before.Tags), sortedStrings(after.Tags))
+	add("frameworks", sortedStrings(before.Frameworks), sortedStrings(after.Frameworks))
+	add("techniques", sortedStrings(before.Techniques), sortedStrings(after.Techniques))
+
+	oldFields, newFields := customFieldMap(before.CustomFields), customFieldMap(after.CustomFields)
+	for _, key := range unionKeys(oldFields, newFields) {
+		add("custom_fields."+key, oldFields[key], newFields[key])
+	}
+
+	add("assets", assetNames(before.Assets), assetNames(after.Assets))
+
+	oldMitigations := map[uuid.UUID]Mitigation{}
+	for _, m := range before.Mitigations {
+		oldMitigations[m.ID] = m
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, m := range after.Mitigations {
+		seen[m.ID] = true
+		old, ok := oldMitigations[m.ID]
+		if !ok {
+			add("mitigations", nil, m.Title)
+			continue
+		}
+		prefix := "mitigations[" + m.Title + "]."
+		add(prefix+"title", old.Title, m.Title)
+		add(prefix+"status", old.Status, m.Status)
+		add(prefix+"progress", old.Progress, m.Progress)
+		add(prefix+"assignee", old.Assignee, m.Assignee)
+		add(prefix+"techniques", sortedStrings(old.Techniques), sortedStrings(m.Techniques))
+		add(prefix+"countermeasures", sortedStrings(old.Countermeasures), sortedStrings(m.Countermeasures))
+		if !old.DueDate.Equal(m.DueDate) {
+			changes = append(changes, RiskFieldChange{Field: prefix + "due_date", From: old.DueDate, To: m.DueDate})
+		}
+	}
+	for _, m := range before.Mitigations {
+		if !seen[m.ID] {
+			add("mitigations", m.Title, nil)
+		}
+	}
+	return changes
+}
+
+func sortedStrings(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func assetNames(assets []*Asset) []string {
+	names := make([]string, 0, len(assets))
+	for _, a := range assets {
+		if a.RetiredAt != nil {
+			names = append(names, a.Name+" (retired)")
+			continue
+		}
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func customFieldMap(raw datatypes.JSON) map[string]interface{} {
+	values := map[string]interface{}{}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &values)
+	}
+	return values
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestDiffRisks(t *testing.T) {
+	due := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	kept, dropped, added := uuid.New(), uuid.New(), uuid.New()
+	before := &Risk{
+		Title: "Ransomware", Status: StatusActive, Impact: 4, Probability: 4, Score: 16,
+		Tags:         []string{"ops", "cyber"},
+		CustomFields: datatypes.JSON(`{"bu":"EU","budget":1000}`),
+		Assets:       []*Asset{{Name: "ERP"}, {Name: "CRM"}},
+		Mitigations: []Mitigation{
+			{ID: kept, Title: "Backups", Status: MitigationInProgress, Progress: 40, DueDate: due},
+			{ID: dropped, Title: "Training", Status: MitigationPlanned},
+		},
+	}
+	after := &Risk{
+		Title: "Ransomware", Status: StatusMitigated, Impact: 4, Probability: 2, Score: 8,
+		Tags:         []string{"cyber", "ops"},
+		CustomFields: datatypes.JSON(`{"bu":"EU","owner_team":"SOC"}`),
+		Assets:       []*Asset{{Name: "CRM"}, {Name: "ERP"}},
+		Mitigations: []Mitigation{
+			{ID: kept, Title: "Backups", Status: MitigationDone, Progress: 100, DueDate: due},
+			{ID: added, Title: "EDR"},
+		},
+	}
+
+	changes := map[string]RiskFieldChange{}
+	for _, c := range DiffRisks(before, after) {
+		changes[c.Field] = c
+	}
+	assert.Equal(t, RiskFieldChange{Field: "status", From: StatusActive, To: StatusMitigated}, changes["status"])
+	assert.Equal(t, RiskFieldChange{Field: "score", From: 16.0, To: 8.0}, changes["score"])
+	assert.Contains(t, changes, "probability")
+	assert.NotContains(t, changes, "tags", "tag order does not matter")
+	assert.NotContains(t, changes, "assets", "asset order does not matter")
+	assert.Equal(t, RiskFieldChange{Field: "custom_fields.budget", From: 1000.0, To: nil}, changes["custom_fields.budget"])
+	assert.Equal(t, RiskFieldChange{Field: "custom_fields.owner_team", From: nil, To: "SOC"}, changes["custom_fields.owner_team"])
+	assert.NotContains(t, changes, "custom_fields.bu")
+	assert.Equal(t, MitigationDone, changes["mitigations[Backups].status"].To)
+	assert.Contains(t, changes, "mitigations[Backups].progress")
+	assert.NotContains(t, changes, "mitigations[Backups].due_date")
+	assert.Len(t, changes, 8)
+
+	// Added and removed mitigations share the "mitigations" field
+	var mitigations []RiskFieldChange
+	for _, c := range DiffRisks(before, after) {
+		if c.Field == "mitigations" {
+			mitigations = append(mitigations, c)
+		}
+	}
+	assert.ElementsMatch(t, []RiskFieldChange{
+		{Field: "mitigations", From: nil, To: "EDR"},
+		{Field: "mitigations", From: "Training", To: nil},
+	}, mitigations)
+
+	assert.Empty(t, DiffRisks(after, after))
+}
+
+func TestRiskHistoryFieldChanges(t *testing.T) {
+	var h RiskHistory
+	changes, err := h.FieldChanges()
+	require.NoError(t, err)
+	assert.Empty(t, changes, "entries recorded before field diffs have none")
+
+	require.NoError(t, h.SetFieldChanges([]RiskFieldChange{{Field: "title", From: "Old", To: "New"}}))
+	changes, err = h.FieldChanges()
+	require.NoError(t, err)
+	assert.Equal(t, []RiskFieldChange{{Field: "title", From: "Old", To: "New"}}, changes)
+
+	require.NoError(t, h.SetFieldChanges(nil))
+	assert.Nil(t, h.Changes)
+}
+
+func TestActorFrom(t *testing.T) {
+	assert.Equal(t, SystemActor, ActorFrom(nil))
+	assert.Equal(t, SystemActor, ActorFrom(context.Background()))
+
+	userID := uuid.New().String()
+	ctx := WithActor(context.Background(), userID)
+	assert.Equal(t, userID, ActorFrom(ctx))
+	assert.Equal(t, SystemActor, ActorFrom(WithActor(ctx, "")), "an empty actor falls back to the system")
+}
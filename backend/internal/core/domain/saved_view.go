@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// ViewVisibility decides who can see a saved view or a dashboard
+type ViewVisibility string
+
+const (
+	VisibilityPrivate ViewVisibility = "private" // Only the owner
+	VisibilityTeam    ViewVisibility = "team"    // Members of TeamID
+	VisibilityTenant  ViewVisibility = "tenant"  // Every user of the tenant
+)
+
+// SavedView is a named risk selection: a filter expression, a sort order and
+// the columns to display
+type SavedView struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    uuid.UUID      `gorm:"type:uuid;index" json:"tenant_id"`
+	OwnerID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"owner_id"`
+	Name        string         `gorm:"size:255;not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	Filter      string         `gorm:"type:text" json:"filter"` // Risk filter expression
+	SortBy      string         `gorm:"size:50" json:"sort_by"`
+	SortDir     string         `gorm:"size:4" json:"sort_dir"` // asc, desc
+	Columns     pq.StringArray `gorm:"type:text[]" json:"columns"`
+	Visibility  ViewVisibility `gorm:"size:10;default:'private';index" json:"visibility"`
+	TeamID      *uuid.UUID     `gorm:"type:uuid;index" json:"team_id,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName returns the table name for SavedView
+func (SavedView) TableName() string {
+	return "saved_views"
+}
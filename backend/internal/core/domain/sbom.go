@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+)
+
+// Risk sources of the risks raised from SBOMs
+const (
+	RiskSourceSBOM        = "SBOM"         // Vulnerable component shipped by an asset
+	RiskSourceSBOMLicense = "SBOM_LICENSE" // Component under a license the policy forbids
+)
+
+// SBOMFormat is the standard of a software bill of materials
+type SBOMFormat string
+
+const (
+	SBOMCycloneDX SBOMFormat = "CycloneDX"
+	SBOMSPDX      SBOMFormat = "SPDX"
+)
+
+// SBOMDocument records an SBOM uploaded against an asset. The last one sets
+// the component inventory of the asset.
+type SBOMDocument struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AssetID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"asset_id"`
+	Format      SBOMFormat `gorm:"size:20;not null" json:"format"`
+	Encoding    string     `gorm:"size:20" json:"encoding"` // json, xml, tag-value
+	SpecVersion string     `gorm:"size:20" json:"spec_version"`
+	Name        string     `gorm:"size:255" json:"name"`    // Product described by the SBOM
+	Version     string     `gorm:"size:100" json:"version"` // Release of the product
+	FileName    string     `gorm:"size:255" json:"file_name,omitempty"`
+
+	ComponentCount     int `json:"component_count"`
+	VulnerabilityCount int `json:"vulnerability_count"` // Vulnerabilities matched at upload
+	LicenseViolations  int `json:"license_violations"`
+
+	UploadedBy string    `gorm:"size:100" json:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for SBOMDocument
+func (SBOMDocument) TableName() string {
+	return "sbom_documents"
+}
+
+// SoftwareComponent is a component an asset ships, from its last SBOM.
+// PackageName is the name under which vulnerability feeds know the package
+// ("org.apache.logging.log4j:log4j-core" for the component log4j-core).
+type SoftwareComponent struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AssetID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"asset_id"`
+	SBOMID      uuid.UUID      `gorm:"column:sbom_id;type:uuid;not null;index" json:"sbom_id"`
+	Name        string         `gorm:"size:255;not null;index" json:"name"`
+	Group       string         `gorm:"size:255" json:"group,omitempty"`
+	Version     string         `gorm:"size:100" json:"version"`
+	PURL        string         `gorm:"column:purl;size:1000;index" json:"purl,omitempty"`
+	Ecosystem   string         `gorm:"size:50;index" json:"ecosystem,omitempty"` // OSV ecosystem: Maven, npm, PyPI, Go...
+	PackageName string         `gorm:"size:500;index" json:"package_name,omitempty"`
+	Licenses    pq.StringArray `gorm:"type:text[]" json:"licenses,omitempty"` // SPDX identifiers or expressions
+	Supplier    string         `gorm:"size:255" json:"supplier,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+
+	Asset *Asset `gorm:"foreignKey:AssetID" json:"asset,omitempty"`
+}
+
+// TableName returns the table name for SoftwareComponent
+func (SoftwareComponent) TableName() string {
+	return "software_components"
+}
+
+// Vulnerability is an advisory of a vulnerability feed (OSV), identified by
+// the feed's ID; Aliases holds the CVE and other identifiers
+type Vulnerability struct {
+	ID         string         `gorm:"size:100;primaryKey" json:"id"`
+	Aliases    pq.StringArray `gorm:"type:text[]" json:"aliases,omitempty"`
+	Summary    string         `gorm:"type:text" json:"summary"`
+	Details    string         `gorm:"type:text" json:"details,omitempty"`
+	Severity   string         `gorm:"size:20" json:"severity"` // LOW, MEDIUM, HIGH, CRITICAL
+	CVSSVector string         `gorm:"size:200" json:"cvss_vector,omitempty"`
+	Source     string         `gorm:"size:20" json:"source"` // OSV
+	Published  *time.Time     `json:"published,omitempty"`
+	Modified   *time.Time     `json:"modified,omitempty"`
+	Withdrawn  *time.Time     `json:"withdrawn,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+
+	Packages []VulnerablePackage `gorm:"foreignKey:VulnerabilityID" json:"packages,omitempty"`
+}
+
+// TableName returns the table name for Vulnerability
+func (Vulnerability) TableName() string {
+	return "vulnerabilities"
+}
+
+// CVE returns the CVE identifier of the vulnerability, if any
+func (v Vulnerability) CVE() string {
+	if strings.HasPrefix(v.ID, "CVE-") {
+		return v.ID
+	}
+	for _, alias := range v.Aliases {
+		if strings.HasPrefix(alias, "CVE-") {
+			return alias
+		}
+	}
+	return ""
+}
+
+// VulnerablePackage is a package a vulnerability affects, with the affected
+// version ranges and versions
+type VulnerablePackage struct {
+	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	VulnerabilityID string         `gorm:"size:100;not null;index" json:"vulnerability_id"`
+	Ecosystem       string         `gorm:"size:50;not null" json:"ecosystem"`
+	Name            string         `gorm:"size:500;not null;index" json:"name"`
+	Ranges          datatypes.JSON `gorm:"type:jsonb" json:"ranges,omitempty"` // []VersionRange
+	Versions        pq.StringArray `gorm:"type:text[]" json:"versions,omitempty"`
+}
+
+// TableName returns the table name for VulnerablePackage
+func (VulnerablePackage) TableName() string {
+	return "vulnerable_packages"
+}
+
+// ComponentVulnerability is a vulnerability matched on a component of an
+// asset, and the risk raised for it
+type ComponentVulnerability struct {
+	ComponentID     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"component_id"`
+	VulnerabilityID string     `gorm:"size:100;primaryKey" json:"vulnerability_id"`
+	AssetID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"asset_id"`
+	FixedVersion    string     `gorm:"size:100" json:"fixed_version,omitempty"`
+	RiskID          *uuid.UUID `gorm:"type:uuid;index" json:"risk_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	Component     *SoftwareComponent `gorm:"foreignKey:ComponentID" json:"component,omitempty"`
+	Vulnerability *Vulnerability     `gorm:"foreignKey:VulnerabilityID" json:"vulnerability,omitempty"`
+}
+
+// TableName returns the table name for ComponentVulnerability
+func (ComponentVulnerability) TableName() string {
+	return "component_vulnerabilities"
+}
+
+// LicensePolicy forbids a license in shipped components. Violations raise a
+// compliance risk on the asset, unless the policy is report-only.
+type LicensePolicy struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	License    string    `gorm:"size:100;not null;uniqueIndex" json:"license"` // SPDX identifier: GPL-3.0-only, AGPL-3.0-or-later...
+	Severity   string    `gorm:"size:20;not null;default:'HIGH'" json:"severity"`
+	Reason     string    `gorm:"type:text" json:"reason,omitempty"`
+	ReportOnly bool      `gorm:"not null;default:false" json:"report_only"` // Violations are listed but raise no risk
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for LicensePolicy
+func (LicensePolicy) TableName() string {
+	return "license_policies"
+}
+
+// LicenseIDs returns the license identifiers of SPDX license expressions,
+// without operators, exceptions, parentheses and NOASSERTION/NONE
+func LicenseIDs(expressions []string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, expr := range expressions {
+		exception := false
+		for _, token := range strings.FieldsFunc(expr, func(r rune) bool { return r == ' ' || r == '(' || r == ')' }) {
+			switch strings.ToUpper(token) {
+			case "WITH":
+				// The next token is a license exception
+				exception = true
+				continue
+			case "AND", "OR", "NOASSERTION", "NONE":
+				continue
+			}
+			if exception {
+				exception = false
+				continue
+			}
+			token = strings.TrimSuffix(token, "+")
+			if !seen[strings.ToLower(token)] {
+				seen[strings.ToLower(token)] = true
+				ids = append(ids, token)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,283 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MissedRunPolicy decides what happens when a periodic job comes due after one
+// or more of its scheduled times have passed (e.g. every replica was down)
+type MissedRunPolicy string
+
+const (
+	MissedRunOnce MissedRunPolicy = "RUN_ONCE" // Run once to catch up, then resume the schedule
+	MissedRunSkip MissedRunPolicy = "SKIP"     // Drop the missed runs and wait for the next scheduled time
+)
+
+// ScheduledRunStatus is the outcome of a scheduled job run
+type ScheduledRunStatus string
+
+const (
+	ScheduledRunRunning   ScheduledRunStatus = "RUNNING"
+	ScheduledRunSucceeded ScheduledRunStatus = "SUCCEEDED"
+	ScheduledRunFailed    ScheduledRunStatus = "FAILED"
+	ScheduledRunSkipped   ScheduledRunStatus = "SKIPPED"
+)
+
+// ScheduledRunTrigger records why a run started
+type ScheduledRunTrigger string
+
+const (
+	ScheduledRunBySchedule ScheduledRunTrigger = "SCHEDULE"
+	ScheduledRunManual     ScheduledRunTrigger = "MANUAL"
+)
+
+// ScheduledJob is the cluster-wide state of a periodic worker. Exactly one
+// replica runs a due job: it must first take the row's lease (LockedBy/LeaseUntil).
+type ScheduledJob struct {
+	Name            string          `gorm:"size:100;primaryKey" json:"name"`
+	Description     string          `gorm:"size:255" json:"description"`
+	Schedule        string          `gorm:"size:100;not null" json:"schedule"` // Cron expression, @daily-style descriptor or "@every 5m"
+	MissedRunPolicy MissedRunPolicy `gorm:"size:20;default:'RUN_ONCE'" json:"missed_run_policy"`
+	TimeoutSeconds  int             `gorm:"default:600" json:"timeout_seconds"`
+
+	Paused           bool       `gorm:"default:false" json:"paused"`
+	PausedBy         *uuid.UUID `gorm:"type:uuid" json:"paused_by,omitempty"`
+	TriggerRequested bool       `gorm:"default:false" json:"trigger_requested"`
+	TriggeredBy      *uuid.UUID `gorm:"type:uuid" json:"triggered_by,omitempty"`
+
+	NextRunAt      time.Time          `gorm:"index" json:"next_run_at"`
+	LastRunAt      *time.Time         `json:"last_run_at,omitempty"`
+	LastStatus     ScheduledRunStatus `gorm:"size:20" json:"last_status,omitempty"`
+	LastError      string             `gorm:"type:text" json:"last_error,omitempty"`
+	LastDurationMs int64              `json:"last_duration_ms"`
+
+	LockedBy   string     `gorm:"size:100" json:"locked_by,omitempty"`
+	LeaseUntil *time.Time `json:"lease_until,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScheduledJobRun is one entry of a scheduled job's run history
+type ScheduledJobRun struct {
+	ID           uuid.UUID           `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	JobName      string              `gorm:"size:100;not null;index" json:"job_name"`
+	Trigger      ScheduledRunTrigger `gorm:"size:20" json:"trigger"`
+	Status       ScheduledRunStatus  `gorm:"size:20;index" json:"status"`
+	Instance     string              `gorm:"size:100" json:"instance"` // Replica that ran the job
+	ScheduledFor time.Time           `json:"scheduled_for"`
+	MissedRuns   int                 `json:"missed_runs"`
+	TriggeredBy  *uuid.UUID          `gorm:"type:uuid" json:"triggered_by,omitempty"`
+	StartedAt    time.Time           `gorm:"index" json:"started_at"`
+	FinishedAt   *time.Time          `json:"finished_at,omitempty"`
+	DurationMs   int64               `json:"duration_ms"`
+	Error        string              `gorm:"type:text" json:"error,omitempty"`
+}
+
+// Schedule yields the fire times of a periodic job
+type Schedule interface {
+	// Next returns the first fire time strictly after t
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a standard 5-field cron expression (minute hour
+// day-of-month month day-of-week), a descriptor (@hourly, @daily, @midnight,
+// @weekly, @monthly, @yearly, @annually) or a fixed interval ("@every 90s").
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if strings.HasPrefix(expr, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", expr, err)
+		}
+		if every < time.Second {
+			return nil, fmt.Errorf("interval must be at least one second")
+		}
+		return intervalSchedule{every: every}, nil
+	}
+
+	switch expr {
+	case "@hourly":
+		expr = "0 * * * *"
+	case "@daily", "@midnight":
+		expr = "0 0 * * *"
+	case "@weekly":
+		expr = "0 0 * * 0"
+	case "@monthly":
+		expr = "0 0 1 * *"
+	case "@yearly", "@annually":
+		expr = "0 0 1 1 *"
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var (
+		s   cronSchedule
+		err error
+	)
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// 7 is an alias for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*" || fields[2] == "?"
+	s.dowAny = fields[4] == "*" || fields[4] == "?"
+
+	return s, nil
+}
+
+type intervalSchedule struct {
+	every time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.every)
+}
+
+// cronSchedule stores each field as a bitmask of allowed values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+func (s cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Five years covers every satisfiable expression (e.g. Feb 29th)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron semantics: when both day fields are restricted, either may match
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// parseCronField parses lists of values, ranges and steps ("1,15", "9-17", "*/5", "10-50/10")
+func parseCronField(field string, min, max int) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+
+	return mask, nil
+}
+
+// PlanScheduledRun decides whether a job that was due at due should run at now.
+// missed counts the scheduled times that passed after due without a run; next is
+// the following scheduled time once this one is handled.
+func PlanScheduledRun(schedule Schedule, due, now time.Time, policy MissedRunPolicy) (run bool, missed int, next time.Time) {
+	next = schedule.Next(now)
+
+	for t := schedule.Next(due); !t.IsZero() && !t.After(now) && missed < 1000; t = schedule.Next(t) {
+		missed++
+	}
+
+	if missed > 0 && policy == MissedRunSkip {
+		return false, missed, next
+	}
+	return true, missed, next
+}
+
+// TableName returns the table name for ScheduledJob
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
+
+// TableName returns the table name for ScheduledJobRun
+func (ScheduledJobRun) TableName() string {
+	return "scheduled_job_runs"
+}
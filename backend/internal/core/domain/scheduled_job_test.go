@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduleNext(t *testing.T) {
+	from := time.Date(2026, 3, 4, 10, 7, 30, 0, time.UTC) // Wednesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * 1-5", time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2026, 3, 5, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseScheduleDayOfMonthOrWeek(t *testing.T) {
+	// Both day fields restricted: the 15th or any Monday
+	schedule, err := ParseSchedule("0 0 15 * 1")
+	require.NoError(t, err)
+
+	from := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), schedule.Next(from))
+	assert.Equal(t, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), schedule.Next(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "@every 10ms", "@every soon"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestPlanScheduledRun(t *testing.T) {
+	schedule, err := ParseSchedule("@hourly")
+	require.NoError(t, err)
+
+	due := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	// On time
+	run, missed, next := PlanScheduledRun(schedule, due, due.Add(20*time.Second), MissedRunOnce)
+	assert.True(t, run)
+	assert.Equal(t, 0, missed)
+	assert.Equal(t, due.Add(time.Hour), next)
+
+	// Three hours late: catch up once
+	late := due.Add(3*time.Hour + 5*time.Minute)
+	run, missed, next = PlanScheduledRun(schedule, due, late, MissedRunOnce)
+	assert.True(t, run)
+	assert.Equal(t, 3, missed)
+	assert.Equal(t, time.Date(2026, 3, 4, 14, 0, 0, 0, time.UTC), next)
+
+	// Three hours late: skip
+	run, missed, _ = PlanScheduledRun(schedule, due, late, MissedRunSkip)
+	assert.False(t, run)
+	assert.Equal(t, 3, missed)
+}
@@ -20,6 +20,11 @@ type Team struct {
 	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
 }
 
+// AfterDelete moves the team and its memberships to the recycle bin
+func (t *Team) AfterDelete(tx *gorm.DB) error {
+	return cascadeTeamDeletion(tx, t.ID)
+}
+
 // TeamMember represents a user's membership in a team with additional role info
 type TeamMember struct {
 	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
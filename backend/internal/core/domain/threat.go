@@ -0,0 +1,209 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Traffic Light Protocol 2.0 markings, from the least to the most restrictive
+const (
+	TLPClear       = "CLEAR"
+	TLPGreen       = "GREEN"
+	TLPAmber       = "AMBER"
+	TLPAmberStrict = "AMBER+STRICT"
+	TLPRed         = "RED"
+)
+
+// DefaultTLP is the marking of risks and threats that carry none: they are
+// shared as AMBER
+const DefaultTLP = TLPAmber
+
+var ErrInvalidTLP = errors.New("invalid TLP marking: expected CLEAR, GREEN, AMBER, AMBER+STRICT or RED")
+
+var tlpOrder = []string{TLPClear, TLPGreen, TLPAmber, TLPAmberStrict, TLPRed}
+
+// NormalizeTLP normalizes a marking as written in feeds ("tlp:amber",
+// "TLP:WHITE", "green"); TLP 1.0 WHITE is CLEAR. An empty marking stays empty.
+func NormalizeTLP(tlp string) (string, error) {
+	tlp = strings.ToUpper(strings.TrimSpace(tlp))
+	tlp = strings.TrimSpace(strings.TrimPrefix(tlp, "TLP:"))
+	switch tlp {
+	case "":
+		return "", nil
+	case "WHITE":
+		return TLPClear, nil
+	case "AMBER STRICT", "AMBER_STRICT":
+		return TLPAmberStrict, nil
+	}
+	for _, known := range tlpOrder {
+		if tlp == known {
+			return tlp, nil
+		}
+	}
+	return "", ErrInvalidTLP
+}
+
+// tlpRank orders markings; no marking ranks as DefaultTLP
+func tlpRank(tlp string) int {
+	if tlp == "" {
+		tlp = DefaultTLP
+	}
+	for i, known := range tlpOrder {
+		if tlp == known {
+			return i
+		}
+	}
+	return len(tlpOrder) - 1
+}
+
+// TLPAllows reports whether information marked tlp may be shared under the
+// ceiling marking
+func TLPAllows(ceiling, tlp string) bool {
+	return tlpRank(tlp) <= tlpRank(ceiling)
+}
+
+// TLPsAllowedBy lists the markings shared under the ceiling marking
+func TLPsAllowedBy(ceiling string) []string {
+	return tlpOrder[:tlpRank(ceiling)+1]
+}
+
+// EffectiveTLP returns the marking information is shared under: its own, or
+// DefaultTLP
+func EffectiveTLP(tlp string) string {
+	if tlp == "" {
+		return DefaultTLP
+	}
+	return tlp
+}
+
+// Types of STIX 2.1 objects imported as threats
+const (
+	ThreatIndicator     = "indicator"
+	ThreatVulnerability = "vulnerability"
+	ThreatAttackPattern = "attack-pattern"
+	ThreatActor         = "threat-actor"
+)
+
+// IsThreatType reports whether STIX objects of type t are imported as threats
+func IsThreatType(t string) bool {
+	switch t {
+	case ThreatIndicator, ThreatVulnerability, ThreatAttackPattern, ThreatActor:
+		return true
+	}
+	return false
+}
+
+// RiskSTIXID returns the STIX ID of the vulnerability a risk is shared as
+func RiskSTIXID(riskID uuid.UUID) string {
+	return ThreatVulnerability + "--" + riskID.String()
+}
+
+// RiskIDFromSTIXID returns the risk a shared vulnerability stands for
+func RiskIDFromSTIXID(stixID string) (uuid.UUID, bool) {
+	raw, ok := strings.CutPrefix(stixID, ThreatVulnerability+"--")
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(raw)
+	return id, err == nil
+}
+
+// Threat is a piece of threat intelligence imported from a STIX bundle or a
+// TAXII feed (Contract with OpenCTI), keyed by its STIX ID. A newer version of
+// the object (by its modified date) replaces it.
+type Threat struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	STIXID      string         `gorm:"column:stix_id;size:100;uniqueIndex;not null" json:"stix_id"`
+	Type        string         `gorm:"size:30;index" json:"type"` // indicator, vulnerability, attack-pattern, threat-actor
+	Name        string         `gorm:"size:255" json:"name"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	TLP         string         `gorm:"size:20" json:"tlp"`                         // Traffic Light Protocol
+	ExternalID  string         `gorm:"size:50;index" json:"external_id,omitempty"` // CVE of a vulnerability, ATT&CK ID of an attack pattern
+	Pattern     string         `gorm:"type:text" json:"pattern,omitempty"`         // Detection pattern of an indicator
+	PatternType string         `gorm:"size:30" json:"pattern_type,omitempty"`
+	Labels      pq.StringArray `gorm:"type:text[]" json:"labels,omitempty"`  // Labels and indicator or threat actor types
+	Aliases     pq.StringArray `gorm:"type:text[]" json:"aliases,omitempty"` // Of a threat actor
+	Confidence  int            `json:"confidence,omitempty"`                 // 0-100
+	Source      string         `gorm:"size:255" json:"source,omitempty"`     // Feed or upload it came from
+	Revoked     bool           `gorm:"not null;default:false" json:"revoked,omitempty"`
+	ValidFrom   *time.Time     `json:"valid_from,omitempty"`
+	ValidUntil  *time.Time     `json:"valid_until,omitempty"`
+	ReportedAt  time.Time      `json:"reported_at"`         // STIX created
+	ModifiedAt  time.Time      `json:"modified_at"`         // STIX modified
+	Raw         datatypes.JSON `gorm:"type:jsonb" json:"-"` // STIX object as received, shared again as is
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TableName returns the table name for Threat
+func (Threat) TableName() string {
+	return "threats"
+}
+
+// RiskThreat links a risk to a threat that realizes or informs it
+type RiskThreat struct {
+	RiskID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"risk_id"`
+	ThreatID     uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"threat_id"`
+	Relationship string    `gorm:"size:30" json:"relationship"` // STIX relationship type (targets, related-to)
+	Source       string    `gorm:"size:255" json:"source,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for RiskThreat
+func (RiskThreat) TableName() string {
+	return "risk_threats"
+}
+
+// TAXIICollection is a collection of the TAXII 2.1 server: the tenant's risks
+// marked at most MaxTLP, and having one of Tags when set, shared as STIX with
+// the holder of its access token
+type TAXIICollection struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    uuid.UUID      `gorm:"type:uuid;index" json:"tenant_id"`
+	Title       string         `gorm:"size:255;not null" json:"title"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	MaxTLP      string         `gorm:"column:max_tlp;size:20;not null" json:"max_tlp"`
+	Tags        pq.StringArray `gorm:"type:text[]" json:"tags,omitempty"`
+	TokenHash   string         `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	CreatedBy   uuid.UUID      `gorm:"type:uuid" json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName returns the table name for TAXIICollection
+func (TAXIICollection) TableName() string {
+	return "taxii_collections"
+}
+
+// ThreatFeed is a TAXII 2.1 collection polled for threats. AddedAfter is
+// where the next poll resumes.
+type ThreatFeed struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID     uuid.UUID  `gorm:"type:uuid;index" json:"tenant_id"`
+	Name         string     `gorm:"size:255;not null" json:"name"`
+	APIRoot      string     `gorm:"column:api_root;size:500;not null" json:"api_root"`
+	CollectionID string     `gorm:"size:100;not null" json:"collection_id"`
+	Username     string     `gorm:"size:255" json:"username,omitempty"`
+	Password     string     `gorm:"size:500" json:"-"`
+	DefaultTLP   string     `gorm:"column:default_tlp;size:20" json:"default_tlp,omitempty"` // For unmarked objects
+	Disabled     bool       `gorm:"not null;default:false" json:"disabled"`
+	AddedAfter   *time.Time `json:"added_after,omitempty"`
+	LastPolledAt *time.Time `json:"last_polled_at,omitempty"`
+	LastError    string     `gorm:"type:text" json:"last_error,omitempty"`
+	LastImported int        `json:"last_imported"`
+	CreatedBy    uuid.UUID  `gorm:"type:uuid" json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for ThreatFeed
+func (ThreatFeed) TableName() string {
+	return "threat_feeds"
+}
@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// VersionRange is a range of affected versions of a vulnerable package, as
+// OSV ranges describe them: from Introduced ("0" for all versions) up to
+// Fixed excluded or LastAffected included. Both ends empty means unbounded.
+type VersionRange struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// Contains tells whether version falls in the range
+func (r VersionRange) Contains(version string) bool {
+	if r.Introduced != "" && r.Introduced != "0" && CompareVersions(version, r.Introduced) < 0 {
+		return false
+	}
+	if r.Fixed != "" && CompareVersions(version, r.Fixed) >= 0 {
+		return false
+	}
+	if r.LastAffected != "" && CompareVersions(version, r.LastAffected) > 0 {
+		return false
+	}
+	return true
+}
+
+// preReleaseRank orders the qualifiers that come before a release
+// (1.0-alpha < 1.0-beta < 1.0-rc1 < 1.0); the ones of a release rank
+// releaseRank, others follow a release (1.0-sp1 > 1.0)
+var preReleaseRank = map[string]int{
+	"dev": 0, "snapshot": 1, "alpha": 2, "a": 2, "beta": 3, "b": 3,
+	"milestone": 4, "m": 4, "rc": 5, "cr": 5, "pre": 5, "preview": 5,
+	"final": releaseRank, "ga": releaseRank, "release": releaseRank,
+}
+
+const releaseRank = 10
+
+type versionToken struct {
+	number  string // Digits without leading zeros
+	text    string
+	numeric bool
+}
+
+// versionTokens splits a version into runs of digits and letters, dropping
+// a "v" prefix and semver build metadata
+func versionTokens(version string) []versionToken {
+	v := strings.ToLower(strings.TrimSpace(version))
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i > 0 {
+		v = v[:i]
+	}
+	var tokens []versionToken
+	var current strings.Builder
+	digits := false
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		s := current.String()
+		if digits {
+			s = strings.TrimLeft(s, "0")
+			tokens = append(tokens, versionToken{number: s, numeric: true})
+		} else {
+			tokens = append(tokens, versionToken{text: s})
+		}
+		current.Reset()
+	}
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			if !digits {
+				flush()
+			}
+			digits = true
+			current.WriteRune(r)
+		case unicode.IsLetter(r):
+			if digits {
+				flush()
+			}
+			digits = false
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// CompareVersions compares two versions across the usual schemes (semver,
+// Maven, PEP 440, Debian-like): numeric parts compare as numbers, missing
+// parts as zero, and pre-release qualifiers sort before the release. It
+// returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	ta, tb := versionTokens(a), versionTokens(b)
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		var x, y *versionToken
+		if i < len(ta) {
+			x = &ta[i]
+		}
+		if i < len(tb) {
+			y = &tb[i]
+		}
+		if c := compareVersionTokens(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareVersionTokens(x, y *versionToken) int {
+	switch {
+	case x == nil && y == nil:
+		return 0
+	case x == nil:
+		return -compareVersionTokens(y, nil)
+	case y == nil:
+		if x.numeric {
+			if x.number == "" {
+				return 0
+			}
+			return 1
+		}
+		return compareInts(qualifierRank(x.text), releaseRank)
+	case x.numeric && y.numeric:
+		if c := compareInts(len(x.number), len(y.number)); c != 0 {
+			return c
+		}
+		return strings.Compare(x.number, y.number)
+	case x.numeric:
+		// 1.0.1 > 1.0-rc1, and 1.0.1 > 1.0-sp1
+		return 1
+	case y.numeric:
+		return -1
+	}
+	if c := compareInts(qualifierRank(x.text), qualifierRank(y.text)); c != 0 {
+		return c
+	}
+	return strings.Compare(x.text, y.text)
+}
+
+func qualifierRank(text string) int {
+	if rank, ok := preReleaseRank[text]; ok {
+		return rank
+	}
+	return releaseRank + 1
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// VersionConstraint is a set of version comparisons that must all hold,
+// written like "<2.17", ">=2.0, <2.17.1" or "=2.14.1"
+type VersionConstraint []versionComparison
+
+type versionComparison struct {
+	op      string
+	version string
+}
+
+// ParseVersionConstraint reads a comma-separated list of comparisons; a
+// bare version means equality
+func ParseVersionConstraint(raw string) (VersionConstraint, error) {
+	var constraint VersionConstraint
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		op := ""
+		for _, candidate := range []string{"<=", ">=", "==", "!=", "<", ">", "="} {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				break
+			}
+		}
+		version := strings.TrimSpace(strings.TrimPrefix(part, op))
+		if version == "" {
+			return nil, fmt.Errorf("invalid version constraint %q", part)
+		}
+		if op == "" || op == "==" {
+			op = "="
+		}
+		constraint = append(constraint, versionComparison{op: op, version: version})
+	}
+	if len(constraint) == 0 {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+	return constraint, nil
+}
+
+// Matches tells whether version satisfies every comparison
+func (c VersionConstraint) Matches(version string) bool {
+	for _, cmp := range c {
+		r := CompareVersions(version, cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "!=":
+			ok = r != 0
+		default:
+			ok = r == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.14.1", "2.17", -1},
+		{"2.17.0", "2.17", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"2.0.0-SNAPSHOT", "2.0.0-rc1", -1},
+		{"1.0.Final", "1.0", 0},
+		{"1.0-sp1", "1.0", 1},
+		{"1.0.1", "1.0-sp1", 1},
+		{"1.2.3+build.5", "1.2.3", 0},
+		{"0010", "9", 1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, -tt.want, CompareVersions(tt.b, tt.a), "%s vs %s", tt.b, tt.a)
+	}
+}
+
+func TestVersionRangeContains(t *testing.T) {
+	log4shell := VersionRange{Introduced: "2.0-beta9", Fixed: "2.15.0"}
+	assert.True(t, log4shell.Contains("2.14.1"))
+	assert.True(t, log4shell.Contains("2.0"))
+	assert.False(t, log4shell.Contains("2.15.0"))
+	assert.False(t, log4shell.Contains("1.2.17"))
+
+	upTo := VersionRange{Introduced: "0", LastAffected: "1.4.2"}
+	assert.True(t, upTo.Contains("0.1"))
+	assert.True(t, upTo.Contains("1.4.2"))
+	assert.False(t, upTo.Contains("1.4.3"))
+}
+
+func TestVersionConstraint(t *testing.T) {
+	c, err := ParseVersionConstraint(">=2.0, <2.17")
+	require.NoError(t, err)
+	assert.True(t, c.Matches("2.14.1"))
+	assert.False(t, c.Matches("2.17.0"))
+	assert.False(t, c.Matches("1.2.17"))
+
+	exact, err := ParseVersionConstraint("2.14.1")
+	require.NoError(t, err)
+	assert.True(t, exact.Matches("2.14.1"))
+	assert.False(t, exact.Matches("2.14.2"))
+
+	_, err = ParseVersionConstraint("<")
+	assert.Error(t, err)
+	_, err = ParseVersionConstraint(" , ")
+	assert.Error(t, err)
+}
+
+func TestLicenseIDs(t *testing.T) {
+	assert.Equal(t, []string{"Apache-2.0", "MIT"}, LicenseIDs([]string{"(Apache-2.0 OR MIT)", "mit"}))
+	assert.Equal(t, []string{"GPL-2.0-only"}, LicenseIDs([]string{"GPL-2.0-only WITH Classpath-exception-2.0", "NOASSERTION"}))
+}
@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+)
+
+// Offline feeds enriching the CVEs referenced by risks
+const (
+	FeedNVD  = "NVD"  // NIST National Vulnerability Database: CVSS vectors
+	FeedKEV  = "KEV"  // CISA Known Exploited Vulnerabilities catalog
+	FeedEPSS = "EPSS" // FIRST Exploit Prediction Scoring System
+)
+
+// TagKEV is added to the risks referencing a CVE of the KEV catalog
+const TagKEV = "KEV"
+
+// CVERecord is what the offline feeds know about a CVE. Each feed fills its
+// own columns: NVD the description and CVSS, KEV the exploitation status,
+// EPSS the exploitation probability.
+type CVERecord struct {
+	ID           string     `gorm:"size:30;primaryKey" json:"id"` // CVE-2021-44228
+	Description  string     `gorm:"type:text" json:"description,omitempty"`
+	CVSSVector   string     `gorm:"size:200" json:"cvss_vector,omitempty"`
+	CVSSVersion  string     `gorm:"size:10" json:"cvss_version,omitempty"`
+	CVSSScore    float64    `gorm:"type:numeric(3,1)" json:"cvss_score,omitempty"`
+	CVSSSeverity string     `gorm:"size:20" json:"cvss_severity,omitempty"`
+	Published    *time.Time `json:"published,omitempty"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+
+	KEV           bool       `gorm:"not null;default:false" json:"kev"`
+	KEVName       string     `gorm:"size:255" json:"kev_name,omitempty"`
+	KEVDateAdded  *time.Time `json:"kev_date_added,omitempty"`
+	KEVDueDate    *time.Time `json:"kev_due_date,omitempty"`
+	KEVRansomware bool       `gorm:"not null;default:false" json:"kev_ransomware"` // Known use in ransomware campaigns
+
+	EPSS           *float64   `json:"epss,omitempty"` // Probability of exploitation in the next 30 days
+	EPSSPercentile *float64   `json:"epss_percentile,omitempty"`
+	EPSSDate       *time.Time `json:"epss_date,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for CVERecord
+func (CVERecord) TableName() string {
+	return "cve_records"
+}
+
+// VulnFeedImport records a load of an offline feed
+type VulnFeedImport struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Source      string    `gorm:"size:10;not null;index" json:"source"` // NVD, KEV, EPSS
+	Version     string    `gorm:"size:100" json:"version"`              // Feed timestamp, catalog version or EPSS model and score date
+	FileName    string    `gorm:"size:255" json:"file_name,omitempty"`
+	RecordCount int       `json:"record_count"`
+	ImportedBy  string    `gorm:"size:100" json:"imported_by"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName returns the table name for VulnFeedImport
+func (VulnFeedImport) TableName() string {
+	return "vuln_feed_imports"
+}
+
+// EPSSThreshold maps EPSS percentiles from Percentile up to a probability
+type EPSSThreshold struct {
+	Percentile  float64 `json:"percentile"` // 0 to 1
+	Probability int     `json:"probability"`
+}
+
+// EnrichmentPolicy sets, for a tenant, whether and how enrichment moves the
+// probability of risks. Probability is only raised unless AllowDecrease.
+type EnrichmentPolicy struct {
+	ID                uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID          uuid.UUID      `gorm:"type:uuid;uniqueIndex" json:"tenant_id"`
+	AdjustProbability bool           `gorm:"not null;default:false" json:"adjust_probability"`
+	AllowDecrease     bool           `gorm:"not null;default:false" json:"allow_decrease"`
+	KEVProbability    int            `gorm:"not null;default:5" json:"kev_probability"` // 0 ignores the KEV status
+	EPSSThresholds    datatypes.JSON `gorm:"type:jsonb" json:"epss_thresholds"`         // []EPSSThreshold
+
+	UpdatedBy uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for EnrichmentPolicy
+func (EnrichmentPolicy) TableName() string {
+	return "enrichment_policies"
+}
+
+// DefaultEPSSThresholds is the EPSS mapping of tenants without a policy
+var DefaultEPSSThresholds = []EPSSThreshold{
+	{Percentile: 0.95, Probability: 5},
+	{Percentile: 0.80, Probability: 4},
+	{Percentile: 0.50, Probability: 3},
+	{Percentile: 0.20, Probability: 2},
+	{Percentile: 0, Probability: 1},
+}
+
+// DefaultEnrichmentPolicy is used for tenants without a configured policy:
+// risks are tagged but their probability is left alone
+func DefaultEnrichmentPolicy(tenantID uuid.UUID) EnrichmentPolicy {
+	thresholds, _ := json.Marshal(DefaultEPSSThresholds)
+	return EnrichmentPolicy{
+		TenantID:       tenantID,
+		KEVProbability: 5,
+		EPSSThresholds: thresholds,
+	}
+}
+
+// Thresholds returns the EPSS mapping, highest percentile first
+func (p *EnrichmentPolicy) Thresholds() []EPSSThreshold {
+	var thresholds []EPSSThreshold
+	if len(p.EPSSThresholds) > 0 {
+		_ = json.Unmarshal(p.EPSSThresholds, &thresholds)
+	}
+	sort.SliceStable(thresholds, func(i, j int) bool { return thresholds[i].Percentile > thresholds[j].Percentile })
+	return thresholds
+}
+
+// Validate checks the probabilities and percentiles of the policy
+func (p *EnrichmentPolicy) Validate() error {
+	if p.KEVProbability < 0 || p.KEVProbability > 5 {
+		return fmt.Errorf("kev_probability must be between 0 and 5")
+	}
+	var thresholds []EPSSThreshold
+	if len(p.EPSSThresholds) > 0 {
+		if err := json.Unmarshal(p.EPSSThresholds, &thresholds); err != nil {
+			return fmt.Errorf("invalid epss_thresholds: %w", err)
+		}
+	}
+	for _, t := range thresholds {
+		if t.Percentile < 0 || t.Percentile > 1 {
+			return fmt.Errorf("EPSS percentile %v must be between 0 and 1", t.Percentile)
+		}
+		if t.Probability < 1 || t.Probability > 5 {
+			return fmt.Errorf("probability for EPSS percentile %v must be between 1 and 5", t.Percentile)
+		}
+	}
+	return nil
+}
+
+// ProbabilityFor maps the exploitation evidence of a risk to a probability
+// and the rule that gave it; 0 when no rule applies. KEV listing prevails
+// over EPSS.
+func (p *EnrichmentPolicy) ProbabilityFor(kev bool, epssPercentile *float64) (int, string) {
+	if kev && p.KEVProbability > 0 {
+		return p.KEVProbability, "listed in the CISA KEV catalog"
+	}
+	if epssPercentile == nil {
+		return 0, ""
+	}
+	for _, t := range p.Thresholds() {
+		if *epssPercentile >= t.Percentile {
+			return t.Probability, fmt.Sprintf("EPSS percentile %.2f >= %.2f", *epssPercentile, t.Percentile)
+		}
+	}
+	return 0, ""
+}
+
+// CVEEvidence is what the feeds said about a CVE of a risk when it was enriched
+type CVEEvidence struct {
+	CVE            string     `json:"cve"`
+	CVSSVector     string     `json:"cvss_vector,omitempty"`
+	CVSSScore      float64    `json:"cvss_score,omitempty"`
+	KEV            bool       `json:"kev"`
+	KEVDateAdded   *time.Time `json:"kev_date_added,omitempty"`
+	EPSS           *float64   `json:"epss,omitempty"`
+	EPSSPercentile *float64   `json:"epss_percentile,omitempty"`
+}
+
+// RiskEnrichment is the provenance of the last enrichment of a risk: the
+// CVEs it references, what the feeds said about them, the policy rule that
+// applied and the last probability change it made
+type RiskEnrichment struct {
+	RiskID            uuid.UUID      `gorm:"type:uuid;primaryKey" json:"risk_id"`
+	TenantID          uuid.UUID      `gorm:"type:uuid;index" json:"tenant_id"` // Tenant whose policy applied
+	CVEs              pq.StringArray `gorm:"column:cves;type:text[]" json:"cves"`
+	Evidence          datatypes.JSON `gorm:"type:jsonb" json:"evidence"` // []CVEEvidence
+	KEV               bool           `gorm:"not null;default:false" json:"kev"`
+	MaxCVSSScore      float64        `gorm:"column:max_cvss_score;type:numeric(3,1)" json:"max_cvss_score,omitempty"`
+	MaxEPSSPercentile *float64       `gorm:"column:max_epss_percentile" json:"max_epss_percentile,omitempty"`
+
+	SuggestedProbability int    `json:"suggested_probability,omitempty"` // From the policy mapping; 0 when no rule applies
+	Reason               string `gorm:"type:text" json:"reason,omitempty"`
+
+	// Last probability change made by enrichment
+	ProbabilityFrom      int        `json:"probability_from,omitempty"`
+	ProbabilityTo        int        `json:"probability_to,omitempty"`
+	ProbabilityReason    string     `gorm:"type:text" json:"probability_reason,omitempty"`
+	ProbabilityChangedAt *time.Time `json:"probability_changed_at,omitempty"`
+
+	Feeds      datatypes.JSON `gorm:"type:jsonb" json:"feeds"` // Version of each feed used
+	EnrichedAt time.Time      `json:"enriched_at"`
+}
+
+// TableName returns the table name for RiskEnrichment
+func (RiskEnrichment) TableName() string {
+	return "risk_enrichments"
+}
+
+var cvePattern = regexp.MustCompile(`(?i)\bCVE-\d{4}-\d{4,}\b`)
+
+// ExtractCVEs returns the CVE identifiers found in texts, upper-cased, in
+// order of appearance and without duplicates
+func ExtractCVEs(texts ...string) []string {
+	seen := map[string]bool{}
+	var cves []string
+	for _, text := range texts {
+		for _, match := range cvePattern.FindAllString(text, -1) {
+			cve := strings.ToUpper(match)
+			if !seen[cve] {
+				seen[cve] = true
+				cves = append(cves, cve)
+			}
+		}
+	}
+	return cves
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCVEs(t *testing.T) {
+	cves := ExtractCVEs(
+		"GHSA-jfh8-c2jp-5v3q@asset",
+		"Log4Shell (cve-2021-44228), see also CVE-2021-45046 and CVE-2021-44228.",
+		"CVE-2024-123456",
+		"NOTCVE-2021-1", "CVE-21-1234",
+	)
+	assert.Equal(t, []string{"CVE-2021-44228", "CVE-2021-45046", "CVE-2024-123456"}, cves)
+	assert.Empty(t, ExtractCVEs("", "no reference"))
+}
+
+func TestEnrichmentPolicyProbabilityFor(t *testing.T) {
+	policy := DefaultEnrichmentPolicy(uuid.New())
+	pct := func(v float64) *float64 { return &v }
+
+	p, rule := policy.ProbabilityFor(true, pct(0.1))
+	assert.Equal(t, 5, p)
+	assert.Contains(t, rule, "KEV")
+
+	p, rule = policy.ProbabilityFor(false, pct(0.97))
+	assert.Equal(t, 5, p)
+	assert.Equal(t, "EPSS percentile 0.97 >= 0.95", rule)
+
+	p, _ = policy.ProbabilityFor(false, pct(0.5))
+	assert.Equal(t, 3, p)
+
+	p, _ = policy.ProbabilityFor(false, nil)
+	assert.Zero(t, p, "no evidence, no rule")
+
+	// KEV ignored and a sparse mapping
+	policy.KEVProbability = 0
+	policy.EPSSThresholds, _ = json.Marshal([]EPSSThreshold{{Percentile: 0.5, Probability: 3}, {Percentile: 0.9, Probability: 4}})
+	p, _ = policy.ProbabilityFor(true, pct(0.92))
+	assert.Equal(t, 4, p)
+	p, _ = policy.ProbabilityFor(true, pct(0.3))
+	assert.Zero(t, p)
+}
+
+func TestEnrichmentPolicyValidate(t *testing.T) {
+	policy := DefaultEnrichmentPolicy(uuid.Nil)
+	require.NoError(t, policy.Validate())
+
+	policy.KEVProbability = 6
+	assert.Error(t, policy.Validate())
+
+	policy.KEVProbability = 5
+	policy.EPSSThresholds = []byte(`[{"percentile": 1.5, "probability": 3}]`)
+	assert.Error(t, policy.Validate())
+	policy.EPSSThresholds = []byte(`[{"percentile": 0.5, "probability": 0}]`)
+	assert.Error(t, policy.Validate())
+}
@@ -1,18 +1,67 @@
 package ports
 
-import "github.com/opendefender/openrisk/internal/core/domain"
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
 
 // IncidentProvider : Interface que TheHive devra respecter
 type IncidentProvider interface {
 	FetchRecentIncidents() ([]domain.Incident, error)
 }
 
+// IncidentRecorder : persiste les incidents synchronisés et les lie aux
+// risques qu'ils ont réalisés (IncidentService)
+type IncidentRecorder interface {
+	RecordIncident(ctx context.Context, incident *domain.Incident, riskIDs ...uuid.UUID) (*domain.Incident, error)
+}
+
 // ThreatProvider : Interface que OpenCTI devra respecter
 type ThreatProvider interface {
 	FetchThreats() ([]domain.Threat, error)
 }
 
+// ThreatRelationship : relation STIX entre deux objets, par leurs IDs STIX
+type ThreatRelationship struct {
+	Type      string // targets, indicates, related-to...
+	SourceRef string
+	TargetRef string
+}
+
+// ThreatIntel : menaces lues d'un bundle STIX ou d'une collection TAXII et
+// les relations qui les lient, entre elles ou aux risques partagés
+// (vulnerability--<ID du risque>)
+type ThreatIntel struct {
+	Threats       []domain.Threat
+	Relationships []ThreatRelationship
+}
+
+// ThreatFeedProvider : Interface que les clients TAXII devront respecter.
+// PollFeed renvoie les objets ajoutés à la collection du flux après
+// feed.AddedAfter, et la date d'ajout du dernier, d'où reprendre
+type ThreatFeedProvider interface {
+	PollFeed(ctx context.Context, feed domain.ThreatFeed) (*ThreatIntel, *time.Time, error)
+}
+
 // ComplianceProvider : Interface que OpenRMF devra respecter
 type ComplianceProvider interface {
 	FetchControls() ([]domain.Control, error)
-}
\ No newline at end of file
+}
+
+// DiscoveredAsset : ressource d'un inventaire cloud ou CMDB traduite en asset.
+// Criticality et Owner sont vides quand la source ne les indique pas ;
+// Retired si la source signale la ressource supprimée ou retirée.
+type DiscoveredAsset struct {
+	Asset   domain.Asset
+	Retired bool
+}
+
+// AssetInventoryProvider : Interface que les connecteurs CMDB (ServiceNow)
+// devront respecter
+type AssetInventoryProvider interface {
+	Source() string
+	FetchAssets(ctx context.Context) ([]DiscoveredAsset, error)
+}
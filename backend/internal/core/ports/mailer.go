@@ -0,0 +1,23 @@
+package ports
+
+import "context"
+
+// Attachment : pièce jointe d'un email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// EmailMessage : email sortant (rapports planifiés, notifications)
+type EmailMessage struct {
+	To          []string
+	Subject     string
+	Body        string // Texte brut
+	Attachments []Attachment
+}
+
+// Mailer : envoi d'emails. Implémenté par l'adaptateur SMTP.
+type Mailer interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
@@ -0,0 +1,18 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when the key does not exist
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore : stockage des artefacts générés (exports, rapports).
+// Implémenté par le système de fichiers local et par tout stockage compatible S3 (AWS, MinIO).
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
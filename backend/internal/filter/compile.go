@@ -0,0 +1,451 @@
+package filter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Options carries the context macros are resolved in
+type Options struct {
+	// Now anchors @now and @today; defaults to the current time
+	Now time.Time
+	// Resolve expands identity macros such as @me or @team:infra into the
+	// values they stand for. Without it those macros are rejected.
+	Resolve func(macro string) ([]string, error)
+}
+
+// Condition is a compiled expression: a SQL condition with "?" placeholders
+// and their arguments, ready for gorm's Where. An empty SQL matches everything.
+type Condition struct {
+	SQL  string
+	Args []interface{}
+}
+
+// CompileString parses and compiles an expression
+func CompileString(input string, schema *Schema, opts Options) (*Condition, error) {
+	n, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(n, schema, opts)
+}
+
+// Compile validates a parsed expression against the schema and translates it to SQL
+func Compile(n Node, schema *Schema, opts Options) (*Condition, error) {
+	if n == nil {
+		return &Condition{}, nil
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	c := &compiler{schema: schema, opts: opts}
+	sql, err := c.compile(n)
+	if err != nil {
+		return nil, err
+	}
+	return &Condition{SQL: sql, Args: c.args}, nil
+}
+
+type compiler struct {
+	schema *Schema
+	opts   Options
+	args   []interface{}
+}
+
+func (c *compiler) compile(n Node) (string, error) {
+	switch n := n.(type) {
+	case *Logical:
+		left, err := c.compile(n.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := c.compile(n.Right)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + n.Op + " " + right + ")", nil
+	case *Not:
+		expr, err := c.compile(n.Expr)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + wrap(expr), nil
+	case *Comparison:
+		return c.comparison(n)
+	}
+	return "", errorAt(0, "unsupported expression")
+}
+
+func wrap(sql string) string {
+	if strings.HasPrefix(sql, "(") && strings.HasSuffix(sql, ")") {
+		return sql
+	}
+	return "(" + sql + ")"
+}
+
+// col writes the field expression, binding its arguments
+func (c *compiler) col(f Field) string {
+	c.args = append(c.args, f.ExprArgs...)
+	return f.Expr
+}
+
+// bind binds a value and returns its placeholder
+func (c *compiler) bind(v interface{}) string {
+	c.args = append(c.args, v)
+	return "?"
+}
+
+func (c *compiler) comparison(cmp *Comparison) (string, error) {
+	f, ok := c.schema.Field(cmp.Field)
+	if !ok {
+		return "", errorAt(cmp.Pos, "unknown field %q", cmp.Field)
+	}
+
+	allowed := false
+	for _, op := range operatorsFor(f.Type) {
+		if op == cmp.Op {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		hint := ""
+		if (f.Type == FieldArray || f.Type == FieldRelation) && (cmp.Op == OpEq || cmp.Op == OpIn) {
+			hint = " (use HAS or HAS ANY)"
+		}
+		return "", errorAt(cmp.Pos, "operator %s is not supported on %s field %q%s", cmp.Op, f.Type, f.Name, hint)
+	}
+
+	switch f.Type {
+	case FieldNumber:
+		return c.numberComparison(f, cmp)
+	case FieldDate:
+		return c.dateComparison(f, cmp)
+	case FieldBool:
+		return c.boolComparison(f, cmp)
+	case FieldArray:
+		return c.arrayComparison(f, cmp)
+	case FieldRelation:
+		return c.relationComparison(f, cmp)
+	}
+	return c.textComparison(f, cmp)
+}
+
+// texts converts values to strings, expanding identity macros and checking
+// enum and UUID values
+func (c *compiler) texts(f Field, values []Value) ([]string, error) {
+	var out []string
+	for _, v := range values {
+		var texts []string
+		if v.Kind == ValueMacro {
+			if c.opts.Resolve == nil {
+				return nil, errorAt(v.Pos, "macro @%s is not available here", v.Text)
+			}
+			expanded, err := c.opts.Resolve(v.Text)
+			if err != nil {
+				return nil, errorAt(v.Pos, "@%s: %s", v.Text, err.Error())
+			}
+			texts = expanded
+		} else {
+			texts = []string{v.Text}
+		}
+
+		for _, t := range texts {
+			switch f.Type {
+			case FieldEnum:
+				canonical := ""
+				for _, allowed := range f.Values {
+					if strings.EqualFold(allowed, t) {
+						canonical = allowed
+						break
+					}
+				}
+				if canonical == "" {
+					return nil, errorAt(v.Pos, "%q is not a valid %s (expected one of %s)", t, f.Name, strings.Join(f.Values, ", "))
+				}
+				t = canonical
+			case FieldUUID:
+				id, err := uuid.Parse(t)
+				if err != nil {
+					return nil, errorAt(v.Pos, "%q is not a valid ID", t)
+				}
+				t = id.String()
+			}
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (c *compiler) textComparison(f Field, cmp *Comparison) (string, error) {
+	empty := func() string {
+		if f.Type == FieldUUID {
+			return c.col(f) + " IS NULL"
+		}
+		return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " = '')"
+	}
+	switch cmp.Op {
+	case OpIsEmpty:
+		return empty(), nil
+	case OpIsNotEmpty:
+		return "NOT " + wrap(empty()), nil
+	}
+
+	values, err := c.texts(f, cmp.Values)
+	if err != nil {
+		return "", err
+	}
+
+	switch cmp.Op {
+	case OpContains:
+		if len(values) != 1 {
+			return "", errorAt(cmp.Pos, "CONTAINS takes a single value")
+		}
+		return c.col(f) + " ILIKE " + c.bind("%"+escapeLike(values[0])+"%"), nil
+	case OpEq, OpIn:
+		if len(values) == 0 {
+			return "1 = 0", nil
+		}
+		if len(values) == 1 {
+			return c.col(f) + " = " + c.bind(values[0]), nil
+		}
+		return c.col(f) + " IN " + c.bind(values), nil
+	case OpNe, OpNotIn:
+		if len(values) == 0 {
+			return "1 = 1", nil
+		}
+		if len(values) == 1 {
+			return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " <> " + c.bind(values[0]) + ")", nil
+		}
+		return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " NOT IN " + c.bind(values) + ")", nil
+	}
+	return "", errorAt(cmp.Pos, "operator %s is not supported on %q", cmp.Op, f.Name)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func (c *compiler) numberComparison(f Field, cmp *Comparison) (string, error) {
+	switch cmp.Op {
+	case OpIsEmpty:
+		return c.col(f) + " IS NULL", nil
+	case OpIsNotEmpty:
+		return c.col(f) + " IS NOT NULL", nil
+	}
+
+	numbers := make([]float64, 0, len(cmp.Values))
+	for _, v := range cmp.Values {
+		if v.Kind == ValueMacro {
+			return "", errorAt(v.Pos, "macro @%s cannot be compared with number field %q", v.Text, f.Name)
+		}
+		n, err := strconv.ParseFloat(v.Text, 64)
+		if err != nil {
+			return "", errorAt(v.Pos, "%q is not a number", v.Text)
+		}
+		numbers = append(numbers, n)
+	}
+
+	switch cmp.Op {
+	case OpIn:
+		return c.col(f) + " IN " + c.bind(numbers), nil
+	case OpNotIn:
+		return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " NOT IN " + c.bind(numbers) + ")", nil
+	case OpNe:
+		return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " <> " + c.bind(numbers[0]) + ")", nil
+	}
+	return c.col(f) + " " + cmp.Op + " " + c.bind(numbers[0]), nil
+}
+
+// dateValue is a point in time; day values ("2026-01-31", @today) cover the whole day
+type dateValue struct {
+	t   time.Time
+	day bool
+}
+
+var dateMacro = regexp.MustCompile(`^(?i)(now|today)(?:([+-])(\d+)(h|d|w|mo|y))?$`)
+
+func (c *compiler) date(v Value) (dateValue, error) {
+	if v.Kind == ValueMacro {
+		m := dateMacro.FindStringSubmatch(v.Text)
+		if m == nil {
+			return dateValue{}, errorAt(v.Pos, "unknown date macro @%s (use @now or @today, optionally with an offset such as @today-30d)", v.Text)
+		}
+		d := dateValue{t: c.opts.Now}
+		if strings.EqualFold(m[1], "today") {
+			y, mo, day := c.opts.Now.Date()
+			d = dateValue{t: time.Date(y, mo, day, 0, 0, 0, 0, c.opts.Now.Location()), day: true}
+		}
+		if m[2] != "" {
+			n, _ := strconv.Atoi(m[3])
+			if m[2] == "-" {
+				n = -n
+			}
+			switch strings.ToLower(m[4]) {
+			case "h":
+				d.t = d.t.Add(time.Duration(n) * time.Hour)
+			case "d":
+				d.t = d.t.AddDate(0, 0, n)
+			case "w":
+				d.t = d.t.AddDate(0, 0, 7*n)
+			case "mo":
+				d.t = d.t.AddDate(0, n, 0)
+			case "y":
+				d.t = d.t.AddDate(n, 0, 0)
+			}
+		}
+		return d, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", v.Text, c.opts.Now.Location()); err == nil {
+		return dateValue{t: t, day: true}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, v.Text, c.opts.Now.Location()); err == nil {
+			return dateValue{t: t}, nil
+		}
+	}
+	return dateValue{}, errorAt(v.Pos, "%q is not a date (use YYYY-MM-DD, RFC 3339 or @today)", v.Text)
+}
+
+func (c *compiler) dateComparison(f Field, cmp *Comparison) (string, error) {
+	switch cmp.Op {
+	case OpIsEmpty:
+		return c.col(f) + " IS NULL", nil
+	case OpIsNotEmpty:
+		return c.col(f) + " IS NOT NULL", nil
+	case OpIn, OpNotIn:
+		var parts []string
+		for _, v := range cmp.Values {
+			d, err := c.date(v)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, c.dateOp(f, OpEq, d))
+		}
+		sql := "(" + strings.Join(parts, " OR ") + ")"
+		if cmp.Op == OpNotIn {
+			sql = "(" + c.col(f) + " IS NULL OR NOT " + sql + ")"
+		}
+		return sql, nil
+	}
+
+	d, err := c.date(cmp.Values[0])
+	if err != nil {
+		return "", err
+	}
+	return c.dateOp(f, cmp.Op, d), nil
+}
+
+func (c *compiler) dateOp(f Field, op string, d dateValue) string {
+	if !d.day {
+		if op == OpNe {
+			return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " <> " + c.bind(d.t) + ")"
+		}
+		return c.col(f) + " " + op + " " + c.bind(d.t)
+	}
+
+	next := d.t.AddDate(0, 0, 1)
+	switch op {
+	case OpEq:
+		return "(" + c.col(f) + " >= " + c.bind(d.t) + " AND " + c.col(f) + " < " + c.bind(next) + ")"
+	case OpNe:
+		return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " < " + c.bind(d.t) + " OR " + c.col(f) + " >= " + c.bind(next) + ")"
+	case OpGt:
+		return c.col(f) + " >= " + c.bind(next)
+	case OpLe:
+		return c.col(f) + " < " + c.bind(next)
+	}
+	return c.col(f) + " " + op + " " + c.bind(d.t)
+}
+
+func (c *compiler) boolComparison(f Field, cmp *Comparison) (string, error) {
+	switch cmp.Op {
+	case OpIsEmpty:
+		return c.col(f) + " IS NULL", nil
+	case OpIsNotEmpty:
+		return c.col(f) + " IS NOT NULL", nil
+	}
+
+	v := cmp.Values[0]
+	b, err := strconv.ParseBool(v.Text)
+	if err != nil || v.Kind == ValueMacro {
+		return "", errorAt(v.Pos, "%q is not true or false", v.Text)
+	}
+	if cmp.Op == OpNe {
+		return "(" + c.col(f) + " IS NULL OR " + c.col(f) + " <> " + c.bind(strconv.FormatBool(b)) + ")", nil
+	}
+	return c.col(f) + " = " + c.bind(strconv.FormatBool(b)), nil
+}
+
+func (c *compiler) arrayComparison(f Field, cmp *Comparison) (string, error) {
+	switch cmp.Op {
+	case OpIsEmpty:
+		return "(" + c.col(f) + " IS NULL OR cardinality(" + c.col(f) + ") = 0)", nil
+	case OpIsNotEmpty:
+		return "cardinality(" + c.col(f) + ") > 0", nil
+	}
+
+	values, err := c.texts(f, cmp.Values)
+	if err != nil {
+		return "", err
+	}
+
+	switch cmp.Op {
+	case OpHas:
+		if len(values) == 1 {
+			return c.bind(values[0]) + " = ANY(" + c.col(f) + ")", nil
+		}
+		// A macro expanding to several values
+		return c.col(f) + " && " + c.bind(pq.StringArray(values)), nil
+	case OpHasAny:
+		return c.col(f) + " && " + c.bind(pq.StringArray(values)), nil
+	}
+	return c.col(f) + " @> " + c.bind(pq.StringArray(values)), nil
+}
+
+func (c *compiler) relationComparison(f Field, cmp *Comparison) (string, error) {
+	rel := f.Relation
+	switch cmp.Op {
+	case OpIsEmpty:
+		return "NOT EXISTS (" + rel.Exists + ")", nil
+	case OpIsNotEmpty:
+		return "EXISTS (" + rel.Exists + ")", nil
+	}
+
+	values, err := c.texts(f, cmp.Values)
+	if err != nil {
+		return "", err
+	}
+	for i, v := range values {
+		values[i] = strings.ToLower(v)
+	}
+	if len(values) == 0 {
+		return "1 = 0", nil
+	}
+
+	matchAny := func(values []string) string {
+		parts := make([]string, len(rel.Match))
+		for i, col := range rel.Match {
+			parts[i] = "lower(" + col + ") IN " + c.bind(values)
+		}
+		return "EXISTS (" + rel.Exists + " AND (" + strings.Join(parts, " OR ") + "))"
+	}
+
+	if cmp.Op == OpHasAll {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = matchAny([]string{v})
+		}
+		return "(" + strings.Join(parts, " AND ") + ")", nil
+	}
+	return matchAny(values), nil
+}
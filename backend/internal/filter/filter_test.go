@@ -0,0 +1,206 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = NewSchema(
+	Field{Name: "title", Type: FieldString, Expr: "title"},
+	Field{Name: "owner", Type: FieldString, Expr: "owner"},
+	Field{Name: "score", Type: FieldNumber, Expr: "score"},
+	Field{Name: "status", Type: FieldEnum, Expr: "status", Values: []string{"DRAFT", "ACTIVE"}},
+	Field{Name: "tags", Type: FieldArray, Expr: "tags"},
+	Field{Name: "created_at", Type: FieldDate, Expr: "created_at"},
+	Field{Name: "custom.unit", Type: FieldString, Expr: "custom_fields ->> ?", ExprArgs: []interface{}{"unit"}},
+	Field{Name: "custom.ok", Type: FieldBool, Expr: "custom_fields ->> ?", ExprArgs: []interface{}{"ok"}},
+	Field{Name: "assets", Type: FieldRelation, Relation: &Relation{
+		Exists: "SELECT 1 FROM risk_assets ra JOIN assets a ON a.id = ra.asset_id WHERE ra.risk_id = risks.id",
+		Match:  []string{"a.name"},
+	}},
+)
+
+var testNow = time.Date(2026, 3, 15, 10, 30, 0, 0, time.UTC)
+
+func compileTest(t *testing.T, expr string) *Condition {
+	t.Helper()
+	cond, err := CompileString(expr, testSchema, Options{
+		Now: testNow,
+		Resolve: func(macro string) ([]string, error) {
+			switch macro {
+			case "me":
+				return []string{"u1", "me@example.com"}, nil
+			case "team:infra":
+				return []string{"u2", "u3"}, nil
+			case "team:empty":
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown macro")
+		},
+	})
+	require.NoError(t, err, expr)
+	return cond
+}
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		expr string
+		sql  string
+		args []interface{}
+	}{
+		{
+			expr: `score >= 12 AND tags HAS "GDPR"`,
+			sql:  "(score >= ? AND ? = ANY(tags))",
+			args: []interface{}{12.0, "GDPR"},
+		},
+		{
+			expr: `status = active or NOT (title CONTAINS '50%_off')`,
+			sql:  "(status = ? OR NOT (title ILIKE ?))",
+			args: []interface{}{"ACTIVE", `%50\%\_off%`},
+		},
+		{
+			expr: `owner IN (@team:infra, "ops@example.com") AND owner != @me`,
+			sql:  "(owner IN ? AND (owner IS NULL OR owner NOT IN ?))",
+			args: []interface{}{[]string{"u2", "u3", "ops@example.com"}, []string{"u1", "me@example.com"}},
+		},
+		{
+			expr: `owner = @team:empty`,
+			sql:  "1 = 0",
+		},
+		{
+			expr: `custom.unit = "EU" AND custom.ok = true`,
+			sql:  "(custom_fields ->> ? = ? AND custom_fields ->> ? = ?)",
+			args: []interface{}{"unit", "EU", "ok", "true"},
+		},
+		{
+			expr: `tags HAS ANY ("a", "b") OR tags HAS ALL ("c") OR tags IS EMPTY`,
+			sql:  "((tags && ? OR tags @> ?) OR (tags IS NULL OR cardinality(tags) = 0))",
+			args: []interface{}{pq.StringArray{"a", "b"}, pq.StringArray{"c"}},
+		},
+		{
+			expr: `score NOT IN (1, 2.5) AND title IS NOT EMPTY`,
+			sql:  "((score IS NULL OR score NOT IN ?) AND NOT (title IS NULL OR title = ''))",
+			args: []interface{}{[]float64{1, 2.5}},
+		},
+		{
+			expr: `assets HAS ALL ("ERP", "CRM")`,
+			sql: "(EXISTS (SELECT 1 FROM risk_assets ra JOIN assets a ON a.id = ra.asset_id WHERE ra.risk_id = risks.id AND (lower(a.name) IN ?))" +
+				" AND EXISTS (SELECT 1 FROM risk_assets ra JOIN assets a ON a.id = ra.asset_id WHERE ra.risk_id = risks.id AND (lower(a.name) IN ?)))",
+			args: []interface{}{[]string{"erp"}, []string{"crm"}},
+		},
+	}
+
+	for _, tc := range cases {
+		cond := compileTest(t, tc.expr)
+		assert.Equal(t, tc.sql, cond.SQL, tc.expr)
+		assert.Equal(t, tc.args, cond.Args, tc.expr)
+	}
+}
+
+func TestCompileDates(t *testing.T) {
+	day := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	cond := compileTest(t, `created_at = "2026-01-31"`)
+	assert.Equal(t, "(created_at >= ? AND created_at < ?)", cond.SQL)
+	assert.Equal(t, []interface{}{day, day.AddDate(0, 0, 1)}, cond.Args)
+
+	cond = compileTest(t, `created_at <= "2026-01-31"`)
+	assert.Equal(t, "created_at < ?", cond.SQL, "a day bound includes the whole day")
+
+	cond = compileTest(t, `created_at >= @today-30d AND created_at < @now`)
+	assert.Equal(t, []interface{}{time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC), testNow}, cond.Args)
+
+	cond = compileTest(t, `created_at > "2026-01-31T08:00:00Z"`)
+	assert.Equal(t, "created_at > ?", cond.SQL)
+}
+
+func TestCompileBlankMatchesEverything(t *testing.T) {
+	cond := compileTest(t, "   ")
+	assert.Empty(t, cond.SQL)
+	assert.Empty(t, cond.Args)
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := map[string]int{
+		`score >=`:                  8,
+		`score >= 12 AND`:           15,
+		`(score > 1`:                10,
+		`title = "unterminated`:     8,
+		`password = "x"`:            0,
+		`status = CLOSED`:           9,
+		`score CONTAINS "1"`:        0,
+		`tags = "GDPR"`:             0,
+		`score > "high"`:            8,
+		`created_at > @yesterday`:   13,
+		`owner = @team:unknown`:     8,
+		`title IN ("a" "b")`:        14,
+		`score ! 1`:                 6,
+		`title = "a" OR OR`:         15,
+		`custom.ok = maybe`:         12,
+		`score > 1 title = "x"`:     10,
+		`tags HAS ANY "GDPR"`:       13,
+		`owner NOT "x"`:             10,
+		`created_at IN ("2026-13")`: 15,
+	}
+
+	for expr, pos := range cases {
+		_, err := CompileString(expr, testSchema, Options{
+			Resolve: func(string) ([]string, error) { return nil, fmt.Errorf("unknown team") },
+		})
+		var ferr *Error
+		if assert.True(t, errors.As(err, &ferr), "%s: %v", expr, err) {
+			assert.Equal(t, pos, ferr.Pos, "%s: %s", expr, ferr.Msg)
+		}
+	}
+}
+
+func TestIdentityMacrosNeedResolver(t *testing.T) {
+	_, err := CompileString(`owner = @me`, testSchema, Options{})
+	assert.ErrorContains(t, err, "not available")
+}
+
+func TestParseLimits(t *testing.T) {
+	deep := ""
+	for i := 0; i < MaxDepth+2; i++ {
+		deep += "("
+	}
+	_, err := Parse(deep + "score > 1")
+	assert.ErrorContains(t, err, "nested")
+
+	long := make([]byte, MaxExpressionLength+1)
+	_, err = Parse(string(long))
+	assert.ErrorContains(t, err, "longer")
+}
+
+func TestQuoteRoundTrips(t *testing.T) {
+	for _, s := range []string{`plain`, `with "quotes"`, `back\slash`, "tab\tand ünïcode"} {
+		cond := compileTest(t, "title = "+Quote(s))
+		assert.Equal(t, []interface{}{s}, cond.Args)
+	}
+
+	cond := compileTest(t, `title = 'it\'s'`)
+	assert.Equal(t, []interface{}{"it's"}, cond.Args)
+}
+
+func TestAnd(t *testing.T) {
+	assert.Equal(t, "", And("", " "))
+	assert.Equal(t, "a = 1", And("a = 1", ""))
+	assert.Equal(t, "(a = 1 OR b = 2) AND (c = 3)", And("a = 1 OR b = 2", "c = 3"))
+}
+
+func TestSchemaFieldsListOperators(t *testing.T) {
+	fields := testSchema.Fields()
+	require.NotEmpty(t, fields)
+	assert.Equal(t, "assets", fields[0].Name)
+	assert.Contains(t, fields[0].Operators, OpHasAll)
+
+	f, ok := testSchema.Field("SCORE")
+	require.True(t, ok)
+	assert.Equal(t, FieldNumber, f.Type)
+}
@@ -0,0 +1,536 @@
+// Package filter implements the OpenRisk filter language: boolean expressions
+// over record fields such as
+//
+//	score >= 12 AND tags HAS "GDPR" AND owner IN (@team:infra) AND custom.business_unit = "EU"
+//
+// Expressions are parsed into a syntax tree and compiled against a Schema into
+// a parameterized SQL condition. Field names and operators are checked against
+// the schema; values are always bound as query arguments.
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Limits protecting the database from pathological expressions
+const (
+	MaxExpressionLength = 4096
+	MaxDepth            = 32
+	MaxListValues       = 500
+)
+
+// Operators of the language
+const (
+	OpEq         = "="
+	OpNe         = "!="
+	OpGt         = ">"
+	OpGe         = ">="
+	OpLt         = "<"
+	OpLe         = "<="
+	OpIn         = "IN"
+	OpNotIn      = "NOT IN"
+	OpHas        = "HAS"
+	OpHasAny     = "HAS ANY"
+	OpHasAll     = "HAS ALL"
+	OpContains   = "CONTAINS"
+	OpIsEmpty    = "IS EMPTY"
+	OpIsNotEmpty = "IS NOT EMPTY"
+)
+
+// Error is a parse or validation error at a byte offset of the expression
+type Error struct {
+	Pos int    `json:"position"`
+	Msg string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter error at position %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Node is a node of the syntax tree
+type Node interface {
+	node()
+}
+
+// Logical combines two expressions with AND or OR
+type Logical struct {
+	Op          string
+	Left, Right Node
+}
+
+// Not negates an expression
+type Not struct {
+	Expr Node
+}
+
+// Comparison tests a field: "score >= 12", "tags HAS ANY ("a", "b")", "owner IS EMPTY"
+type Comparison struct {
+	Field  string
+	Op     string
+	Values []Value
+	Pos    int
+}
+
+func (*Logical) node()    {}
+func (*Not) node()        {}
+func (*Comparison) node() {}
+
+// ValueKind tells how a literal was written
+type ValueKind int
+
+const (
+	ValueString ValueKind = iota // "quoted" or 'quoted'
+	ValueNumber                  // 12, -1.5
+	ValueWord                    // Bare word: ACTIVE, ISO27001, true
+	ValueMacro                   // @me, @team:infra, @today-7d
+)
+
+// Value is a literal of a comparison
+type Value struct {
+	Kind ValueKind
+	Text string // Unquoted text; macro name without the "@"
+	Pos  int
+}
+
+// Quote returns s as a string literal of the language
+func Quote(s string) string {
+	return strconv.Quote(s)
+}
+
+// And joins expressions with AND, skipping blank ones
+func And(exprs ...string) string {
+	var parts []string
+	for _, e := range exprs {
+		if e = strings.TrimSpace(e); e != "" {
+			parts = append(parts, e)
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	for i, p := range parts {
+		parts[i] = "(" + p + ")"
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// Parse parses an expression. A blank expression yields a nil node (match everything).
+func Parse(input string) (Node, error) {
+	if len(input) > MaxExpressionLength {
+		return nil, errorAt(MaxExpressionLength, "expression is longer than %d characters", MaxExpressionLength)
+	}
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, nil
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorAt(t.pos, "unexpected %q", t.text)
+	}
+	return n, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokMacro
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Bare words are ASCII; anything else has to be quoted
+func isIdentStart(r byte) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isIdentPart(r byte) bool {
+	return isIdentStart(r) || r == '.' || (r >= '0' && r <= '9')
+}
+
+func isDigit(r byte) bool {
+	return r >= '0' && r <= '9'
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			start := i
+			op := string(c)
+			if i+1 < len(input) && (input[i+1] == '=' || (c == '<' && input[i+1] == '>')) {
+				op += string(input[i+1])
+			}
+			i += len(op)
+			switch op {
+			case "==":
+				op = OpEq
+			case "<>":
+				op = OpNe
+			case "!":
+				return nil, errorAt(start, "unexpected \"!\" (use != or NOT)")
+			}
+			tokens = append(tokens, token{tokOp, op, start})
+
+		case c == '"' || c == '\'':
+			text, end, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i = end
+
+		case isDigit(c) || (c == '-' && i+1 < len(input) && isDigit(input[i+1])):
+			start := i
+			i++
+			for i < len(input) && (isDigit(input[i]) || input[i] == '.') {
+				i++
+			}
+			if _, err := strconv.ParseFloat(input[start:i], 64); err != nil {
+				return nil, errorAt(start, "invalid number %q", input[start:i])
+			}
+			tokens = append(tokens, token{tokNumber, input[start:i], start})
+
+		case c == '@':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(input) {
+				r := input[i]
+				if isIdentPart(r) || r == ':' || r == '+' || r == '-' {
+					b.WriteByte(r)
+					i++
+					continue
+				}
+				// Quoted macro arguments: @team:"Blue Team"
+				if (r == '"' || r == '\'') && strings.HasSuffix(b.String(), ":") {
+					text, end, err := lexString(input, i)
+					if err != nil {
+						return nil, err
+					}
+					b.WriteString(text)
+					i = end
+				}
+				break
+			}
+			if b.Len() == 0 {
+				return nil, errorAt(start, "expected a macro name after \"@\"")
+			}
+			tokens = append(tokens, token{tokMacro, b.String(), start})
+
+		case isIdentStart(c):
+			start := i
+			for i < len(input) && isIdentPart(input[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, input[start:i], start})
+
+		default:
+			return nil, errorAt(i, "unexpected character %q", c)
+		}
+	}
+
+	return append(tokens, token{tokEOF, "end of expression", len(input)}), nil
+}
+
+// lexString reads a quoted string starting at input[start]; backslash escapes
+// follow Go syntax in double quotes, \' and \\ in single quotes
+func lexString(input string, start int) (string, int, error) {
+	quote := input[start]
+	i := start + 1
+	for i < len(input) {
+		switch input[i] {
+		case '\\':
+			i += 2
+			continue
+		case quote:
+			raw := input[start : i+1]
+			if quote == '"' {
+				text, err := strconv.Unquote(raw)
+				if err != nil {
+					return "", 0, errorAt(start, "invalid string %s", raw)
+				}
+				return text, i + 1, nil
+			}
+			text := strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(raw[1 : len(raw)-1])
+			return text, i + 1, nil
+		}
+		i++
+	}
+	return "", 0, errorAt(start, "unterminated string")
+}
+
+func isKeyword(word string) bool {
+	switch strings.ToUpper(word) {
+	case "AND", "OR", "NOT", "IN", "HAS", "IS", "CONTAINS":
+		return true
+	}
+	return false
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword (case-insensitive)
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) parseOr(depth int) (Node, error) {
+	if depth > MaxDepth {
+		return nil, errorAt(p.peek().pos, "expression is nested more than %d levels deep", MaxDepth)
+	}
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (Node, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		p.next()
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot(depth int) (Node, error) {
+	if p.keyword("NOT") {
+		p.next()
+		if depth+1 > MaxDepth {
+			return nil, errorAt(p.peek().pos, "expression is nested more than %d levels deep", MaxDepth)
+		}
+		expr, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (Node, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		n, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, errorAt(t.pos, "expected \")\", found %q", t.text)
+		}
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.kind != tokIdent || isKeyword(field.text) {
+		return nil, errorAt(field.pos, "expected a field name, found %q", field.text)
+	}
+	cmp := &Comparison{Field: field.text, Pos: field.pos}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokOp:
+		p.next()
+		cmp.Op = t.text
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		cmp.Values = []Value{v}
+
+	case p.keyword("IN"):
+		p.next()
+		cmp.Op = OpIn
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		cmp.Values = values
+
+	case p.keyword("NOT"):
+		p.next()
+		if !p.keyword("IN") {
+			return nil, errorAt(p.peek().pos, "expected IN after NOT")
+		}
+		p.next()
+		cmp.Op = OpNotIn
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		cmp.Values = values
+
+	case p.keyword("HAS"):
+		p.next()
+		switch {
+		case p.keyword("ANY"), p.keyword("ALL"):
+			cmp.Op = OpHas + " " + strings.ToUpper(p.next().text)
+			values, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			cmp.Values = values
+		default:
+			cmp.Op = OpHas
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			cmp.Values = []Value{v}
+		}
+
+	case p.keyword("CONTAINS"):
+		p.next()
+		cmp.Op = OpContains
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		cmp.Values = []Value{v}
+
+	case p.keyword("IS"):
+		p.next()
+		cmp.Op = OpIsEmpty
+		if p.keyword("NOT") {
+			p.next()
+			cmp.Op = OpIsNotEmpty
+		}
+		if !p.keyword("EMPTY") && !p.keyword("NULL") {
+			return nil, errorAt(p.peek().pos, "expected EMPTY or NULL after IS")
+		}
+		p.next()
+
+	default:
+		return nil, errorAt(t.pos, "expected an operator after %q, found %q", field.text, t.text)
+	}
+
+	return cmp, nil
+}
+
+func (p *parser) parseValue() (Value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return Value{Kind: ValueString, Text: t.text, Pos: t.pos}, nil
+	case tokNumber:
+		return Value{Kind: ValueNumber, Text: t.text, Pos: t.pos}, nil
+	case tokMacro:
+		return Value{Kind: ValueMacro, Text: t.text, Pos: t.pos}, nil
+	case tokIdent:
+		if isKeyword(t.text) {
+			return Value{}, errorAt(t.pos, "expected a value, found keyword %s", strings.ToUpper(t.text))
+		}
+		return Value{Kind: ValueWord, Text: t.text, Pos: t.pos}, nil
+	}
+	return Value{}, errorAt(t.pos, "expected a value, found %q", t.text)
+}
+
+// parseList reads "(v1, v2, ...)"; a lone macro may stand for a list: IN @team:infra
+func (p *parser) parseList() ([]Value, error) {
+	if p.peek().kind == tokMacro {
+		v, _ := p.parseValue()
+		return []Value{v}, nil
+	}
+
+	open := p.next()
+	if open.kind != tokLParen {
+		return nil, errorAt(open.pos, "expected \"(\" to start a list, found %q", open.text)
+	}
+
+	var values []Value
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if len(values) > MaxListValues {
+			return nil, errorAt(v.Pos, "lists are limited to %d values", MaxListValues)
+		}
+
+		t := p.next()
+		if t.kind == tokRParen {
+			return values, nil
+		}
+		if t.kind != tokComma {
+			return nil, errorAt(t.pos, "expected \",\" or \")\" in list, found %q", t.text)
+		}
+	}
+}
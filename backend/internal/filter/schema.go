@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldType decides which operators and values a field accepts
+type FieldType string
+
+const (
+	FieldString   FieldType = "string"
+	FieldNumber   FieldType = "number"
+	FieldDate     FieldType = "date"
+	FieldEnum     FieldType = "enum"
+	FieldBool     FieldType = "bool" // Stored as 'true'/'false' text (JSON custom fields)
+	FieldUUID     FieldType = "uuid"
+	FieldArray    FieldType = "array"    // Postgres text[] column
+	FieldRelation FieldType = "relation" // Related records matched through an EXISTS subquery
+)
+
+// Relation describes a to-many relation filtered with HAS, HAS ANY, HAS ALL and IS EMPTY
+type Relation struct {
+	// Subquery selecting the related rows of the current record, e.g.
+	// "SELECT 1 FROM risk_assets ra JOIN assets a ON a.id = ra.asset_id WHERE ra.risk_id = risks.id"
+	Exists string
+	// Columns of the related rows a value may match (case-insensitively)
+	Match []string
+}
+
+// Field is a filterable field. Expr and Relation are trusted SQL written by the
+// schema author; user input only ever reaches the query as bound arguments.
+type Field struct {
+	Name        string    `json:"name"`
+	Type        FieldType `json:"type"`
+	Description string    `json:"description,omitempty"`
+	Values      []string  `json:"values,omitempty"`    // Allowed values of enum fields
+	Macros      []string  `json:"macros,omitempty"`    // Macros the field accepts, for autocompletion
+	Operators   []string  `json:"operators,omitempty"` // Filled in by Schema.Fields
+
+	Expr     string        `json:"-"` // SQL expression of the field, "?" placeholders bound to ExprArgs
+	ExprArgs []interface{} `json:"-"`
+	Relation *Relation     `json:"-"`
+}
+
+// Schema is the set of fields an expression may reference
+type Schema struct {
+	fields map[string]Field
+}
+
+// NewSchema creates a schema from fields
+func NewSchema(fields ...Field) *Schema {
+	s := &Schema{fields: make(map[string]Field, len(fields))}
+	for _, f := range fields {
+		s.Add(f)
+	}
+	return s
+}
+
+// Add adds or replaces a field; names are case-insensitive
+func (s *Schema) Add(f Field) {
+	s.fields[strings.ToLower(f.Name)] = f
+}
+
+// Field looks a field up by name
+func (s *Schema) Field(name string) (Field, bool) {
+	f, ok := s.fields[strings.ToLower(name)]
+	return f, ok
+}
+
+// Fields lists the fields by name with the operators they support
+func (s *Schema) Fields() []Field {
+	fields := make([]Field, 0, len(s.fields))
+	for _, f := range s.fields {
+		f.Operators = operatorsFor(f.Type)
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+func operatorsFor(t FieldType) []string {
+	switch t {
+	case FieldString:
+		return []string{OpEq, OpNe, OpIn, OpNotIn, OpContains, OpIsEmpty, OpIsNotEmpty}
+	case FieldEnum, FieldUUID:
+		return []string{OpEq, OpNe, OpIn, OpNotIn, OpIsEmpty, OpIsNotEmpty}
+	case FieldNumber, FieldDate:
+		return []string{OpEq, OpNe, OpGt, OpGe, OpLt, OpLe, OpIn, OpNotIn, OpIsEmpty, OpIsNotEmpty}
+	case FieldBool:
+		return []string{OpEq, OpNe, OpIsEmpty, OpIsNotEmpty}
+	case FieldArray, FieldRelation:
+		return []string{OpHas, OpHasAny, OpHasAll, OpIsEmpty, OpIsNotEmpty}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/opendefender/openrisk/internal/middleware"
 	"github.com/opendefender/openrisk/internal/services"
 )
@@ -23,17 +24,21 @@ func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *Analytics
 }
 
 // GetRiskMetrics retrieves aggregated risk metrics
-// GET /api/v1/analytics/risks/metrics
+// GET /api/v1/analytics/risks/metrics?filter=<expression>
 func (h *AnalyticsHandler) GetRiskMetrics(c *fiber.Ctx) error {
 	// Check permission
-	userID := c.Locals("userID").(string)
-	if userID == "" {
+	if _, userID := requestActor(c); userID == uuid.Nil {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"error": "unauthorized",
 		})
 	}
 
-	metrics, err := h.analyticsService.GetRiskMetrics(c.Context())
+	riskFilter, err := compileRiskFilter(c, c.Query("filter"))
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
+	metrics, err := h.analyticsService.GetRiskMetrics(c.Context(), riskFilter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to retrieve risk metrics",
@@ -44,16 +49,20 @@ func (h *AnalyticsHandler) GetRiskMetrics(c *fiber.Ctx) error {
 }
 
 // GetRiskTrends retrieves risk trends over time
-// GET /api/v1/analytics/risks/trends?days=30
+// GET /api/v1/analytics/risks/trends?days=30&filter=<expression>
 func (h *AnalyticsHandler) GetRiskTrends(c *fiber.Ctx) error {
 	// Check permission
-	userID := c.Locals("userID").(string)
-	if userID == "" {
+	if _, userID := requestActor(c); userID == uuid.Nil {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"error": "unauthorized",
 		})
 	}
 
+	riskFilter, err := compileRiskFilter(c, c.Query("filter"))
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
 	// Parse days parameter
 	days := 30
 	if daysStr := c.Query("days"); daysStr != "" {
@@ -62,7 +71,7 @@ func (h *AnalyticsHandler) GetRiskTrends(c *fiber.Ctx) error {
 		}
 	}
 
-	trends, err := h.analyticsService.GetRiskTrends(c.Context(), days)
+	trends, err := h.analyticsService.GetRiskTrends(c.Context(), days, riskFilter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to retrieve risk trends",
@@ -79,14 +88,18 @@ func (h *AnalyticsHandler) GetRiskTrends(c *fiber.Ctx) error {
 // GET /api/v1/analytics/mitigations/metrics
 func (h *AnalyticsHandler) GetMitigationMetrics(c *fiber.Ctx) error {
 	// Check permission
-	userID := c.Locals("userID").(string)
-	if userID == "" {
+	if _, userID := requestActor(c); userID == uuid.Nil {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"error": "unauthorized",
 		})
 	}
 
-	metrics, err := h.analyticsService.GetMitigationMetrics(c.Context())
+	riskFilter, err := compileRiskFilter(c, c.Query("filter"))
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
+	metrics, err := h.analyticsService.GetMitigationMetrics(c.Context(), riskFilter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to retrieve mitigation metrics",
@@ -100,14 +113,18 @@ func (h *AnalyticsHandler) GetMitigationMetrics(c *fiber.Ctx) error {
 // GET /api/v1/analytics/frameworks
 func (h *AnalyticsHandler) GetFrameworkAnalytics(c *fiber.Ctx) error {
 	// Check permission
-	userID := c.Locals("userID").(string)
-	if userID == "" {
+	if _, userID := requestActor(c); userID == uuid.Nil {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"error": "unauthorized",
 		})
 	}
 
-	analytics, err := h.analyticsService.GetFrameworkAnalytics(c.Context())
+	riskFilter, err := compileRiskFilter(c, c.Query("filter"))
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
+	analytics, err := h.analyticsService.GetFrameworkAnalytics(c.Context(), riskFilter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to retrieve framework analytics",
@@ -121,14 +138,18 @@ func (h *AnalyticsHandler) GetFrameworkAnalytics(c *fiber.Ctx) error {
 // GET /api/v1/analytics/dashboard
 func (h *AnalyticsHandler) GetDashboardSnapshot(c *fiber.Ctx) error {
 	// Check permission
-	userID := c.Locals("userID").(string)
-	if userID == "" {
+	if _, userID := requestActor(c); userID == uuid.Nil {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"error": "unauthorized",
 		})
 	}
 
-	snapshot, err := h.analyticsService.GetDashboardSnapshot(c.Context())
+	riskFilter, err := compileRiskFilter(c, c.Query("filter"))
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
+	snapshot, err := h.analyticsService.GetDashboardSnapshot(c.Context(), riskFilter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to retrieve dashboard snapshot",
@@ -138,20 +159,71 @@ func (h *AnalyticsHandler) GetDashboardSnapshot(c *fiber.Ctx) error {
 	return c.Status(http.StatusOK).JSON(snapshot)
 }
 
+// GetLossAnalytics compares, per risk category, the expected loss of the
+// risks with the loss events of the incidents that realized them
+// GET /api/v1/analytics/losses?from=2026-01-01&to=2026-12-31&filter=<expression>
+func (h *AnalyticsHandler) GetLossAnalytics(c *fiber.Ctx) error {
+	// Check permission
+	if _, userID := requestActor(c); userID == uuid.Nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	riskFilter, err := compileRiskFilter(c, c.Query("filter"))
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
+	// The period covers whole days, the last year by default
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if raw := c.Query("to"); raw != "" {
+		day, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "to must be a date (YYYY-MM-DD)"})
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+	from := to.AddDate(-1, 0, 0)
+	if raw := c.Query("from"); raw != "" {
+		day, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "from must be a date (YYYY-MM-DD)"})
+		}
+		from = day
+	}
+	if !from.Before(to) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "from must be before to"})
+	}
+
+	analytics, err := h.analyticsService.GetLossAnalytics(c.Context(), from, to, riskFilter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve loss analytics",
+		})
+	}
+
+	return c.Status(http.StatusOK).JSON(analytics)
+}
+
 // GetExportData exports analytics data in various formats
 // GET /api/v1/analytics/export?format=json|csv|pdf
 func (h *AnalyticsHandler) GetExportData(c *fiber.Ctx) error {
 	// Check permission
-	userID := c.Locals("userID").(string)
-	if userID == "" {
+	if _, userID := requestActor(c); userID == uuid.Nil {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"error": "unauthorized",
 		})
 	}
 
+	riskFilter, err := compileRiskFilter(c, c.Query("filter"))
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
 	format := c.Query("format", "json")
 
-	snapshot, err := h.analyticsService.GetDashboardSnapshot(c.Context())
+	snapshot, err := h.analyticsService.GetDashboardSnapshot(c.Context(), riskFilter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to retrieve data for export",
@@ -223,5 +295,6 @@ func RegisterAnalyticsRoutes(app *fiber.App, handler *AnalyticsHandler) {
 	protected.Get("/mitigations/metrics", handler.GetMitigationMetrics)
 	protected.Get("/frameworks", handler.GetFrameworkAnalytics)
 	protected.Get("/dashboard", handler.GetDashboardSnapshot)
+	protected.Get("/losses", handler.GetLossAnalytics)
 	protected.Get("/export", handler.GetExportData)
 }
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
+)
+
+// ApprovalWorkflowHandler exposes approval chain configuration, approval requests, votes and delegations
+type ApprovalWorkflowHandler struct {
+	approvalService *services.ApprovalWorkflowService
+}
+
+// NewApprovalWorkflowHandler creates a new approval workflow handler
+func NewApprovalWorkflowHandler(approvalService *services.ApprovalWorkflowService) *ApprovalWorkflowHandler {
+	return &ApprovalWorkflowHandler{
+		approvalService: approvalService,
+	}
+}
+
+// CreateWorkflow - Configure an approval chain
+// POST /api/v1/risk-management/approval-workflows
+func (h *ApprovalWorkflowHandler) CreateWorkflow(c *fiber.Ctx) error {
+	type WorkflowInput struct {
+		Name         string                          `json:"name" validate:"required"`
+		Description  string                          `json:"description"`
+		EntityType   string                          `json:"entity_type" validate:"required,oneof=RISK_DECISION TREATMENT_PLAN RISK_EXCEPTION"`
+		DecisionType string                          `json:"decision_type"`
+		MinRiskLevel string                          `json:"min_risk_level"`
+		Priority     int                             `json:"priority"`
+		Steps        []domain.ApprovalStepDefinition `json:"steps" validate:"required,min=1"`
+	}
+
+	input := new(WorkflowInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	workflow, err := h.approvalService.CreateWorkflow(tenantID, userID, &domain.ApprovalWorkflow{
+		Name:         input.Name,
+		Description:  input.Description,
+		EntityType:   domain.ApprovalEntityType(input.EntityType),
+		DecisionType: input.DecisionType,
+		MinRiskLevel: input.MinRiskLevel,
+		Priority:     input.Priority,
+	}, input.Steps)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to create approval workflow", "details": err.Error()})
+	}
+
+	return c.Status(201).JSON(workflow)
+}
+
+// ListWorkflows - List approval chains
+// GET /api/v1/risk-management/approval-workflows?entity_type=RISK_DECISION
+func (h *ApprovalWorkflowHandler) ListWorkflows(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	workflows, err := h.approvalService.ListWorkflows(tenantID, c.Query("entity_type"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list approval workflows", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(workflows)
+}
+
+// GetWorkflow - Get an approval chain
+// GET /api/v1/risk-management/approval-workflows/:id
+func (h *ApprovalWorkflowHandler) GetWorkflow(c *fiber.Ctx) error {
+	workflowID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid workflow ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+
+	workflow, err := h.approvalService.GetWorkflow(tenantID, workflowID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Approval workflow not found"})
+	}
+
+	return c.Status(200).JSON(workflow)
+}
+
+// UpdateWorkflow - Replace the steps, priority or active flag of an approval chain
+// PATCH /api/v1/risk-management/approval-workflows/:id
+func (h *ApprovalWorkflowHandler) UpdateWorkflow(c *fiber.Ctx) error {
+	type UpdateWorkflowInput struct {
+		Steps    []domain.ApprovalStepDefinition `json:"steps"`
+		IsActive *bool                           `json:"is_active"`
+		Priority *int                            `json:"priority"`
+	}
+
+	workflowID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid workflow ID"})
+	}
+
+	input := new(UpdateWorkflowInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	tenantID, _ := requestActor(c)
+
+	workflow, err := h.approvalService.UpdateWorkflow(tenantID, workflowID, input.Steps, input.IsActive, input.Priority)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to update approval workflow", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(workflow)
+}
+
+// DeleteWorkflow - Delete an approval chain
+// DELETE /api/v1/risk-management/approval-workflows/:id
+func (h *ApprovalWorkflowHandler) DeleteWorkflow(c *fiber.Ctx) error {
+	workflowID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid workflow ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+
+	if err := h.approvalService.DeleteWorkflow(tenantID, workflowID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Approval workflow not found"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// ListRequests - List approval requests
+// GET /api/v1/risk-management/approvals?status=PENDING&limit=20&offset=0
+func (h *ApprovalWorkflowHandler) ListRequests(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	requests, total, err := h.approvalService.ListRequests(tenantID, c.Query("status"), limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list approval requests", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":   requests,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetRequest - Get an approval request with its tasks and votes
+// GET /api/v1/risk-management/approvals/:id
+func (h *ApprovalWorkflowHandler) GetRequest(c *fiber.Ctx) error {
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid approval request ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+
+	request, err := h.approvalService.GetRequest(tenantID, requestID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Approval request not found"})
+	}
+
+	return c.Status(200).JSON(request)
+}
+
+// CancelRequest - Withdraw an open approval request
+// POST /api/v1/risk-management/approvals/:id/cancel
+func (h *ApprovalWorkflowHandler) CancelRequest(c *fiber.Ctx) error {
+	type CancelInput struct {
+		Reason string `json:"reason"`
+	}
+
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid approval request ID"})
+	}
+
+	input := new(CancelInput)
+	_ = c.BodyParser(input)
+
+	tenantID, userID := requestActor(c)
+
+	if err := h.approvalService.CancelRequest(tenantID, requestID, userID, isAdmin(c), input.Reason); err != nil {
+		if errors.Is(err, services.ErrNotRequester) {
+			return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to cancel approval request", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(fiber.Map{"message": "Approval request cancelled"})
+}
+
+// ListMyTasks - List pending approval tasks of the current user, including delegated ones
+// GET /api/v1/risk-management/approvals/mine
+func (h *ApprovalWorkflowHandler) ListMyTasks(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+
+	tasks, err := h.approvalService.ListPendingTasksForUser(tenantID, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list approval tasks", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(tasks)
+}
+
+// Vote - Approve or reject an approval task
+// POST /api/v1/risk-management/approval-tasks/:id/vote
+func (h *ApprovalWorkflowHandler) Vote(c *fiber.Ctx) error {
+	type VoteInput struct {
+		Decision string `json:"decision" validate:"required,oneof=APPROVE REJECT"`
+		Comment  string `json:"comment"`
+	}
+
+	taskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid approval task ID"})
+	}
+
+	input := new(VoteInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	request, err := h.approvalService.CastVote(tenantID, taskID, userID, domain.ApprovalVoteDecision(input.Decision), input.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotEligibleApprover):
+			return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrAlreadyVoted), errors.Is(err, services.ErrTaskNotPending):
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record vote", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(request)
+}
+
+// CreateDelegation - Delegate the current user's approvals for a period
+// POST /api/v1/risk-management/approval-delegations
+func (h *ApprovalWorkflowHandler) CreateDelegation(c *fiber.Ctx) error {
+	type DelegationInput struct {
+		DelegateID string    `json:"delegate_id" validate:"required,uuid4"`
+		ValidFrom  time.Time `json:"valid_from"`
+		ValidUntil time.Time `json:"valid_until" validate:"required"`
+		Reason     string    `json:"reason"`
+	}
+
+	input := new(DelegationInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	delegateID, _ := uuid.Parse(input.DelegateID)
+	if input.ValidFrom.IsZero() {
+		input.ValidFrom = time.Now()
+	}
+
+	delegation, err := h.approvalService.CreateDelegation(tenantID, userID, delegateID, input.ValidFrom, input.ValidUntil, input.Reason)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to create delegation", "details": err.Error()})
+	}
+
+	return c.Status(201).JSON(delegation)
+}
+
+// ListDelegations - List delegations given or received by the current user
+// GET /api/v1/risk-management/approval-delegations
+func (h *ApprovalWorkflowHandler) ListDelegations(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+
+	delegations, err := h.approvalService.ListDelegations(tenantID, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list delegations", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(delegations)
+}
+
+// RevokeDelegation - End a delegation early
+// DELETE /api/v1/risk-management/approval-delegations/:id
+func (h *ApprovalWorkflowHandler) RevokeDelegation(c *fiber.Ctx) error {
+	delegationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid delegation ID"})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	if err := h.approvalService.RevokeDelegation(tenantID, delegationID, userID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Delegation not found"})
+	}
+
+	return c.SendStatus(204)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/adapters/inventory"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// AssetDiscoveryHandler exposes the ingestion of cloud and CMDB inventories
+type AssetDiscoveryHandler struct {
+	discoveryService *services.AssetDiscoveryService
+}
+
+// NewAssetDiscoveryHandler creates a new asset discovery handler
+func NewAssetDiscoveryHandler(discoveryService *services.AssetDiscoveryService) *AssetDiscoveryHandler {
+	return &AssetDiscoveryHandler{
+		discoveryService: discoveryService,
+	}
+}
+
+// Ingest - Reconcile the assets of a source with an inventory export, sent as
+// the multipart field "file" or as the request body. Formats: aws-config,
+// azure-resource-graph, gcp-asset-inventory, servicenow-cmdb. retire_missing
+// retires the assets of the source missing from the export, for exports that
+// cover the whole source.
+// POST /api/v1/asset-discovery/:format?dry_run=true&retire_missing=true
+func (h *AssetDiscoveryHandler) Ingest(c *fiber.Ctx) error {
+	format, err := inventory.ParseFormat(c.Params("format"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error(), "formats": inventory.Formats})
+	}
+
+	var body io.Reader
+	fileName := ""
+	if header, err := c.FormFile("file"); err == nil {
+		file, err := header.Open()
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Could not read uploaded file"})
+		}
+		defer file.Close()
+		body, fileName = file, header.Filename
+	} else {
+		if len(c.Body()) == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "Missing inventory (multipart field \"file\" or request body)"})
+		}
+		body = bytes.NewReader(c.Body())
+	}
+
+	assets, err := inventory.Parse(format, body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	opts := services.DiscoveryOptions{
+		DryRun:        c.QueryBool("dry_run", false),
+		RetireMissing: c.QueryBool("retire_missing", false),
+	}
+	run, err := h.discoveryService.Reconcile(c.UserContext(), format.Source(), string(format), fileName, assets, opts)
+	if err != nil {
+		return assetDiscoveryError(c, err)
+	}
+
+	return c.Status(200).JSON(run)
+}
+
+// SyncCMDB - Fetch the configured ServiceNow CMDB table and reconcile its
+// assets; configuration items missing from the table are retired
+// POST /api/v1/asset-discovery/servicenow/sync?dry_run=true
+func (h *AssetDiscoveryHandler) SyncCMDB(c *fiber.Ctx) error {
+	run, err := h.discoveryService.SyncCMDB(c.UserContext(), c.QueryBool("dry_run", false))
+	if err != nil {
+		if run != nil {
+			return c.Status(502).JSON(fiber.Map{"error": "CMDB sync failed", "details": err.Error(), "run": run})
+		}
+		return assetDiscoveryError(c, err)
+	}
+
+	return c.Status(200).JSON(run)
+}
+
+// ListRuns - Asset discovery runs, most recent first
+// GET /api/v1/asset-discovery/runs?source=&limit=50&offset=0
+func (h *AssetDiscoveryHandler) ListRuns(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	runs, total, err := h.discoveryService.ListRuns(c.Query("source"), limit, offset)
+	if err != nil {
+		return assetDiscoveryError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"items":  runs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetRun - An asset discovery run with its changes
+// GET /api/v1/asset-discovery/runs/:id
+func (h *AssetDiscoveryHandler) GetRun(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid run ID"})
+	}
+
+	run, err := h.discoveryService.GetRun(id)
+	if err != nil {
+		return assetDiscoveryError(c, err)
+	}
+
+	return c.Status(200).JSON(run)
+}
+
+func assetDiscoveryError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrDiscoveryRunNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Asset discovery run not found"})
+	case errors.Is(err, services.ErrInventoryNotSupported):
+		return c.Status(503).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Asset discovery failed", "details": err.Error()})
+	}
+}
@@ -1,30 +1,348 @@
 package handlers
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/opendefender/openrisk/database"
-	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/services"
 )
 
-// GetAssets : Liste avec pagination optionnelle (simplifiée ici)
-func GetAssets(c *fiber.Ctx) error {
-	var assets []domain.Asset
-	// On précharge les risques pour afficher le nombre de risques par asset
-	if err := database.DB.Preload("Risks").Find(&assets).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch assets"})
+// AssetHandler serves the asset inventory: assets and their hierarchy,
+// relationships between assets, business services and the blast radius of
+// assets and risks
+type AssetHandler struct {
+	assetService  *services.AssetService
+	impactService *services.ImpactService
+}
+
+// NewAssetHandler creates a new asset handler
+func NewAssetHandler(assetService *services.AssetService, impactService *services.ImpactService) *AssetHandler {
+	return &AssetHandler{assetService: assetService, impactService: impactService}
+}
+
+// GetAssets - List assets with their linked risks, filtered and paginated
+// GET /api/v1/assets?q=&type=&criticality=&owner=&source=&tag=&parent_id=&roots=true&service_id=&retired=false&limit=50&offset=0
+func (h *AssetHandler) GetAssets(c *fiber.Ctx) error {
+	filter := services.AssetFilter{
+		Search:      c.Query("q"),
+		Type:        c.Query("type"),
+		Criticality: c.Query("criticality"),
+		Owner:       c.Query("owner"),
+		Source:      c.Query("source"),
+		Tag:         c.Query("tag"),
+		RootsOnly:   c.QueryBool("roots", false),
+		Limit:       c.QueryInt("limit", 50),
+		Offset:      c.QueryInt("offset", 0),
+	}
+	if filter.Limit < 1 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if raw := c.Query("retired"); raw != "" {
+		retired := c.QueryBool("retired", false)
+		filter.Retired = &retired
+	}
+	for param, target := range map[string]**uuid.UUID{"parent_id": &filter.ParentID, "service_id": &filter.ServiceID} {
+		if raw := c.Query(param); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "Invalid " + param})
+			}
+			*target = &id
+		}
+	}
+
+	assets, total, err := h.assetService.ListAssets(filter)
+	if err != nil {
+		return assetError(c, err)
 	}
-	return c.JSON(assets)
+	return c.Status(200).JSON(fiber.Map{
+		"items":  assets,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
 }
 
-// CreateAsset : Manuel (ou via Sync)
-func CreateAsset(c *fiber.Ctx) error {
-	asset := new(domain.Asset)
-	if err := c.BodyParser(asset); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+// GetAsset - Get an asset with its linked risks and direct children
+// GET /api/v1/assets/:id
+func (h *AssetHandler) GetAsset(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
 	}
+	asset, err := h.assetService.GetAsset(id)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(asset)
+}
 
-	if err := database.DB.Create(asset).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Could not create asset"})
+// GetAssetTree - Get an asset with its ancestors and all its descendants
+// GET /api/v1/assets/:id/tree
+func (h *AssetHandler) GetAssetTree(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	tree, err := h.assetService.GetAssetTree(id)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(tree)
+}
+
+// CreateAsset - Create an asset, manually or from a sync
+// POST /api/v1/assets
+func (h *AssetHandler) CreateAsset(c *fiber.Ctx) error {
+	var input services.AssetInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	asset, err := h.assetService.CreateAsset(c.UserContext(), input)
+	if err != nil {
+		return assetError(c, err)
 	}
 	return c.Status(201).JSON(asset)
-}
\ No newline at end of file
+}
+
+// UpdateAsset - Update the given fields of an asset; "parent_id": "" detaches
+// it from its parent
+// PATCH /api/v1/assets/:id
+func (h *AssetHandler) UpdateAsset(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	var input services.AssetInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	asset, err := h.assetService.UpdateAsset(c.UserContext(), id, input)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(asset)
+}
+
+// DeleteAsset - Soft delete ; l'asset est retiré des risques liés et reste
+// dans la corbeille jusqu'à sa restauration ou sa purge
+// DELETE /api/v1/assets/:id
+func (h *AssetHandler) DeleteAsset(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	if err := h.assetService.DeleteAsset(c.UserContext(), id); err != nil {
+		return assetError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+// GetAssetRelationships - List the relationships of an asset in both directions
+// GET /api/v1/assets/:id/relationships
+func (h *AssetHandler) GetAssetRelationships(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	relationships, err := h.assetService.ListRelationships(id)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(relationships)
+}
+
+// CreateAssetRelationship - Relate the asset to another: depends_on, hosts,
+// connects_to or processes_data_of
+// POST /api/v1/assets/:id/relationships
+func (h *AssetHandler) CreateAssetRelationship(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	var input services.AssetRelationshipInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	relationship, err := h.assetService.CreateRelationship(c.UserContext(), id, input)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(201).JSON(relationship)
+}
+
+// DeleteAssetRelationship - Remove a relationship of an asset
+// DELETE /api/v1/assets/:id/relationships/:relationshipId
+func (h *AssetHandler) DeleteAssetRelationship(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	relationshipID, err := uuid.Parse(c.Params("relationshipId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid relationship UUID"})
+	}
+	if err := h.assetService.DeleteRelationship(c.UserContext(), id, relationshipID); err != nil {
+		return assetError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+// GetAssetBlastRadius - List every asset and business service affected if
+// the asset is hit, following dependencies up to depth hops
+// GET /api/v1/assets/:id/blast-radius?depth=10
+func (h *AssetHandler) GetAssetBlastRadius(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	radius, err := h.impactService.AssetBlastRadius(id, c.QueryInt("depth", services.DefaultImpactDepth))
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(radius)
+}
+
+// GetRiskBlastRadius - List every asset and business service affected if the
+// risk materializes on its assets
+// GET /api/v1/risks/:id/blast-radius?depth=10
+func (h *AssetHandler) GetRiskBlastRadius(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	radius, err := h.impactService.RiskBlastRadius(id, c.QueryInt("depth", services.DefaultImpactDepth))
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(radius)
+}
+
+// GetBusinessServices - List business services with their aggregate risk
+// GET /api/v1/business-services?q=
+func (h *AssetHandler) GetBusinessServices(c *fiber.Ctx) error {
+	views, err := h.assetService.ListBusinessServices(c.Query("q"))
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(views)
+}
+
+// GetBusinessService - Get a business service with its assets and aggregate risk
+// GET /api/v1/business-services/:id
+func (h *AssetHandler) GetBusinessService(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	view, err := h.assetService.GetBusinessService(id)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(view)
+}
+
+// CreateBusinessService - Create a business service with its initial assets
+// POST /api/v1/business-services
+func (h *AssetHandler) CreateBusinessService(c *fiber.Ctx) error {
+	var input services.BusinessServiceInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	view, err := h.assetService.CreateBusinessService(c.UserContext(), input)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(201).JSON(view)
+}
+
+// UpdateBusinessService - Update the given fields of a business service
+// PATCH /api/v1/business-services/:id
+func (h *AssetHandler) UpdateBusinessService(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	var input services.BusinessServiceInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	view, err := h.assetService.UpdateBusinessService(c.UserContext(), id, input)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(view)
+}
+
+// DeleteBusinessService - Delete a business service; its assets are kept
+// DELETE /api/v1/business-services/:id
+func (h *AssetHandler) DeleteBusinessService(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	if err := h.assetService.DeleteBusinessService(c.UserContext(), id); err != nil {
+		return assetError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+// AddBusinessServiceAssets - Add assets to a business service
+// POST /api/v1/business-services/:id/assets
+func (h *AssetHandler) AddBusinessServiceAssets(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	var req struct {
+		AssetIDs []uuid.UUID `json:"asset_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	view, err := h.assetService.AddServiceAssets(c.UserContext(), id, req.AssetIDs)
+	if err != nil {
+		return assetError(c, err)
+	}
+	return c.Status(200).JSON(view)
+}
+
+// RemoveBusinessServiceAsset - Remove an asset from a business service
+// DELETE /api/v1/business-services/:id/assets/:assetId
+func (h *AssetHandler) RemoveBusinessServiceAsset(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+	assetID, err := uuid.Parse(c.Params("assetId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid asset UUID"})
+	}
+	if err := h.assetService.RemoveServiceAsset(c.UserContext(), id, assetID); err != nil {
+		return assetError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+func assetError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAsset), errors.Is(err, services.ErrInvalidBusinessService):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAssetNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Asset not found"})
+	case errors.Is(err, services.ErrRelationshipNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Asset relationship not found"})
+	case errors.Is(err, services.ErrRiskNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+	case errors.Is(err, services.ErrBusinessServiceNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Business service not found"})
+	case errors.Is(err, services.ErrAssetHasChildren), errors.Is(err, services.ErrRelationshipExists):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Asset operation failed", "details": err.Error()})
+	}
+}
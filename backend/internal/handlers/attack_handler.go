@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/adapters/attack"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// AttackHandler exposes the MITRE ATT&CK catalog and the coverage of its
+// techniques by risks and mitigations
+type AttackHandler struct {
+	attackService *services.AttackService
+}
+
+// NewAttackHandler creates a new ATT&CK handler
+func NewAttackHandler(attackService *services.AttackService) *AttackHandler {
+	return &AttackHandler{
+		attackService: attackService,
+	}
+}
+
+// ImportBundle - Load the ATT&CK Enterprise STIX bundle (enterprise-attack.json,
+// gzipped or not) into the catalog
+// POST /api/v1/attack/bundle
+func (h *AttackHandler) ImportBundle(c *fiber.Ctx) error {
+	body, fileName, err := uploadBody(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer body.Close()
+
+	bundle, err := attack.Read(body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	bundle.FileName = fileName
+	if _, userID := requestActor(c); userID != uuid.Nil {
+		bundle.ImportedBy = userID.String()
+	}
+
+	imported, err := h.attackService.ImportBundle(c.UserContext(), bundle.AttackBundleImport, bundle.Objects, bundle.Mitigates)
+	if err != nil {
+		return attackError(c, err)
+	}
+
+	return c.Status(200).JSON(imported)
+}
+
+// GetImports - Last loads of the ATT&CK bundle, most recent first
+// GET /api/v1/attack/imports?limit=20
+func (h *AttackHandler) GetImports(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 200 {
+		limit = 20
+	}
+
+	imports, err := h.attackService.ListImports(limit)
+	if err != nil {
+		return attackError(c, err)
+	}
+
+	return c.Status(200).JSON(imports)
+}
+
+// GetTactics - ATT&CK Enterprise tactics
+// GET /api/v1/attack/tactics
+func (h *AttackHandler) GetTactics(c *fiber.Ctx) error {
+	return h.listObjects(c, domain.AttackTactic)
+}
+
+// GetTechniques - ATT&CK techniques and sub-techniques, optionally matching
+// an ID prefix or a name and of a tactic
+// GET /api/v1/attack/techniques?q=&tactic=execution&deprecated=false&limit=100
+func (h *AttackHandler) GetTechniques(c *fiber.Ctx) error {
+	return h.listObjects(c, domain.AttackTechnique)
+}
+
+// GetMitigations - ATT&CK mitigations
+// GET /api/v1/attack/mitigations?q=&limit=100
+func (h *AttackHandler) GetMitigations(c *fiber.Ctx) error {
+	return h.listObjects(c, domain.AttackMitigation)
+}
+
+func (h *AttackHandler) listObjects(c *fiber.Ctx, kind string) error {
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	objects, err := h.attackService.ListObjects(kind, c.Query("q"), c.Query("tactic"), c.QueryBool("deprecated"), limit)
+	if err != nil {
+		return attackError(c, err)
+	}
+
+	return c.Status(200).JSON(objects)
+}
+
+// GetObject - An ATT&CK technique with its sub-techniques and mitigations, a
+// mitigation with the techniques it mitigates or a tactic with its techniques
+// GET /api/v1/attack/objects/:id
+func (h *AttackHandler) GetObject(c *fiber.Ctx) error {
+	object, err := h.attackService.GetObject(c.Params("id"))
+	if err != nil {
+		return attackError(c, err)
+	}
+
+	return c.Status(200).JSON(object)
+}
+
+// GetCoverage - Techniques linked to risks or mitigations with their open
+// risks and done or planned mitigations, most exposed first
+// GET /api/v1/threats/coverage
+func (h *AttackHandler) GetCoverage(c *fiber.Ctx) error {
+	coverage, err := h.attackService.Coverage(c.UserContext())
+	if err != nil {
+		return attackError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"techniques": coverage,
+		"total":      len(coverage),
+	})
+}
+
+// GetCoverageLayer - The coverage as an ATT&CK Navigator layer: techniques
+// colored by status and scored by their open risks
+// GET /api/v1/threats/coverage/layer
+func (h *AttackHandler) GetCoverageLayer(c *fiber.Ctx) error {
+	coverage, err := h.attackService.Coverage(c.UserContext())
+	if err != nil {
+		return attackError(c, err)
+	}
+	version := ""
+	last, err := h.attackService.LatestImport()
+	if err != nil {
+		return attackError(c, err)
+	}
+	if last != nil {
+		version = last.Version
+	}
+
+	layer := attack.NavigatorLayer(c.Query("name", "OpenRisk coverage"), version, coverage)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "openrisk-attack-layer.json"))
+	return c.Status(200).JSON(layer)
+}
+
+func attackError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrAttackObjectNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUnknownAttackID):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "ATT&CK request failed", "details": err.Error()})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// BrandingHandler manages the tenant branding used on generated documents
+type BrandingHandler struct {
+	brandingService *services.BrandingService
+}
+
+// NewBrandingHandler creates a new branding handler
+func NewBrandingHandler(brandingService *services.BrandingService) *BrandingHandler {
+	return &BrandingHandler{brandingService: brandingService}
+}
+
+// GetBranding - Get the current tenant's branding
+// GET /api/v1/branding
+func (h *BrandingHandler) GetBranding(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	branding, _, err := h.brandingService.GetBranding(tenantID)
+	if err != nil {
+		return brandingError(c, err)
+	}
+	return c.JSON(branding)
+}
+
+// UpdateBranding - Replace the current tenant's branding
+// PUT /api/v1/branding
+func (h *BrandingHandler) UpdateBranding(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	var input domain.TenantBranding
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	branding, err := h.brandingService.UpdateBranding(tenantID, input)
+	if err != nil {
+		return brandingError(c, err)
+	}
+	return c.JSON(branding)
+}
+
+func brandingError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrBrandingTenantNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidBranding):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": "Could not process branding", "details": err.Error()})
+}
@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
 	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
 	"github.com/opendefender/openrisk/internal/services"
 )
 
@@ -14,9 +20,9 @@ type BulkOperationHandler struct {
 }
 
 // NewBulkOperationHandler creates a new bulk operation handler
-func NewBulkOperationHandler() *BulkOperationHandler {
+func NewBulkOperationHandler(service *services.BulkOperationService) *BulkOperationHandler {
 	return &BulkOperationHandler{
-		service: services.NewBulkOperationService(),
+		service: service,
 	}
 }
 
@@ -48,6 +54,10 @@ func (h *BulkOperationHandler) CreateBulkOperation(c *fiber.Ctx) error {
 	// Create bulk operation
 	op, err := h.service.CreateBulkOperation(userClaims.ID, req)
 	if err != nil {
+		var filterErr *filter.Error
+		if errors.As(err, &filterErr) {
+			return riskFilterError(c, err)
+		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -108,3 +118,98 @@ func (h *BulkOperationHandler) ListBulkOperations(c *fiber.Ctx) error {
 		"count":      len(ops),
 	})
 }
+
+// ListExportColumns handles GET /bulk-operations/export-columns
+// Lists the columns that can be selected for a risk export
+func (h *BulkOperationHandler) ListExportColumns(c *fiber.Ctx) error {
+	columns, err := h.service.ExportColumns()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(columns)
+}
+
+// GetExportResult handles GET /bulk-operations/:id/export-result
+// Returns a signed, expiring download URL for a completed export
+func (h *BulkOperationHandler) GetExportResult(c *fiber.Ctx) error {
+	userClaims, ok := c.Locals("user").(*domain.UserClaims)
+	if !ok || userClaims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	opID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid operation ID",
+		})
+	}
+
+	op, err := h.service.GetBulkOperation(opID)
+	if err != nil || op.CreatedBy != userClaims.ID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Operation not found",
+		})
+	}
+
+	ttl := time.Duration(c.QueryInt("expires_in", 900)) * time.Second
+	if ttl <= 0 || ttl > 24*time.Hour {
+		ttl = 15 * time.Minute
+	}
+
+	url, expiresAt, err := h.service.ExportDownloadURL(op, ttl)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":  err.Error(),
+			"status": op.Status,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"download_url": c.BaseURL() + url,
+		"expires_at":   expiresAt,
+		"format":       op.ExportFormat,
+		"size":         op.ArtifactSize,
+	})
+}
+
+// DownloadExport handles GET /exports/:id/download
+// Streams an export artifact; the signature in the URL authenticates the request
+func (h *BulkOperationHandler) DownloadExport(c *fiber.Ctx) error {
+	opID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid operation ID",
+		})
+	}
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": services.ErrInvalidDownloadSignature.Error(),
+		})
+	}
+
+	body, op, err := h.service.OpenExport(c.Context(), opID, expires, c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDownloadSignature):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrExportNotReady):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	contentType, ext := services.ExportFileType(op.ExportFormat)
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=risks-export-%s.%s", op.ID.String()[:8], ext))
+
+	// Fiber closes the reader once the response has been streamed
+	return c.SendStream(body, int(op.ArtifactSize))
+}
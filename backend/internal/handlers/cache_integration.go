@@ -2,10 +2,13 @@ package handlers
 
 import (
 "context"
+"crypto/sha256"
 "fmt"
+"strings"
 "time"
 
 "github.com/gofiber/fiber/v2"
+"github.com/google/uuid"
 "github.com/opendefender/openrisk/internal/cache"
 )
 
@@ -86,10 +89,15 @@ func (ch *CacheableHandlers) CacheRiskListGET(handler fiber.Handler) fiber.Handl
 return ch.decoration.WrapWithCache(
 handler,
 func(c *fiber.Ctx) string {
-page := c.Query("page", "1")
-severity := c.Query("severity", "")
-status := c.Query("status", "")
-return fmt.Sprintf("risk:list:page:%s:sev:%s:status:%s", page, severity, status)
+// Every query param shapes the page (filter, legacy filters, sorting, paging)
+sum := sha256.Sum256(c.Request().URI().QueryString())
+key := fmt.Sprintf("risk:list:%x", sum[:16])
+// Macros such as @me and saved views (visibility) make the result depend on the caller
+if strings.Contains(c.Query("filter"), "@") || c.Query("view") != "" {
+userID, _ := c.Locals("user_id").(uuid.UUID)
+key += ":user:" + userID.String()
+}
+return key
 },
 ch.cacheConfig.RiskCacheTTL,
 )
@@ -113,7 +121,12 @@ return ch.decoration.WrapWithCache(
 handler,
 func(c *fiber.Ctx) string {
 riskID := c.Params("id")
-return fmt.Sprintf("risk:id:%s", riskID)
+key := fmt.Sprintf("risk:id:%s", riskID)
+// A past version of the risk is cached apart from the current one
+if asOf := c.Query("as_of"); asOf != "" {
+key += ":as_of:" + asOf
+}
+return key
 },
 ch.cacheConfig.RiskCacheTTL,
 )
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// requestActor returns the tenant and user of the request.
+// Single-tenant deployments have no tenant in context and use the nil tenant.
+func requestActor(c *fiber.Ctx) (uuid.UUID, uuid.UUID) {
+	tenantID, _ := c.Locals("tenant_id").(uuid.UUID)
+	userID, _ := c.Locals("user_id").(uuid.UUID)
+	return tenantID, userID
+}
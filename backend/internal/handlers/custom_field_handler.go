@@ -143,7 +143,7 @@ func (h *CustomFieldHandler) DeleteCustomField(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.service.DeleteCustomField(fieldID); err != nil {
+	if err := h.service.DeleteCustomField(c.UserContext(), fieldID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
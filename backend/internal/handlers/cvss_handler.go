@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
+)
+
+// CVSSHandler exposes the CVSS calculator and the tenant mapping of CVSS
+// vectors to risk impact and probability
+type CVSSHandler struct {
+	cvssService *services.CVSSService
+}
+
+// NewCVSSHandler creates a new CVSS handler
+func NewCVSSHandler(cvssService *services.CVSSService) *CVSSHandler {
+	return &CVSSHandler{
+		cvssService: cvssService,
+	}
+}
+
+// Calculate - Validate a CVSS v3.1 or v4.0 vector and return its base,
+// temporal and environmental scores with the impact and probability it maps
+// to, the environmental requirements taken from the given assets
+// POST /api/v1/cvss/calculate
+func (h *CVSSHandler) Calculate(c *fiber.Ctx) error {
+	type CalculateInput struct {
+		Vector   string   `json:"vector" validate:"required,max=200"`
+		AssetIDs []string `json:"asset_ids" validate:"omitempty,dive,uuid"`
+	}
+
+	input := new(CalculateInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, _ := requestActor(c)
+	mapping, err := h.cvssService.Calculate(tenantID, input.Vector, input.AssetIDs)
+	if err != nil {
+		return cvssError(c, err)
+	}
+
+	return c.Status(200).JSON(mapping)
+}
+
+// GetPolicy - Get the CVSS mapping policy of the tenant
+// GET /api/v1/cvss/policy
+func (h *CVSSHandler) GetPolicy(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	return c.Status(200).JSON(h.cvssService.GetPolicy(tenantID))
+}
+
+// UpdatePolicy - Set how the CVSS components of a vector translate into the
+// impact and probability of the tenant's risks
+// PUT /api/v1/cvss/policy
+func (h *CVSSHandler) UpdatePolicy(c *fiber.Ctx) error {
+	type PolicyInput struct {
+		ImpactThresholds       []domain.CVSSThreshold `json:"impact_thresholds"`
+		ProbabilityThresholds  []domain.CVSSThreshold `json:"probability_thresholds"`
+		IgnoreAssetCriticality bool                   `json:"ignore_asset_criticality"`
+	}
+
+	input := new(PolicyInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	policy := domain.CVSSMappingPolicy{
+		IgnoreAssetCriticality: input.IgnoreAssetCriticality,
+	}
+	if input.ImpactThresholds != nil {
+		policy.ImpactThresholds, _ = json.Marshal(input.ImpactThresholds)
+	}
+	if input.ProbabilityThresholds != nil {
+		policy.ProbabilityThresholds, _ = json.Marshal(input.ProbabilityThresholds)
+	}
+	updated, err := h.cvssService.UpdatePolicy(tenantID, userID, policy)
+	if err != nil {
+		return cvssError(c, err)
+	}
+
+	return c.Status(200).JSON(updated)
+}
+
+func cvssError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCVSSVector), errors.Is(err, services.ErrInvalidCVSSMappingPolicy):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "CVSS calculation failed", "details": err.Error()})
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// setETag sets the ETag header of a versioned resource
+func setETag(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, domain.ETag(version))
+}
+
+// preconditionFailed answers an If-Match that no longer matches with the
+// current state of the resource, so the client can merge and retry
+func preconditionFailed(c *fiber.Ctx, current interface{}, version int64) error {
+	setETag(c, version)
+	return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+		"error":   "Resource was modified since it was read",
+		"etag":    domain.ETag(version),
+		"current": current,
+	})
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// ImportHandler exposes the spreadsheet import pipeline
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importService *services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// ListFields - Fields of an entity that import columns can be mapped to (risk custom fields included)
+// GET /api/v1/imports/fields/:entity
+func (h *ImportHandler) ListFields(c *fiber.Ctx) error {
+	fields, err := h.importService.Fields(domain.ImportEntity(c.Params("entity")))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(200).JSON(fields)
+}
+
+// Upload - Upload a CSV or XLSX file (multipart field "file") for import; the
+// response carries the detected headers and a suggested column mapping
+// POST /api/v1/imports?entity=risk|asset|mitigation
+func (h *ImportHandler) Upload(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+
+	entity := c.Query("entity", c.FormValue("entity"))
+	if entity == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "entity is required (risk, asset or mitigation)"})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Missing file upload (multipart field \"file\")"})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Could not read uploaded file"})
+	}
+	defer file.Close()
+
+	batch, err := h.importService.Upload(c.UserContext(), tenantID, userID, domain.ImportEntity(entity), header.Filename, file)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return c.Status(201).JSON(batch)
+}
+
+// ListImports - List import batches (admins see every batch, other users their own)
+// GET /api/v1/imports?limit=50&offset=0
+func (h *ImportHandler) ListImports(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	var createdBy *uuid.UUID
+	if !isAdmin(c) || c.QueryBool("mine") {
+		createdBy = &userID
+	}
+
+	batches, total, err := h.importService.ListBatches(tenantID, createdBy, limit, offset)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"imports": batches,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// GetImport - Get an import batch with its mapping and dry-run preview
+// GET /api/v1/imports/:id
+func (h *ImportHandler) GetImport(c *fiber.Ctx) error {
+	batch, err := h.visibleBatch(c)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return c.Status(200).JSON(batch)
+}
+
+// DryRun - Confirm the column mapping and preview the creates, updates and errors
+// POST /api/v1/imports/:id/dry-run
+func (h *ImportHandler) DryRun(c *fiber.Ctx) error {
+	batch, err := h.visibleBatch(c)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	opts := services.DryRunOptions{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&opts); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+		}
+	}
+
+	batch, err = h.importService.DryRun(c.UserContext(), batch.TenantID, batch.ID, opts)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return c.Status(200).JSON(batch)
+}
+
+// CommitImport - Apply a validated import as a background job
+// POST /api/v1/imports/:id/commit
+func (h *ImportHandler) CommitImport(c *fiber.Ctx) error {
+	batch, err := h.visibleBatch(c)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	_, userID := requestActor(c)
+	batch, err = h.importService.Commit(batch.TenantID, batch.ID, userID)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return c.Status(202).JSON(batch)
+}
+
+// RollbackImport - Revert every record a committed import created or updated
+// POST /api/v1/imports/:id/rollback
+func (h *ImportHandler) RollbackImport(c *fiber.Ctx) error {
+	batch, err := h.visibleBatch(c)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	_, userID := requestActor(c)
+	batch, err = h.importService.Rollback(c.UserContext(), batch.TenantID, batch.ID, userID)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return c.Status(200).JSON(batch)
+}
+
+// visibleBatch loads the import batch in the path if the current user may see it
+func (h *ImportHandler) visibleBatch(c *fiber.Ctx) (*domain.ImportBatch, error) {
+	batchID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid import ID")
+	}
+
+	tenantID, userID := requestActor(c)
+
+	batch, err := h.importService.GetBatch(tenantID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.CreatedBy != userID && !isAdmin(c) {
+		return nil, services.ErrImportNotFound
+	}
+
+	return batch, nil
+}
+
+func (h *ImportHandler) importError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &fiberErr):
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	case errors.Is(err, services.ErrImportNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Import not found"})
+	case errors.Is(err, services.ErrInvalidImport):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrImportState), errors.Is(err, services.ErrImportHasErrors):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Import operation failed", "details": err.Error()})
+	}
+}
@@ -1,27 +1,57 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
 )
 
-// IncidentHandler manages incident endpoints
+// IncidentHandler manages incident endpoints: incidents and the risks they
+// realized, their loss events and the recalibration of risk probability
 type IncidentHandler struct {
-	db *gorm.DB
+	incidentService *services.IncidentService
 }
 
 // NewIncidentHandler creates a new incident handler
-func NewIncidentHandler(db *gorm.DB) *IncidentHandler {
-	return &IncidentHandler{db: db}
+func NewIncidentHandler(incidentService *services.IncidentService) *IncidentHandler {
+	return &IncidentHandler{incidentService: incidentService}
+}
+
+// LossEventInput is the body of a loss event
+type LossEventInput struct {
+	Description  string     `json:"description"`
+	DirectLoss   float64    `json:"direct_loss" validate:"min=0"`
+	IndirectLoss float64    `json:"indirect_loss" validate:"min=0"`
+	RecoveryCost float64    `json:"recovery_cost" validate:"min=0"`
+	OccurredAt   time.Time  `json:"occurred_at" validate:"required"`
+	DiscoveredAt *time.Time `json:"discovered_at"`
+	RecoveredAt  *time.Time `json:"recovered_at"`
 }
 
-// GetIncidents retrieves all incidents with pagination
+func (in *LossEventInput) event() domain.LossEvent {
+	return domain.LossEvent{
+		Description:  in.Description,
+		DirectLoss:   in.DirectLoss,
+		IndirectLoss: in.IndirectLoss,
+		RecoveryCost: in.RecoveryCost,
+		OccurredAt:   in.OccurredAt,
+		DiscoveredAt: in.DiscoveredAt,
+		RecoveredAt:  in.RecoveredAt,
+	}
+}
+
+// GetIncidents retrieves incidents with pagination, most recent first
+// GET /api/v1/incidents?page=1&limit=10&severity=&status=&source=&risk_id=
 func (h *IncidentHandler) GetIncidents(c *fiber.Ctx) error {
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 10)
-	severity := c.Query("severity")
-	status := c.Query("status")
-
 	if page < 1 {
 		page = 1
 	}
@@ -29,80 +59,316 @@ func (h *IncidentHandler) GetIncidents(c *fiber.Ctx) error {
 		limit = 10
 	}
 
-	offset := (page - 1) * limit
+	query := services.IncidentQuery{
+		Severity: strings.ToUpper(c.Query("severity")),
+		Status:   c.Query("status"),
+		Source:   strings.ToUpper(c.Query("source")),
+		Offset:   (page - 1) * limit,
+		Limit:    limit,
+	}
+	if raw := c.Query("risk_id"); raw != "" {
+		riskID, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+		}
+		query.RiskID = riskID
+	}
 
-	type IncidentResponse struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Severity    string `json:"severity"`
-		Status      string `json:"status"`
-		Date        string `json:"date"`
-		Assignee    string `json:"assignee"`
-		Description string `json:"description"`
+	incidents, total, err := h.incidentService.ListIncidents(query)
+	if err != nil {
+		return incidentError(c, err)
 	}
 
-	var incidents []IncidentResponse
-	query := h.db
+	return c.JSON(fiber.Map{
+		"incidents": incidents,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
 
-	if severity != "" {
-		query = query.Where("severity = ?", severity)
+// GetIncident retrieves an incident with the risks it realized and its loss events
+// GET /api/v1/incidents/:id
+func (h *IncidentHandler) GetIncident(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid incident ID"})
 	}
-	if status != "" {
-		query = query.Where("status = ?", status)
+
+	incident, err := h.incidentService.GetIncident(id)
+	if err != nil {
+		return incidentError(c, err)
 	}
 
-	result := query.
-		Offset(offset).
-		Limit(limit).
-		Find(&incidents)
+	return c.JSON(incident)
+}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(fiber.Map{"error": result.Error.Error()})
+// CreateIncident - Record an incident, linked to the risks it realized
+// POST /api/v1/incidents
+func (h *IncidentHandler) CreateIncident(c *fiber.Ctx) error {
+	type IncidentInput struct {
+		Title       string     `json:"title" validate:"required,max=255"`
+		Description string     `json:"description"`
+		Severity    string     `json:"severity" validate:"required,oneof=LOW MEDIUM HIGH CRITICAL"`
+		Status      string     `json:"status" validate:"max=50"`
+		OpenedAt    *time.Time `json:"opened_at"`
+		ResolvedAt  *time.Time `json:"resolved_at"`
+		RiskIDs     []string   `json:"risk_ids" validate:"omitempty,dive,uuid"`
 	}
 
-	var total int64
-	countQuery := h.db
-	if severity != "" {
-		countQuery = countQuery.Where("severity = ?", severity)
+	input := new(IncidentInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
 	}
-	if status != "" {
-		countQuery = countQuery.Where("status = ?", status)
+	input.Severity = strings.ToUpper(input.Severity)
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
-	countQuery.Model(&IncidentResponse{}).Count(&total)
 
-	return c.JSON(fiber.Map{
-		"incidents": incidents,
-		"total":     total,
-		"page":      page,
-		"limit":     limit,
+	incident := &domain.Incident{
+		Title:       input.Title,
+		Description: input.Description,
+		Severity:    input.Severity,
+		Status:      input.Status,
+		ResolvedAt:  input.ResolvedAt,
+		Source:      domain.IncidentSourceManual,
+	}
+	if incident.Status == "" {
+		incident.Status = "Open"
+	}
+	if input.OpenedAt != nil {
+		incident.CreatedAt = *input.OpenedAt
+	}
+	riskIDs := make([]uuid.UUID, 0, len(input.RiskIDs))
+	for _, raw := range input.RiskIDs {
+		riskIDs = append(riskIDs, uuid.MustParse(raw))
+	}
+
+	created, err := h.incidentService.RecordIncident(c.UserContext(), incident, riskIDs...)
+	if err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.Status(201).JSON(created)
+}
+
+// LinkRisks - Record that an incident realized risks; risks realized
+// repeatedly have their probability raised
+// POST /api/v1/incidents/:id/risks
+func (h *IncidentHandler) LinkRisks(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid incident ID"})
+	}
+	type LinkInput struct {
+		RiskIDs []string `json:"risk_ids" validate:"required,min=1,dive,uuid"`
+	}
+	input := new(LinkInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+	riskIDs := make([]uuid.UUID, 0, len(input.RiskIDs))
+	for _, raw := range input.RiskIDs {
+		riskIDs = append(riskIDs, uuid.MustParse(raw))
+	}
+
+	recalibrations, err := h.incidentService.LinkRisks(c.UserContext(), id, riskIDs)
+	if err != nil {
+		return incidentError(c, err)
+	}
+	incident, err := h.incidentService.GetIncident(id)
+	if err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"incident":       incident,
+		"recalibrations": recalibrations,
 	})
 }
 
-// GetIncident retrieves a single incident by ID
-func (h *IncidentHandler) GetIncident(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	type IncidentDetail struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Severity    string `json:"severity"`
-		Status      string `json:"status"`
-		Date        string `json:"date"`
-		Assignee    string `json:"assignee"`
-		Description string `json:"description"`
-		Source      string `json:"source"`
-		ExternalID  string `json:"external_id"`
-	}
-
-	var incident IncidentDetail
-	result := h.db.Where("id = ?", id).First(&incident)
-
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(404).JSON(fiber.Map{"error": "Incident not found"})
-		}
-		return c.Status(500).JSON(fiber.Map{"error": result.Error.Error()})
+// UnlinkRisk - Remove the link between an incident and a risk
+// DELETE /api/v1/incidents/:id/risks/:riskId
+func (h *IncidentHandler) UnlinkRisk(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid incident ID"})
+	}
+	riskID, err := uuid.Parse(c.Params("riskId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
 	}
 
-	return c.JSON(incident)
+	if err := h.incidentService.UnlinkRisk(id, riskID); err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// GetRiskIncidents - Incidents that realized a risk and the raises of its
+// probability they caused
+// GET /api/v1/risks/:id/incidents
+func (h *IncidentHandler) GetRiskIncidents(c *fiber.Ctx) error {
+	riskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+	}
+
+	incidents, total, err := h.incidentService.ListIncidents(services.IncidentQuery{RiskID: riskID, Limit: 200})
+	if err != nil {
+		return incidentError(c, err)
+	}
+	recalibrations, err := h.incidentService.ListRecalibrations(riskID)
+	if err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"incidents":      incidents,
+		"total":          total,
+		"recalibrations": recalibrations,
+	})
+}
+
+// CreateLossEvent - Record a loss caused by an incident
+// POST /api/v1/incidents/:id/loss-events
+func (h *IncidentHandler) CreateLossEvent(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid incident ID"})
+	}
+	input := new(LossEventInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	event, err := h.incidentService.AddLossEvent(c.UserContext(), id, input.event())
+	if err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.Status(201).JSON(event)
+}
+
+// UpdateLossEvent - Replace the amounts and dates of a loss event
+// PUT /api/v1/loss-events/:id
+func (h *IncidentHandler) UpdateLossEvent(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid loss event ID"})
+	}
+	input := new(LossEventInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	event, err := h.incidentService.UpdateLossEvent(c.UserContext(), id, input.event())
+	if err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.Status(200).JSON(event)
+}
+
+// DeleteLossEvent - Remove a loss event
+// DELETE /api/v1/loss-events/:id
+func (h *IncidentHandler) DeleteLossEvent(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid loss event ID"})
+	}
+
+	if err := h.incidentService.DeleteLossEvent(id); err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// GetRecalibrationPolicy - Get the recalibration policy of the tenant
+// GET /api/v1/incidents/recalibration/policy
+func (h *IncidentHandler) GetRecalibrationPolicy(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	return c.Status(200).JSON(h.incidentService.GetPolicy(tenantID))
+}
+
+// UpdateRecalibrationPolicy - Set how many incidents within which window
+// raise the probability of the tenant's risks, then recalibrate them
+// PUT /api/v1/incidents/recalibration/policy
+func (h *IncidentHandler) UpdateRecalibrationPolicy(c *fiber.Ctx) error {
+	type PolicyInput struct {
+		Enabled    bool                              `json:"enabled"`
+		WindowDays int                               `json:"window_days" validate:"min=1,max=3650"`
+		Thresholds []domain.MaterializationThreshold `json:"thresholds"`
+	}
+
+	input := new(PolicyInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	policy := domain.RecalibrationPolicy{
+		Enabled:    input.Enabled,
+		WindowDays: input.WindowDays,
+	}
+	if input.Thresholds != nil {
+		policy.Thresholds, _ = json.Marshal(input.Thresholds)
+	}
+	updated, err := h.incidentService.UpdatePolicy(tenantID, userID, policy)
+	if err != nil {
+		return incidentError(c, err)
+	}
+	result, err := h.incidentService.RecalibrateAll(c.UserContext())
+	if err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"policy": updated,
+		"result": result,
+	})
+}
+
+// RunRecalibration - Recalibrate every risk realized by an incident now
+// POST /api/v1/incidents/recalibration/run
+func (h *IncidentHandler) RunRecalibration(c *fiber.Ctx) error {
+	result, err := h.incidentService.RecalibrateAll(c.UserContext())
+	if err != nil {
+		return incidentError(c, err)
+	}
+
+	return c.Status(200).JSON(result)
+}
+
+func incidentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidLossEvent), errors.Is(err, services.ErrInvalidRecalibrationPolicy):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrIncidentNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Incident not found"})
+	case errors.Is(err, services.ErrRiskNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+	case errors.Is(err, services.ErrIncidentRiskNotLinked):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrLossEventNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Loss event not found"})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Incident operation failed", "details": err.Error()})
+	}
 }
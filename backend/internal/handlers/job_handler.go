@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// JobHandler exposes the status, progress and errors of background jobs
+type JobHandler struct {
+	jobService *services.JobQueueService
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobService *services.JobQueueService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// ListJobs - List background jobs (admins see every job, other users their own)
+// GET /api/v1/jobs?type=bulk_operation&status=FAILED&limit=50&offset=0
+func (h *JobHandler) ListJobs(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	var createdBy *uuid.UUID
+	if !isAdmin(c) || c.QueryBool("mine") {
+		createdBy = &userID
+	}
+
+	jobs, total, err := h.jobService.ListJobs(tenantID, createdBy, c.Query("type"), strings.ToUpper(c.Query("status")), limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list jobs", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"jobs":   jobs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetJob - Get the status, progress and last error of a job
+// GET /api/v1/jobs/:id
+func (h *JobHandler) GetJob(c *fiber.Ctx) error {
+	job, err := h.visibleJob(c)
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	return c.Status(200).JSON(job)
+}
+
+// CancelJob - Cancel a queued or running job
+// POST /api/v1/jobs/:id/cancel
+func (h *JobHandler) CancelJob(c *fiber.Ctx) error {
+	job, err := h.visibleJob(c)
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	job, err = h.jobService.CancelJob(job.TenantID, job.ID)
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	return c.Status(200).JSON(job)
+}
+
+// RetryJob - Re-queue a failed or cancelled job
+// POST /api/v1/jobs/:id/retry
+func (h *JobHandler) RetryJob(c *fiber.Ctx) error {
+	job, err := h.visibleJob(c)
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	job, err = h.jobService.RetryJob(job.TenantID, job.ID)
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	return c.Status(200).JSON(job)
+}
+
+// visibleJob loads the job in the path if the current user may see it
+func (h *JobHandler) visibleJob(c *fiber.Ctx) (*domain.Job, error) {
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid job ID")
+	}
+
+	tenantID, userID := requestActor(c)
+
+	job, err := h.jobService.GetJob(tenantID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.CreatedBy != userID && !isAdmin(c) {
+		return nil, services.ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+func (h *JobHandler) jobError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &fiberErr):
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	case errors.Is(err, services.ErrJobNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Job not found"})
+	case errors.Is(err, services.ErrJobFinished), errors.Is(err, services.ErrJobNotRetryable):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Job operation failed", "details": err.Error()})
+	}
+}
+
+// isAdmin reports whether the current user has the admin role
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("role").(string)
+	return strings.EqualFold(role, "admin")
+}
@@ -441,17 +441,26 @@ func (h *MarketplaceHandler) UpdateAppSync(c *fiber.Ctx) error {
 // @Param id path string true "App ID"
 // @Produce json
 // @Success 200 {object} map[string]string
+// @Success 202 {object} map[string]interface{}
 // @Router /marketplace/apps/{id}/sync [post]
 func (h *MarketplaceHandler) TriggerSync(c *fiber.Ctx) error {
 	appID := c.Params("id")
 	userID := c.Locals("user_id").(string)
 
-	if err := h.service.TriggerSync(c.Context(), appID, userID); err != nil {
+	job, err := h.service.RequestSync(c.Context(), appID, userID)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	if job != nil {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "sync queued",
+			"job_id":  job.ID,
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "sync triggered successfully",
 	})
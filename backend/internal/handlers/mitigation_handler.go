@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"sort"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/opendefender/openrisk/database"
 	"github.com/opendefender/openrisk/internal/core/domain"
 	"github.com/opendefender/openrisk/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AddMitigation ajoute une action corrective à un risque
@@ -29,7 +32,17 @@ func AddMitigation(c *fiber.Ctx) error {
 	mitigation.RiskID = uuid.MustParse(riskID)
 	mitigation.Status = domain.MitigationPlanned
 
-	if err := database.DB.Create(mitigation).Error; err != nil {
+	// Techniques ATT&CK contrées et contre-mesures ATT&CK / D3FEND mises en œuvre
+	attack := services.NewAttackService(database.DB)
+	var err error
+	if mitigation.Techniques, err = attack.NormalizeTechniques(mitigation.Techniques); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if mitigation.Countermeasures, err = attack.NormalizeCountermeasures(mitigation.Countermeasures); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := database.DB.WithContext(c.UserContext()).Create(mitigation).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not create mitigation"})
 	}
 
@@ -38,26 +51,58 @@ func AddMitigation(c *fiber.Ctx) error {
 
 // ToggleMitigationStatus change le statut (PLANNED <-> DONE)
 func ToggleMitigationStatus(c *fiber.Ctx) error {
-	mitigationID := c.Params("mitigationId")
 	var mitigation domain.Mitigation
+	err := database.DB.WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		if err := lockMitigation(c, tx, &mitigation); err != nil {
+			return err
+		}
 
-	if err := database.DB.First(&mitigation, "id = ?", mitigationID).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Mitigation not found"})
-	}
+		// Logique de bascule simple
+		if mitigation.Status == domain.MitigationDone {
+			mitigation.Status = domain.MitigationInProgress
+			mitigation.Progress = 50
+		} else {
+			mitigation.Status = domain.MitigationDone
+			mitigation.Progress = 100
+		}
 
-	// Logique de bascule simple
-	if mitigation.Status == domain.MitigationDone {
-		mitigation.Status = domain.MitigationInProgress
-		mitigation.Progress = 50
-	} else {
-		mitigation.Status = domain.MitigationDone
-		mitigation.Progress = 100
+		return tx.Save(&mitigation).Error
+	})
+	if err != nil {
+		return mitigationWriteError(c, err, "Could not update mitigation")
 	}
 
-	database.DB.Save(&mitigation)
+	setETag(c, int64(mitigation.Version))
 	return c.JSON(mitigation)
 }
 
+// lockMitigation charge la mitigation de la route pour mise à jour et la
+// compare à l'en-tête If-Match
+func lockMitigation(c *fiber.Ctx, tx *gorm.DB, mitigation *domain.Mitigation) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(mitigation, "id = ?", c.Params("mitigationId")).Error; err != nil {
+		return err
+	}
+	return domain.CheckVersion(c.Get(fiber.HeaderIfMatch), int64(mitigation.Version))
+}
+
+// mitigationWriteError répond 404, 412 avec l'état courant, ou 500
+func mitigationWriteError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Mitigation not found"})
+	case errors.Is(err, domain.ErrVersionConflict):
+		var current domain.Mitigation
+		if err := database.DB.First(&current, "id = ?", c.Params("mitigationId")).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Mitigation not found"})
+		}
+		return preconditionFailed(c, current, int64(current.Version))
+	case errors.Is(err, services.ErrUnknownAttackID):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": message})
+	}
+}
+
 // GetRecommendedMitigations expose la liste des mitigations triées par SPP.
 func GetRecommendedMitigations(c *fiber.Ctx) error {
 	service := services.NewRecommendationService()
@@ -77,15 +122,9 @@ func GetRecommendedMitigations(c *fiber.Ctx) error {
 	return c.JSON(mitigations)
 }
 
-// UpdateMitigation met à jour les champs éditables d'une mitigation
+// UpdateMitigation met à jour les champs éditables d'une mitigation ; avec
+// If-Match, seulement si elle est toujours à cette version (412 sinon)
 func UpdateMitigation(c *fiber.Ctx) error {
-	mitigationID := c.Params("mitigationId")
-	var mitigation domain.Mitigation
-
-	if err := database.DB.First(&mitigation, "id = ?", mitigationID).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Mitigation not found"})
-	}
-
 	// Parse payload
 	payload := struct {
 		Title          *string `json:"title"`
@@ -95,44 +134,88 @@ func UpdateMitigation(c *fiber.Ctx) error {
 		DueDate        *string `json:"due_date"`
 		Cost           *int    `json:"cost"`
 		MitigationTime *int    `json:"mitigation_time"`
+		// Une liste vide retire les techniques ou contre-mesures
+		Techniques      *[]string `json:"techniques"`
+		Countermeasures *[]string `json:"countermeasures"`
 	}{}
 
 	if err := c.BodyParser(&payload); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid payload"})
 	}
 
-	if payload.Title != nil {
-		mitigation.Title = *payload.Title
-	}
-	if payload.Assignee != nil {
-		mitigation.Assignee = *payload.Assignee
-	}
-	if payload.Status != nil {
-		mitigation.Status = domain.MitigationStatus(*payload.Status)
-	}
-	if payload.Progress != nil {
-		mitigation.Progress = *payload.Progress
-	}
-	if payload.Cost != nil {
-		mitigation.Cost = *payload.Cost
-	}
-	if payload.MitigationTime != nil {
-		mitigation.MitigationTime = *payload.MitigationTime
-	}
-	if payload.DueDate != nil {
-		// try parse RFC3339
-		if t, err := time.Parse(time.RFC3339, *payload.DueDate); err == nil {
-			mitigation.DueDate = t
+	var mitigation domain.Mitigation
+	err := database.DB.WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		if err := lockMitigation(c, tx, &mitigation); err != nil {
+			return err
+		}
+
+		if payload.Title != nil {
+			mitigation.Title = *payload.Title
+		}
+		if payload.Assignee != nil {
+			mitigation.Assignee = *payload.Assignee
+		}
+		if payload.Status != nil {
+			mitigation.Status = domain.MitigationStatus(*payload.Status)
+		}
+		if payload.Progress != nil {
+			mitigation.Progress = *payload.Progress
+		}
+		if payload.Cost != nil {
+			mitigation.Cost = *payload.Cost
+		}
+		if payload.MitigationTime != nil {
+			mitigation.MitigationTime = *payload.MitigationTime
+		}
+		if payload.DueDate != nil {
+			// try parse RFC3339
+			if t, err := time.Parse(time.RFC3339, *payload.DueDate); err == nil {
+				mitigation.DueDate = t
+			}
+		}
+		if payload.Techniques != nil {
+			techniques, err := services.NewAttackService(tx).NormalizeTechniques(*payload.Techniques)
+			if err != nil {
+				return err
+			}
+			mitigation.Techniques = techniques
+		}
+		if payload.Countermeasures != nil {
+			countermeasures, err := services.NewAttackService(tx).NormalizeCountermeasures(*payload.Countermeasures)
+			if err != nil {
+				return err
+			}
+			mitigation.Countermeasures = countermeasures
 		}
-	}
 
-	if err := database.DB.Save(&mitigation).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Could not update mitigation"})
+		return tx.Save(&mitigation).Error
+	})
+	if err != nil {
+		return mitigationWriteError(c, err, "Could not update mitigation")
 	}
 
+	setETag(c, int64(mitigation.Version))
 	return c.JSON(mitigation)
 }
 
+// DeleteMitigation supprime une mitigation (soft delete) ; elle reste dans la
+// corbeille jusqu'à sa restauration ou sa purge. Avec If-Match, seulement si
+// elle est toujours à cette version (412 sinon)
+func DeleteMitigation(c *fiber.Ctx) error {
+	err := database.DB.WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		var mitigation domain.Mitigation
+		if err := lockMitigation(c, tx, &mitigation); err != nil {
+			return err
+		}
+		// Le modèle chargé porte le risque, versionné par le hook AfterDelete
+		return tx.Delete(&mitigation).Error
+	})
+	if err != nil {
+		return mitigationWriteError(c, err, "Could not delete mitigation")
+	}
+	return c.SendStatus(204)
+}
+
 // CreateMitigationSubAction ajoute une sous-action (checklist) à une mitigation
 func CreateMitigationSubAction(c *fiber.Ctx) error {
 	mitigationID := c.Params("id")
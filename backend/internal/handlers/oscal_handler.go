@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/adapters/oscal"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
+)
+
+// OSCALHandler exposes the OSCAL import of catalogs and profiles into the
+// control catalog, the implementation of controls by the tenant, and the
+// OSCAL export of system security plan fragments and plans of action and
+// milestones
+type OSCALHandler struct {
+	controlService *services.ControlService
+}
+
+// NewOSCALHandler creates a new OSCAL handler
+func NewOSCALHandler(controlService *services.ControlService) *OSCALHandler {
+	return &OSCALHandler{
+		controlService: controlService,
+	}
+}
+
+// ImportDocument - Import an OSCAL catalog, or a profile of an imported
+// catalog or profile, in JSON or XML into the control catalog
+// POST /api/v1/oscal/import
+func (h *OSCALHandler) ImportDocument(c *fiber.Ctx) error {
+	_, userID := requestActor(c)
+	body, fileName, err := uploadBody(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "could not read upload"})
+	}
+	sum := sha256.Sum256(data)
+
+	doc, _, err := oscal.Read(bytes.NewReader(data))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var catalog *domain.ControlCatalog
+	switch doc.Model() {
+	case "catalog":
+		catalog, err = h.controlService.ImportCatalog(c.UserContext(), controlCatalog(doc.Catalog.UUID, doc.Catalog.Metadata, fileName, sum[:], userID),
+			oscal.CatalogControls(doc.Catalog))
+	case "profile":
+		catalog, err = h.importProfile(c, doc.Profile, controlCatalog(doc.Profile.UUID, doc.Profile.Metadata, fileName, sum[:], userID))
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("%s: only catalogs and profiles can be imported", oscal.ErrUnsupportedModel)})
+	}
+	if err != nil {
+		return oscalError(c, err)
+	}
+
+	return c.Status(201).JSON(catalog)
+}
+
+// importProfile resolves the imports of a profile against the loaded
+// catalogs and profiles, all of the same base catalog, and stores the
+// controls they select
+func (h *OSCALHandler) importProfile(c *fiber.Ctx, profile *oscal.Profile, meta domain.ControlCatalog) (*domain.ControlCatalog, error) {
+	var base *domain.ControlCatalog
+	var selected []string
+	for _, imp := range profile.Imports {
+		catalog, controls, err := h.controlService.ResolveImport(oscal.ImportRefs(profile, imp))
+		if err != nil {
+			return nil, err
+		}
+		if base != nil && base.ID != catalog.ID {
+			return nil, fmt.Errorf("%w: the imports of a profile must select controls of one catalog", services.ErrInvalidControlCatalog)
+		}
+		base = catalog
+		for _, id := range oscal.SelectControls(imp, controls) {
+			if !slices.Contains(selected, id) {
+				selected = append(selected, id)
+			}
+		}
+	}
+	if base == nil {
+		return nil, fmt.Errorf("%w: the profile imports nothing", services.ErrInvalidControlCatalog)
+	}
+	return h.controlService.ImportProfile(c.UserContext(), meta, base.ID, selected)
+}
+
+func controlCatalog(oscalUUID string, metadata oscal.Metadata, fileName string, checksum []byte, importedBy uuid.UUID) domain.ControlCatalog {
+	return domain.ControlCatalog{
+		OSCALUUID:    oscalUUID,
+		Title:        metadata.Title,
+		Version:      metadata.Version,
+		OSCALVersion: metadata.OSCALVersion,
+		LastModified: oscal.LastModified(metadata),
+		FileName:     fileName,
+		Checksum:     hex.EncodeToString(checksum),
+		ImportedBy:   importedBy,
+	}
+}
+
+// GetCatalogs - Loaded control catalogs and profiles
+// GET /api/v1/controls/catalogs
+func (h *OSCALHandler) GetCatalogs(c *fiber.Ctx) error {
+	catalogs, err := h.controlService.ListCatalogs()
+	if err != nil {
+		return oscalError(c, err)
+	}
+
+	return c.Status(200).JSON(catalogs)
+}
+
+// GetControls - Controls in catalog order with their implementation state
+// GET /api/v1/controls?catalog_id=&family=&status=&q=&page=1&limit=100
+func (h *OSCALHandler) GetControls(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+	var catalogID uuid.UUID
+	if value := c.Query("catalog_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid catalog_id"})
+		}
+		catalogID = id
+	}
+
+	controls, total, err := h.controlService.ListControls(services.ControlFilter{
+		TenantID:  tenantID,
+		CatalogID: catalogID,
+		Family:    c.Query("family"),
+		Status:    c.Query("status"),
+		Query:     c.Query("q"),
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	})
+	if err != nil {
+		return oscalError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"controls": controls,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// GetControl - A control with its implementation and compliance evidence
+// GET /api/v1/controls/:id
+func (h *OSCALHandler) GetControl(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid control ID"})
+	}
+
+	control, err := h.controlService.GetControl(tenantID, id)
+	if err != nil {
+		return oscalError(c, err)
+	}
+
+	return c.Status(200).JSON(control)
+}
+
+// SetImplementation - Record how the tenant implements a control
+// PUT /api/v1/controls/:id/implementation
+func (h *OSCALHandler) SetImplementation(c *fiber.Ctx) error {
+	type ImplementationInput struct {
+		Status          string `json:"status" validate:"required"`
+		Description     string `json:"description"`
+		ResponsibleRole string `json:"responsible_role" validate:"max=100"`
+	}
+
+	tenantID, userID := requestActor(c)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid control ID"})
+	}
+	input := new(ImplementationInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	impl, err := h.controlService.SetImplementation(c.UserContext(), tenantID, userID, id, domain.ControlImplementation{
+		Status:          input.Status,
+		Description:     input.Description,
+		ResponsibleRole: input.ResponsibleRole,
+	})
+	if err != nil {
+		return oscalError(c, err)
+	}
+
+	return c.Status(200).JSON(impl)
+}
+
+// ExportSSP - System security plan fragment of a catalog or profile: the
+// implementation of its controls with their evidence
+// GET /api/v1/oscal/ssp?catalog_id=&system_name=&format=json|xml
+func (h *OSCALHandler) ExportSSP(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	catalogID, err := uuid.Parse(c.Query("catalog_id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid catalog_id"})
+	}
+	format, err := oscalFormat(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	plan, err := h.controlService.ImplementationPlan(tenantID, catalogID)
+	if err != nil {
+		return oscalError(c, err)
+	}
+	doc := oscal.BuildSSP(oscal.SSPInput{
+		SystemID:        tenantID,
+		SystemName:      c.Query("system_name", "OpenRisk"),
+		Profile:         plan.Profile,
+		Controls:        plan.Controls,
+		Implementations: plan.Implementations,
+		Evidence:        plan.Evidence,
+		GeneratedAt:     time.Now(),
+	})
+	return sendOSCAL(c, doc, format, "openrisk-ssp")
+}
+
+// ExportPOAM - Plan of action and milestones of the open risks with their
+// treatment plans and actions; OSCAL has no empty plan
+// GET /api/v1/oscal/poam?system_name=&format=json|xml
+func (h *OSCALHandler) ExportPOAM(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	format, err := oscalFormat(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	open, err := h.controlService.OpenRisks(tenantID)
+	if err != nil {
+		return oscalError(c, err)
+	}
+	if len(open) == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "No open risks to put in a plan of action and milestones"})
+	}
+	risks := make([]oscal.POAMRisk, 0, len(open))
+	for _, r := range open {
+		risks = append(risks, oscal.POAMRisk{Risk: r.Risk, Plans: r.Plans, Deviation: r.Deviation})
+	}
+	doc := oscal.BuildPOAM(oscal.POAMInput{
+		SystemID:    tenantID,
+		SystemName:  c.Query("system_name", "OpenRisk"),
+		Risks:       risks,
+		GeneratedAt: time.Now(),
+	})
+	return sendOSCAL(c, doc, format, "openrisk-poam")
+}
+
+func oscalFormat(c *fiber.Ctx) (string, error) {
+	switch format := c.Query("format", oscal.FormatJSON); format {
+	case oscal.FormatJSON, oscal.FormatXML:
+		return format, nil
+	}
+	return "", errors.New("format must be json or xml")
+}
+
+func sendOSCAL(c *fiber.Ctx, doc *oscal.Document, format, name string) error {
+	var buf bytes.Buffer
+	if err := oscal.Write(&buf, doc, format); err != nil {
+		return oscalError(c, err)
+	}
+	c.Set(fiber.HeaderContentType, oscal.ContentType(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	return c.Status(200).Send(buf.Bytes())
+}
+
+func oscalError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrControlNotFound), errors.Is(err, services.ErrControlCatalogNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidControlCatalog), errors.Is(err, domain.ErrInvalidControlStatus),
+		errors.Is(err, oscal.ErrNotOSCAL), errors.Is(err, oscal.ErrUnsupportedModel):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "OSCAL request failed", "details": err.Error()})
+	}
+}
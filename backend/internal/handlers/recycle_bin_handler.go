@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// RecycleBinHandler serves the recycle bin of soft-deleted risks, assets,
+// mitigations, teams and custom fields
+type RecycleBinHandler struct {
+	recycleBinService *services.RecycleBinService
+}
+
+// NewRecycleBinHandler creates a new recycle bin handler
+func NewRecycleBinHandler(recycleBinService *services.RecycleBinService) *RecycleBinHandler {
+	return &RecycleBinHandler{recycleBinService: recycleBinService}
+}
+
+// ListDeletedItems - List the deleted items of a type with who deleted them,
+// when, and when they will be purged
+// GET /api/v1/recycle-bin/:type?limit=50&offset=0
+func (h *RecycleBinHandler) ListDeletedItems(c *fiber.Ctx) error {
+	resource, err := services.ParseRecycleResource(c.Params("type"))
+	if err != nil {
+		return recycleBinError(c, err)
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	items, total, err := h.recycleBinService.List(resource, limit, offset)
+	if err != nil {
+		return recycleBinError(c, err)
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"items":  items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// RestoreDeletedItem - Restore a deleted item with what was deleted along
+// with it (mitigations and asset links of a risk, memberships of a team).
+// Teams are restored by admins only.
+// POST /api/v1/recycle-bin/:type/:id/restore
+func (h *RecycleBinHandler) RestoreDeletedItem(c *fiber.Ctx) error {
+	resource, id, err := recycleBinTarget(c)
+	if err != nil {
+		return recycleBinError(c, err)
+	}
+	if resource == domain.RecycleTeam && !isAdmin(c) {
+		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	item, err := h.recycleBinService.Restore(c.UserContext(), resource, id)
+	if err != nil {
+		return recycleBinError(c, err)
+	}
+	return c.Status(200).JSON(item)
+}
+
+// PurgeDeletedItem - Permanently delete an item of the recycle bin (admin only)
+// DELETE /api/v1/recycle-bin/:type/:id
+func (h *RecycleBinHandler) PurgeDeletedItem(c *fiber.Ctx) error {
+	resource, id, err := recycleBinTarget(c)
+	if err != nil {
+		return recycleBinError(c, err)
+	}
+
+	if err := h.recycleBinService.Purge(c.UserContext(), resource, id); err != nil {
+		return recycleBinError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+func recycleBinTarget(c *fiber.Ctx) (domain.RecycleResource, uuid.UUID, error) {
+	resource, err := services.ParseRecycleResource(c.Params("type"))
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return "", uuid.Nil, errInvalidRecycleID
+	}
+	return resource, id, nil
+}
+
+var errInvalidRecycleID = errors.New("invalid UUID")
+
+func recycleBinError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidRecycleResource), errors.Is(err, errInvalidRecycleID):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrDeletedItemNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Deleted item not found"})
+	case errors.Is(err, services.ErrRestoreConflict):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Recycle bin operation failed", "details": err.Error()})
+	}
+}
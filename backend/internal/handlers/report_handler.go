@@ -1,27 +1,32 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
+	"github.com/opendefender/openrisk/internal/services"
 )
 
 // ReportHandler manages report endpoints
 type ReportHandler struct {
-	db *gorm.DB
+	reportService *services.ReportService
 }
 
 // NewReportHandler creates a new report handler
-func NewReportHandler(db *gorm.DB) *ReportHandler {
-	return &ReportHandler{db: db}
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
 }
 
-// GetReports retrieves all reports with pagination
+// GetReports - List generated reports with pagination
+// GET /api/v1/reports?type=&status=&schedule_id=&page=&limit=
 func (h *ReportHandler) GetReports(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 10)
-	reportType := c.Query("type")
-	status := c.Query("status")
-
 	if page < 1 {
 		page = 1
 	}
@@ -29,102 +34,301 @@ func (h *ReportHandler) GetReports(c *fiber.Ctx) error {
 		limit = 10
 	}
 
-	offset := (page - 1) * limit
+	opts := services.ReportListOptions{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+		Page:   page,
+		Limit:  limit,
+	}
+	if raw := c.Query("schedule_id"); raw != "" {
+		scheduleID, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid schedule ID"})
+		}
+		opts.ScheduleID = &scheduleID
+	}
 
-	type ReportResponse struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Type        string `json:"type"`
-		Format      string `json:"format"`
-		CreatedAt   string `json:"created_at"`
-		GeneratedBy string `json:"generated_by"`
-		Status      string `json:"status"`
-		Size        string `json:"size"`
+	reports, total, err := h.reportService.ListReports(tenantID, opts)
+	if err != nil {
+		return reportError(c, err)
 	}
 
-	var reports []ReportResponse
-	query := h.db
+	return c.JSON(fiber.Map{
+		"reports": reports,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
 
-	if reportType != "" {
-		query = query.Where("type = ?", reportType)
+// GetReport - Get a report and its generation status
+// GET /api/v1/reports/:id
+func (h *ReportHandler) GetReport(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid report ID"})
 	}
-	if status != "" {
-		query = query.Where("status = ?", status)
+
+	report, err := h.reportService.GetReport(tenantID, reportID)
+	if err != nil {
+		return reportError(c, err)
 	}
 
-	result := query.
-		Offset(offset).
-		Limit(limit).
-		Order("created_at DESC").
-		Find(&reports)
+	return c.JSON(report)
+}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(fiber.Map{"error": result.Error.Error()})
+// GetReportStats - Count reports by status and enabled schedules
+// GET /api/v1/reports/stats
+func (h *ReportHandler) GetReportStats(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	stats, err := h.reportService.Stats(tenantID)
+	if err != nil {
+		return reportError(c, err)
 	}
 
-	var total int64
-	countQuery := h.db
-	if reportType != "" {
-		countQuery = countQuery.Where("type = ?", reportType)
-	}
-	if status != "" {
-		countQuery = countQuery.Where("status = ?", status)
-	}
-	countQuery.Model(&ReportResponse{}).Count(&total)
+	return c.JSON(stats)
+}
 
+// GetReportTemplates - List the report templates and formats
+// GET /api/v1/reports/templates
+func (h *ReportHandler) GetReportTemplates(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
-		"reports": reports,
-		"total":   total,
-		"page":    page,
-		"limit":   limit,
+		"templates": domain.ValidReportTemplates,
+		"formats":   []domain.ReportFormat{domain.ReportFormatPDF, domain.ReportFormatDOCX, domain.ReportFormatHTML},
+		"periods": []domain.ReportPeriod{domain.ReportPeriodLastWeek, domain.ReportPeriodLastMonth,
+			domain.ReportPeriodLastQuarter, domain.ReportPeriodLast30Days},
 	})
 }
 
-// GetReport retrieves a single report by ID
-func (h *ReportHandler) GetReport(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	type ReportDetail struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Type        string `json:"type"`
-		Format      string `json:"format"`
-		CreatedAt   string `json:"created_at"`
-		GeneratedBy string `json:"generated_by"`
-		Status      string `json:"status"`
-		Size        string `json:"size"`
-		Content     string `json:"content"`
-	}
-
-	var report ReportDetail
-	result := h.db.Where("id = ?", id).First(&report)
-
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(404).JSON(fiber.Map{"error": "Report not found"})
+// GenerateReport - Queue a report from a template
+// POST /api/v1/reports
+func (h *ReportHandler) GenerateReport(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+	var input services.GenerateReportInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	report, err := h.reportService.Generate(tenantID, userID, input)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Status(202).JSON(report)
+}
+
+// RegenerateReport - Render a report again from its snapshot, optionally in another format
+// POST /api/v1/reports/:id/regenerate
+func (h *ReportHandler) RegenerateReport(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid report ID"})
+	}
+
+	var input struct {
+		Format domain.ReportFormat `json:"format"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": result.Error.Error()})
+	}
+
+	report, err := h.reportService.Regenerate(tenantID, userID, reportID, input.Format)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Status(202).JSON(report)
+}
+
+// DownloadReport - Download the rendered report
+// GET /api/v1/reports/:id/download
+func (h *ReportHandler) DownloadReport(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid report ID"})
+	}
+
+	body, report, err := h.reportService.OpenArtifact(c.Context(), tenantID, reportID)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	contentType, filename := services.ReportFileType(report)
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	// Fiber closes the reader once the response has been streamed
+	return c.SendStream(body, int(report.Size))
+}
+
+// DeliverReport - Email a completed report to its distribution list, or to the given recipients
+// POST /api/v1/reports/:id/deliver
+func (h *ReportHandler) DeliverReport(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid report ID"})
+	}
+
+	var input struct {
+		Recipients []string `json:"recipients"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+		}
+	}
+
+	report, err := h.reportService.Deliver(c.Context(), tenantID, reportID, input.Recipients)
+	if err != nil {
+		return reportError(c, err)
 	}
 
 	return c.JSON(report)
 }
 
-// GetReportStats retrieves report statistics
-func (h *ReportHandler) GetReportStats(c *fiber.Ctx) error {
-	type StatsResponse struct {
-		TotalReports    int `json:"total_reports"`
-		CompletedCount  int `json:"completed"`
-		GeneratingCount int `json:"generating"`
-		ScheduledCount  int `json:"scheduled"`
+// DeleteReport - Delete a report and its file
+// DELETE /api/v1/reports/:id
+func (h *ReportHandler) DeleteReport(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid report ID"})
 	}
 
-	// TODO: Calculate actual stats from database
-	stats := StatsResponse{
-		TotalReports:    6,
-		CompletedCount:  4,
-		GeneratingCount: 1,
-		ScheduledCount:  1,
+	if err := h.reportService.DeleteReport(c.Context(), tenantID, reportID); err != nil {
+		return reportError(c, err)
 	}
 
-	return c.JSON(stats)
+	return c.SendStatus(204)
+}
+
+// ListReportSchedules - List report schedules
+// GET /api/v1/reports/schedules
+func (h *ReportHandler) ListReportSchedules(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	schedules, err := h.reportService.ListSchedules(tenantID)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.JSON(schedules)
+}
+
+// GetReportSchedule - Get a report schedule
+// GET /api/v1/reports/schedules/:id
+func (h *ReportHandler) GetReportSchedule(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	scheduleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid schedule ID"})
+	}
+
+	schedule, err := h.reportService.GetSchedule(tenantID, scheduleID)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.JSON(schedule)
+}
+
+// CreateReportSchedule - Generate a report on a cron schedule and email it
+// POST /api/v1/reports/schedules
+func (h *ReportHandler) CreateReportSchedule(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+	var input services.ReportScheduleInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	schedule, err := h.reportService.CreateSchedule(tenantID, userID, input)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Status(201).JSON(schedule)
+}
+
+// UpdateReportSchedule - Replace the settings of a report schedule
+// PUT /api/v1/reports/schedules/:id
+func (h *ReportHandler) UpdateReportSchedule(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+	scheduleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid schedule ID"})
+	}
+
+	var input services.ReportScheduleInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	schedule, err := h.reportService.UpdateSchedule(tenantID, userID, scheduleID, input)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.JSON(schedule)
+}
+
+// DeleteReportSchedule - Delete a report schedule
+// DELETE /api/v1/reports/schedules/:id
+func (h *ReportHandler) DeleteReportSchedule(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	scheduleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid schedule ID"})
+	}
+
+	if err := h.reportService.DeleteSchedule(tenantID, scheduleID); err != nil {
+		return reportError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// RunReportSchedule - Generate a schedule's report now
+// POST /api/v1/reports/schedules/:id/run
+func (h *ReportHandler) RunReportSchedule(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	scheduleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid schedule ID"})
+	}
+
+	report, err := h.reportService.RunSchedule(tenantID, scheduleID)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Status(202).JSON(report)
+}
+
+// reportError maps report service errors to HTTP responses
+func reportError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound), errors.Is(err, services.ErrReportScheduleNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidReport):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrReportNotReady):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrReportDelivery):
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var filterErr *filter.Error
+	if errors.As(err, &filterErr) {
+		return c.Status(400).JSON(fiber.Map{
+			"error":    "Invalid filter",
+			"details":  filterErr.Msg,
+			"position": filterErr.Pos,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": "Could not process report", "details": err.Error()})
 }
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
+)
+
+// ReviewScheduleHandler exposes the review cadence policy, review tasks, stale risks and calendar feeds
+type ReviewScheduleHandler struct {
+	reviewService *services.ReviewSchedulerService
+}
+
+// NewReviewScheduleHandler creates a new review schedule handler
+func NewReviewScheduleHandler(reviewService *services.ReviewSchedulerService) *ReviewScheduleHandler {
+	return &ReviewScheduleHandler{
+		reviewService: reviewService,
+	}
+}
+
+// GetPolicy - Get the review cadence policy
+// GET /api/v1/reviews/policy
+func (h *ReviewScheduleHandler) GetPolicy(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	return c.Status(200).JSON(h.reviewService.GetPolicy(tenantID))
+}
+
+// UpdatePolicy - Set the review frequency per risk level
+// PUT /api/v1/reviews/policy
+func (h *ReviewScheduleHandler) UpdatePolicy(c *fiber.Ctx) error {
+	type PolicyInput struct {
+		Critical  string `json:"critical" validate:"required"`
+		High      string `json:"high" validate:"required"`
+		Medium    string `json:"medium" validate:"required"`
+		Low       string `json:"low" validate:"required"`
+		LeadDays  int    `json:"lead_days" validate:"min=0,max=90"`
+		GraceDays int    `json:"grace_days" validate:"min=0,max=90"`
+	}
+
+	input := new(PolicyInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	policy, err := h.reviewService.UpdatePolicy(tenantID, userID, domain.ReviewCadencePolicy{
+		Critical:  domain.ReviewFrequency(input.Critical),
+		High:      domain.ReviewFrequency(input.High),
+		Medium:    domain.ReviewFrequency(input.Medium),
+		Low:       domain.ReviewFrequency(input.Low),
+		LeadDays:  input.LeadDays,
+		GraceDays: input.GraceDays,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to update review policy", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(policy)
+}
+
+// GetSchedule - Computed review schedule of every open risk
+// GET /api/v1/reviews/schedule
+func (h *ReviewScheduleHandler) GetSchedule(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	schedule, err := h.reviewService.ComputeSchedule(tenantID, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to compute review schedule", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(schedule)
+}
+
+// GetStaleRisks - Risks not reviewed within their cadence
+// GET /api/v1/reviews/stale
+func (h *ReviewScheduleHandler) GetStaleRisks(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	stale, err := h.reviewService.StaleRisks(tenantID, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to compute stale risks", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"total": len(stale),
+		"risks": stale,
+	})
+}
+
+// ListTasks - List review tasks (mine=true restricts to the current user)
+// GET /api/v1/reviews/tasks?status=OPEN&mine=true
+func (h *ReviewScheduleHandler) ListTasks(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+
+	var assignee *uuid.UUID
+	if c.QueryBool("mine") {
+		assignee = &userID
+	}
+
+	tasks, err := h.reviewService.ListTasks(tenantID, assignee, strings.ToUpper(c.Query("status")))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list review tasks", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(tasks)
+}
+
+// CreateCalendarFeed - Issue an iCalendar subscription URL for the current user or one of their teams
+// POST /api/v1/reviews/calendar-feeds
+func (h *ReviewScheduleHandler) CreateCalendarFeed(c *fiber.Ctx) error {
+	type FeedInput struct {
+		OwnerType string `json:"owner_type" validate:"required,oneof=USER TEAM"`
+		TeamID    string `json:"team_id" validate:"required_if=OwnerType TEAM,omitempty,uuid"`
+	}
+
+	input := new(FeedInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	ownerID := userID
+	if input.OwnerType == string(domain.CalendarFeedTeam) {
+		ownerID, _ = uuid.Parse(input.TeamID)
+	}
+
+	feed, token, err := h.reviewService.CreateCalendarFeed(tenantID, domain.CalendarFeedOwner(input.OwnerType), ownerID, userID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to create calendar feed", "details": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"feed":  feed,
+		"token": token,
+		"url":   c.BaseURL() + "/api/v1/calendar/reviews/" + token + ".ics",
+	})
+}
+
+// ListCalendarFeeds - List the calendar feeds created by the current user
+// GET /api/v1/reviews/calendar-feeds
+func (h *ReviewScheduleHandler) ListCalendarFeeds(c *fiber.Ctx) error {
+	tenantID, userID := requestActor(c)
+
+	feeds, err := h.reviewService.ListCalendarFeeds(tenantID, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list calendar feeds", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(feeds)
+}
+
+// RevokeCalendarFeed - Disable a calendar feed URL
+// DELETE /api/v1/reviews/calendar-feeds/:id
+func (h *ReviewScheduleHandler) RevokeCalendarFeed(c *fiber.Ctx) error {
+	feedID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid calendar feed ID"})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	if err := h.reviewService.RevokeCalendarFeed(tenantID, feedID, userID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Calendar feed not found"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// GetCalendarFeed - Serve a calendar feed; the secret token in the URL authenticates the request
+// GET /api/v1/calendar/reviews/:token.ics
+func (h *ReviewScheduleHandler) GetCalendarFeed(c *fiber.Ctx) error {
+	token := strings.TrimSuffix(c.Params("token"), ".ics")
+
+	calendar, err := h.reviewService.RenderCalendarFeed(token, time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrCalendarFeedNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Calendar feed not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to render calendar feed", "details": err.Error()})
+	}
+
+	c.Set("Content-Type", "text/calendar; charset=utf-8")
+	c.Set("Content-Disposition", "inline; filename=openrisk-reviews.ics")
+	return c.SendString(calendar)
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
+)
+
+// RiskExceptionHandler exposes the risk acceptance / exception lifecycle
+type RiskExceptionHandler struct {
+	exceptionService *services.RiskExceptionService
+}
+
+// NewRiskExceptionHandler creates a new risk exception handler
+func NewRiskExceptionHandler(exceptionService *services.RiskExceptionService) *RiskExceptionHandler {
+	return &RiskExceptionHandler{
+		exceptionService: exceptionService,
+	}
+}
+
+// reminderRecipients returns the keys an owner can be addressed by (user ID and email)
+func reminderRecipients(c *fiber.Ctx) []string {
+	_, userID := requestActor(c)
+	recipients := []string{userID.String()}
+	if claims, ok := c.Locals("user").(*domain.UserClaims); ok && claims.Email != "" {
+		recipients = append(recipients, claims.Email)
+	}
+	return recipients
+}
+
+func exceptionError(c *fiber.Ctx, message string, err error) error {
+	if errors.Is(err, services.ErrApprovalPending) {
+		return c.Status(409).JSON(fiber.Map{"error": "Exception is awaiting workflow approval", "details": err.Error()})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": message, "details": err.Error()})
+}
+
+// CreateException - Request a time-boxed risk acceptance
+// POST /api/v1/exceptions
+func (h *RiskExceptionHandler) CreateException(c *fiber.Ctx) error {
+	type ExceptionInput struct {
+		RiskID               string                       `json:"risk_id" validate:"required,uuid4"`
+		DecisionID           string                       `json:"decision_id" validate:"omitempty,uuid4"`
+		Title                string                       `json:"title" validate:"required"`
+		Justification        string                       `json:"justification" validate:"required"`
+		Owner                string                       `json:"owner"`
+		ValidFrom            time.Time                    `json:"valid_from"`
+		ExpiresAt            time.Time                    `json:"expires_at" validate:"required"`
+		ReminderDays         []int64                      `json:"reminder_days"`
+		CompensatingControls []domain.CompensatingControl `json:"compensating_controls"`
+		AssetIDs             []string                     `json:"asset_ids" validate:"dive,uuid"`
+	}
+
+	input := new(ExceptionInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	exception := &domain.RiskException{
+		Title:         input.Title,
+		Justification: input.Justification,
+		Owner:         input.Owner,
+		ValidFrom:     input.ValidFrom,
+		ExpiresAt:     input.ExpiresAt,
+		ReminderDays:  input.ReminderDays,
+	}
+	exception.RiskID, _ = uuid.Parse(input.RiskID)
+	if input.DecisionID != "" {
+		decisionID, _ := uuid.Parse(input.DecisionID)
+		exception.DecisionID = &decisionID
+	}
+
+	assetIDs := make([]uuid.UUID, 0, len(input.AssetIDs))
+	for _, id := range input.AssetIDs {
+		parsed, _ := uuid.Parse(id)
+		assetIDs = append(assetIDs, parsed)
+	}
+
+	created, err := h.exceptionService.CreateException(tenantID, userID, exception, input.CompensatingControls, assetIDs)
+	if err != nil {
+		return exceptionError(c, "Failed to create exception", err)
+	}
+
+	return c.Status(201).JSON(created)
+}
+
+// ListExceptions - List exceptions
+// GET /api/v1/exceptions?status=ACTIVE&risk_id=<uuid>
+func (h *RiskExceptionHandler) ListExceptions(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	var riskID *uuid.UUID
+	if raw := c.Query("risk_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+		}
+		riskID = &parsed
+	}
+
+	exceptions, err := h.exceptionService.ListExceptions(tenantID, strings.ToUpper(c.Query("status")), riskID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list exceptions", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(exceptions)
+}
+
+// GetException - Get an exception with its risk and scoped assets
+// GET /api/v1/exceptions/:id
+func (h *RiskExceptionHandler) GetException(c *fiber.Ctx) error {
+	exceptionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid exception ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+
+	exception, err := h.exceptionService.GetException(tenantID, exceptionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Exception not found"})
+	}
+
+	return c.Status(200).JSON(exception)
+}
+
+// ApproveException - Approve a pending exception or re-certification (single-step approval)
+// POST /api/v1/exceptions/:id/approve
+func (h *RiskExceptionHandler) ApproveException(c *fiber.Ctx) error {
+	exceptionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid exception ID"})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	exception, err := h.exceptionService.ApproveException(tenantID, exceptionID, userID)
+	if err != nil {
+		return exceptionError(c, "Failed to approve exception", err)
+	}
+
+	return c.Status(200).JSON(exception)
+}
+
+// RejectException - Reject a pending exception or re-certification (single-step approval)
+// POST /api/v1/exceptions/:id/reject
+func (h *RiskExceptionHandler) RejectException(c *fiber.Ctx) error {
+	type RejectInput struct {
+		Reason string `json:"reason"`
+	}
+
+	exceptionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid exception ID"})
+	}
+
+	input := new(RejectInput)
+	_ = c.BodyParser(input)
+
+	tenantID, _ := requestActor(c)
+
+	exception, err := h.exceptionService.RejectException(tenantID, exceptionID, input.Reason)
+	if err != nil {
+		return exceptionError(c, "Failed to reject exception", err)
+	}
+
+	return c.Status(200).JSON(exception)
+}
+
+// RecertifyException - Request an extension of an exception, subject to fresh approval
+// POST /api/v1/exceptions/:id/recertify
+func (h *RiskExceptionHandler) RecertifyException(c *fiber.Ctx) error {
+	type RecertifyInput struct {
+		ExpiresAt time.Time `json:"expires_at" validate:"required"`
+		Reason    string    `json:"reason" validate:"required"`
+	}
+
+	exceptionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid exception ID"})
+	}
+
+	input := new(RecertifyInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	exception, err := h.exceptionService.Recertify(tenantID, exceptionID, input.ExpiresAt, input.Reason, userID)
+	if err != nil {
+		return exceptionError(c, "Failed to re-certify exception", err)
+	}
+
+	return c.Status(200).JSON(exception)
+}
+
+// RevokeException - End an active exception early
+// POST /api/v1/exceptions/:id/revoke
+func (h *RiskExceptionHandler) RevokeException(c *fiber.Ctx) error {
+	type RevokeInput struct {
+		Reason string `json:"reason" validate:"required"`
+	}
+
+	exceptionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid exception ID"})
+	}
+
+	input := new(RevokeInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	exception, err := h.exceptionService.RevokeException(tenantID, exceptionID, userID, input.Reason)
+	if err != nil {
+		return exceptionError(c, "Failed to revoke exception", err)
+	}
+
+	return c.Status(200).JSON(exception)
+}
+
+// ListMyReminders - List expiry reminders addressed to the current user
+// GET /api/v1/exceptions/reminders?unread=true
+func (h *RiskExceptionHandler) ListMyReminders(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	reminders, err := h.exceptionService.ListReminders(tenantID, reminderRecipients(c), c.QueryBool("unread"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list reminders", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(reminders)
+}
+
+// AcknowledgeReminder - Mark a reminder as read
+// POST /api/v1/exceptions/reminders/:id/ack
+func (h *RiskExceptionHandler) AcknowledgeReminder(c *fiber.Ctx) error {
+	reminderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid reminder ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+
+	if err := h.exceptionService.AcknowledgeReminder(tenantID, reminderID, reminderRecipients(c)); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Reminder not found"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// GetExceptionRegister - Exceptions register report (active and expiring exceptions)
+// GET /api/v1/exceptions/register?expiring_days=30&format=json|csv
+func (h *RiskExceptionHandler) GetExceptionRegister(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	expiringDays := c.QueryInt("expiring_days", 30)
+	if expiringDays <= 0 || expiringDays > 365 {
+		expiringDays = 30
+	}
+
+	register, err := h.exceptionService.GetExceptionRegister(tenantID, expiringDays, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build exceptions register", "details": err.Error()})
+	}
+
+	switch c.Query("format", "json") {
+	case "csv":
+		return h.exportRegisterCSV(c, register)
+	case "json":
+		return c.Status(200).JSON(register)
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "unsupported format"})
+	}
+}
+
+// exportRegisterCSV writes the exceptions register as CSV
+func (h *RiskExceptionHandler) exportRegisterCSV(c *fiber.Ctx, register *services.ExceptionRegister) error {
+	c.Set("Content-Disposition", "attachment; filename=exceptions-register-"+register.GeneratedAt.Format("2006-01-02")+".csv")
+	c.Set("Content-Type", "text/csv")
+
+	w := csv.NewWriter(c)
+	_ = w.Write([]string{"Exception", "Risk", "Score", "Owner", "Expires At", "Days Remaining", "Expiring", "Recertifications", "Compensating Controls", "Assets"})
+	for _, entry := range register.Entries {
+		controls := make([]string, 0, len(entry.CompensatingControls))
+		for _, control := range entry.CompensatingControls {
+			controls = append(controls, control.Name)
+		}
+		_ = w.Write([]string{
+			entry.Title,
+			entry.RiskTitle,
+			strconv.FormatFloat(entry.RiskScore, 'f', 2, 64),
+			entry.Owner,
+			entry.ExpiresAt.Format(time.RFC3339),
+			strconv.Itoa(entry.DaysRemaining),
+			strconv.FormatBool(entry.Expiring),
+			strconv.Itoa(entry.RecertificationCount),
+			strings.Join(controls, "; "),
+			strings.Join(entry.Assets, "; "),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/opendefender/openrisk/database"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// riskFilterContext evaluates filter macros as the current user
+func riskFilterContext(c *fiber.Ctx) *services.RiskFilterContext {
+	fctx := &services.RiskFilterContext{Now: time.Now()}
+	if claims, ok := c.Locals("user").(*domain.UserClaims); ok && claims != nil {
+		fctx.UserID = claims.ID
+		fctx.Email = claims.Email
+	}
+	return fctx
+}
+
+// compileRiskFilter compiles an expression for the current user, over the
+// register as of the "as_of" param when given
+func compileRiskFilter(c *fiber.Ctx, expression string) (*services.RiskFilter, error) {
+	asOf, err := services.ParseAsOf(c.Query("as_of"), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return compileRiskFilterAsOf(c, expression, asOf)
+}
+
+// compileRiskFilterAsOf compiles an expression for the current user over the
+// register as of a date, the current one when nil
+func compileRiskFilterAsOf(c *fiber.Ctx, expression string, asOf *time.Time) (*services.RiskFilter, error) {
+	fctx := riskFilterContext(c)
+	fctx.AsOf = asOf
+	return services.NewRiskFilterService().Compile(expression, fctx)
+}
+
+// requestRiskFilter compiles the risk selection of a request: the "filter"
+// expression, the legacy q, status, min_score, max_score and tag params and
+// any extra expressions (e.g. a saved view's filter)
+func requestRiskFilter(c *fiber.Ctx, extra ...string) (*services.RiskFilter, error) {
+	legacy := map[string]interface{}{}
+	for _, key := range []string{"q", "status", "min_score", "max_score", "tag"} {
+		if v := c.Query(key); v != "" {
+			legacy[key] = v
+		}
+	}
+
+	exprs := append([]string{c.Query("filter"), services.LegacyRiskFilter(legacy)}, extra...)
+	return compileRiskFilter(c, filter.And(exprs...))
+}
+
+// riskFilterError reports an invalid filter with the position of the error
+func riskFilterError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrInvalidAsOf) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	var filterErr *filter.Error
+	if errors.As(err, &filterErr) {
+		return c.Status(400).JSON(fiber.Map{
+			"error":    "Invalid filter",
+			"details":  filterErr.Msg,
+			"position": filterErr.Pos,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": "Could not apply filter", "details": err.Error()})
+}
+
+// GetRiskFilterFields - Fields, operators and macros of the risk filter language
+// GET /api/v1/risks/filter/fields
+func GetRiskFilterFields(c *fiber.Ctx) error {
+	schema, err := services.NewRiskFilterService().Schema()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not load filter fields"})
+	}
+
+	return c.Status(200).JSON(schema.Fields())
+}
+
+// ValidateRiskFilter - Check a filter expression and count the risks it selects,
+// the count a bulk operation with the same filter will process
+// POST /api/v1/risks/filter/validate
+func ValidateRiskFilter(c *fiber.Ctx) error {
+	var input struct {
+		Filter string `json:"filter"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	riskFilter, err := compileRiskFilter(c, input.Filter)
+	if err != nil {
+		var filterErr *filter.Error
+		if errors.As(err, &filterErr) {
+			return c.Status(200).JSON(fiber.Map{
+				"valid":    false,
+				"error":    filterErr.Msg,
+				"position": filterErr.Pos,
+			})
+		}
+		return riskFilterError(c, err)
+	}
+
+	var total int64
+	if err := riskFilter.Scope(database.DB.Model(&domain.Risk{})).Count(&total).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not count risks"})
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"valid":  true,
+		"filter": riskFilter.Expression,
+		"total":  total,
+	})
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -11,6 +13,8 @@ import (
 	"github.com/opendefender/openrisk/internal/core/domain"
 	"github.com/opendefender/openrisk/internal/services"
 	"github.com/opendefender/openrisk/internal/validation"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateRiskInput : DTO pour séparer la logique API de la logique DB
@@ -18,11 +22,19 @@ import (
 type CreateRiskInput struct {
 	Title       string   `json:"title" validate:"required"`
 	Description string   `json:"description"`
-	Impact      int      `json:"impact" validate:"required,min=1,max=5"`
-	Probability int      `json:"probability" validate:"required,min=1,max=5"`
+	Impact      int      `json:"impact" validate:"omitempty,min=1,max=5"`
+	Probability int      `json:"probability" validate:"omitempty,min=1,max=5"`
 	Tags        []string `json:"tags"`
 	AssetIDs    []string `json:"asset_ids"` // Liste des UUIDs des assets concernés
 	Frameworks  []string `json:"frameworks"`
+	// Vecteur CVSS : Impact et Probability en sont alors dérivés
+	CVSSVector string `json:"cvss_vector" validate:"omitempty,max=200"`
+	// Perte annuelle attendue (ALE), dans la devise du tenant
+	ExpectedLoss float64 `json:"expected_loss" validate:"omitempty,min=0"`
+	// Techniques MITRE ATT&CK qui réalisent le risque (T1566, T1059.001)
+	Techniques []string `json:"techniques" validate:"omitempty,max=100"`
+	// Marquage TLP du risque partagé en STIX (CLEAR, GREEN, AMBER, AMBER+STRICT, RED)
+	TLP string `json:"tlp"`
 	// New validation tags will be added here
 	// Example: Tags        []string `json:"tags" validate:"omitempty,dive,required"`
 	// Example: AssetIDs    []string `json:"asset_ids" validate:"omitempty,dive,uuid4"`
@@ -38,6 +50,15 @@ type UpdateRiskInput struct {
 	Tags        []string `json:"tags" validate:"omitempty,dive,required"`
 	AssetIDs    []string `json:"asset_ids" validate:"omitempty,dive,uuid4"`
 	Frameworks  []string `json:"frameworks" validate:"omitempty,dive,required"`
+	// Vecteur CVSS ; une chaîne vide le retire. S'il change, ou si les assets
+	// changent, Impact et Probability en sont dérivés à nouveau
+	CVSSVector *string `json:"cvss_vector" validate:"omitempty,max=200"`
+	// Perte annuelle attendue (ALE) ; zéro la retire
+	ExpectedLoss *float64 `json:"expected_loss" validate:"omitempty,min=0"`
+	// Techniques MITRE ATT&CK ; une liste vide les retire
+	Techniques *[]string `json:"techniques"`
+	// Marquage TLP ; une chaîne vide revient au marquage par défaut
+	TLP *string `json:"tlp"`
 	// New validation tags will be added here
 	// Example: Tags        []string `json:"tags" validate:"omitempty,dive,required"`
 	// Example: AssetIDs    []string `json:"asset_ids" validate:"omitempty,dive,uuid4"`
@@ -46,6 +67,7 @@ type UpdateRiskInput struct {
 // CreateRisk godoc
 // @Summary Créer un nouveau risque
 // @Description Ajoute un risque, calcule son score et lie les assets.
+// @Description Avec un vecteur CVSS, Impact et Probability en sont dérivés par la politique du tenant.
 func CreateRisk(c *fiber.Ctx) error {
 	input := new(CreateRiskInput)
 
@@ -67,20 +89,24 @@ func CreateRisk(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Title is required"})
 	}
 
-	if input.Impact < 1 || input.Impact > 5 {
-		return c.Status(400).JSON(fiber.Map{"error": "Impact must be between 1 and 5"})
-	}
-	if input.Probability < 1 || input.Probability > 5 {
-		return c.Status(400).JSON(fiber.Map{"error": "Probability must be between 1 and 5"})
+	if input.CVSSVector == "" {
+		if input.Impact < 1 || input.Impact > 5 {
+			return c.Status(400).JSON(fiber.Map{"error": "Impact must be between 1 and 5"})
+		}
+		if input.Probability < 1 || input.Probability > 5 {
+			return c.Status(400).JSON(fiber.Map{"error": "Probability must be between 1 and 5"})
+		}
 	}
 
 	// 3. Mapping DTO -> Domain Entity
 	risk := domain.Risk{
-		Title:       input.Title,
-		Description: input.Description,
-		Impact:      input.Impact,
-		Probability: input.Probability,
-		Status:      domain.StatusDraft, // Statut par défaut
+		Title:        input.Title,
+		Description:  input.Description,
+		Impact:       input.Impact,
+		Probability:  input.Probability,
+		CVSSVector:   input.CVSSVector,
+		ExpectedLoss: input.ExpectedLoss,
+		Status:       domain.StatusDraft, // Statut par défaut
 	}
 
 	// Only set Tags if provided to avoid inserting NULL into databases that
@@ -94,6 +120,21 @@ func CreateRisk(c *fiber.Ctx) error {
 		risk.Frameworks = input.Frameworks
 	}
 
+	// ATT&CK techniques, checked against the catalog when loaded (optional)
+	if len(input.Techniques) > 0 {
+		techniques, err := services.NewAttackService(database.DB).NormalizeTechniques(input.Techniques)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		risk.Techniques = techniques
+	}
+
+	tlp, err := domain.NormalizeTLP(input.TLP)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	risk.TLP = tlp
+
 	// 3. Gestion des relations Assets (Many-to-Many)
 	if len(input.AssetIDs) > 0 {
 		var assets []*domain.Asset
@@ -107,7 +148,17 @@ func CreateRisk(c *fiber.Ctx) error {
 		risk.Assets = assets
 	}
 
-	// 4. Compute final score using asset criticality and save
+	// 4. Compute final score using asset criticality, raised by the business
+	// services that depend on the assets, and save
+	if err := services.NewImpactService(database.DB).ApplyEffectiveCriticality(risk.Assets); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to compute asset criticality"})
+	}
+	if risk.CVSSVector != "" {
+		tenantID, _ := requestActor(c)
+		if _, err := services.NewCVSSService(database.DB).ApplyToRisk(&risk, tenantID); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
 	final := services.ComputeRiskScore(risk.Impact, risk.Probability, risk.Assets)
 	risk.Score = final
 
@@ -125,6 +176,18 @@ func CreateRisk(c *fiber.Ctx) error {
 	if len(risk.Frameworks) == 0 {
 		omit = append(omit, "frameworks")
 	}
+	if risk.CVSSVector == "" {
+		omit = append(omit, "cvss_vector", "cvss_score")
+	}
+	if risk.ExpectedLoss == 0 {
+		omit = append(omit, "expected_loss")
+	}
+	if len(risk.Techniques) == 0 {
+		omit = append(omit, "techniques")
+	}
+	if risk.TLP == "" {
+		omit = append(omit, "tlp")
+	}
 	// custom_fields is datatypes.JSON in production; omit when nil/empty
 	omit = append(omit, "custom_fields")
 	// If the default Source value is present but not provided by caller, omit it for sqlite tests
@@ -132,12 +195,14 @@ func CreateRisk(c *fiber.Ctx) error {
 		omit = append(omit, "source")
 	}
 
+	// The request context attributes the history entry to the user
+	db := database.DB.WithContext(c.UserContext())
 	if len(omit) > 0 {
-		if err := database.DB.Omit(omit...).Create(&risk).Error; err != nil {
+		if err := db.Omit(omit...).Create(&risk).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Could not create risk"})
 		}
 	} else {
-		if err := database.DB.Create(&risk).Error; err != nil {
+		if err := db.Create(&risk).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Could not create risk"})
 		}
 	}
@@ -155,23 +220,39 @@ func CreateRisk(c *fiber.Ctx) error {
 // @Summary Lister tous les risques
 // @Description Récupère les risques triés par score décroissant (les plus critiques en premier).
 func GetRisks(c *fiber.Ctx) error {
-	var risks []domain.Risk
+	var risks []*domain.Risk
 
-	// Supported query params: q, status, min_score, max_score, tag
-	q := c.Query("q")
-	status := c.Query("status")
-	minScoreStr := c.Query("min_score")
-	maxScoreStr := c.Query("max_score")
-	tag := c.Query("tag")
+	// "view" applies a saved view: its filter is combined with the request's
+	// and its sort order is used unless sort_by is given
+	view, err := requestSavedView(c)
+	if err != nil {
+		return viewError(c, err)
+	}
+	var viewFilter string
+	if view != nil {
+		viewFilter = view.Filter
+	}
 
-	db := database.DB.Model(&domain.Risk{}).
-		Preload("Mitigations").
-		Preload("Mitigations.SubActions").
-		Preload("Assets")
+	// "filter" takes a filter expression; the older q, status, min_score,
+	// max_score and tag params are still accepted and combined with it.
+	// "as_of" lists the register as it was at that date.
+	riskFilter, err := requestRiskFilter(c, viewFilter)
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
+	db := riskFilter.Preload(database.DB.Model(&domain.Risk{}),
+		"Mitigations", "Mitigations.SubActions", "Assets")
 
 	// Server-side sorting: safe-guard allowed fields and map friendly names
 	sortBy := c.Query("sort_by")
 	sortDir := strings.ToLower(c.Query("sort_dir"))
+	if sortBy == "" && view != nil {
+		sortBy = view.SortBy
+		if sortDir == "" {
+			sortDir = view.SortDir
+		}
+	}
 	if sortDir != "asc" && sortDir != "desc" {
 		sortDir = "desc"
 	}
@@ -225,31 +306,7 @@ func GetRisks(c *fiber.Ctx) error {
 		}
 	}
 
-	if q != "" {
-		like := fmt.Sprintf("%%%s%%", q)
-		db = db.Where("title ILIKE ? OR description ILIKE ?", like, like)
-	}
-
-	if status != "" {
-		db = db.Where("status = ?", status)
-	}
-
-	if minScoreStr != "" {
-		if v, err := strconv.ParseFloat(minScoreStr, 64); err == nil {
-			db = db.Where("score >= ?", v)
-		}
-	}
-
-	if maxScoreStr != "" {
-		if v, err := strconv.ParseFloat(maxScoreStr, 64); err == nil {
-			db = db.Where("score <= ?", v)
-		}
-	}
-
-	if tag != "" {
-		// check membership in tags array
-		db = db.Where("? = ANY(tags)", tag)
-	}
+	db = riskFilter.Scope(db)
 
 	var total int64
 	if err := db.Count(&total).Error; err != nil {
@@ -261,8 +318,15 @@ func GetRisks(c *fiber.Ctx) error {
 	if result.Error != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch risks"})
 	}
+	if err := riskFilter.AttachVersions(database.DB, risks); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch risks"})
+	}
 
-	return c.JSON(fiber.Map{"items": risks, "total": total})
+	response := fiber.Map{"items": risks, "total": total}
+	if view != nil {
+		response["view"] = view
+	}
+	return c.JSON(response)
 }
 
 // GetRisk godoc
@@ -270,10 +334,27 @@ func GetRisks(c *fiber.Ctx) error {
 // @Description Détails complets d'un risque par ID.
 func GetRisk(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if _, err := uuid.Parse(id); err != nil {
+	riskID, err := uuid.Parse(id)
+	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
 	}
 
+	// "as_of" returns the version of the risk valid at that date
+	asOf, err := services.ParseAsOf(c.Query("as_of"), time.Now())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if asOf != nil {
+		risk, err := services.NewRiskVersionService(database.DB).GetRiskAsOf(riskID, *asOf)
+		if err != nil {
+			if errors.Is(err, services.ErrRiskVersionNotFound) {
+				return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": "Could not fetch risk"})
+		}
+		return c.JSON(risk)
+	}
+
 	var risk domain.Risk
 	result := database.DB.
 		Preload("Mitigations").
@@ -285,22 +366,40 @@ func GetRisk(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
 	}
 
+	setETag(c, int64(risk.Version))
 	return c.JSON(risk)
 }
 
+// loadRiskForResponse loads a risk with its relations, as returned by the API
+func loadRiskForResponse(id string) (*domain.Risk, error) {
+	var risk domain.Risk
+	if err := database.DB.Preload("Mitigations").Preload("Mitigations.SubActions").Preload("Assets").First(&risk, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &risk, nil
+}
+
+// riskConflict answers 412 with the current state of a risk changed since the
+// client read it
+func riskConflict(c *fiber.Ctx, id string) error {
+	current, err := loadRiskForResponse(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+	}
+	return preconditionFailed(c, current, int64(current.Version))
+}
+
 // UpdateRisk godoc
 // @Summary Mettre à jour un risque
 // @Description Mise à jour des champs (Titre, Score, Statut). Recalcule le score automatiquement.
+// @Description Avec If-Match, la mise à jour n'a lieu que si le risque est toujours à cette version (412 sinon).
 func UpdateRisk(c *fiber.Ctx) error {
 	id := c.Params("id")
-	var risk domain.Risk
-
-	// 1. Vérifier l'existence
-	if err := database.DB.First(&risk, "id = ?", id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+	if _, err := uuid.Parse(id); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
 	}
 
-	// 2. Parser les nouvelles données
+	// 1. Parser les nouvelles données
 	input := new(UpdateRiskInput)
 	if err := c.BodyParser(input); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
@@ -311,98 +410,187 @@ func UpdateRisk(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	// 3. Mise à jour des champs (uniquement si fournis)
-	if input.Title != "" {
-		risk.Title = input.Title
-	}
-	if input.Description != "" {
-		risk.Description = input.Description
-	}
-	if input.Status != "" {
-		risk.Status = domain.RiskStatus(input.Status)
-	}
-	if len(input.Tags) > 0 {
-		risk.Tags = input.Tags
-	}
+	err := database.DB.WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		// 2. Vérifier l'existence et la version ; le verrou sérialise les mises
+		// à jour concurrentes, la seconde voit la version produite par la première
+		var risk domain.Risk
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&risk, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := domain.CheckVersion(c.Get(fiber.HeaderIfMatch), int64(risk.Version)); err != nil {
+			return err
+		}
 
-	if len(input.Frameworks) > 0 {
-		risk.Frameworks = input.Frameworks
-	}
+		// 3. Mise à jour des champs (uniquement si fournis)
+		if input.Title != "" {
+			risk.Title = input.Title
+		}
+		if input.Description != "" {
+			risk.Description = input.Description
+		}
+		if input.Status != "" {
+			risk.Status = domain.RiskStatus(input.Status)
+		}
+		if len(input.Tags) > 0 {
+			risk.Tags = input.Tags
+		}
 
-	// If AssetIDs provided, reload and attach assets before computing score
-	if len(input.AssetIDs) > 0 {
-		var assets []*domain.Asset
-		if err := database.DB.Where("id IN ?", input.AssetIDs).Find(&assets).Error; err == nil {
-			risk.Assets = assets
+		if len(input.Frameworks) > 0 {
+			risk.Frameworks = input.Frameworks
 		}
-	}
 
-	// Si Impact ou Proba change, le hook BeforeSave recalculera le Score
-	if input.Impact != 0 {
-		risk.Impact = input.Impact
-	}
-	if input.Probability != 0 {
-		risk.Probability = input.Probability
-	}
+		techniquesChanged := false
+		if input.Techniques != nil {
+			techniques, err := services.NewAttackService(tx).NormalizeTechniques(*input.Techniques)
+			if err != nil {
+				return err
+			}
+			techniquesChanged = len(techniques) > 0 || len(risk.Techniques) > 0
+			risk.Techniques = techniques
+		}
 
-	// 4. Recompute score with assets criticality and save
-	final := services.ComputeRiskScore(risk.Impact, risk.Probability, risk.Assets)
-	risk.Score = final
+		expectedLossChanged := input.ExpectedLoss != nil && *input.ExpectedLoss != risk.ExpectedLoss
+		if input.ExpectedLoss != nil {
+			risk.ExpectedLoss = *input.ExpectedLoss
+		}
 
-	omit := []string{}
-	if len(input.Tags) == 0 {
-		omit = append(omit, "tags")
-	}
-	if risk.Owner == "" {
-		omit = append(omit, "owner")
-	}
-	if risk.ExternalID == "" {
-		omit = append(omit, "external_id")
-	}
-	if len(risk.Frameworks) == 0 {
-		omit = append(omit, "frameworks")
-	}
-	omit = append(omit, "custom_fields")
+		tlpChanged := false
+		if input.TLP != nil {
+			tlp, err := domain.NormalizeTLP(*input.TLP)
+			if err != nil {
+				return err
+			}
+			tlpChanged = tlp != risk.TLP
+			risk.TLP = tlp
+		}
 
-	if len(omit) > 0 {
-		if err := database.DB.Omit(omit...).Save(&risk).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Could not update risk"})
+		// If AssetIDs provided, reload and attach assets before computing score
+		if len(input.AssetIDs) > 0 {
+			var assets []*domain.Asset
+			if err := tx.Where("id IN ?", input.AssetIDs).Find(&assets).Error; err == nil {
+				risk.Assets = assets
+			}
 		}
-	} else {
-		if err := database.DB.Save(&risk).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Could not update risk"})
+
+		// Si Impact ou Proba change, le hook BeforeSave recalculera le Score
+		if input.Impact != 0 {
+			risk.Impact = input.Impact
+		}
+		if input.Probability != 0 {
+			risk.Probability = input.Probability
+		}
+
+		// Un nouveau vecteur CVSS, ou de nouveaux assets pour un risque qui en
+		// porte un, dérive à nouveau Impact et Probability
+		vectorChanged := input.CVSSVector != nil && *input.CVSSVector != risk.CVSSVector
+		if input.CVSSVector != nil {
+			risk.CVSSVector = *input.CVSSVector
+			if risk.CVSSVector == "" {
+				risk.CVSSScore = 0
+			}
+		}
+		derive := risk.CVSSVector != "" && (vectorChanged || len(input.AssetIDs) > 0)
+		if derive && len(input.AssetIDs) == 0 {
+			if err := tx.Model(&risk).Association("Assets").Find(&risk.Assets); err != nil {
+				return err
+			}
+		}
+
+		// 4. Recompute score with assets effective criticality and save
+		if err := services.NewImpactService(tx).ApplyEffectiveCriticality(risk.Assets); err != nil {
+			return err
+		}
+		if derive {
+			tenantID, _ := requestActor(c)
+			if _, err := services.NewCVSSService(tx).ApplyToRisk(&risk, tenantID); err != nil {
+				return err
+			}
+		}
+		final := services.ComputeRiskScore(risk.Impact, risk.Probability, risk.Assets)
+		risk.Score = final
+
+		omit := []string{}
+		if len(input.Tags) == 0 {
+			omit = append(omit, "tags")
+		}
+		if risk.Owner == "" {
+			omit = append(omit, "owner")
+		}
+		if risk.ExternalID == "" {
+			omit = append(omit, "external_id")
+		}
+		if len(risk.Frameworks) == 0 {
+			omit = append(omit, "frameworks")
 		}
+		if risk.CVSSVector == "" && !vectorChanged {
+			omit = append(omit, "cvss_vector", "cvss_score")
+		}
+		if risk.ExpectedLoss == 0 && !expectedLossChanged {
+			omit = append(omit, "expected_loss")
+		}
+		if len(risk.Techniques) == 0 && !techniquesChanged {
+			omit = append(omit, "techniques")
+		}
+		if risk.TLP == "" && !tlpChanged {
+			omit = append(omit, "tlp")
+		}
+		omit = append(omit, "custom_fields")
+
+		return tx.Omit(omit...).Save(&risk).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+	case errors.Is(err, domain.ErrVersionConflict):
+		return riskConflict(c, id)
+	case errors.Is(err, domain.ErrInvalidCVSSVector), errors.Is(err, services.ErrUnknownAttackID), errors.Is(err, domain.ErrInvalidTLP):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "Could not update risk"})
 	}
 
 	// Reload with relations for response
-	var out domain.Risk
-	if err := database.DB.Preload("Mitigations").Preload("Mitigations.SubActions").Preload("Assets").First(&out, "id = ?", id).Error; err != nil {
-		return c.JSON(risk)
+	out, err := loadRiskForResponse(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch risk"})
 	}
 
+	setETag(c, int64(out.Version))
 	return c.JSON(out)
 }
 
 // DeleteRisk godoc
 // @Summary Supprimer un risque
-// @Description Soft delete d'un risque.
+// @Description Soft delete d'un risque. Avec If-Match, seulement s'il est toujours à cette version (412 sinon).
 func DeleteRisk(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	// Validation UUID
-	if _, err := uuid.Parse(id); err != nil {
+	riskID, err := uuid.Parse(id)
+	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
 	}
 
-	// Delete avec GORM (Soft Delete par défaut grâce au champ DeletedAt dans le modèle)
-	result := database.DB.Delete(&domain.Risk{}, "id = ?", id)
-
-	if result.Error != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Could not delete risk"})
-	}
+	err = database.DB.WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		var risk domain.Risk
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&risk, "id = ?", riskID).Error; err != nil {
+			return err
+		}
+		if err := domain.CheckVersion(c.Get(fiber.HeaderIfMatch), int64(risk.Version)); err != nil {
+			return err
+		}
 
-	if result.RowsAffected == 0 {
+		// Delete avec GORM (Soft Delete par défaut grâce au champ DeletedAt dans le modèle).
+		// L'ID est porté par le modèle pour que le hook AfterDelete versionne la suppression.
+		return tx.Delete(&domain.Risk{ID: riskID}).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
 		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+	case errors.Is(err, domain.ErrVersionConflict):
+		return riskConflict(c, id)
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "Could not delete risk"})
 	}
 
 	return c.SendStatus(204) // No Content
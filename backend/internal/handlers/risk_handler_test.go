@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
@@ -38,16 +39,28 @@ type UserT struct {
 func (UserT) TableName() string { return "users" }
 
 type RiskT struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Title       string    `gorm:"size:255;not null"`
-	Description string    `gorm:"type:text"`
-	Impact      int
-	Probability int
-	Score       float64
-	Status      string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Title        string    `gorm:"size:255;not null"`
+	Description  string    `gorm:"type:text"`
+	Impact       int
+	Probability  int
+	Score        float64
+	Status       string
+	Tags         string
+	Owner        string
+	Source       string
+	ExternalID   string
+	CVSSVector   string  `gorm:"column:cvss_vector"`
+	CVSSScore    float64 `gorm:"column:cvss_score"`
+	ExpectedLoss float64
+	CustomFields string
+	Frameworks   string
+	Techniques   string
+	TLP          string `gorm:"column:tlp"`
+	Version      int    `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
 }
 
 func (RiskT) TableName() string { return "risks" }
@@ -64,17 +77,85 @@ type MitigationT struct {
 func (MitigationT) TableName() string { return "mitigations" }
 
 type AssetT struct {
-	ID   uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Name string
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name        string
+	Type        string
+	Criticality string
+	Owner       string
+	Description string
+	Tags        string
+	ParentID    *uuid.UUID
+	Source      string
+	ExternalID  string
+	RetiredAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
 }
 
 func (AssetT) TableName() string { return "assets" }
 
+type RiskAssetT struct {
+	RiskID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AssetID uuid.UUID `gorm:"type:uuid;primaryKey"`
+}
+
+func (RiskAssetT) TableName() string { return "risk_assets" }
+
+type RiskVersionT struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RiskID     uuid.UUID `gorm:"type:uuid;not null"`
+	Version    int       `gorm:"not null"`
+	ChangeType string    `gorm:"not null"`
+	Deleted    bool      `gorm:"not null;default:false"`
+	ValidFrom  time.Time `gorm:"not null"`
+	ValidTo    *time.Time
+	Snapshot   string `gorm:"not null"`
+	Checksum   string `gorm:"not null"`
+}
+
+func (RiskVersionT) TableName() string { return "risk_versions" }
+
+type DeletedItemT struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ResourceType string    `gorm:"not null;uniqueIndex:idx_deleted_items_resource"`
+	ResourceID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_deleted_items_resource"`
+	Name         string
+	DeletedBy    string
+	DeletedAt    time.Time `gorm:"not null"`
+	Cascade      string
+}
+
+func (DeletedItemT) TableName() string { return "deleted_items" }
+
+type AuditLogT struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID       *uuid.UUID
+	Action       string
+	Resource     string
+	ResourceID   *uuid.UUID
+	Result       string
+	ErrorMessage string
+	IPAddress    *string
+	UserAgent    string
+	Duration     int64
+	Timestamp    time.Time
+}
+
+func (AuditLogT) TableName() string { return "audit_logs" }
+
 type RiskHistoryT struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	RiskID    uuid.UUID
-	Score     float64
-	CreatedAt time.Time
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RiskID      uuid.UUID
+	Score       float64
+	Impact      int
+	Probability int
+	Status      string
+	Version     int
+	Changes     string
+	ChangedBy   string
+	ChangeType  string
+	CreatedAt   time.Time
 }
 
 func (RiskHistoryT) TableName() string { return "risk_histories" }
@@ -86,8 +167,24 @@ func setupAppWithDB(t *testing.T) *fiber.App {
 		t.Fatalf("failed to open sqlite: %v", err)
 	}
 
+	// sqlite has no gen_random_uuid(): generate the UUID primary keys Postgres would
+	if err := db.Callback().Create().Before("gorm:create").Register("test:uuid_primary_key", func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil || tx.Statement.ReflectValue.Kind() != reflect.Struct {
+			return
+		}
+		field := tx.Statement.Schema.PrioritizedPrimaryField
+		if field == nil || field.FieldType != reflect.TypeOf(uuid.UUID{}) {
+			return
+		}
+		if _, zero := field.ValueOf(tx.Statement.Context, tx.Statement.ReflectValue); zero {
+			tx.Statement.SetColumn(field.Name, uuid.New())
+		}
+	}); err != nil {
+		t.Fatalf("failed to register uuid callback: %v", err)
+	}
+
 	// migrate schema using test-only structs
-	if err := db.AutoMigrate(&UserT{}, &RiskT{}, &MitigationT{}, &AssetT{}, &RiskHistoryT{}); err != nil {
+	if err := db.AutoMigrate(&UserT{}, &RiskT{}, &MitigationT{}, &AssetT{}, &RiskAssetT{}, &RiskHistoryT{}, &RiskVersionT{}, &DeletedItemT{}, &AuditLogT{}); err != nil {
 		t.Fatalf("auto migrate failed: %v", err)
 	}
 
@@ -134,6 +231,10 @@ func TestRiskCRUDFlow(t *testing.T) {
 	if getResp.StatusCode != 200 {
 		t.Fatalf("expected 200 got %d", getResp.StatusCode)
 	}
+	etag := getResp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected an ETag on get")
+	}
 
 	// 3. Update risk
 	updatePayload := map[string]interface{}{"title": "Updated", "impact": 5}
@@ -150,9 +251,22 @@ func TestRiskCRUDFlow(t *testing.T) {
 	if updated.Title != "Updated" || updated.Impact != 5 {
 		t.Fatalf("update did not apply: %+v", updated)
 	}
+	if upResp.Header.Get("ETag") == etag {
+		t.Fatalf("expected the update to change the ETag")
+	}
+
+	// 4. Update with the ETag read before the update
+	staleReq := httptest.NewRequest(http.MethodPatch, "/api/v1/risks/"+created.ID.String(), bytes.NewReader(ub))
+	staleReq.Header.Set("Content-Type", "application/json")
+	staleReq.Header.Set("If-Match", etag)
+	staleResp, _ := app.Test(staleReq)
+	if staleResp.StatusCode != 412 {
+		t.Fatalf("expected 412 on stale update got %d", staleResp.StatusCode)
+	}
 
-	// 4. Delete
+	// 5. Delete
 	delReq := httptest.NewRequest(http.MethodDelete, "/api/v1/risks/"+created.ID.String(), nil)
+	delReq.Header.Set("If-Match", upResp.Header.Get("ETag"))
 	delResp, _ := app.Test(delReq)
 	if delResp.StatusCode != 204 {
 		t.Fatalf("expected 204 on delete got %d", delResp.StatusCode)
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
 	"github.com/opendefender/openrisk/internal/services"
 	"github.com/opendefender/openrisk/internal/validation"
 )
@@ -40,8 +42,7 @@ func (h *RiskManagementHandler) IdentifyRisk(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 
 	riskID, _ := uuid.Parse(input.RiskID)
 
@@ -86,8 +87,7 @@ func (h *RiskManagementHandler) AnalyzeRisk(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 
 	riskRegisterID, _ := uuid.Parse(input.RiskRegisterID)
 
@@ -132,8 +132,7 @@ func (h *RiskManagementHandler) EvaluateRisk(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 
 	riskRegisterID, _ := uuid.Parse(input.RiskRegisterID)
 
@@ -179,8 +178,7 @@ func (h *RiskManagementHandler) CreateTreatmentPlan(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 
 	riskRegisterID, _ := uuid.Parse(input.RiskRegisterID)
 	responsiblePersonID, _ := uuid.Parse(input.ResponsiblePersonID)
@@ -231,8 +229,7 @@ func (h *RiskManagementHandler) AddTreatmentAction(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 	treatmentPlanID := c.Params("id")
 
 	treatmentPlanUUID, _ := uuid.Parse(treatmentPlanID)
@@ -279,8 +276,7 @@ func (h *RiskManagementHandler) CreateMonitoringReview(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 
 	riskRegisterID, _ := uuid.Parse(input.RiskRegisterID)
 
@@ -327,8 +323,7 @@ func (h *RiskManagementHandler) RecordDecision(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 
 	riskRegisterID, _ := uuid.Parse(input.RiskRegisterID)
 
@@ -354,11 +349,30 @@ func (h *RiskManagementHandler) RecordDecision(c *fiber.Ctx) error {
 	})
 }
 
-// ApproveDecision - Approve Recorded Decision
+// GetDecision - Get a Recorded Decision, with its ETag
+// GET /api/v1/risk-management/decisions/:id
+func (h *RiskManagementHandler) GetDecision(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+
+	decisionUUID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid decision ID"})
+	}
+
+	decision, err := h.riskMgmtService.GetDecision(tenantID, decisionUUID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Decision not found"})
+	}
+
+	setETag(c, services.DecisionVersion(decision))
+	return c.JSON(decision)
+}
+
+// ApproveDecision - Approve Recorded Decision; with If-Match, only if the
+// decision is still at that version (412 with the current decision otherwise)
 // POST /api/v1/risk-management/decisions/:id/approve
 func (h *RiskManagementHandler) ApproveDecision(c *fiber.Ctx) error {
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 	decisionID := c.Params("id")
 
 	decisionUUID, err := uuid.Parse(decisionID)
@@ -366,13 +380,25 @@ func (h *RiskManagementHandler) ApproveDecision(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid decision ID"})
 	}
 
-	if err := h.riskMgmtService.ApproveDecision(tenantID, decisionUUID, userID); err != nil {
+	decision, err := h.riskMgmtService.ApproveDecision(tenantID, decisionUUID, userID, c.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		if errors.Is(err, domain.ErrVersionConflict) {
+			return preconditionFailed(c, decision, services.DecisionVersion(decision))
+		}
+		if errors.Is(err, services.ErrApprovalPending) {
+			return c.Status(409).JSON(fiber.Map{"error": "Decision is awaiting workflow approval", "details": err.Error()})
+		}
+		if errors.Is(err, services.ErrApprovalNotGranted) {
+			return c.Status(409).JSON(fiber.Map{"error": "Decision was not approved by its workflow", "details": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to approve decision", "details": err.Error()})
 	}
 
+	setETag(c, services.DecisionVersion(decision))
 	return c.Status(200).JSON(fiber.Map{
-		"message": "Decision approved successfully",
-		"status":  "APPROVED",
+		"message":  "Decision approved successfully",
+		"status":   "APPROVED",
+		"decision": decision,
 	})
 }
 
@@ -395,8 +421,7 @@ func (h *RiskManagementHandler) GenerateAuditReport(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
 	}
 
-	tenantID := c.Locals("tenant_id").(uuid.UUID)
-	userID := c.Locals("user_id").(uuid.UUID)
+	tenantID, userID := requestActor(c)
 
 	startDate, _ := time.Parse("2006-01-02", input.ReportingStartDate)
 	endDate, _ := time.Parse("2006-01-02", input.ReportingEndDate)
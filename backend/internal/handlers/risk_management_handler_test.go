@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// Test-only approval tables: the domain ones default their IDs with gen_random_uuid()
+type ApprovalWorkflowT struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TenantID   uuid.UUID
+	EntityType string
+	IsActive   bool
+	Priority   int
+	CreatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+func (ApprovalWorkflowT) TableName() string { return "approval_workflows" }
+
+type ApprovalRequestT struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TenantID   uuid.UUID
+	EntityType string
+	EntityID   uuid.UUID
+	Status     string
+	CreatedAt  time.Time
+}
+
+func (ApprovalRequestT) TableName() string { return "approval_requests" }
+
+// setupRiskManagementApp routes the risk management endpoints the server exposes.
+// Requests carry only what the auth middleware sets: a user and a role, no tenant.
+func setupRiskManagementApp(t *testing.T) (*fiber.App, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file:risk_management?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&domain.RiskRegister{},
+		&domain.RiskTreatmentPlan{},
+		&domain.RiskDecision{},
+		&domain.RiskChangeLog{},
+		&ApprovalWorkflowT{},
+		&ApprovalRequestT{},
+	); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	h := NewRiskManagementHandler(services.NewRiskManagementService(db))
+	userID := uuid.New()
+
+	app := fiber.New()
+	api := app.Group("/api/v1", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		c.Locals("role", "admin")
+		return c.Next()
+	})
+	api.Post("/risk-management/treatment-plans", h.CreateTreatmentPlan)
+	api.Post("/risk-management/decisions", h.RecordDecision)
+	api.Get("/risk-management/decisions/:id", h.GetDecision)
+	api.Post("/risk-management/decisions/:id/approve", h.ApproveDecision)
+
+	return app, db
+}
+
+func postJSON(t *testing.T, app *fiber.App, path string, payload interface{}) *http.Response {
+	b, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request to %s failed: %v", path, err)
+	}
+	return resp
+}
+
+func TestRiskManagementRoutesWithoutTenant(t *testing.T) {
+	app, db := setupRiskManagementApp(t)
+
+	register := domain.RiskRegister{ID: uuid.New(), RiskID: uuid.New(), RiskOwner: uuid.New(), Status: "IDENTIFIED"}
+	if err := db.Create(&register).Error; err != nil {
+		t.Fatalf("failed to seed risk register: %v", err)
+	}
+
+	resp := postJSON(t, app, "/api/v1/risk-management/treatment-plans", map[string]interface{}{
+		"risk_register_id":      register.ID.String(),
+		"treatment_type":        "MITIGATE",
+		"treatment_name":        "Patch",
+		"description":           "Patch the exposed hosts",
+		"responsible_person_id": uuid.New().String(),
+		"start_date":            "2026-01-01",
+		"end_date":              "2026-02-01",
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected 201 on treatment plan got %d", resp.StatusCode)
+	}
+
+	resp = postJSON(t, app, "/api/v1/risk-management/decisions", map[string]interface{}{
+		"risk_register_id": register.ID.String(),
+		"decision_type":    "ACCEPT",
+		"decision_title":   "Accept residual risk",
+		"description":      "Residual risk is within appetite",
+		"rationale":        "Compensating controls in place",
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected 201 on decision got %d", resp.StatusCode)
+	}
+	var recorded struct {
+		Decision domain.RiskDecision `json:"decision"`
+	}
+	json.NewDecoder(resp.Body).Decode(&recorded)
+	path := "/api/v1/risk-management/decisions/" + recorded.Decision.ID.String()
+
+	getResp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatalf("get decision failed: %v", err)
+	}
+	if getResp.StatusCode != 200 {
+		t.Fatalf("expected 200 on get decision got %d", getResp.StatusCode)
+	}
+	if getResp.Header.Get("ETag") == "" {
+		t.Fatalf("expected an ETag on get decision")
+	}
+
+	resp = postJSON(t, app, path+"/approve", nil)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 on approve got %d", resp.StatusCode)
+	}
+}
+
+func TestApproveDecisionIfMatch(t *testing.T) {
+	app, db := setupRiskManagementApp(t)
+
+	register := domain.RiskRegister{ID: uuid.New(), RiskID: uuid.New(), RiskOwner: uuid.New(), Status: "IDENTIFIED"}
+	if err := db.Create(&register).Error; err != nil {
+		t.Fatalf("failed to seed risk register: %v", err)
+	}
+
+	resp := postJSON(t, app, "/api/v1/risk-management/decisions", map[string]interface{}{
+		"risk_register_id": register.ID.String(),
+		"decision_type":    "ACCEPT",
+		"decision_title":   "Accept residual risk",
+		"description":      "Residual risk is within appetite",
+		"rationale":        "Compensating controls in place",
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected 201 on decision got %d", resp.StatusCode)
+	}
+	var recorded struct {
+		Decision domain.RiskDecision `json:"decision"`
+	}
+	json.NewDecoder(resp.Body).Decode(&recorded)
+	path := "/api/v1/risk-management/decisions/" + recorded.Decision.ID.String()
+
+	getResp, _ := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	etag := getResp.Header.Get("ETag")
+
+	// A decision saved since it was read is not approved
+	if err := db.Model(&recorded.Decision).Update("rationale", "Revised rationale").Error; err != nil {
+		t.Fatalf("failed to update decision: %v", err)
+	}
+	staleReq := httptest.NewRequest(http.MethodPost, path+"/approve", nil)
+	staleReq.Header.Set("If-Match", etag)
+	staleResp, _ := app.Test(staleReq)
+	if staleResp.StatusCode != 412 {
+		t.Fatalf("expected 412 on stale approve got %d", staleResp.StatusCode)
+	}
+	current := staleResp.Header.Get("ETag")
+	if current == etag {
+		t.Fatalf("expected the 412 to carry the current ETag")
+	}
+
+	okReq := httptest.NewRequest(http.MethodPost, path+"/approve", nil)
+	okReq.Header.Set("If-Match", current)
+	okResp, _ := app.Test(okReq)
+	if okResp.StatusCode != 200 {
+		t.Fatalf("expected 200 on approve got %d", okResp.StatusCode)
+	}
+	if okResp.Header.Get("ETag") == current {
+		t.Fatalf("expected the approval to change the ETag")
+	}
+}
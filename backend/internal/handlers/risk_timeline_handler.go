@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
@@ -50,7 +53,7 @@ func (h *RiskTimelineHandler) GetRiskTimeline(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"timeline": timeline,
+		"timeline": ConvertToTimelineEvents(timeline),
 		"total":    total,
 		"limit":    limit,
 		"offset":   offset,
@@ -76,7 +79,7 @@ func (h *RiskTimelineHandler) GetStatusChanges(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"changes": changes,
+		"changes": ConvertToTimelineEvents(changes),
 		"count":   len(changes),
 	})
 }
@@ -99,7 +102,7 @@ func (h *RiskTimelineHandler) GetScoreChanges(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"changes": changes,
+		"changes": ConvertToTimelineEvents(changes),
 		"count":   len(changes),
 	})
 }
@@ -150,7 +153,7 @@ func (h *RiskTimelineHandler) GetChangesByType(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"change_type": changeType,
-		"changes":     changes,
+		"changes":     ConvertToTimelineEvents(changes),
 		"count":       len(changes),
 	})
 }
@@ -171,7 +174,7 @@ func (h *RiskTimelineHandler) GetRecentActivity(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"activity": activity,
+		"activity": ConvertToTimelineEvents(activity),
 		"count":    len(activity),
 		"limit":    limit,
 	})
@@ -203,35 +206,81 @@ func (h *RiskTimelineHandler) GetChangesSince(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"since":   since,
-		"changes": changes,
+		"changes": ConvertToTimelineEvents(changes),
 		"count":   len(changes),
 	})
 }
 
-// TimelineEvent represents a single event in the timeline
+// TimelineEvent represents a single event in the timeline: who changed what,
+// with the old and new value of each field, and the key values after it
 type TimelineEvent struct {
-	ID          uuid.UUID         `json:"id"`
-	RiskID      uuid.UUID         `json:"risk_id"`
-	Score       float64           `json:"score"`
-	Impact      int               `json:"impact"`
-	Probability int               `json:"probability"`
-	Status      domain.RiskStatus `json:"status"`
-	ChangeType  string            `json:"change_type"`
-	ChangedBy   string            `json:"changed_by"`
-	CreatedAt   int64             `json:"timestamp"`
+	ID          uuid.UUID                `json:"id"`
+	RiskID      uuid.UUID                `json:"risk_id"`
+	Version     int                      `json:"version,omitempty"`
+	Score       float64                  `json:"score"`
+	Impact      int                      `json:"impact"`
+	Probability int                      `json:"probability"`
+	Status      domain.RiskStatus        `json:"status"`
+	ChangeType  string                   `json:"change_type"`
+	ChangedBy   string                   `json:"changed_by"`
+	Changes     []domain.RiskFieldChange `json:"changes"`
+	Summary     string                   `json:"summary"`
+	CreatedAt   int64                    `json:"timestamp"`
 }
 
 // ConvertToTimelineEvent converts RiskHistory to TimelineEvent
 func ConvertToTimelineEvent(h *domain.RiskHistory) *TimelineEvent {
+	changes, err := h.FieldChanges()
+	if err != nil || changes == nil {
+		changes = []domain.RiskFieldChange{}
+	}
 	return &TimelineEvent{
 		ID:          h.ID,
 		RiskID:      h.RiskID,
+		Version:     h.Version,
 		Score:       h.Score,
 		Impact:      h.Impact,
 		Probability: h.Probability,
 		Status:      h.Status,
 		ChangeType:  h.ChangeType,
 		ChangedBy:   h.ChangedBy,
+		Changes:     changes,
+		Summary:     summarizeChanges(h.ChangeType, changes),
 		CreatedAt:   h.CreatedAt.Unix(),
 	}
 }
+
+// ConvertToTimelineEvents converts history entries, keeping their order
+func ConvertToTimelineEvents(history []*domain.RiskHistory) []*TimelineEvent {
+	events := make([]*TimelineEvent, 0, len(history))
+	for _, h := range history {
+		events = append(events, ConvertToTimelineEvent(h))
+	}
+	return events
+}
+
+// summarizeChanges renders a diff as one line, e.g.
+// "UPDATE: status ACTIVE → MITIGATED; tags [] → [GDPR]"
+func summarizeChanges(changeType string, changes []domain.RiskFieldChange) string {
+	if len(changes) == 0 {
+		return changeType
+	}
+	parts := make([]string, 0, len(changes))
+	for _, c := range changes {
+		parts = append(parts, fmt.Sprintf("%s %s → %s", c.Field, timelineValue(c.From), timelineValue(c.To)))
+	}
+	return changeType + ": " + strings.Join(parts, "; ")
+}
+
+func timelineValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "∅"
+	case string:
+		if v == "" {
+			return "∅"
+		}
+		return v
+	}
+	return fmt.Sprint(v)
+}
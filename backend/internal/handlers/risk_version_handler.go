@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// RiskVersionHandler serves the version history of risks and the comparison
+// of the register between two dates
+type RiskVersionHandler struct {
+	versionService *services.RiskVersionService
+}
+
+// NewRiskVersionHandler creates a new risk version handler
+func NewRiskVersionHandler(versionService *services.RiskVersionService) *RiskVersionHandler {
+	return &RiskVersionHandler{versionService: versionService}
+}
+
+// ListRiskVersions - Versions of a risk, latest first, each with the full
+// snapshot of the risk, its mitigations and assets
+// GET /api/v1/risks/:id/versions
+func (h *RiskVersionHandler) ListRiskVersions(c *fiber.Ctx) error {
+	riskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+	}
+
+	versions, err := h.versionService.ListVersions(riskID)
+	if err != nil {
+		if errors.Is(err, services.ErrRiskVersionNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch risk versions"})
+	}
+	return c.JSON(versions)
+}
+
+// DiffRegister - Compare the risks a filter selects at two dates: added,
+// removed and changed risks with the fields that changed. "to" defaults to
+// now.
+// GET /api/v1/risks/diff?from=2026-01-01&to=2026-06-30&filter=...
+func (h *RiskVersionHandler) DiffRegister(c *fiber.Ctx) error {
+	now := time.Now()
+	if c.Query("from") == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Missing required param: from"})
+	}
+	from, err := services.ParseAsOf(c.Query("from"), now)
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+	to := &now
+	if c.Query("to") != "" {
+		if to, err = services.ParseAsOf(c.Query("to"), now); err != nil {
+			return riskFilterError(c, err)
+		}
+	}
+
+	// The same expression is compiled over both dates
+	fromFilter, err := compileRiskFilterAsOf(c, c.Query("filter"), from)
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+	toFilter, err := compileRiskFilterAsOf(c, c.Query("filter"), to)
+	if err != nil {
+		return riskFilterError(c, err)
+	}
+
+	diff, err := h.versionService.Diff(c.Context(), fromFilter, toFilter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAsOf) {
+			return riskFilterError(c, err)
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Could not compare the register", "details": err.Error()})
+	}
+	return c.JSON(diff)
+}
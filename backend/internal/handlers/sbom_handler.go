@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/adapters/osv"
+	"github.com/opendefender/openrisk/internal/adapters/sbom"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// SBOMHandler exposes SBOM ingestion, the component inventory of assets, the
+// vulnerability feed and the license policies
+type SBOMHandler struct {
+	sbomService *services.SBOMService
+}
+
+// NewSBOMHandler creates a new SBOM handler
+func NewSBOMHandler(sbomService *services.SBOMService) *SBOMHandler {
+	return &SBOMHandler{
+		sbomService: sbomService,
+	}
+}
+
+// uploadBody returns the multipart field "file" or, failing that, the
+// request body, with the uploaded file name
+func uploadBody(c *fiber.Ctx) (io.ReadCloser, string, error) {
+	if header, err := c.FormFile("file"); err == nil {
+		file, err := header.Open()
+		if err != nil {
+			return nil, "", errors.New("could not read uploaded file")
+		}
+		return file, header.Filename, nil
+	}
+	if len(c.Body()) == 0 {
+		return nil, "", errors.New("missing upload (multipart field \"file\" or request body)")
+	}
+	return io.NopCloser(bytes.NewReader(c.Body())), "", nil
+}
+
+// UploadSBOM - Upload a CycloneDX (JSON or XML) or SPDX (JSON or tag-value)
+// SBOM for an asset; it replaces the asset's component inventory, which is
+// matched against the vulnerability feed and the license policies
+// POST /api/v1/assets/:id/sbom
+func (h *SBOMHandler) UploadSBOM(c *fiber.Ctx) error {
+	assetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid asset ID"})
+	}
+	body, fileName, err := uploadBody(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer body.Close()
+
+	parsed, err := sbom.Parse(body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	parsed.FileName = fileName
+
+	doc, result, err := h.sbomService.ImportSBOM(c.UserContext(), assetID, parsed.SBOMDocument, parsed.Components)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"sbom":   doc,
+		"result": result,
+	})
+}
+
+// GetAssetSBOMs - SBOMs uploaded for an asset, most recent first
+// GET /api/v1/assets/:id/sboms
+func (h *SBOMHandler) GetAssetSBOMs(c *fiber.Ctx) error {
+	assetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid asset ID"})
+	}
+
+	docs, err := h.sbomService.ListSBOMs(assetID)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(docs)
+}
+
+// GetAssetComponents - Software components an asset ships, from its last SBOM
+// GET /api/v1/assets/:id/components
+func (h *SBOMHandler) GetAssetComponents(c *fiber.Ctx) error {
+	assetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid asset ID"})
+	}
+
+	components, err := h.sbomService.ListComponents(assetID)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(components)
+}
+
+// GetAssetVulnerabilities - Vulnerabilities found on the components of an asset
+// GET /api/v1/assets/:id/vulnerabilities
+func (h *SBOMHandler) GetAssetVulnerabilities(c *fiber.Ctx) error {
+	assetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid asset ID"})
+	}
+
+	found, err := h.sbomService.ListAssetVulnerabilities(assetID)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(found)
+}
+
+// SearchComponents - Components shipped by assets, e.g. which assets ship
+// log4j-core < 2.17: ?name=log4j-core&version=<2.17
+// GET /api/v1/software-components?name=&ecosystem=&version=&license=&limit=50&offset=0
+func (h *SBOMHandler) SearchComponents(c *fiber.Ctx) error {
+	filter := services.ComponentFilter{
+		Name:      c.Query("name"),
+		Ecosystem: c.Query("ecosystem"),
+		License:   c.Query("license"),
+		Limit:     c.QueryInt("limit", 50),
+		Offset:    c.QueryInt("offset", 0),
+	}
+	if filter.Name == "" && filter.License == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name or license is required"})
+	}
+	if filter.Limit < 1 || filter.Limit > 500 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if raw := c.Query("version"); raw != "" {
+		constraint, err := domain.ParseVersionConstraint(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		filter.Constraint = constraint
+	}
+
+	components, total, assetCount, err := h.sbomService.SearchComponents(filter)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"items":       components,
+		"total":       total,
+		"asset_count": assetCount,
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+	})
+}
+
+// ImportOSV - Load an OSV dump (all.zip of an ecosystem, an advisory or a
+// JSON array of advisories) into the vulnerability feed and match every
+// asset again
+// POST /api/v1/vulnerability-feeds/osv
+func (h *SBOMHandler) ImportOSV(c *fiber.Ctx) error {
+	body, _, err := uploadBody(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer body.Close()
+
+	vulns, err := osv.Read(body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	result, err := h.sbomService.ImportVulnerabilities(c.UserContext(), vulns)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"loaded": len(vulns),
+		"result": result,
+	})
+}
+
+// GetVulnerability - An advisory of the vulnerability feed with its packages
+// GET /api/v1/vulnerabilities/:id
+func (h *SBOMHandler) GetVulnerability(c *fiber.Ctx) error {
+	vuln, err := h.sbomService.GetVulnerability(c.Params("id"))
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(vuln)
+}
+
+// GetLicensePolicies - Licenses forbidden in shipped components
+// GET /api/v1/license-policies
+func (h *SBOMHandler) GetLicensePolicies(c *fiber.Ctx) error {
+	policies, err := h.sbomService.ListLicensePolicies()
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(policies)
+}
+
+// CreateLicensePolicy - Forbid a license; assets are matched again
+// POST /api/v1/license-policies
+func (h *SBOMHandler) CreateLicensePolicy(c *fiber.Ctx) error {
+	var policy domain.LicensePolicy
+	if err := c.BodyParser(&policy); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	result, err := h.sbomService.CreateLicensePolicy(c.UserContext(), &policy)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"policy": policy,
+		"result": result,
+	})
+}
+
+// DeleteLicensePolicy - Allow a license again; the compliance risks it raised are resolved
+// DELETE /api/v1/license-policies/:id
+func (h *SBOMHandler) DeleteLicensePolicy(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid policy ID"})
+	}
+
+	result, err := h.sbomService.DeleteLicensePolicy(c.UserContext(), id)
+	if err != nil {
+		return sbomError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{"result": result})
+}
+
+func sbomError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrEmptySBOM), errors.Is(err, services.ErrInvalidLicensePolicy):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAssetNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Asset not found"})
+	case errors.Is(err, services.ErrVulnerabilityNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Vulnerability not found"})
+	case errors.Is(err, services.ErrLicensePolicyNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "License policy not found"})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "SBOM operation failed", "details": err.Error()})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// SchedulerHandler exposes the cluster scheduler to administrators
+type SchedulerHandler struct {
+	scheduler *services.SchedulerService
+}
+
+// NewSchedulerHandler creates a new scheduler handler
+func NewSchedulerHandler(scheduler *services.SchedulerService) *SchedulerHandler {
+	return &SchedulerHandler{
+		scheduler: scheduler,
+	}
+}
+
+// ListJobs - List periodic jobs with their schedule, next run and last outcome
+// GET /api/v1/scheduler/jobs
+func (h *SchedulerHandler) ListJobs(c *fiber.Ctx) error {
+	jobs, err := h.scheduler.ListJobs()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list scheduled jobs", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(jobs)
+}
+
+// GetJob - Get a periodic job
+// GET /api/v1/scheduler/jobs/:name
+func (h *SchedulerHandler) GetJob(c *fiber.Ctx) error {
+	job, err := h.scheduler.GetJob(c.Params("name"))
+	return h.jobResponse(c, job, err)
+}
+
+// ListRuns - Run history of a periodic job
+// GET /api/v1/scheduler/jobs/:name/runs?limit=50
+func (h *SchedulerHandler) ListRuns(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	runs, err := h.scheduler.ListRuns(c.Params("name"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrScheduledJobNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Scheduled job not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list runs", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(runs)
+}
+
+// TriggerJob - Run a periodic job on the next scheduler tick
+// POST /api/v1/scheduler/jobs/:name/trigger
+func (h *SchedulerHandler) TriggerJob(c *fiber.Ctx) error {
+	_, userID := requestActor(c)
+	job, err := h.scheduler.TriggerNow(c.Params("name"), userID)
+	if err == nil {
+		return c.Status(202).JSON(job)
+	}
+	return h.jobResponse(c, job, err)
+}
+
+// PauseJob - Stop scheduled runs of a periodic job
+// POST /api/v1/scheduler/jobs/:name/pause
+func (h *SchedulerHandler) PauseJob(c *fiber.Ctx) error {
+	_, userID := requestActor(c)
+	job, err := h.scheduler.Pause(c.Params("name"), userID)
+	return h.jobResponse(c, job, err)
+}
+
+// ResumeJob - Resume a paused periodic job from its next scheduled time
+// POST /api/v1/scheduler/jobs/:name/resume
+func (h *SchedulerHandler) ResumeJob(c *fiber.Ctx) error {
+	job, err := h.scheduler.Resume(c.Params("name"))
+	return h.jobResponse(c, job, err)
+}
+
+func (h *SchedulerHandler) jobResponse(c *fiber.Ctx, job *domain.ScheduledJob, err error) error {
+	if err != nil {
+		if errors.Is(err, services.ErrScheduledJobNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Scheduled job not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Scheduler operation failed", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(job)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/opendefender/openrisk/internal/adapters/stix"
+	"github.com/opendefender/openrisk/internal/adapters/taxii"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// TAXIIHandler serves the TAXII 2.1 collections of risks. Readers
+// authenticate with the token of a collection, as HTTP Basic password or
+// bearer token, and only see that collection.
+type TAXIIHandler struct {
+	intelService *services.ThreatIntelService
+}
+
+// NewTAXIIHandler creates a new TAXII handler
+func NewTAXIIHandler(intelService *services.ThreatIntelService) *TAXIIHandler {
+	return &TAXIIHandler{
+		intelService: intelService,
+	}
+}
+
+// Discovery - TAXII server discovery
+// GET /api/v1/taxii2/
+func (h *TAXIIHandler) Discovery(c *fiber.Ctx) error {
+	if _, err := h.collection(c); err != nil {
+		return taxiiError(c, err)
+	}
+	apiRoot := c.BaseURL() + "/api/v1/taxii2/api/"
+	return taxiiJSON(c, 200, taxii.Discovery{
+		Title:       "OpenRisk",
+		Description: "Risks shared as STIX 2.1 vulnerabilities",
+		Default:     apiRoot,
+		APIRoots:    []string{apiRoot},
+	})
+}
+
+// APIRoot - The TAXII API root of the risk collections
+// GET /api/v1/taxii2/api/
+func (h *TAXIIHandler) APIRoot(c *fiber.Ctx) error {
+	if _, err := h.collection(c); err != nil {
+		return taxiiError(c, err)
+	}
+	return taxiiJSON(c, 200, taxii.APIRoot{
+		Title:    "OpenRisk risks",
+		Versions: []string{taxii.MediaType},
+	})
+}
+
+// Collections - The collection the token reads
+// GET /api/v1/taxii2/api/collections/
+func (h *TAXIIHandler) Collections(c *fiber.Ctx) error {
+	collection, err := h.collection(c)
+	if err != nil {
+		return taxiiError(c, err)
+	}
+	return taxiiJSON(c, 200, taxii.Collections{Collections: []taxii.Collection{taxiiCollection(collection)}})
+}
+
+// Collection - A collection, when the token reads it
+// GET /api/v1/taxii2/api/collections/:id/
+func (h *TAXIIHandler) Collection(c *fiber.Ctx) error {
+	collection, err := h.readable(c)
+	if err != nil {
+		return taxiiError(c, err)
+	}
+	return taxiiJSON(c, 200, taxiiCollection(collection))
+}
+
+// Objects - The STIX objects of the collection's risks updated after
+// added_after, a page of limit risks at a time
+// GET /api/v1/taxii2/api/collections/:id/objects/?added_after=&limit=100&next=&match[type]=&match[id]=
+func (h *TAXIIHandler) Objects(c *fiber.Ctx) error {
+	collection, err := h.readable(c)
+	if err != nil {
+		return taxiiError(c, err)
+	}
+
+	filter := services.RiskExportFilter{
+		TenantID: collection.TenantID,
+		Tags:     collection.Tags,
+		MaxTLP:   collection.MaxTLP,
+		Limit:    c.QueryInt("limit", 100),
+	}
+	if filter.Limit < 1 || filter.Limit > 1000 {
+		filter.Limit = 100
+	}
+	if value := c.Query("added_after"); value != "" {
+		addedAfter, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return taxiiJSON(c, 400, taxii.Error{Title: "Invalid added_after", HTTPStatus: "400"})
+		}
+		filter.AddedAfter = &addedAfter
+	}
+	if value := c.Query("next"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return taxiiJSON(c, 400, taxii.Error{Title: "Invalid next", HTTPStatus: "400"})
+		}
+		filter.Offset = offset
+	}
+
+	intel, err := h.intelService.ExportRisks(c.UserContext(), filter)
+	if err != nil {
+		return taxiiError(c, err)
+	}
+	objects, err := stix.ExportObjects(stix.ExportInput{
+		Risks:      intel.Risks,
+		Threats:    intel.Threats,
+		Techniques: intel.Techniques,
+		MaxTLP:     collection.MaxTLP,
+	})
+	if err != nil {
+		return taxiiError(c, err)
+	}
+	objects = matchObjects(objects, stringList(c.Query("match[type]")), stringList(c.Query("match[id]")))
+
+	envelope := taxii.Envelope{More: intel.More, Objects: objects}
+	if envelope.Objects == nil {
+		envelope.Objects = []json.RawMessage{}
+	}
+	if intel.More {
+		envelope.Next = strconv.Itoa(filter.Offset + len(intel.Risks))
+	}
+	if len(intel.Risks) > 0 {
+		c.Set(taxii.HeaderDateAddedFirst, intel.Risks[0].UpdatedAt.UTC().Format(time.RFC3339Nano))
+		c.Set(taxii.HeaderDateAddedLast, intel.Risks[len(intel.Risks)-1].UpdatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	return taxiiJSON(c, 200, envelope)
+}
+
+// collection returns the collection of the request's token
+func (h *TAXIIHandler) collection(c *fiber.Ctx) (*domain.TAXIICollection, error) {
+	return h.intelService.CollectionForToken(taxiiToken(c))
+}
+
+// readable returns the collection of the path when the token reads it
+func (h *TAXIIHandler) readable(c *fiber.Ctx) (*domain.TAXIICollection, error) {
+	collection, err := h.collection(c)
+	if err != nil {
+		return nil, err
+	}
+	if collection.ID.String() != c.Params("id") {
+		return nil, errTAXIICollectionNotReadable
+	}
+	return collection, nil
+}
+
+var errTAXIICollectionNotReadable = errors.New("collection not found")
+
+// taxiiToken reads the token of a Basic password or a bearer token
+func taxiiToken(c *fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if encoded, ok := strings.CutPrefix(header, "Basic "); ok {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return ""
+		}
+		_, password, _ := strings.Cut(string(decoded), ":")
+		return password
+	}
+	return ""
+}
+
+func taxiiCollection(collection *domain.TAXIICollection) taxii.Collection {
+	return taxii.Collection{
+		ID:          collection.ID.String(),
+		Title:       collection.Title,
+		Description: collection.Description,
+		CanRead:     true,
+		MediaTypes:  []string{"application/stix+json;version=" + stix.SpecVersion},
+	}
+}
+
+// matchObjects keeps the objects of one of types and ids, when set
+func matchObjects(objects []json.RawMessage, types, ids []string) []json.RawMessage {
+	if len(types) == 0 && len(ids) == 0 {
+		return objects
+	}
+	var matched []json.RawMessage
+	for _, raw := range objects {
+		var o struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		}
+		if json.Unmarshal(raw, &o) != nil {
+			continue
+		}
+		if (len(types) == 0 || slices.Contains(types, o.Type)) && (len(ids) == 0 || slices.Contains(ids, o.ID)) {
+			matched = append(matched, raw)
+		}
+	}
+	return matched
+}
+
+func taxiiJSON(c *fiber.Ctx, status int, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, taxii.MediaType)
+	return c.Status(status).Send(data)
+}
+
+func taxiiError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTAXIICollectionNotFound):
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="OpenRisk TAXII"`)
+		return taxiiJSON(c, 401, taxii.Error{Title: "Unauthorized", HTTPStatus: "401"})
+	case errors.Is(err, errTAXIICollectionNotReadable):
+		return taxiiJSON(c, 404, taxii.Error{Title: "Collection not found", HTTPStatus: "404"})
+	default:
+		return taxiiJSON(c, 500, taxii.Error{Title: "TAXII request failed", Description: err.Error(), HTTPStatus: "500"})
+	}
+}
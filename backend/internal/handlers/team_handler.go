@@ -256,13 +256,8 @@ func DeleteTeam(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Team not found"})
 	}
 
-	// Delete team members first
-	if err := database.DB.Where("team_id = ?", team.ID).Delete(&domain.TeamMember{}).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete team members"})
-	}
-
-	// Delete team
-	if err := database.DB.Delete(&team).Error; err != nil {
+	// Delete team; its members go to the recycle bin with it (see Team.AfterDelete)
+	if err := database.DB.WithContext(c.UserContext()).Delete(&team).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete team"})
 	}
 
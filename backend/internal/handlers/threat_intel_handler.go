@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/adapters/stix"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
+)
+
+// ThreatIntelHandler exposes the STIX import and export of threat
+// intelligence, the links of threats to risks, the collections of the TAXII
+// server and the TAXII feeds polled for threats
+type ThreatIntelHandler struct {
+	intelService *services.ThreatIntelService
+}
+
+// NewThreatIntelHandler creates a new threat intelligence handler
+func NewThreatIntelHandler(intelService *services.ThreatIntelService) *ThreatIntelHandler {
+	return &ThreatIntelHandler{
+		intelService: intelService,
+	}
+}
+
+// ImportBundle - Import the indicators, vulnerabilities, attack patterns and
+// threat actors of a STIX 2.1 bundle as threats, linked to the given risks,
+// to shared risks they relate to, and to risks with their CVE or technique
+// POST /api/v1/stix/bundle?source=&default_tlp=AMBER&risk_ids=
+func (h *ThreatIntelHandler) ImportBundle(c *fiber.Ctx) error {
+	riskIDs, err := uuidList(c.Query("risk_ids"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk_ids"})
+	}
+	body, fileName, err := uploadBody(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer body.Close()
+
+	intel, err := stix.Read(body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	source := c.Query("source", fileName)
+	if source == "" {
+		source = "upload"
+	}
+
+	result, err := h.intelService.Import(c.UserContext(), intel, services.ThreatImportOptions{
+		Source:     source,
+		DefaultTLP: c.Query("default_tlp"),
+		RiskIDs:    riskIDs,
+	})
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(200).JSON(result)
+}
+
+// ExportBundle - Export risks as STIX 2.1 vulnerabilities with their
+// mitigations, techniques and threats; objects marked above max_tlp are left out
+// GET /api/v1/stix/bundle?risk_ids=&tags=&max_tlp=RED
+func (h *ThreatIntelHandler) ExportBundle(c *fiber.Ctx) error {
+	riskIDs, err := uuidList(c.Query("risk_ids"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk_ids"})
+	}
+	maxTLP, err := domain.NormalizeTLP(c.Query("max_tlp", domain.TLPRed))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if maxTLP == "" {
+		maxTLP = domain.TLPRed
+	}
+
+	intel, err := h.intelService.ExportRisks(c.UserContext(), services.RiskExportFilter{
+		RiskIDs: riskIDs,
+		Tags:    stringList(c.Query("tags")),
+		MaxTLP:  maxTLP,
+	})
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+	bundle, err := stix.Export(stix.ExportInput{
+		Risks:      intel.Risks,
+		Threats:    intel.Threats,
+		Techniques: intel.Techniques,
+		MaxTLP:     maxTLP,
+	})
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="openrisk-risks.stix.json"`)
+	return c.Status(200).JSON(bundle)
+}
+
+// GetThreats - Imported threats, most recently modified first
+// GET /api/v1/threat-intel/threats?type=&q=&tlp=&page=1&limit=50
+func (h *ThreatIntelHandler) GetThreats(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	tlp, err := domain.NormalizeTLP(c.Query("tlp"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	threats, total, err := h.intelService.ListThreats(services.ThreatFilter{
+		Type:   c.Query("type"),
+		Query:  c.Query("q"),
+		TLP:    tlp,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"threats": threats,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetThreat - A threat with the risks it is linked to
+// GET /api/v1/threat-intel/threats/:id
+func (h *ThreatIntelHandler) GetThreat(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid threat ID"})
+	}
+
+	threat, err := h.intelService.GetThreat(id)
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(200).JSON(threat)
+}
+
+// GetRiskThreats - Threats linked to a risk
+// GET /api/v1/risks/:id/threats
+func (h *ThreatIntelHandler) GetRiskThreats(c *fiber.Ctx) error {
+	riskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+	}
+
+	threats, err := h.intelService.RiskThreats(riskID)
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(200).JSON(threats)
+}
+
+// LinkRiskThreat - Link a threat to a risk
+// POST /api/v1/risks/:id/threats
+func (h *ThreatIntelHandler) LinkRiskThreat(c *fiber.Ctx) error {
+	type LinkInput struct {
+		ThreatID     string `json:"threat_id" validate:"required,uuid"`
+		Relationship string `json:"relationship" validate:"omitempty,max=30"`
+	}
+
+	riskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+	}
+	input := new(LinkInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	if err := h.intelService.LinkRisk(c.UserContext(), riskID, uuid.MustParse(input.ThreatID), input.Relationship); err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// UnlinkRiskThreat - Remove the link between a threat and a risk
+// DELETE /api/v1/risks/:id/threats/:threatId
+func (h *ThreatIntelHandler) UnlinkRiskThreat(c *fiber.Ctx) error {
+	riskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+	}
+	threatID, err := uuid.Parse(c.Params("threatId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid threat ID"})
+	}
+
+	if err := h.intelService.UnlinkRisk(c.UserContext(), riskID, threatID); err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// CreateCollection - Share the tenant's risks marked at most max_tlp, with
+// one of tags when given, as a collection of the TAXII server. The access
+// token of its readers is only returned once.
+// POST /api/v1/taxii-collections
+func (h *ThreatIntelHandler) CreateCollection(c *fiber.Ctx) error {
+	type CollectionInput struct {
+		Title       string   `json:"title" validate:"required,max=255"`
+		Description string   `json:"description"`
+		MaxTLP      string   `json:"max_tlp" validate:"required"`
+		Tags        []string `json:"tags" validate:"omitempty,dive,required"`
+	}
+
+	input := new(CollectionInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+	collection, token, err := h.intelService.CreateCollection(tenantID, userID, domain.TAXIICollection{
+		Title:       input.Title,
+		Description: input.Description,
+		MaxTLP:      input.MaxTLP,
+		Tags:        input.Tags,
+	})
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"collection": collection,
+		"token":      token,
+		"api_root":   c.BaseURL() + "/api/v1/taxii2/api/",
+	})
+}
+
+// GetCollections - The tenant's TAXII collections
+// GET /api/v1/taxii-collections
+func (h *ThreatIntelHandler) GetCollections(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	collections, err := h.intelService.ListCollections(tenantID)
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(200).JSON(collections)
+}
+
+// DeleteCollection - Stop sharing a TAXII collection; its token is revoked
+// DELETE /api/v1/taxii-collections/:id
+func (h *ThreatIntelHandler) DeleteCollection(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid collection ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+	if err := h.intelService.DeleteCollection(tenantID, id); err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// CreateFeed - Poll the collection of a TAXII 2.1 server for threats. A
+// password without username is sent as a bearer token.
+// POST /api/v1/threat-feeds
+func (h *ThreatIntelHandler) CreateFeed(c *fiber.Ctx) error {
+	type FeedInput struct {
+		Name         string     `json:"name" validate:"required,max=255"`
+		APIRoot      string     `json:"api_root" validate:"required,url,max=500"`
+		CollectionID string     `json:"collection_id" validate:"required,max=100"`
+		Username     string     `json:"username"`
+		Password     string     `json:"password"`
+		DefaultTLP   string     `json:"default_tlp"`
+		AddedAfter   *time.Time `json:"added_after"`
+	}
+
+	input := new(FeedInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+	feed, err := h.intelService.CreateFeed(tenantID, userID, domain.ThreatFeed{
+		Name:         input.Name,
+		APIRoot:      input.APIRoot,
+		CollectionID: input.CollectionID,
+		Username:     input.Username,
+		Password:     input.Password,
+		DefaultTLP:   input.DefaultTLP,
+		AddedAfter:   input.AddedAfter,
+	})
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(201).JSON(feed)
+}
+
+// GetFeeds - The tenant's TAXII threat feeds with the outcome of their last poll
+// GET /api/v1/threat-feeds
+func (h *ThreatIntelHandler) GetFeeds(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	feeds, err := h.intelService.ListFeeds(tenantID)
+	if err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.Status(200).JSON(feeds)
+}
+
+// DeleteFeed - Stop polling a threat feed; its threats are kept
+// DELETE /api/v1/threat-feeds/:id
+func (h *ThreatIntelHandler) DeleteFeed(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid feed ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+	if err := h.intelService.DeleteFeed(tenantID, id); err != nil {
+		return threatIntelError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// PollFeed - Import now the threats added to a feed since its last poll
+// POST /api/v1/threat-feeds/:id/poll
+func (h *ThreatIntelHandler) PollFeed(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid feed ID"})
+	}
+
+	tenantID, _ := requestActor(c)
+	result, err := h.intelService.PollFeed(c.UserContext(), tenantID, id)
+	if err != nil {
+		if errors.Is(err, services.ErrThreatFeedNotFound) {
+			return threatIntelError(c, err)
+		}
+		return c.Status(502).JSON(fiber.Map{"error": "Threat feed poll failed", "details": err.Error()})
+	}
+
+	return c.Status(200).JSON(result)
+}
+
+// uuidList parses a comma-separated list of UUIDs
+func uuidList(value string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, item := range stringList(value) {
+		id, err := uuid.Parse(item)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// stringList splits a comma-separated list, dropping empty items
+func stringList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func threatIntelError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrThreatNotFound), errors.Is(err, services.ErrThreatFeedNotFound),
+		errors.Is(err, services.ErrTAXIICollectionNotFound), errors.Is(err, services.ErrRiskNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, domain.ErrInvalidTLP), errors.Is(err, services.ErrInvalidThreatFeed),
+		errors.Is(err, services.ErrInvalidTAXIICollection):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Threat intelligence request failed", "details": err.Error()})
+	}
+}
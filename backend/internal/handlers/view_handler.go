@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/database"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
+	"github.com/opendefender/openrisk/internal/services"
+)
+
+// ViewHandler exposes saved views and dashboards
+type ViewHandler struct {
+	viewService *services.ViewService
+}
+
+// NewViewHandler creates a new view handler
+func NewViewHandler(viewService *services.ViewService) *ViewHandler {
+	return &ViewHandler{
+		viewService: viewService,
+	}
+}
+
+// viewActor is the current user as seen by the view service
+func viewActor(c *fiber.Ctx) services.ViewActor {
+	tenantID, userID := requestActor(c)
+	actor := services.ViewActor{TenantID: tenantID, UserID: userID, Admin: isAdmin(c)}
+	if claims, ok := c.Locals("user").(*domain.UserClaims); ok && claims != nil {
+		actor.Email = claims.Email
+	}
+	return actor
+}
+
+// requestSavedView loads the saved view named by the "view" query param, if any
+func requestSavedView(c *fiber.Ctx) (*domain.SavedView, error) {
+	raw := c.Query("view")
+	if raw == "" {
+		return nil, nil
+	}
+	viewID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, services.ErrViewNotFound
+	}
+	return services.NewViewService(database.DB).GetView(viewActor(c), viewID)
+}
+
+// ListViews - List the saved views visible to the user, their own first
+// GET /api/v1/views
+func (h *ViewHandler) ListViews(c *fiber.Ctx) error {
+	views, err := h.viewService.ListViews(viewActor(c))
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(views)
+}
+
+// GetView - Get a saved view
+// GET /api/v1/views/:id
+func (h *ViewHandler) GetView(c *fiber.Ctx) error {
+	viewID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid view ID"})
+	}
+
+	view, err := h.viewService.GetView(viewActor(c), viewID)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(view)
+}
+
+// CreateView - Save a named filter, sort and column set
+// POST /api/v1/views
+func (h *ViewHandler) CreateView(c *fiber.Ctx) error {
+	var input services.SavedViewInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	view, err := h.viewService.CreateView(viewActor(c), input)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(201).JSON(view)
+}
+
+// UpdateView - Replace a saved view (owner or admin)
+// PUT /api/v1/views/:id
+func (h *ViewHandler) UpdateView(c *fiber.Ctx) error {
+	viewID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid view ID"})
+	}
+
+	var input services.SavedViewInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	view, err := h.viewService.UpdateView(viewActor(c), viewID, input)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(view)
+}
+
+// DeleteView - Delete a saved view (owner or admin)
+// DELETE /api/v1/views/:id
+func (h *ViewHandler) DeleteView(c *fiber.Ctx) error {
+	viewID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid view ID"})
+	}
+
+	if err := h.viewService.DeleteView(viewActor(c), viewID); err != nil {
+		return viewError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// ExportViews - Download views and dashboards as a JSON bundle for another
+// tenant; with no IDs, everything the user owns is exported
+// POST /api/v1/views/export
+func (h *ViewHandler) ExportViews(c *fiber.Ctx) error {
+	var input struct {
+		ViewIDs      []uuid.UUID `json:"view_ids"`
+		DashboardIDs []uuid.UUID `json:"dashboard_ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+		}
+	}
+
+	bundle, err := h.viewService.Export(viewActor(c), input.ViewIDs, input.DashboardIDs)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=openrisk-views-%s.json", time.Now().Format("20060102")))
+	return c.Status(200).JSON(bundle)
+}
+
+// ImportViews - Create the views and dashboards of a JSON bundle; items that
+// do not validate against this tenant are reported and skipped
+// POST /api/v1/views/import
+func (h *ViewHandler) ImportViews(c *fiber.Ctx) error {
+	bundle, err := services.ParseViewBundle(c.Body())
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	result, err := h.viewService.Import(viewActor(c), bundle)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(201).JSON(result)
+}
+
+// ListDashboards - List the dashboards visible to the user (without widgets)
+// GET /api/v1/dashboards
+func (h *ViewHandler) ListDashboards(c *fiber.Ctx) error {
+	dashboards, err := h.viewService.ListDashboards(viewActor(c))
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(dashboards)
+}
+
+// GetDefaultDashboard - Get the user's home dashboard
+// GET /api/v1/dashboards/default
+func (h *ViewHandler) GetDefaultDashboard(c *fiber.Ctx) error {
+	dashboard, err := h.viewService.DefaultDashboard(viewActor(c))
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(dashboard)
+}
+
+// GetDashboard - Get a dashboard with its widgets
+// GET /api/v1/dashboards/:id
+func (h *ViewHandler) GetDashboard(c *fiber.Ctx) error {
+	dashboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid dashboard ID"})
+	}
+
+	dashboard, err := h.viewService.GetDashboard(viewActor(c), dashboardID)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(dashboard)
+}
+
+// CreateDashboard - Create a dashboard of widgets
+// POST /api/v1/dashboards
+func (h *ViewHandler) CreateDashboard(c *fiber.Ctx) error {
+	var input services.DashboardInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	dashboard, err := h.viewService.CreateDashboard(viewActor(c), input)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(201).JSON(dashboard)
+}
+
+// UpdateDashboard - Replace a dashboard and its widgets (owner or admin)
+// PUT /api/v1/dashboards/:id
+func (h *ViewHandler) UpdateDashboard(c *fiber.Ctx) error {
+	dashboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid dashboard ID"})
+	}
+
+	var input services.DashboardInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input", "details": err.Error()})
+	}
+
+	dashboard, err := h.viewService.UpdateDashboard(viewActor(c), dashboardID, input)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(dashboard)
+}
+
+// DeleteDashboard - Delete a dashboard (owner or admin)
+// DELETE /api/v1/dashboards/:id
+func (h *ViewHandler) DeleteDashboard(c *fiber.Ctx) error {
+	dashboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid dashboard ID"})
+	}
+
+	if err := h.viewService.DeleteDashboard(viewActor(c), dashboardID); err != nil {
+		return viewError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+// GetDashboardData - Render every widget of a dashboard for the current user
+// GET /api/v1/dashboards/:id/data
+func (h *ViewHandler) GetDashboardData(c *fiber.Ctx) error {
+	dashboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid dashboard ID"})
+	}
+
+	widgets, err := h.viewService.DashboardData(c.UserContext(), viewActor(c), dashboardID)
+	if err != nil {
+		return viewError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"dashboard_id": dashboardID,
+		"generated_at": time.Now(),
+		"widgets":      widgets,
+	})
+}
+
+func viewError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrViewNotFound), errors.Is(err, services.ErrDashboardNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrViewForbidden):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidView):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Dashboard errors name the widget whose filter is invalid
+	var filterErr *filter.Error
+	if errors.As(err, &filterErr) {
+		return c.Status(400).JSON(fiber.Map{
+			"error":    "Invalid filter",
+			"details":  err.Error(),
+			"position": filterErr.Pos,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": "Could not process view", "details": err.Error()})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/adapters/vulnfeeds"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/services"
+	"github.com/opendefender/openrisk/internal/validation"
+)
+
+// VulnEnrichmentHandler exposes the NVD, KEV and EPSS feeds, the enrichment
+// of risks referencing CVEs and its tenant policy
+type VulnEnrichmentHandler struct {
+	enrichmentService *services.VulnEnrichmentService
+}
+
+// NewVulnEnrichmentHandler creates a new vulnerability enrichment handler
+func NewVulnEnrichmentHandler(enrichmentService *services.VulnEnrichmentService) *VulnEnrichmentHandler {
+	return &VulnEnrichmentHandler{
+		enrichmentService: enrichmentService,
+	}
+}
+
+// ImportFeed - Load an NVD CVE JSON, the CISA KEV catalog (JSON or CSV) or an
+// EPSS score CSV, gzipped or not, then enrich the risks again
+// POST /api/v1/vulnerability-feeds/:source (nvd, kev, epss)
+func (h *VulnEnrichmentHandler) ImportFeed(c *fiber.Ctx) error {
+	body, fileName, err := uploadBody(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer body.Close()
+
+	feed, err := vulnfeeds.Read(c.Params("source"), body)
+	if errors.Is(err, vulnfeeds.ErrUnknownFeed) {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	feed.FileName = fileName
+
+	imported, err := h.enrichmentService.ImportFeed(c.UserContext(), feed.VulnFeedImport, feed.Records)
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+	result, err := h.enrichmentService.EnrichAll(c.UserContext())
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"import": imported,
+		"result": result,
+	})
+}
+
+// GetFeedImports - Last loads of the feeds, most recent first
+// GET /api/v1/vulnerability-feeds/imports?source=&limit=50
+func (h *VulnEnrichmentHandler) GetFeedImports(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	imports, err := h.enrichmentService.ListFeedImports(c.Query("source"), limit)
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+
+	return c.Status(200).JSON(imports)
+}
+
+// GetCVE - What the feeds know about a CVE: CVSS, KEV status and EPSS
+// GET /api/v1/cves/:id
+func (h *VulnEnrichmentHandler) GetCVE(c *fiber.Ctx) error {
+	record, err := h.enrichmentService.GetCVE(c.Params("id"))
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+
+	return c.Status(200).JSON(record)
+}
+
+// GetRiskEnrichment - Provenance of the last enrichment of a risk: its CVEs,
+// what the feeds said and why its probability moved
+// GET /api/v1/risks/:id/enrichment
+func (h *VulnEnrichmentHandler) GetRiskEnrichment(c *fiber.Ctx) error {
+	riskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+	}
+
+	enrichment, err := h.enrichmentService.GetRiskEnrichment(riskID)
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+
+	return c.Status(200).JSON(enrichment)
+}
+
+// EnrichRisk - Enrich a risk now
+// POST /api/v1/risks/:id/enrich
+func (h *VulnEnrichmentHandler) EnrichRisk(c *fiber.Ctx) error {
+	riskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid risk ID"})
+	}
+
+	enrichment, err := h.enrichmentService.EnrichRisk(c.UserContext(), riskID)
+	if errors.Is(err, services.ErrRiskNotEnriched) {
+		return c.Status(422).JSON(fiber.Map{"error": "Risk references no CVE"})
+	}
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+
+	return c.Status(200).JSON(enrichment)
+}
+
+// RunEnrichment - Enrich every risk referencing a CVE now
+// POST /api/v1/vulnerability-enrichment/run
+func (h *VulnEnrichmentHandler) RunEnrichment(c *fiber.Ctx) error {
+	result, err := h.enrichmentService.EnrichAll(c.UserContext())
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+
+	return c.Status(200).JSON(result)
+}
+
+// GetPolicy - Get the enrichment policy of the tenant
+// GET /api/v1/vulnerability-enrichment/policy
+func (h *VulnEnrichmentHandler) GetPolicy(c *fiber.Ctx) error {
+	tenantID, _ := requestActor(c)
+	return c.Status(200).JSON(h.enrichmentService.GetPolicy(tenantID))
+}
+
+// UpdatePolicy - Set whether and how KEV status and EPSS percentile move the
+// probability of the tenant's risks
+// PUT /api/v1/vulnerability-enrichment/policy
+func (h *VulnEnrichmentHandler) UpdatePolicy(c *fiber.Ctx) error {
+	type PolicyInput struct {
+		AdjustProbability bool                   `json:"adjust_probability"`
+		AllowDecrease     bool                   `json:"allow_decrease"`
+		KEVProbability    int                    `json:"kev_probability" validate:"min=0,max=5"`
+		EPSSThresholds    []domain.EPSSThreshold `json:"epss_thresholds"`
+	}
+
+	input := new(PolicyInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
+	}
+
+	if err := validation.GetValidator().Struct(input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "validation_failed", "details": err.Error()})
+	}
+
+	tenantID, userID := requestActor(c)
+
+	policy := domain.EnrichmentPolicy{
+		AdjustProbability: input.AdjustProbability,
+		AllowDecrease:     input.AllowDecrease,
+		KEVProbability:    input.KEVProbability,
+	}
+	if input.EPSSThresholds != nil {
+		policy.EPSSThresholds, _ = json.Marshal(input.EPSSThresholds)
+	}
+	updated, err := h.enrichmentService.UpdatePolicy(tenantID, userID, policy)
+	if err != nil {
+		return enrichmentError(c, err)
+	}
+
+	return c.Status(200).JSON(updated)
+}
+
+func enrichmentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidEnrichmentPolicy):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrRiskNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Risk not found"})
+	case errors.Is(err, services.ErrCVENotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "CVE not found"})
+	case errors.Is(err, services.ErrRiskNotEnriched):
+		return c.Status(404).JSON(fiber.Map{"error": "Risk has not been enriched"})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "Vulnerability enrichment failed", "details": err.Error()})
+	}
+}
@@ -65,6 +65,8 @@ func AuthMiddleware(jwtSecret string) fiber.Handler {
 		c.Locals("user_id", claims.ID)
 		c.Locals("role", claims.RoleName)
 		c.Locals("permissions", claims.Permissions)
+		// Attributes the risk changes of the request to the user (risk history)
+		c.SetUserContext(domain.WithActor(c.UserContext(), claims.ID.String()))
 
 		return c.Next()
 	}
@@ -92,6 +92,8 @@ func TestPublicEndpoint(t *testing.T) {
 		"/api/v1/risks",
 		"/api/v1/mitigations",
 		"/api/v1/users",
+		"/api/v1/calendar/reviews/settings",
+		"/api/v1/exports/00000000-0000-0000-0000-000000000000",
 	}
 
 	for _, path := range privateEndpoints {
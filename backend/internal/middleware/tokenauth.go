@@ -8,6 +8,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"github.com/opendefender/openrisk/internal/core/domain"
 	"github.com/opendefender/openrisk/internal/services"
 )
 
@@ -87,6 +88,7 @@ func (ta *TokenAuth) Verify(c *fiber.Ctx) error {
 	c.Locals("tokenID", token.ID)
 	c.Locals("tokenPermissions", token.Permissions)
 	c.Locals("tokenType", token.Type)
+	c.SetUserContext(domain.WithActor(c.UserContext(), token.UserID.String()))
 
 	return c.Next()
 }
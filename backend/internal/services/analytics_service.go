@@ -2,8 +2,12 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"sort"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/opendefender/openrisk/internal/core/domain"
 	"gorm.io/gorm"
 )
@@ -20,6 +24,16 @@ func NewAnalyticsService(db *gorm.DB) *AnalyticsService {
 	}
 }
 
+// risks starts a query on the risks selected by filter (all risks when nil)
+func (s *AnalyticsService) risks(ctx context.Context, filter *RiskFilter) *gorm.DB {
+	return filter.Scope(s.db.WithContext(ctx).Model(&domain.Risk{}))
+}
+
+// mitigations starts a query on the mitigations of the risks selected by filter
+func (s *AnalyticsService) mitigations(ctx context.Context, filter *RiskFilter) *gorm.DB {
+	return filter.Mitigations(s.db.WithContext(ctx))
+}
+
 // RiskMetrics represents aggregated risk metrics
 type RiskMetrics struct {
 	TotalRisks       int64            `json:"total_risks"`
@@ -35,23 +49,23 @@ type RiskMetrics struct {
 	UpdatedThisMonth int64            `json:"updated_this_month"`
 }
 
-// GetRiskMetrics returns aggregated risk metrics
-func (s *AnalyticsService) GetRiskMetrics(ctx context.Context) (*RiskMetrics, error) {
+// GetRiskMetrics returns aggregated metrics of the risks selected by filter (nil for all risks)
+func (s *AnalyticsService) GetRiskMetrics(ctx context.Context, filter *RiskFilter) (*RiskMetrics, error) {
 	metrics := &RiskMetrics{
 		RisksByFramework: make(map[string]int64),
 		RisksByStatus:    make(map[string]int64),
 	}
 
 	// Total risks
-	s.db.WithContext(ctx).Model(&domain.Risk{}).Count(&metrics.TotalRisks)
+	s.risks(ctx, filter).Count(&metrics.TotalRisks)
 
 	// Active risks
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Where("status = ?", "active").
 		Count(&metrics.ActiveRisks)
 
 	// Mitigated risks
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Where("status = ?", "mitigated").
 		Count(&metrics.MitigatedRisks)
 
@@ -60,19 +74,19 @@ func (s *AnalyticsService) GetRiskMetrics(ctx context.Context) (*RiskMetrics, er
 		Avg float64
 	}
 	var scoreRes scoreResult
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Select("AVG(score) as avg").
 		Scan(&scoreRes)
 	metrics.AverageScore = scoreRes.Avg
 
 	// Risks by level
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Where("level = ?", "high").
 		Count(&metrics.HighRisks)
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Where("level = ?", "medium").
 		Count(&metrics.MediumRisks)
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Where("level = ?", "low").
 		Count(&metrics.LowRisks)
 
@@ -82,7 +96,7 @@ func (s *AnalyticsService) GetRiskMetrics(ctx context.Context) (*RiskMetrics, er
 		Count     int64
 	}
 	var frameworks []frameworkResult
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Select("framework, COUNT(*) as count").
 		Group("framework").
 		Scan(&frameworks)
@@ -96,7 +110,7 @@ func (s *AnalyticsService) GetRiskMetrics(ctx context.Context) (*RiskMetrics, er
 		Count  int64
 	}
 	var statuses []statusResult
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Select("status, COUNT(*) as count").
 		Group("status").
 		Scan(&statuses)
@@ -104,15 +118,15 @@ func (s *AnalyticsService) GetRiskMetrics(ctx context.Context) (*RiskMetrics, er
 		metrics.RisksByStatus[st.Status] = st.Count
 	}
 
-	// Created this month
-	now := time.Now()
+	// Created this month (of the as-of date, if any)
+	now := filter.At()
 	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Where("created_at >= ?", monthStart).
 		Count(&metrics.CreatedThisMonth)
 
 	// Updated this month
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Where("updated_at >= ?", monthStart).
 		Count(&metrics.UpdatedThisMonth)
 
@@ -129,11 +143,11 @@ type RiskTrendPoint struct {
 }
 
 // GetRiskTrends returns risk trends over time (last 30 days)
-func (s *AnalyticsService) GetRiskTrends(ctx context.Context, days int) ([]RiskTrendPoint, error) {
+func (s *AnalyticsService) GetRiskTrends(ctx context.Context, days int, filter *RiskFilter) ([]RiskTrendPoint, error) {
 	var trends []RiskTrendPoint
 
-	// Generate daily data for last N days
-	now := time.Now()
+	// Generate daily data for last N days, up to the as-of date if any
+	now := filter.At()
 	for i := days - 1; i >= 0; i-- {
 		date := now.AddDate(0, 0, -i)
 		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
@@ -144,7 +158,7 @@ func (s *AnalyticsService) GetRiskTrends(ctx context.Context, days int) ([]RiskT
 		}
 
 		// Total risks as of this date
-		s.db.WithContext(ctx).Model(&domain.Risk{}).
+		s.risks(ctx, filter).
 			Where("created_at <= ?", endOfDay).
 			Count(&point.Count)
 
@@ -153,19 +167,19 @@ func (s *AnalyticsService) GetRiskTrends(ctx context.Context, days int) ([]RiskT
 			Avg float64
 		}
 		var scoreRes scoreResult
-		s.db.WithContext(ctx).Model(&domain.Risk{}).
+		s.risks(ctx, filter).
 			Where("created_at <= ?", endOfDay).
 			Select("AVG(score) as avg").
 			Scan(&scoreRes)
 		point.AvgScore = scoreRes.Avg
 
 		// New risks created on this day
-		s.db.WithContext(ctx).Model(&domain.Risk{}).
+		s.risks(ctx, filter).
 			Where("created_at >= ? AND created_at < ?", startOfDay, endOfDay).
 			Count(&point.NewRisks)
 
 		// Mitigated on this day
-		s.db.WithContext(ctx).Model(&domain.Risk{}).
+		s.risks(ctx, filter).
 			Where("status = ?", "mitigated").
 			Where("updated_at >= ? AND updated_at < ?", startOfDay, endOfDay).
 			Count(&point.Mitigated)
@@ -188,28 +202,28 @@ type MitigationMetrics struct {
 	MitigationsByRisk    map[string]int64 `json:"mitigations_by_risk"`
 }
 
-// GetMitigationMetrics returns mitigation analytics
-func (s *AnalyticsService) GetMitigationMetrics(ctx context.Context) (*MitigationMetrics, error) {
+// GetMitigationMetrics returns analytics of the mitigations of the risks selected by filter
+func (s *AnalyticsService) GetMitigationMetrics(ctx context.Context, filter *RiskFilter) (*MitigationMetrics, error) {
 	metrics := &MitigationMetrics{
 		MitigationsByRisk: make(map[string]int64),
 	}
 
 	// Total mitigations
-	s.db.WithContext(ctx).Model(&domain.Mitigation{}).Count(&metrics.TotalMitigations)
+	s.mitigations(ctx, filter).Count(&metrics.TotalMitigations)
 
 	// Completed mitigations
-	s.db.WithContext(ctx).Model(&domain.Mitigation{}).
+	s.mitigations(ctx, filter).
 		Where("status = ?", "completed").
 		Count(&metrics.CompletedMitigations)
 
 	// Pending mitigations
-	s.db.WithContext(ctx).Model(&domain.Mitigation{}).
+	s.mitigations(ctx, filter).
 		Where("status IN ?", []string{"open", "in_progress"}).
 		Count(&metrics.PendingMitigations)
 
 	// Overdue mitigations
-	now := time.Now()
-	s.db.WithContext(ctx).Model(&domain.Mitigation{}).
+	now := filter.At()
+	s.mitigations(ctx, filter).
 		Where("status != ?", "completed").
 		Where("due_date < ?", now).
 		Count(&metrics.OverdueMitigations)
@@ -224,7 +238,7 @@ func (s *AnalyticsService) GetMitigationMetrics(ctx context.Context) (*Mitigatio
 		AvgDays float64
 	}
 	var compRes completionResult
-	s.db.WithContext(ctx).Model(&domain.Mitigation{}).
+	s.mitigations(ctx, filter).
 		Where("status = ?", "completed").
 		Select("AVG(EXTRACT(DAY FROM (completed_at - created_at))) as avg_days").
 		Scan(&compRes)
@@ -236,7 +250,7 @@ func (s *AnalyticsService) GetMitigationMetrics(ctx context.Context) (*Mitigatio
 		Count  int64
 	}
 	var riskMitigations []riskMitigationResult
-	s.db.WithContext(ctx).Model(&domain.Mitigation{}).
+	s.mitigations(ctx, filter).
 		Select("risk_id, COUNT(*) as count").
 		Group("risk_id").
 		Scan(&riskMitigations)
@@ -256,14 +270,14 @@ type FrameworkAnalytics struct {
 }
 
 // GetFrameworkAnalytics returns compliance analytics by framework
-func (s *AnalyticsService) GetFrameworkAnalytics(ctx context.Context) ([]FrameworkAnalytics, error) {
+func (s *AnalyticsService) GetFrameworkAnalytics(ctx context.Context, filter *RiskFilter) ([]FrameworkAnalytics, error) {
 	var analytics []FrameworkAnalytics
 
 	type frameworkResult struct {
 		Framework string
 	}
 	var frameworks []frameworkResult
-	s.db.WithContext(ctx).Model(&domain.Risk{}).
+	s.risks(ctx, filter).
 		Distinct("framework").
 		Scan(&frameworks)
 
@@ -271,7 +285,7 @@ func (s *AnalyticsService) GetFrameworkAnalytics(ctx context.Context) ([]Framewo
 		analytic := FrameworkAnalytics{Framework: fw.Framework}
 
 		// Associated risks
-		s.db.WithContext(ctx).Model(&domain.Risk{}).
+		s.risks(ctx, filter).
 			Where("framework = ?", fw.Framework).
 			Count(&analytic.AssociatedRisks)
 
@@ -280,7 +294,7 @@ func (s *AnalyticsService) GetFrameworkAnalytics(ctx context.Context) ([]Framewo
 			Avg float64
 		}
 		var scoreRes scoreResult
-		s.db.WithContext(ctx).Model(&domain.Risk{}).
+		s.risks(ctx, filter).
 			Where("framework = ?", fw.Framework).
 			Select("AVG(score) as avg").
 			Scan(&scoreRes)
@@ -302,31 +316,31 @@ type DashboardSnapshot struct {
 }
 
 // GetDashboardSnapshot returns a complete dashboard snapshot
-func (s *AnalyticsService) GetDashboardSnapshot(ctx context.Context) (*DashboardSnapshot, error) {
+func (s *AnalyticsService) GetDashboardSnapshot(ctx context.Context, filter *RiskFilter) (*DashboardSnapshot, error) {
 	snapshot := &DashboardSnapshot{
-		Timestamp: time.Now(),
+		Timestamp: filter.At(),
 	}
 
 	// Get all metrics
-	riskMetrics, err := s.GetRiskMetrics(ctx)
+	riskMetrics, err := s.GetRiskMetrics(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	snapshot.RiskMetrics = riskMetrics
 
-	mitigationMetrics, err := s.GetMitigationMetrics(ctx)
+	mitigationMetrics, err := s.GetMitigationMetrics(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	snapshot.MitigationMetrics = mitigationMetrics
 
-	frameworkAnalytics, err := s.GetFrameworkAnalytics(ctx)
+	frameworkAnalytics, err := s.GetFrameworkAnalytics(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	snapshot.FrameworkAnalytics = frameworkAnalytics
 
-	trends, err := s.GetRiskTrends(ctx, 30)
+	trends, err := s.GetRiskTrends(ctx, 30, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -334,3 +348,162 @@ func (s *AnalyticsService) GetDashboardSnapshot(ctx context.Context) (*Dashboard
 
 	return snapshot, nil
 }
+
+// UncategorizedRisks is the category of risks without a register category
+const UncategorizedRisks = "UNCATEGORIZED"
+
+// LossComparison compares, for a risk category, the loss its risks were
+// expected to cause over a period with the losses of the incidents that
+// realized them
+type LossComparison struct {
+	Category      string   `json:"category"`
+	Risks         int      `json:"risks"`
+	Incidents     int      `json:"incidents"`      // Incidents with a loss in the period
+	PredictedLoss float64  `json:"predicted_loss"` // Expected annual loss prorated to the period
+	ActualLoss    float64  `json:"actual_loss"`
+	Variance      float64  `json:"variance"`        // Actual minus predicted
+	Ratio         *float64 `json:"ratio,omitempty"` // Actual over predicted; nil when nothing was predicted
+}
+
+// LossAnalytics compares predicted and actual losses per risk category
+type LossAnalytics struct {
+	From          time.Time        `json:"from"`
+	To            time.Time        `json:"to"`
+	Categories    []LossComparison `json:"categories"`
+	PredictedLoss float64          `json:"predicted_loss"`
+	ActualLoss    float64          `json:"actual_loss"`
+	// Losses of incidents linked to no risk, which no category accounts for
+	UnattributedLoss float64 `json:"unattributed_loss"`
+}
+
+// lossRisk is a risk with its category and expected annual loss
+type lossRisk struct {
+	ID           uuid.UUID
+	Category     string
+	ExpectedLoss float64
+}
+
+// lossIncident is an incident with its losses in the period and the risks
+// it realized
+type lossIncident struct {
+	ID      uuid.UUID
+	Loss    float64
+	RiskIDs []uuid.UUID
+}
+
+// GetLossAnalytics compares, per risk category, the expected loss of the
+// risks selected by filter over [from, to) with the loss events that
+// occurred then. The loss of an incident is shared equally between the risks
+// it realized.
+func (s *AnalyticsService) GetLossAnalytics(ctx context.Context, from, to time.Time, filter *RiskFilter) (*LossAnalytics, error) {
+	var risks []lossRisk
+	if err := s.risks(ctx, filter).
+		Joins("LEFT JOIN risk_registers ON risk_registers.risk_id = risks.id AND risk_registers.deleted_at IS NULL").
+		Select("risks.id, COALESCE(NULLIF(risk_registers.risk_category, ''), ?) AS category, COALESCE(risks.expected_loss, 0) AS expected_loss", UncategorizedRisks).
+		Scan(&risks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risks: %w", err)
+	}
+
+	type incidentLoss struct {
+		IncidentID uuid.UUID
+		Loss       float64
+	}
+	var losses []incidentLoss
+	if err := s.db.WithContext(ctx).Model(&domain.LossEvent{}).
+		Select("incident_id, SUM(direct_loss + indirect_loss + recovery_cost) AS loss").
+		Where("occurred_at >= ? AND occurred_at < ?", from, to).
+		Group("incident_id").
+		Scan(&losses).Error; err != nil {
+		return nil, fmt.Errorf("failed to load loss events: %w", err)
+	}
+	incidentIDs := make([]uuid.UUID, 0, len(losses))
+	for _, l := range losses {
+		incidentIDs = append(incidentIDs, l.IncidentID)
+	}
+	var links []domain.IncidentRisk
+	if len(incidentIDs) > 0 {
+		if err := s.db.WithContext(ctx).Where("incident_id IN ?", incidentIDs).Find(&links).Error; err != nil {
+			return nil, fmt.Errorf("failed to load incident risks: %w", err)
+		}
+	}
+	linked := map[uuid.UUID][]uuid.UUID{}
+	for _, l := range links {
+		linked[l.IncidentID] = append(linked[l.IncidentID], l.RiskID)
+	}
+	incidents := make([]lossIncident, 0, len(losses))
+	for _, l := range losses {
+		incidents = append(incidents, lossIncident{ID: l.IncidentID, Loss: l.Loss, RiskIDs: linked[l.IncidentID]})
+	}
+
+	analytics := compareLosses(risks, incidents, to.Sub(from).Hours()/24/365)
+	analytics.From, analytics.To = from, to
+	return analytics, nil
+}
+
+// compareLosses aggregates predicted and actual losses per category, the
+// expected annual losses being prorated to years. Shares of incidents
+// realizing risks outside the selection are left out; incidents realizing no
+// risk are unattributed.
+func compareLosses(risks []lossRisk, incidents []lossIncident, years float64) *LossAnalytics {
+	byCategory := map[string]*LossComparison{}
+	categoryOf := make(map[uuid.UUID]string, len(risks))
+	for _, r := range risks {
+		c, ok := byCategory[r.Category]
+		if !ok {
+			c = &LossComparison{Category: r.Category}
+			byCategory[r.Category] = c
+		}
+		c.Risks++
+		c.PredictedLoss += r.ExpectedLoss * years
+		categoryOf[r.ID] = r.Category
+	}
+
+	analytics := &LossAnalytics{Categories: []LossComparison{}}
+	for _, inc := range incidents {
+		if len(inc.RiskIDs) == 0 {
+			analytics.UnattributedLoss += inc.Loss
+			continue
+		}
+		share := inc.Loss / float64(len(inc.RiskIDs))
+		counted := map[string]bool{}
+		for _, id := range inc.RiskIDs {
+			category, ok := categoryOf[id]
+			if !ok {
+				continue
+			}
+			byCategory[category].ActualLoss += share
+			if !counted[category] {
+				counted[category] = true
+				byCategory[category].Incidents++
+			}
+		}
+	}
+
+	for _, c := range byCategory {
+		c.PredictedLoss = roundCents(c.PredictedLoss)
+		c.ActualLoss = roundCents(c.ActualLoss)
+		c.Variance = roundCents(c.ActualLoss - c.PredictedLoss)
+		if c.PredictedLoss > 0 {
+			ratio := math.Round(c.ActualLoss/c.PredictedLoss*100) / 100
+			c.Ratio = &ratio
+		}
+		analytics.PredictedLoss += c.PredictedLoss
+		analytics.ActualLoss += c.ActualLoss
+		analytics.Categories = append(analytics.Categories, *c)
+	}
+	sort.Slice(analytics.Categories, func(i, j int) bool {
+		a, b := analytics.Categories[i], analytics.Categories[j]
+		if a.ActualLoss != b.ActualLoss {
+			return a.ActualLoss > b.ActualLoss
+		}
+		return a.Category < b.Category
+	})
+	analytics.PredictedLoss = roundCents(analytics.PredictedLoss)
+	analytics.ActualLoss = roundCents(analytics.ActualLoss)
+	analytics.UnattributedLoss = roundCents(analytics.UnattributedLoss)
+	return analytics
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,886 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	// ErrApprovalPending is returned when a record must go through its approval chain
+	ErrApprovalPending = errors.New("record is governed by a pending approval workflow")
+	// ErrNotEligibleApprover is returned when a user votes on a task they cannot approve
+	ErrNotEligibleApprover = errors.New("user is not an eligible approver for this task")
+	// ErrAlreadyVoted is returned when an approver (or their delegate) votes twice on a task
+	ErrAlreadyVoted = errors.New("approver has already voted on this task")
+	// ErrTaskNotPending is returned when voting on a task that is not awaiting votes
+	ErrTaskNotPending = errors.New("approval task is not pending")
+	// ErrApprovalNotGranted is returned when a record governed by an approval workflow is
+	// approved directly while its latest approval request was rejected or cancelled
+	ErrApprovalNotGranted = errors.New("record is governed by an approval workflow that has not approved it")
+	// ErrNotRequester is returned when a user other than the requester or an admin cancels a request
+	ErrNotRequester = errors.New("only the requester or an admin can cancel this approval request")
+)
+
+// ApprovalWorkflowService runs configurable multi-step approval chains for risk decisions and treatment plans
+type ApprovalWorkflowService struct {
+	db *gorm.DB
+}
+
+// NewApprovalWorkflowService creates a new approval workflow service
+func NewApprovalWorkflowService(db *gorm.DB) *ApprovalWorkflowService {
+	return &ApprovalWorkflowService{db: db}
+}
+
+// CreateWorkflow validates and stores a new approval chain configuration
+func (s *ApprovalWorkflowService) CreateWorkflow(
+	tenantID uuid.UUID,
+	createdBy uuid.UUID,
+	workflow *domain.ApprovalWorkflow,
+	steps []domain.ApprovalStepDefinition,
+) (*domain.ApprovalWorkflow, error) {
+	if err := validateApprovalSteps(steps); err != nil {
+		return nil, err
+	}
+	switch workflow.EntityType {
+	case domain.ApprovalEntityDecision, domain.ApprovalEntityTreatmentPlan, domain.ApprovalEntityException:
+	default:
+		return nil, fmt.Errorf("invalid entity type: %s", workflow.EntityType)
+	}
+	if workflow.MinRiskLevel != "" && domain.RiskLevelRank(workflow.MinRiskLevel) == 0 {
+		return nil, fmt.Errorf("invalid min risk level: %s", workflow.MinRiskLevel)
+	}
+
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal steps: %w", err)
+	}
+
+	workflow.ID = uuid.New()
+	workflow.TenantID = tenantID
+	workflow.CreatedBy = createdBy
+	workflow.MinRiskLevel = strings.ToUpper(workflow.MinRiskLevel)
+	workflow.Steps = data
+	workflow.IsActive = true
+
+	if err := s.db.Create(workflow).Error; err != nil {
+		return nil, fmt.Errorf("failed to create approval workflow: %w", err)
+	}
+	return workflow, nil
+}
+
+// UpdateWorkflow replaces the steps and/or active flag of an approval chain.
+// Running requests keep the tasks they were created with.
+func (s *ApprovalWorkflowService) UpdateWorkflow(
+	tenantID uuid.UUID,
+	workflowID uuid.UUID,
+	steps []domain.ApprovalStepDefinition,
+	isActive *bool,
+	priority *int,
+) (*domain.ApprovalWorkflow, error) {
+	workflow, err := s.GetWorkflow(tenantID, workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	if steps != nil {
+		if err := validateApprovalSteps(steps); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(steps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal steps: %w", err)
+		}
+		workflow.Steps = data
+	}
+	if isActive != nil {
+		workflow.IsActive = *isActive
+	}
+	if priority != nil {
+		workflow.Priority = *priority
+	}
+
+	if err := s.db.Save(workflow).Error; err != nil {
+		return nil, fmt.Errorf("failed to update approval workflow: %w", err)
+	}
+	return workflow, nil
+}
+
+// GetWorkflow retrieves an approval workflow of a tenant
+func (s *ApprovalWorkflowService) GetWorkflow(tenantID, workflowID uuid.UUID) (*domain.ApprovalWorkflow, error) {
+	var workflow domain.ApprovalWorkflow
+	if err := s.db.First(&workflow, "id = ? AND tenant_id = ?", workflowID, tenantID).Error; err != nil {
+		return nil, fmt.Errorf("approval workflow not found: %w", err)
+	}
+	return &workflow, nil
+}
+
+// ListWorkflows lists the approval workflows of a tenant, optionally filtered by entity type
+func (s *ApprovalWorkflowService) ListWorkflows(tenantID uuid.UUID, entityType string) ([]domain.ApprovalWorkflow, error) {
+	var workflows []domain.ApprovalWorkflow
+	query := s.db.Where("tenant_id = ?", tenantID)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if err := query.Order("entity_type, priority DESC, created_at").Find(&workflows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list approval workflows: %w", err)
+	}
+	return workflows, nil
+}
+
+// DeleteWorkflow soft-deletes an approval workflow
+func (s *ApprovalWorkflowService) DeleteWorkflow(tenantID, workflowID uuid.UUID) error {
+	result := s.db.Delete(&domain.ApprovalWorkflow{}, "id = ? AND tenant_id = ?", workflowID, tenantID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete approval workflow: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("approval workflow not found")
+	}
+	return nil
+}
+
+// ResolveWorkflow returns the highest priority active workflow matching the decision, or nil
+func (s *ApprovalWorkflowService) ResolveWorkflow(
+	tenantID uuid.UUID,
+	entityType domain.ApprovalEntityType,
+	decisionType string,
+	riskLevel string,
+) (*domain.ApprovalWorkflow, error) {
+	var candidates []domain.ApprovalWorkflow
+	if err := s.db.Where("tenant_id = ? AND entity_type = ? AND is_active = ?", tenantID, entityType, true).
+		Order("priority DESC, created_at").
+		Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to resolve approval workflow: %w", err)
+	}
+	return selectApprovalWorkflow(candidates, entityType, decisionType, riskLevel), nil
+}
+
+// StartApproval opens an approval request for a record if a workflow applies to it.
+// It returns nil without error when no workflow matches, in which case the record
+// keeps the single-step approval behaviour.
+func (s *ApprovalWorkflowService) StartApproval(
+	tenantID uuid.UUID,
+	entityType domain.ApprovalEntityType,
+	entityID uuid.UUID,
+	riskRegisterID uuid.UUID,
+	decisionType string,
+	requestedBy uuid.UUID,
+) (*domain.ApprovalRequest, error) {
+	var register domain.RiskRegister
+	riskLevel := ""
+	if err := s.db.First(&register, "id = ?", riskRegisterID).Error; err == nil {
+		riskLevel = register.ResidualRiskLevel
+		if riskLevel == "" {
+			riskLevel = register.InherentRiskLevel
+		}
+	}
+
+	return s.StartApprovalAtLevel(tenantID, entityType, entityID, riskRegisterID, decisionType, riskLevel, requestedBy)
+}
+
+// StartApprovalAtLevel is StartApproval for records whose risk level is known by the caller,
+// such as exceptions on risks that have no register entry.
+func (s *ApprovalWorkflowService) StartApprovalAtLevel(
+	tenantID uuid.UUID,
+	entityType domain.ApprovalEntityType,
+	entityID uuid.UUID,
+	riskRegisterID uuid.UUID,
+	decisionType string,
+	riskLevel string,
+	requestedBy uuid.UUID,
+) (*domain.ApprovalRequest, error) {
+	var register domain.RiskRegister
+	if riskRegisterID != uuid.Nil {
+		s.db.First(&register, "id = ?", riskRegisterID)
+	}
+
+	workflow, err := s.ResolveWorkflow(tenantID, entityType, decisionType, riskLevel)
+	if err != nil || workflow == nil {
+		return nil, err
+	}
+	steps, err := workflow.StepDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	request := &domain.ApprovalRequest{
+		ID:             uuid.New(),
+		TenantID:       tenantID,
+		WorkflowID:     workflow.ID,
+		EntityType:     entityType,
+		EntityID:       entityID,
+		RiskRegisterID: riskRegisterID,
+		DecisionType:   decisionType,
+		RiskLevel:      riskLevel,
+		Status:         domain.ApprovalRequestPending,
+		RequestedBy:    requestedBy,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(request).Error; err != nil {
+			return fmt.Errorf("failed to create approval request: %w", err)
+		}
+
+		for _, step := range steps {
+			task := domain.ApprovalTask{
+				ID:              uuid.New(),
+				RequestID:       request.ID,
+				TenantID:        tenantID,
+				StepName:        step.Name,
+				Stage:           step.Stage,
+				Approvers:       s.resolveApprovers(tx, tenantID, register.RiskOwner, step.ApproverRoles, step.ApproverUsers),
+				TimeoutHours:    step.TimeoutHours,
+				EscalateToRoles: step.EscalateToRoles,
+				EscalateToUsers: uuidStrings(step.EscalateToUsers),
+				Status:          domain.ApprovalTaskWaiting,
+			}
+			task.RequiredApprovals = clampRequired(step.RequiredApprovals(len(task.Approvers)), len(task.Approvers))
+			if err := tx.Create(&task).Error; err != nil {
+				return fmt.Errorf("failed to create approval task: %w", err)
+			}
+			request.Tasks = append(request.Tasks, task)
+		}
+
+		s.logTransition(tx, request, string(request.EntityType), request.EntityID, "APPROVAL_STARTED", "", string(domain.ApprovalRequestPending), requestedBy, workflow.Name)
+		return s.advance(tx, request, requestedBy, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// HasPendingApproval reports whether a record has an open approval request
+func (s *ApprovalWorkflowService) HasPendingApproval(entityType domain.ApprovalEntityType, entityID uuid.UUID) (bool, error) {
+	var count int64
+	if err := s.db.Model(&domain.ApprovalRequest{}).
+		Where("entity_type = ? AND entity_id = ? AND status = ?", entityType, entityID, domain.ApprovalRequestPending).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// LatestApproval returns the most recent approval request of a record, or nil when no
+// workflow was ever started for it
+func (s *ApprovalWorkflowService) LatestApproval(entityType domain.ApprovalEntityType, entityID uuid.UUID) (*domain.ApprovalRequest, error) {
+	var requests []domain.ApprovalRequest
+	if err := s.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("created_at DESC").Limit(1).
+		Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	return &requests[0], nil
+}
+
+// GetRequest retrieves an approval request with its tasks and votes
+func (s *ApprovalWorkflowService) GetRequest(tenantID, requestID uuid.UUID) (*domain.ApprovalRequest, error) {
+	var request domain.ApprovalRequest
+	if err := s.db.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("stage, step_name")
+	}).Preload("Tasks.Votes").
+		First(&request, "id = ? AND tenant_id = ?", requestID, tenantID).Error; err != nil {
+		return nil, fmt.Errorf("approval request not found: %w", err)
+	}
+	return &request, nil
+}
+
+// ListRequests lists approval requests of a tenant
+func (s *ApprovalWorkflowService) ListRequests(tenantID uuid.UUID, status string, limit int, offset int) ([]domain.ApprovalRequest, int64, error) {
+	var requests []domain.ApprovalRequest
+	var total int64
+
+	query := s.db.Model(&domain.ApprovalRequest{}).Where("tenant_id = ?", tenantID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count approval requests: %w", err)
+	}
+	if err := query.Preload("Tasks").Order("created_at DESC").Limit(limit).Offset(offset).Find(&requests).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list approval requests: %w", err)
+	}
+	return requests, total, nil
+}
+
+// ListPendingTasksForUser lists pending tasks the user can vote on, directly or as a delegate
+func (s *ApprovalWorkflowService) ListPendingTasksForUser(tenantID, userID uuid.UUID) ([]domain.ApprovalTask, error) {
+	seats := []string{userID.String()}
+	for _, d := range s.activeDelegationsTo(s.db, tenantID, userID, time.Now()) {
+		seats = append(seats, d.DelegatorID.String())
+	}
+
+	var tasks []domain.ApprovalTask
+	if err := s.db.Where("tenant_id = ? AND status = ? AND approvers && ?", tenantID, domain.ApprovalTaskPending, pq.StringArray(seats)).
+		Order("due_at NULLS LAST, created_at").
+		Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list approval tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// CastVote records an approver's answer on a task and advances the request
+func (s *ApprovalWorkflowService) CastVote(
+	tenantID uuid.UUID,
+	taskID uuid.UUID,
+	userID uuid.UUID,
+	decision domain.ApprovalVoteDecision,
+	comment string,
+) (*domain.ApprovalRequest, error) {
+	if decision != domain.VoteApprove && decision != domain.VoteReject {
+		return nil, fmt.Errorf("invalid vote decision: %s", decision)
+	}
+
+	var request domain.ApprovalRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the request then the task so concurrent votes are counted one after the other
+		// and a step cannot be advanced or the request closed twice
+		var task domain.ApprovalTask
+		if err := tx.First(&task, "id = ? AND tenant_id = ?", taskID, tenantID).Error; err != nil {
+			return fmt.Errorf("approval task not found: %w", err)
+		}
+		var locked domain.ApprovalRequest
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "id = ?", task.RequestID).Error; err != nil {
+			return fmt.Errorf("approval request not found: %w", err)
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task, "id = ?", task.ID).Error; err != nil {
+			return fmt.Errorf("approval task not found: %w", err)
+		}
+		if task.Status != domain.ApprovalTaskPending {
+			return ErrTaskNotPending
+		}
+
+		now := time.Now()
+		var onBehalfOf *uuid.UUID
+		seat := userID
+		if !task.HasApprover(userID) {
+			delegator, ok := s.delegatedSeat(tx, tenantID, &task, userID, now)
+			if !ok {
+				return ErrNotEligibleApprover
+			}
+			onBehalfOf = &delegator
+			seat = delegator
+		}
+
+		var existing int64
+		tx.Model(&domain.ApprovalVote{}).
+			Where("task_id = ? AND (approver_id = ? OR on_behalf_of = ?)", task.ID, seat, seat).
+			Count(&existing)
+		if existing > 0 {
+			return ErrAlreadyVoted
+		}
+
+		vote := &domain.ApprovalVote{
+			ID:         uuid.New(),
+			TaskID:     task.ID,
+			RequestID:  task.RequestID,
+			ApproverID: userID,
+			OnBehalfOf: onBehalfOf,
+			Decision:   decision,
+			Comment:    comment,
+			CreatedAt:  now,
+		}
+		if err := tx.Create(vote).Error; err != nil {
+			return fmt.Errorf("failed to record vote: %w", err)
+		}
+
+		if decision == domain.VoteApprove {
+			task.ApprovalCount++
+		} else {
+			task.RejectionCount++
+		}
+		oldStatus := task.Status
+		task.Status = domain.EvaluateApprovalTask(task.RequiredApprovals, len(task.Approvers), task.ApprovalCount, task.RejectionCount)
+		if task.Status != domain.ApprovalTaskPending {
+			task.CompletedAt = &now
+		}
+		if err := tx.Save(&task).Error; err != nil {
+			return fmt.Errorf("failed to update approval task: %w", err)
+		}
+
+		if err := tx.Preload("Tasks").First(&request, "id = ?", task.RequestID).Error; err != nil {
+			return fmt.Errorf("approval request not found: %w", err)
+		}
+
+		s.logTransition(tx, &request, "APPROVAL_TASK", task.ID, "VOTE_"+string(decision), string(oldStatus), string(task.Status), userID, comment)
+		return s.advance(tx, &request, userID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRequest(tenantID, request.ID)
+}
+
+// CancelRequest withdraws an open approval request; only its requester or an admin can
+func (s *ApprovalWorkflowService) CancelRequest(tenantID, requestID, cancelledBy uuid.UUID, isAdmin bool, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var request domain.ApprovalRequest
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, "id = ? AND tenant_id = ?", requestID, tenantID).Error; err != nil {
+			return fmt.Errorf("approval request not found: %w", err)
+		}
+		if request.RequestedBy != cancelledBy && !isAdmin {
+			return ErrNotRequester
+		}
+		if err := tx.Where("request_id = ?", request.ID).Find(&request.Tasks).Error; err != nil {
+			return fmt.Errorf("failed to load approval tasks: %w", err)
+		}
+		if request.Status != domain.ApprovalRequestPending {
+			return fmt.Errorf("approval request is already %s", request.Status)
+		}
+		return s.complete(tx, &request, domain.ApprovalRequestCancelled, cancelledBy, reason, time.Now())
+	})
+}
+
+// CreateDelegation delegates a user's approval rights to another user for a period
+func (s *ApprovalWorkflowService) CreateDelegation(
+	tenantID uuid.UUID,
+	delegatorID uuid.UUID,
+	delegateID uuid.UUID,
+	validFrom time.Time,
+	validUntil time.Time,
+	reason string,
+) (*domain.ApprovalDelegation, error) {
+	if delegatorID == delegateID {
+		return nil, fmt.Errorf("cannot delegate approvals to yourself")
+	}
+	if !validUntil.After(validFrom) {
+		return nil, fmt.Errorf("valid_until must be after valid_from")
+	}
+
+	delegation := &domain.ApprovalDelegation{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		DelegatorID: delegatorID,
+		DelegateID:  delegateID,
+		ValidFrom:   validFrom,
+		ValidUntil:  validUntil,
+		Reason:      reason,
+	}
+	if err := s.db.Create(delegation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create delegation: %w", err)
+	}
+	return delegation, nil
+}
+
+// ListDelegations lists delegations given or received by a user
+func (s *ApprovalWorkflowService) ListDelegations(tenantID, userID uuid.UUID) ([]domain.ApprovalDelegation, error) {
+	var delegations []domain.ApprovalDelegation
+	if err := s.db.Where("tenant_id = ? AND (delegator_id = ? OR delegate_id = ?)", tenantID, userID, userID).
+		Order("valid_from DESC").
+		Find(&delegations).Error; err != nil {
+		return nil, fmt.Errorf("failed to list delegations: %w", err)
+	}
+	return delegations, nil
+}
+
+// RevokeDelegation ends a delegation early; only the delegator can revoke it
+func (s *ApprovalWorkflowService) RevokeDelegation(tenantID, delegationID, userID uuid.UUID) error {
+	now := time.Now()
+	result := s.db.Model(&domain.ApprovalDelegation{}).
+		Where("id = ? AND tenant_id = ? AND delegator_id = ? AND revoked_at IS NULL", delegationID, tenantID, userID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke delegation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("delegation not found")
+	}
+	return nil
+}
+
+// ProcessTimeouts escalates pending tasks whose timeout has elapsed.
+// Escalation adds the escalation approvers to the task and restarts its timer once.
+func (s *ApprovalWorkflowService) ProcessTimeouts(now time.Time) (int, error) {
+	var tasks []domain.ApprovalTask
+	if err := s.db.Where("status = ? AND escalated = ? AND due_at IS NOT NULL AND due_at < ?", domain.ApprovalTaskPending, false, now).
+		Find(&tasks).Error; err != nil {
+		return 0, fmt.Errorf("failed to load overdue approval tasks: %w", err)
+	}
+
+	escalated := 0
+	for i := range tasks {
+		skipped := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// Lock the request then the task, as votes do, and re-check the task under the lock:
+			// a vote or another run may have closed or escalated it since it was listed
+			var request domain.ApprovalRequest
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, "id = ?", tasks[i].RequestID).Error; err != nil {
+				return fmt.Errorf("approval request not found: %w", err)
+			}
+			var task domain.ApprovalTask
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&task, "id = ? AND status = ? AND escalated = ?", tasks[i].ID, domain.ApprovalTaskPending, false).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				skipped = true
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to lock approval task: %w", err)
+			}
+			return s.escalate(tx, &request, &task, now)
+		})
+		if err != nil {
+			log.Printf("approval: failed to escalate task %s: %v", tasks[i].ID, err)
+			continue
+		}
+		if !skipped {
+			escalated++
+		}
+	}
+	return escalated, nil
+}
+
+// advance activates the next stage once the current one is approved, or closes the request
+func (s *ApprovalWorkflowService) advance(tx *gorm.DB, request *domain.ApprovalRequest, actor uuid.UUID, now time.Time) error {
+	if request.Status != domain.ApprovalRequestPending {
+		return nil
+	}
+
+	for _, task := range request.Tasks {
+		if task.Stage == request.CurrentStage && task.Status == domain.ApprovalTaskRejected {
+			return s.complete(tx, request, domain.ApprovalRequestRejected, actor, task.StepName, now)
+		}
+	}
+	if request.CurrentStage > 0 && !stageApproved(request.Tasks, request.CurrentStage) {
+		return nil
+	}
+
+	next := nextApprovalStage(request.Tasks, request.CurrentStage)
+	if next == 0 {
+		return s.complete(tx, request, domain.ApprovalRequestApproved, actor, "", now)
+	}
+
+	oldStage := request.CurrentStage
+	request.CurrentStage = next
+	if err := tx.Model(request).Update("current_stage", next).Error; err != nil {
+		return fmt.Errorf("failed to advance approval request: %w", err)
+	}
+
+	for i := range request.Tasks {
+		task := &request.Tasks[i]
+		if task.Stage != next {
+			continue
+		}
+		task.Status = domain.ApprovalTaskPending
+		if task.TimeoutHours > 0 {
+			due := now.Add(time.Duration(task.TimeoutHours) * time.Hour)
+			task.DueAt = &due
+		}
+		if err := tx.Save(task).Error; err != nil {
+			return fmt.Errorf("failed to activate approval task: %w", err)
+		}
+		if len(task.Approvers) == 0 {
+			// Nobody can approve this step: escalate straight away rather than letting it stall
+			if err := s.escalate(tx, request, task, now); err != nil {
+				return err
+			}
+			if request.Status != domain.ApprovalRequestPending {
+				return nil
+			}
+		}
+	}
+
+	s.logTransition(tx, request, string(request.EntityType), request.EntityID, "STAGE_ADVANCED", fmt.Sprint(oldStage), fmt.Sprint(next), actor, "")
+	return nil
+}
+
+// complete closes the request, skips unreached tasks and applies the outcome to the governed record
+func (s *ApprovalWorkflowService) complete(
+	tx *gorm.DB,
+	request *domain.ApprovalRequest,
+	status domain.ApprovalRequestStatus,
+	actor uuid.UUID,
+	reason string,
+	now time.Time,
+) error {
+	oldStatus := request.Status
+	request.Status = status
+	request.CompletedAt = &now
+	if err := tx.Model(request).Updates(map[string]interface{}{
+		"status":       status,
+		"completed_at": now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to close approval request: %w", err)
+	}
+
+	if err := tx.Model(&domain.ApprovalTask{}).
+		Where("request_id = ? AND status IN ?", request.ID, []domain.ApprovalTaskStatus{domain.ApprovalTaskWaiting, domain.ApprovalTaskPending}).
+		Updates(map[string]interface{}{"status": domain.ApprovalTaskSkipped, "completed_at": now}).Error; err != nil {
+		return fmt.Errorf("failed to close approval tasks: %w", err)
+	}
+
+	if err := s.applyOutcome(tx, request, actor, now); err != nil {
+		return err
+	}
+
+	s.logTransition(tx, request, string(request.EntityType), request.EntityID, string(status), string(oldStatus), string(status), actor, reason)
+	return nil
+}
+
+// applyOutcome writes the final approval status on the governed decision or treatment plan
+func (s *ApprovalWorkflowService) applyOutcome(tx *gorm.DB, request *domain.ApprovalRequest, actor uuid.UUID, now time.Time) error {
+	var status string
+	switch request.Status {
+	case domain.ApprovalRequestApproved:
+		status = "APPROVED"
+	case domain.ApprovalRequestRejected:
+		status = "REJECTED"
+	default:
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"approved_by":   actor,
+		"approved_date": now,
+	}
+	if request.Status == domain.ApprovalRequestRejected {
+		updates = map[string]interface{}{
+			"rejected_by":   actor,
+			"rejected_date": now,
+		}
+	}
+
+	switch request.EntityType {
+	case domain.ApprovalEntityDecision:
+		updates["status"] = status
+		// Updated by ID, so the AfterSave hook cannot bump the version itself
+		updates["version"] = gorm.Expr("version + 1")
+		if err := tx.Model(&domain.RiskDecision{}).Where("id = ?", request.EntityID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to apply approval outcome to decision: %w", err)
+		}
+	case domain.ApprovalEntityTreatmentPlan:
+		updates["approval_status"] = status
+		if err := tx.Model(&domain.RiskTreatmentPlan{}).Where("id = ?", request.EntityID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to apply approval outcome to treatment plan: %w", err)
+		}
+	case domain.ApprovalEntityException:
+		if err := applyExceptionOutcome(tx, request.EntityID, request.Status == domain.ApprovalRequestApproved, actor, now); err != nil {
+			return fmt.Errorf("failed to apply approval outcome to exception: %w", err)
+		}
+	}
+	return nil
+}
+
+// escalate widens the approver list of an overdue task.
+// A task still left without approvers rejects the request, since nobody could ever vote on it.
+func (s *ApprovalWorkflowService) escalate(tx *gorm.DB, request *domain.ApprovalRequest, task *domain.ApprovalTask, now time.Time) error {
+	var register domain.RiskRegister
+	tx.First(&register, "id = ?", request.RiskRegisterID)
+
+	escalateUsers := make([]uuid.UUID, 0, len(task.EscalateToUsers))
+	for _, id := range task.EscalateToUsers {
+		if parsed, err := uuid.Parse(id); err == nil {
+			escalateUsers = append(escalateUsers, parsed)
+		}
+	}
+	added := s.resolveApprovers(tx, task.TenantID, register.RiskOwner, task.EscalateToRoles, escalateUsers)
+
+	task.Approvers = mergeApprovers(task.Approvers, added)
+	task.Escalated = true
+	task.EscalatedAt = &now
+	if task.TimeoutHours > 0 {
+		due := now.Add(time.Duration(task.TimeoutHours) * time.Hour)
+		task.DueAt = &due
+	}
+	if task.RequiredApprovals > len(task.Approvers) && len(task.Approvers) > 0 {
+		task.RequiredApprovals = len(task.Approvers)
+	}
+	updates := map[string]interface{}{
+		"approvers":          task.Approvers,
+		"escalated":          true,
+		"escalated_at":       now,
+		"due_at":             task.DueAt,
+		"required_approvals": task.RequiredApprovals,
+	}
+	if len(task.Approvers) == 0 {
+		task.Status = domain.ApprovalTaskRejected
+		task.CompletedAt = &now
+		updates["status"] = task.Status
+		updates["completed_at"] = now
+	}
+	if err := tx.Model(&domain.ApprovalTask{}).Where("id = ?", task.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to escalate approval task: %w", err)
+	}
+
+	s.logTransition(tx, request, "APPROVAL_TASK", task.ID, "ESCALATED", "", strings.Join(task.Approvers, ","), uuid.Nil, task.StepName)
+	if task.Status == domain.ApprovalTaskRejected {
+		return s.complete(tx, request, domain.ApprovalRequestRejected, uuid.Nil, "no approvers for step "+task.StepName, now)
+	}
+	return nil
+}
+
+// resolveApprovers turns role names and explicit users into a deduplicated list of user IDs
+func (s *ApprovalWorkflowService) resolveApprovers(
+	tx *gorm.DB,
+	tenantID uuid.UUID,
+	riskOwner uuid.UUID,
+	roles []string,
+	users []uuid.UUID,
+) []string {
+	resolved := uuidStrings(users)
+
+	var roleNames []string
+	for _, role := range roles {
+		if strings.EqualFold(role, domain.ApproverRoleRiskOwner) {
+			if riskOwner != uuid.Nil {
+				resolved = append(resolved, riskOwner.String())
+			}
+			continue
+		}
+		roleNames = append(roleNames, strings.ToLower(role))
+	}
+
+	if len(roleNames) > 0 {
+		var ids []uuid.UUID
+		tx.Table("users").
+			Joins("JOIN roles ON roles.id = users.role_id").
+			Where("LOWER(roles.name) IN ? AND users.is_active = ? AND users.deleted_at IS NULL", roleNames, true).
+			Where("users.tenant_id = ? OR users.tenant_id IS NULL", tenantID).
+			Pluck("users.id", &ids)
+		resolved = append(resolved, uuidStrings(ids)...)
+	}
+
+	return mergeApprovers(nil, resolved)
+}
+
+// delegatedSeat finds the approver on whose behalf the user may vote through an active delegation
+func (s *ApprovalWorkflowService) delegatedSeat(tx *gorm.DB, tenantID uuid.UUID, task *domain.ApprovalTask, userID uuid.UUID, now time.Time) (uuid.UUID, bool) {
+	for _, d := range s.activeDelegationsTo(tx, tenantID, userID, now) {
+		if task.HasApprover(d.DelegatorID) {
+			return d.DelegatorID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+func (s *ApprovalWorkflowService) activeDelegationsTo(tx *gorm.DB, tenantID, userID uuid.UUID, now time.Time) []domain.ApprovalDelegation {
+	var delegations []domain.ApprovalDelegation
+	tx.Where("tenant_id = ? AND delegate_id = ? AND revoked_at IS NULL AND valid_from <= ? AND valid_until > ?", tenantID, userID, now, now).
+		Find(&delegations)
+	return delegations
+}
+
+// logTransition records an approval transition in the risk change log
+func (s *ApprovalWorkflowService) logTransition(
+	tx *gorm.DB,
+	request *domain.ApprovalRequest,
+	entityType string,
+	entityID uuid.UUID,
+	changeType string,
+	oldValue string,
+	newValue string,
+	changedBy uuid.UUID,
+	reason string,
+) {
+	tx.Create(&domain.RiskChangeLog{
+		ID:               uuid.New(),
+		TenantID:         request.TenantID,
+		RiskRegisterID:   request.RiskRegisterID,
+		EntityType:       entityType,
+		EntityID:         entityID,
+		ChangeType:       changeType,
+		FieldName:        "approval_status",
+		OldValue:         oldValue,
+		NewValue:         newValue,
+		ReasonForChange:  reason,
+		ApprovalRequired: true,
+		ChangedBy:        changedBy,
+		ChangedAt:        time.Now(),
+	})
+}
+
+// selectApprovalWorkflow picks the first matching workflow from candidates ordered by priority
+func selectApprovalWorkflow(candidates []domain.ApprovalWorkflow, entityType domain.ApprovalEntityType, decisionType, riskLevel string) *domain.ApprovalWorkflow {
+	for i := range candidates {
+		if candidates[i].Matches(entityType, decisionType, riskLevel) {
+			return &candidates[i]
+		}
+	}
+	return nil
+}
+
+// nextApprovalStage returns the smallest stage greater than current, or 0 when none is left
+func nextApprovalStage(tasks []domain.ApprovalTask, current int) int {
+	stages := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Stage > current {
+			stages = append(stages, task.Stage)
+		}
+	}
+	if len(stages) == 0 {
+		return 0
+	}
+	sort.Ints(stages)
+	return stages[0]
+}
+
+// stageApproved reports whether every task of a stage has been approved
+func stageApproved(tasks []domain.ApprovalTask, stage int) bool {
+	for _, task := range tasks {
+		if task.Stage == stage && task.Status != domain.ApprovalTaskApproved {
+			return false
+		}
+	}
+	return true
+}
+
+func validateApprovalSteps(steps []domain.ApprovalStepDefinition) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("at least one approval step is required")
+	}
+	for _, step := range steps {
+		if err := step.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clampRequired keeps the required approvals reachable by the resolved approvers
+func clampRequired(required, eligible int) int {
+	if eligible > 0 && required > eligible {
+		return eligible
+	}
+	if required < 1 {
+		return 1
+	}
+	return required
+}
+
+func mergeApprovers(existing []string, added []string) []string {
+	seen := make(map[string]bool, len(existing)+len(added))
+	merged := make([]string, 0, len(existing)+len(added))
+	for _, id := range append(append([]string{}, existing...), added...) {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		merged = append(merged, id)
+	}
+	return merged
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.String())
+	}
+	return out
+}
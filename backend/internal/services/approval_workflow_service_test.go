@@ -0,0 +1,131 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestApprovalStepDefinition_RequiredApprovals(t *testing.T) {
+	tests := []struct {
+		name     string
+		step     domain.ApprovalStepDefinition
+		eligible int
+		want     int
+	}{
+		{"all", domain.ApprovalStepDefinition{Quorum: domain.QuorumAll}, 3, 3},
+		{"any", domain.ApprovalStepDefinition{Quorum: domain.QuorumAny}, 3, 1},
+		{"majority of four", domain.ApprovalStepDefinition{Quorum: domain.QuorumMajority}, 4, 3},
+		{"majority of three", domain.ApprovalStepDefinition{Quorum: domain.QuorumMajority}, 3, 2},
+		{"explicit minimum", domain.ApprovalStepDefinition{Quorum: domain.QuorumAll, MinApprovals: 2}, 5, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.step.RequiredApprovals(tt.eligible))
+		})
+	}
+}
+
+func TestApprovalStepDefinition_Validate(t *testing.T) {
+	valid := domain.ApprovalStepDefinition{Name: "CISO", Stage: 1, ApproverRoles: []string{"ciso"}, Quorum: domain.QuorumAny}
+	assert.NoError(t, valid.Validate())
+
+	noApprovers := valid
+	noApprovers.ApproverRoles = nil
+	assert.Error(t, noApprovers.Validate())
+
+	badStage := valid
+	badStage.Stage = 0
+	assert.Error(t, badStage.Validate())
+
+	badQuorum := valid
+	badQuorum.Quorum = "SOME"
+	assert.Error(t, badQuorum.Validate())
+}
+
+func TestEvaluateApprovalTask(t *testing.T) {
+	// Quorum of 2 out of 3 approvers
+	assert.Equal(t, domain.ApprovalTaskPending, domain.EvaluateApprovalTask(2, 3, 1, 0))
+	assert.Equal(t, domain.ApprovalTaskApproved, domain.EvaluateApprovalTask(2, 3, 2, 0))
+	assert.Equal(t, domain.ApprovalTaskPending, domain.EvaluateApprovalTask(2, 3, 1, 1))
+	assert.Equal(t, domain.ApprovalTaskRejected, domain.EvaluateApprovalTask(2, 3, 0, 2))
+
+	// Unanimity: a single rejection is final
+	assert.Equal(t, domain.ApprovalTaskRejected, domain.EvaluateApprovalTask(3, 3, 2, 1))
+}
+
+func TestApprovalWorkflow_Matches(t *testing.T) {
+	wf := domain.ApprovalWorkflow{
+		EntityType:   domain.ApprovalEntityDecision,
+		DecisionType: "RISK_ACCEPTANCE",
+		MinRiskLevel: "HIGH",
+		IsActive:     true,
+	}
+
+	assert.True(t, wf.Matches(domain.ApprovalEntityDecision, "risk_acceptance", "HIGH"))
+	assert.True(t, wf.Matches(domain.ApprovalEntityDecision, "RISK_ACCEPTANCE", "CRITICAL"))
+	assert.False(t, wf.Matches(domain.ApprovalEntityDecision, "RISK_ACCEPTANCE", "MEDIUM"))
+	assert.False(t, wf.Matches(domain.ApprovalEntityDecision, "RISK_TRANSFER", "HIGH"))
+	assert.False(t, wf.Matches(domain.ApprovalEntityTreatmentPlan, "RISK_ACCEPTANCE", "HIGH"))
+
+	wf.IsActive = false
+	assert.False(t, wf.Matches(domain.ApprovalEntityDecision, "RISK_ACCEPTANCE", "HIGH"))
+}
+
+func TestSelectApprovalWorkflow_UsesPriorityOrder(t *testing.T) {
+	specific := domain.ApprovalWorkflow{Name: "high acceptance", EntityType: domain.ApprovalEntityDecision, DecisionType: "RISK_ACCEPTANCE", MinRiskLevel: "HIGH", IsActive: true}
+	generic := domain.ApprovalWorkflow{Name: "default", EntityType: domain.ApprovalEntityDecision, IsActive: true}
+	candidates := []domain.ApprovalWorkflow{specific, generic}
+
+	got := selectApprovalWorkflow(candidates, domain.ApprovalEntityDecision, "RISK_ACCEPTANCE", "HIGH")
+	assert.Equal(t, "high acceptance", got.Name)
+
+	got = selectApprovalWorkflow(candidates, domain.ApprovalEntityDecision, "RISK_ACCEPTANCE", "LOW")
+	assert.Equal(t, "default", got.Name)
+
+	assert.Nil(t, selectApprovalWorkflow(candidates, domain.ApprovalEntityTreatmentPlan, "", "HIGH"))
+}
+
+func TestApprovalStages(t *testing.T) {
+	// Owner, then CISO and DPO in parallel, then CFO
+	tasks := []domain.ApprovalTask{
+		{StepName: "owner", Stage: 1, Status: domain.ApprovalTaskApproved},
+		{StepName: "ciso", Stage: 2, Status: domain.ApprovalTaskApproved},
+		{StepName: "dpo", Stage: 2, Status: domain.ApprovalTaskPending},
+		{StepName: "cfo", Stage: 3, Status: domain.ApprovalTaskWaiting},
+	}
+
+	assert.Equal(t, 1, nextApprovalStage(tasks, 0))
+	assert.Equal(t, 2, nextApprovalStage(tasks, 1))
+	assert.Equal(t, 3, nextApprovalStage(tasks, 2))
+	assert.Equal(t, 0, nextApprovalStage(tasks, 3))
+
+	assert.True(t, stageApproved(tasks, 1))
+	assert.False(t, stageApproved(tasks, 2))
+}
+
+func TestClampRequired(t *testing.T) {
+	assert.Equal(t, 2, clampRequired(3, 2))
+	assert.Equal(t, 1, clampRequired(0, 2))
+	assert.Equal(t, 3, clampRequired(3, 0))
+}
+
+func TestApprovalDelegation_IsActive(t *testing.T) {
+	now := time.Now()
+	d := domain.ApprovalDelegation{
+		DelegatorID: uuid.New(),
+		DelegateID:  uuid.New(),
+		ValidFrom:   now.Add(-time.Hour),
+		ValidUntil:  now.Add(time.Hour),
+	}
+	assert.True(t, d.IsActive(now))
+	assert.False(t, d.IsActive(now.Add(2*time.Hour)))
+
+	d.RevokedAt = &now
+	assert.False(t, d.IsActive(now))
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+)
+
+var (
+	ErrDiscoveryRunNotFound  = errors.New("asset discovery run not found")
+	ErrInventoryNotSupported = errors.New("no asset inventory connector is configured")
+)
+
+const (
+	// Changes kept on a discovery run
+	maxDiscoveryChanges = 500
+	// Review tasks opened by a retirement are due after this many days
+	retiredAssetReviewDays = 7
+)
+
+// errDiscoveryDryRun rolls back the changes of a dry run
+var errDiscoveryDryRun = errors.New("dry run")
+
+// DiscoveryOptions tune the reconciliation of an inventory. RetireMissing
+// retires the assets of the source missing from the inventory, for
+// inventories that cover the whole source.
+type DiscoveryOptions struct {
+	DryRun        bool
+	RetireMissing bool
+}
+
+// AssetDiscoveryService reconciles the assets of a source (AWS, Azure, GCP,
+// ServiceNow) with an inventory of it: new resources are created, changed
+// ones updated, and resources reported deleted or retired are retired. The
+// risks of a retired asset get an ASSET_RETIRED history entry and, when they
+// are in the risk register, a review task for their owner.
+type AssetDiscoveryService struct {
+	db   *gorm.DB
+	cmdb ports.AssetInventoryProvider
+}
+
+// NewAssetDiscoveryService creates a new asset discovery service. cmdb may be
+// nil when no CMDB connector is configured.
+func NewAssetDiscoveryService(db *gorm.DB, cmdb ports.AssetInventoryProvider) *AssetDiscoveryService {
+	return &AssetDiscoveryService{db: db, cmdb: cmdb}
+}
+
+// SyncCMDB fetches the configured CMDB and reconciles its assets. A CMDB
+// table covers the whole source, so missing assets are retired.
+func (s *AssetDiscoveryService) SyncCMDB(ctx context.Context, dryRun bool) (*domain.AssetDiscoveryRun, error) {
+	if s.cmdb == nil {
+		return nil, ErrInventoryNotSupported
+	}
+	assets, err := s.cmdb.FetchAssets(ctx)
+	if err != nil {
+		run := s.newRun(ctx, s.cmdb.Source(), "api", "", DiscoveryOptions{DryRun: dryRun, RetireMissing: true})
+		return run, s.finish(run, err)
+	}
+	return s.Reconcile(ctx, s.cmdb.Source(), "api", "", assets, DiscoveryOptions{DryRun: dryRun, RetireMissing: true})
+}
+
+// Reconcile applies an inventory of a source to its assets and records the
+// run. The run is returned with its error when the reconciliation fails.
+func (s *AssetDiscoveryService) Reconcile(ctx context.Context, source, format, fileName string, discovered []ports.DiscoveredAsset, opts DiscoveryOptions) (*domain.AssetDiscoveryRun, error) {
+	run := s.newRun(ctx, source, format, fileName, opts)
+	run.TotalCount = len(discovered)
+
+	var changes []domain.AssetDiscoveryChange
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = reconcileAssets(tx, run, discovered, opts)
+		if err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDiscoveryDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDiscoveryDryRun) {
+		err = nil
+	}
+	if len(changes) > maxDiscoveryChanges {
+		changes = changes[:maxDiscoveryChanges]
+	}
+	if len(changes) > 0 {
+		run.Changes, _ = json.Marshal(changes)
+	}
+	return run, s.finish(run, err)
+}
+
+func (s *AssetDiscoveryService) newRun(ctx context.Context, source, format, fileName string, opts DiscoveryOptions) *domain.AssetDiscoveryRun {
+	return &domain.AssetDiscoveryRun{
+		ID:            uuid.New(),
+		Source:        source,
+		Format:        format,
+		FileName:      fileName,
+		DryRun:        opts.DryRun,
+		RetireMissing: opts.RetireMissing,
+		StartedBy:     domain.ActorFrom(ctx),
+		StartedAt:     time.Now().UTC(),
+	}
+}
+
+// finish records the outcome of a run and returns the error of the run, or
+// the one of recording it
+func (s *AssetDiscoveryService) finish(run *domain.AssetDiscoveryRun, runErr error) error {
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.Status = domain.AssetDiscoveryCompleted
+	if runErr != nil {
+		run.Status = domain.AssetDiscoveryFailed
+		run.ErrorMessage = runErr.Error()
+	}
+	if err := s.db.Create(run).Error; err != nil && runErr == nil {
+		return fmt.Errorf("failed to record asset discovery run: %w", err)
+	}
+	return runErr
+}
+
+// reconcileAssets creates, updates and retires the assets of the run's source
+// and counts the changes on the run
+func reconcileAssets(tx *gorm.DB, run *domain.AssetDiscoveryRun, discovered []ports.DiscoveredAsset, opts DiscoveryOptions) ([]domain.AssetDiscoveryChange, error) {
+	var existing []domain.Asset
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("source = ?", run.Source).Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load %s assets: %w", run.Source, err)
+	}
+	byExternalID := make(map[string]*domain.Asset, len(existing))
+	for i := range existing {
+		byExternalID[existing[i].ExternalID] = &existing[i]
+	}
+
+	now := time.Now().UTC()
+	var changes []domain.AssetDiscoveryChange
+	seen := make(map[string]bool, len(discovered))
+	for _, item := range discovered {
+		incoming := item.Asset
+		seen[incoming.ExternalID] = true
+		current, ok := byExternalID[incoming.ExternalID]
+
+		if !ok {
+			if item.Retired {
+				// Never known, already gone
+				run.UnchangedCount++
+				continue
+			}
+			asset := incoming
+			asset.ID = uuid.New()
+			asset.Source = run.Source
+			if asset.Criticality == "" {
+				asset.Criticality = domain.CriticalityMedium
+			}
+			if err := tx.Omit(clause.Associations).Create(&asset).Error; err != nil {
+				return nil, fmt.Errorf("failed to create asset %q: %w", asset.ExternalID, err)
+			}
+			run.CreatedCount++
+			changes = append(changes, discoveryChange(domain.AssetDiscoveryCreate, &asset, nil))
+			continue
+		}
+
+		fields := mergeDiscoveredAsset(current, incoming)
+		action := domain.AssetDiscoveryUpdate
+		switch {
+		case item.Retired && current.RetiredAt == nil:
+			change, err := retireAsset(tx, run, current, fields, now)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change)
+			continue
+		case !item.Retired && current.RetiredAt != nil:
+			fields["retired_at"] = domain.ImportFieldChange{From: current.RetiredAt, To: nil}
+			current.RetiredAt = nil
+			action = domain.AssetDiscoveryReactivate
+		}
+		if len(fields) == 0 {
+			run.UnchangedCount++
+			continue
+		}
+		if err := tx.Omit(clause.Associations).Save(current).Error; err != nil {
+			return nil, fmt.Errorf("failed to update asset %q: %w", current.ExternalID, err)
+		}
+		run.UpdatedCount++
+		changes = append(changes, discoveryChange(action, current, fields))
+	}
+
+	if opts.RetireMissing {
+		for i := range existing {
+			current := &existing[i]
+			if seen[current.ExternalID] || current.RetiredAt != nil {
+				continue
+			}
+			change, err := retireAsset(tx, run, current, map[string]domain.ImportFieldChange{}, now)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// mergeDiscoveredAsset applies the inventory's view of an asset and returns
+// the fields changed. Owner and criticality are only set when the inventory
+// tells them, so that values set by hand are kept otherwise.
+func mergeDiscoveredAsset(current *domain.Asset, incoming domain.Asset) map[string]domain.ImportFieldChange {
+	fields := map[string]domain.ImportFieldChange{}
+	set := func(field string, target *string, value string) {
+		if *target != value {
+			fields[field] = domain.ImportFieldChange{From: *target, To: value}
+			*target = value
+		}
+	}
+	set("name", &current.Name, incoming.Name)
+	set("type", &current.Type, incoming.Type)
+	set("description", &current.Description, incoming.Description)
+	if incoming.Owner != "" {
+		set("owner", &current.Owner, incoming.Owner)
+	}
+	if incoming.Criticality != "" && incoming.Criticality != current.Criticality {
+		fields["criticality"] = domain.ImportFieldChange{From: current.Criticality, To: incoming.Criticality}
+		current.Criticality = incoming.Criticality
+	}
+	if strings.Join(current.Tags, "\n") != strings.Join(incoming.Tags, "\n") {
+		fields["tags"] = domain.ImportFieldChange{From: []string(current.Tags), To: []string(incoming.Tags)}
+		current.Tags = incoming.Tags
+	}
+	return fields
+}
+
+// retireAsset marks an asset retired and raises its risks for review
+func retireAsset(tx *gorm.DB, run *domain.AssetDiscoveryRun, asset *domain.Asset, fields map[string]domain.ImportFieldChange, now time.Time) (domain.AssetDiscoveryChange, error) {
+	fields["retired_at"] = domain.ImportFieldChange{From: nil, To: now}
+	asset.RetiredAt = &now
+	if err := tx.Omit(clause.Associations).Save(asset).Error; err != nil {
+		return domain.AssetDiscoveryChange{}, fmt.Errorf("failed to retire asset %q: %w", asset.ExternalID, err)
+	}
+	riskIDs, err := raiseRetiredAssetReviews(tx, asset, now)
+	if err != nil {
+		return domain.AssetDiscoveryChange{}, err
+	}
+	run.RetiredCount++
+	run.ReviewCount += len(riskIDs)
+	change := discoveryChange(domain.AssetDiscoveryRetire, asset, fields)
+	change.RiskIDs = riskIDs
+	return change, nil
+}
+
+// raiseRetiredAssetReviews records the retirement in the history of the
+// asset's risks and opens a review task for those in the risk register that
+// have none pending. It returns the risks raised.
+func raiseRetiredAssetReviews(tx *gorm.DB, asset *domain.Asset, now time.Time) ([]uuid.UUID, error) {
+	var riskIDs []uuid.UUID
+	if err := tx.Model(&domain.Risk{}).
+		Joins("JOIN risk_assets ON risk_assets.risk_id = risks.id").
+		Where("risk_assets.asset_id = ?", asset.ID).
+		Order("risks.id").Pluck("risks.id", &riskIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list risks of asset %q: %w", asset.ExternalID, err)
+	}
+	if len(riskIDs) == 0 {
+		return nil, nil
+	}
+	for _, riskID := range riskIDs {
+		if err := domain.RecordRiskChange(tx, riskID, domain.RiskChangeAssetRetired); err != nil {
+			return nil, err
+		}
+	}
+
+	var registers []domain.RiskRegister
+	if err := tx.Where("risk_id IN ? AND status <> ?", riskIDs, "CLOSED").Find(&registers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk register: %w", err)
+	}
+	var pending []uuid.UUID
+	if err := tx.Model(&domain.ReviewTask{}).
+		Where("risk_id IN ? AND status IN ?", riskIDs, []domain.ReviewTaskStatus{domain.ReviewTaskOpen, domain.ReviewTaskOverdue}).
+		Pluck("risk_id", &pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to load review tasks: %w", err)
+	}
+	hasTask := make(map[uuid.UUID]bool, len(pending))
+	for _, id := range pending {
+		hasTask[id] = true
+	}
+
+	titles := map[uuid.UUID]string{}
+	var risks []domain.Risk
+	if err := tx.Select("id", "title").Where("id IN ?", riskIDs).Find(&risks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risks: %w", err)
+	}
+	for _, r := range risks {
+		titles[r.ID] = r.Title
+	}
+	for _, register := range registers {
+		if hasTask[register.RiskID] {
+			continue
+		}
+		task := domain.ReviewTask{
+			ID:             uuid.New(),
+			TenantID:       register.TenantID,
+			RiskRegisterID: register.ID,
+			RiskID:         register.RiskID,
+			Title:          fmt.Sprintf("Review: %s (asset %s retired)", titles[register.RiskID], asset.Name),
+			AssigneeID:     register.RiskOwner,
+			RiskLevel:      register.ResidualRiskLevel,
+			DueDate:        now.AddDate(0, 0, retiredAssetReviewDays),
+			Status:         domain.ReviewTaskOpen,
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return nil, fmt.Errorf("failed to open review task: %w", err)
+		}
+		hasTask[register.RiskID] = true
+	}
+	return riskIDs, nil
+}
+
+func discoveryChange(action string, asset *domain.Asset, fields map[string]domain.ImportFieldChange) domain.AssetDiscoveryChange {
+	id := asset.ID
+	if len(fields) == 0 {
+		fields = nil
+	}
+	return domain.AssetDiscoveryChange{
+		Action:     action,
+		AssetID:    &id,
+		ExternalID: asset.ExternalID,
+		Name:       asset.Name,
+		Fields:     fields,
+	}
+}
+
+// ListRuns returns the discovery runs, most recent first, without their changes
+func (s *AssetDiscoveryService) ListRuns(source string, limit, offset int) ([]domain.AssetDiscoveryRun, int64, error) {
+	query := s.db.Model(&domain.AssetDiscoveryRun{})
+	if source != "" {
+		query = query.Where("source = ?", strings.ToUpper(source))
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count asset discovery runs: %w", err)
+	}
+	var runs []domain.AssetDiscoveryRun
+	if err := query.Omit("changes").Order("started_at DESC").Limit(limit).Offset(offset).Find(&runs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list asset discovery runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// GetRun returns a discovery run with its changes
+func (s *AssetDiscoveryService) GetRun(id uuid.UUID) (*domain.AssetDiscoveryRun, error) {
+	var run domain.AssetDiscoveryRun
+	if err := s.db.Take(&run, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDiscoveryRunNotFound
+		}
+		return nil, fmt.Errorf("failed to load asset discovery run: %w", err)
+	}
+	return &run, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestMergeDiscoveredAsset(t *testing.T) {
+	current := &domain.Asset{
+		Name:        "web-1",
+		Type:        "Server",
+		Owner:       "alice",
+		Criticality: domain.CriticalityCritical,
+		Tags:        pq.StringArray{"env=prod"},
+	}
+
+	// The inventory tells neither owner nor criticality: the values set by hand are kept
+	fields := mergeDiscoveredAsset(current, domain.Asset{Name: "web-1", Type: "Server", Tags: pq.StringArray{"env=prod"}})
+	assert.Empty(t, fields)
+	assert.Equal(t, "alice", current.Owner)
+	assert.Equal(t, domain.CriticalityCritical, current.Criticality)
+
+	fields = mergeDiscoveredAsset(current, domain.Asset{
+		Name:        "web-01",
+		Type:        "Server",
+		Owner:       "platform",
+		Criticality: domain.CriticalityHigh,
+		Tags:        pq.StringArray{"env=prod", "team=platform"},
+	})
+	assert.Len(t, fields, 4)
+	assert.Equal(t, domain.ImportFieldChange{From: "web-1", To: "web-01"}, fields["name"])
+	assert.Equal(t, domain.ImportFieldChange{From: "alice", To: "platform"}, fields["owner"])
+	assert.Equal(t, domain.ImportFieldChange{From: domain.CriticalityCritical, To: domain.CriticalityHigh}, fields["criticality"])
+	assert.Contains(t, fields, "tags")
+	assert.Equal(t, "web-01", current.Name)
+	assert.Equal(t, domain.CriticalityHigh, current.Criticality)
+}
@@ -0,0 +1,751 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrAssetNotFound           = errors.New("asset not found")
+	ErrInvalidAsset            = errors.New("invalid asset")
+	ErrAssetHasChildren        = errors.New("asset has child assets")
+	ErrRelationshipNotFound    = errors.New("asset relationship not found")
+	ErrRelationshipExists      = errors.New("asset relationship already exists")
+	ErrBusinessServiceNotFound = errors.New("business service not found")
+	ErrInvalidBusinessService  = errors.New("invalid business service")
+)
+
+// AssetService manages the asset inventory: assets and their hierarchy, typed
+// relationships between assets, and business services composed of assets
+type AssetService struct {
+	db *gorm.DB
+}
+
+// NewAssetService creates a new asset service
+func NewAssetService(db *gorm.DB) *AssetService {
+	return &AssetService{db: db}
+}
+
+// AssetFilter narrows GET /assets. Search matches the name, owner, description
+// and external ID.
+type AssetFilter struct {
+	Search      string
+	Type        string
+	Criticality string
+	Owner       string
+	Source      string
+	Tag         string
+	ParentID    *uuid.UUID
+	RootsOnly   bool // Only assets without a parent
+	ServiceID   *uuid.UUID
+	Retired     *bool // Only retired, or only active, assets
+	Limit       int
+	Offset      int
+}
+
+// AssetInput is the editable part of an asset. On update, nil fields are left
+// unchanged and an empty parent_id detaches the asset from its parent.
+type AssetInput struct {
+	Name        *string                  `json:"name"`
+	Type        *string                  `json:"type"`
+	Criticality *domain.AssetCriticality `json:"criticality"`
+	Owner       *string                  `json:"owner"`
+	Description *string                  `json:"description"`
+	Tags        []string                 `json:"tags"`
+	ParentID    *string                  `json:"parent_id"`
+	Source      *string                  `json:"source"`
+	ExternalID  *string                  `json:"external_id"`
+}
+
+// AssetTree is an asset with its ancestors, root first, and its descendants
+// nested in Children
+type AssetTree struct {
+	Asset     *domain.Asset  `json:"asset"`
+	Ancestors []domain.Asset `json:"ancestors"`
+}
+
+// ListAssets returns a page of assets matching the filter, by name, with
+// their linked risks
+func (s *AssetService) ListAssets(filter AssetFilter) ([]domain.Asset, int64, error) {
+	query := s.db.Model(&domain.Asset{})
+	if q := strings.TrimSpace(filter.Search); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("name ILIKE ? OR owner ILIKE ? OR description ILIKE ? OR external_id ILIKE ?", like, like, like, like)
+	}
+	if filter.Type != "" {
+		query = query.Where("LOWER(type) = LOWER(?)", filter.Type)
+	}
+	if filter.Criticality != "" {
+		query = query.Where("criticality = ?", strings.ToUpper(filter.Criticality))
+	}
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", strings.ToUpper(filter.Source))
+	}
+	if filter.Tag != "" {
+		query = query.Where("? = ANY(tags)", filter.Tag)
+	}
+	if filter.ParentID != nil {
+		query = query.Where("parent_id = ?", *filter.ParentID)
+	} else if filter.RootsOnly {
+		query = query.Where("parent_id IS NULL")
+	}
+	if filter.Retired != nil {
+		if *filter.Retired {
+			query = query.Where("retired_at IS NOT NULL")
+		} else {
+			query = query.Where("retired_at IS NULL")
+		}
+	}
+	if filter.ServiceID != nil {
+		query = query.Where("id IN (?)", s.db.Table("business_service_assets").
+			Select("asset_id").Where("business_service_id = ?", *filter.ServiceID))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count assets: %w", err)
+	}
+	var assets []domain.Asset
+	if err := query.Preload("Risks").Order("name ASC, id ASC").
+		Limit(filter.Limit).Offset(filter.Offset).Find(&assets).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list assets: %w", err)
+	}
+	return assets, total, nil
+}
+
+// GetAsset returns an asset with its linked risks and direct children
+func (s *AssetService) GetAsset(id uuid.UUID) (*domain.Asset, error) {
+	var asset domain.Asset
+	err := s.db.Preload("Risks").Preload("Children", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name ASC")
+	}).Take(&asset, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAssetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset: %w", err)
+	}
+	return &asset, nil
+}
+
+// CreateAsset validates and creates an asset
+func (s *AssetService) CreateAsset(ctx context.Context, input AssetInput) (*domain.Asset, error) {
+	asset := &domain.Asset{Criticality: domain.CriticalityMedium, Source: "MANUAL"}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := applyAssetInput(tx, asset, input); err != nil {
+			return err
+		}
+		return tx.Create(asset).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// UpdateAsset applies the set fields of the input to an asset. An asset
+// cannot be moved under itself or one of its descendants.
+func (s *AssetService) UpdateAsset(ctx context.Context, id uuid.UUID, input AssetInput) (*domain.Asset, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var asset domain.Asset
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&asset, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAssetNotFound
+			}
+			return fmt.Errorf("failed to load asset: %w", err)
+		}
+		if err := applyAssetInput(tx, &asset, input); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(&asset).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAsset(id)
+}
+
+// DeleteAsset moves an asset to the recycle bin. Assets with children must
+// have them moved or deleted first.
+func (s *AssetService) DeleteAsset(ctx context.Context, id uuid.UUID) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&domain.Asset{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return fmt.Errorf("failed to count child assets: %w", err)
+		}
+		if children > 0 {
+			return fmt.Errorf("%w: move or delete its %d children first", ErrAssetHasChildren, children)
+		}
+		result := tx.Delete(&domain.Asset{ID: id})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete asset: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrAssetNotFound
+		}
+		return nil
+	})
+}
+
+// GetAssetTree returns an asset with its ancestors and all its descendants
+func (s *AssetService) GetAssetTree(id uuid.UUID) (*AssetTree, error) {
+	var assets []domain.Asset
+	err := s.db.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM assets WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT a.id FROM assets a JOIN subtree s ON a.parent_id = s.id WHERE a.deleted_at IS NULL
+		)
+		SELECT assets.* FROM assets JOIN subtree ON subtree.id = assets.id
+		ORDER BY assets.name ASC, assets.id ASC`, id).Scan(&assets).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset tree: %w", err)
+	}
+	root := buildAssetTree(id, assets)
+	if root == nil {
+		return nil, ErrAssetNotFound
+	}
+
+	var ancestors []domain.Asset
+	err = s.db.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT parent_id AS id, 1 AS depth FROM assets WHERE id = ?
+			UNION
+			SELECT a.parent_id, an.depth + 1 FROM assets a JOIN ancestors an ON a.id = an.id
+			WHERE a.parent_id IS NOT NULL AND an.depth < 100
+		)
+		SELECT assets.* FROM assets JOIN ancestors ON ancestors.id = assets.id
+		WHERE assets.deleted_at IS NULL ORDER BY ancestors.depth DESC`, id).Scan(&ancestors).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset ancestors: %w", err)
+	}
+	return &AssetTree{Asset: root, Ancestors: ancestors}, nil
+}
+
+// buildAssetTree nests assets under their parents and returns the one with
+// the given ID; nil when it is not among them
+func buildAssetTree(rootID uuid.UUID, assets []domain.Asset) *domain.Asset {
+	byID := make(map[uuid.UUID]*domain.Asset, len(assets))
+	for i := range assets {
+		byID[assets[i].ID] = &assets[i]
+	}
+	root := byID[rootID]
+	for i := range assets {
+		a := &assets[i]
+		if a.ID == rootID || a.ParentID == nil {
+			continue
+		}
+		if parent, ok := byID[*a.ParentID]; ok {
+			parent.Children = append(parent.Children, a)
+		}
+	}
+	return root
+}
+
+// applyAssetInput sets the fields of the input on an asset and validates it
+func applyAssetInput(tx *gorm.DB, asset *domain.Asset, input AssetInput) error {
+	if input.Name != nil {
+		asset.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Type != nil {
+		asset.Type = strings.TrimSpace(*input.Type)
+	}
+	if input.Criticality != nil {
+		asset.Criticality = domain.AssetCriticality(strings.ToUpper(string(*input.Criticality)))
+	}
+	if input.Owner != nil {
+		asset.Owner = strings.TrimSpace(*input.Owner)
+	}
+	if input.Description != nil {
+		asset.Description = *input.Description
+	}
+	if input.Tags != nil {
+		asset.Tags = pq.StringArray(input.Tags)
+	}
+	if input.Source != nil && *input.Source != "" {
+		asset.Source = strings.ToUpper(*input.Source)
+	}
+	if input.ExternalID != nil {
+		asset.ExternalID = *input.ExternalID
+	}
+
+	if asset.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAsset)
+	}
+	if len(asset.Name) > 255 {
+		return fmt.Errorf("%w: name must be at most 255 characters", ErrInvalidAsset)
+	}
+	if !asset.Criticality.IsValid() {
+		return fmt.Errorf("%w: criticality must be LOW, MEDIUM, HIGH or CRITICAL", ErrInvalidAsset)
+	}
+
+	if input.ParentID == nil {
+		return nil
+	}
+	if *input.ParentID == "" {
+		asset.ParentID = nil
+		return nil
+	}
+	parentID, err := uuid.Parse(*input.ParentID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid parent_id", ErrInvalidAsset)
+	}
+	if err := validateAssetParent(tx, asset.ID, parentID); err != nil {
+		return err
+	}
+	asset.ParentID = &parentID
+	return nil
+}
+
+// validateAssetParent checks that the parent exists and that it is neither
+// the asset nor one of its descendants, which would make a cycle
+func validateAssetParent(tx *gorm.DB, assetID, parentID uuid.UUID) error {
+	if parentID == assetID {
+		return fmt.Errorf("%w: an asset cannot be its own parent", ErrInvalidAsset)
+	}
+	var parent domain.Asset
+	if err := tx.Select("id").Take(&parent, "id = ?", parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: parent asset not found", ErrInvalidAsset)
+		}
+		return fmt.Errorf("failed to load parent asset: %w", err)
+	}
+	if assetID == uuid.Nil {
+		return nil
+	}
+
+	var cycles int64
+	err := tx.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT parent_id AS id FROM assets WHERE id = ?
+			UNION
+			SELECT a.parent_id FROM assets a JOIN ancestors an ON a.id = an.id WHERE a.parent_id IS NOT NULL
+		)
+		SELECT COUNT(*) FROM ancestors WHERE id = ?`, parentID, assetID).Scan(&cycles).Error
+	if err != nil {
+		return fmt.Errorf("failed to check asset hierarchy: %w", err)
+	}
+	if cycles > 0 {
+		return fmt.Errorf("%w: an asset cannot be moved under one of its descendants", ErrInvalidAsset)
+	}
+	return nil
+}
+
+// AssetRelationshipInput creates a relationship from an asset
+type AssetRelationshipInput struct {
+	TargetID    uuid.UUID                `json:"target_id"`
+	Type        domain.AssetRelationType `json:"type"`
+	Description string                   `json:"description"`
+}
+
+// ListRelationships returns the relationships of an asset in both directions,
+// with both ends. Relationships with an asset in the recycle bin are hidden
+// until it is restored.
+func (s *AssetService) ListRelationships(assetID uuid.UUID) ([]domain.AssetRelationship, error) {
+	if _, err := s.GetAsset(assetID); err != nil {
+		return nil, err
+	}
+	live := s.db.Model(&domain.Asset{}).Select("id")
+	var relationships []domain.AssetRelationship
+	err := s.db.Preload("Source").Preload("Target").
+		Where("source_id = ? OR target_id = ?", assetID, assetID).
+		Where("source_id IN (?) AND target_id IN (?)", live, live).
+		Order("type ASC, created_at ASC").Find(&relationships).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list asset relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+// CreateRelationship relates an asset to another, e.g. "app depends_on db"
+func (s *AssetService) CreateRelationship(ctx context.Context, sourceID uuid.UUID, input AssetRelationshipInput) (*domain.AssetRelationship, error) {
+	relationship := &domain.AssetRelationship{
+		SourceID:    sourceID,
+		TargetID:    input.TargetID,
+		Type:        domain.AssetRelationType(strings.ToLower(string(input.Type))),
+		Description: input.Description,
+	}
+	if !relationship.Type.IsValid() {
+		return nil, fmt.Errorf("%w: type must be depends_on, hosts, connects_to or processes_data_of", ErrInvalidAsset)
+	}
+	if sourceID == input.TargetID {
+		return nil, fmt.Errorf("%w: an asset cannot be related to itself", ErrInvalidAsset)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var found int64
+		if err := tx.Model(&domain.Asset{}).Where("id IN ?", []uuid.UUID{sourceID, input.TargetID}).Count(&found).Error; err != nil {
+			return fmt.Errorf("failed to load assets: %w", err)
+		}
+		if found < 2 {
+			var source int64
+			tx.Model(&domain.Asset{}).Where("id = ?", sourceID).Count(&source)
+			if source == 0 {
+				return ErrAssetNotFound
+			}
+			return fmt.Errorf("%w: target asset not found", ErrInvalidAsset)
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(relationship)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create asset relationship: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrRelationshipExists
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Preload("Source").Preload("Target").Take(relationship, "id = ?", relationship.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load asset relationship: %w", err)
+	}
+	return relationship, nil
+}
+
+// DeleteRelationship removes a relationship of an asset
+func (s *AssetService) DeleteRelationship(ctx context.Context, assetID, relationshipID uuid.UUID) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND (source_id = ? OR target_id = ?)", relationshipID, assetID, assetID).
+		Delete(&domain.AssetRelationship{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete asset relationship: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRelationshipNotFound
+	}
+	return nil
+}
+
+// BusinessServiceInput is the editable part of a business service. On update,
+// nil fields are left unchanged.
+type BusinessServiceInput struct {
+	Name        *string                  `json:"name"`
+	Description *string                  `json:"description"`
+	Owner       *string                  `json:"owner"`
+	Criticality *domain.AssetCriticality `json:"criticality"`
+	AssetIDs    []uuid.UUID              `json:"asset_ids"` // On create only
+}
+
+// ServiceRiskSummary aggregates the risks linked to the assets of a business
+// service and to their descendants. Each risk counts once, however many of the
+// service's assets it is linked to. Levels count open risks only.
+type ServiceRiskSummary struct {
+	AssetCount     int            `json:"asset_count"`
+	RiskCount      int            `json:"risk_count"`
+	OpenRiskCount  int            `json:"open_risk_count"`
+	OpenByLevel    map[string]int `json:"open_by_level"`
+	MaxScore       float64        `json:"max_score"`
+	TotalOpenScore float64        `json:"total_open_score"`
+}
+
+// BusinessServiceView is a business service with its aggregate risk
+type BusinessServiceView struct {
+	domain.BusinessService
+	Risk ServiceRiskSummary `json:"risk"`
+}
+
+// ListBusinessServices returns the business services by name with their
+// aggregate risk
+func (s *AssetService) ListBusinessServices(search string) ([]BusinessServiceView, error) {
+	query := s.db.Model(&domain.BusinessService{})
+	if q := strings.TrimSpace(search); q != "" {
+		query = query.Where("name ILIKE ? OR owner ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+	var services []domain.BusinessService
+	if err := query.Order("name ASC").Find(&services).Error; err != nil {
+		return nil, fmt.Errorf("failed to list business services: %w", err)
+	}
+
+	ids := make([]uuid.UUID, len(services))
+	for i, svc := range services {
+		ids[i] = svc.ID
+	}
+	summaries, err := s.serviceRiskSummaries(ids)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]BusinessServiceView, len(services))
+	for i, svc := range services {
+		views[i] = BusinessServiceView{BusinessService: svc, Risk: summaries[svc.ID]}
+	}
+	return views, nil
+}
+
+// GetBusinessService returns a business service with its assets and its
+// aggregate risk
+func (s *AssetService) GetBusinessService(id uuid.UUID) (*BusinessServiceView, error) {
+	var svc domain.BusinessService
+	err := s.db.Preload("Assets", func(db *gorm.DB) *gorm.DB {
+		return db.Order("assets.name ASC")
+	}).Take(&svc, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBusinessServiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load business service: %w", err)
+	}
+	summaries, err := s.serviceRiskSummaries([]uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	return &BusinessServiceView{BusinessService: svc, Risk: summaries[id]}, nil
+}
+
+// CreateBusinessService validates and creates a business service with its
+// initial assets
+func (s *AssetService) CreateBusinessService(ctx context.Context, input BusinessServiceInput) (*BusinessServiceView, error) {
+	svc := &domain.BusinessService{Criticality: domain.CriticalityMedium}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := applyBusinessServiceInput(tx, svc, input); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(svc).Error; err != nil {
+			return fmt.Errorf("failed to create business service: %w", err)
+		}
+		return linkServiceAssets(tx, svc.ID, input.AssetIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBusinessService(svc.ID)
+}
+
+// UpdateBusinessService applies the set fields of the input to a business
+// service
+func (s *AssetService) UpdateBusinessService(ctx context.Context, id uuid.UUID, input BusinessServiceInput) (*BusinessServiceView, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var svc domain.BusinessService
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&svc, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBusinessServiceNotFound
+			}
+			return fmt.Errorf("failed to load business service: %w", err)
+		}
+		if err := applyBusinessServiceInput(tx, &svc, input); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(&svc).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBusinessService(id)
+}
+
+// DeleteBusinessService deletes a business service. Its assets are kept.
+func (s *AssetService) DeleteBusinessService(ctx context.Context, id uuid.UUID) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM business_service_assets WHERE business_service_id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to unlink business service assets: %w", err)
+		}
+		result := tx.Delete(&domain.BusinessService{ID: id})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete business service: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrBusinessServiceNotFound
+		}
+		return nil
+	})
+}
+
+// AddServiceAssets adds assets to a business service
+func (s *AssetService) AddServiceAssets(ctx context.Context, id uuid.UUID, assetIDs []uuid.UUID) (*BusinessServiceView, error) {
+	if len(assetIDs) == 0 {
+		return nil, fmt.Errorf("%w: asset_ids is required", ErrInvalidBusinessService)
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var found int64
+		if err := tx.Model(&domain.BusinessService{}).Where("id = ?", id).Count(&found).Error; err != nil {
+			return fmt.Errorf("failed to load business service: %w", err)
+		}
+		if found == 0 {
+			return ErrBusinessServiceNotFound
+		}
+		return linkServiceAssets(tx, id, assetIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBusinessService(id)
+}
+
+// RemoveServiceAsset removes an asset from a business service
+func (s *AssetService) RemoveServiceAsset(ctx context.Context, id, assetID uuid.UUID) error {
+	result := s.db.WithContext(ctx).
+		Exec("DELETE FROM business_service_assets WHERE business_service_id = ? AND asset_id = ?", id, assetID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove asset from business service: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAssetNotFound
+	}
+	return nil
+}
+
+func applyBusinessServiceInput(tx *gorm.DB, svc *domain.BusinessService, input BusinessServiceInput) error {
+	if input.Name != nil {
+		svc.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		svc.Description = *input.Description
+	}
+	if input.Owner != nil {
+		svc.Owner = strings.TrimSpace(*input.Owner)
+	}
+	if input.Criticality != nil {
+		svc.Criticality = domain.AssetCriticality(strings.ToUpper(string(*input.Criticality)))
+	}
+
+	if svc.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidBusinessService)
+	}
+	if len(svc.Name) > 255 {
+		return fmt.Errorf("%w: name must be at most 255 characters", ErrInvalidBusinessService)
+	}
+	if !svc.Criticality.IsValid() {
+		return fmt.Errorf("%w: criticality must be LOW, MEDIUM, HIGH or CRITICAL", ErrInvalidBusinessService)
+	}
+	var taken int64
+	if err := tx.Model(&domain.BusinessService{}).Where("LOWER(name) = LOWER(?) AND id <> ?", svc.Name, svc.ID).
+		Count(&taken).Error; err != nil {
+		return fmt.Errorf("failed to check business service name: %w", err)
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: a business service named %q already exists", ErrInvalidBusinessService, svc.Name)
+	}
+	return nil
+}
+
+// linkServiceAssets adds assets to a service, ignoring those already in it
+func linkServiceAssets(tx *gorm.DB, serviceID uuid.UUID, assetIDs []uuid.UUID) error {
+	if len(assetIDs) == 0 {
+		return nil
+	}
+	var found int64
+	if err := tx.Model(&domain.Asset{}).Where("id IN ?", assetIDs).Count(&found).Error; err != nil {
+		return fmt.Errorf("failed to load assets: %w", err)
+	}
+	if int(found) != len(uniqueUUIDs(assetIDs)) {
+		return fmt.Errorf("%w: unknown asset in asset_ids", ErrInvalidBusinessService)
+	}
+	err := tx.Exec(`INSERT INTO business_service_assets (business_service_id, asset_id)
+		SELECT ?, id FROM assets WHERE id IN ? AND deleted_at IS NULL
+		ON CONFLICT DO NOTHING`, serviceID, assetIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to add assets to business service: %w", err)
+	}
+	return nil
+}
+
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// serviceAssetRisk is an asset of a service, directly or as a descendant of
+// one, with one of its risks; RiskID is nil for assets without risks
+type serviceAssetRisk struct {
+	ServiceID uuid.UUID
+	AssetID   uuid.UUID
+	RiskID    *uuid.UUID
+	Score     float64
+	Status    domain.RiskStatus
+}
+
+// serviceRiskSummaries aggregates the risks of services, walking the asset
+// hierarchy down from their assets
+func (s *AssetService) serviceRiskSummaries(serviceIDs []uuid.UUID) (map[uuid.UUID]ServiceRiskSummary, error) {
+	if len(serviceIDs) == 0 {
+		return map[uuid.UUID]ServiceRiskSummary{}, nil
+	}
+	var rows []serviceAssetRisk
+	err := s.db.Raw(`WITH RECURSIVE service_assets AS (
+			SELECT bsa.business_service_id AS service_id, a.id AS asset_id
+			FROM business_service_assets bsa JOIN assets a ON a.id = bsa.asset_id AND a.deleted_at IS NULL
+			WHERE bsa.business_service_id IN ?
+			UNION
+			SELECT sa.service_id, c.id FROM assets c JOIN service_assets sa ON c.parent_id = sa.asset_id
+			WHERE c.deleted_at IS NULL
+		)
+		SELECT sa.service_id, sa.asset_id, r.id AS risk_id, COALESCE(r.score, 0) AS score, COALESCE(r.status, '') AS status
+		FROM service_assets sa
+		LEFT JOIN risk_assets ra ON ra.asset_id = sa.asset_id
+		LEFT JOIN risks r ON r.id = ra.risk_id AND r.deleted_at IS NULL`, serviceIDs).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate business service risks: %w", err)
+	}
+	summaries := summarizeServiceRisks(rows)
+	for _, id := range serviceIDs {
+		if _, ok := summaries[id]; !ok {
+			summaries[id] = ServiceRiskSummary{OpenByLevel: map[string]int{}}
+		}
+	}
+	return summaries, nil
+}
+
+func summarizeServiceRisks(rows []serviceAssetRisk) map[uuid.UUID]ServiceRiskSummary {
+	type accumulator struct {
+		assets map[uuid.UUID]bool
+		risks  map[uuid.UUID]serviceAssetRisk
+	}
+	byService := map[uuid.UUID]*accumulator{}
+	for _, row := range rows {
+		acc, ok := byService[row.ServiceID]
+		if !ok {
+			acc = &accumulator{assets: map[uuid.UUID]bool{}, risks: map[uuid.UUID]serviceAssetRisk{}}
+			byService[row.ServiceID] = acc
+		}
+		acc.assets[row.AssetID] = true
+		if row.RiskID != nil {
+			acc.risks[*row.RiskID] = row
+		}
+	}
+
+	summaries := make(map[uuid.UUID]ServiceRiskSummary, len(byService))
+	for serviceID, acc := range byService {
+		summary := ServiceRiskSummary{
+			AssetCount:  len(acc.assets),
+			RiskCount:   len(acc.risks),
+			OpenByLevel: map[string]int{},
+		}
+		riskIDs := make([]uuid.UUID, 0, len(acc.risks))
+		for id := range acc.risks {
+			riskIDs = append(riskIDs, id)
+		}
+		// Summing in a fixed order keeps the float totals stable
+		sort.Slice(riskIDs, func(i, j int) bool { return riskIDs[i].String() < riskIDs[j].String() })
+		for _, id := range riskIDs {
+			risk := acc.risks[id]
+			if risk.Score > summary.MaxScore {
+				summary.MaxScore = risk.Score
+			}
+			if !riskOpen(risk.Status) {
+				continue
+			}
+			summary.OpenRiskCount++
+			summary.OpenByLevel[riskLevelForScore(risk.Score)]++
+			summary.TotalOpenScore += risk.Score
+		}
+		summaries[serviceID] = summary
+	}
+	return summaries
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestBuildAssetTree(t *testing.T) {
+	datacenter, cluster, host, other := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	assets := []domain.Asset{
+		{ID: cluster, Name: "cluster", ParentID: &datacenter},
+		{ID: datacenter, Name: "datacenter"},
+		{ID: host, Name: "host", ParentID: &cluster},
+		{ID: other, Name: "other", ParentID: &host},
+	}
+
+	root := buildAssetTree(datacenter, assets)
+	require.NotNil(t, root)
+	require.Len(t, root.Children, 1)
+	assert.Equal(t, cluster, root.Children[0].ID)
+	require.Len(t, root.Children[0].Children, 1)
+	assert.Equal(t, host, root.Children[0].Children[0].ID)
+	assert.Len(t, root.Children[0].Children[0].Children, 1)
+
+	assert.Nil(t, buildAssetTree(uuid.New(), assets))
+}
+
+func TestSummarizeServiceRisks(t *testing.T) {
+	payments, empty := uuid.New(), uuid.New()
+	api, db := uuid.New(), uuid.New()
+	shared, open, mitigated := uuid.New(), uuid.New(), uuid.New()
+
+	summaries := summarizeServiceRisks([]serviceAssetRisk{
+		// A risk linked to two assets of the service counts once
+		{ServiceID: payments, AssetID: api, RiskID: &shared, Score: 20, Status: domain.StatusActive},
+		{ServiceID: payments, AssetID: db, RiskID: &shared, Score: 20, Status: domain.StatusActive},
+		{ServiceID: payments, AssetID: db, RiskID: &open, Score: 9, Status: domain.StatusDraft},
+		{ServiceID: payments, AssetID: api, RiskID: &mitigated, Score: 25, Status: domain.StatusMitigated},
+		{ServiceID: empty, AssetID: api},
+	})
+
+	got := summaries[payments]
+	assert.Equal(t, 2, got.AssetCount)
+	assert.Equal(t, 3, got.RiskCount)
+	assert.Equal(t, 2, got.OpenRiskCount)
+	assert.Equal(t, map[string]int{"CRITICAL": 1, "MEDIUM": 1}, got.OpenByLevel)
+	assert.Equal(t, 25.0, got.MaxScore)
+	assert.Equal(t, 29.0, got.TotalOpenScore)
+
+	assert.Equal(t, ServiceRiskSummary{AssetCount: 1, OpenByLevel: map[string]int{}}, summaries[empty])
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrAttackObjectNotFound = errors.New("ATT&CK object not found")
+	ErrUnknownAttackID      = errors.New("unknown ATT&CK ID")
+)
+
+// AttackService keeps the MITRE ATT&CK catalog loaded from the Enterprise
+// STIX bundle, validates the techniques linked to risks and the
+// countermeasures linked to mitigations, and computes the coverage of
+// techniques by open risks and done mitigations
+type AttackService struct {
+	db *gorm.DB
+}
+
+// NewAttackService creates a new ATT&CK service
+func NewAttackService(db *gorm.DB) *AttackService {
+	return &AttackService{db: db}
+}
+
+// AttackObjectDetail is an ATT&CK object with its related objects: the
+// sub-techniques and mitigations of a technique, the techniques a mitigation
+// mitigates or of a tactic
+type AttackObjectDetail struct {
+	domain.AttackObject
+	Subtechniques []domain.AttackObject `json:"subtechniques,omitempty"`
+	Mitigations   []domain.AttackObject `json:"mitigations,omitempty"`
+	Techniques    []domain.AttackObject `json:"techniques,omitempty"`
+}
+
+// ImportBundle replaces the catalog with the objects of a bundle. Objects the
+// bundle no longer has are kept for existing links to resolve.
+func (s *AttackService) ImportBundle(ctx context.Context, bundle domain.AttackBundleImport, objects []domain.AttackObject, mitigates []domain.AttackMitigates) (*domain.AttackBundleImport, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(objects) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				UpdateAll: true,
+			}).CreateInBatches(objects, 500).Error; err != nil {
+				return fmt.Errorf("failed to store ATT&CK objects: %w", err)
+			}
+		}
+		if err := tx.Where("1 = 1").Delete(&domain.AttackMitigates{}).Error; err != nil {
+			return fmt.Errorf("failed to clear ATT&CK mitigations: %w", err)
+		}
+		if len(mitigates) > 0 {
+			if err := tx.CreateInBatches(mitigates, 1000).Error; err != nil {
+				return fmt.Errorf("failed to store ATT&CK mitigations: %w", err)
+			}
+		}
+		bundle.ID = uuid.New()
+		if err := tx.Create(&bundle).Error; err != nil {
+			return fmt.Errorf("failed to record ATT&CK bundle import: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// LatestImport returns the last load of the bundle, nil if none
+func (s *AttackService) LatestImport() (*domain.AttackBundleImport, error) {
+	var last domain.AttackBundleImport
+	err := s.db.Order("created_at DESC").Take(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ATT&CK bundle imports: %w", err)
+	}
+	return &last, nil
+}
+
+// ListImports returns the last loads of the bundle, most recent first
+func (s *AttackService) ListImports(limit int) ([]domain.AttackBundleImport, error) {
+	var imports []domain.AttackBundleImport
+	if err := s.db.Order("created_at DESC").Limit(limit).Find(&imports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list ATT&CK bundle imports: %w", err)
+	}
+	return imports, nil
+}
+
+// ListObjects lists the objects of a kind, by ID, optionally matching a
+// query on their ID or name and, for techniques, of a tactic (short name)
+func (s *AttackService) ListObjects(kind, query, tactic string, includeDeprecated bool, limit int) ([]domain.AttackObject, error) {
+	db := s.db.Model(&domain.AttackObject{}).Where("type = ?", kind)
+	if !includeDeprecated {
+		db = db.Where("deprecated = ?", false)
+	}
+	if query != "" {
+		db = db.Where("id ILIKE ? OR name ILIKE ?", query+"%", "%"+query+"%")
+	}
+	if tactic != "" {
+		db = db.Where("? = ANY(tactics)", tactic)
+	}
+	var objects []domain.AttackObject
+	if err := db.Order("id").Limit(limit).Find(&objects).Error; err != nil {
+		return nil, fmt.Errorf("failed to list ATT&CK objects: %w", err)
+	}
+	return objects, nil
+}
+
+// GetObject returns an ATT&CK object with its related objects
+func (s *AttackService) GetObject(id string) (*AttackObjectDetail, error) {
+	var detail AttackObjectDetail
+	if err := s.db.Take(&detail.AttackObject, "id = ?", strings.ToUpper(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttackObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to load ATT&CK object: %w", err)
+	}
+
+	var err error
+	switch detail.Type {
+	case domain.AttackTechnique:
+		err = s.db.Where("parent_id = ?", detail.ID).Order("id").Find(&detail.Subtechniques).Error
+		if err == nil {
+			err = s.db.Where("id IN (?)", s.db.Model(&domain.AttackMitigates{}).Select("mitigation_id").Where("technique_id = ?", detail.ID)).
+				Order("id").Find(&detail.Mitigations).Error
+		}
+	case domain.AttackMitigation:
+		err = s.db.Where("id IN (?)", s.db.Model(&domain.AttackMitigates{}).Select("technique_id").Where("mitigation_id = ?", detail.ID)).
+			Order("id").Find(&detail.Techniques).Error
+	case domain.AttackTactic:
+		err = s.db.Where("type = ? AND deprecated = ? AND ? = ANY(tactics)", domain.AttackTechnique, false, detail.ShortName).
+			Order("id").Find(&detail.Techniques).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load related ATT&CK objects: %w", err)
+	}
+	return &detail, nil
+}
+
+// NormalizeTechniques normalizes technique IDs and checks them against the
+// catalog; only their shape is checked while no bundle is loaded
+func (s *AttackService) NormalizeTechniques(ids []string) (pq.StringArray, error) {
+	normalized := domain.NormalizeAttackIDs(ids)
+	for _, id := range normalized {
+		if !domain.IsAttackTechniqueID(id) {
+			return nil, fmt.Errorf("%w: %q is not a technique ID", ErrUnknownAttackID, id)
+		}
+	}
+	if err := s.checkKnown(domain.AttackTechnique, normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// NormalizeCountermeasures normalizes ATT&CK mitigation (M1032) and D3FEND
+// countermeasure (D3-MFA) IDs, the former checked against the catalog
+func (s *AttackService) NormalizeCountermeasures(ids []string) (pq.StringArray, error) {
+	normalized := domain.NormalizeAttackIDs(ids)
+	var mitigations []string
+	for _, id := range normalized {
+		switch {
+		case domain.IsAttackMitigationID(id):
+			mitigations = append(mitigations, id)
+		case domain.IsD3FENDID(id):
+		default:
+			return nil, fmt.Errorf("%w: %q is neither an ATT&CK mitigation nor a D3FEND countermeasure", ErrUnknownAttackID, id)
+		}
+	}
+	if err := s.checkKnown(domain.AttackMitigation, mitigations); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// checkKnown checks that the catalog has the IDs, unless it has no object of
+// their kind
+func (s *AttackService) checkKnown(kind string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var loaded int64
+	if err := s.db.Model(&domain.AttackObject{}).Where("type = ?", kind).Limit(1).Count(&loaded).Error; err != nil {
+		return fmt.Errorf("failed to check ATT&CK catalog: %w", err)
+	}
+	if loaded == 0 {
+		return nil
+	}
+	var known []string
+	if err := s.db.Model(&domain.AttackObject{}).Where("type = ? AND id IN ?", kind, ids).Pluck("id", &known).Error; err != nil {
+		return fmt.Errorf("failed to check ATT&CK catalog: %w", err)
+	}
+	found := map[string]bool{}
+	for _, id := range known {
+		found[id] = true
+	}
+	var unknown []string
+	for _, id := range ids {
+		if !found[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownAttackID, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Coverage lists the techniques linked to risks or mitigations with their
+// open risks and done or planned mitigations, most open risks first
+func (s *AttackService) Coverage(ctx context.Context) ([]domain.TechniqueCoverage, error) {
+	db := s.db.WithContext(ctx)
+	var risks []domain.Risk
+	if err := db.Select("id", "title", "status", "techniques").
+		Where("cardinality(techniques) > 0").Find(&risks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risks with techniques: %w", err)
+	}
+	riskIDs := make([]uuid.UUID, 0, len(risks))
+	for _, r := range risks {
+		riskIDs = append(riskIDs, r.ID)
+	}
+
+	var mitigations []domain.Mitigation
+	if err := db.Select("id", "risk_id", "title", "status", "techniques", "countermeasures").
+		Where("cardinality(techniques) > 0 OR cardinality(countermeasures) > 0 OR risk_id IN ?", riskIDs).
+		Find(&mitigations).Error; err != nil {
+		return nil, fmt.Errorf("failed to load mitigations: %w", err)
+	}
+
+	var links []domain.AttackMitigates
+	if err := db.Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to load ATT&CK mitigations: %w", err)
+	}
+	mitigates := map[string][]string{}
+	for _, l := range links {
+		mitigates[l.MitigationID] = append(mitigates[l.MitigationID], l.TechniqueID)
+	}
+
+	var techniques []domain.AttackObject
+	if err := db.Select("id", "name", "tactics").Where("type = ?", domain.AttackTechnique).Find(&techniques).Error; err != nil {
+		return nil, fmt.Errorf("failed to load ATT&CK techniques: %w", err)
+	}
+	catalog := make(map[string]domain.AttackObject, len(techniques))
+	for _, t := range techniques {
+		catalog[t.ID] = t
+	}
+
+	return computeCoverage(risks, mitigations, mitigates, catalog), nil
+}
+
+// computeCoverage sums up open risks and mitigations by technique. A
+// mitigation counters its techniques, those its ATT&CK countermeasures
+// mitigate, or, with neither, the techniques of its risk.
+func computeCoverage(risks []domain.Risk, mitigations []domain.Mitigation, mitigates map[string][]string, catalog map[string]domain.AttackObject) []domain.TechniqueCoverage {
+	byTechnique := map[string]*domain.TechniqueCoverage{}
+	entry := func(id string) *domain.TechniqueCoverage {
+		c, ok := byTechnique[id]
+		if !ok {
+			c = &domain.TechniqueCoverage{TechniqueID: id}
+			if t, known := catalog[id]; known {
+				c.Name = t.Name
+				c.Tactics = t.Tactics
+			}
+			byTechnique[id] = c
+		}
+		return c
+	}
+
+	riskTechniques := map[uuid.UUID][]string{}
+	for _, r := range risks {
+		riskTechniques[r.ID] = r.Techniques
+		if r.Status == domain.StatusMitigated || r.Status == domain.StatusAccepted {
+			continue
+		}
+		for _, id := range r.Techniques {
+			c := entry(id)
+			c.OpenRisks++
+			c.RiskIDs = append(c.RiskIDs, r.ID)
+			c.RiskTitles = append(c.RiskTitles, r.Title)
+		}
+	}
+
+	for _, m := range mitigations {
+		countered := map[string]bool{}
+		for _, id := range m.Techniques {
+			countered[id] = true
+		}
+		for _, id := range m.Countermeasures {
+			for _, technique := range mitigates[id] {
+				countered[technique] = true
+			}
+		}
+		if len(m.Techniques) == 0 && len(m.Countermeasures) == 0 {
+			for _, id := range riskTechniques[m.RiskID] {
+				countered[id] = true
+			}
+		}
+		for id := range countered {
+			c := entry(id)
+			if m.Status == domain.MitigationDone {
+				c.DoneMitigations++
+			} else {
+				c.PlannedMitigations++
+			}
+		}
+	}
+
+	coverage := make([]domain.TechniqueCoverage, 0, len(byTechnique))
+	for _, c := range byTechnique {
+		switch {
+		case c.OpenRisks > 0 && c.DoneMitigations > 0:
+			c.Status = domain.CoverageMitigated
+		case c.OpenRisks > 0:
+			c.Status = domain.CoverageExposed
+		case c.DoneMitigations > 0:
+			c.Status = domain.CoverageCovered
+		default:
+			c.Status = domain.CoveragePlanned
+		}
+		coverage = append(coverage, *c)
+	}
+	sort.Slice(coverage, func(i, j int) bool {
+		if coverage[i].OpenRisks != coverage[j].OpenRisks {
+			return coverage[i].OpenRisks > coverage[j].OpenRisks
+		}
+		return coverage[i].TechniqueID < coverage[j].TechniqueID
+	})
+	return coverage
+}
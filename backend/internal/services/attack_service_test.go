@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	phishing := uuid.New()
+	powershell := uuid.New()
+	closed := uuid.New()
+	risks := []domain.Risk{
+		{ID: phishing, Title: "Phishing", Status: domain.StatusActive, Techniques: pq.StringArray{"T1566", "T1078"}},
+		{ID: powershell, Title: "PowerShell abuse", Status: domain.StatusDraft, Techniques: pq.StringArray{"T1059.001"}},
+		{ID: closed, Title: "Old", Status: domain.StatusMitigated, Techniques: pq.StringArray{"T1190"}},
+	}
+	mitigations := []domain.Mitigation{
+		// Counters the techniques of its risk
+		{RiskID: phishing, Status: domain.MitigationPlanned},
+		// Counters what M1038 mitigates
+		{RiskID: powershell, Status: domain.MitigationDone, Countermeasures: pq.StringArray{"M1038", "D3-EAL"}},
+		// Explicit technique
+		{RiskID: closed, Status: domain.MitigationDone, Techniques: pq.StringArray{"T1190"}},
+	}
+	mitigates := map[string][]string{"M1038": {"T1059", "T1059.001"}}
+	catalog := map[string]domain.AttackObject{
+		"T1566": {ID: "T1566", Name: "Phishing", Tactics: pq.StringArray{"initial-access"}},
+	}
+
+	coverage := computeCoverage(risks, mitigations, mitigates, catalog)
+	byID := map[string]domain.TechniqueCoverage{}
+	for _, c := range coverage {
+		byID[c.TechniqueID] = c
+	}
+	require.Len(t, byID, 5)
+
+	assert.Equal(t, []string{"T1059.001", "T1078", "T1566", "T1059", "T1190"}, []string{
+		coverage[0].TechniqueID, coverage[1].TechniqueID, coverage[2].TechniqueID, coverage[3].TechniqueID, coverage[4].TechniqueID,
+	}, "most open risks first, then by ID")
+
+	assert.Equal(t, domain.CoverageExposed, byID["T1566"].Status)
+	assert.Equal(t, 1, byID["T1566"].PlannedMitigations)
+	assert.Equal(t, "Phishing", byID["T1566"].Name)
+	assert.Equal(t, []string{"initial-access"}, byID["T1566"].Tactics)
+	assert.Equal(t, []uuid.UUID{phishing}, byID["T1566"].RiskIDs)
+
+	assert.Equal(t, domain.CoverageMitigated, byID["T1059.001"].Status)
+	assert.Equal(t, []string{"PowerShell abuse"}, byID["T1059.001"].RiskTitles)
+	assert.Equal(t, domain.CoverageCovered, byID["T1059"].Status)
+	assert.Equal(t, 0, byID["T1059"].OpenRisks)
+
+	assert.Equal(t, domain.CoverageCovered, byID["T1190"].Status, "closed risks are not open")
+	assert.Equal(t, 1, byID["T1190"].DoneMitigations)
+}
+
+func TestComputeCoveragePlanned(t *testing.T) {
+	coverage := computeCoverage(nil, []domain.Mitigation{
+		{Status: domain.MitigationInProgress, Techniques: pq.StringArray{"T1110"}},
+	}, nil, nil)
+	require.Len(t, coverage, 1)
+	assert.Equal(t, domain.CoveragePlanned, coverage[0].Status)
+	assert.Equal(t, 1, coverage[0].PlannedMitigations)
+}
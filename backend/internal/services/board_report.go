@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// boardTrendMonths is the number of monthly points of the board trend chart
+const boardTrendMonths = 12
+
+// boardTopRisks is the number of risks detailed in the board report
+const boardTopRisks = 10
+
+// riskLevels are the risk levels from lowest to highest
+var riskLevels = []string{"LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// boardReport is the content of the board PDF
+type boardReport struct {
+	Title        string
+	Organisation string
+	Branding     domain.TenantBranding
+	Snapshot     *reportSnapshot
+	Inherent     [5][5]int // Open risks by [impact-1][probability-1] as assessed
+	Residual     [5][5]int // Open risks by [impact-1][probability-1] after treatment
+	Trend        []boardTrendPoint
+	Coverage     []boardCoverage
+}
+
+// boardTrendPoint counts the open risks by level at the end of a month
+type boardTrendPoint struct {
+	Label   string
+	ByLevel map[string]int
+}
+
+// boardCoverage is the share of a framework's risks that are closed or treated
+type boardCoverage struct {
+	Framework string
+	Risks     int
+	Covered   int
+}
+
+func (c boardCoverage) percent() float64 {
+	if c.Risks == 0 {
+		return 0
+	}
+	return float64(c.Covered) * 100 / float64(c.Risks)
+}
+
+// riskPosition is a cell of the 5x5 matrix
+type riskPosition struct {
+	Impact      int
+	Probability int
+}
+
+// RenderBoardReport writes the board PDF for the risks selected by riskFilter,
+// branded for the tenant. A filter as of a past date renders the register,
+// trend and placements as they were then.
+func RenderBoardReport(ctx context.Context, db *gorm.DB, tenantID uuid.UUID, riskFilter *RiskFilter, w io.Writer) error {
+	branding, tenantName, err := NewBrandingService(db).GetBranding(tenantID)
+	if err != nil {
+		return err
+	}
+	report, err := collectBoardReport(ctx, db, riskFilter, riskFilter.At().UTC())
+	if err != nil {
+		return err
+	}
+	report.Branding = *branding
+	report.Organisation = branding.DisplayName
+	if report.Organisation == "" {
+		report.Organisation = tenantName
+	}
+	return renderBoardPDF(report, w)
+}
+
+// collectBoardReport gathers the snapshot, matrix placements, trend and
+// framework coverage of the selected risks
+func collectBoardReport(ctx context.Context, db *gorm.DB, riskFilter *RiskFilter, now time.Time) (*boardReport, error) {
+	months := boardMonthEnds(now, boardTrendMonths)
+	params := domain.ReportParameters{PeriodStart: months[0].AddDate(0, -1, 0), PeriodEnd: now}
+	if riskFilter != nil {
+		params.Filter = riskFilter.Expression
+	}
+	snap, err := collectReportSnapshot(ctx, db, riskFilter, params, now)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := riskFilter.Scope(db.Model(&domain.Risk{})).Select("risks.id")
+	reviewed, err := reviewedPositions(ctx, db, selected, now)
+	if err != nil {
+		return nil, err
+	}
+	var history []domain.RiskHistory
+	if err := db.WithContext(ctx).Select("risk_id", "score", "status", "created_at").
+		Where("risk_id IN (?) AND created_at <= ?", selected, now).Order("created_at ASC").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk history: %w", err)
+	}
+
+	report := &boardReport{
+		Title:    "Board Risk Report",
+		Snapshot: snap,
+		Trend:    boardTrend(snap.Risks, history, months),
+		Coverage: boardFrameworkCoverage(snap.Risks),
+	}
+	for _, r := range snap.Risks {
+		if !riskOpen(r.Status) || !validPosition(r.Impact, r.Probability) {
+			continue
+		}
+		report.Inherent[r.Impact-1][r.Probability-1]++
+		residual := residualPosition(r, reviewed[r.ID])
+		report.Residual[residual.Impact-1][residual.Probability-1]++
+	}
+	return report, nil
+}
+
+func validPosition(impact, probability int) bool {
+	return impact >= 1 && impact <= 5 && probability >= 1 && probability <= 5
+}
+
+// reviewedPositions returns the impact and probability recorded by the latest
+// monitoring review of each selected risk up to now
+func reviewedPositions(ctx context.Context, db *gorm.DB, selected *gorm.DB, now time.Time) (map[uuid.UUID]*riskPosition, error) {
+	var registers []domain.RiskRegister
+	if err := db.WithContext(ctx).Select("id", "risk_id").Where("risk_id IN (?)", selected).Find(&registers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk registers: %w", err)
+	}
+	positions := map[uuid.UUID]*riskPosition{}
+	if len(registers) == 0 {
+		return positions, nil
+	}
+
+	riskIDs := make(map[uuid.UUID]uuid.UUID, len(registers))
+	registerIDs := make([]uuid.UUID, 0, len(registers))
+	for _, register := range registers {
+		riskIDs[register.ID] = register.RiskID
+		registerIDs = append(registerIDs, register.ID)
+	}
+
+	var reviews []domain.RiskMonitoringReview
+	if err := db.WithContext(ctx).Select("risk_register_id", "current_impact_score", "current_probability_score", "review_date").
+		Where("risk_register_id IN ? AND review_date <= ?", registerIDs, now).Order("review_date DESC").Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to load monitoring reviews: %w", err)
+	}
+	for _, review := range reviews {
+		riskID := riskIDs[review.RiskRegisterID]
+		if _, ok := positions[riskID]; ok || !validPosition(review.CurrentImpactScore, review.CurrentProbabilityScore) {
+			continue
+		}
+		positions[riskID] = &riskPosition{Impact: review.CurrentImpactScore, Probability: review.CurrentProbabilityScore}
+	}
+	return positions, nil
+}
+
+// treatmentProgress is the average progress of a risk's treatments, completed
+// treatments counting as 100%; ok is false when the risk has none
+func treatmentProgress(r reportRisk) (percent int, ok bool) {
+	if len(r.Mitigations) == 0 {
+		return 0, false
+	}
+	total := 0
+	for _, m := range r.Mitigations {
+		if m.Status == domain.MitigationDone {
+			total += 100
+		} else {
+			total += min(max(m.Progress, 0), 100)
+		}
+	}
+	return total / len(r.Mitigations), true
+}
+
+// residualPosition places a risk after treatment. A monitoring review's
+// assessment wins; otherwise treatment progress lowers the probability, full
+// progress bringing it down to 1, while the impact is unchanged.
+func residualPosition(r reportRisk, reviewed *riskPosition) riskPosition {
+	if reviewed != nil {
+		return *reviewed
+	}
+	progress, _ := treatmentProgress(r)
+	reduction := int(math.Round(float64(r.Probability-1) * float64(progress) / 100))
+	return riskPosition{Impact: r.Impact, Probability: r.Probability - reduction}
+}
+
+// boardMonthEnds returns the exclusive ends of the last n months, the
+// current month ending now
+func boardMonthEnds(now time.Time, n int) []time.Time {
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	ends := make([]time.Time, 0, n)
+	for i := n - 1; i >= 1; i-- {
+		ends = append(ends, first.AddDate(0, -(i-1), 0))
+	}
+	return append(ends, now)
+}
+
+// boardTrend replays the risk history to count the open risks by level at
+// each month end. Risks without history before a month end are counted with
+// their current values from their creation on.
+func boardTrend(risks []reportRisk, history []domain.RiskHistory, ends []time.Time) []boardTrendPoint {
+	byRisk := map[uuid.UUID][]domain.RiskHistory{}
+	for _, h := range history {
+		byRisk[h.RiskID] = append(byRisk[h.RiskID], h)
+	}
+	for _, entries := range byRisk {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+	}
+
+	points := make([]boardTrendPoint, len(ends))
+	for i, end := range ends {
+		points[i] = boardTrendPoint{
+			Label:   end.Add(-time.Nanosecond).Format("Jan 06"),
+			ByLevel: map[string]int{},
+		}
+		for _, r := range risks {
+			if !r.CreatedAt.Before(end) {
+				continue
+			}
+			score, status := r.Score, r.Status
+			for _, h := range byRisk[r.ID] {
+				if !h.CreatedAt.Before(end) {
+					break
+				}
+				score, status = h.Score, h.Status
+			}
+			if i == len(ends)-1 {
+				// The last point is now: the risk as it stands
+				score, status = r.Score, r.Status
+			}
+			if riskOpen(status) {
+				points[i].ByLevel[riskLevelForScore(score)]++
+			}
+		}
+	}
+	return points
+}
+
+// boardFrameworkCoverage computes, per framework, how many risks are closed or
+// have a treatment under way, least covered first
+func boardFrameworkCoverage(risks []reportRisk) []boardCoverage {
+	byName := map[string]*boardCoverage{}
+	for _, r := range risks {
+		for _, name := range r.Frameworks {
+			c := byName[name]
+			if c == nil {
+				c = &boardCoverage{Framework: name}
+				byName[name] = c
+			}
+			c.Risks++
+			if !riskOpen(r.Status) || r.treated() {
+				c.Covered++
+			}
+		}
+	}
+	coverage := make([]boardCoverage, 0, len(byName))
+	for _, c := range byName {
+		coverage = append(coverage, *c)
+	}
+	sort.Slice(coverage, func(i, j int) bool {
+		if coverage[i].percent() != coverage[j].percent() {
+			return coverage[i].percent() < coverage[j].percent()
+		}
+		return coverage[i].Framework < coverage[j].Framework
+	})
+	return coverage
+}
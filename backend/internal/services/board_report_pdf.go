@@ -0,0 +1,549 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// boardLevelColors are the fill colors of the risk levels
+var boardLevelColors = map[string][3]int{
+	"LOW":      {46, 160, 67},
+	"MEDIUM":   {242, 169, 0},
+	"HIGH":     {230, 102, 0},
+	"CRITICAL": {200, 30, 45},
+}
+
+// boardPDF lays out the board report. Contents entries are page aliases
+// resolved when the document is written, so the contents page can precede
+// the sections it lists.
+type boardPDF struct {
+	pdf      *fpdf.Fpdf
+	report   *boardReport
+	primary  [3]int
+	accent   [3]int
+	sections []boardSection
+}
+
+type boardSection struct {
+	title string
+	link  int
+}
+
+func renderBoardPDF(report *boardReport, w io.Writer) error {
+	b := &boardPDF{pdf: newReportPDF(), report: report}
+	b.primary[0], b.primary[1], b.primary[2] = brandingColor(report.Branding.PrimaryColor, defaultPrimaryColor)
+	b.accent[0], b.accent[1], b.accent[2] = brandingColor(report.Branding.AccentColor, defaultAccentColor)
+	pdf := b.pdf
+	pdf.AliasNbPages("")
+	pdf.SetTitle(report.Title, true)
+	pdf.SetAuthor(report.Organisation, true)
+
+	pdf.SetFooterFunc(func() {
+		if pdf.PageNo() == 1 {
+			return
+		}
+		pdf.SetY(-12)
+		pdf.SetFont(reportFont, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		left := report.Title
+		if report.Organisation != "" {
+			left = report.Organisation + " · " + left
+		}
+		pdf.CellFormat(pdfPageWidth*0.7, 5, fitPDFText(pdf, left, pdfPageWidth*0.7), "", 0, "L", false, 0, "")
+		pdf.CellFormat(pdfPageWidth*0.3, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	for _, title := range []string{"Executive summary", "Risk heatmap", "Risk trend", "Top risks", "Framework coverage", "Appendix: risk register"} {
+		b.sections = append(b.sections, boardSection{title: title, link: pdf.AddLink()})
+	}
+
+	b.cover()
+	b.contents()
+	b.summary(0)
+	b.heatmaps(1)
+	b.trend(2)
+	b.topRisks(3)
+	b.coverage(4)
+	b.register(5)
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render board report: %w", err)
+	}
+	return pdf.Output(w)
+}
+
+func (b *boardPDF) fill(c [3]int) { b.pdf.SetFillColor(c[0], c[1], c[2]) }
+func (b *boardPDF) text(c [3]int) { b.pdf.SetTextColor(c[0], c[1], c[2]) }
+
+// tint mixes a color with white; amount 0 keeps the color, 1 is white
+func tint(c [3]int, amount float64) [3]int {
+	var t [3]int
+	for i := range c {
+		t[i] = c[i] + int(float64(255-c[i])*amount)
+	}
+	return t
+}
+
+func (b *boardPDF) cover() {
+	pdf := b.pdf
+	report := b.report
+	pdf.AddPage()
+	pageWidth, pageHeight := pdf.GetPageSize()
+
+	b.fill(b.primary)
+	pdf.Rect(0, 0, pageWidth, 110, "F")
+	b.fill(b.accent)
+	pdf.Rect(0, 110, pageWidth, 3, "F")
+
+	if logo := b.logo(); logo != nil {
+		// Fit the logo in a 60 x 30 mm white plate
+		width, height := logo.Width(), logo.Height()
+		scale := math.Min(56/width, 26/height)
+		pdf.SetFillColor(255, 255, 255)
+		pdf.Rect(15, 15, 60, 30, "F")
+		pdf.ImageOptions("logo", 15+(60-width*scale)/2, 15+(30-height*scale)/2, width*scale, height*scale, false, fpdf.ImageOptions{}, 0, "")
+	}
+
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetXY(15, 60)
+	pdf.SetFont(reportFont, "B", 28)
+	pdf.MultiCell(pdfPageWidth, 12, report.Title, "", "L", false)
+	if report.Organisation != "" {
+		pdf.SetX(15)
+		pdf.SetFont(reportFont, "", 16)
+		pdf.MultiCell(pdfPageWidth, 8, report.Organisation, "", "L", false)
+	}
+
+	snap := report.Snapshot
+	pdf.SetTextColor(60, 60, 60)
+	pdf.SetXY(15, 125)
+	pdf.SetFont(reportFont, "", 11)
+	pdf.MultiCell(pdfPageWidth, 6, "As of "+snap.Metrics.AsOf.Format("2 January 2006 15:04 MST"), "", "L", false)
+	scope := "All risks"
+	if snap.Metrics.Filter != "" {
+		scope = "Risks matching: " + snap.Metrics.Filter
+	}
+	pdf.SetX(15)
+	pdf.MultiCell(pdfPageWidth, 6, scope, "", "L", false)
+
+	pdf.SetXY(15, pageHeight-40)
+	pdf.SetFont(reportFont, "I", 9)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.MultiCell(pdfPageWidth, 5, "Confidential. Prepared for the board of directors from the OpenRisk risk register.", "", "L", false)
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// logo registers the tenant logo, skipping images the PDF library rejects
+func (b *boardPDF) logo() *fpdf.ImageInfoType {
+	if b.report.Branding.Logo == "" {
+		return nil
+	}
+	imageType, data, err := decodeBrandingLogo(b.report.Branding.Logo)
+	if err != nil {
+		return nil
+	}
+	// fpdf errors are sticky, so try the image on a scratch document first
+	probe := fpdf.New("P", "mm", "A4", "")
+	probe.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(data))
+	if probe.Error() != nil {
+		return nil
+	}
+	return b.pdf.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(data))
+}
+
+func (b *boardPDF) contents() {
+	pdf := b.pdf
+	pdf.AddPage()
+	b.heading("Contents")
+	pdf.Ln(4)
+	for i, section := range b.sections {
+		pdf.SetFont(reportFont, "", 12)
+		pdf.SetTextColor(40, 40, 40)
+		pdf.CellFormat(pdfPageWidth-20, 9, fmt.Sprintf("%d.  %s", i+1, section.title), "B", 0, "L", false, section.link, "")
+		pdf.CellFormat(20, 9, fmt.Sprintf("{toc:%d}", i), "B", 1, "R", false, section.link, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// startSection opens a section on a new page and records its page for the contents
+func (b *boardPDF) startSection(i int) {
+	pdf := b.pdf
+	pdf.AddPage()
+	pdf.SetLink(b.sections[i].link, 0, -1)
+	pdf.RegisterAlias(fmt.Sprintf("{toc:%d}", i), fmt.Sprint(pdf.PageNo()))
+	b.heading(b.sections[i].title)
+}
+
+func (b *boardPDF) heading(title string) {
+	pdf := b.pdf
+	pdf.SetFont(reportFont, "B", 18)
+	b.text(b.primary)
+	pdf.MultiCell(pdfPageWidth, 10, title, "", "L", false)
+	b.fill(b.accent)
+	pdf.Rect(15, pdf.GetY()+1, 30, 1.2, "F")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(6)
+}
+
+func (b *boardPDF) subheading(title string) {
+	pdf := b.pdf
+	pdf.Ln(3)
+	pdf.SetFont(reportFont, "B", 12)
+	b.text(b.primary)
+	pdf.MultiCell(pdfPageWidth, 7, title, "", "L", false)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(1)
+}
+
+func (b *boardPDF) summary(i int) {
+	pdf := b.pdf
+	snap := b.report.Snapshot
+	b.startSection(i)
+
+	open := 0
+	for _, r := range snap.Risks {
+		if riskOpen(r.Status) {
+			open++
+		}
+	}
+	tiles := []struct {
+		label string
+		value int
+		color [3]int
+	}{
+		{"Open risks", open, b.primary},
+		{"Critical", snap.ByLevel["CRITICAL"], boardLevelColors["CRITICAL"]},
+		{"High", snap.ByLevel["HIGH"], boardLevelColors["HIGH"]},
+		{"Overdue treatments", snap.Treatments.Overdue, b.accent},
+	}
+	width := (pdfPageWidth - 3*4) / 4
+	y := pdf.GetY()
+	for n, tile := range tiles {
+		x := 15 + float64(n)*(width+4)
+		b.fill(tint(tile.color, 0.88))
+		pdf.Rect(x, y, width, 26, "F")
+		b.fill(tile.color)
+		pdf.Rect(x, y, 1.5, 26, "F")
+		b.text(tile.color)
+		pdf.SetFont(reportFont, "B", 22)
+		pdf.SetXY(x+4, y+3)
+		pdf.CellFormat(width-6, 11, fmt.Sprint(tile.value), "", 0, "L", false, 0, "")
+		pdf.SetTextColor(60, 60, 60)
+		pdf.SetFont(reportFont, "", 9)
+		pdf.SetXY(x+4, y+16)
+		pdf.CellFormat(width-6, 6, fitPDFText(pdf, tile.label, width-6), "", 0, "L", false, 0, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(15, y+32)
+
+	pdf.SetFont(reportFont, "", 10.5)
+	pdf.MultiCell(pdfPageWidth, 5.5, snap.Summary, "", "L", false)
+	b.subheading("Key findings")
+	pdf.SetFont(reportFont, "", 10.5)
+	for _, finding := range snap.Findings {
+		pdf.SetX(15)
+		pdf.CellFormat(5, 5.5, "•", "", 0, "L", false, 0, "")
+		pdf.MultiCell(pdfPageWidth-5, 5.5, finding, "", "L", false)
+		pdf.Ln(1)
+	}
+}
+
+func (b *boardPDF) heatmaps(i int) {
+	pdf := b.pdf
+	b.startSection(i)
+	pdf.SetFont(reportFont, "", 10)
+	pdf.MultiCell(pdfPageWidth, 5, "Open risks placed by impact and probability. Inherent placement is the assessed rating; residual placement uses the latest monitoring review or, without one, lowers the probability in proportion to treatment progress.", "", "L", false)
+	pdf.Ln(4)
+
+	y := pdf.GetY()
+	b.heatmap(15, y, "Inherent", &b.report.Inherent)
+	b.heatmap(15+pdfPageWidth/2+2, y, "Residual", &b.report.Residual)
+
+	// Legend
+	pdf.SetXY(15, y+98)
+	pdf.SetFont(reportFont, "", 9)
+	for _, level := range riskLevels {
+		b.fill(tint(boardLevelColors[level], 0.45))
+		pdf.Rect(pdf.GetX(), pdf.GetY()+1, 4, 4, "F")
+		pdf.SetX(pdf.GetX() + 5)
+		pdf.CellFormat(28, 6, level, "", 0, "L", false, 0, "")
+	}
+	pdf.Ln(10)
+}
+
+// heatmap draws a 5x5 matrix, impact rising upwards and probability to the
+// right, with the number of risks in each cell
+func (b *boardPDF) heatmap(x, y float64, title string, counts *[5][5]int) {
+	pdf := b.pdf
+	const cell = 14.0
+	gridX, gridY := x+10, y+10
+
+	pdf.SetFont(reportFont, "B", 11)
+	b.text(b.primary)
+	pdf.SetXY(gridX, y)
+	pdf.CellFormat(cell*5, 7, title, "", 0, "C", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+
+	pdf.SetDrawColor(255, 255, 255)
+	pdf.SetLineWidth(0.6)
+	for impact := 5; impact >= 1; impact-- {
+		for probability := 1; probability <= 5; probability++ {
+			cx := gridX + float64(probability-1)*cell
+			cy := gridY + float64(5-impact)*cell
+			count := counts[impact-1][probability-1]
+			shade := 0.6
+			if count > 0 {
+				shade = 0.2
+			}
+			b.fill(tint(boardLevelColors[riskLevelForScore(float64(impact*probability))], shade))
+			pdf.Rect(cx, cy, cell, cell, "FD")
+			if count > 0 {
+				pdf.SetFont(reportFont, "B", 12)
+				pdf.SetTextColor(255, 255, 255)
+				pdf.SetXY(cx, cy)
+				pdf.CellFormat(cell, cell, fmt.Sprint(count), "", 0, "C", false, 0, "")
+			}
+		}
+	}
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.2)
+	pdf.SetTextColor(90, 90, 90)
+
+	pdf.SetFont(reportFont, "", 8)
+	for n := 1; n <= 5; n++ {
+		pdf.SetXY(gridX+float64(n-1)*cell, gridY+5*cell)
+		pdf.CellFormat(cell, 5, fmt.Sprint(n), "", 0, "C", false, 0, "")
+		pdf.SetXY(gridX-5, gridY+float64(5-n)*cell)
+		pdf.CellFormat(4, cell, fmt.Sprint(n), "", 0, "R", false, 0, "")
+	}
+	pdf.SetXY(gridX, gridY+5*cell+5)
+	pdf.CellFormat(cell*5, 5, "Probability", "", 0, "C", false, 0, "")
+	pdf.TransformBegin()
+	pdf.TransformRotate(90, x+2, gridY+2.5*cell+6)
+	pdf.Text(x+2, gridY+2.5*cell+6, "Impact")
+	pdf.TransformEnd()
+	pdf.SetTextColor(0, 0, 0)
+}
+
+func (b *boardPDF) trend(i int) {
+	pdf := b.pdf
+	points := b.report.Trend
+	b.startSection(i)
+	pdf.SetFont(reportFont, "", 10)
+	pdf.MultiCell(pdfPageWidth, 5, fmt.Sprintf("Open risks by level at the end of each of the last %d months, rebuilt from the risk history.", len(points)), "", "L", false)
+	pdf.Ln(4)
+
+	chartX, chartY, chartW, chartH := 27.0, pdf.GetY()+2, pdfPageWidth-12, 90.0
+	peak := 0
+	for _, p := range points {
+		total := 0
+		for _, n := range p.ByLevel {
+			total += n
+		}
+		peak = max(peak, total)
+	}
+	step := niceStep(peak)
+	top := step * int(math.Ceil(float64(max(peak, 1))/float64(step)))
+
+	// Grid and axis
+	pdf.SetFont(reportFont, "", 8)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.SetDrawColor(220, 220, 220)
+	for v := 0; v <= top; v += step {
+		gy := chartY + chartH - float64(v)/float64(top)*chartH
+		pdf.Line(chartX, gy, chartX+chartW, gy)
+		pdf.SetXY(chartX-12, gy-2.5)
+		pdf.CellFormat(10, 5, fmt.Sprint(v), "", 0, "R", false, 0, "")
+	}
+
+	slot := chartW / float64(len(points))
+	barW := slot * 0.6
+	for n, p := range points {
+		bx := chartX + float64(n)*slot + (slot-barW)/2
+		by := chartY + chartH
+		for _, level := range riskLevels {
+			count := p.ByLevel[level]
+			if count == 0 {
+				continue
+			}
+			h := float64(count) / float64(top) * chartH
+			b.fill(boardLevelColors[level])
+			by -= h
+			pdf.Rect(bx, by, barW, h, "F")
+		}
+		pdf.SetXY(chartX+float64(n)*slot, chartY+chartH+1)
+		pdf.CellFormat(slot, 5, p.Label, "", 0, "C", false, 0, "")
+	}
+	pdf.SetDrawColor(0, 0, 0)
+
+	pdf.SetXY(chartX, chartY+chartH+9)
+	for _, level := range riskLevels {
+		b.fill(boardLevelColors[level])
+		pdf.Rect(pdf.GetX(), pdf.GetY()+1, 4, 4, "F")
+		pdf.SetX(pdf.GetX() + 5)
+		pdf.CellFormat(28, 6, level, "", 0, "L", false, 0, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(12)
+
+	// The figures behind the chart
+	table := &reportTable{Columns: append([]string{"Month"}, riskLevels...), Widths: []float64{2, 1, 1, 1, 1}}
+	for _, p := range points {
+		row := []string{p.Label}
+		for _, level := range riskLevels {
+			row = append(row, fmt.Sprint(p.ByLevel[level]))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	renderPDFTable(pdf, table)
+}
+
+// niceStep picks a gridline step giving about five lines up to peak
+func niceStep(peak int) int {
+	for _, step := range []int{1, 2, 5, 10, 20, 25, 50, 100, 200, 250, 500, 1000} {
+		if peak/step <= 5 {
+			return step
+		}
+	}
+	return int(math.Pow(10, math.Ceil(math.Log10(float64(peak)/5))))
+}
+
+func (b *boardPDF) topRisks(i int) {
+	pdf := b.pdf
+	b.startSection(i)
+
+	var risks []reportRisk
+	for _, r := range b.report.Snapshot.Risks {
+		if riskOpen(r.Status) {
+			risks = append(risks, r)
+			if len(risks) == boardTopRisks {
+				break
+			}
+		}
+	}
+	if len(risks) == 0 {
+		pdf.SetFont(reportFont, "I", 10)
+		pdf.MultiCell(pdfPageWidth, 5, "No open risks.", "", "L", false)
+		return
+	}
+
+	widths := []float64{8, 72, 22, 14, 30, 34}
+	columns := []string{"#", "Risk", "Level", "Score", "Owner", "Treatment"}
+	pdf.SetFont(reportFont, "B", 9)
+	b.fill(tint(b.primary, 0.85))
+	for n, col := range columns {
+		pdf.CellFormat(widths[n], 7, col, "", 0, "L", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	const rowH = 11.0
+	for n, r := range risks {
+		y := pdf.GetY()
+		x := 15.0
+		pdf.SetDrawColor(225, 225, 225)
+		pdf.Line(15, y+rowH, 15+pdfPageWidth, y+rowH)
+		pdf.SetDrawColor(0, 0, 0)
+
+		pdf.SetFont(reportFont, "B", 10)
+		pdf.SetXY(x, y)
+		pdf.CellFormat(widths[0], rowH, fmt.Sprint(n+1), "", 0, "L", false, 0, "")
+		x += widths[0]
+
+		pdf.SetFont(reportFont, "", 9.5)
+		pdf.SetXY(x, y)
+		pdf.CellFormat(widths[1], rowH, fitPDFText(pdf, r.Title, widths[1]), "", 0, "L", false, 0, "")
+		x += widths[1]
+
+		// Level chip
+		b.fill(boardLevelColors[r.Level])
+		pdf.Rect(x, y+3, widths[2]-3, 5, "F")
+		pdf.SetFont(reportFont, "B", 7.5)
+		pdf.SetTextColor(255, 255, 255)
+		pdf.SetXY(x, y+3)
+		pdf.CellFormat(widths[2]-3, 5, r.Level, "", 0, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		x += widths[2]
+
+		pdf.SetFont(reportFont, "", 9.5)
+		pdf.SetXY(x, y)
+		pdf.CellFormat(widths[3], rowH, fmt.Sprintf("%.1f", r.Score), "", 0, "L", false, 0, "")
+		x += widths[3]
+		pdf.SetXY(x, y)
+		pdf.CellFormat(widths[4], rowH, fitPDFText(pdf, r.Owner, widths[4]), "", 0, "L", false, 0, "")
+		x += widths[4]
+
+		// Progress bar
+		progress, ok := treatmentProgress(r)
+		pdf.SetFont(reportFont, "", 8)
+		if !ok {
+			pdf.SetTextColor(200, 30, 45)
+			pdf.SetXY(x, y)
+			pdf.CellFormat(widths[5], rowH, "No treatment", "", 0, "L", false, 0, "")
+			pdf.SetTextColor(0, 0, 0)
+		} else {
+			barW := widths[5] - 11
+			pdf.SetFillColor(230, 230, 230)
+			pdf.Rect(x, y+4, barW, 3, "F")
+			b.fill(b.accent)
+			pdf.Rect(x, y+4, barW*float64(progress)/100, 3, "F")
+			pdf.SetXY(x+barW+1, y)
+			pdf.CellFormat(10, rowH, fmt.Sprintf("%d%%", progress), "", 0, "R", false, 0, "")
+		}
+		pdf.SetXY(15, y+rowH)
+	}
+}
+
+func (b *boardPDF) coverage(i int) {
+	pdf := b.pdf
+	coverage := b.report.Coverage
+	b.startSection(i)
+	pdf.SetFont(reportFont, "", 10)
+	pdf.MultiCell(pdfPageWidth, 5, "Share of each framework's risks that are closed or have a treatment in progress or completed, least covered first.", "", "L", false)
+	pdf.Ln(4)
+	if len(coverage) == 0 {
+		pdf.SetFont(reportFont, "I", 10)
+		pdf.MultiCell(pdfPageWidth, 5, "No risk in scope is mapped to a framework.", "", "L", false)
+		return
+	}
+
+	_, pageHeight := pdf.GetPageSize()
+	labelW, countW := 45.0, 35.0
+	barW := pdfPageWidth - labelW - countW
+	for _, c := range coverage {
+		if pdf.GetY()+9 > pageHeight-25 {
+			pdf.AddPage()
+		}
+		y := pdf.GetY()
+		pdf.SetFont(reportFont, "", 9.5)
+		pdf.SetXY(15, y)
+		pdf.CellFormat(labelW, 7, fitPDFText(pdf, c.Framework, labelW), "", 0, "L", false, 0, "")
+
+		pdf.SetFillColor(232, 232, 232)
+		pdf.Rect(15+labelW, y+1.5, barW, 4, "F")
+		color := b.accent
+		if c.percent() < 50 {
+			color = boardLevelColors["HIGH"]
+		}
+		b.fill(color)
+		pdf.Rect(15+labelW, y+1.5, barW*c.percent()/100, 4, "F")
+
+		pdf.SetXY(15+labelW+barW, y)
+		pdf.CellFormat(countW, 7, fmt.Sprintf("%.0f%%  (%d/%d)", c.percent(), c.Covered, c.Risks), "", 0, "R", false, 0, "")
+		pdf.SetXY(15, y+9)
+	}
+}
+
+func (b *boardPDF) register(i int) {
+	b.startSection(i)
+	section := registerSection(b.report.Snapshot)
+	b.pdf.SetFont(reportFont, "", 10)
+	b.pdf.MultiCell(pdfPageWidth, 5, strings.Join(section.Paragraphs, " "), "", "L", false)
+	b.pdf.Ln(2)
+	renderPDFTable(b.pdf, section.Table)
+}
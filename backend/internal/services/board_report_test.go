@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResidualPosition(t *testing.T) {
+	r := reportRisk{Impact: 4, Probability: 5}
+	assert.Equal(t, riskPosition{Impact: 4, Probability: 5}, residualPosition(r, nil), "untreated risks stay in place")
+
+	r.Mitigations = []reportMitigation{{Status: domain.MitigationInProgress, Progress: 50}}
+	assert.Equal(t, riskPosition{Impact: 4, Probability: 3}, residualPosition(r, nil))
+
+	r.Mitigations = append(r.Mitigations, reportMitigation{Status: domain.MitigationDone})
+	assert.Equal(t, riskPosition{Impact: 4, Probability: 2}, residualPosition(r, nil), "done treatments count as complete")
+
+	reviewed := &riskPosition{Impact: 2, Probability: 4}
+	assert.Equal(t, *reviewed, residualPosition(r, reviewed), "a monitoring review wins")
+}
+
+func TestBoardTrend(t *testing.T) {
+	now := time.Date(2026, 5, 14, 9, 30, 0, 0, time.UTC)
+	ends := boardMonthEnds(now, 3)
+	require.Equal(t, []time.Time{
+		time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		now,
+	}, ends)
+
+	escalated := reportRisk{ID: uuid.New(), Status: domain.StatusActive, Score: 20, CreatedAt: time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)}
+	mitigated := reportRisk{ID: uuid.New(), Status: domain.StatusMitigated, Score: 6, CreatedAt: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)}
+	recent := reportRisk{ID: uuid.New(), Status: domain.StatusActive, Score: 3, CreatedAt: time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC)}
+	history := []domain.RiskHistory{
+		{RiskID: escalated.ID, Score: 8, Status: domain.StatusActive, CreatedAt: escalated.CreatedAt},
+		{RiskID: escalated.ID, Score: 20, Status: domain.StatusActive, CreatedAt: time.Date(2026, 4, 20, 0, 0, 0, 0, time.UTC)},
+		{RiskID: mitigated.ID, Score: 6, Status: domain.StatusActive, CreatedAt: mitigated.CreatedAt},
+		{RiskID: mitigated.ID, Score: 6, Status: domain.StatusMitigated, CreatedAt: time.Date(2026, 4, 25, 0, 0, 0, 0, time.UTC)},
+	}
+
+	points := boardTrend([]reportRisk{escalated, mitigated, recent}, history, ends)
+	require.Len(t, points, 3)
+	assert.Equal(t, "Mar 26", points[0].Label)
+	assert.Equal(t, map[string]int{"MEDIUM": 2}, points[0].ByLevel, "both risks were medium at the end of March")
+	assert.Equal(t, map[string]int{"CRITICAL": 1}, points[1].ByLevel, "escalated in April, the other one mitigated")
+	assert.Equal(t, map[string]int{"CRITICAL": 1, "LOW": 1}, points[2].ByLevel)
+}
+
+func TestBoardFrameworkCoverage(t *testing.T) {
+	coverage := boardFrameworkCoverage(testReportSnapshot().Risks)
+	require.Len(t, coverage, 2)
+	assert.Equal(t, boardCoverage{Framework: "ISO27001", Risks: 2, Covered: 1}, coverage[0], "least covered first")
+	assert.Equal(t, boardCoverage{Framework: "NIST", Risks: 1, Covered: 1}, coverage[1])
+	assert.Equal(t, 50.0, coverage[0].percent())
+}
+
+func TestRenderBoardPDF(t *testing.T) {
+	snap := testReportSnapshot()
+	snap.Risks[0].Title = "Отказ ЦОД — 数据中心故障"
+	snap.Risks[0].Impact, snap.Risks[0].Probability = 5, 4
+
+	report := &boardReport{
+		Title:        "Board Risk Report",
+		Organisation: "Société Générale d'Exemple",
+		Branding:     domain.TenantBranding{PrimaryColor: "#112233", Logo: testBrandingLogo(t)},
+		Snapshot:     snap,
+		Trend:        boardTrend(snap.Risks, nil, boardMonthEnds(snap.Metrics.AsOf, boardTrendMonths)),
+		Coverage:     boardFrameworkCoverage(snap.Risks),
+	}
+	report.Inherent[4][3] = 1
+	report.Residual[4][2] = 1
+
+	var out bytes.Buffer
+	require.NoError(t, renderBoardPDF(report, &out))
+	assert.True(t, bytes.HasPrefix(out.Bytes(), []byte("%PDF-")))
+	assert.Contains(t, out.String(), "/FontFile2", "the UTF-8 font is embedded")
+
+	// An unreadable logo is left out rather than failing the export
+	report.Branding.Logo = "data:image/png;base64,AAAA"
+	out.Reset()
+	require.NoError(t, renderBoardPDF(report, &out))
+}
+
+func TestBrandingValidation(t *testing.T) {
+	r, g, b, ok := parseHexColor("#1F3A5F")
+	assert.True(t, ok)
+	assert.Equal(t, []int{0x1F, 0x3A, 0x5F}, []int{r, g, b})
+	for _, color := range []string{"1F3A5F", "#1F3A5", "#GGGGGG", "red"} {
+		_, _, _, ok := parseHexColor(color)
+		assert.False(t, ok, color)
+	}
+	r, g, b = brandingColor("", defaultAccentColor)
+	assert.Equal(t, []int{0x2E, 0x86, 0xAB}, []int{r, g, b})
+
+	imageType, data, err := decodeBrandingLogo(testBrandingLogo(t))
+	require.NoError(t, err)
+	assert.Equal(t, "PNG", imageType)
+	assert.NotEmpty(t, data)
+
+	for _, logo := range []string{
+		"https://example.com/logo.png",
+		"data:image/gif;base64,R0lGODlhAQABAAAAACw=",
+		"data:image/png;base64,not base64",
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("not a png")),
+	} {
+		_, _, err := decodeBrandingLogo(logo)
+		assert.True(t, errors.Is(err, ErrInvalidBranding), logo)
+	}
+}
+
+func testBrandingLogo(t *testing.T) string {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20))))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
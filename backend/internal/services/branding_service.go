@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// maxBrandingLogoSize is the largest logo accepted, in bytes once decoded
+const maxBrandingLogoSize = 512 << 10
+
+var (
+	ErrBrandingTenantNotFound = errors.New("tenant not found")
+	ErrInvalidBranding        = errors.New("invalid branding")
+)
+
+// Colors used when a tenant has no branding
+const (
+	defaultPrimaryColor = "#1F3A5F"
+	defaultAccentColor  = "#2E86AB"
+)
+
+// BrandingService reads and stores tenant branding
+type BrandingService struct {
+	db *gorm.DB
+}
+
+// NewBrandingService creates a new branding service
+func NewBrandingService(db *gorm.DB) *BrandingService {
+	return &BrandingService{db: db}
+}
+
+// GetBranding returns a tenant's branding; tenants without branding, or
+// requests without a tenant, get the defaults
+func (s *BrandingService) GetBranding(tenantID uuid.UUID) (*domain.TenantBranding, string, error) {
+	branding := &domain.TenantBranding{}
+	if tenantID == uuid.Nil {
+		return branding, "", nil
+	}
+
+	var tenant domain.Tenant
+	if err := s.db.Select("id", "name", "metadata").First(&tenant, "id = ?", tenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return branding, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to load tenant: %w", err)
+	}
+
+	var metadata struct {
+		Branding *domain.TenantBranding `json:"branding"`
+	}
+	if len(tenant.Metadata) > 0 {
+		// Metadata written by other features may not match; branding is then absent
+		if err := json.Unmarshal(tenant.Metadata, &metadata); err == nil && metadata.Branding != nil {
+			branding = metadata.Branding
+		}
+	}
+	return branding, tenant.Name, nil
+}
+
+// UpdateBranding validates and stores a tenant's branding, keeping the rest of
+// its metadata
+func (s *BrandingService) UpdateBranding(tenantID uuid.UUID, branding domain.TenantBranding) (*domain.TenantBranding, error) {
+	branding.DisplayName = strings.TrimSpace(branding.DisplayName)
+	for _, color := range []*string{&branding.PrimaryColor, &branding.AccentColor} {
+		*color = strings.ToUpper(strings.TrimSpace(*color))
+		if *color == "" {
+			continue
+		}
+		if _, _, _, ok := parseHexColor(*color); !ok {
+			return nil, fmt.Errorf("%w: colors must be #RRGGBB, got %q", ErrInvalidBranding, *color)
+		}
+	}
+	if branding.Logo != "" {
+		if _, _, err := decodeBrandingLogo(branding.Logo); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var tenant domain.Tenant
+		if err := tx.Select("id", "metadata").First(&tenant, "id = ?", tenantID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBrandingTenantNotFound
+			}
+			return err
+		}
+
+		metadata := map[string]interface{}{}
+		if len(tenant.Metadata) > 0 {
+			if err := json.Unmarshal(tenant.Metadata, &metadata); err != nil {
+				return fmt.Errorf("tenant metadata is not a JSON object: %w", err)
+			}
+		}
+		metadata["branding"] = branding
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+		tenant.Metadata = encoded
+		return tx.Model(&tenant).Select("metadata").Updates(&tenant).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrBrandingTenantNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update branding: %w", err)
+	}
+	return &branding, nil
+}
+
+// parseHexColor parses #RRGGBB
+func parseHexColor(s string) (int, int, int, bool) {
+	if len(s) != 7 || s[0] != '#' {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16 & 0xFF), int(v >> 8 & 0xFF), int(v & 0xFF), true
+}
+
+// brandingColor returns the RGB components of a branding color, or of the
+// fallback when the color is unset
+func brandingColor(color, fallback string) (int, int, int) {
+	if r, g, b, ok := parseHexColor(color); ok {
+		return r, g, b
+	}
+	r, g, b, _ := parseHexColor(fallback)
+	return r, g, b
+}
+
+// decodeBrandingLogo decodes a PNG or JPEG data URI into its fpdf image type
+// and bytes
+func decodeBrandingLogo(uri string) (string, []byte, error) {
+	header, payload, ok := strings.Cut(uri, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return "", nil, fmt.Errorf("%w: logo must be a base64 data URI", ErrInvalidBranding)
+	}
+
+	var imageType string
+	switch strings.TrimSuffix(strings.ToLower(header), ";base64") {
+	case "data:image/png":
+		imageType = "PNG"
+	case "data:image/jpeg", "data:image/jpg":
+		imageType = "JPG"
+	default:
+		return "", nil, fmt.Errorf("%w: logo must be a PNG or JPEG image", ErrInvalidBranding)
+	}
+
+	if base64.StdEncoding.DecodedLen(len(payload)) > maxBrandingLogoSize+3 {
+		return "", nil, fmt.Errorf("%w: logo is larger than %d KB", ErrInvalidBranding, maxBrandingLogoSize>>10)
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: logo is not valid base64", ErrInvalidBranding)
+	}
+	if len(data) > maxBrandingLogoSize {
+		return "", nil, fmt.Errorf("%w: logo is larger than %d KB", ErrInvalidBranding, maxBrandingLogoSize>>10)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return "", nil, fmt.Errorf("%w: logo is not a readable image", ErrInvalidBranding)
+	}
+	return imageType, data, nil
+}
@@ -1,27 +1,63 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/opendefender/openrisk/database"
 	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+	"github.com/opendefender/openrisk/internal/filter"
+)
+
+// JobTypeBulkOperation is the job queue type that executes bulk operations
+const JobTypeBulkOperation = "bulk_operation"
+
+// exportBatchSize is the number of risks loaded per query while streaming an export
+const exportBatchSize = 500
+
+var (
+	ErrExportNotReady           = errors.New("export result is not available")
+	ErrInvalidDownloadSignature = errors.New("download link is invalid or has expired")
 )
 
 // BulkOperationService handles async bulk operations
 type BulkOperationService struct {
-	db *gorm.DB
+	db         *gorm.DB
+	jobs       *JobQueueService
+	blobs      ports.BlobStore
+	filters    *RiskFilterService
+	signingKey []byte
 }
 
-// NewBulkOperationService creates a new bulk operation service
-func NewBulkOperationService() *BulkOperationService {
-	return &BulkOperationService{
-		db: database.DB,
+// bulkOperationJob is the job payload of a bulk operation
+type bulkOperationJob struct {
+	OperationID uuid.UUID `json:"operation_id"`
+}
+
+// NewBulkOperationService creates a new bulk operation service and registers
+// its job handler on the queue. Export artifacts are written to blobs and
+// downloaded through links signed with signingKey.
+func NewBulkOperationService(jobs *JobQueueService, blobs ports.BlobStore, signingKey []byte) *BulkOperationService {
+	s := &BulkOperationService{
+		db:         database.DB,
+		jobs:       jobs,
+		blobs:      blobs,
+		filters:    NewRiskFilterService(),
+		signingKey: signingKey,
 	}
+	jobs.RegisterHandler(JobTypeBulkOperation, s.runBulkOperationJob)
+	return s
 }
 
 // CreateBulkOperation creates a new bulk operation job
@@ -35,10 +71,30 @@ func (s *BulkOperationService) CreateBulkOperation(userID uuid.UUID, req *domain
 		return nil, fmt.Errorf("invalid operation type: %s", req.OperationType)
 	}
 
-	// Count matching resources
-	count := int64(0)
-	if err := s.countResourcesByFilter(&count, req.FilterQuery); err != nil {
-		return nil, fmt.Errorf("failed to count resources: %w", err)
+	now := time.Now()
+	asOf, err := ParseAsOf(req.AsOf, now)
+	if err != nil {
+		return nil, err
+	}
+	if asOf != nil && req.OperationType != domain.BulkOperationTypeExport {
+		// Past versions are read-only
+		return nil, fmt.Errorf("%w: only exports can read the register as of a date", ErrInvalidAsOf)
+	}
+
+	if req.OperationType == domain.BulkOperationTypeExport {
+		if req.ExportFormat == "" {
+			req.ExportFormat = ExportFormatCSV
+		}
+		if _, _, err := exportContentType(req.ExportFormat); err != nil {
+			return nil, err
+		}
+		available, err := s.ExportColumns()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := SelectExportColumns(available, req.ExportColumns); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create operation
@@ -47,19 +103,58 @@ func (s *BulkOperationService) CreateBulkOperation(userID uuid.UUID, req *domain
 		OperationType: req.OperationType,
 		Status:        domain.BulkOperationStatusPending,
 		FilterQuery:   req.FilterQuery,
-		ResourceCount: int(count),
+		Filter:        filter.And(req.Filter, LegacyRiskFilter(req.FilterQuery)),
 		UpdateData:    req.UpdateData,
 		ExportFormat:  req.ExportFormat,
+		ExportColumns: req.ExportColumns,
+		AsOf:          asOf,
 		CreatedBy:     userID,
-		CreatedAt:     time.Now(),
+		CreatedAt:     now,
+	}
+
+	if req.OperationType == domain.BulkOperationTypeExport {
+		// Count matching resources with the query the job will run
+		query, err := s.riskQuery(op)
+		if err != nil {
+			return nil, err
+		}
+		count := int64(0)
+		if err := query.Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to count resources: %w", err)
+		}
+		op.ResourceCount = int(count)
+	} else {
+		// Changes apply to the risks selected now, as they are now
+		versions, err := s.selectRiskVersions(op)
+		if err != nil {
+			return nil, err
+		}
+		if op.RiskVersions, err = json.Marshal(versions); err != nil {
+			return nil, fmt.Errorf("failed to encode selected risks: %w", err)
+		}
+		op.ResourceCount = len(versions)
 	}
 
 	if err := s.db.Create(op).Error; err != nil {
 		return nil, fmt.Errorf("failed to create bulk operation: %w", err)
 	}
 
-	// Start processing asynchronously
-	go s.processBulkOperation(op)
+	// Process asynchronously on the durable job queue
+	job, err := s.jobs.Enqueue(JobTypeBulkOperation, bulkOperationJob{OperationID: op.ID}, EnqueueOptions{
+		CreatedBy:      userID,
+		IdempotencyKey: fmt.Sprintf("bulk_operation:%s", op.ID),
+		MaxAttempts:    3,
+	})
+	if err != nil {
+		s.db.Model(op).Updates(map[string]interface{}{
+			"status":        domain.BulkOperationStatusFailed,
+			"error_message": err.Error(),
+		})
+		return nil, err
+	}
+
+	op.JobID = &job.ID
+	s.db.Model(op).Update("job_id", job.ID)
 
 	return op, nil
 }
@@ -86,10 +181,37 @@ func (s *BulkOperationService) ListBulkOperations(userID uuid.UUID, limit int, o
 	return ops, nil
 }
 
+// runBulkOperationJob is the job queue handler for bulk operations
+func (s *BulkOperationService) runBulkOperationJob(ctx context.Context, job *domain.Job) error {
+	var payload bulkOperationJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid bulk operation payload: %w", err))
+	}
+
+	op, err := s.GetBulkOperation(payload.OperationID)
+	if err != nil {
+		return PermanentJobError(fmt.Errorf("bulk operation %s not found: %w", payload.OperationID, err))
+	}
+
+	// The job may start before CreateBulkOperation has linked it
+	op.JobID = &job.ID
+
+	// A retried attempt starts over; per-resource logs of earlier attempts are
+	// kept, and the risks they changed or skipped are not processed again
+	op.ProcessedCount = 0
+	op.ErrorCount = 0
+	op.SkippedCount = 0
+
+	return s.processBulkOperation(ctx, op)
+}
+
 // processBulkOperation processes a bulk operation job
-func (s *BulkOperationService) processBulkOperation(op *domain.BulkOperation) {
+func (s *BulkOperationService) processBulkOperation(ctx context.Context, op *domain.BulkOperation) error {
 	log.Printf("📊 Starting bulk operation: %s (%s)", op.ID, op.OperationType)
 
+	// Risk changes are attributed to the creator of the operation
+	ctx = domain.WithActor(ctx, op.CreatedBy.String())
+
 	// Mark as processing
 	now := time.Now()
 	s.db.Model(op).Updates(map[string]interface{}{
@@ -100,13 +222,13 @@ func (s *BulkOperationService) processBulkOperation(op *domain.BulkOperation) {
 	var err error
 	switch op.OperationType {
 	case domain.BulkOperationTypeUpdate:
-		err = s.processBulkUpdate(op)
+		err = s.processBulkUpdate(ctx, op)
 	case domain.BulkOperationTypeDelete:
-		err = s.processBulkDelete(op)
+		err = s.processBulkDelete(ctx, op)
 	case domain.BulkOperationTypeExport:
-		err = s.processBulkExport(op)
+		err = s.processBulkExport(ctx, op)
 	case domain.BulkOperationTypeAssign:
-		err = s.processBulkAssign(op)
+		err = s.processBulkAssign(ctx, op)
 	}
 
 	// Mark as completed
@@ -117,8 +239,14 @@ func (s *BulkOperationService) processBulkOperation(op *domain.BulkOperation) {
 	}
 
 	s.db.Model(op).Updates(map[string]interface{}{
-		"status":       status,
-		"completed_at": completed,
+		"status":          status,
+		"completed_at":    completed,
+		"processed_count": op.ProcessedCount,
+		"error_count":     op.ErrorCount,
+		"skipped_count":   op.SkippedCount,
+		"result_url":      op.ResultURL,
+		"artifact_key":    op.ArtifactKey,
+		"artifact_size":   op.ArtifactSize,
 		"error_message": func() string {
 			if err != nil {
 				return err.Error()
@@ -128,141 +256,326 @@ func (s *BulkOperationService) processBulkOperation(op *domain.BulkOperation) {
 	})
 
 	log.Printf("✅ Bulk operation completed: %s (status: %s)", op.ID, status)
+	return err
 }
 
 // processBulkUpdate handles bulk update operations
-func (s *BulkOperationService) processBulkUpdate(op *domain.BulkOperation) error {
-	risks, err := s.getRisksByFilter(op.FilterQuery)
+func (s *BulkOperationService) processBulkUpdate(ctx context.Context, op *domain.BulkOperation) error {
+	return s.forEachRisk(ctx, op, func(tx *gorm.DB, risk *domain.Risk) error {
+		// Update the risk with provided data
+		return s.updateRiskFromData(tx, risk, op.UpdateData)
+	})
+}
+
+// processBulkDelete handles bulk delete operations
+func (s *BulkOperationService) processBulkDelete(ctx context.Context, op *domain.BulkOperation) error {
+	return s.forEachRisk(ctx, op, func(tx *gorm.DB, risk *domain.Risk) error {
+		return tx.Delete(risk).Error
+	})
+}
+
+// forEachRisk applies a change to each risk the operation selected. Each risk
+// is locked while it is checked and changed: risks changed or deleted since
+// the operation was created are skipped and logged, and risks an earlier
+// attempt of the job changed or skipped are not processed again.
+func (s *BulkOperationService) forEachRisk(ctx context.Context, op *domain.BulkOperation, apply func(tx *gorm.DB, risk *domain.Risk) error) error {
+	selected, err := op.SelectedVersions()
 	if err != nil {
-		return err
+		return PermanentJobError(err)
+	}
+	if selected == nil {
+		// Operations created before the selection was recorded
+		if selected, err = s.selectRiskVersions(op); err != nil {
+			return err
+		}
 	}
 
-	for _, risk := range risks {
-		// Update the risk with provided data
-		if err := s.updateRiskFromData(risk, op.UpdateData); err != nil {
-			s.logBulkOperationError(op.ID, risk.ID, "risk", err.Error())
-			op.ErrorCount++
+	var logs []domain.BulkOperationLog
+	if err := s.db.Select("resource_id, status").
+		Where("bulk_operation_id = ? AND status IN ?", op.ID, []string{"success", "skipped"}).
+		Find(&logs).Error; err != nil {
+		return fmt.Errorf("failed to load bulk operation logs: %w", err)
+	}
+	done := make(map[uuid.UUID]string, len(logs))
+	for _, l := range logs {
+		done[l.ResourceID] = l.Status
+	}
+
+	for _, ref := range selected {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch done[ref.ID] {
+		case "success":
+			op.ProcessedCount++
+			continue
+		case "skipped":
+			op.SkippedCount++
 			continue
 		}
 
-		s.logBulkOperationSuccess(op.ID, risk.ID, "risk")
-		op.ProcessedCount++
-		s.db.Model(op).Update("processed_count", op.ProcessedCount)
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var risk domain.Risk
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&risk, "id = ?", ref.ID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: the risk was deleted", domain.ErrVersionConflict)
+				}
+				return err
+			}
+			if risk.Version != ref.Version {
+				return fmt.Errorf("%w: version %d was selected, the risk is now at version %d",
+					domain.ErrVersionConflict, ref.Version, risk.Version)
+			}
+			return apply(tx, &risk)
+		})
+		switch {
+		case errors.Is(err, domain.ErrVersionConflict):
+			s.logBulkOperationSkipped(op.ID, ref.ID, "risk", err.Error())
+			op.SkippedCount++
+		case err != nil:
+			s.logBulkOperationError(op.ID, ref.ID, "risk", err.Error())
+			op.ErrorCount++
+		default:
+			s.logBulkOperationSuccess(op.ID, ref.ID, "risk")
+			op.ProcessedCount++
+			s.recordProgress(op)
+		}
 	}
 
 	return nil
 }
 
-// processBulkDelete handles bulk delete operations
-func (s *BulkOperationService) processBulkDelete(op *domain.BulkOperation) error {
-	risks, err := s.getRisksByFilter(op.FilterQuery)
+// processBulkExport renders the filtered risks into a CSV, XLSX or JSON file.
+// Risks are streamed in batches into a temporary file, so memory stays flat
+// regardless of the export size, then the file is uploaded to the blob store.
+func (s *BulkOperationService) processBulkExport(ctx context.Context, op *domain.BulkOperation) error {
+	contentType, ext, err := exportContentType(op.ExportFormat)
+	if err != nil {
+		return PermanentJobError(err)
+	}
+
+	available, err := s.ExportColumns()
 	if err != nil {
 		return err
 	}
+	columns, err := SelectExportColumns(available, op.ExportColumns)
+	if err != nil {
+		return PermanentJobError(err)
+	}
 
-	for _, risk := range risks {
-		if err := s.db.Delete(risk).Error; err != nil {
-			s.logBulkOperationError(op.ID, risk.ID, "risk", err.Error())
-			op.ErrorCount++
-			continue
-		}
+	tmp, err := os.CreateTemp("", "openrisk-export-*."+ext)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-		s.logBulkOperationSuccess(op.ID, risk.ID, "risk")
-		op.ProcessedCount++
-		s.db.Model(op).Update("processed_count", op.ProcessedCount)
+	writer, err := newExportWriter(op.ExportFormat, tmp, columns)
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
+	riskFilter, err := s.riskFilter(op)
+	if err != nil {
+		return err
+	}
 
-// processBulkExport handles bulk export operations
-func (s *BulkOperationService) processBulkExport(op *domain.BulkOperation) error {
-	risks, err := s.getRisksByFilter(op.FilterQuery)
+	query := riskFilter.Preload(riskFilter.Scope(s.db.Model(&domain.Risk{})), "Mitigations", "Assets")
+	err = pageRisksByCreation(ctx, query, exportBatchSize, func(batch []*domain.Risk) error {
+		if err := riskFilter.AttachVersions(s.db, batch); err != nil {
+			return err
+		}
+		for _, risk := range batch {
+			if err := writer.WriteRow(risk); err != nil {
+				return fmt.Errorf("failed to write export row: %w", err)
+			}
+			op.ProcessedCount++
+		}
+		s.recordProgress(op)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish export file: %w", err)
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat export file: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind export file: %w", err)
+	}
 
-	// For now, just generate a JSON export URL (production would use S3/blob storage)
+	key := fmt.Sprintf("exports/bulk-operations/%s.%s", op.ID, ext)
+	if err := s.blobs.Put(ctx, key, tmp, info.Size(), contentType); err != nil {
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+
+	op.ArtifactKey = key
+	op.ArtifactSize = info.Size()
 	op.ResultURL = fmt.Sprintf("/api/v1/bulk-operations/%s/export-result", op.ID)
-	op.ProcessedCount = len(risks)
 
 	return nil
 }
 
-// processBulkAssign handles bulk mitigation assignment
-func (s *BulkOperationService) processBulkAssign(op *domain.BulkOperation) error {
-	risks, err := s.getRisksByFilter(op.FilterQuery)
+// pageRisksByCreation passes the risks selected by query to fn in pages of size, oldest
+// first. Pages are read by keyset on (created_at, id): FindInBatches pages on the
+// primary key alone and skips rows when the query is ordered by anything else.
+func pageRisksByCreation(ctx context.Context, query *gorm.DB, size int, fn func([]*domain.Risk) error) error {
+	query = query.Session(&gorm.Session{})
+
+	var last *domain.Risk
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page := query.Order("risks.created_at ASC, risks.id ASC").Limit(size)
+		if last != nil {
+			page = page.Where("risks.created_at > ? OR (risks.created_at = ? AND risks.id > ?)", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+		var batch []*domain.Risk
+		if err := page.Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < size {
+			return nil
+		}
+		last = batch[len(batch)-1]
+	}
+}
+
+// ExportColumns lists the columns a risk export can contain, including custom fields
+func (s *BulkOperationService) ExportColumns() ([]ExportColumn, error) {
+	var fields []domain.CustomField
+	if err := s.db.Where("scope = ?", domain.CustomFieldScopeRisk).Order("position ASC, name ASC").Find(&fields).Error; err != nil {
+		return nil, fmt.Errorf("failed to load custom fields: %w", err)
+	}
+	return AvailableExportColumns(fields), nil
+}
+
+// ExportDownloadURL returns a signed, expiring download path for a completed export
+func (s *BulkOperationService) ExportDownloadURL(op *domain.BulkOperation, ttl time.Duration) (string, time.Time, error) {
+	if op.OperationType != domain.BulkOperationTypeExport || op.Status != domain.BulkOperationStatusCompleted || op.ArtifactKey == "" {
+		return "", time.Time{}, ErrExportNotReady
+	}
+
+	expires := time.Now().Add(ttl)
+	signature := signExportDownload(s.signingKey, op.ID, expires)
+	return fmt.Sprintf("/api/v1/exports/%s/download?expires=%d&signature=%s", op.ID, expires.Unix(), signature), expires, nil
+}
+
+// OpenExport verifies a signed download link and opens the export artifact
+func (s *BulkOperationService) OpenExport(ctx context.Context, opID uuid.UUID, expires int64, signature string) (io.ReadCloser, *domain.BulkOperation, error) {
+	if !verifyExportDownload(s.signingKey, opID, expires, signature, time.Now()) {
+		return nil, nil, ErrInvalidDownloadSignature
+	}
+
+	op, err := s.GetBulkOperation(opID)
+	if err != nil || op.ArtifactKey == "" {
+		return nil, nil, ErrExportNotReady
+	}
+
+	body, err := s.blobs.Get(ctx, op.ArtifactKey)
 	if err != nil {
-		return err
+		if errors.Is(err, ports.ErrBlobNotFound) {
+			return nil, nil, ErrExportNotReady
+		}
+		return nil, nil, err
 	}
+	return body, op, nil
+}
 
+// processBulkAssign handles bulk mitigation assignment operations
+func (s *BulkOperationService) processBulkAssign(ctx context.Context, op *domain.BulkOperation) error {
 	// Get mitigation ID from update data
 	mitigationID, ok := op.UpdateData["mitigation_id"].(string)
 	if !ok {
 		return fmt.Errorf("mitigation_id required for assign operation")
 	}
 
-	for _, risk := range risks {
+	err := s.forEachRisk(ctx, op, func(tx *gorm.DB, risk *domain.Risk) error {
 		// Create risk-mitigation association
-		if err := s.db.Model(risk).Association("Mitigations").Append(&domain.Mitigation{
+		return tx.Model(risk).Association("Mitigations").Append(&domain.Mitigation{
 			Title: "Assigned via bulk operation",
-		}); err != nil {
-			s.logBulkOperationError(op.ID, risk.ID, "risk", err.Error())
-			op.ErrorCount++
-			continue
-		}
-
-		s.logBulkOperationSuccess(op.ID, risk.ID, "risk")
-		op.ProcessedCount++
-		s.db.Model(op).Update("processed_count", op.ProcessedCount)
-	}
+		})
+	})
 
 	_ = mitigationID // Use the ID as needed
-	return nil
+	return err
 }
 
-// getRisksByFilter retrieves risks matching a filter
-func (s *BulkOperationService) getRisksByFilter(filter map[string]interface{}) ([]*domain.Risk, error) {
-	var risks []*domain.Risk
-	query := s.db
+// recordProgress persists the processed count and mirrors it on the job
+func (s *BulkOperationService) recordProgress(op *domain.BulkOperation) {
+	s.db.Model(op).Update("processed_count", op.ProcessedCount)
 
-	// Apply filters (simple key-value matching for now)
-	if status, ok := filter["status"].(string); ok {
-		query = query.Where("status = ?", status)
-	}
-	if minScore, ok := filter["min_score"].(float64); ok {
-		query = query.Where("score >= ?", minScore)
-	}
-	if maxScore, ok := filter["max_score"].(float64); ok {
-		query = query.Where("score <= ?", maxScore)
-	}
-	if tags, ok := filter["tags"].([]interface{}); ok {
-		query = query.Where("tags && ?", tags)
+	if op.JobID != nil && op.ResourceCount > 0 {
+		percent := op.ProcessedCount * 100 / op.ResourceCount
+		s.jobs.ReportProgress(*op.JobID, percent, fmt.Sprintf("%d/%d resources processed", op.ProcessedCount, op.ResourceCount))
 	}
+}
 
-	if err := query.Find(&risks).Error; err != nil {
+// selectRiskVersions lists the risks an operation selects with their
+// current version
+func (s *BulkOperationService) selectRiskVersions(op *domain.BulkOperation) ([]domain.ResourceVersion, error) {
+	query, err := s.riskQuery(op)
+	if err != nil {
 		return nil, err
 	}
 
-	return risks, nil
+	versions := []domain.ResourceVersion{} // Recorded as [] when nothing matches
+	if err := query.Select("risks.id, risks.version").Order("risks.created_at ASC, risks.id ASC").
+		Scan(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to select resources: %w", err)
+	}
+	return versions, nil
 }
 
-// countResourcesByFilter counts resources matching a filter
-func (s *BulkOperationService) countResourcesByFilter(count *int64, filter map[string]interface{}) error {
-	query := s.db
-
-	if status, ok := filter["status"].(string); ok {
-		query = query.Where("status = ?", status)
+// riskQuery selects the risks of an operation
+func (s *BulkOperationService) riskQuery(op *domain.BulkOperation) (*gorm.DB, error) {
+	riskFilter, err := s.riskFilter(op)
+	if err != nil {
+		return nil, err
 	}
-	if minScore, ok := filter["min_score"].(float64); ok {
-		query = query.Where("score >= ?", minScore)
+	return riskFilter.Scope(s.db.Model(&domain.Risk{})), nil
+}
+
+// riskFilter compiles the filter of an operation. It is evaluated as its
+// creator at its creation time, so the job processes the selection the
+// creator previewed even when it runs later, and exports as of a date read
+// the versions valid then.
+func (s *BulkOperationService) riskFilter(op *domain.BulkOperation) (*RiskFilter, error) {
+	expression := op.Filter
+	if expression == "" {
+		// Operations queued before filter expressions existed
+		expression = LegacyRiskFilter(op.FilterQuery)
 	}
 
-	return query.Model(&domain.Risk{}).Count(count).Error
+	fctx := s.filters.Context(op.CreatedBy)
+	fctx.Now = op.CreatedAt
+	fctx.AsOf = op.AsOf
+	riskFilter, err := s.filters.Compile(expression, fctx)
+	if err != nil {
+		var filterErr *filter.Error
+		if errors.As(err, &filterErr) {
+			return nil, PermanentJobError(err)
+		}
+		return nil, err
+	}
+	return riskFilter, nil
 }
 
 // updateRiskFromData updates a risk with provided data
-func (s *BulkOperationService) updateRiskFromData(risk *domain.Risk, data map[string]interface{}) error {
+func (s *BulkOperationService) updateRiskFromData(tx *gorm.DB, risk *domain.Risk, data map[string]interface{}) error {
 	if status, ok := data["status"].(string); ok {
 		risk.Status = domain.RiskStatus(status)
 	}
@@ -276,7 +589,7 @@ func (s *BulkOperationService) updateRiskFromData(risk *domain.Risk, data map[st
 		risk.Owner = owner
 	}
 
-	return s.db.Save(risk).Error
+	return tx.Save(risk).Error
 }
 
 // logBulkOperationSuccess logs a successful resource processing
@@ -291,6 +604,20 @@ func (s *BulkOperationService) logBulkOperationSuccess(opID, resourceID uuid.UUI
 	})
 }
 
+// logBulkOperationSkipped logs a resource left unchanged because it changed
+// since the operation was created
+func (s *BulkOperationService) logBulkOperationSkipped(opID, resourceID uuid.UUID, resourceType, reason string) {
+	s.db.Create(&domain.BulkOperationLog{
+		ID:              uuid.New(),
+		BulkOperationID: opID,
+		ResourceID:      resourceID,
+		ResourceType:    resourceType,
+		Status:          "skipped",
+		ErrorMessage:    reason,
+		CreatedAt:       time.Now(),
+	})
+}
+
 // logBulkOperationError logs an error during resource processing
 func (s *BulkOperationService) logBulkOperationError(opID, resourceID uuid.UUID, resourceType, errMsg string) {
 	s.db.Create(&domain.BulkOperationLog{
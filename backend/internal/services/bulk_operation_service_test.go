@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// exportRiskT is the part of the risks table read by the export paging
+type exportRiskT struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Title     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (exportRiskT) TableName() string { return "risks" }
+
+func TestPageRisksByCreation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:page_risks?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&exportRiskT{}))
+
+	// More than several pages, inserted out of creation order, with creation times shared
+	// by several risks so pages also break ties on the ID
+	const total = 3*exportBatchSize + 37
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := make([]exportRiskT, total)
+	for i := range rows {
+		rows[i] = exportRiskT{
+			ID:        uuid.New(),
+			Title:     "risk",
+			CreatedAt: base.Add(time.Duration(rand.Intn(total/4)) * time.Minute),
+		}
+	}
+	rand.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+	require.NoError(t, db.CreateInBatches(rows, 200).Error)
+
+	seen := make(map[uuid.UUID]bool, total)
+	var previous time.Time
+	pages := 0
+	err = pageRisksByCreation(context.Background(), db.Model(&domain.Risk{}), exportBatchSize, func(batch []*domain.Risk) error {
+		pages++
+		for _, risk := range batch {
+			assert.False(t, seen[risk.ID], "risk %s exported twice", risk.ID)
+			seen[risk.ID] = true
+			assert.False(t, risk.CreatedAt.Before(previous), "risks out of creation order")
+			previous = risk.CreatedAt
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, seen, total)
+	assert.Equal(t, 4, pages)
+}
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrControlNotFound        = errors.New("control not found")
+	ErrControlCatalogNotFound = errors.New("control catalog not found")
+	ErrInvalidControlCatalog  = errors.New("invalid control catalog")
+)
+
+// ControlService keeps the control catalog loaded from OSCAL catalogs and
+// profiles, how each tenant implements the controls, and selects what system
+// security plans and plans of action and milestones are built from
+type ControlService struct {
+	db *gorm.DB
+}
+
+// NewControlService creates a new control service
+func NewControlService(db *gorm.DB) *ControlService {
+	return &ControlService{db: db}
+}
+
+// ImportCatalog stores a catalog with its controls, replacing the version
+// stored under the same OSCAL UUID. Controls keep their ID across versions
+// so that their implementations follow; those no longer in the catalog are
+// removed with their implementations.
+func (s *ControlService) ImportCatalog(ctx context.Context, catalog domain.ControlCatalog, controls []domain.Control) (*domain.ControlCatalog, error) {
+	if catalog.OSCALUUID == "" || len(controls) == 0 {
+		return nil, fmt.Errorf("%w: a catalog needs a UUID and controls", ErrInvalidControlCatalog)
+	}
+	catalog.Kind = domain.ControlCatalogKind
+	catalog.Controls = len(controls)
+	catalog.BaseCatalogID = nil
+	catalog.ControlIDs = nil
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveCatalog(tx, &catalog); err != nil {
+			return err
+		}
+
+		var existing []domain.Control
+		if err := tx.Select("id", "control_id").Where("catalog_id = ?", catalog.ID).Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to load controls: %w", err)
+		}
+		ids := make(map[string]uuid.UUID, len(existing))
+		for _, c := range existing {
+			ids[c.ControlID] = c.ID
+		}
+
+		kept := make([]uuid.UUID, 0, len(controls))
+		for i := range controls {
+			c := &controls[i]
+			c.CatalogID = catalog.ID
+			if id, ok := ids[c.ControlID]; ok {
+				c.ID = id
+			} else {
+				c.ID = uuid.New()
+			}
+			kept = append(kept, c.ID)
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			UpdateAll: true,
+		}).CreateInBatches(controls, 500).Error; err != nil {
+			return fmt.Errorf("failed to store controls: %w", err)
+		}
+
+		removed := tx.Model(&domain.Control{}).Select("id").Where("catalog_id = ? AND id NOT IN ?", catalog.ID, kept)
+		if err := tx.Where("control_id IN (?)", removed).Delete(&domain.ControlImplementation{}).Error; err != nil {
+			return fmt.Errorf("failed to remove implementations: %w", err)
+		}
+		if err := tx.Where("catalog_id = ? AND id NOT IN ?", catalog.ID, kept).Delete(&domain.Control{}).Error; err != nil {
+			return fmt.Errorf("failed to remove controls: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
+
+// ImportProfile stores a profile selecting controlIDs of the catalog baseID,
+// replacing the version stored under the same OSCAL UUID
+func (s *ControlService) ImportProfile(ctx context.Context, profile domain.ControlCatalog, baseID uuid.UUID, controlIDs []string) (*domain.ControlCatalog, error) {
+	if profile.OSCALUUID == "" || len(controlIDs) == 0 {
+		return nil, fmt.Errorf("%w: a profile needs a UUID and selects controls", ErrInvalidControlCatalog)
+	}
+	profile.Kind = domain.ControlProfileKind
+	profile.BaseCatalogID = &baseID
+	profile.ControlIDs = pq.StringArray(controlIDs)
+	profile.Controls = len(controlIDs)
+	if err := saveCatalog(s.db.WithContext(ctx), &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func saveCatalog(tx *gorm.DB, catalog *domain.ControlCatalog) error {
+	var current domain.ControlCatalog
+	err := tx.Take(&current, "oscal_uuid = ?", catalog.OSCALUUID).Error
+	switch {
+	case err == nil:
+		if current.Kind != catalog.Kind {
+			return fmt.Errorf("%w: %s is already loaded as a %s", ErrInvalidControlCatalog, catalog.OSCALUUID, current.Kind)
+		}
+		catalog.ID = current.ID
+		catalog.CreatedAt = current.CreatedAt
+		if err := tx.Save(catalog).Error; err != nil {
+			return fmt.Errorf("failed to update control catalog: %w", err)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		catalog.ID = uuid.New()
+		if err := tx.Create(catalog).Error; err != nil {
+			return fmt.Errorf("failed to store control catalog: %w", err)
+		}
+	default:
+		return fmt.Errorf("failed to load control catalog: %w", err)
+	}
+	return nil
+}
+
+// ResolveImport finds the catalog or profile a profile import refers to,
+// by the OSCAL UUID or file name its references carry, and returns the
+// catalog holding the controls with the controls it offers: all of a
+// catalog, those a profile selects
+func (s *ControlService) ResolveImport(refs []string) (*domain.ControlCatalog, []domain.Control, error) {
+	var catalogs []domain.ControlCatalog
+	if err := s.db.Find(&catalogs).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load control catalogs: %w", err)
+	}
+	for _, ref := range refs {
+		for i := range catalogs {
+			c := &catalogs[i]
+			if strings.Contains(strings.ToLower(ref), strings.ToLower(c.OSCALUUID)) ||
+				(c.FileName != "" && path.Base(ref) == c.FileName) {
+				return s.CatalogControls(c.ID)
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: imported document %s is not loaded, import it first", ErrInvalidControlCatalog, strings.Join(refs, ", "))
+}
+
+// CatalogControls returns the catalog holding the controls of a catalog or
+// profile, with those controls in catalog order
+func (s *ControlService) CatalogControls(id uuid.UUID) (*domain.ControlCatalog, []domain.Control, error) {
+	var catalog domain.ControlCatalog
+	if err := s.db.Take(&catalog, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrControlCatalogNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to load control catalog: %w", err)
+	}
+	db := s.controlsOf(&catalog)
+	var controls []domain.Control
+	if err := db.Order("position").Find(&controls).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load controls: %w", err)
+	}
+	if catalog.Kind == domain.ControlProfileKind && catalog.BaseCatalogID != nil {
+		var base domain.ControlCatalog
+		if err := s.db.Take(&base, "id = ?", *catalog.BaseCatalogID).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to load base catalog: %w", err)
+		}
+		return &base, controls, nil
+	}
+	return &catalog, controls, nil
+}
+
+// controlsOf selects the controls of a catalog or profile
+func (s *ControlService) controlsOf(catalog *domain.ControlCatalog) *gorm.DB {
+	db := s.db.Model(&domain.Control{})
+	if catalog.Kind == domain.ControlProfileKind && catalog.BaseCatalogID != nil {
+		return db.Where("catalog_id = ? AND control_id IN ?", *catalog.BaseCatalogID, []string(catalog.ControlIDs))
+	}
+	return db.Where("catalog_id = ?", catalog.ID)
+}
+
+// ListCatalogs lists the loaded catalogs and profiles
+func (s *ControlService) ListCatalogs() ([]domain.ControlCatalog, error) {
+	catalogs := []domain.ControlCatalog{}
+	if err := s.db.Order("kind, title").Find(&catalogs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list control catalogs: %w", err)
+	}
+	return catalogs, nil
+}
+
+// ControlFilter selects controls of a catalog or profile, with their
+// implementation state for a tenant
+type ControlFilter struct {
+	TenantID  uuid.UUID
+	CatalogID uuid.UUID
+	Family    string
+	Status    string // Implementation state; "none" for controls not yet assessed
+	Query     string
+	Limit     int
+	Offset    int
+}
+
+// ListControls lists controls in catalog order with their implementation
+// state for the tenant
+func (s *ControlService) ListControls(filter ControlFilter) ([]domain.Control, int64, error) {
+	db := s.db.Model(&domain.Control{})
+	if filter.CatalogID != uuid.Nil {
+		var catalog domain.ControlCatalog
+		if err := s.db.Take(&catalog, "id = ?", filter.CatalogID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, 0, ErrControlCatalogNotFound
+			}
+			return nil, 0, fmt.Errorf("failed to load control catalog: %w", err)
+		}
+		db = s.controlsOf(&catalog)
+	}
+	if filter.Family != "" {
+		db = db.Where("family = ?", filter.Family)
+	}
+	if filter.Query != "" {
+		like := "%" + filter.Query + "%"
+		db = db.Where("control_id ILIKE ? OR label ILIKE ? OR name ILIKE ?", like, like, like)
+	}
+	implemented := s.db.Model(&domain.ControlImplementation{}).Select("control_id").Where("tenant_id = ?", filter.TenantID)
+	switch {
+	case filter.Status == "none":
+		db = db.Where("id NOT IN (?)", implemented)
+	case filter.Status != "":
+		if !domain.IsControlStatus(filter.Status) {
+			return nil, 0, domain.ErrInvalidControlStatus
+		}
+		db = db.Where("id IN (?)", implemented.Where("status = ?", filter.Status))
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count controls: %w", err)
+	}
+	controls := []domain.Control{}
+	if err := db.Order("catalog_id, position").Limit(filter.Limit).Offset(filter.Offset).Find(&controls).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list controls: %w", err)
+	}
+	if len(controls) == 0 {
+		return controls, total, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(controls))
+	for _, c := range controls {
+		ids = append(ids, c.ID)
+	}
+	var implementations []domain.ControlImplementation
+	if err := s.db.Where("tenant_id = ? AND control_id IN ?", filter.TenantID, ids).Find(&implementations).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load control implementations: %w", err)
+	}
+	status := make(map[uuid.UUID]string, len(implementations))
+	for _, impl := range implementations {
+		status[impl.ControlID] = impl.Status
+	}
+	for i := range controls {
+		controls[i].Status = status[controls[i].ID]
+	}
+	return controls, total, nil
+}
+
+// ControlDetail is a control with its implementation by the tenant and the
+// compliance evidence referencing it
+type ControlDetail struct {
+	domain.Control
+	Implementation *domain.ControlImplementation   `json:"implementation,omitempty"`
+	Evidence       []domain.RiskComplianceEvidence `json:"evidence"`
+}
+
+// GetControl returns a control with its implementation and evidence
+func (s *ControlService) GetControl(tenantID, id uuid.UUID) (*ControlDetail, error) {
+	var detail ControlDetail
+	if err := s.db.Take(&detail.Control, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrControlNotFound
+		}
+		return nil, fmt.Errorf("failed to load control: %w", err)
+	}
+	var impl domain.ControlImplementation
+	err := s.db.Take(&impl, "tenant_id = ? AND control_id = ?", tenantID, id).Error
+	switch {
+	case err == nil:
+		detail.Implementation = &impl
+		detail.Status = impl.Status
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to load control implementation: %w", err)
+	}
+	evidence, err := s.controlEvidence(tenantID, []domain.Control{detail.Control})
+	if err != nil {
+		return nil, err
+	}
+	detail.Evidence = evidence[detail.ID]
+	if detail.Evidence == nil {
+		detail.Evidence = []domain.RiskComplianceEvidence{}
+	}
+	return &detail, nil
+}
+
+// SetImplementation records how the tenant implements a control
+func (s *ControlService) SetImplementation(ctx context.Context, tenantID, userID, controlID uuid.UUID, input domain.ControlImplementation) (*domain.ControlImplementation, error) {
+	if !domain.IsControlStatus(input.Status) {
+		return nil, domain.ErrInvalidControlStatus
+	}
+	db := s.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&domain.Control{}).Where("id = ?", controlID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to load control: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrControlNotFound
+	}
+
+	impl := domain.ControlImplementation{
+		ID:              uuid.New(),
+		TenantID:        tenantID,
+		ControlID:       controlID,
+		Status:          input.Status,
+		Description:     strings.TrimSpace(input.Description),
+		ResponsibleRole: strings.TrimSpace(input.ResponsibleRole),
+		UpdatedBy:       userID,
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "control_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "description", "responsible_role", "updated_by", "updated_at"}),
+	}).Create(&impl).Error; err != nil {
+		return nil, fmt.Errorf("failed to store control implementation: %w", err)
+	}
+	if err := db.Take(&impl, "tenant_id = ? AND control_id = ?", tenantID, controlID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load control implementation: %w", err)
+	}
+	return &impl, nil
+}
+
+// ControlPlan is what a system security plan is built from: the controls of
+// a catalog or profile, how the tenant implements them and the evidence
+// referencing them, by control ID
+type ControlPlan struct {
+	Profile         domain.ControlCatalog
+	Controls        []domain.Control
+	Implementations map[uuid.UUID]domain.ControlImplementation
+	Evidence        map[uuid.UUID][]domain.RiskComplianceEvidence
+}
+
+// ImplementationPlan selects the implementation of the controls of a
+// catalog or profile by the tenant
+func (s *ControlService) ImplementationPlan(tenantID, catalogID uuid.UUID) (*ControlPlan, error) {
+	plan := &ControlPlan{
+		Implementations: map[uuid.UUID]domain.ControlImplementation{},
+	}
+	if err := s.db.Take(&plan.Profile, "id = ?", catalogID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrControlCatalogNotFound
+		}
+		return nil, fmt.Errorf("failed to load control catalog: %w", err)
+	}
+	if err := s.controlsOf(&plan.Profile).Order("position").Find(&plan.Controls).Error; err != nil {
+		return nil, fmt.Errorf("failed to load controls: %w", err)
+	}
+	if len(plan.Controls) == 0 {
+		return nil, fmt.Errorf("%w: %s has no controls", ErrInvalidControlCatalog, plan.Profile.Title)
+	}
+
+	ids := make([]uuid.UUID, 0, len(plan.Controls))
+	for _, c := range plan.Controls {
+		ids = append(ids, c.ID)
+	}
+	var implementations []domain.ControlImplementation
+	if err := s.db.Where("tenant_id = ? AND control_id IN ?", tenantID, ids).Find(&implementations).Error; err != nil {
+		return nil, fmt.Errorf("failed to load control implementations: %w", err)
+	}
+	for _, impl := range implementations {
+		plan.Implementations[impl.ControlID] = impl
+	}
+
+	evidence, err := s.controlEvidence(tenantID, plan.Controls)
+	if err != nil {
+		return nil, err
+	}
+	plan.Evidence = evidence
+	return plan, nil
+}
+
+// controlEvidence loads the compliance evidence of the tenant and matches it
+// to the controls
+func (s *ControlService) controlEvidence(tenantID uuid.UUID, controls []domain.Control) (map[uuid.UUID][]domain.RiskComplianceEvidence, error) {
+	var evidence []domain.RiskComplianceEvidence
+	if err := s.db.Where("tenant_id = ? AND requirement_reference <> ''", tenantID).
+		Order("evidence_date DESC").Find(&evidence).Error; err != nil {
+		return nil, fmt.Errorf("failed to load compliance evidence: %w", err)
+	}
+	return matchEvidence(controls, evidence), nil
+}
+
+// matchEvidence matches evidence to the controls its requirement reference
+// names, by OSCAL ID or label, however written (AC-2(1), ac-2.1)
+func matchEvidence(controls []domain.Control, evidence []domain.RiskComplianceEvidence) map[uuid.UUID][]domain.RiskComplianceEvidence {
+	refs := map[string]uuid.UUID{}
+	for _, c := range controls {
+		refs[domain.NormalizeControlRef(c.ControlID)] = c.ID
+		if c.Label != "" {
+			if _, taken := refs[domain.NormalizeControlRef(c.Label)]; !taken {
+				refs[domain.NormalizeControlRef(c.Label)] = c.ID
+			}
+		}
+	}
+	matched := map[uuid.UUID][]domain.RiskComplianceEvidence{}
+	for _, e := range evidence {
+		if id, ok := refs[domain.NormalizeControlRef(e.RequirementReference)]; ok {
+			matched[id] = append(matched[id], e)
+		}
+	}
+	return matched
+}
+
+// OpenRisk is a risk still calling for action, with its treatment plans and
+// their actions; Deviation is set when an active exception accepts it
+type OpenRisk struct {
+	Risk      domain.Risk
+	Plans     []domain.RiskTreatmentPlan
+	Deviation bool
+}
+
+// OpenRisks selects the open risks of the tenant, highest score first: those
+// not mitigated, accepted ones only under an active exception
+func (s *ControlService) OpenRisks(tenantID uuid.UUID) ([]OpenRisk, error) {
+	db := s.db.Model(&domain.Risk{}).Preload("Mitigations").Where("status <> ?", domain.StatusMitigated)
+	if tenantID != uuid.Nil {
+		db = db.Where("id IN (?)", s.db.Model(&domain.RiskRegister{}).Select("risk_id").Where("tenant_id = ?", tenantID))
+	}
+	var risks []domain.Risk
+	if err := db.Order("score DESC, id").Find(&risks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risks: %w", err)
+	}
+	if len(risks) == 0 {
+		return []OpenRisk{}, nil
+	}
+	riskIDs := make([]uuid.UUID, 0, len(risks))
+	for _, r := range risks {
+		riskIDs = append(riskIDs, r.ID)
+	}
+
+	var exceptions []domain.RiskException
+	if err := s.db.Select("risk_id").Where("risk_id IN ? AND status = ?", riskIDs, domain.ExceptionActive).Find(&exceptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk exceptions: %w", err)
+	}
+	deviations := map[uuid.UUID]bool{}
+	for _, e := range exceptions {
+		deviations[e.RiskID] = true
+	}
+
+	var registers []domain.RiskRegister
+	if err := s.db.Select("id", "risk_id").Where("risk_id IN ?", riskIDs).Find(&registers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk registers: %w", err)
+	}
+	plans := map[uuid.UUID][]domain.RiskTreatmentPlan{}
+	if len(registers) > 0 {
+		riskOf := make(map[uuid.UUID]uuid.UUID, len(registers))
+		registerIDs := make([]uuid.UUID, 0, len(registers))
+		for _, reg := range registers {
+			riskOf[reg.ID] = reg.RiskID
+			registerIDs = append(registerIDs, reg.ID)
+		}
+		var treatments []domain.RiskTreatmentPlan
+		if err := s.db.Preload("Actions", func(db *gorm.DB) *gorm.DB { return db.Order("due_date") }).
+			Where("risk_register_id IN ?", registerIDs).Order("implementation_end").Find(&treatments).Error; err != nil {
+			return nil, fmt.Errorf("failed to load treatment plans: %w", err)
+		}
+		for _, p := range treatments {
+			plans[riskOf[p.RiskRegisterID]] = append(plans[riskOf[p.RiskRegisterID]], p)
+		}
+	}
+
+	open := make([]OpenRisk, 0, len(risks))
+	for _, r := range risks {
+		if r.Status == domain.StatusAccepted && !deviations[r.ID] {
+			continue
+		}
+		open = append(open, OpenRisk{Risk: r, Plans: plans[r.ID], Deviation: deviations[r.ID]})
+	}
+	return open, nil
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestMatchEvidence(t *testing.T) {
+	ac2 := domain.Control{ID: uuid.New(), ControlID: "ac-2", Label: "AC-2"}
+	ac21 := domain.Control{ID: uuid.New(), ControlID: "ac-2.1", Label: "AC-2(1)"}
+	iso := domain.Control{ID: uuid.New(), ControlID: "a.5.15", Label: "A.5.15"}
+	evidence := []domain.RiskComplianceEvidence{
+		{ID: uuid.New(), RequirementReference: "AC-2"},
+		{ID: uuid.New(), RequirementReference: "AC-2 (1)"},
+		{ID: uuid.New(), RequirementReference: "ac-2.1"},
+		{ID: uuid.New(), RequirementReference: "A.5.15"},
+		{ID: uuid.New(), RequirementReference: "AC-3"},
+	}
+
+	matched := matchEvidence([]domain.Control{ac2, ac21, iso}, evidence)
+	assert.Equal(t, []domain.RiskComplianceEvidence{evidence[0]}, matched[ac2.ID])
+	assert.Equal(t, []domain.RiskComplianceEvidence{evidence[1], evidence[2]}, matched[ac21.ID], "label and ID, however written")
+	assert.Equal(t, []domain.RiskComplianceEvidence{evidence[3]}, matched[iso.ID])
+	assert.Len(t, matched, 3, "AC-3 is not in the catalog")
+}
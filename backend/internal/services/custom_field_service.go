@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -161,9 +162,10 @@ func (s *CustomFieldService) UpdateCustomField(fieldID uuid.UUID, req *domain.Up
 	return field, nil
 }
 
-// DeleteCustomField deletes a custom field (soft delete)
-func (s *CustomFieldService) DeleteCustomField(fieldID uuid.UUID) error {
-	return s.db.Delete(&domain.CustomField{}, "id = ?", fieldID).Error
+// DeleteCustomField deletes a custom field (soft delete); it stays in the
+// recycle bin until restored or purged
+func (s *CustomFieldService) DeleteCustomField(ctx context.Context, fieldID uuid.UUID) error {
+	return s.db.WithContext(ctx).Delete(&domain.CustomField{ID: fieldID}).Error
 }
 
 // CreateTemplate creates a custom field template
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var ErrInvalidCVSSMappingPolicy = errors.New("invalid CVSS mapping policy")
+
+// CVSSService scores CVSS vectors and derives the impact and probability of
+// risks carrying one, following the mapping policy of their tenant and the
+// criticality of their assets
+type CVSSService struct {
+	db *gorm.DB
+}
+
+// NewCVSSService creates a new CVSS service
+func NewCVSSService(db *gorm.DB) *CVSSService {
+	return &CVSSService{db: db}
+}
+
+// GetPolicy returns the tenant's CVSS mapping policy, or the default one
+func (s *CVSSService) GetPolicy(tenantID uuid.UUID) domain.CVSSMappingPolicy {
+	var policy domain.CVSSMappingPolicy
+	if err := s.db.First(&policy, "tenant_id = ?", tenantID).Error; err != nil {
+		return domain.DefaultCVSSMappingPolicy(tenantID)
+	}
+	return policy
+}
+
+// UpdatePolicy creates or replaces the tenant's CVSS mapping policy
+func (s *CVSSService) UpdatePolicy(tenantID, updatedBy uuid.UUID, input domain.CVSSMappingPolicy) (*domain.CVSSMappingPolicy, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCVSSMappingPolicy, err)
+	}
+
+	policy := s.GetPolicy(tenantID)
+	if len(input.ImpactThresholds) > 0 {
+		policy.ImpactThresholds = input.ImpactThresholds
+	}
+	if len(input.ProbabilityThresholds) > 0 {
+		policy.ProbabilityThresholds = input.ProbabilityThresholds
+	}
+	policy.IgnoreAssetCriticality = input.IgnoreAssetCriticality
+	policy.UpdatedBy = updatedBy
+	if policy.ID == uuid.Nil {
+		policy.ID = uuid.New()
+	}
+
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save CVSS mapping policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// Calculate scores a vector and maps it with the tenant's policy, as it
+// would be for a risk on the given assets
+func (s *CVSSService) Calculate(tenantID uuid.UUID, vector string, assetIDs []string) (*domain.CVSSMapping, error) {
+	parsed, err := domain.ParseCVSSVector(vector)
+	if err != nil {
+		return nil, err
+	}
+	var assets []*domain.Asset
+	if len(assetIDs) > 0 {
+		if err := s.db.Where("id IN ?", assetIDs).Find(&assets).Error; err != nil {
+			return nil, fmt.Errorf("failed to load assets: %w", err)
+		}
+		if err := NewImpactService(s.db).ApplyEffectiveCriticality(assets); err != nil {
+			return nil, err
+		}
+	}
+	policy := s.GetPolicy(tenantID)
+	mapping := policy.Map(parsed, assets)
+	return &mapping, nil
+}
+
+// ApplyToRisk derives the impact, probability and CVSS score of a risk from
+// its vector, normalising the vector. The effective criticality of the
+// risk's assets must be applied. The policy is the one of the tenant of the
+// risk's register entry, else of tenantID. The risk is not saved.
+func (s *CVSSService) ApplyToRisk(risk *domain.Risk, tenantID uuid.UUID) (*domain.CVSSMapping, error) {
+	parsed, err := domain.ParseCVSSVector(risk.CVSSVector)
+	if err != nil {
+		return nil, err
+	}
+	policy := s.GetPolicy(domain.RiskTenant(s.db, risk.ID, tenantID))
+	mapping := policy.Map(parsed, risk.Assets)
+
+	risk.CVSSVector = parsed.String()
+	risk.CVSSScore = mapping.Environmental
+	risk.Impact = mapping.Impact
+	risk.Probability = mapping.Probability
+	return &mapping, nil
+}
+
+// RederiveImpacts follows the changes of asset criticality and mapping
+// policies: the impact and CVSS score of risks carrying a vector are derived
+// again. Their probability is left alone, as enrichment may have moved it.
+func (s *CVSSService) RederiveImpacts(ctx context.Context) (int, error) {
+	db := s.db.WithContext(domain.WithActor(ctx, domain.SystemActor))
+	var riskIDs []uuid.UUID
+	if err := db.Model(&domain.Risk{}).Where("cvss_vector <> ''").Pluck("id", &riskIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list risks with a CVSS vector: %w", err)
+	}
+
+	impact := NewImpactService(db)
+	cache := map[uuid.UUID]domain.AssetCriticality{}
+	policies := map[uuid.UUID]domain.CVSSMappingPolicy{}
+	rederived := 0
+	for _, id := range riskIDs {
+		if err := ctx.Err(); err != nil {
+			return rederived, err
+		}
+		var risk domain.Risk
+		if err := db.Preload("Assets").Take(&risk, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return rederived, fmt.Errorf("failed to load risk: %w", err)
+		}
+		parsed, err := domain.ParseCVSSVector(risk.CVSSVector)
+		if err != nil {
+			continue
+		}
+		if err := impact.applyEffectiveCriticality(risk.Assets, cache); err != nil {
+			return rederived, err
+		}
+		tenantID := domain.RiskTenant(s.db, risk.ID, uuid.Nil)
+		policy, ok := policies[tenantID]
+		if !ok {
+			policy = s.GetPolicy(tenantID)
+			policies[tenantID] = policy
+		}
+		mapping := policy.Map(parsed, risk.Assets)
+		if mapping.Impact == risk.Impact && mapping.Environmental == risk.CVSSScore {
+			continue
+		}
+		if err := db.Model(&risk).Updates(map[string]interface{}{
+			"impact":     mapping.Impact,
+			"cvss_score": mapping.Environmental,
+			"score":      ComputeRiskScore(mapping.Impact, risk.Probability, risk.Assets),
+		}).Error; err != nil {
+			return rederived, fmt.Errorf("failed to update risk %s: %w", id, err)
+		}
+		rederived++
+	}
+	return rederived, nil
+}
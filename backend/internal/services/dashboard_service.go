@@ -0,0 +1,516 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
+)
+
+// Dashboard limits
+const (
+	maxDashboardWidgets = 50
+	maxWidgetListLimit  = 50
+	gridColumns         = 12
+)
+
+// DashboardInput is the editable part of a dashboard; Widgets replaces the
+// whole widget list
+type DashboardInput struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Filter      string                `json:"filter"`
+	Visibility  domain.ViewVisibility `json:"visibility"`
+	TeamID      *uuid.UUID            `json:"team_id"`
+	IsDefault   bool                  `json:"is_default"`
+	Widgets     []DashboardWidgetSpec `json:"widgets"`
+}
+
+// DashboardWidgetSpec describes a widget, as saved and as exported
+type DashboardWidgetSpec struct {
+	Type        domain.WidgetType `json:"type"`
+	Title       string            `json:"title,omitempty"`
+	Filter      string            `json:"filter,omitempty"`
+	Options     json.RawMessage   `json:"options,omitempty"`
+	X           int               `json:"x"`
+	Y           int               `json:"y"`
+	Width       int               `json:"width,omitempty"`
+	Height      int               `json:"height,omitempty"`
+	RefreshRate int               `json:"refresh_rate,omitempty"`
+}
+
+// widgetOptions are the options widgets understand; each type reads its own
+type widgetOptions struct {
+	Limit int `json:"limit"` // top_risks, sla_breaches
+	Days  int `json:"days"`  // trend
+}
+
+func orderWidgets(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func widgetSpec(w domain.DashboardWidget) DashboardWidgetSpec {
+	return DashboardWidgetSpec{
+		Type:        w.Type,
+		Title:       w.Title,
+		Filter:      w.Filter,
+		Options:     json.RawMessage(w.Options),
+		X:           w.X,
+		Y:           w.Y,
+		Width:       w.Width,
+		Height:      w.Height,
+		RefreshRate: w.RefreshRate,
+	}
+}
+
+// ListDashboards lists the dashboards visible to the actor, without widgets
+func (s *ViewService) ListDashboards(actor ViewActor) ([]domain.Dashboard, error) {
+	var dashboards []domain.Dashboard
+	if err := s.db.Scopes(s.visibleTo(actor)).
+		Order(clauseOwnFirst(actor.UserID)).
+		Order("name ASC").
+		Find(&dashboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to list dashboards: %w", err)
+	}
+	return dashboards, nil
+}
+
+// GetDashboard returns a dashboard visible to the actor with its widgets
+func (s *ViewService) GetDashboard(actor ViewActor, dashboardID uuid.UUID) (*domain.Dashboard, error) {
+	var dashboard domain.Dashboard
+	if err := s.db.Scopes(s.visibleTo(actor)).Preload("Widgets", orderWidgets).
+		First(&dashboard, "id = ?", dashboardID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDashboardNotFound
+		}
+		return nil, fmt.Errorf("failed to load dashboard: %w", err)
+	}
+	return &dashboard, nil
+}
+
+// DefaultDashboard returns the actor's home dashboard
+func (s *ViewService) DefaultDashboard(actor ViewActor) (*domain.Dashboard, error) {
+	var dashboard domain.Dashboard
+	if err := s.db.Preload("Widgets", orderWidgets).
+		Where("tenant_id = ? AND owner_id = ? AND is_default = ?", actor.TenantID, actor.UserID, true).
+		First(&dashboard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDashboardNotFound
+		}
+		return nil, fmt.Errorf("failed to load dashboard: %w", err)
+	}
+	return &dashboard, nil
+}
+
+// CreateDashboard saves a new dashboard owned by the actor
+func (s *ViewService) CreateDashboard(actor ViewActor, input DashboardInput) (*domain.Dashboard, error) {
+	dashboard := &domain.Dashboard{
+		ID:       uuid.New(),
+		TenantID: actor.TenantID,
+		OwnerID:  actor.UserID,
+	}
+	widgets, err := s.applyDashboardInput(actor, dashboard, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.clearDefault(tx, dashboard); err != nil {
+			return err
+		}
+		if err := tx.Omit("Widgets").Create(dashboard).Error; err != nil {
+			return err
+		}
+		return saveWidgets(tx, dashboard, widgets)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dashboard: %w", err)
+	}
+	return dashboard, nil
+}
+
+// UpdateDashboard replaces a dashboard's settings and widgets
+func (s *ViewService) UpdateDashboard(actor ViewActor, dashboardID uuid.UUID, input DashboardInput) (*domain.Dashboard, error) {
+	dashboard, err := s.GetDashboard(actor, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditView(actor, dashboard.OwnerID) {
+		return nil, ErrViewForbidden
+	}
+	widgets, err := s.applyDashboardInput(actor, dashboard, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.clearDefault(tx, dashboard); err != nil {
+			return err
+		}
+		if err := tx.Omit("Widgets").Save(dashboard).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("dashboard_id = ?", dashboard.ID).Delete(&domain.DashboardWidget{}).Error; err != nil {
+			return err
+		}
+		return saveWidgets(tx, dashboard, widgets)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update dashboard: %w", err)
+	}
+	return dashboard, nil
+}
+
+// DeleteDashboard deletes a dashboard
+func (s *ViewService) DeleteDashboard(actor ViewActor, dashboardID uuid.UUID) error {
+	dashboard, err := s.GetDashboard(actor, dashboardID)
+	if err != nil {
+		return err
+	}
+	if !canEditView(actor, dashboard.OwnerID) {
+		return ErrViewForbidden
+	}
+	return s.db.Delete(dashboard).Error
+}
+
+// clearDefault keeps a single home dashboard per user
+func (s *ViewService) clearDefault(tx *gorm.DB, dashboard *domain.Dashboard) error {
+	if !dashboard.IsDefault {
+		return nil
+	}
+	return tx.Model(&domain.Dashboard{}).
+		Where("tenant_id = ? AND owner_id = ? AND id <> ? AND is_default = ?", dashboard.TenantID, dashboard.OwnerID, dashboard.ID, true).
+		Update("is_default", false).Error
+}
+
+func saveWidgets(tx *gorm.DB, dashboard *domain.Dashboard, widgets []domain.DashboardWidget) error {
+	for i := range widgets {
+		widgets[i].DashboardID = dashboard.ID
+	}
+	if len(widgets) > 0 {
+		if err := tx.Create(&widgets).Error; err != nil {
+			return err
+		}
+	}
+	dashboard.Widgets = widgets
+	return nil
+}
+
+// applyDashboardInput validates input, copies the settings onto dashboard and
+// returns the widgets to store
+func (s *ViewService) applyDashboardInput(actor ViewActor, dashboard *domain.Dashboard, input DashboardInput) ([]domain.DashboardWidget, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return nil, fmt.Errorf("%w: name is required (at most 255 characters)", ErrInvalidView)
+	}
+	if _, err := s.filters.Compile(input.Filter, actor.filterContext()); err != nil {
+		return nil, err
+	}
+	if err := s.checkVisibility(actor, input.Visibility, input.TeamID); err != nil {
+		return nil, err
+	}
+	if len(input.Widgets) > maxDashboardWidgets {
+		return nil, fmt.Errorf("%w: a dashboard holds at most %d widgets", ErrInvalidView, maxDashboardWidgets)
+	}
+
+	widgets := make([]domain.DashboardWidget, 0, len(input.Widgets))
+	for i, spec := range input.Widgets {
+		widget, err := s.buildWidget(actor, spec)
+		if err != nil {
+			return nil, fmt.Errorf("widget %d: %w", i+1, err)
+		}
+		widget.Position = i
+		widgets = append(widgets, widget)
+	}
+
+	dashboard.Name = name
+	dashboard.Description = input.Description
+	dashboard.Filter = strings.TrimSpace(input.Filter)
+	dashboard.Visibility = visibilityOrDefault(input.Visibility)
+	dashboard.TeamID = nil
+	if dashboard.Visibility == domain.VisibilityTeam {
+		dashboard.TeamID = input.TeamID
+	}
+	dashboard.IsDefault = input.IsDefault
+	return widgets, nil
+}
+
+func (s *ViewService) buildWidget(actor ViewActor, spec DashboardWidgetSpec) (domain.DashboardWidget, error) {
+	valid := false
+	for _, t := range domain.ValidWidgetTypes {
+		if spec.Type == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return domain.DashboardWidget{}, fmt.Errorf("%w: unknown widget type %q", ErrInvalidView, spec.Type)
+	}
+
+	if _, err := s.filters.Compile(spec.Filter, actor.filterContext()); err != nil {
+		return domain.DashboardWidget{}, err
+	}
+
+	var options datatypes.JSON
+	if len(spec.Options) > 0 && string(spec.Options) != "null" {
+		var opts widgetOptions
+		if err := json.Unmarshal(spec.Options, &opts); err != nil {
+			return domain.DashboardWidget{}, fmt.Errorf("%w: options must be a JSON object: %v", ErrInvalidView, err)
+		}
+		options = datatypes.JSON(spec.Options)
+	}
+
+	width, height := spec.Width, spec.Height
+	if width <= 0 {
+		width = 4
+	}
+	if height <= 0 {
+		height = 3
+	}
+	if spec.X < 0 || spec.Y < 0 || width > gridColumns || spec.X+width > gridColumns {
+		return domain.DashboardWidget{}, fmt.Errorf("%w: widget does not fit the %d column grid", ErrInvalidView, gridColumns)
+	}
+	if spec.RefreshRate < 0 {
+		return domain.DashboardWidget{}, fmt.Errorf("%w: refresh_rate must not be negative", ErrInvalidView)
+	}
+
+	title := strings.TrimSpace(spec.Title)
+	if len(title) > 255 {
+		return domain.DashboardWidget{}, fmt.Errorf("%w: widget title is longer than 255 characters", ErrInvalidView)
+	}
+
+	return domain.DashboardWidget{
+		ID:          uuid.New(),
+		Type:        spec.Type,
+		Title:       title,
+		Filter:      strings.TrimSpace(spec.Filter),
+		Options:     options,
+		X:           spec.X,
+		Y:           spec.Y,
+		Width:       width,
+		Height:      height,
+		RefreshRate: spec.RefreshRate,
+	}, nil
+}
+
+// WidgetData is the rendered content of a widget. A widget that fails (e.g.
+// its filter names a custom field that was since removed) carries an error
+// without failing the rest of the dashboard.
+type WidgetData struct {
+	WidgetID uuid.UUID         `json:"widget_id"`
+	Type     domain.WidgetType `json:"type"`
+	Title    string            `json:"title"`
+	Filter   string            `json:"filter"` // Effective filter: dashboard AND widget
+	Data     interface{}       `json:"data,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// RiskMatrixCell is the number of risks with an impact and probability
+type RiskMatrixCell struct {
+	Impact      int   `json:"impact"`
+	Probability int   `json:"probability"`
+	Count       int64 `json:"count"`
+}
+
+// TopRisk is a row of the top risks widget
+type TopRisk struct {
+	ID     uuid.UUID         `json:"id"`
+	Title  string            `json:"title"`
+	Score  float64           `json:"score"`
+	Status domain.RiskStatus `json:"status"`
+	Owner  string            `json:"owner"`
+}
+
+// SLABreach is an overdue review or mitigation
+type SLABreach struct {
+	Kind        string    `json:"kind"` // review, mitigation
+	ID          uuid.UUID `json:"id"`
+	RiskID      uuid.UUID `json:"risk_id"`
+	Title       string    `json:"title"`
+	DueDate     time.Time `json:"due_date"`
+	DaysOverdue int       `json:"days_overdue"`
+}
+
+// FrameworkCoverage is the number of risks classified under a framework
+type FrameworkCoverage struct {
+	Framework    string  `json:"framework"`
+	Risks        int64   `json:"risks"`
+	AverageScore float64 `json:"average_score"`
+}
+
+// DashboardData renders every widget of a dashboard. Filters are evaluated
+// for the viewer, so @me on a shared dashboard means whoever looks at it.
+func (s *ViewService) DashboardData(ctx context.Context, actor ViewActor, dashboardID uuid.UUID) ([]WidgetData, error) {
+	dashboard, err := s.GetDashboard(actor, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]WidgetData, 0, len(dashboard.Widgets))
+	for _, w := range dashboard.Widgets {
+		data := WidgetData{
+			WidgetID: w.ID,
+			Type:     w.Type,
+			Title:    w.Title,
+			Filter:   filter.And(dashboard.Filter, w.Filter),
+		}
+		var err error
+		data.Data, err = s.renderWidget(ctx, actor, w, data.Filter)
+		if err != nil {
+			data.Error = err.Error()
+		}
+		results = append(results, data)
+	}
+	return results, nil
+}
+
+func (s *ViewService) renderWidget(ctx context.Context, actor ViewActor, w domain.DashboardWidget, expression string) (interface{}, error) {
+	riskFilter, err := s.filters.Compile(expression, actor.filterContext())
+	if err != nil {
+		return nil, err
+	}
+
+	var opts widgetOptions
+	if len(w.Options) > 0 {
+		json.Unmarshal(w.Options, &opts)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > maxWidgetListLimit {
+		limit = maxWidgetListLimit
+	}
+
+	risks := func() *gorm.DB {
+		return riskFilter.Scope(s.db.WithContext(ctx).Model(&domain.Risk{}))
+	}
+
+	switch w.Type {
+	case domain.WidgetRiskMatrix:
+		cells := []RiskMatrixCell{}
+		if err := risks().Select("risks.impact AS impact, risks.probability AS probability, COUNT(*) AS count").
+			Group("risks.impact, risks.probability").
+			Order("risks.impact DESC, risks.probability DESC").
+			Scan(&cells).Error; err != nil {
+			return nil, fmt.Errorf("failed to compute risk matrix: %w", err)
+		}
+		var total int64
+		for _, c := range cells {
+			total += c.Count
+		}
+		return map[string]interface{}{"cells": cells, "total": total}, nil
+
+	case domain.WidgetTrend:
+		days := opts.Days
+		if days <= 0 {
+			days = 30
+		}
+		if days > 365 {
+			days = 365
+		}
+		trends, err := s.analytics.GetRiskTrends(ctx, days, riskFilter)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"days": days, "points": trends}, nil
+
+	case domain.WidgetTopRisks:
+		top := []TopRisk{}
+		if err := risks().Select("risks.id, risks.title, risks.score, risks.status, risks.owner").
+			Order("risks.score DESC").Limit(limit).
+			Scan(&top).Error; err != nil {
+			return nil, fmt.Errorf("failed to load top risks: %w", err)
+		}
+		return top, nil
+
+	case domain.WidgetSLABreaches:
+		return s.slaBreaches(ctx, actor, riskFilter, limit)
+
+	case domain.WidgetFrameworkCoverage:
+		coverage := []FrameworkCoverage{}
+		if err := risks().Select("unnest(risks.frameworks) AS framework, COUNT(*) AS risks, AVG(risks.score) AS average_score").
+			Group("framework").
+			Order("risks DESC, framework ASC").
+			Scan(&coverage).Error; err != nil {
+			return nil, fmt.Errorf("failed to compute framework coverage: %w", err)
+		}
+		var unclassified int64
+		risks().Where("risks.frameworks IS NULL OR cardinality(risks.frameworks) = 0").Count(&unclassified)
+		return map[string]interface{}{"frameworks": coverage, "unclassified": unclassified}, nil
+	}
+
+	return nil, fmt.Errorf("unknown widget type %q", w.Type)
+}
+
+// slaBreaches lists overdue review tasks and mitigations of the selected risks,
+// most overdue first
+func (s *ViewService) slaBreaches(ctx context.Context, actor ViewActor, riskFilter *RiskFilter, limit int) (interface{}, error) {
+	now := time.Now()
+	riskIDs := riskFilter.Scope(s.db.WithContext(ctx).Model(&domain.Risk{})).Select("risks.id")
+
+	var reviews []domain.ReviewTask
+	reviewQuery := s.db.WithContext(ctx).Model(&domain.ReviewTask{}).
+		Where("tenant_id = ?", actor.TenantID).
+		Where("status IN ?", []domain.ReviewTaskStatus{domain.ReviewTaskOpen, domain.ReviewTaskOverdue}).
+		Where("due_date < ?", now).
+		Where("risk_id IN (?)", riskIDs)
+	var reviewCount int64
+	if err := reviewQuery.Count(&reviewCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count overdue reviews: %w", err)
+	}
+	if err := reviewQuery.Order("due_date ASC").Limit(limit).Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to load overdue reviews: %w", err)
+	}
+
+	// Mitigations without a due date carry the zero time
+	var mitigations []domain.Mitigation
+	mitigationQuery := s.db.WithContext(ctx).Model(&domain.Mitigation{}).
+		Where("status <> ?", domain.MitigationDone).
+		Where("due_date > ? AND due_date < ?", time.Unix(0, 0), now).
+		Where("risk_id IN (?)", riskIDs)
+	var mitigationCount int64
+	if err := mitigationQuery.Count(&mitigationCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count overdue mitigations: %w", err)
+	}
+	if err := mitigationQuery.Order("due_date ASC").Limit(limit).Find(&mitigations).Error; err != nil {
+		return nil, fmt.Errorf("failed to load overdue mitigations: %w", err)
+	}
+
+	items := make([]SLABreach, 0, len(reviews)+len(mitigations))
+	for _, r := range reviews {
+		items = append(items, SLABreach{Kind: "review", ID: r.ID, RiskID: r.RiskID, Title: r.Title,
+			DueDate: r.DueDate, DaysOverdue: int(now.Sub(r.DueDate).Hours() / 24)})
+	}
+	for _, m := range mitigations {
+		items = append(items, SLABreach{Kind: "mitigation", ID: m.ID, RiskID: m.RiskID, Title: m.Title,
+			DueDate: m.DueDate, DaysOverdue: int(now.Sub(m.DueDate).Hours() / 24)})
+	}
+	sortSLABreaches(items)
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	return map[string]interface{}{
+		"overdue_reviews":     reviewCount,
+		"overdue_mitigations": mitigationCount,
+		"items":               items,
+	}, nil
+}
+
+func sortSLABreaches(items []SLABreach) {
+	for i := 1; i < len(items); i++ {
+		for j := i; j > 0 && items[j].DueDate.Before(items[j-1].DueDate); j-- {
+			items[j], items[j-1] = items[j-1], items[j]
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// Depth limits of impact traversals, in relationship hops from the origin
+const (
+	DefaultImpactDepth = 10
+	MaxImpactDepth     = 25
+)
+
+// ErrRiskNotFound is returned for unknown or deleted risks
+var ErrRiskNotFound = errors.New("risk not found")
+
+// ImpactService propagates impact through the asset dependency graph. When an
+// asset is hit, the impact reaches:
+//   - the assets that depend on it (source of a depends_on to it)
+//   - the assets it hosts and those whose data it processes
+//   - its children in the asset hierarchy
+//   - the business services composed of any of these assets or of one of
+//     their ancestors
+//
+// connects_to is network reachability, not dependency, and does not
+// propagate. Traversals visit each asset once, so cycles are safe, and stop
+// at a depth limit.
+type ImpactService struct {
+	db *gorm.DB
+}
+
+// NewImpactService creates a new impact service
+func NewImpactService(db *gorm.DB) *ImpactService {
+	return &ImpactService{db: db}
+}
+
+// AffectedAsset is an asset reached by an impact: Depth hops from the origin,
+// through the Via relationship from the asset FromID. Origins have depth 0.
+type AffectedAsset struct {
+	Asset  domain.Asset `json:"asset"`
+	Depth  int          `json:"depth"`
+	Via    string       `json:"via,omitempty"`
+	FromID *uuid.UUID   `json:"from_id,omitempty"`
+}
+
+// AffectedService is a business service reached by an impact through the
+// affected assets AssetIDs, at the depth of the closest of them
+type AffectedService struct {
+	Service  domain.BusinessService `json:"service"`
+	Depth    int                    `json:"depth"`
+	AssetIDs []uuid.UUID            `json:"asset_ids"`
+}
+
+// BlastRadius lists everything affected if the origin assets are hit. Its
+// effective criticality is the highest of the origins and affected services.
+// Truncated tells that the depth limit stopped the traversal.
+type BlastRadius struct {
+	OriginAssetIDs       []uuid.UUID             `json:"origin_asset_ids"`
+	MaxDepth             int                     `json:"max_depth"`
+	Truncated            bool                    `json:"truncated"`
+	EffectiveCriticality domain.AssetCriticality `json:"effective_criticality"`
+	Assets               []AffectedAsset         `json:"assets"`
+	Services             []AffectedService       `json:"services"`
+}
+
+// impactEdge tells that an impact on From reaches To
+type impactEdge struct {
+	FromID uuid.UUID
+	ToID   uuid.UUID
+	Via    string
+}
+
+// impactStep is how the traversal first reached an asset
+type impactStep struct {
+	Depth  int
+	Via    string
+	FromID *uuid.UUID
+}
+
+// traverseImpact walks the impact edges breadth first from the origins, up to
+// maxDepth hops. Each asset is visited once, at its shortest distance.
+// Truncated tells that unvisited assets lay beyond the depth limit.
+func traverseImpact(origins []uuid.UUID, maxDepth int, next func([]uuid.UUID) ([]impactEdge, error)) (map[uuid.UUID]impactStep, bool, error) {
+	visited := make(map[uuid.UUID]impactStep, len(origins))
+	frontier := make([]uuid.UUID, 0, len(origins))
+	for _, id := range origins {
+		if _, ok := visited[id]; !ok && id != uuid.Nil {
+			visited[id] = impactStep{}
+			frontier = append(frontier, id)
+		}
+	}
+
+	for depth := 1; len(frontier) > 0; depth++ {
+		edges, err := next(frontier)
+		if err != nil {
+			return nil, false, err
+		}
+		// Edges are sorted so that an asset reached through several paths of
+		// the same length always reports the same one
+		sort.Slice(edges, func(i, j int) bool {
+			if edges[i].FromID != edges[j].FromID {
+				return edges[i].FromID.String() < edges[j].FromID.String()
+			}
+			return edges[i].Via < edges[j].Via
+		})
+		var reached []uuid.UUID
+		for _, edge := range edges {
+			if _, ok := visited[edge.ToID]; ok {
+				continue
+			}
+			if depth > maxDepth {
+				return visited, true, nil
+			}
+			from := edge.FromID
+			visited[edge.ToID] = impactStep{Depth: depth, Via: edge.Via, FromID: &from}
+			reached = append(reached, edge.ToID)
+		}
+		frontier = reached
+	}
+	return visited, false, nil
+}
+
+// impactEdges loads the edges leaving the frontier towards live assets
+func (s *ImpactService) impactEdges(frontier []uuid.UUID) ([]impactEdge, error) {
+	var edges []impactEdge
+	err := s.db.Raw(`SELECT r.target_id AS from_id, r.source_id AS to_id, r.type AS via
+		FROM asset_relationships r JOIN assets a ON a.id = r.source_id AND a.deleted_at IS NULL
+		WHERE r.type = @dependsOn AND r.target_id IN @frontier
+		UNION ALL
+		SELECT r.source_id, r.target_id, r.type
+		FROM asset_relationships r JOIN assets a ON a.id = r.target_id AND a.deleted_at IS NULL
+		WHERE r.type IN @downstream AND r.source_id IN @frontier
+		UNION ALL
+		SELECT parent_id, id, 'parent_of' FROM assets WHERE parent_id IN @frontier AND deleted_at IS NULL`,
+		map[string]interface{}{
+			"frontier":   frontier,
+			"dependsOn":  domain.RelationDependsOn,
+			"downstream": []domain.AssetRelationType{domain.RelationHosts, domain.RelationProcessesDataOf},
+		}).Scan(&edges).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset dependencies: %w", err)
+	}
+	return edges, nil
+}
+
+// serviceHit is a business service composed of an affected asset or of one
+// of its ancestors
+type serviceHit struct {
+	ServiceID uuid.UUID
+	AssetID   uuid.UUID
+}
+
+// affectedServices returns the business services reached by the affected
+// assets. The walk up the hierarchy dedupes its rows, so cycles end.
+func (s *ImpactService) affectedServices(assetIDs []uuid.UUID) ([]serviceHit, error) {
+	var hits []serviceHit
+	err := s.db.Raw(`WITH RECURSIVE lineage AS (
+			SELECT id AS asset_id, id AS affected_id FROM assets WHERE id IN ?
+			UNION
+			SELECT p.parent_id, l.affected_id FROM assets p JOIN lineage l ON p.id = l.asset_id
+			WHERE p.parent_id IS NOT NULL
+		)
+		SELECT DISTINCT bsa.business_service_id AS service_id, l.affected_id AS asset_id
+		FROM lineage l JOIN business_service_assets bsa ON bsa.asset_id = l.asset_id`, assetIDs).Scan(&hits).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load affected business services: %w", err)
+	}
+	return hits, nil
+}
+
+// AssetBlastRadius lists every asset and business service affected if an
+// asset is hit
+func (s *ImpactService) AssetBlastRadius(assetID uuid.UUID, maxDepth int) (*BlastRadius, error) {
+	var found int64
+	if err := s.db.Model(&domain.Asset{}).Where("id = ?", assetID).Count(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to load asset: %w", err)
+	}
+	if found == 0 {
+		return nil, ErrAssetNotFound
+	}
+	return s.BlastRadius([]uuid.UUID{assetID}, maxDepth)
+}
+
+// RiskBlastRadius lists every asset and business service affected if a risk
+// materializes on its assets
+func (s *ImpactService) RiskBlastRadius(riskID uuid.UUID, maxDepth int) (*BlastRadius, error) {
+	var risk domain.Risk
+	if err := s.db.Preload("Assets").Take(&risk, "id = ?", riskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRiskNotFound
+		}
+		return nil, fmt.Errorf("failed to load risk: %w", err)
+	}
+	origins := make([]uuid.UUID, len(risk.Assets))
+	for i, a := range risk.Assets {
+		origins[i] = a.ID
+	}
+	return s.BlastRadius(origins, maxDepth)
+}
+
+// BlastRadius lists every asset and business service affected if the origin
+// assets are hit, closest first. A depth out of 1..MaxImpactDepth falls back
+// to DefaultImpactDepth.
+func (s *ImpactService) BlastRadius(origins []uuid.UUID, maxDepth int) (*BlastRadius, error) {
+	if maxDepth < 1 || maxDepth > MaxImpactDepth {
+		maxDepth = DefaultImpactDepth
+	}
+	radius := &BlastRadius{
+		OriginAssetIDs: uniqueUUIDs(origins),
+		MaxDepth:       maxDepth,
+		Assets:         []AffectedAsset{},
+		Services:       []AffectedService{},
+	}
+	steps, truncated, err := traverseImpact(origins, maxDepth, s.impactEdges)
+	if err != nil {
+		return nil, err
+	}
+	radius.Truncated = truncated
+	if len(steps) == 0 {
+		return radius, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(steps))
+	for id := range steps {
+		ids = append(ids, id)
+	}
+	var assets []domain.Asset
+	if err := s.db.Where("id IN ?", ids).Find(&assets).Error; err != nil {
+		return nil, fmt.Errorf("failed to load affected assets: %w", err)
+	}
+	for _, a := range assets {
+		step := steps[a.ID]
+		radius.Assets = append(radius.Assets, AffectedAsset{Asset: a, Depth: step.Depth, Via: step.Via, FromID: step.FromID})
+		if step.Depth == 0 {
+			radius.EffectiveCriticality = maxCriticality(radius.EffectiveCriticality, a.Criticality)
+		}
+	}
+	sort.Slice(radius.Assets, func(i, j int) bool {
+		if radius.Assets[i].Depth != radius.Assets[j].Depth {
+			return radius.Assets[i].Depth < radius.Assets[j].Depth
+		}
+		return radius.Assets[i].Asset.Name < radius.Assets[j].Asset.Name
+	})
+
+	hits, err := s.affectedServices(ids)
+	if err != nil {
+		return nil, err
+	}
+	byService := map[uuid.UUID]*AffectedService{}
+	serviceIDs := []uuid.UUID{}
+	for _, hit := range hits {
+		affected, ok := byService[hit.ServiceID]
+		if !ok {
+			affected = &AffectedService{Depth: steps[hit.AssetID].Depth}
+			byService[hit.ServiceID] = affected
+			serviceIDs = append(serviceIDs, hit.ServiceID)
+		}
+		if depth := steps[hit.AssetID].Depth; depth < affected.Depth {
+			affected.Depth = depth
+		}
+		affected.AssetIDs = append(affected.AssetIDs, hit.AssetID)
+	}
+	if len(serviceIDs) > 0 {
+		var services []domain.BusinessService
+		if err := s.db.Where("id IN ?", serviceIDs).Find(&services).Error; err != nil {
+			return nil, fmt.Errorf("failed to load affected business services: %w", err)
+		}
+		for _, svc := range services {
+			affected := byService[svc.ID]
+			affected.Service = svc
+			sort.Slice(affected.AssetIDs, func(i, j int) bool { return affected.AssetIDs[i].String() < affected.AssetIDs[j].String() })
+			radius.Services = append(radius.Services, *affected)
+			radius.EffectiveCriticality = maxCriticality(radius.EffectiveCriticality, svc.Criticality)
+		}
+		sort.Slice(radius.Services, func(i, j int) bool {
+			if radius.Services[i].Depth != radius.Services[j].Depth {
+				return radius.Services[i].Depth < radius.Services[j].Depth
+			}
+			return radius.Services[i].Service.Name < radius.Services[j].Service.Name
+		})
+	}
+	return radius, nil
+}
+
+// ApplyEffectiveCriticality sets the effective criticality of assets: the
+// highest of their own and of the business services that transitively depend
+// on them. Unsaved assets keep their own.
+func (s *ImpactService) ApplyEffectiveCriticality(assets []*domain.Asset) error {
+	return s.applyEffectiveCriticality(assets, map[uuid.UUID]domain.AssetCriticality{})
+}
+
+// applyEffectiveCriticality is ApplyEffectiveCriticality with a cache of the
+// criticalities already computed
+func (s *ImpactService) applyEffectiveCriticality(assets []*domain.Asset, cache map[uuid.UUID]domain.AssetCriticality) error {
+	for _, a := range assets {
+		if a == nil || a.ID == uuid.Nil {
+			continue
+		}
+		effective, ok := cache[a.ID]
+		if !ok {
+			radius, err := s.BlastRadius([]uuid.UUID{a.ID}, DefaultImpactDepth)
+			if err != nil {
+				return err
+			}
+			effective = maxCriticality(a.Criticality, radius.EffectiveCriticality)
+			cache[a.ID] = effective
+		}
+		a.EffectiveCriticality = effective
+	}
+	return nil
+}
+
+// RescoreRisks recomputes the score of the risks linked to assets, whose
+// effective criticality follows changes of relationships and business
+// services. Changed scores are saved, so they are versioned. It returns how
+// many risks were rescored.
+func (s *ImpactService) RescoreRisks(ctx context.Context) (int, error) {
+	db := s.db.WithContext(domain.WithActor(ctx, domain.SystemActor))
+	var riskIDs []uuid.UUID
+	if err := db.Model(&domain.Risk{}).Distinct("risks.id").
+		Joins("JOIN risk_assets ON risk_assets.risk_id = risks.id").
+		Pluck("risks.id", &riskIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list risks with assets: %w", err)
+	}
+
+	cache := map[uuid.UUID]domain.AssetCriticality{}
+	rescored := 0
+	for _, id := range riskIDs {
+		if err := ctx.Err(); err != nil {
+			return rescored, err
+		}
+		var risk domain.Risk
+		if err := db.Preload("Assets").Take(&risk, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return rescored, fmt.Errorf("failed to load risk: %w", err)
+		}
+		if err := s.applyEffectiveCriticality(risk.Assets, cache); err != nil {
+			return rescored, err
+		}
+		score := ComputeRiskScore(risk.Impact, risk.Probability, risk.Assets)
+		if score == risk.Score {
+			continue
+		}
+		if err := db.Model(&risk).Update("score", score).Error; err != nil {
+			return rescored, fmt.Errorf("failed to rescore risk %s: %w", id, err)
+		}
+		rescored++
+	}
+	return rescored, nil
+}
+
+// maxCriticality returns the more critical of two criticalities; unknown or
+// empty ones rank lowest
+func maxCriticality(a, b domain.AssetCriticality) domain.AssetCriticality {
+	if criticalityFactor[b] > criticalityFactor[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// impactGraph serves the edges of an in-memory graph to traverseImpact
+type impactGraph []impactEdge
+
+func (g impactGraph) next(frontier []uuid.UUID) ([]impactEdge, error) {
+	var edges []impactEdge
+	for _, id := range frontier {
+		for _, edge := range g {
+			if edge.FromID == id {
+				edges = append(edges, edge)
+			}
+		}
+	}
+	return edges, nil
+}
+
+func TestTraverseImpact(t *testing.T) {
+	db, app, web, vm := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	graph := impactGraph{
+		{FromID: db, ToID: app, Via: "depends_on"},
+		{FromID: app, ToID: web, Via: "depends_on"},
+		{FromID: db, ToID: web, Via: "depends_on"},
+		// A cycle back to the origin is not followed again
+		{FromID: web, ToID: db, Via: "depends_on"},
+		{FromID: web, ToID: vm, Via: "hosts"},
+	}
+
+	steps, truncated, err := traverseImpact([]uuid.UUID{db}, DefaultImpactDepth, graph.next)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, steps, 4)
+	assert.Equal(t, 0, steps[db].Depth)
+	assert.Nil(t, steps[db].FromID)
+	assert.Equal(t, 1, steps[app].Depth)
+	// The shortest path wins
+	assert.Equal(t, 1, steps[web].Depth)
+	assert.Equal(t, db, *steps[web].FromID)
+	assert.Equal(t, 2, steps[vm].Depth)
+	assert.Equal(t, "hosts", steps[vm].Via)
+
+	steps, truncated, err = traverseImpact([]uuid.UUID{db}, 1, graph.next)
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Len(t, steps, 3)
+	assert.NotContains(t, steps, vm)
+
+	steps, truncated, err = traverseImpact([]uuid.UUID{vm}, 1, graph.next)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	assert.Len(t, steps, 1)
+}
+
+func TestMaxCriticality(t *testing.T) {
+	assert.Equal(t, domain.CriticalityHigh, maxCriticality(domain.CriticalityLow, domain.CriticalityHigh))
+	assert.Equal(t, domain.CriticalityCritical, maxCriticality(domain.CriticalityCritical, domain.CriticalityMedium))
+	assert.Equal(t, domain.CriticalityLow, maxCriticality(domain.CriticalityLow, ""))
+	assert.Equal(t, domain.CriticalityMedium, maxCriticality("", domain.CriticalityMedium))
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// Import field value types
+const (
+	importText    = "text"
+	importInt     = "integer"
+	importNumber  = "number"
+	importList    = "list"
+	importDate    = "date"
+	importBoolean = "boolean"
+	importChoice  = "choice"
+)
+
+// ImportField is a record field a spreadsheet column can be mapped to
+type ImportField struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`          // Needed to create a record
+	Choices  []string `json:"choices,omitempty"` // Allowed values of choice fields
+
+	aliases []string
+	min     int
+	max     int
+	custom  *domain.CustomField
+}
+
+var riskImportFields = []ImportField{
+	{Name: "external_id", Label: "External ID", Type: importText, aliases: []string{"id", "riskid", "ref", "reference", "riskref"}},
+	{Name: "title", Label: "Title", Type: importText, Required: true, aliases: []string{"risk", "name", "riskname", "risktitle", "summary"}},
+	{Name: "description", Label: "Description", Type: importText, aliases: []string{"details", "riskdescription"}},
+	{Name: "impact", Label: "Impact", Type: importInt, Required: true, min: 1, max: 5, aliases: []string{"impactscore", "consequence", "severity"}},
+	{Name: "probability", Label: "Probability", Type: importInt, Required: true, min: 1, max: 5, aliases: []string{"likelihood", "probabilityscore"}},
+	{Name: "status", Label: "Status", Type: importChoice, Choices: []string{string(domain.StatusDraft), string(domain.StatusActive), string(domain.StatusMitigated), string(domain.StatusAccepted)}, aliases: []string{"riskstatus", "state"}},
+	{Name: "owner", Label: "Owner", Type: importText, aliases: []string{"riskowner", "accountable"}},
+	{Name: "tags", Label: "Tags", Type: importList, aliases: []string{"tag", "labels", "categories", "category"}},
+	{Name: "frameworks", Label: "Frameworks", Type: importList, aliases: []string{"framework", "standards", "compliance"}},
+	{Name: "assets", Label: "Assets", Type: importList, aliases: []string{"asset", "affectedassets", "systems"}},
+}
+
+var assetImportFields = []ImportField{
+	{Name: "external_id", Label: "External ID", Type: importText, aliases: []string{"id", "assetid", "ref", "reference", "assettag"}},
+	{Name: "name", Label: "Name", Type: importText, Required: true, aliases: []string{"asset", "assetname", "hostname", "system"}},
+	{Name: "type", Label: "Type", Type: importText, aliases: []string{"assettype", "kind", "category"}},
+	{Name: "criticality", Label: "Criticality", Type: importChoice, Choices: []string{string(domain.CriticalityLow), string(domain.CriticalityMedium), string(domain.CriticalityHigh), string(domain.CriticalityCritical)}, aliases: []string{"businesscriticality", "assetcriticality"}},
+	{Name: "owner", Label: "Owner", Type: importText, aliases: []string{"assetowner", "custodian"}},
+}
+
+var mitigationImportFields = []ImportField{
+	{Name: "risk", Label: "Risk (external ID or title)", Type: importText, Required: true, aliases: []string{"riskid", "riskref", "riskreference", "risktitle", "riskexternalid"}},
+	{Name: "title", Label: "Title", Type: importText, Required: true, aliases: []string{"mitigation", "mitigationtitle", "action", "control", "treatment"}},
+	{Name: "assignee", Label: "Assignee", Type: importText, aliases: []string{"owner", "assignedto", "responsible"}},
+	{Name: "status", Label: "Status", Type: importChoice, Choices: []string{string(domain.MitigationPlanned), string(domain.MitigationInProgress), string(domain.MitigationDone)}, aliases: []string{"mitigationstatus", "state"}},
+	{Name: "progress", Label: "Progress (%)", Type: importInt, min: 0, max: 100, aliases: []string{"percentcomplete", "complete", "completion"}},
+	{Name: "due_date", Label: "Due date", Type: importDate, aliases: []string{"due", "deadline", "targetdate"}},
+	{Name: "cost", Label: "Cost (1-3)", Type: importInt, min: 1, max: 3, aliases: []string{"costcategory"}},
+	{Name: "mitigation_time", Label: "Effort (days)", Type: importInt, min: 0, max: 3650, aliases: []string{"effort", "effortdays", "days", "duration"}},
+}
+
+// ImportFields lists the fields of an entity a column can be mapped to.
+// Risk custom field definitions are offered as "custom.<name>".
+func ImportFields(entity domain.ImportEntity, customFields []*domain.CustomField) ([]ImportField, error) {
+	var base []ImportField
+	switch entity {
+	case domain.ImportEntityRisk:
+		base = riskImportFields
+	case domain.ImportEntityAsset:
+		base = assetImportFields
+	case domain.ImportEntityMitigation:
+		base = mitigationImportFields
+	default:
+		return nil, fmt.Errorf("invalid import entity: %s", entity)
+	}
+
+	fields := append([]ImportField(nil), base...)
+	if entity != domain.ImportEntityRisk {
+		return fields, nil
+	}
+
+	for _, cf := range customFields {
+		var validation domain.CustomFieldValidation
+		if len(cf.Validation) > 0 {
+			json.Unmarshal(cf.Validation, &validation)
+		}
+
+		field := ImportField{
+			Name:     "custom." + cf.Name,
+			Label:    cf.DisplayName,
+			Required: validation.Required,
+			aliases:  []string{cf.Name},
+			custom:   cf,
+		}
+		if field.Label == "" {
+			field.Label = cf.Name
+		}
+		switch cf.FieldType {
+		case domain.CustomFieldTypeNumber:
+			field.Type = importNumber
+		case domain.CustomFieldTypeDate:
+			field.Type = importDate
+		case domain.CustomFieldTypeCheckbox:
+			field.Type = importBoolean
+		case domain.CustomFieldTypeChoice:
+			field.Type = importChoice
+			field.Choices = validation.AllowedValues
+		default:
+			field.Type = importText
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// normalizeImportHeader reduces a header to lowercase letters and digits so
+// "Due Date", "due_date" and "DUE-DATE" compare equal
+func normalizeImportHeader(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// SuggestImportMapping maps each header to the field whose name or label it
+// matches, then to fields it is a known alias of. Unmatched headers map to "".
+func SuggestImportMapping(headers []string, fields []ImportField) map[string]string {
+	mapping := make(map[string]string, len(headers))
+	used := map[string]bool{}
+
+	match := func(candidates func(ImportField) []string) {
+		for _, h := range headers {
+			if mapping[h] != "" {
+				continue
+			}
+			norm := normalizeImportHeader(h)
+			for _, f := range fields {
+				if used[f.Name] {
+					continue
+				}
+				for _, c := range candidates(f) {
+					if norm != "" && norm == normalizeImportHeader(c) {
+						mapping[h] = f.Name
+						used[f.Name] = true
+						break
+					}
+				}
+				if mapping[h] != "" {
+					break
+				}
+			}
+		}
+	}
+
+	match(func(f ImportField) []string { return []string{f.Name, f.Label} })
+	match(func(f ImportField) []string { return f.aliases })
+
+	for _, h := range headers {
+		if _, ok := mapping[h]; !ok {
+			mapping[h] = ""
+		}
+	}
+	return mapping
+}
+
+// importColumn is a spreadsheet column mapped to a field
+type importColumn struct {
+	index int
+	field ImportField
+}
+
+// resolveImportMapping checks a header -> field mapping and returns the mapped
+// columns. Every header must exist, a field may be mapped once, and the fields
+// identifying a record must be mapped.
+func resolveImportMapping(entity domain.ImportEntity, headers []string, fields []ImportField, mapping map[string]string) ([]importColumn, error) {
+	byName := make(map[string]ImportField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	headerIndex := make(map[string]int, len(headers))
+	for i, h := range headers {
+		headerIndex[h] = i
+	}
+
+	var columns []importColumn
+	mapped := map[string]string{}
+	for header, target := range mapping {
+		idx, ok := headerIndex[header]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", header)
+		}
+		if target == "" {
+			continue
+		}
+		field, ok := byName[target]
+		if !ok {
+			return nil, fmt.Errorf("column %q is mapped to unknown field %q", header, target)
+		}
+		if other, dup := mapped[target]; dup {
+			return nil, fmt.Errorf("columns %q and %q are both mapped to %q", other, header, target)
+		}
+		mapped[target] = header
+		columns = append(columns, importColumn{index: idx, field: field})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].index < columns[j].index })
+
+	switch entity {
+	case domain.ImportEntityRisk:
+		if mapped["title"] == "" && mapped["external_id"] == "" {
+			return nil, fmt.Errorf("map a column to title or external_id")
+		}
+	case domain.ImportEntityAsset:
+		if mapped["name"] == "" && mapped["external_id"] == "" {
+			return nil, fmt.Errorf("map a column to name or external_id")
+		}
+	case domain.ImportEntityMitigation:
+		if mapped["risk"] == "" || mapped["title"] == "" {
+			return nil, fmt.Errorf("map a column to risk and one to title")
+		}
+	}
+
+	return columns, nil
+}
+
+// importValidator validates custom field values (CustomFieldService.ValidateFieldValue)
+type importValidator func(field *domain.CustomField, value interface{}) error
+
+// parseImportRow converts the mapped, non-blank cells of a row to typed values
+// keyed by field name. Every invalid cell is reported, not only the first one.
+func parseImportRow(row importRow, columns []importColumn, validate importValidator) (map[string]interface{}, []string) {
+	values := map[string]interface{}{}
+	var errs []string
+
+	for _, col := range columns {
+		raw := row.Cell(col.index)
+		if raw == "" {
+			continue
+		}
+		value, err := parseImportValue(col.field, raw)
+		if err == nil && col.field.custom != nil && validate != nil {
+			err = validate(col.field.custom, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", col.field.Label, err))
+			continue
+		}
+		values[col.field.Name] = value
+	}
+
+	return values, errs
+}
+
+func parseImportValue(field ImportField, raw string) (interface{}, error) {
+	switch field.Type {
+	case importInt:
+		s := strings.TrimSuffix(strings.TrimSpace(raw), "%")
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		// Excel stores percentages as fractions
+		if field.Name == "progress" && !strings.HasSuffix(raw, "%") && f > 0 && f < 1 {
+			f *= 100
+		}
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("%q is not a whole number", raw)
+		}
+		n := int(f)
+		if n < field.min || n > field.max {
+			return nil, fmt.Errorf("%d is outside %d-%d", n, field.min, field.max)
+		}
+		return n, nil
+
+	case importNumber:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return f, nil
+
+	case importList:
+		return splitImportList(raw), nil
+
+	case importDate:
+		t, err := parseImportDate(raw)
+		if err != nil {
+			return nil, err
+		}
+		if field.custom != nil {
+			return t.Format("2006-01-02"), nil
+		}
+		return t, nil
+
+	case importBoolean:
+		switch strings.ToLower(raw) {
+		case "true", "yes", "y", "x", "1", "oui":
+			return true, nil
+		case "false", "no", "n", "0", "non":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not yes/no", raw)
+
+	case importChoice:
+		// Custom choices are matched exactly by ValidateFieldValue
+		if field.custom != nil {
+			return raw, nil
+		}
+		norm := strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_").Replace(raw))
+		for _, c := range field.Choices {
+			if c == norm {
+				return c, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %s", raw, strings.Join(field.Choices, ", "))
+
+	default:
+		if field.Name == "title" || field.Name == "name" {
+			if len(raw) > 255 {
+				return nil, fmt.Errorf("must be at most 255 characters")
+			}
+		}
+		return raw, nil
+	}
+}
+
+// splitImportList splits a multi-value cell on semicolons, commas, pipes or
+// line breaks, dropping blanks and duplicates
+func splitImportList(raw string) []string {
+	parts := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ';' || r == ',' || r == '|' || r == '\n'
+	})
+	var out []string
+	seen := map[string]bool{}
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[strings.ToLower(p)] {
+			continue
+		}
+		seen[strings.ToLower(p)] = true
+		out = append(out, p)
+	}
+	return out
+}
+
+// excelEpoch is day zero of spreadsheet date serial numbers
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// parseImportDate accepts ISO dates and timestamps, day-first slashed dates
+// and the serial numbers XLSX files store dates as
+func parseImportDate(raw string) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+		if serial < 1 || serial > 2958465 {
+			return time.Time{}, fmt.Errorf("%q is not a date", raw)
+		}
+		return excelEpoch.AddDate(0, 0, int(serial)), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01-02 15:04:05", "2006/01/02", "02/01/2006", "02.01.2006"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (use YYYY-MM-DD)", raw)
+}
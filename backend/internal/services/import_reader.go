@@ -0,0 +1,393 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Supported import file formats
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatXLSX = "xlsx"
+)
+
+// maxImportRows bounds the size of a single import batch
+const maxImportRows = 50000
+
+// importTable is the content of an uploaded spreadsheet: the header row and
+// the non-blank data rows below it
+type importTable struct {
+	Headers []string
+	Rows    []importRow
+}
+
+// importRow is a data row; Line is its spreadsheet line number
+type importRow struct {
+	Line  int
+	Cells []string
+}
+
+// Cell returns the trimmed value of column i, or "" past the end of the row
+func (r importRow) Cell(i int) string {
+	if i < 0 || i >= len(r.Cells) {
+		return ""
+	}
+	return strings.TrimSpace(r.Cells[i])
+}
+
+// ImportFormatFromFileName detects the import format from the file extension
+func ImportFormatFromFileName(name string) (string, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv", ".txt":
+		return ImportFormatCSV, nil
+	case ".xlsx":
+		return ImportFormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported import file %q: upload a .csv or .xlsx file", name)
+	}
+}
+
+// readImportTable parses a CSV or XLSX file. The first non-blank row is the header.
+func readImportTable(format string, r io.ReaderAt, size int64) (*importTable, error) {
+	var (
+		lines [][]string
+		nums  []int
+		err   error
+	)
+	switch format {
+	case ImportFormatCSV:
+		lines, nums, err = readCSVLines(io.NewSectionReader(r, 0, size))
+	case ImportFormatXLSX:
+		lines, nums, err = readXLSXLines(r, size)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	table := &importTable{}
+	for i, cells := range lines {
+		if isBlankRow(cells) {
+			continue
+		}
+		if table.Headers == nil {
+			table.Headers = uniqueHeaders(cells)
+			continue
+		}
+		if len(table.Rows) == maxImportRows {
+			return nil, fmt.Errorf("file has more than %d rows; split it into several imports", maxImportRows)
+		}
+		table.Rows = append(table.Rows, importRow{Line: nums[i], Cells: cells})
+	}
+	if table.Headers == nil {
+		return nil, errors.New("file is empty: the first row must contain column headers")
+	}
+
+	return table, nil
+}
+
+func isBlankRow(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueHeaders names blank header cells after their column and suffixes repeated ones
+func uniqueHeaders(cells []string) []string {
+	headers := make([]string, len(cells))
+	seen := map[string]int{}
+	for i, c := range cells {
+		h := strings.TrimSpace(c)
+		if h == "" {
+			h = "Column " + xlsxColumnName(i)
+		}
+		seen[strings.ToLower(h)]++
+		if n := seen[strings.ToLower(h)]; n > 1 {
+			h = fmt.Sprintf("%s (%d)", h, n)
+		}
+		headers[i] = h
+	}
+	return headers
+}
+
+// readCSVLines reads every record of a CSV file. Excel exports from European
+// locales separate fields with semicolons; the delimiter is taken from the header line.
+func readCSVLines(r io.Reader) ([][]string, []int, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	if first, err := br.Peek(4096); len(first) > 0 && (err == nil || err == io.EOF || err == bufio.ErrBufferFull) {
+		if i := bytes.IndexByte(first, '\n'); i >= 0 {
+			first = first[:i]
+		}
+		if bytes.Count(first, []byte{';'}) > bytes.Count(first, []byte{','}) {
+			cr.Comma = ';'
+		}
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	var (
+		lines [][]string
+		nums  []int
+	)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		lines = append(lines, record)
+		nums = append(nums, line)
+		if len(lines) > maxImportRows+1 {
+			break
+		}
+	}
+
+	return lines, nums, nil
+}
+
+// readXLSXLines reads the first worksheet of an OOXML workbook
+func readXLSXLines(r io.ReaderAt, size int64) ([][]string, []int, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sharedStrings, err := readXLSXSharedStrings(files["xl/sharedStrings.xml"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sheet := files[xlsxFirstSheetPath(files)]
+	if sheet == nil {
+		return nil, nil, errors.New("invalid XLSX file: workbook has no worksheet")
+	}
+	rc, err := sheet.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	defer rc.Close()
+
+	var (
+		lines [][]string
+		nums  []int
+		row   []string
+		line  int
+		cell  struct {
+			col     int
+			kind    string
+			value   strings.Builder
+			inValue bool
+		}
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid XLSX worksheet: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				line++
+				if n, err := strconv.Atoi(xmlAttr(t, "r")); err == nil {
+					line = n
+				}
+				row = nil
+			case "c":
+				cell.col = len(row)
+				if ref := xmlAttr(t, "r"); ref != "" {
+					cell.col = xlsxColumnIndex(ref)
+				}
+				cell.kind = xmlAttr(t, "t")
+				cell.value.Reset()
+			case "v", "t":
+				cell.inValue = true
+			}
+		case xml.CharData:
+			if cell.inValue {
+				cell.value.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				cell.inValue = false
+			case "c":
+				value := cell.value.String()
+				switch cell.kind {
+				case "s":
+					idx, err := strconv.Atoi(value)
+					if err != nil || idx < 0 || idx >= len(sharedStrings) {
+						return nil, nil, fmt.Errorf("invalid XLSX worksheet: bad shared string reference %q", value)
+					}
+					value = sharedStrings[idx]
+				case "b":
+					value = strings.ToUpper(strconv.FormatBool(value == "1"))
+				}
+				for len(row) < cell.col {
+					row = append(row, "")
+				}
+				row = append(row, value)
+			case "row":
+				lines = append(lines, row)
+				nums = append(nums, line)
+				if len(lines) > maxImportRows+1 {
+					return lines, nums, nil
+				}
+			}
+		}
+	}
+
+	return lines, nums, nil
+}
+
+// readXLSXSharedStrings loads the workbook string table; rich text runs are concatenated
+func readXLSXSharedStrings(f *zip.File) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	defer rc.Close()
+
+	var (
+		items   []string
+		current strings.Builder
+		inText  bool
+		inPhon  bool
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX shared strings: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "t":
+				inText = true
+			case "rPh": // Phonetic hints are not part of the value
+				inPhon = true
+			}
+		case xml.CharData:
+			if inText && !inPhon {
+				current.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "rPh":
+				inPhon = false
+			case "si":
+				items = append(items, current.String())
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// xlsxFirstSheetPath resolves the first sheet of the workbook through its relationships
+func xlsxFirstSheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if decodeZipXML(files["xl/workbook.xml"], &workbook) != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+	if decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels) != nil {
+		return fallback
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return errors.New("missing part")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func xmlAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// xlsxColumnIndex converts the column letters of a cell reference ("AB12") to a 0-based index
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
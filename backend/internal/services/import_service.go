@@ -0,0 +1,1300 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/database"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+)
+
+// JobTypeImportCommit is the job queue type that applies a validated import batch
+const JobTypeImportCommit = "import_commit"
+
+// importPreviewLimit caps the dry-run rows stored on a batch
+const importPreviewLimit = 500
+
+// importSourceTag marks records created by an import
+const importSourceTag = "IMPORT"
+
+var (
+	ErrImportNotFound  = errors.New("import batch not found")
+	ErrImportState     = errors.New("import batch is not in a state that allows this operation")
+	ErrImportHasErrors = errors.New("import has invalid rows: fix them or enable skip_invalid")
+	ErrInvalidImport   = errors.New("invalid import")
+)
+
+// ImportService runs spreadsheet imports: upload, column mapping, dry-run,
+// commit on the job queue and rollback of a whole batch
+type ImportService struct {
+	db           *gorm.DB
+	jobs         *JobQueueService
+	blobs        ports.BlobStore
+	customFields *CustomFieldService
+}
+
+// importCommitJob is the job payload of an import commit
+type importCommitJob struct {
+	BatchID uuid.UUID `json:"batch_id"`
+}
+
+// DryRunOptions confirms the mapping and matching of an import before its dry-run
+type DryRunOptions struct {
+	Mapping     map[string]string      `json:"mapping"` // Header -> field name ("" ignores the column); nil keeps the current mapping
+	MatchBy     domain.ImportMatchMode `json:"match_by"`
+	SkipInvalid bool                   `json:"skip_invalid"`
+}
+
+// NewImportService creates a new import service and registers its commit job
+// handler on the queue. Uploaded files are kept in blobs until the batch is deleted.
+func NewImportService(jobs *JobQueueService, blobs ports.BlobStore) *ImportService {
+	s := &ImportService{
+		db:           database.DB,
+		jobs:         jobs,
+		blobs:        blobs,
+		customFields: NewCustomFieldService(),
+	}
+	jobs.RegisterHandler(JobTypeImportCommit, s.runCommitJob)
+	return s
+}
+
+// Fields lists the fields of an entity that columns can be mapped to
+func (s *ImportService) Fields(entity domain.ImportEntity) ([]ImportField, error) {
+	var customFields []*domain.CustomField
+	if entity == domain.ImportEntityRisk {
+		var err error
+		if customFields, err = s.customFields.GetCustomFieldsByScope(domain.CustomFieldScopeRisk); err != nil {
+			return nil, fmt.Errorf("failed to load custom fields: %w", err)
+		}
+	}
+	return ImportFields(entity, customFields)
+}
+
+// Upload stores a CSV or XLSX file, reads its header row and suggests a column mapping
+func (s *ImportService) Upload(ctx context.Context, tenantID, userID uuid.UUID, entity domain.ImportEntity, fileName string, body io.Reader) (*domain.ImportBatch, error) {
+	if _, err := ImportFields(entity, nil); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	fields, err := s.Fields(entity)
+	if err != nil {
+		return nil, err
+	}
+	format, err := ImportFormatFromFileName(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	tmp, err := os.CreateTemp("", "openrisk-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer upload: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer upload: %w", err)
+	}
+	table, err := readImportTable(format, tmp, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	batch := &domain.ImportBatch{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Entity:    entity,
+		Status:    domain.ImportStatusUploaded,
+		FileName:  fileName,
+		Format:    format,
+		FileSize:  size,
+		Headers:   table.Headers,
+		Mapping:   importJSON(SuggestImportMapping(table.Headers, fields)),
+		MatchBy:   domain.ImportMatchAuto,
+		TotalRows: len(table.Rows),
+		CreatedBy: userID,
+	}
+	batch.FileKey = fmt.Sprintf("imports/%s.%s", batch.ID, format)
+
+	contentType := "text/csv; charset=utf-8"
+	if format == ImportFormatXLSX {
+		contentType, _, _ = exportContentType(ExportFormatXLSX)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind upload: %w", err)
+	}
+	if err := s.blobs.Put(ctx, batch.FileKey, tmp, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store import file: %w", err)
+	}
+
+	if err := s.db.Create(batch).Error; err != nil {
+		s.blobs.Delete(ctx, batch.FileKey)
+		return nil, fmt.Errorf("failed to create import batch: %w", err)
+	}
+
+	return batch, nil
+}
+
+// GetBatch retrieves an import batch of a tenant
+func (s *ImportService) GetBatch(tenantID, batchID uuid.UUID) (*domain.ImportBatch, error) {
+	var batch domain.ImportBatch
+	err := s.db.Where("id = ? AND tenant_id = ?", batchID, tenantID).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load import batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// ListBatches lists the import batches of a tenant, newest first. The dry-run
+// preview is left out; fetch a single batch to read it.
+func (s *ImportService) ListBatches(tenantID uuid.UUID, createdBy *uuid.UUID, limit, offset int) ([]domain.ImportBatch, int64, error) {
+	query := s.db.Model(&domain.ImportBatch{}).Where("tenant_id = ?", tenantID)
+	if createdBy != nil {
+		query = query.Where("created_by = ?", *createdBy)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count import batches: %w", err)
+	}
+
+	var batches []domain.ImportBatch
+	if err := query.Omit("preview").Order("created_at DESC").Limit(limit).Offset(offset).Find(&batches).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list import batches: %w", err)
+	}
+	return batches, total, nil
+}
+
+// DryRun applies the confirmed mapping, validates every row and stores the
+// creates, updates and errors committing the batch would produce. Nothing is written.
+func (s *ImportService) DryRun(ctx context.Context, tenantID, batchID uuid.UUID, opts DryRunOptions) (*domain.ImportBatch, error) {
+	batch, err := s.GetBatch(tenantID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if !batch.CanValidate() {
+		return nil, ErrImportState
+	}
+
+	if opts.Mapping != nil {
+		batch.Mapping = importJSON(opts.Mapping)
+	}
+	switch opts.MatchBy {
+	case "":
+	case domain.ImportMatchAuto, domain.ImportMatchExternalID, domain.ImportMatchTitle, domain.ImportMatchNone:
+		batch.MatchBy = opts.MatchBy
+	default:
+		return nil, fmt.Errorf("%w: unknown match_by %q", ErrInvalidImport, opts.MatchBy)
+	}
+	batch.SkipInvalid = opts.SkipInvalid
+
+	table, err := s.loadTable(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := s.plan(ctx, s.db, batch, table)
+	if err != nil {
+		return nil, err
+	}
+	summarizeImport(batch, ops)
+
+	now := time.Now()
+	batch.Status = domain.ImportStatusValidated
+	batch.ValidatedAt = &now
+	batch.ErrorMessage = ""
+	if err := s.db.Model(batch).Select("mapping", "match_by", "skip_invalid", "status", "validated_at", "error_message",
+		"total_rows", "create_count", "update_count", "unchanged_count", "error_count", "preview").Updates(batch).Error; err != nil {
+		return nil, fmt.Errorf("failed to save dry-run: %w", err)
+	}
+
+	return batch, nil
+}
+
+// Commit queues a validated batch for import. Batches with invalid rows are
+// refused unless the dry-run enabled skip_invalid.
+func (s *ImportService) Commit(tenantID, batchID, userID uuid.UUID) (*domain.ImportBatch, error) {
+	batch, err := s.GetBatch(tenantID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status != domain.ImportStatusValidated {
+		return nil, ErrImportState
+	}
+	if batch.ErrorCount > 0 && !batch.SkipInvalid {
+		return nil, ErrImportHasErrors
+	}
+
+	res := s.db.Model(&domain.ImportBatch{}).
+		Where("id = ? AND status = ?", batch.ID, domain.ImportStatusValidated).
+		Update("status", domain.ImportStatusCommitting)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to start import commit: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrImportState
+	}
+	batch.Status = domain.ImportStatusCommitting
+
+	// One job per validation: re-validating a failed batch allows a new commit
+	job, err := s.jobs.Enqueue(JobTypeImportCommit, importCommitJob{BatchID: batch.ID}, EnqueueOptions{
+		TenantID:       tenantID,
+		CreatedBy:      userID,
+		IdempotencyKey: fmt.Sprintf("import_commit:%s:%d", batch.ID, batch.ValidatedAt.Unix()),
+		MaxAttempts:    3,
+	})
+	if err != nil {
+		s.markFailed(batch.ID, err)
+		return nil, err
+	}
+
+	batch.JobID = &job.ID
+	s.db.Model(batch).Update("job_id", job.ID)
+
+	return batch, nil
+}
+
+// Rollback reverts a committed batch: records it created are deleted and the
+// values it overwrote are restored, in reverse row order, in one transaction.
+// Records changed since the import are left as they are, flagged on their
+// journal entry and counted in the batch's RollbackSkippedCount.
+func (s *ImportService) Rollback(ctx context.Context, tenantID, batchID, userID uuid.UUID) (*domain.ImportBatch, error) {
+	batch, err := s.GetBatch(tenantID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status != domain.ImportStatusCommitted {
+		return nil, ErrImportState
+	}
+
+	var records []domain.ImportRecord
+	if err := s.db.Where("batch_id = ?", batch.ID).Order("row_number DESC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load import journal: %w", err)
+	}
+
+	now := time.Now()
+	var skipped []uuid.UUID
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		skipped = skipped[:0]
+		// Whether each record was skipped, once its latest row is rolled back: earlier
+		// rows of the same record follow that outcome, as restoring a later row changes it
+		outcome := make(map[uuid.UUID]bool)
+		for _, rec := range records {
+			previous, seen := outcome[rec.EntityID]
+			if seen && previous {
+				skipped = append(skipped, rec.ID)
+				continue
+			}
+			changed, err := rollbackImportRecord(tx, rec, !seen)
+			if err != nil {
+				return fmt.Errorf("failed to roll back row %d: %w", rec.RowNumber, err)
+			}
+			outcome[rec.EntityID] = changed
+			if changed {
+				log.Printf("⚠️ Import batch %s: row %d skipped on rollback, %s %s changed since the import", batch.ID, rec.RowNumber, rec.Entity, rec.EntityID)
+				skipped = append(skipped, rec.ID)
+			}
+		}
+		if len(skipped) > 0 {
+			if err := tx.Model(&domain.ImportRecord{}).Where("id IN ?", skipped).Update("rollback_skipped", true).Error; err != nil {
+				return fmt.Errorf("failed to flag skipped records: %w", err)
+			}
+		}
+
+		res := tx.Model(&domain.ImportBatch{}).
+			Where("id = ? AND status = ?", batch.ID, domain.ImportStatusCommitted).
+			Updates(map[string]interface{}{
+				"status":                 domain.ImportStatusRolledBack,
+				"rolled_back_at":         now,
+				"rolled_back_by":         userID,
+				"rollback_skipped_count": len(skipped),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrImportState
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batch.Status = domain.ImportStatusRolledBack
+	batch.RolledBackAt = &now
+	batch.RolledBackBy = &userID
+	batch.RollbackSkippedCount = len(skipped)
+	log.Printf("↩️ Import batch %s rolled back (%d records, %d skipped)", batch.ID, len(records)-len(skipped), len(skipped))
+	return batch, nil
+}
+
+// runCommitJob is the job queue handler applying an import batch
+func (s *ImportService) runCommitJob(ctx context.Context, job *domain.Job) error {
+	var payload importCommitJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid import payload: %w", err))
+	}
+
+	batch, err := s.GetBatch(job.TenantID, payload.BatchID)
+	if err != nil {
+		return PermanentJobError(err)
+	}
+	switch batch.Status {
+	case domain.ImportStatusCommitted:
+		return nil
+	case domain.ImportStatusCommitting, domain.ImportStatusFailed:
+		// Failed attempts rolled back their transaction and are retried from scratch
+	default:
+		return PermanentJobError(ErrImportState)
+	}
+
+	table, err := s.loadTable(ctx, batch)
+	if err != nil {
+		s.markFailed(batch.ID, err)
+		return err
+	}
+
+	// Imported changes are attributed to the user who committed the batch
+	ctx = domain.WithActor(ctx, job.CreatedBy.String())
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Re-plan against the current data: records may have changed since the dry-run
+		ops, err := s.plan(ctx, tx, batch, table)
+		if err != nil {
+			return err
+		}
+		summarizeImport(batch, ops)
+		if batch.ErrorCount > 0 && !batch.SkipInvalid {
+			return PermanentJobError(ErrImportHasErrors)
+		}
+
+		for i, op := range ops {
+			if op.apply == nil {
+				continue
+			}
+			entityID, before, err := op.apply(tx)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", op.result.Row, err)
+			}
+
+			record := domain.ImportRecord{
+				ID:        uuid.New(),
+				BatchID:   batch.ID,
+				RowNumber: op.result.Row,
+				Entity:    batch.Entity,
+				EntityID:  entityID,
+				Action:    op.result.Action,
+			}
+			if before != nil {
+				record.Before = importJSON(before)
+			}
+			if record.EntityUpdatedAt, err = importedUpdatedAt(tx, batch.Entity, entityID); err != nil {
+				return fmt.Errorf("row %d: %w", op.result.Row, err)
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return fmt.Errorf("failed to journal row %d: %w", op.result.Row, err)
+			}
+
+			if (i+1)%100 == 0 {
+				s.jobs.ReportProgress(job.ID, (i+1)*100/len(ops), fmt.Sprintf("%d/%d rows imported", i+1, len(ops)))
+			}
+		}
+
+		now := time.Now()
+		return tx.Model(&domain.ImportBatch{}).Where("id = ?", batch.ID).Updates(map[string]interface{}{
+			"status":          domain.ImportStatusCommitted,
+			"committed_at":    now,
+			"create_count":    batch.CreateCount,
+			"update_count":    batch.UpdateCount,
+			"unchanged_count": batch.UnchangedCount,
+			"error_count":     batch.ErrorCount,
+			"preview":         batch.Preview,
+			"error_message":   "",
+		}).Error
+	})
+	if err != nil {
+		s.markFailed(batch.ID, err)
+		return err
+	}
+
+	log.Printf("📥 Import batch %s committed: %d created, %d updated", batch.ID, batch.CreateCount, batch.UpdateCount)
+	return nil
+}
+
+func (s *ImportService) markFailed(batchID uuid.UUID, err error) {
+	s.db.Model(&domain.ImportBatch{}).Where("id = ?", batchID).Updates(map[string]interface{}{
+		"status":        domain.ImportStatusFailed,
+		"error_message": err.Error(),
+	})
+}
+
+// loadTable reads the uploaded file of a batch back from the blob store
+func (s *ImportService) loadTable(ctx context.Context, batch *domain.ImportBatch) (*importTable, error) {
+	body, err := s.blobs.Get(ctx, batch.FileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp("", "openrisk-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer import file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer import file: %w", err)
+	}
+	return readImportTable(batch.Format, tmp, size)
+}
+
+// importOp is the planned outcome of a row; apply performs it and returns the
+// record ID and the column values it overwrote
+type importOp struct {
+	result domain.ImportRowResult
+	apply  func(tx *gorm.DB) (uuid.UUID, map[string]interface{}, error)
+}
+
+// plan validates every row of a batch and decides what committing it does
+func (s *ImportService) plan(ctx context.Context, db *gorm.DB, batch *domain.ImportBatch, table *importTable) ([]importOp, error) {
+	fields, err := s.Fields(batch.Entity)
+	if err != nil {
+		return nil, err
+	}
+	var mapping map[string]string
+	if len(batch.Mapping) > 0 {
+		if err := json.Unmarshal(batch.Mapping, &mapping); err != nil {
+			return nil, fmt.Errorf("invalid mapping: %w", err)
+		}
+	}
+	columns, err := resolveImportMapping(batch.Entity, table.Headers, fields, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	p := &importPlanner{
+		db:      db,
+		entity:  batch.Entity,
+		matchBy: batch.MatchBy,
+		fields:  fields,
+		seen:    map[string]int{},
+	}
+
+	ops := make([]importOp, 0, len(table.Rows))
+	for _, row := range table.Rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		values, errs := parseImportRow(row, columns, s.customFields.ValidateFieldValue)
+		op, err := p.planRow(row.Line, values, errs)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// summarizeImport counts the planned actions and keeps a preview, errors first
+func summarizeImport(batch *domain.ImportBatch, ops []importOp) {
+	batch.CreateCount, batch.UpdateCount, batch.UnchangedCount, batch.ErrorCount = 0, 0, 0, 0
+	batch.TotalRows = len(ops)
+
+	preview := make([]domain.ImportRowResult, 0, len(ops))
+	for _, op := range ops {
+		switch op.result.Action {
+		case domain.ImportActionCreate:
+			batch.CreateCount++
+		case domain.ImportActionUpdate:
+			batch.UpdateCount++
+		case domain.ImportActionUnchanged:
+			batch.UnchangedCount++
+		case domain.ImportActionError:
+			batch.ErrorCount++
+		}
+		preview = append(preview, op.result)
+	}
+
+	sort.SliceStable(preview, func(i, j int) bool {
+		return preview[i].Action == domain.ImportActionError && preview[j].Action != domain.ImportActionError
+	})
+	if len(preview) > importPreviewLimit {
+		preview = preview[:importPreviewLimit]
+	}
+	batch.Preview = importJSON(preview)
+}
+
+// importPlanner matches rows against existing records. Lookup indexes are
+// loaded once per plan.
+type importPlanner struct {
+	db      *gorm.DB
+	entity  domain.ImportEntity
+	matchBy domain.ImportMatchMode
+	fields  []ImportField
+
+	risks       *importIndex
+	assets      *importIndex
+	mitigations map[uuid.UUID][]domain.Mitigation
+	seen        map[string]int // Record identity -> first row targeting it
+}
+
+// importIndex finds records by external ID or case-insensitive title/name
+type importIndex struct {
+	byExternalID map[string][]uuid.UUID
+	byName       map[string][]uuid.UUID
+	names        map[uuid.UUID]string
+}
+
+func (p *importPlanner) loadIndex(model interface{}, nameColumn string) (*importIndex, error) {
+	var rows []struct {
+		ID         uuid.UUID
+		Name       string
+		ExternalID string
+	}
+	if err := p.db.Model(model).Select("id, " + nameColumn + " AS name, external_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to index existing records: %w", err)
+	}
+
+	idx := &importIndex{
+		byExternalID: map[string][]uuid.UUID{},
+		byName:       map[string][]uuid.UUID{},
+		names:        map[uuid.UUID]string{},
+	}
+	for _, r := range rows {
+		if r.ExternalID != "" {
+			idx.byExternalID[r.ExternalID] = append(idx.byExternalID[r.ExternalID], r.ID)
+		}
+		key := strings.ToLower(strings.TrimSpace(r.Name))
+		idx.byName[key] = append(idx.byName[key], r.ID)
+		idx.names[r.ID] = r.Name
+	}
+	return idx, nil
+}
+
+func (p *importPlanner) riskIndex() (*importIndex, error) {
+	if p.risks == nil {
+		idx, err := p.loadIndex(&domain.Risk{}, "title")
+		if err != nil {
+			return nil, err
+		}
+		p.risks = idx
+	}
+	return p.risks, nil
+}
+
+func (p *importPlanner) assetIndex() (*importIndex, error) {
+	if p.assets == nil {
+		idx, err := p.loadIndex(&domain.Asset{}, "name")
+		if err != nil {
+			return nil, err
+		}
+		p.assets = idx
+	}
+	return p.assets, nil
+}
+
+// match finds the record a row updates following the batch's match mode.
+// A nil ID means the row creates a record.
+func (p *importPlanner) match(idx *importIndex, externalID, name string) (*uuid.UUID, error) {
+	if p.matchBy == domain.ImportMatchNone {
+		return nil, nil
+	}
+
+	if externalID != "" && p.matchBy != domain.ImportMatchTitle {
+		if ids := idx.byExternalID[externalID]; len(ids) > 0 {
+			if len(ids) > 1 {
+				return nil, fmt.Errorf("%d existing records have external ID %q", len(ids), externalID)
+			}
+			return &ids[0], nil
+		}
+	}
+	if name != "" && p.matchBy != domain.ImportMatchExternalID {
+		if ids := idx.byName[strings.ToLower(name)]; len(ids) > 0 {
+			if len(ids) > 1 {
+				return nil, fmt.Errorf("%d existing records are named %q: add an external ID column to tell them apart", len(ids), name)
+			}
+			return &ids[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// reference resolves a cell naming another record by external ID, then by title/name
+func reference(idx *importIndex, value string) (uuid.UUID, error) {
+	if ids := idx.byExternalID[value]; len(ids) == 1 {
+		return ids[0], nil
+	}
+	ids := idx.byName[strings.ToLower(value)]
+	switch len(ids) {
+	case 0:
+		return uuid.Nil, fmt.Errorf("%q does not match any external ID or name", value)
+	case 1:
+		return ids[0], nil
+	}
+	return uuid.Nil, fmt.Errorf("%q matches %d records", value, len(ids))
+}
+
+// planRow turns a parsed row into a create, an update, "unchanged" or an error
+func (p *importPlanner) planRow(line int, values map[string]interface{}, errs []string) (importOp, error) {
+	op := importOp{result: domain.ImportRowResult{Row: line, Errors: errs}}
+
+	var (
+		identities []string
+		err        error
+	)
+	switch p.entity {
+	case domain.ImportEntityRisk:
+		identities, err = p.planRisk(&op, values)
+	case domain.ImportEntityAsset:
+		identities, err = p.planAsset(&op, values)
+	case domain.ImportEntityMitigation:
+		identities, err = p.planMitigation(&op, values)
+	}
+	if err != nil {
+		return op, err
+	}
+
+	for _, identity := range identities {
+		if first, dup := p.seen[identity]; dup {
+			op.result.Errors = append(op.result.Errors, fmt.Sprintf("targets the same record as row %d", first))
+			break
+		}
+	}
+	if len(op.result.Errors) == 0 {
+		for _, identity := range identities {
+			p.seen[identity] = line
+		}
+	}
+
+	if len(op.result.Errors) > 0 {
+		op.result.Action = domain.ImportActionError
+		op.result.Changes = nil
+		op.apply = nil
+	}
+	return op, nil
+}
+
+// createIdentities keys a record a row creates by external ID and by name, so
+// a later row naming the same record either way is reported as a duplicate.
+// Without matching every row is a distinct record.
+func (p *importPlanner) createIdentities(kind, externalID, name string) []string {
+	if p.matchBy == domain.ImportMatchNone {
+		return nil
+	}
+	var identities []string
+	if externalID != "" {
+		identities = append(identities, kind+":external:"+externalID)
+	}
+	if name != "" {
+		identities = append(identities, kind+":name:"+strings.ToLower(name))
+	}
+	return identities
+}
+
+// fieldChanges diffs row values against current column values. Fields absent
+// from the row keep their value: blank cells never clear data.
+type fieldChanges struct {
+	changes map[string]domain.ImportFieldChange
+	updates map[string]interface{}
+	before  map[string]interface{}
+}
+
+func newFieldChanges() *fieldChanges {
+	return &fieldChanges{
+		changes: map[string]domain.ImportFieldChange{},
+		updates: map[string]interface{}{},
+		before:  map[string]interface{}{},
+	}
+}
+
+func (fc *fieldChanges) set(values map[string]interface{}, field, column string, current interface{}) {
+	value, ok := values[field]
+	if !ok || sameImportValue(current, value) {
+		return
+	}
+	if list, isList := value.([]string); isList {
+		value = pq.StringArray(list)
+	}
+	fc.changes[field] = domain.ImportFieldChange{From: current, To: value}
+	fc.updates[column] = value
+	fc.before[column] = current
+}
+
+func sameImportValue(a, b interface{}) bool {
+	norm := func(v interface{}) string {
+		switch t := v.(type) {
+		case nil:
+			return ""
+		case time.Time:
+			if t.IsZero() {
+				return ""
+			}
+			return t.Format("2006-01-02")
+		case pq.StringArray:
+			return strings.Join(t, "\x00")
+		case []string:
+			return strings.Join(t, "\x00")
+		}
+		return fmt.Sprint(v)
+	}
+	return norm(a) == norm(b)
+}
+
+func stringValue(values map[string]interface{}, field string) string {
+	s, _ := values[field].(string)
+	return s
+}
+
+func intValue(values map[string]interface{}, field string) int {
+	n, _ := values[field].(int)
+	return n
+}
+
+// createChanges lists every provided value of a new record
+func createChanges(values map[string]interface{}) map[string]domain.ImportFieldChange {
+	changes := make(map[string]domain.ImportFieldChange, len(values))
+	for field, v := range values {
+		changes[field] = domain.ImportFieldChange{To: v}
+	}
+	return changes
+}
+
+func (p *importPlanner) missingRequired(values map[string]interface{}) []string {
+	var errs []string
+	for _, f := range p.fields {
+		if _, ok := values[f.Name]; f.Required && !ok {
+			errs = append(errs, fmt.Sprintf("%s is required to create a record", f.Label))
+		}
+	}
+	return errs
+}
+
+func (p *importPlanner) planRisk(op *importOp, values map[string]interface{}) ([]string, error) {
+	risks, err := p.riskIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	title, externalID := stringValue(values, "title"), stringValue(values, "external_id")
+	op.result.Key = title
+	if title == "" {
+		op.result.Key = externalID
+	}
+
+	var assets []*domain.Asset
+	assetNames, assetsGiven := values["assets"].([]string)
+	if assetsGiven {
+		idx, err := p.assetIndex()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range assetNames {
+			id, err := reference(idx, name)
+			if err != nil {
+				op.result.Errors = append(op.result.Errors, "Assets: "+err.Error())
+				continue
+			}
+			assets = append(assets, &domain.Asset{ID: id})
+		}
+		if len(assets) > 0 {
+			ids := assetIDs(assets)
+			assets = nil
+			if err := p.db.Where("id IN ?", ids).Find(&assets).Error; err != nil {
+				return nil, fmt.Errorf("failed to load assets: %w", err)
+			}
+		}
+	}
+
+	custom := map[string]interface{}{}
+	for field, v := range values {
+		if strings.HasPrefix(field, "custom.") {
+			custom[strings.TrimPrefix(field, "custom.")] = v
+		}
+	}
+
+	id, err := p.match(risks, externalID, title)
+	if err != nil {
+		op.result.Errors = append(op.result.Errors, err.Error())
+		return nil, nil
+	}
+
+	if id == nil {
+		op.result.Errors = append(op.result.Errors, p.missingRequired(values)...)
+
+		risk := &domain.Risk{
+			ID:          uuid.New(),
+			Title:       title,
+			Description: stringValue(values, "description"),
+			Impact:      intValue(values, "impact"),
+			Probability: intValue(values, "probability"),
+			Status:      domain.StatusDraft,
+			Owner:       stringValue(values, "owner"),
+			Source:      importSourceTag,
+			ExternalID:  externalID,
+			Assets:      assets,
+		}
+		if status := stringValue(values, "status"); status != "" {
+			risk.Status = domain.RiskStatus(status)
+		}
+		if tags, ok := values["tags"].([]string); ok {
+			risk.Tags = tags
+		}
+		if frameworks, ok := values["frameworks"].([]string); ok {
+			risk.Frameworks = frameworks
+		}
+		if len(custom) > 0 {
+			risk.CustomFields = importJSON(custom)
+		}
+		if err := NewImpactService(p.db).ApplyEffectiveCriticality(risk.Assets); err != nil {
+			return nil, err
+		}
+		risk.Score = ComputeRiskScore(risk.Impact, risk.Probability, risk.Assets)
+
+		op.result.Action = domain.ImportActionCreate
+		op.result.Changes = createChanges(values)
+		op.apply = func(tx *gorm.DB) (uuid.UUID, map[string]interface{}, error) {
+			return risk.ID, nil, tx.Create(risk).Error
+		}
+
+		return p.createIdentities("risk", externalID, title), nil
+	}
+
+	var existing domain.Risk
+	if err := p.db.Preload("Assets").First(&existing, "id = ?", *id).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk %s: %w", *id, err)
+	}
+	op.result.EntityID = id
+
+	fc := newFieldChanges()
+	fc.set(values, "title", "title", existing.Title)
+	fc.set(values, "description", "description", existing.Description)
+	fc.set(values, "impact", "impact", existing.Impact)
+	fc.set(values, "probability", "probability", existing.Probability)
+	fc.set(values, "status", "status", string(existing.Status))
+	fc.set(values, "owner", "owner", existing.Owner)
+	fc.set(values, "external_id", "external_id", existing.ExternalID)
+	fc.set(values, "tags", "tags", existing.Tags)
+	fc.set(values, "frameworks", "frameworks", existing.Frameworks)
+
+	if len(custom) > 0 {
+		merged := map[string]interface{}{}
+		if len(existing.CustomFields) > 0 {
+			json.Unmarshal(existing.CustomFields, &merged)
+		}
+		changed := false
+		for name, v := range custom {
+			if old := merged[name]; !sameImportValue(old, v) {
+				fc.changes["custom."+name] = domain.ImportFieldChange{From: old, To: v}
+				merged[name] = v
+				changed = true
+			}
+		}
+		if changed {
+			fc.updates["custom_fields"] = importJSON(merged)
+			fc.before["custom_fields"] = existing.CustomFields
+		}
+	}
+
+	assetsChanged := assetsGiven && !sameImportValue(sortedIDs(assetIDs(existing.Assets)), sortedIDs(assetIDs(assets)))
+	if assetsChanged {
+		fc.changes["assets"] = domain.ImportFieldChange{From: assetNamesOf(existing.Assets), To: assetNamesOf(assets)}
+		fc.before["assets"] = assetIDs(existing.Assets)
+	}
+
+	// Score follows impact, probability and asset criticality
+	impact, probability, scoreAssets := existing.Impact, existing.Probability, existing.Assets
+	if v, ok := fc.updates["impact"].(int); ok {
+		impact = v
+	}
+	if v, ok := fc.updates["probability"].(int); ok {
+		probability = v
+	}
+	if assetsChanged {
+		scoreAssets = assets
+	}
+	if err := NewImpactService(p.db).ApplyEffectiveCriticality(scoreAssets); err != nil {
+		return nil, err
+	}
+	if score := ComputeRiskScore(impact, probability, scoreAssets); score != existing.Score {
+		fc.updates["score"] = score
+		fc.before["score"] = existing.Score
+	}
+
+	if len(fc.changes) == 0 {
+		op.result.Action = domain.ImportActionUnchanged
+		return []string{"risk:id:" + id.String()}, nil
+	}
+
+	op.result.Action = domain.ImportActionUpdate
+	op.result.Changes = fc.changes
+	op.apply = func(tx *gorm.DB) (uuid.UUID, map[string]interface{}, error) {
+		if len(fc.updates) > 0 {
+			if err := tx.Model(&existing).Omit(clause.Associations).Updates(fc.updates).Error; err != nil {
+				return existing.ID, nil, err
+			}
+		}
+		if assetsChanged {
+			if err := replaceRiskAssets(tx, existing.ID, assets); err != nil {
+				return existing.ID, nil, err
+			}
+		}
+		return existing.ID, fc.before, nil
+	}
+	return []string{"risk:id:" + id.String()}, nil
+}
+
+func (p *importPlanner) planAsset(op *importOp, values map[string]interface{}) ([]string, error) {
+	assets, err := p.assetIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	name, externalID := stringValue(values, "name"), stringValue(values, "external_id")
+	op.result.Key = name
+	if name == "" {
+		op.result.Key = externalID
+	}
+
+	id, err := p.match(assets, externalID, name)
+	if err != nil {
+		op.result.Errors = append(op.result.Errors, err.Error())
+		return nil, nil
+	}
+
+	if id == nil {
+		op.result.Errors = append(op.result.Errors, p.missingRequired(values)...)
+
+		asset := &domain.Asset{
+			ID:          uuid.New(),
+			Name:        name,
+			Type:        stringValue(values, "type"),
+			Criticality: domain.CriticalityMedium,
+			Owner:       stringValue(values, "owner"),
+			Source:      importSourceTag,
+			ExternalID:  externalID,
+		}
+		if criticality := stringValue(values, "criticality"); criticality != "" {
+			asset.Criticality = domain.AssetCriticality(criticality)
+		}
+
+		op.result.Action = domain.ImportActionCreate
+		op.result.Changes = createChanges(values)
+		op.apply = func(tx *gorm.DB) (uuid.UUID, map[string]interface{}, error) {
+			return asset.ID, nil, tx.Create(asset).Error
+		}
+
+		return p.createIdentities("asset", externalID, name), nil
+	}
+
+	var existing domain.Asset
+	if err := p.db.First(&existing, "id = ?", *id).Error; err != nil {
+		return nil, fmt.Errorf("failed to load asset %s: %w", *id, err)
+	}
+	op.result.EntityID = id
+
+	fc := newFieldChanges()
+	fc.set(values, "name", "name", existing.Name)
+	fc.set(values, "type", "type", existing.Type)
+	fc.set(values, "criticality", "criticality", string(existing.Criticality))
+	fc.set(values, "owner", "owner", existing.Owner)
+	fc.set(values, "external_id", "external_id", existing.ExternalID)
+
+	if len(fc.changes) == 0 {
+		op.result.Action = domain.ImportActionUnchanged
+		return []string{"asset:id:" + id.String()}, nil
+	}
+
+	op.result.Action = domain.ImportActionUpdate
+	op.result.Changes = fc.changes
+	op.apply = func(tx *gorm.DB) (uuid.UUID, map[string]interface{}, error) {
+		return existing.ID, fc.before, tx.Model(&existing).Omit(clause.Associations).Updates(fc.updates).Error
+	}
+	return []string{"asset:id:" + id.String()}, nil
+}
+
+func (p *importPlanner) planMitigation(op *importOp, values map[string]interface{}) ([]string, error) {
+	risks, err := p.riskIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	title := stringValue(values, "title")
+	op.result.Key = title
+
+	ref := stringValue(values, "risk")
+	if ref == "" {
+		op.result.Errors = append(op.result.Errors, "Risk is required")
+		return nil, nil
+	}
+	riskID, err := reference(risks, ref)
+	if err != nil {
+		op.result.Errors = append(op.result.Errors, "Risk: "+err.Error())
+		return nil, nil
+	}
+
+	// Mitigations have no external ID: they are matched by title within their risk
+	var existing *domain.Mitigation
+	if p.matchBy != domain.ImportMatchNone && p.matchBy != domain.ImportMatchExternalID && title != "" {
+		if p.mitigations == nil {
+			p.mitigations = map[uuid.UUID][]domain.Mitigation{}
+		}
+		list, ok := p.mitigations[riskID]
+		if !ok {
+			if err := p.db.Where("risk_id = ?", riskID).Find(&list).Error; err != nil {
+				return nil, fmt.Errorf("failed to load mitigations: %w", err)
+			}
+			p.mitigations[riskID] = list
+		}
+		for i := range list {
+			if !strings.EqualFold(strings.TrimSpace(list[i].Title), title) {
+				continue
+			}
+			if existing != nil {
+				op.result.Errors = append(op.result.Errors, fmt.Sprintf("several mitigations of risk %q are titled %q", ref, title))
+				return nil, nil
+			}
+			existing = &list[i]
+		}
+	}
+
+	if existing == nil {
+		op.result.Errors = append(op.result.Errors, p.missingRequired(values)...)
+
+		mitigation := &domain.Mitigation{
+			ID:             uuid.New(),
+			RiskID:         riskID,
+			Title:          title,
+			Assignee:       stringValue(values, "assignee"),
+			Status:         domain.MitigationPlanned,
+			Progress:       intValue(values, "progress"),
+			Cost:           intValue(values, "cost"),
+			MitigationTime: intValue(values, "mitigation_time"),
+		}
+		if status := stringValue(values, "status"); status != "" {
+			mitigation.Status = domain.MitigationStatus(status)
+		}
+		if due, ok := values["due_date"].(time.Time); ok {
+			mitigation.DueDate = due
+		}
+
+		op.result.Action = domain.ImportActionCreate
+		op.result.Changes = createChanges(values)
+		op.apply = func(tx *gorm.DB) (uuid.UUID, map[string]interface{}, error) {
+			return mitigation.ID, nil, tx.Create(mitigation).Error
+		}
+
+		return p.createIdentities("mitigation:"+riskID.String(), "", title), nil
+	}
+
+	op.result.EntityID = &existing.ID
+
+	fc := newFieldChanges()
+	fc.set(values, "assignee", "assignee", existing.Assignee)
+	fc.set(values, "status", "status", string(existing.Status))
+	fc.set(values, "progress", "progress", existing.Progress)
+	fc.set(values, "due_date", "due_date", existing.DueDate)
+	fc.set(values, "cost", "cost", existing.Cost)
+	fc.set(values, "mitigation_time", "mitigation_time", existing.MitigationTime)
+
+	identity := []string{"mitigation:id:" + existing.ID.String()}
+	if len(fc.changes) == 0 {
+		op.result.Action = domain.ImportActionUnchanged
+		return identity, nil
+	}
+
+	target := *existing
+	op.result.Action = domain.ImportActionUpdate
+	op.result.Changes = fc.changes
+	op.apply = func(tx *gorm.DB) (uuid.UUID, map[string]interface{}, error) {
+		return target.ID, fc.before, tx.Model(&target).Omit(clause.Associations).Updates(fc.updates).Error
+	}
+	return identity, nil
+}
+
+func assetIDs(assets []*domain.Asset) []string {
+	ids := make([]string, 0, len(assets))
+	for _, a := range assets {
+		ids = append(ids, a.ID.String())
+	}
+	return ids
+}
+
+func sortedIDs(ids []string) []string {
+	sort.Strings(ids)
+	return ids
+}
+
+func assetNamesOf(assets []*domain.Asset) []string {
+	names := make([]string, 0, len(assets))
+	for _, a := range assets {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func replaceRiskAssets(tx *gorm.DB, riskID uuid.UUID, assets []*domain.Asset) error {
+	association := tx.Model(&domain.Risk{ID: riskID}).Association("Assets")
+	var err error
+	if len(assets) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(assets)
+	}
+	if err != nil {
+		return err
+	}
+	// Link changes bypass the risk hooks
+	return domain.RecordRiskVersion(tx, riskID)
+}
+
+// importModel returns an empty model of an import entity
+func importModel(entity domain.ImportEntity) (interface{}, error) {
+	switch entity {
+	case domain.ImportEntityRisk:
+		return &domain.Risk{}, nil
+	case domain.ImportEntityAsset:
+		return &domain.Asset{}, nil
+	case domain.ImportEntityMitigation:
+		return &domain.Mitigation{}, nil
+	}
+	return nil, fmt.Errorf("unknown entity %q", entity)
+}
+
+// importedUpdatedAt reads the stored updated_at of a record, nil if it is gone
+func importedUpdatedAt(tx *gorm.DB, entity domain.ImportEntity, id uuid.UUID) (*time.Time, error) {
+	model, err := importModel(entity)
+	if err != nil {
+		return nil, err
+	}
+	var stamps []time.Time
+	if err := tx.Unscoped().Model(model).Where("id = ?", id).Pluck("updated_at", &stamps).Error; err != nil {
+		return nil, err
+	}
+	if len(stamps) == 0 {
+		return nil, nil
+	}
+	return &stamps[0], nil
+}
+
+// rollbackImportRecord reverts one journaled change. With checkChanged, a record
+// updated since the import is left alone and reported as changed.
+func rollbackImportRecord(tx *gorm.DB, rec domain.ImportRecord, checkChanged bool) (bool, error) {
+	model, err := importModel(rec.Entity)
+	if err != nil {
+		return false, err
+	}
+
+	if checkChanged && rec.EntityUpdatedAt != nil {
+		current, err := importedUpdatedAt(tx, rec.Entity, rec.EntityID)
+		if err != nil {
+			return false, err
+		}
+		if current != nil && !current.Equal(*rec.EntityUpdatedAt) {
+			return true, nil
+		}
+	}
+
+	if rec.Action == domain.ImportActionCreate {
+		// Loaded first so model hooks (risk versions) see what is deleted
+		if err := tx.First(model, "id = ?", rec.EntityID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		return false, tx.Delete(model).Error
+	}
+
+	before := map[string]interface{}{}
+	if len(rec.Before) > 0 {
+		if err := json.Unmarshal(rec.Before, &before); err != nil {
+			return false, fmt.Errorf("invalid journal entry: %w", err)
+		}
+	}
+
+	// Load the record so model hooks (risk history) see the restored values
+	if err := tx.Unscoped().First(model, "id = ?", rec.EntityID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	assets, restoreAssets := before["assets"]
+	delete(before, "assets")
+	for column, v := range before {
+		before[column] = restoreImportColumn(column, v)
+	}
+	if len(before) > 0 {
+		if err := tx.Model(model).Omit(clause.Associations).Updates(before).Error; err != nil {
+			return false, err
+		}
+	}
+
+	if restoreAssets {
+		var ids []string
+		if list, ok := assets.([]interface{}); ok {
+			for _, id := range list {
+				ids = append(ids, fmt.Sprint(id))
+			}
+		}
+		var previous []*domain.Asset
+		if len(ids) > 0 {
+			if err := tx.Where("id IN ?", ids).Find(&previous).Error; err != nil {
+				return false, err
+			}
+		}
+		return false, replaceRiskAssets(tx, rec.EntityID, previous)
+	}
+	return false, nil
+}
+
+// restoreImportColumn converts a journaled JSON value back to its column type
+func restoreImportColumn(column string, v interface{}) interface{} {
+	switch column {
+	case "tags", "frameworks":
+		list, _ := v.([]interface{})
+		out := make(pq.StringArray, 0, len(list))
+		for _, item := range list {
+			out = append(out, fmt.Sprint(item))
+		}
+		return out
+	case "custom_fields":
+		if v == nil {
+			return nil
+		}
+		return importJSON(v)
+	case "due_date":
+		if s, ok := v.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t
+			}
+		}
+	}
+	return v
+}
+
+func importJSON(v interface{}) datatypes.JSON {
+	data, _ := json.Marshal(v)
+	return datatypes.JSON(data)
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func readTestTable(t *testing.T, format string, data []byte) *importTable {
+	table, err := readImportTable(format, bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return table
+}
+
+func TestReadImportTableCSV(t *testing.T) {
+	data := []byte("\xEF\xBB\xBFRisk ID;Title;Likelihood\n\nR-1;\"Ransomware; on ERP\";4\n;;\nR-2;Phishing\n")
+	table := readTestTable(t, ImportFormatCSV, data)
+
+	assert.Equal(t, []string{"Risk ID", "Title", "Likelihood"}, table.Headers)
+	require.Len(t, table.Rows, 2)
+	assert.Equal(t, 3, table.Rows[0].Line)
+	assert.Equal(t, "Ransomware; on ERP", table.Rows[0].Cell(1))
+	assert.Equal(t, 5, table.Rows[1].Line)
+	assert.Equal(t, "", table.Rows[1].Cell(2), "short rows read as blank cells")
+}
+
+func TestReadImportTableXLSX(t *testing.T) {
+	columns := exportTestColumns(t, "title", "score", "tags")
+	data := writeExport(t, ExportFormatXLSX, columns)
+
+	table := readTestTable(t, ImportFormatXLSX, data)
+	assert.Equal(t, []string{"Title", "Score", "Tags"}, table.Headers)
+	require.Len(t, table.Rows, 2)
+	assert.Equal(t, 2, table.Rows[0].Line)
+	assert.Equal(t, "12", table.Rows[0].Cell(1))
+	assert.Equal(t, "ISO27001; GDPR", table.Rows[0].Cell(2))
+	assert.Equal(t, "Vendor <lock-in> & outage", table.Rows[1].Cell(0))
+}
+
+func TestReadImportTableRejectsEmptyFiles(t *testing.T) {
+	_, err := readImportTable(ImportFormatCSV, bytes.NewReader([]byte("\n\n")), 2)
+	assert.Error(t, err)
+
+	_, err = readImportTable(ImportFormatXLSX, bytes.NewReader([]byte("not a zip")), 9)
+	assert.Error(t, err)
+}
+
+func TestUniqueHeaders(t *testing.T) {
+	assert.Equal(t, []string{"Owner", "Column B", "owner (2)"}, uniqueHeaders([]string{"Owner", " ", "owner"}))
+}
+
+func importTestFields(t *testing.T) []ImportField {
+	fields, err := ImportFields(domain.ImportEntityRisk, []*domain.CustomField{
+		{Name: "department", DisplayName: "Business Unit", FieldType: domain.CustomFieldTypeChoice,
+			Validation: datatypes.JSON(`{"required":true,"allowed_values":["Finance","IT"]}`)},
+		{Name: "budget", FieldType: domain.CustomFieldTypeNumber, Validation: datatypes.JSON(`{"max":1000}`)},
+		{Name: "reviewed", FieldType: domain.CustomFieldTypeCheckbox},
+	})
+	require.NoError(t, err)
+	return fields
+}
+
+func TestSuggestImportMapping(t *testing.T) {
+	headers := []string{"Risk ID", "Risk Title", "Likelihood", "IMPACT", "Business unit", "budget", "Notes", "Name"}
+	mapping := SuggestImportMapping(headers, importTestFields(t))
+
+	assert.Equal(t, "external_id", mapping["Risk ID"])
+	assert.Equal(t, "title", mapping["Risk Title"])
+	assert.Equal(t, "probability", mapping["Likelihood"])
+	assert.Equal(t, "impact", mapping["IMPACT"])
+	assert.Equal(t, "custom.department", mapping["Business unit"])
+	assert.Equal(t, "custom.budget", mapping["budget"])
+	assert.Equal(t, "", mapping["Notes"])
+	assert.Equal(t, "", mapping["Name"], "title is already mapped")
+}
+
+func TestResolveImportMapping(t *testing.T) {
+	fields := importTestFields(t)
+	headers := []string{"A", "B"}
+
+	_, err := resolveImportMapping(domain.ImportEntityRisk, headers, fields, map[string]string{"A": "title", "B": "title"})
+	assert.Error(t, err, "field mapped twice")
+
+	_, err = resolveImportMapping(domain.ImportEntityRisk, headers, fields, map[string]string{"A": "password"})
+	assert.Error(t, err, "unknown field")
+
+	_, err = resolveImportMapping(domain.ImportEntityRisk, headers, fields, map[string]string{"C": "title"})
+	assert.Error(t, err, "unknown header")
+
+	_, err = resolveImportMapping(domain.ImportEntityRisk, headers, fields, map[string]string{"A": "impact"})
+	assert.Error(t, err, "no identifying column")
+
+	mitigationFields, _ := ImportFields(domain.ImportEntityMitigation, nil)
+	_, err = resolveImportMapping(domain.ImportEntityMitigation, headers, mitigationFields, map[string]string{"A": "title"})
+	assert.Error(t, err, "mitigations need their risk")
+
+	columns, err := resolveImportMapping(domain.ImportEntityRisk, headers, fields, map[string]string{"B": "title", "A": ""})
+	require.NoError(t, err)
+	require.Len(t, columns, 1)
+	assert.Equal(t, 1, columns[0].index)
+}
+
+func TestParseImportRow(t *testing.T) {
+	fields := importTestFields(t)
+	headers := []string{"Title", "Impact", "Status", "Tags", "Unit", "Budget", "Reviewed"}
+	columns, err := resolveImportMapping(domain.ImportEntityRisk, headers, fields, map[string]string{
+		"Title": "title", "Impact": "impact", "Status": "status", "Tags": "tags",
+		"Unit": "custom.department", "Budget": "custom.budget", "Reviewed": "custom.reviewed",
+	})
+	require.NoError(t, err)
+	validate := (&CustomFieldService{}).ValidateFieldValue
+
+	values, errs := parseImportRow(importRow{Line: 2, Cells: []string{"Data leak", "4", "mitigated", "GDPR; ISO27001, gdpr", "Finance", "250", "yes"}}, columns, validate)
+	assert.Empty(t, errs)
+	assert.Equal(t, "Data leak", values["title"])
+	assert.Equal(t, 4, values["impact"])
+	assert.Equal(t, "MITIGATED", values["status"])
+	assert.Equal(t, []string{"GDPR", "ISO27001"}, values["tags"])
+	assert.Equal(t, "Finance", values["custom.department"])
+	assert.Equal(t, 250.0, values["custom.budget"])
+	assert.Equal(t, true, values["custom.reviewed"])
+
+	values, errs = parseImportRow(importRow{Line: 3, Cells: []string{"Outage", "7", "closed", "", "Sales", "5000", "maybe"}}, columns, validate)
+	assert.Len(t, errs, 5, "every invalid cell is reported: %v", errs)
+	assert.Equal(t, map[string]interface{}{"title": "Outage"}, values)
+}
+
+func TestParseImportValue(t *testing.T) {
+	progress := mitigationImportFields[4]
+	for raw, want := range map[string]int{"40": 40, "40%": 40, "0.4": 40, "100": 100} {
+		v, err := parseImportValue(progress, raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, v, raw)
+	}
+	_, err := parseImportValue(progress, "2.5")
+	assert.Error(t, err)
+
+	due := mitigationImportFields[5]
+	for _, raw := range []string{"2026-03-31", "31/03/2026", "46112", "2026-03-31T00:00:00Z"} {
+		v, err := parseImportValue(due, raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), v, raw)
+	}
+	_, err = parseImportValue(due, "next week")
+	assert.Error(t, err)
+
+	status := mitigationImportFields[3]
+	v, err := parseImportValue(status, "In progress")
+	require.NoError(t, err)
+	assert.Equal(t, "IN_PROGRESS", v)
+}
+
+func TestImportPlannerMatch(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	idx := &importIndex{
+		byExternalID: map[string][]uuid.UUID{"R-1": {a}},
+		byName:       map[string][]uuid.UUID{"ransomware": {a}, "phishing": {b, c}},
+	}
+	p := &importPlanner{matchBy: domain.ImportMatchAuto}
+
+	id, err := p.match(idx, "R-1", "Other title")
+	require.NoError(t, err)
+	assert.Equal(t, a, *id)
+
+	id, err = p.match(idx, "R-9", "Ransomware")
+	require.NoError(t, err)
+	assert.Equal(t, a, *id, "unknown external IDs fall back to the title")
+
+	_, err = p.match(idx, "", "Phishing")
+	assert.Error(t, err, "ambiguous title")
+
+	p.matchBy = domain.ImportMatchExternalID
+	id, err = p.match(idx, "R-9", "Ransomware")
+	require.NoError(t, err)
+	assert.Nil(t, id)
+
+	p.matchBy = domain.ImportMatchNone
+	id, _ = p.match(idx, "R-1", "Ransomware")
+	assert.Nil(t, id)
+}
+
+func TestSummarizeImportPutsErrorsFirst(t *testing.T) {
+	batch := &domain.ImportBatch{}
+	summarizeImport(batch, []importOp{
+		{result: domain.ImportRowResult{Row: 2, Action: domain.ImportActionCreate}},
+		{result: domain.ImportRowResult{Row: 3, Action: domain.ImportActionUpdate}},
+		{result: domain.ImportRowResult{Row: 4, Action: domain.ImportActionError}},
+		{result: domain.ImportRowResult{Row: 5, Action: domain.ImportActionUnchanged}},
+	})
+
+	assert.Equal(t, 4, batch.TotalRows)
+	assert.Equal(t, []int{1, 1, 1, 1}, []int{batch.CreateCount, batch.UpdateCount, batch.UnchangedCount, batch.ErrorCount})
+	assert.Contains(t, string(batch.Preview), `[{"row":4,"action":"ERROR"`)
+}
+
+// importAssetT is the part of the assets table an import rollback restores
+type importAssetT struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name      string
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (importAssetT) TableName() string { return "assets" }
+
+func TestRollbackImportRecordSkipsChangedRecords(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:import_rollback?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&importAssetT{}))
+
+	imported := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	untouched := importAssetT{ID: uuid.New(), Name: "imported", UpdatedAt: imported}
+	edited := importAssetT{ID: uuid.New(), Name: "edited later", UpdatedAt: imported.Add(time.Hour)}
+	require.NoError(t, db.Create(&untouched).Error)
+	require.NoError(t, db.Create(&edited).Error)
+
+	journal := func(id uuid.UUID) domain.ImportRecord {
+		return domain.ImportRecord{
+			Entity:          domain.ImportEntityAsset,
+			EntityID:        id,
+			Action:          domain.ImportActionUpdate,
+			Before:          datatypes.JSON(`{"name":"original"}`),
+			EntityUpdatedAt: &imported,
+		}
+	}
+
+	changed, err := rollbackImportRecord(db, journal(untouched.ID), true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = rollbackImportRecord(db, journal(edited.ID), true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	var names []string
+	require.NoError(t, db.Model(&importAssetT{}).Order("name").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"edited later", "original"}, names)
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrIncidentNotFound           = errors.New("incident not found")
+	ErrIncidentRiskNotLinked      = errors.New("risk is not linked to the incident")
+	ErrLossEventNotFound          = errors.New("loss event not found")
+	ErrInvalidRecalibrationPolicy = errors.New("invalid recalibration policy")
+)
+
+// IncidentService keeps the incidents synchronised from TheHive or recorded
+// by hand, the risks they realized and the loss events they caused. A risk
+// realized repeatedly has its probability raised following the
+// recalibration policy of its tenant; the provenance of each raise is kept
+// in RiskRecalibration.
+type IncidentService struct {
+	db *gorm.DB
+}
+
+// NewIncidentService creates a new incident service
+func NewIncidentService(db *gorm.DB) *IncidentService {
+	return &IncidentService{db: db}
+}
+
+// IncidentQuery selects the incidents of a listing
+type IncidentQuery struct {
+	Severity string
+	Status   string
+	Source   string
+	RiskID   uuid.UUID // Incidents that realized this risk
+	Offset   int
+	Limit    int
+}
+
+// RecalibrationResult sums up a recalibration run
+type RecalibrationResult struct {
+	Risks        int `json:"risks"`        // Risks realized by at least one incident
+	Recalibrated int `json:"recalibrated"` // Risks whose probability was raised
+}
+
+// RecordIncident creates an incident, or updates the one with the same
+// source and external ID, and links it to the given risks, which are then
+// recalibrated. Incidents without an external ID are always created.
+func (s *IncidentService) RecordIncident(ctx context.Context, incident *domain.Incident, riskIDs ...uuid.UUID) (*domain.Incident, error) {
+	if incident.Source == "" {
+		incident.Source = domain.IncidentSourceManual
+	}
+	db := s.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing domain.Incident
+		found := false
+		if incident.ExternalID != "" {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("source = ? AND external_id = ?", incident.Source, incident.ExternalID).
+				Take(&existing).Error
+			switch {
+			case err == nil:
+				found = true
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return fmt.Errorf("failed to load incident: %w", err)
+			}
+		}
+
+		if found {
+			// The source is authoritative for what it reports; the opening
+			// date and the links are kept
+			incident.ID = existing.ID
+			incident.CreatedAt = existing.CreatedAt
+			if err := tx.Model(&existing).Select("title", "status", "severity", "description", "resolved_at").Updates(incident).Error; err != nil {
+				return fmt.Errorf("failed to update incident: %w", err)
+			}
+		} else {
+			if incident.ID == uuid.Nil {
+				incident.ID = uuid.New()
+			}
+			if err := tx.Omit(clause.Associations).Create(incident).Error; err != nil {
+				return fmt.Errorf("failed to create incident: %w", err)
+			}
+		}
+
+		_, err := s.link(tx, incident, riskIDs, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetIncident(incident.ID)
+}
+
+// ListIncidents returns a page of incidents, most recent first, and the
+// number of incidents selected
+func (s *IncidentService) ListIncidents(query IncidentQuery) ([]domain.Incident, int64, error) {
+	q := s.db.Model(&domain.Incident{})
+	if query.Severity != "" {
+		q = q.Where("severity = ?", query.Severity)
+	}
+	if query.Status != "" {
+		q = q.Where("status = ?", query.Status)
+	}
+	if query.Source != "" {
+		q = q.Where("source = ?", query.Source)
+	}
+	if query.RiskID != uuid.Nil {
+		q = q.Where("id IN (?)", s.db.Model(&domain.IncidentRisk{}).Select("incident_id").Where("risk_id = ?", query.RiskID))
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+	var incidents []domain.Incident
+	if err := q.Preload("Risks").Preload("LossEvents").
+		Order("created_at DESC").Offset(query.Offset).Limit(query.Limit).
+		Find(&incidents).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	return incidents, total, nil
+}
+
+// GetIncident returns an incident with its risks and loss events
+func (s *IncidentService) GetIncident(id uuid.UUID) (*domain.Incident, error) {
+	var incident domain.Incident
+	err := s.db.Preload("Risks", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("LossEvents", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at") }).
+		Take(&incident, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIncidentNotFound
+		}
+		return nil, fmt.Errorf("failed to load incident: %w", err)
+	}
+	return &incident, nil
+}
+
+// LinkRisks records that an incident realized the given risks and
+// recalibrates them. It returns the recalibrations made.
+func (s *IncidentService) LinkRisks(ctx context.Context, incidentID uuid.UUID, riskIDs []uuid.UUID) ([]domain.RiskRecalibration, error) {
+	var recalibrations []domain.RiskRecalibration
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var incident domain.Incident
+		if err := tx.Take(&incident, "id = ?", incidentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrIncidentNotFound
+			}
+			return fmt.Errorf("failed to load incident: %w", err)
+		}
+		var err error
+		recalibrations, err = s.link(tx, &incident, riskIDs, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recalibrations, nil
+}
+
+// UnlinkRisk removes the link between an incident and a risk. The risk's
+// probability is left alone: recalibration never lowers it.
+func (s *IncidentService) UnlinkRisk(incidentID, riskID uuid.UUID) error {
+	result := s.db.Where("incident_id = ? AND risk_id = ?", incidentID, riskID).Delete(&domain.IncidentRisk{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unlink risk: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrIncidentRiskNotLinked
+	}
+	return nil
+}
+
+// ListRecalibrations returns the probability raises of a risk, most recent first
+func (s *IncidentService) ListRecalibrations(riskID uuid.UUID) ([]domain.RiskRecalibration, error) {
+	var recalibrations []domain.RiskRecalibration
+	if err := s.db.Where("risk_id = ?", riskID).Order("created_at DESC").Find(&recalibrations).Error; err != nil {
+		return nil, fmt.Errorf("failed to list risk recalibrations: %w", err)
+	}
+	return recalibrations, nil
+}
+
+// AddLossEvent records a loss caused by an incident
+func (s *IncidentService) AddLossEvent(ctx context.Context, incidentID uuid.UUID, event domain.LossEvent) (*domain.LossEvent, error) {
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := s.db.Model(&domain.Incident{}).Where("id = ?", incidentID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to load incident: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrIncidentNotFound
+	}
+
+	event.ID = uuid.New()
+	event.IncidentID = incidentID
+	event.RecordedBy = domain.ActorFrom(ctx)
+	if err := s.db.Create(&event).Error; err != nil {
+		return nil, fmt.Errorf("failed to create loss event: %w", err)
+	}
+	return &event, nil
+}
+
+// UpdateLossEvent replaces the amounts, dates and description of a loss event
+func (s *IncidentService) UpdateLossEvent(ctx context.Context, id uuid.UUID, input domain.LossEvent) (*domain.LossEvent, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	var event domain.LossEvent
+	if err := s.db.Take(&event, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLossEventNotFound
+		}
+		return nil, fmt.Errorf("failed to load loss event: %w", err)
+	}
+
+	event.Description = input.Description
+	event.DirectLoss = input.DirectLoss
+	event.IndirectLoss = input.IndirectLoss
+	event.RecoveryCost = input.RecoveryCost
+	event.OccurredAt = input.OccurredAt
+	event.DiscoveredAt = input.DiscoveredAt
+	event.RecoveredAt = input.RecoveredAt
+	event.RecordedBy = domain.ActorFrom(ctx)
+	if err := s.db.Save(&event).Error; err != nil {
+		return nil, fmt.Errorf("failed to save loss event: %w", err)
+	}
+	return &event, nil
+}
+
+// DeleteLossEvent removes a loss event
+func (s *IncidentService) DeleteLossEvent(id uuid.UUID) error {
+	result := s.db.Delete(&domain.LossEvent{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete loss event: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLossEventNotFound
+	}
+	return nil
+}
+
+// GetPolicy returns the tenant's recalibration policy, or the default one
+func (s *IncidentService) GetPolicy(tenantID uuid.UUID) domain.RecalibrationPolicy {
+	var policy domain.RecalibrationPolicy
+	if err := s.db.First(&policy, "tenant_id = ?", tenantID).Error; err != nil {
+		return domain.DefaultRecalibrationPolicy(tenantID)
+	}
+	return policy
+}
+
+// UpdatePolicy creates or replaces the tenant's recalibration policy
+func (s *IncidentService) UpdatePolicy(tenantID, updatedBy uuid.UUID, input domain.RecalibrationPolicy) (*domain.RecalibrationPolicy, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecalibrationPolicy, err)
+	}
+
+	policy := s.GetPolicy(tenantID)
+	policy.Enabled = input.Enabled
+	policy.WindowDays = input.WindowDays
+	if len(input.Thresholds) > 0 {
+		policy.Thresholds = input.Thresholds
+	}
+	policy.UpdatedBy = updatedBy
+	if policy.ID == uuid.Nil {
+		policy.ID = uuid.New()
+	}
+
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save recalibration policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// RecalibrateAll recalibrates every risk realized by an incident, e.g. after
+// a policy change
+func (s *IncidentService) RecalibrateAll(ctx context.Context) (*RecalibrationResult, error) {
+	db := s.db.WithContext(domain.WithActor(ctx, domain.SystemActor))
+	var riskIDs []uuid.UUID
+	if err := db.Model(&domain.IncidentRisk{}).Distinct("risk_id").Pluck("risk_id", &riskIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list risks realized by incidents: %w", err)
+	}
+
+	run := s.newRecalibrationRun()
+	now := time.Now()
+	result := &RecalibrationResult{}
+	for _, id := range riskIDs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			recalibration, err := run.recalibrate(tx, id, nil, now)
+			if recalibration != nil {
+				result.Recalibrated++
+			}
+			return err
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to recalibrate risk %s: %w", id, err)
+		}
+		result.Risks++
+	}
+	return result, nil
+}
+
+// link records the links of an incident to risks, which must exist, and
+// recalibrates them when one is new
+func (s *IncidentService) link(tx *gorm.DB, incident *domain.Incident, riskIDs []uuid.UUID, now time.Time) ([]domain.RiskRecalibration, error) {
+	riskIDs = uniqueUUIDs(riskIDs)
+	if len(riskIDs) == 0 {
+		return nil, nil
+	}
+	var count int64
+	if err := tx.Model(&domain.Risk{}).Where("id IN ?", riskIDs).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risks: %w", err)
+	}
+	if int(count) != len(riskIDs) {
+		return nil, ErrRiskNotFound
+	}
+	actor := domain.ActorFrom(tx.Statement.Context)
+	links := make([]domain.IncidentRisk, 0, len(riskIDs))
+	for _, id := range riskIDs {
+		links = append(links, domain.IncidentRisk{IncidentID: incident.ID, RiskID: id, LinkedBy: actor})
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to link risks: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	run := s.newRecalibrationRun()
+	var recalibrations []domain.RiskRecalibration
+	for _, id := range riskIDs {
+		recalibration, err := run.recalibrate(tx, id, &incident.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if recalibration != nil {
+			recalibrations = append(recalibrations, *recalibration)
+		}
+	}
+	return recalibrations, nil
+}
+
+// recalibrationRun caches the tenant policies shared between risks
+type recalibrationRun struct {
+	service  *IncidentService
+	policies map[uuid.UUID]*domain.RecalibrationPolicy
+}
+
+func (s *IncidentService) newRecalibrationRun() *recalibrationRun {
+	return &recalibrationRun{service: s, policies: map[uuid.UUID]*domain.RecalibrationPolicy{}}
+}
+
+func (run *recalibrationRun) policy(tenantID uuid.UUID) *domain.RecalibrationPolicy {
+	if p, ok := run.policies[tenantID]; ok {
+		return p
+	}
+	p := run.service.GetPolicy(tenantID)
+	run.policies[tenantID] = &p
+	return &p
+}
+
+// recalibrate raises the probability of a risk to what the number of
+// incidents that realized it within the window of its tenant's policy calls
+// for. The change is recorded in the risk's history and its provenance in
+// RiskRecalibration. It returns nil when the probability is left alone.
+func (run *recalibrationRun) recalibrate(tx *gorm.DB, riskID uuid.UUID, incidentID *uuid.UUID, now time.Time) (*domain.RiskRecalibration, error) {
+	var risk domain.Risk
+	if err := tx.Preload("Assets").Take(&risk, "id = ?", riskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load risk: %w", err)
+	}
+
+	tenantID := domain.RiskTenant(tx, risk.ID, uuid.Nil)
+	policy := run.policy(tenantID)
+	if !policy.Enabled {
+		return nil, nil
+	}
+	var occurrences int64
+	if err := tx.Model(&domain.IncidentRisk{}).
+		Joins("JOIN incidents ON incidents.id = incident_risks.incident_id").
+		Where("incident_risks.risk_id = ? AND incidents.created_at >= ?", risk.ID, now.Add(-policy.Window())).
+		Count(&occurrences).Error; err != nil {
+		return nil, fmt.Errorf("failed to count incidents of risk: %w", err)
+	}
+	probability, reason := recalibratedProbability(risk.Probability, int(occurrences), policy)
+	if probability == risk.Probability {
+		return nil, nil
+	}
+
+	if err := NewImpactService(tx).ApplyEffectiveCriticality(risk.Assets); err != nil {
+		return nil, err
+	}
+	// The hooks are skipped so the history entry carries the change type
+	if err := tx.Table("risks").Where("id = ?", risk.ID).UpdateColumns(map[string]interface{}{
+		"probability": probability,
+		"score":       ComputeRiskScore(risk.Impact, probability, risk.Assets),
+		"updated_at":  now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update risk: %w", err)
+	}
+	if err := domain.RecordRiskChange(tx, risk.ID, domain.RiskChangeRecalibrated); err != nil {
+		return nil, err
+	}
+
+	recalibration := &domain.RiskRecalibration{
+		ID:              uuid.New(),
+		RiskID:          risk.ID,
+		IncidentID:      incidentID,
+		TenantID:        tenantID,
+		Occurrences:     int(occurrences),
+		WindowDays:      policy.WindowDays,
+		ProbabilityFrom: risk.Probability,
+		ProbabilityTo:   probability,
+		Reason:          reason,
+		CreatedAt:       now,
+	}
+	if err := tx.Create(recalibration).Error; err != nil {
+		return nil, fmt.Errorf("failed to save risk recalibration: %w", err)
+	}
+	return recalibration, nil
+}
+
+// recalibratedProbability is the probability of a risk realized by
+// occurrences incidents within the policy window, and the rule that raised
+// it. Probability is only ever raised.
+func recalibratedProbability(current, occurrences int, policy *domain.RecalibrationPolicy) (int, string) {
+	suggested, reason := policy.ProbabilityFor(occurrences)
+	if suggested <= current {
+		return current, ""
+	}
+	return suggested, reason
+}
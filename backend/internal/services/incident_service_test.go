@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestRecalibratedProbability(t *testing.T) {
+	policy := domain.DefaultRecalibrationPolicy(uuid.Nil)
+
+	p, reason := recalibratedProbability(2, 1, &policy)
+	assert.Equal(t, 2, p)
+	assert.Empty(t, reason)
+
+	p, reason = recalibratedProbability(2, 2, &policy)
+	assert.Equal(t, 4, p)
+	assert.Contains(t, reason, "materialized 2 times")
+
+	p, _ = recalibratedProbability(5, 2, &policy)
+	assert.Equal(t, 5, p, "probability is never lowered")
+}
+
+func TestCompareLosses(t *testing.T) {
+	phishing, ransomware, other, outside := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	risks := []lossRisk{
+		{ID: phishing, Category: "Cyber", ExpectedLoss: 10000},
+		{ID: ransomware, Category: "Cyber", ExpectedLoss: 50000},
+		{ID: other, Category: UncategorizedRisks},
+	}
+	incidents := []lossIncident{
+		{ID: uuid.New(), Loss: 30000, RiskIDs: []uuid.UUID{phishing, ransomware}},
+		{ID: uuid.New(), Loss: 1000, RiskIDs: []uuid.UUID{other, outside}},
+		{ID: uuid.New(), Loss: 700},
+	}
+
+	analytics := compareLosses(risks, incidents, 0.5)
+	require.Len(t, analytics.Categories, 2)
+
+	cyber := analytics.Categories[0]
+	assert.Equal(t, "Cyber", cyber.Category)
+	assert.Equal(t, 2, cyber.Risks)
+	assert.Equal(t, 1, cyber.Incidents)
+	assert.Equal(t, 30000.0, cyber.PredictedLoss, "expected annual losses are prorated to the period")
+	assert.Equal(t, 30000.0, cyber.ActualLoss)
+	assert.Equal(t, 0.0, cyber.Variance)
+	require.NotNil(t, cyber.Ratio)
+	assert.Equal(t, 1.0, *cyber.Ratio)
+
+	uncategorized := analytics.Categories[1]
+	assert.Equal(t, 500.0, uncategorized.ActualLoss, "the share of a risk outside the selection is left out")
+	assert.Nil(t, uncategorized.Ratio, "no ratio without a prediction")
+	assert.Equal(t, 500.0, uncategorized.Variance)
+
+	assert.Equal(t, 30000.0, analytics.PredictedLoss)
+	assert.Equal(t, 30500.0, analytics.ActualLoss)
+	assert.Equal(t, 700.0, analytics.UnattributedLoss)
+}
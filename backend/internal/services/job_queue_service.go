@@ -0,0 +1,531 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobFinished      = errors.New("job has already finished")
+	ErrJobNotRetryable  = errors.New("only failed or cancelled jobs can be retried")
+	ErrUnknownJobType   = errors.New("no handler registered for job type")
+	errJobLeaseLost     = errors.New("job lease lost")
+	errJobCancelRequest = errors.New("job cancelled")
+)
+
+// JobHandler executes one attempt of a job. The context is cancelled when the
+// job is cancelled or the queue shuts down; returning an error schedules a retry
+// unless it is wrapped with PermanentJobError or the attempts are exhausted.
+type JobHandler func(ctx context.Context, job *domain.Job) error
+
+type permanentJobError struct{ err error }
+
+func (e permanentJobError) Error() string { return e.err.Error() }
+func (e permanentJobError) Unwrap() error { return e.err }
+
+// PermanentJobError marks a job error as not worth retrying (e.g. an invalid payload)
+func PermanentJobError(err error) error {
+	return permanentJobError{err: err}
+}
+
+// EnqueueOptions tunes how a job is queued
+type EnqueueOptions struct {
+	TenantID       uuid.UUID
+	CreatedBy      uuid.UUID
+	IdempotencyKey string
+	MaxAttempts    int
+	Priority       int
+	RunAt          time.Time // Zero runs as soon as a worker is free
+}
+
+// JobQueueService is a durable, Postgres-backed job queue shared by every
+// server instance. Jobs are claimed with FOR UPDATE SKIP LOCKED so several
+// workers never run the same job concurrently.
+type JobQueueService struct {
+	db         *gorm.DB
+	workerID   string
+	visibility time.Duration
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	running  map[uuid.UUID]context.CancelFunc
+}
+
+// NewJobQueueService creates a new job queue service
+func NewJobQueueService(db *gorm.DB) *JobQueueService {
+	host, _ := os.Hostname()
+	return &JobQueueService{
+		db:         db,
+		workerID:   fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8]),
+		visibility: 5 * time.Minute,
+		backoff:    10 * time.Second,
+		maxBackoff: time.Hour,
+		handlers:   make(map[string]JobHandler),
+		running:    make(map[uuid.UUID]context.CancelFunc),
+	}
+}
+
+// RegisterHandler registers the function that runs jobs of the given type
+func (s *JobQueueService) RegisterHandler(jobType string, handler JobHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = handler
+}
+
+// Enqueue persists a new job. When an idempotency key is given and a job with the
+// same key already exists for the tenant, that job is returned instead.
+func (s *JobQueueService) Enqueue(jobType string, payload interface{}, opts EnqueueOptions) (*domain.Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job := &domain.Job{
+		ID:          uuid.New(),
+		TenantID:    opts.TenantID,
+		Type:        jobType,
+		Payload:     datatypes.JSON(raw),
+		Status:      domain.JobQueued,
+		Priority:    opts.Priority,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt,
+		CreatedBy:   opts.CreatedBy,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = domain.DefaultJobMaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+
+	if opts.IdempotencyKey != "" {
+		key := opts.IdempotencyKey
+		job.IdempotencyKey = &key
+
+		if existing, err := s.findByIdempotencyKey(opts.TenantID, key); err == nil {
+			return existing, nil
+		}
+	}
+
+	if err := s.db.Create(job).Error; err != nil {
+		// A concurrent enqueue with the same key won the unique index
+		if job.IdempotencyKey != nil {
+			if existing, findErr := s.findByIdempotencyKey(opts.TenantID, *job.IdempotencyKey); findErr == nil {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetJob retrieves a job by ID
+func (s *JobQueueService) GetJob(tenantID, jobID uuid.UUID) (*domain.Job, error) {
+	var job domain.Job
+	if err := s.db.Where("id = ? AND tenant_id = ?", jobID, tenantID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// ListJobs lists jobs, newest first. A nil createdBy lists the jobs of every user.
+func (s *JobQueueService) ListJobs(tenantID uuid.UUID, createdBy *uuid.UUID, jobType, status string, limit, offset int) ([]domain.Job, int64, error) {
+	query := s.db.Model(&domain.Job{}).Where("tenant_id = ?", tenantID)
+	if createdBy != nil {
+		query = query.Where("created_by = ?", *createdBy)
+	}
+	if jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	var jobs []domain.Job
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&jobs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// CancelJob cancels a queued job immediately; a running job is flagged and its
+// context is cancelled by the worker that holds it.
+func (s *JobQueueService) CancelJob(tenantID, jobID uuid.UUID) (*domain.Job, error) {
+	job, err := s.GetJob(tenantID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsTerminal() {
+		return nil, ErrJobFinished
+	}
+
+	now := time.Now()
+	if job.Status == domain.JobQueued {
+		res := s.db.Model(&domain.Job{}).
+			Where("id = ? AND status = ?", job.ID, domain.JobQueued).
+			Updates(map[string]interface{}{
+				"status":           domain.JobCancelled,
+				"cancel_requested": true,
+				"finished_at":      now,
+			})
+		if res.Error != nil {
+			return nil, fmt.Errorf("failed to cancel job: %w", res.Error)
+		}
+		if res.RowsAffected == 1 {
+			return s.GetJob(tenantID, jobID)
+		}
+		// Claimed by a worker in the meantime: fall through and flag it
+	}
+
+	if err := s.db.Model(&domain.Job{}).Where("id = ?", job.ID).Update("cancel_requested", true).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	s.mu.RLock()
+	if cancel, ok := s.running[job.ID]; ok {
+		cancel()
+	}
+	s.mu.RUnlock()
+
+	return s.GetJob(tenantID, jobID)
+}
+
+// RetryJob re-queues a failed or cancelled job with a fresh attempt budget
+func (s *JobQueueService) RetryJob(tenantID, jobID uuid.UUID) (*domain.Job, error) {
+	job, err := s.GetJob(tenantID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != domain.JobFailed && job.Status != domain.JobCancelled {
+		return nil, ErrJobNotRetryable
+	}
+
+	if err := s.db.Model(&domain.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":           domain.JobQueued,
+		"attempts":         0,
+		"run_at":           time.Now(),
+		"cancel_requested": false,
+		"progress":         0,
+		"progress_message": "",
+		"finished_at":      nil,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+
+	return s.GetJob(tenantID, jobID)
+}
+
+// ReportProgress records the progress of a running job and extends its lease,
+// so long-running handlers are not handed to another worker.
+func (s *JobQueueService) ReportProgress(jobID uuid.UUID, percent int, message string) error {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+
+	return s.db.Model(&domain.Job{}).
+		Where("id = ? AND status = ?", jobID, domain.JobRunning).
+		Updates(map[string]interface{}{
+			"progress":         percent,
+			"progress_message": message,
+			"locked_until":     time.Now().Add(s.visibility),
+		}).Error
+}
+
+// SetResult stores the output of a job (e.g. the location of a generated file)
+func (s *JobQueueService) SetResult(jobID uuid.UUID, result interface{}) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode job result: %w", err)
+	}
+	return s.db.Model(&domain.Job{}).Where("id = ?", jobID).Update("result", datatypes.JSON(raw)).Error
+}
+
+// Start launches the worker pool. Workers poll for due jobs every pollInterval
+// and stop when ctx is cancelled.
+func (s *JobQueueService) Start(ctx context.Context, workers int, pollInterval time.Duration) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(pollInterval)
+			defer ticker.Stop()
+
+			for {
+				// Drain due jobs before waiting for the next poll
+				for ctx.Err() == nil {
+					ran, err := s.RunNext(ctx)
+					if err != nil {
+						log.Printf("jobs: worker error: %v", err)
+						break
+					}
+					if !ran {
+						break
+					}
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// RunNext claims and runs a single due job. It reports whether a job was run.
+func (s *JobQueueService) RunNext(ctx context.Context) (bool, error) {
+	if err := s.failExhaustedLeases(time.Now()); err != nil {
+		return false, err
+	}
+
+	job, err := s.claim(time.Now())
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	s.execute(ctx, job)
+	return true, nil
+}
+
+// claim locks the next due job, skipping rows held by other workers
+func (s *JobQueueService) claim(now time.Time) (*domain.Job, error) {
+	types := s.registeredTypes()
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	var claimed *domain.Job
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var job domain.Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("type IN ?", types).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ? AND attempts < max_attempts)",
+				domain.JobQueued, now, domain.JobRunning, now).
+			Order("priority DESC, run_at ASC").
+			First(&job).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		lockedUntil := now.Add(s.visibility)
+		updates := map[string]interface{}{
+			"status":       domain.JobRunning,
+			"attempts":     job.Attempts + 1,
+			"locked_by":    s.workerID,
+			"locked_until": lockedUntil,
+		}
+		if job.StartedAt == nil {
+			updates["started_at"] = now
+		}
+		if err := tx.Model(&domain.Job{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		job.Status = domain.JobRunning
+		job.Attempts++
+		job.LockedBy = s.workerID
+		job.LockedUntil = &lockedUntil
+		claimed = &job
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	return claimed, nil
+}
+
+// failExhaustedLeases fails running jobs whose lease expired on their last attempt
+func (s *JobQueueService) failExhaustedLeases(now time.Time) error {
+	return s.db.Model(&domain.Job{}).
+		Where("status = ? AND locked_until < ? AND attempts >= max_attempts", domain.JobRunning, now).
+		Updates(map[string]interface{}{
+			"status":       domain.JobFailed,
+			"last_error":   "visibility timeout exceeded",
+			"locked_by":    "",
+			"locked_until": nil,
+			"finished_at":  now,
+		}).Error
+}
+
+// execute runs the handler for a claimed job and records the outcome
+func (s *JobQueueService) execute(parent context.Context, job *domain.Job) {
+	s.mu.RLock()
+	handler := s.handlers[job.Type]
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	s.mu.Lock()
+	s.running[job.ID] = func() { cancel(errJobCancelRequest) }
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+
+	stop := s.heartbeat(ctx, cancel, job.ID)
+	err := s.invoke(ctx, handler, job)
+	stop()
+
+	if errors.Is(context.Cause(ctx), errJobLeaseLost) {
+		log.Printf("jobs: lease on %s (%s) was lost, leaving it to the new owner", job.ID, job.Type)
+		return
+	}
+
+	s.finish(job, err, context.Cause(ctx))
+}
+
+// invoke runs the handler, converting panics into job errors
+func (s *JobQueueService) invoke(ctx context.Context, handler JobHandler, job *domain.Job) (err error) {
+	if handler == nil {
+		return PermanentJobError(fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// heartbeat extends the job lease while the handler runs and cancels the
+// handler when a cancellation is requested from another instance
+func (s *JobQueueService) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, jobID uuid.UUID) func() {
+	done := make(chan struct{})
+	interval := s.visibility / 3
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				var job domain.Job
+				if err := s.db.Select("cancel_requested", "locked_by").First(&job, "id = ?", jobID).Error; err != nil {
+					continue
+				}
+				if job.LockedBy != s.workerID {
+					cancel(errJobLeaseLost)
+					return
+				}
+				if job.CancelRequested {
+					cancel(errJobCancelRequest)
+					return
+				}
+				s.db.Model(&domain.Job{}).
+					Where("id = ? AND locked_by = ?", jobID, s.workerID).
+					Update("locked_until", time.Now().Add(s.visibility))
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// finish records the result of an attempt: success, retry with backoff, or failure
+func (s *JobQueueService) finish(job *domain.Job, runErr, cause error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"locked_by":    "",
+		"locked_until": nil,
+	}
+
+	var permanent permanentJobError
+	switch {
+	case runErr == nil:
+		updates["status"] = domain.JobSucceeded
+		updates["progress"] = 100
+		updates["last_error"] = ""
+		updates["finished_at"] = now
+	case errors.Is(cause, errJobCancelRequest):
+		updates["status"] = domain.JobCancelled
+		updates["last_error"] = runErr.Error()
+		updates["finished_at"] = now
+	case errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = domain.JobFailed
+		updates["last_error"] = runErr.Error()
+		updates["finished_at"] = now
+	default:
+		updates["status"] = domain.JobQueued
+		updates["last_error"] = runErr.Error()
+		updates["run_at"] = now.Add(domain.JobBackoff(job.Attempts, s.backoff, s.maxBackoff))
+	}
+
+	if err := s.db.Model(&domain.Job{}).
+		Where("id = ? AND locked_by = ?", job.ID, s.workerID).
+		Updates(updates).Error; err != nil {
+		log.Printf("jobs: failed to record outcome of %s: %v", job.ID, err)
+		return
+	}
+
+	if runErr != nil {
+		log.Printf("jobs: %s (%s) attempt %d/%d: %v -> %s", job.ID, job.Type, job.Attempts, job.MaxAttempts, runErr, updates["status"])
+	}
+}
+
+func (s *JobQueueService) registeredTypes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	types := make([]string, 0, len(s.handlers))
+	for jobType := range s.handlers {
+		types = append(types, jobType)
+	}
+	return types
+}
+
+func (s *JobQueueService) findByIdempotencyKey(tenantID uuid.UUID, key string) (*domain.Job, error) {
+	var job domain.Job
+	res := s.db.Where("tenant_id = ? AND idempotency_key = ?", tenantID, key).Limit(1).Find(&job)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &job, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
@@ -12,6 +13,13 @@ import (
 	"github.com/google/uuid"
 	"github.com/opendefender/openrisk/internal/core/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job queue types used by the marketplace
+const (
+	JobTypeMarketplaceSync = "marketplace_sync"
+	JobTypeMarketplaceLog  = "marketplace_log"
 )
 
 // MarketplaceService handles marketplace operations
@@ -22,9 +30,18 @@ type MarketplaceService struct {
 	installations map[string]*domain.MarketplaceApp
 	syncWorkers   map[string]context.CancelFunc
 	syncMu        sync.RWMutex
+	jobs          *JobQueueService
 	logger        *log.Logger
 }
 
+// marketplaceSyncJob is the job payload of an app sync
+type marketplaceSyncJob struct {
+	AppID     string `json:"app_id"`
+	UserID    string `json:"user_id"`
+	Interval  int    `json:"interval,omitempty"`
+	Scheduled bool   `json:"scheduled"` // Auto-sync run that schedules its successor
+}
+
 // NewMarketplaceService creates a new MarketplaceService
 func NewMarketplaceService(db *gorm.DB, logger *log.Logger) *MarketplaceService {
 	if logger == nil {
@@ -39,6 +56,34 @@ func NewMarketplaceService(db *gorm.DB, logger *log.Logger) *MarketplaceService
 	}
 }
 
+// UseJobQueue moves app syncs and activity logging onto the durable job queue.
+// Without a queue syncs run in-process and logs are written synchronously.
+func (m *MarketplaceService) UseJobQueue(jobs *JobQueueService) {
+	m.jobs = jobs
+	jobs.RegisterHandler(JobTypeMarketplaceSync, m.runSyncJob)
+	jobs.RegisterHandler(JobTypeMarketplaceLog, m.runLogJob)
+}
+
+// ResumeAutoSync makes sure every auto-syncing app has a scheduled sync job,
+// e.g. for apps configured before the job queue was introduced
+func (m *MarketplaceService) ResumeAutoSync(ctx context.Context) error {
+	if m.jobs == nil {
+		return nil
+	}
+
+	var apps []domain.MarketplaceApp
+	if err := m.db.WithContext(ctx).Where("auto_sync = ?", true).Find(&apps).Error; err != nil {
+		return fmt.Errorf("failed to list auto-sync apps: %w", err)
+	}
+
+	for i := range apps {
+		if err := m.scheduleSync(&apps[i], time.Now(), uuid.Nil); err != nil {
+			m.logger.Printf("Warning: failed to schedule sync for app %s: %v", apps[i].ID, err)
+		}
+	}
+	return nil
+}
+
 // RegisterConnector registers a new connector in the marketplace
 func (m *MarketplaceService) RegisterConnector(ctx context.Context, connector *domain.Connector) error {
 	m.mu.Lock()
@@ -347,9 +392,6 @@ func (m *MarketplaceService) UninstallApp(ctx context.Context, appID, userID str
 
 // UpdateAppSync updates sync configuration and starts/stops sync worker
 func (m *MarketplaceService) UpdateAppSync(ctx context.Context, appID, userID string, autoSync bool, syncInterval int) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	app, err := m.GetApp(ctx, appID)
 	if err != nil {
 		return err
@@ -377,14 +419,40 @@ func (m *MarketplaceService) UpdateAppSync(ctx context.Context, appID, userID st
 
 // TriggerSync manually triggers a sync for an app
 func (m *MarketplaceService) TriggerSync(ctx context.Context, appID, userID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	app, err := m.GetApp(ctx, appID)
 	if err != nil {
 		return err
 	}
 
+	return m.syncApp(ctx, app, userID)
+}
+
+// RequestSync queues a manual sync and returns its job. Without a job queue the
+// sync runs immediately and no job is returned.
+func (m *MarketplaceService) RequestSync(ctx context.Context, appID, userID string) (*domain.Job, error) {
+	if m.jobs == nil {
+		return nil, m.TriggerSync(ctx, appID, userID)
+	}
+
+	app, err := m.GetApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if !app.Enabled {
+		return nil, fmt.Errorf("cannot sync disabled app")
+	}
+
+	createdBy, _ := uuid.Parse(userID)
+	return m.jobs.Enqueue(JobTypeMarketplaceSync, marketplaceSyncJob{AppID: appID, UserID: userID}, EnqueueOptions{
+		CreatedBy:   createdBy,
+		MaxAttempts: 3,
+	})
+}
+
+// syncApp runs a sync of an installed app and records its status
+func (m *MarketplaceService) syncApp(ctx context.Context, app *domain.MarketplaceApp, userID string) error {
+	appID := app.ID
+
 	if !app.Enabled {
 		return fmt.Errorf("cannot sync disabled app")
 	}
@@ -472,6 +540,17 @@ func (m *MarketplaceService) AddConnectorReview(ctx context.Context, connectorID
 // Helper functions
 
 func (m *MarketplaceService) startSyncWorker(app *domain.MarketplaceApp) {
+	if m.jobs != nil {
+		// The queued run may use the previous interval: replace it
+		if err := m.cancelScheduledSyncs(app.ID); err != nil {
+			m.logger.Printf("Warning: failed to cancel scheduled syncs for app %s: %v", app.ID, err)
+		}
+		if err := m.scheduleSync(app, time.Now(), uuid.Nil); err != nil {
+			m.logger.Printf("Warning: failed to schedule sync for app %s: %v", app.ID, err)
+		}
+		return
+	}
+
 	m.syncMu.Lock()
 	defer m.syncMu.Unlock()
 
@@ -500,6 +579,12 @@ func (m *MarketplaceService) startSyncWorker(app *domain.MarketplaceApp) {
 }
 
 func (m *MarketplaceService) stopSyncWorker(appID string) {
+	if m.jobs != nil {
+		if err := m.cancelScheduledSyncs(appID); err != nil {
+			m.logger.Printf("Warning: failed to cancel scheduled syncs for app %s: %v", appID, err)
+		}
+	}
+
 	m.syncMu.Lock()
 	defer m.syncMu.Unlock()
 
@@ -510,21 +595,128 @@ func (m *MarketplaceService) stopSyncWorker(appID string) {
 }
 
 func (m *MarketplaceService) logAction(ctx context.Context, appID, userID, action string, details map[string]interface{}, status string) {
-	go func() {
-		log := &domain.MarketplaceLog{
-			ID:        uuid.New().String(),
-			AppID:     appID,
-			UserID:    userID,
-			Action:    action,
-			Details:   details,
-			Status:    status,
-			CreatedAt: time.Now(),
+	entry := &domain.MarketplaceLog{
+		ID:        uuid.New().String(),
+		AppID:     appID,
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+		Status:    status,
+		CreatedAt: time.Now(),
+	}
+
+	if m.jobs != nil {
+		createdBy, _ := uuid.Parse(userID)
+		_, err := m.jobs.Enqueue(JobTypeMarketplaceLog, entry, EnqueueOptions{
+			CreatedBy:      createdBy,
+			IdempotencyKey: "marketplace_log:" + entry.ID,
+		})
+		if err == nil {
+			return
 		}
+		m.logger.Printf("Warning: failed to queue action log, writing it directly: %v", err)
+	}
+
+	if err := m.db.WithContext(ctx).Create(entry).Error; err != nil {
+		m.logger.Printf("Warning: failed to log action: %v", err)
+	}
+}
+
+// scheduleSync queues the next auto-sync of an app one interval after from,
+// unless one is already pending (the job being run is excluded)
+func (m *MarketplaceService) scheduleSync(app *domain.MarketplaceApp, from time.Time, exclude uuid.UUID) error {
+	if app.SyncInterval <= 0 {
+		return fmt.Errorf("invalid sync interval: %d", app.SyncInterval)
+	}
+
+	prefix := fmt.Sprintf("%s:%s:", JobTypeMarketplaceSync, app.ID)
 
-		if err := m.db.WithContext(ctx).Create(log).Error; err != nil {
-			m.logger.Printf("Warning: failed to log action: %v", err)
+	var pending int64
+	if err := m.db.Model(&domain.Job{}).
+		Where("type = ? AND idempotency_key LIKE ? AND status IN ? AND id <> ?",
+			JobTypeMarketplaceSync, prefix+"%", []domain.JobStatus{domain.JobQueued, domain.JobRunning}, exclude).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	interval := time.Duration(app.SyncInterval) * time.Second
+	runAt := from.Add(interval)
+	createdBy, _ := uuid.Parse(app.UserID)
+
+	_, err := m.jobs.Enqueue(JobTypeMarketplaceSync, marketplaceSyncJob{
+		AppID:     app.ID,
+		UserID:    app.UserID,
+		Interval:  app.SyncInterval,
+		Scheduled: true,
+	}, EnqueueOptions{
+		CreatedBy:      createdBy,
+		IdempotencyKey: fmt.Sprintf("%s%d", prefix, runAt.Truncate(interval).Unix()),
+		MaxAttempts:    3,
+		RunAt:          runAt,
+	})
+	return err
+}
+
+// cancelScheduledSyncs cancels the queued auto-syncs of an app. Their idempotency keys
+// are released so the app can be scheduled again for the same run time.
+func (m *MarketplaceService) cancelScheduledSyncs(appID string) error {
+	prefix := fmt.Sprintf("%s:%s:", JobTypeMarketplaceSync, appID)
+	return m.db.Model(&domain.Job{}).
+		Where("type = ? AND idempotency_key LIKE ? AND status = ?", JobTypeMarketplaceSync, prefix+"%", domain.JobQueued).
+		Updates(map[string]interface{}{
+			"status":           domain.JobCancelled,
+			"cancel_requested": true,
+			"idempotency_key":  nil,
+			"finished_at":      time.Now(),
+		}).Error
+}
+
+// runSyncJob is the job queue handler for app syncs
+func (m *MarketplaceService) runSyncJob(ctx context.Context, job *domain.Job) error {
+	var payload marketplaceSyncJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid sync payload: %w", err))
+	}
+
+	app, err := m.GetApp(ctx, payload.AppID)
+	if err != nil {
+		return PermanentJobError(err)
+	}
+
+	if payload.Scheduled {
+		// Auto-sync was turned off since this run was scheduled
+		if !app.AutoSync {
+			return nil
 		}
-	}()
+		// Re-configured since: skip this run but keep the app scheduled at its new interval
+		if app.SyncInterval != payload.Interval {
+			return m.scheduleSync(app, time.Now(), job.ID)
+		}
+		if err := m.scheduleSync(app, time.Now(), job.ID); err != nil {
+			m.logger.Printf("Warning: failed to schedule next sync for app %s: %v", app.ID, err)
+		}
+		m.logger.Printf("Auto-sync triggered for app: %s", app.ID)
+	}
+
+	if !app.Enabled {
+		return PermanentJobError(fmt.Errorf("cannot sync disabled app"))
+	}
+
+	return m.syncApp(ctx, app, payload.UserID)
+}
+
+// runLogJob is the job queue handler writing marketplace activity logs
+func (m *MarketplaceService) runLogJob(ctx context.Context, job *domain.Job) error {
+	var entry domain.MarketplaceLog
+	if err := json.Unmarshal(job.Payload, &entry); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid log payload: %w", err))
+	}
+
+	// Retried attempts must not duplicate the entry
+	return m.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error
 }
 
 func generateRandomSecret(length int) (string, error) {
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrInvalidRecycleResource = errors.New("invalid recycle bin resource type")
+	ErrDeletedItemNotFound    = errors.New("deleted item not found")
+	ErrRestoreConflict        = errors.New("deleted item cannot be restored")
+)
+
+// RecycleBinService lists, restores and purges soft-deleted risks, assets,
+// mitigations, teams and custom fields. Deletions are recorded by the model
+// hooks (see domain.RecordDeletion); items older than the retention window
+// are purged by PurgeExpired.
+type RecycleBinService struct {
+	db        *gorm.DB
+	retention time.Duration
+}
+
+// NewRecycleBinService creates a new recycle bin service. A retention of
+// zero keeps deleted items until they are purged by hand.
+func NewRecycleBinService(db *gorm.DB, retention time.Duration) *RecycleBinService {
+	return &RecycleBinService{db: db, retention: retention}
+}
+
+// ParseRecycleResource reads a resource type as written in URLs: "risks",
+// "custom-fields", "custom_field"...
+func ParseRecycleResource(raw string) (domain.RecycleResource, error) {
+	name := strings.TrimSuffix(strings.ReplaceAll(strings.ToLower(raw), "-", "_"), "s")
+	for _, resource := range domain.RecycleResources {
+		if string(resource) == name {
+			return resource, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidRecycleResource, raw)
+}
+
+// List returns the deleted items of a type, most recently deleted first
+func (s *RecycleBinService) List(resource domain.RecycleResource, limit, offset int) ([]domain.DeletedItem, int64, error) {
+	var items []domain.DeletedItem
+	var total int64
+	query := s.db.Model(&domain.DeletedItem{}).Where("resource_type = ?", resource)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count deleted items: %w", err)
+	}
+	if err := query.Order("deleted_at DESC").Limit(limit).Offset(offset).Find(&items).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list deleted items: %w", err)
+	}
+	for i := range items {
+		s.setPurgeAt(&items[i])
+	}
+	return items, total, nil
+}
+
+// Get returns the recycle bin entry of a deleted resource
+func (s *RecycleBinService) Get(resource domain.RecycleResource, id uuid.UUID) (*domain.DeletedItem, error) {
+	item, err := findDeletedItem(s.db, resource, id)
+	if err != nil {
+		return nil, err
+	}
+	s.setPurgeAt(item)
+	return item, nil
+}
+
+// Restore undeletes a resource with what was deleted along with it: the
+// mitigations and asset links of a risk, the risk links of an asset, the
+// memberships of a team. Links to items deleted since are skipped. The
+// restore is attributed to the actor of ctx.
+func (s *RecycleBinService) Restore(ctx context.Context, resource domain.RecycleResource, id uuid.UUID) (*domain.DeletedItem, error) {
+	actor := domain.ActorFrom(ctx)
+	var item *domain.DeletedItem
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if item, err = findDeletedItem(tx.Clauses(clause.Locking{Strength: "UPDATE"}), resource, id); err != nil {
+			return err
+		}
+		cascade, err := item.Cascaded()
+		if err != nil {
+			return err
+		}
+
+		if resource == domain.RecycleMitigation {
+			var riskDeleted int64
+			if err := tx.Table("mitigations AS m").Joins("JOIN risks r ON r.id = m.risk_id").
+				Where("m.id = ? AND r.deleted_at IS NOT NULL", id).Count(&riskDeleted).Error; err != nil {
+				return fmt.Errorf("failed to check the mitigation's risk: %w", err)
+			}
+			if riskDeleted > 0 {
+				return fmt.Errorf("%w: its risk is deleted, restore the risk first", ErrRestoreConflict)
+			}
+		}
+
+		if err := undelete(tx, resource.Table(), []uuid.UUID{id}); err != nil {
+			return err
+		}
+		switch resource {
+		case domain.RecycleRisk:
+			if err := undelete(tx, "mitigations", cascade.MitigationIDs); err != nil {
+				return err
+			}
+			if err := relinkAssets(tx, id, cascade.AssetIDs); err != nil {
+				return err
+			}
+			if err := domain.RecordRiskVersion(tx, id); err != nil {
+				return err
+			}
+		case domain.RecycleAsset:
+			for _, riskID := range cascade.RiskIDs {
+				if err := relinkAssets(tx, riskID, []uuid.UUID{id}); err != nil {
+					return err
+				}
+				if err := domain.RecordRiskVersion(tx, riskID); err != nil {
+					return err
+				}
+			}
+		case domain.RecycleMitigation:
+			var riskIDs []uuid.UUID
+			if err := tx.Table("mitigations").Where("id = ?", id).Pluck("risk_id", &riskIDs).Error; err != nil {
+				return fmt.Errorf("failed to load the mitigation's risk: %w", err)
+			}
+			for _, riskID := range riskIDs {
+				if err := domain.RecordRiskVersion(tx, riskID); err != nil {
+					return err
+				}
+			}
+		case domain.RecycleTeam:
+			if err := undelete(tx, "team_members", cascade.MemberIDs); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(item).Error; err != nil {
+			return fmt.Errorf("failed to remove deleted item: %w", err)
+		}
+		return domain.AuditRecycle(tx, domain.ActionRestore, resource, id, actor, item.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Purge permanently deletes a resource in the recycle bin with what depends
+// on it; for a risk, its mitigations, versions and history
+func (s *RecycleBinService) Purge(ctx context.Context, resource domain.RecycleResource, id uuid.UUID) error {
+	actor := domain.ActorFrom(ctx)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		item, err := findDeletedItem(tx.Clauses(clause.Locking{Strength: "UPDATE"}), resource, id)
+		if err != nil {
+			return err
+		}
+
+		var statements []string
+		switch resource {
+		case domain.RecycleRisk:
+			statements = []string{
+				"DELETE FROM mitigation_subactions WHERE mitigation_id IN (SELECT id FROM mitigations WHERE risk_id = @id)",
+				"DELETE FROM mitigations WHERE risk_id = @id",
+				"DELETE FROM risk_assets WHERE risk_id = @id",
+				"DELETE FROM risk_versions WHERE risk_id = @id",
+				"DELETE FROM risk_histories WHERE risk_id = @id",
+				"DELETE FROM risk_enrichments WHERE risk_id = @id",
+			}
+		case domain.RecycleAsset:
+			statements = []string{
+				"DELETE FROM risk_assets WHERE asset_id = @id",
+				"DELETE FROM asset_relationships WHERE source_id = @id OR target_id = @id",
+				"DELETE FROM business_service_assets WHERE asset_id = @id",
+				"UPDATE assets SET parent_id = NULL WHERE parent_id = @id",
+				"DELETE FROM component_vulnerabilities WHERE asset_id = @id",
+				"DELETE FROM software_components WHERE asset_id = @id",
+				"DELETE FROM sbom_documents WHERE asset_id = @id",
+			}
+		case domain.RecycleMitigation:
+			statements = []string{"DELETE FROM mitigation_subactions WHERE mitigation_id = @id"}
+		case domain.RecycleTeam:
+			statements = []string{"DELETE FROM team_members WHERE team_id = @id"}
+		}
+		statements = append(statements, "DELETE FROM "+resource.Table()+" WHERE id = @id AND deleted_at IS NOT NULL")
+		for _, statement := range statements {
+			if err := tx.Exec(statement, map[string]interface{}{"id": id}).Error; err != nil {
+				return fmt.Errorf("failed to purge %s: %w", resource, err)
+			}
+		}
+
+		if err := tx.Delete(item).Error; err != nil {
+			return fmt.Errorf("failed to remove deleted item: %w", err)
+		}
+		return domain.AuditRecycle(tx, domain.ActionPurge, resource, id, actor, item.Name)
+	})
+}
+
+// PurgeExpired purges the items deleted longer than the retention window ago
+// and returns how many were purged
+func (s *RecycleBinService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	var items []domain.DeletedItem
+	if err := s.db.WithContext(ctx).Where("deleted_at < ?", now.Add(-s.retention)).
+		Order("deleted_at ASC").Find(&items).Error; err != nil {
+		return 0, fmt.Errorf("failed to list expired deleted items: %w", err)
+	}
+
+	purged := 0
+	ctx = domain.WithActor(ctx, domain.SystemActor)
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		if err := s.Purge(ctx, item.ResourceType, item.ResourceID); err != nil {
+			// Keep going: one item blocked by a reference must not stall the bin
+			log.Printf("Recycle bin: failed to purge %s %s: %v", item.ResourceType, item.ResourceID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (s *RecycleBinService) setPurgeAt(item *domain.DeletedItem) {
+	if s.retention > 0 {
+		purgeAt := item.DeletedAt.Add(s.retention)
+		item.PurgeAt = &purgeAt
+	}
+}
+
+func findDeletedItem(db *gorm.DB, resource domain.RecycleResource, id uuid.UUID) (*domain.DeletedItem, error) {
+	var item domain.DeletedItem
+	if err := db.Where("resource_type = ? AND resource_id = ?", resource, id).Take(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeletedItemNotFound
+		}
+		return nil, fmt.Errorf("failed to load deleted item: %w", err)
+	}
+	return &item, nil
+}
+
+// undelete clears deleted_at without running the model hooks, which would
+// record the restore as an update
+func undelete(tx *gorm.DB, table string, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Table(table).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+		return fmt.Errorf("failed to restore %s: %w", table, err)
+	}
+	return nil
+}
+
+// relinkAssets links a risk back to those of the assets that still exist
+func relinkAssets(tx *gorm.DB, riskID uuid.UUID, assetIDs []uuid.UUID) error {
+	if len(assetIDs) == 0 {
+		return nil
+	}
+	err := tx.Exec(`INSERT INTO risk_assets (risk_id, asset_id)
+		SELECT r.id, a.id FROM risks r, assets a
+		WHERE r.id = ? AND r.deleted_at IS NULL AND a.id IN ? AND a.deleted_at IS NULL
+		ON CONFLICT DO NOTHING`, riskID, assetIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to restore asset links: %w", err)
+	}
+	return nil
+}
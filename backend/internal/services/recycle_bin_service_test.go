@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestParseRecycleResource(t *testing.T) {
+	cases := map[string]domain.RecycleResource{
+		"risks":         domain.RecycleRisk,
+		"risk":          domain.RecycleRisk,
+		"Assets":        domain.RecycleAsset,
+		"mitigations":   domain.RecycleMitigation,
+		"teams":         domain.RecycleTeam,
+		"custom-fields": domain.RecycleCustomField,
+		"custom_field":  domain.RecycleCustomField,
+	}
+	for raw, want := range cases {
+		got, err := ParseRecycleResource(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+
+	for _, raw := range []string{"", "users", "incidents"} {
+		_, err := ParseRecycleResource(raw)
+		assert.True(t, errors.Is(err, ErrInvalidRecycleResource), raw)
+	}
+}
+
+func TestRecycleBinPurgeAt(t *testing.T) {
+	deletedAt := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	item := domain.DeletedItem{DeletedAt: deletedAt}
+	NewRecycleBinService(nil, 30*24*time.Hour).setPurgeAt(&item)
+	require.NotNil(t, item.PurgeAt)
+	assert.Equal(t, time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC), *item.PurgeAt)
+
+	item = domain.DeletedItem{DeletedAt: deletedAt}
+	NewRecycleBinService(nil, 0).setPurgeAt(&item)
+	assert.Nil(t, item.PurgeAt, "no retention means no auto-purge")
+}
+
+func TestDeletedItemCascaded(t *testing.T) {
+	item := domain.DeletedItem{}
+	cascade, err := item.Cascaded()
+	require.NoError(t, err)
+	assert.Empty(t, cascade.MitigationIDs)
+
+	mitigationID, assetID := uuid.New(), uuid.New()
+	item.Cascade = []byte(`{"mitigation_ids":["` + mitigationID.String() + `"],"asset_ids":["` + assetID.String() + `"]}`)
+	cascade, err = item.Cascaded()
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{mitigationID}, cascade.MitigationIDs)
+	assert.Equal(t, []uuid.UUID{assetID}, cascade.AssetIDs)
+	assert.Empty(t, cascade.RiskIDs)
+
+	item.Cascade = []byte(`not json`)
+	_, err = item.Cascaded()
+	assert.Error(t, err)
+}
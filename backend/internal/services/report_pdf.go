@@ -0,0 +1,49 @@
+package services
+
+import (
+	"embed"
+	"unicode/utf8"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// reportFont is the font family of generated PDFs. DejaVu covers Latin,
+// Cyrillic, Greek and many other scripts, so risk titles and owners render
+// as entered rather than through a single-byte code page.
+const reportFont = "DejaVu"
+
+//go:embed fonts/*.ttf
+var reportFonts embed.FS
+
+// newReportPDF creates an A4 portrait document with the report fonts embedded
+func newReportPDF() *fpdf.Fpdf {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	for style, file := range map[string]string{
+		"":  "fonts/DejaVuSansCondensed.ttf",
+		"B": "fonts/DejaVuSansCondensed-Bold.ttf",
+		"I": "fonts/DejaVuSansCondensed-Oblique.ttf",
+	} {
+		data, err := reportFonts.ReadFile(file)
+		if err != nil {
+			pdf.SetError(err)
+			return pdf
+		}
+		pdf.AddUTF8FontFromBytes(reportFont, style, data)
+	}
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 18)
+	return pdf
+}
+
+// fitPDFText shortens s with an ellipsis to fit a cell of the given width
+func fitPDFText(pdf *fpdf.Fpdf, s string, width float64) string {
+	max := width - 2
+	if pdf.GetStringWidth(s) <= max {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"…") > max {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s + "…"
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// reportDocument is the format independent content of a report; templates
+// build it from a snapshot and renderers turn it into PDF, DOCX or HTML
+type reportDocument struct {
+	Title       string
+	Subtitle    string
+	GeneratedAt time.Time
+	Sections    []reportSection
+}
+
+// reportSection is a heading followed by paragraphs, key figures and a table
+type reportSection struct {
+	Heading    string
+	Paragraphs []string
+	Facts      []reportFact
+	Table      *reportTable
+	PageBreak  bool // Start the section on a new page (PDF and DOCX)
+}
+
+type reportFact struct {
+	Label string
+	Value string
+}
+
+// reportTable is a table whose column widths are relative weights
+type reportTable struct {
+	Columns []string
+	Widths  []float64
+	Rows    [][]string
+	Empty   string // Shown instead of an empty table
+}
+
+// reportContentType returns the MIME type and file extension of a report format
+func reportContentType(format domain.ReportFormat) (string, string, error) {
+	switch format {
+	case domain.ReportFormatPDF:
+		return "application/pdf", "pdf", nil
+	case domain.ReportFormatDOCX:
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "docx", nil
+	case domain.ReportFormatHTML:
+		return "text/html; charset=utf-8", "html", nil
+	}
+	return "", "", fmt.Errorf("%w: unsupported report format %q (use pdf, docx or html)", ErrInvalidReport, format)
+}
+
+// renderReport writes doc to w in the given format
+func renderReport(doc *reportDocument, format domain.ReportFormat, w io.Writer) error {
+	switch format {
+	case domain.ReportFormatPDF:
+		return renderReportPDF(doc, w)
+	case domain.ReportFormatDOCX:
+		return renderReportDOCX(doc, w)
+	case domain.ReportFormatHTML:
+		return renderReportHTML(doc, w)
+	}
+	_, _, err := reportContentType(format)
+	return err
+}
+
+// --- PDF ---
+
+const (
+	pdfPageWidth  = 180.0 // A4 width minus margins, mm
+	pdfLineHeight = 6.0
+)
+
+func renderReportPDF(doc *reportDocument, w io.Writer) error {
+	pdf := newReportPDF()
+	pdf.AliasNbPages("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(reportFont, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(pdfPageWidth/2, 5, doc.Title, "", 0, "L", false, 0, "")
+		pdf.CellFormat(pdfPageWidth/2, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	pdf.AddPage()
+	pdf.SetFont(reportFont, "B", 18)
+	pdf.MultiCell(pdfPageWidth, 9, doc.Title, "", "L", false)
+	pdf.SetFont(reportFont, "", 10)
+	pdf.SetTextColor(90, 90, 90)
+	if doc.Subtitle != "" {
+		pdf.MultiCell(pdfPageWidth, 5, doc.Subtitle, "", "L", false)
+	}
+	pdf.MultiCell(pdfPageWidth, 5, "Generated "+doc.GeneratedAt.UTC().Format("2 January 2006 15:04 MST"), "", "L", false)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(4)
+
+	for i, section := range doc.Sections {
+		if section.PageBreak && i > 0 {
+			pdf.AddPage()
+		}
+		if section.Heading != "" {
+			pdf.Ln(2)
+			pdf.SetFont(reportFont, "B", 13)
+			pdf.MultiCell(pdfPageWidth, 7, section.Heading, "", "L", false)
+			pdf.Ln(1)
+		}
+		pdf.SetFont(reportFont, "", 10)
+		for _, p := range section.Paragraphs {
+			pdf.MultiCell(pdfPageWidth, 5, p, "", "L", false)
+			pdf.Ln(2)
+		}
+		if len(section.Facts) > 0 {
+			for _, fact := range section.Facts {
+				pdf.SetFont(reportFont, "B", 10)
+				pdf.CellFormat(70, pdfLineHeight, fitPDFText(pdf, fact.Label, 70), "B", 0, "L", false, 0, "")
+				pdf.SetFont(reportFont, "", 10)
+				pdf.CellFormat(pdfPageWidth-70, pdfLineHeight, fitPDFText(pdf, fact.Value, pdfPageWidth-70), "B", 1, "L", false, 0, "")
+			}
+			pdf.Ln(3)
+		}
+		if section.Table != nil {
+			renderPDFTable(pdf, section.Table)
+			pdf.Ln(3)
+		}
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render PDF: %w", err)
+	}
+	return pdf.Output(w)
+}
+
+func renderPDFTable(pdf *fpdf.Fpdf, table *reportTable) {
+	if len(table.Rows) == 0 {
+		if table.Empty != "" {
+			pdf.SetFont(reportFont, "I", 10)
+			pdf.MultiCell(pdfPageWidth, 5, table.Empty, "", "L", false)
+		}
+		return
+	}
+
+	widths := columnWidths(table, pdfPageWidth)
+	header := func() {
+		pdf.SetFont(reportFont, "B", 9)
+		pdf.SetFillColor(230, 233, 238)
+		for i, col := range table.Columns {
+			pdf.CellFormat(widths[i], pdfLineHeight, fitPDFText(pdf, col, widths[i]), "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(reportFont, "", 8.5)
+	}
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	header()
+	for _, row := range table.Rows {
+		// Rows are single line cells; break pages ourselves to repeat the header
+		if pdf.GetY()+pdfLineHeight > pageHeight-bottom-6 {
+			pdf.AddPage()
+			header()
+		}
+		for i := range table.Columns {
+			value := ""
+			if i < len(row) {
+				value = row[i]
+			}
+			pdf.CellFormat(widths[i], pdfLineHeight, fitPDFText(pdf, value, widths[i]), "1", 0, "L", false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+func columnWidths(table *reportTable, total float64) []float64 {
+	widths := make([]float64, len(table.Columns))
+	sum := 0.0
+	for i := range widths {
+		widths[i] = 1
+		if i < len(table.Widths) && table.Widths[i] > 0 {
+			widths[i] = table.Widths[i]
+		}
+		sum += widths[i]
+	}
+	for i := range widths {
+		widths[i] = widths[i] / sum * total
+	}
+	return widths
+}
+
+// --- DOCX ---
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/><Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/></Types>`
+
+const docxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`
+
+const docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:cs="Calibri"/><w:sz w:val="20"/></w:rPr></w:rPrDefault></w:docDefaults><w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:pPr><w:spacing w:after="120"/></w:pPr></w:style><w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:rPr><w:b/><w:sz w:val="40"/></w:rPr></w:style><w:style w:type="paragraph" w:styleId="Subtitle"><w:name w:val="Subtitle"/><w:basedOn w:val="Normal"/><w:rPr><w:color w:val="5A5A5A"/></w:rPr></w:style><w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style><w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:color="A0A0A0"/><w:left w:val="single" w:sz="4" w:color="A0A0A0"/><w:bottom w:val="single" w:sz="4" w:color="A0A0A0"/><w:right w:val="single" w:sz="4" w:color="A0A0A0"/><w:insideH w:val="single" w:sz="4" w:color="A0A0A0"/><w:insideV w:val="single" w:sz="4" w:color="A0A0A0"/></w:tblBorders></w:tblPr></w:style></w:styles>`
+
+// docxTableWidth is the usable page width in twentieths of a point (A4, 2cm margins)
+const docxTableWidth = 9638
+
+func renderReportDOCX(doc *reportDocument, w io.Writer) error {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	body.WriteString(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`)
+
+	docxParagraph(&body, "Title", doc.Title, false, false)
+	if doc.Subtitle != "" {
+		docxParagraph(&body, "Subtitle", doc.Subtitle, false, false)
+	}
+	docxParagraph(&body, "Subtitle", "Generated "+doc.GeneratedAt.UTC().Format("2 January 2006 15:04 MST"), false, false)
+
+	for i, section := range doc.Sections {
+		if section.Heading != "" {
+			docxParagraph(&body, "Heading1", section.Heading, false, section.PageBreak && i > 0)
+		}
+		for _, p := range section.Paragraphs {
+			docxParagraph(&body, "", p, false, false)
+		}
+		if len(section.Facts) > 0 {
+			facts := &reportTable{Widths: []float64{2, 3}, Columns: []string{"", ""}}
+			for _, fact := range section.Facts {
+				facts.Rows = append(facts.Rows, []string{fact.Label, fact.Value})
+			}
+			docxTable(&body, facts, false)
+		}
+		if section.Table != nil {
+			if len(section.Table.Rows) == 0 {
+				if section.Table.Empty != "" {
+					docxParagraph(&body, "", section.Table.Empty, true, false)
+				}
+			} else {
+				docxTable(&body, section.Table, true)
+			}
+		}
+	}
+
+	body.WriteString(`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="709" w:footer="709" w:gutter="0"/></w:sectPr></w:body></w:document>`)
+
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", body.String()},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("failed to write DOCX: %w", err)
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return fmt.Errorf("failed to write DOCX: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write DOCX: %w", err)
+	}
+	return nil
+}
+
+func docxParagraph(buf *bytes.Buffer, style, text string, italic, pageBreak bool) {
+	buf.WriteString("<w:p>")
+	if style != "" || pageBreak {
+		buf.WriteString("<w:pPr>")
+		if style != "" {
+			fmt.Fprintf(buf, `<w:pStyle w:val="%s"/>`, style)
+		}
+		if pageBreak {
+			buf.WriteString("<w:pageBreakBefore/>")
+		}
+		buf.WriteString("</w:pPr>")
+	}
+	docxRun(buf, text, false, italic)
+	buf.WriteString("</w:p>")
+}
+
+func docxRun(buf *bytes.Buffer, text string, bold, italic bool) {
+	buf.WriteString("<w:r>")
+	if bold || italic {
+		buf.WriteString("<w:rPr>")
+		if bold {
+			buf.WriteString("<w:b/>")
+		}
+		if italic {
+			buf.WriteString("<w:i/>")
+		}
+		buf.WriteString("</w:rPr>")
+	}
+	buf.WriteString(`<w:t xml:space="preserve">`)
+	xml.EscapeText(buf, []byte(text))
+	buf.WriteString("</w:t></w:r>")
+}
+
+func docxTable(buf *bytes.Buffer, table *reportTable, withHeader bool) {
+	widths := columnWidths(table, docxTableWidth)
+	fmt.Fprintf(buf, `<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="%d" w:type="dxa"/></w:tblPr><w:tblGrid>`, docxTableWidth)
+	for _, width := range widths {
+		fmt.Fprintf(buf, `<w:gridCol w:w="%d"/>`, int(width))
+	}
+	buf.WriteString("</w:tblGrid>")
+
+	row := func(values []string, header bool) {
+		buf.WriteString("<w:tr>")
+		if header {
+			buf.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		for i, width := range widths {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			fmt.Fprintf(buf, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, int(width))
+			if header {
+				buf.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="E6E9EE"/>`)
+			}
+			buf.WriteString("</w:tcPr><w:p>")
+			// The first column of a key figures table is its label
+			docxRun(buf, value, header || (!withHeader && i == 0), false)
+			buf.WriteString("</w:p></w:tc>")
+		}
+		buf.WriteString("</w:tr>")
+	}
+
+	if withHeader {
+		row(table.Columns, true)
+	}
+	for _, values := range table.Rows {
+		row(values, false)
+	}
+	buf.WriteString("</w:tbl><w:p/>")
+}
+
+// --- HTML ---
+
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.UTC().Format("2 January 2006 15:04 MST") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2933; max-width: 1100px; margin: 2rem auto; padding: 0 1.5rem; }
+h1 { margin-bottom: .25rem; }
+.subtitle { color: #5a5a5a; margin: 0; }
+h2 { margin-top: 2rem; border-bottom: 1px solid #d9dde3; padding-bottom: .25rem; }
+table { border-collapse: collapse; width: 100%; margin: .75rem 0; font-size: .9rem; }
+th, td { border: 1px solid #d9dde3; padding: .35rem .5rem; text-align: left; vertical-align: top; }
+th { background: #e6e9ee; }
+table.facts th { width: 40%; background: none; }
+.empty { font-style: italic; color: #5a5a5a; }
+@media print { h2.break { page-break-before: always; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Subtitle}}<p class="subtitle">{{.Subtitle}}</p>{{end}}
+<p class="subtitle">Generated {{date .GeneratedAt}}</p>
+{{range .Sections}}
+<section>
+{{if .Heading}}<h2{{if .PageBreak}} class="break"{{end}}>{{.Heading}}</h2>{{end}}
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end}}
+{{if .Facts}}<table class="facts">{{range .Facts}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>{{end}}</table>{{end}}
+{{with .Table}}{{if .Rows}}<table>
+<thead><tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>{{else if .Empty}}<p class="empty">{{.Empty}}</p>{{end}}{{end}}
+</section>
+{{end}}
+</body>
+</html>
+`))
+
+func renderReportHTML(doc *reportDocument, w io.Writer) error {
+	if err := reportHTMLTemplate.Execute(w, doc); err != nil {
+		return fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return nil
+}
+
+// reportFileName is the download name of a report artifact
+func reportFileName(title string, format domain.ReportFormat) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '-'
+		}
+		return -1
+	}, title)
+	if name == "" {
+		name = "report"
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return strings.ToLower(name) + "." + string(format)
+}
+
+// ReportFileType returns the MIME type and download name of a report artifact
+func ReportFileType(report *domain.Report) (string, string) {
+	contentType, _, err := reportContentType(report.Format)
+	if err != nil {
+		contentType = "application/octet-stream"
+	}
+	return contentType, reportFileName(report.Title, report.Format)
+}
@@ -0,0 +1,826 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/database"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+	"github.com/opendefender/openrisk/internal/filter"
+)
+
+// JobTypeReportGenerate is the job queue type that renders reports
+const JobTypeReportGenerate = "report_generate"
+
+// reportAttachmentLimit is the largest report sent as an email attachment;
+// bigger reports are linked instead
+const reportAttachmentLimit = 15 << 20
+
+// defaultReportDays is the period covered when a request gives no start date
+const defaultReportDays = 30
+
+var (
+	ErrReportNotFound         = errors.New("report not found")
+	ErrReportScheduleNotFound = errors.New("report schedule not found")
+	ErrInvalidReport          = errors.New("invalid report")
+	ErrReportNotReady         = errors.New("report is not available")
+	ErrReportDelivery         = errors.New("report delivery failed")
+	ErrMailerNotConfigured    = errors.New("email delivery is not configured")
+)
+
+// ReportService generates reports from risk snapshots, stores the rendered
+// artifacts and runs report schedules
+type ReportService struct {
+	db      *gorm.DB
+	jobs    *JobQueueService
+	blobs   ports.BlobStore
+	mailer  ports.Mailer
+	filters *RiskFilterService
+}
+
+// reportJob is the job payload of a report generation
+type reportJob struct {
+	ReportID uuid.UUID `json:"report_id"`
+}
+
+// NewReportService creates a new report service and registers its job handler
+// on the queue. Artifacts are written to blobs; mailer may be nil, in which
+// case reports with recipients record a delivery error.
+func NewReportService(jobs *JobQueueService, blobs ports.BlobStore, mailer ports.Mailer) *ReportService {
+	s := &ReportService{
+		db:      database.DB,
+		jobs:    jobs,
+		blobs:   blobs,
+		mailer:  mailer,
+		filters: NewRiskFilterService(),
+	}
+	jobs.RegisterHandler(JobTypeReportGenerate, s.runReportJob)
+	return s
+}
+
+// GenerateReportInput requests a report. Dates are YYYY-MM-DD or RFC 3339; a
+// date-only period end includes that whole day.
+type GenerateReportInput struct {
+	Template    domain.ReportTemplate `json:"template"`
+	Format      domain.ReportFormat   `json:"format"`
+	Title       string                `json:"title"`
+	PeriodStart string                `json:"period_start"`
+	PeriodEnd   string                `json:"period_end"`
+	Filter      string                `json:"filter"`
+	Framework   string                `json:"framework"`
+	Recipients  []string              `json:"recipients"`
+}
+
+// ReportListOptions filters and pages the report list
+type ReportListOptions struct {
+	Type       string
+	Status     string
+	ScheduleID *uuid.UUID
+	Page       int
+	Limit      int
+}
+
+// ReportStats counts the reports of a tenant
+type ReportStats struct {
+	TotalReports    int64 `json:"total_reports"`
+	CompletedCount  int64 `json:"completed"`
+	GeneratingCount int64 `json:"generating"` // Pending or generating
+	FailedCount     int64 `json:"failed"`
+	ScheduledCount  int64 `json:"scheduled"` // Enabled schedules
+}
+
+// Generate validates a request and queues the report
+func (s *ReportService) Generate(tenantID, userID uuid.UUID, input GenerateReportInput) (*domain.Report, error) {
+	if input.Format == "" {
+		input.Format = domain.ReportFormatPDF
+	}
+	recipients, err := validateReportRequest(input.Template, input.Format, input.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	params, err := reportParameters(input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.compileFilter(params, s.filters.Context(userID)); err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		title = defaultReportTitle(input.Template, params)
+	}
+	report, err := newReport(tenantID, userID, title, input.Template, input.Format, params)
+	if err != nil {
+		return nil, err
+	}
+	report.Recipients = recipients
+
+	return s.queue(report)
+}
+
+// Regenerate renders an existing report again, optionally in another format.
+// The new report reuses the snapshot, so its figures are identical.
+func (s *ReportService) Regenerate(tenantID, userID, id uuid.UUID, format domain.ReportFormat) (*domain.Report, error) {
+	original, err := s.GetReport(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.SnapshotID == nil {
+		return nil, ErrReportNotReady
+	}
+	if format == "" {
+		format = original.Format
+	}
+	if _, _, err := reportContentType(format); err != nil {
+		return nil, err
+	}
+
+	report := &domain.Report{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Title:       original.Title,
+		Type:        original.Type,
+		Format:      format,
+		Status:      domain.ReportStatusPending,
+		Parameters:  original.Parameters,
+		SnapshotID:  original.SnapshotID,
+		GeneratedBy: userID,
+	}
+	return s.queue(report)
+}
+
+func newReport(tenantID, userID uuid.UUID, title string, template domain.ReportTemplate, format domain.ReportFormat, params domain.ReportParameters) (*domain.Report, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report parameters: %w", err)
+	}
+	return &domain.Report{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Title:       title,
+		Type:        template,
+		Format:      format,
+		Status:      domain.ReportStatusPending,
+		Parameters:  datatypes.JSON(encoded),
+		GeneratedBy: userID,
+	}, nil
+}
+
+// queue saves a pending report and enqueues its generation
+func (s *ReportService) queue(report *domain.Report) (*domain.Report, error) {
+	if err := s.db.Create(report).Error; err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	job, err := s.jobs.Enqueue(JobTypeReportGenerate, reportJob{ReportID: report.ID}, EnqueueOptions{
+		TenantID:       report.TenantID,
+		CreatedBy:      report.GeneratedBy,
+		IdempotencyKey: fmt.Sprintf("report:%s", report.ID),
+		MaxAttempts:    3,
+	})
+	if err != nil {
+		s.db.Model(report).Updates(map[string]interface{}{
+			"status":        domain.ReportStatusFailed,
+			"error_message": err.Error(),
+		})
+		return nil, err
+	}
+
+	report.JobID = &job.ID
+	s.db.Model(report).Update("job_id", job.ID)
+	return report, nil
+}
+
+// validateReportRequest checks the template, format and distribution list and
+// returns the normalized recipient addresses
+func validateReportRequest(template domain.ReportTemplate, format domain.ReportFormat, recipients []string) ([]string, error) {
+	if _, ok := reportTemplateNames[template]; !ok {
+		return nil, fmt.Errorf("%w: unknown template %q", ErrInvalidReport, template)
+	}
+	if _, _, err := reportContentType(format); err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(recipients))
+	seen := map[string]bool{}
+	for _, recipient := range recipients {
+		addr, err := mail.ParseAddress(strings.TrimSpace(recipient))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid recipient %q", ErrInvalidReport, recipient)
+		}
+		email := strings.ToLower(addr.Address)
+		if !seen[email] {
+			seen[email] = true
+			addresses = append(addresses, email)
+		}
+	}
+	return addresses, nil
+}
+
+// reportParameters resolves the requested period; it defaults to the 30 days
+// up to now
+func reportParameters(input GenerateReportInput, now time.Time) (domain.ReportParameters, error) {
+	params := domain.ReportParameters{
+		PeriodEnd: now.UTC(),
+		Filter:    strings.TrimSpace(input.Filter),
+		Framework: strings.TrimSpace(input.Framework),
+	}
+	if input.PeriodEnd != "" {
+		end, err := parseReportDate(input.PeriodEnd, true)
+		if err != nil {
+			return params, err
+		}
+		params.PeriodEnd = end
+	}
+	params.PeriodStart = params.PeriodEnd.AddDate(0, 0, -defaultReportDays)
+	if input.PeriodStart != "" {
+		start, err := parseReportDate(input.PeriodStart, false)
+		if err != nil {
+			return params, err
+		}
+		params.PeriodStart = start
+	}
+	if !params.PeriodStart.Before(params.PeriodEnd) {
+		return params, fmt.Errorf("%w: period_start must be before period_end", ErrInvalidReport)
+	}
+	return params, nil
+}
+
+func parseReportDate(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q, expected YYYY-MM-DD or RFC 3339", ErrInvalidReport, value)
+	}
+	if end {
+		// Period ends are exclusive
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func defaultReportTitle(template domain.ReportTemplate, params domain.ReportParameters) string {
+	title := fmt.Sprintf("%s %s to %s", reportTemplateNames[template],
+		params.PeriodStart.UTC().Format("2006-01-02"), periodEndDisplay(params.PeriodEnd).Format("2006-01-02"))
+	if params.Framework != "" {
+		title += " (" + params.Framework + ")"
+	}
+	return title
+}
+
+// compileFilter combines the report filter with its framework restriction
+func (s *ReportService) compileFilter(params domain.ReportParameters, fctx *RiskFilterContext) (*RiskFilter, error) {
+	expression := params.Filter
+	if params.Framework != "" {
+		expression = filter.And(expression, "frameworks HAS "+filter.Quote(params.Framework))
+	}
+	return s.filters.Compile(expression, fctx)
+}
+
+// runReportJob is the job queue handler for report generation
+func (s *ReportService) runReportJob(ctx context.Context, job *domain.Job) error {
+	var payload reportJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid report payload: %w", err))
+	}
+
+	report := &domain.Report{}
+	if err := s.db.First(report, "id = ?", payload.ReportID).Error; err != nil {
+		return PermanentJobError(fmt.Errorf("report %s not found: %w", payload.ReportID, err))
+	}
+
+	s.db.Model(report).Updates(map[string]interface{}{
+		"status":        domain.ReportStatusGenerating,
+		"job_id":        job.ID,
+		"error_message": "",
+	})
+
+	data, err := s.generate(ctx, report)
+	if err != nil {
+		s.db.Model(report).Updates(map[string]interface{}{
+			"status":        domain.ReportStatusFailed,
+			"error_message": err.Error(),
+		})
+		return err
+	}
+	log.Printf("📄 Report generated: %s (%s, %d bytes)", report.ID, report.Type, report.Size)
+
+	// A retried job must not email the distribution list twice
+	if len(report.Recipients) > 0 && report.DeliveredAt == nil {
+		s.deliver(ctx, report, data)
+	}
+	return nil
+}
+
+// generate renders a report from its snapshot, taking the snapshot first if
+// the report has none, and stores the artifact
+func (s *ReportService) generate(ctx context.Context, report *domain.Report) ([]byte, error) {
+	snap, err := s.snapshot(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := buildReportDocument(report.Type, report.Title, snap)
+	if err != nil {
+		return nil, PermanentJobError(err)
+	}
+	contentType, ext, err := reportContentType(report.Format)
+	if err != nil {
+		return nil, PermanentJobError(err)
+	}
+
+	var buf bytes.Buffer
+	if err := renderReport(doc, report.Format, &buf); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+
+	key := fmt.Sprintf("reports/%s.%s", report.ID, ext)
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store report: %w", err)
+	}
+
+	now := time.Now()
+	report.Status = domain.ReportStatusCompleted
+	report.ArtifactKey = key
+	report.Size = int64(buf.Len())
+	report.GeneratedAt = &now
+	if err := s.db.Model(report).Updates(map[string]interface{}{
+		"status":        report.Status,
+		"artifact_key":  key,
+		"size":          report.Size,
+		"generated_at":  now,
+		"error_message": "",
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// snapshot loads the report's snapshot, or collects and persists one. The
+// filter is evaluated as the report's creator at request time, like bulk
+// operations, so a delayed job reports on what was requested.
+func (s *ReportService) snapshot(ctx context.Context, report *domain.Report) (*reportSnapshot, error) {
+	if report.SnapshotID != nil {
+		audit := &domain.RiskAuditReport{}
+		if err := s.db.WithContext(ctx).First(audit, "id = ?", *report.SnapshotID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, PermanentJobError(fmt.Errorf("report snapshot %s not found", *report.SnapshotID))
+			}
+			return nil, fmt.Errorf("failed to load report snapshot: %w", err)
+		}
+		snap, err := snapshotFromAuditReport(audit)
+		if err != nil {
+			return nil, PermanentJobError(err)
+		}
+		return snap, nil
+	}
+
+	var params domain.ReportParameters
+	if err := json.Unmarshal(report.Parameters, &params); err != nil {
+		return nil, PermanentJobError(fmt.Errorf("invalid report parameters: %w", err))
+	}
+	fctx := s.filters.Context(report.GeneratedBy)
+	fctx.Now = report.CreatedAt
+	riskFilter, err := s.compileFilter(params, fctx)
+	if err != nil {
+		var filterErr *filter.Error
+		if errors.As(err, &filterErr) {
+			return nil, PermanentJobError(err)
+		}
+		return nil, err
+	}
+
+	snap, err := collectReportSnapshot(ctx, s.db, riskFilter, params, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	audit, err := snap.auditReport(report.TenantID, report.GeneratedBy, report.Title, report.Type)
+	if err != nil {
+		return nil, PermanentJobError(err)
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(audit).Error; err != nil {
+			return err
+		}
+		return tx.Model(report).Update("snapshot_id", audit.ID).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save report snapshot: %w", err)
+	}
+	report.SnapshotID = &audit.ID
+	return snap, nil
+}
+
+// deliver emails a generated report to its recipients and records the outcome
+func (s *ReportService) deliver(ctx context.Context, report *domain.Report, data []byte) error {
+	err := s.send(ctx, report, data)
+	if err != nil {
+		log.Printf("⚠️ Report %s delivery failed: %v", report.ID, err)
+		report.DeliveryError = err.Error()
+		s.db.Model(report).Update("delivery_error", report.DeliveryError)
+		return fmt.Errorf("%w: %v", ErrReportDelivery, err)
+	}
+
+	now := time.Now()
+	report.DeliveredAt = &now
+	report.DeliveryError = ""
+	s.db.Model(report).Updates(map[string]interface{}{
+		"delivered_at":   now,
+		"delivery_error": "",
+	})
+	return nil
+}
+
+func (s *ReportService) send(ctx context.Context, report *domain.Report, data []byte) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+	contentType, _, err := reportContentType(report.Format)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("%s\n\nGenerated by OpenRisk on %s.\n", report.Title, report.GeneratedAt.UTC().Format("2 January 2006 15:04 MST"))
+	msg := ports.EmailMessage{To: report.Recipients, Subject: report.Title}
+	if len(data) <= reportAttachmentLimit {
+		msg.Attachments = []ports.Attachment{{
+			Filename:    reportFileName(report.Title, report.Format),
+			ContentType: contentType,
+			Data:        data,
+		}}
+	} else {
+		body += fmt.Sprintf("\nThe report is too large to attach. Download it from OpenRisk: /api/v1/reports/%s/download\n", report.ID)
+	}
+	msg.Body = body
+	return s.mailer.Send(ctx, msg)
+}
+
+// Deliver emails a completed report again, to recipients when given or else
+// to its distribution list
+func (s *ReportService) Deliver(ctx context.Context, tenantID, id uuid.UUID, recipients []string) (*domain.Report, error) {
+	report, err := s.GetReport(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) > 0 {
+		addresses, err := validateReportRequest(report.Type, report.Format, recipients)
+		if err != nil {
+			return nil, err
+		}
+		report.Recipients = addresses
+		s.db.Model(report).Update("recipients", report.Recipients)
+	}
+	if len(report.Recipients) == 0 {
+		return nil, fmt.Errorf("%w: the report has no recipients", ErrInvalidReport)
+	}
+
+	body, _, err := s.OpenArtifact(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	return report, s.deliver(ctx, report, data)
+}
+
+// ListReports lists the reports of a tenant, newest first
+func (s *ReportService) ListReports(tenantID uuid.UUID, opts ReportListOptions) ([]domain.Report, int64, error) {
+	query := s.db.Model(&domain.Report{}).Where("tenant_id = ?", tenantID)
+	if opts.Type != "" {
+		query = query.Where("type = ?", opts.Type)
+	}
+	if opts.Status != "" {
+		query = query.Where("status = ?", strings.ToUpper(opts.Status))
+	}
+	if opts.ScheduleID != nil {
+		query = query.Where("schedule_id = ?", *opts.ScheduleID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+	reports := []domain.Report{}
+	if err := query.Order("created_at DESC").Offset((opts.Page - 1) * opts.Limit).Limit(opts.Limit).Find(&reports).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list reports: %w", err)
+	}
+	return reports, total, nil
+}
+
+// GetReport returns a report of the tenant
+func (s *ReportService) GetReport(tenantID, id uuid.UUID) (*domain.Report, error) {
+	report := &domain.Report{}
+	if err := s.db.First(report, "id = ? AND tenant_id = ?", id, tenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+// OpenArtifact opens the rendered file of a completed report
+func (s *ReportService) OpenArtifact(ctx context.Context, tenantID, id uuid.UUID) (io.ReadCloser, *domain.Report, error) {
+	report, err := s.GetReport(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if report.Status != domain.ReportStatusCompleted || report.ArtifactKey == "" {
+		return nil, nil, ErrReportNotReady
+	}
+
+	body, err := s.blobs.Get(ctx, report.ArtifactKey)
+	if err != nil {
+		if errors.Is(err, ports.ErrBlobNotFound) {
+			return nil, nil, ErrReportNotReady
+		}
+		return nil, nil, err
+	}
+	return body, report, nil
+}
+
+// DeleteReport deletes a report and its artifact. The snapshot is kept: it is
+// part of the audit trail and other reports may render it.
+func (s *ReportService) DeleteReport(ctx context.Context, tenantID, id uuid.UUID) error {
+	report, err := s.GetReport(tenantID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(report).Error; err != nil {
+		return fmt.Errorf("failed to delete report: %w", err)
+	}
+	if report.ArtifactKey != "" {
+		if err := s.blobs.Delete(ctx, report.ArtifactKey); err != nil && !errors.Is(err, ports.ErrBlobNotFound) {
+			log.Printf("⚠️ Failed to delete report artifact %s: %v", report.ArtifactKey, err)
+		}
+	}
+	return nil
+}
+
+// Stats counts the reports and schedules of a tenant
+func (s *ReportService) Stats(tenantID uuid.UUID) (*ReportStats, error) {
+	var rows []struct {
+		Status domain.ReportStatus
+		Count  int64
+	}
+	if err := s.db.Model(&domain.Report{}).Select("status, COUNT(*) AS count").
+		Where("tenant_id = ?", tenantID).Group("status").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	stats := &ReportStats{}
+	for _, row := range rows {
+		stats.TotalReports += row.Count
+		switch row.Status {
+		case domain.ReportStatusCompleted:
+			stats.CompletedCount += row.Count
+		case domain.ReportStatusPending, domain.ReportStatusGenerating:
+			stats.GeneratingCount += row.Count
+		case domain.ReportStatusFailed:
+			stats.FailedCount += row.Count
+		}
+	}
+	if err := s.db.Model(&domain.ReportSchedule{}).Where("tenant_id = ? AND enabled", tenantID).
+		Count(&stats.ScheduledCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count report schedules: %w", err)
+	}
+	return stats, nil
+}
+
+// ReportScheduleInput is the editable part of a report schedule
+type ReportScheduleInput struct {
+	Name       string                `json:"name"`
+	Template   domain.ReportTemplate `json:"template"`
+	Format     domain.ReportFormat   `json:"format"`
+	Schedule   string                `json:"schedule"`
+	Period     domain.ReportPeriod   `json:"period"`
+	Filter     string                `json:"filter"`
+	Framework  string                `json:"framework"`
+	Recipients []string              `json:"recipients"`
+	Enabled    *bool                 `json:"enabled"`
+}
+
+// ListSchedules lists the report schedules of a tenant
+func (s *ReportService) ListSchedules(tenantID uuid.UUID) ([]domain.ReportSchedule, error) {
+	schedules := []domain.ReportSchedule{}
+	if err := s.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list report schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns a report schedule of the tenant
+func (s *ReportService) GetSchedule(tenantID, id uuid.UUID) (*domain.ReportSchedule, error) {
+	schedule := &domain.ReportSchedule{}
+	if err := s.db.First(schedule, "id = ? AND tenant_id = ?", id, tenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportScheduleNotFound
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// CreateSchedule creates a report schedule, enabled unless stated otherwise
+func (s *ReportService) CreateSchedule(tenantID, userID uuid.UUID, input ReportScheduleInput) (*domain.ReportSchedule, error) {
+	schedule := &domain.ReportSchedule{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Enabled:   true,
+		CreatedBy: userID,
+	}
+	if err := s.applyScheduleInput(schedule, userID, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create report schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule replaces the settings of a report schedule
+func (s *ReportService) UpdateSchedule(tenantID, userID, id uuid.UUID, input ReportScheduleInput) (*domain.ReportSchedule, error) {
+	schedule, err := s.GetSchedule(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyScheduleInput(schedule, userID, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update report schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule deletes a report schedule; reports it generated are kept
+func (s *ReportService) DeleteSchedule(tenantID, id uuid.UUID) error {
+	result := s.db.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&domain.ReportSchedule{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete report schedule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportScheduleNotFound
+	}
+	return nil
+}
+
+func (s *ReportService) applyScheduleInput(schedule *domain.ReportSchedule, userID uuid.UUID, input ReportScheduleInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidReport)
+	}
+	if input.Format == "" {
+		input.Format = domain.ReportFormatPDF
+	}
+	if input.Period == "" {
+		input.Period = domain.ReportPeriodLastMonth
+	}
+	recipients, err := validateReportRequest(input.Template, input.Format, input.Recipients)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("%w: a schedule needs at least one recipient", ErrInvalidReport)
+	}
+	parsed, err := domain.ParseSchedule(input.Schedule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+	if _, _, err := reportPeriodRange(input.Period, time.Now()); err != nil {
+		return err
+	}
+	params := domain.ReportParameters{Filter: strings.TrimSpace(input.Filter), Framework: strings.TrimSpace(input.Framework)}
+	if _, err := s.compileFilter(params, s.filters.Context(userID)); err != nil {
+		return err
+	}
+
+	if schedule.Schedule != input.Schedule || schedule.NextRunAt.IsZero() {
+		schedule.NextRunAt = parsed.Next(time.Now())
+	}
+	schedule.Name = name
+	schedule.Template = input.Template
+	schedule.Format = input.Format
+	schedule.Schedule = input.Schedule
+	schedule.Period = input.Period
+	schedule.Filter = params.Filter
+	schedule.Framework = params.Framework
+	schedule.Recipients = recipients
+	if input.Enabled != nil {
+		schedule.Enabled = *input.Enabled
+	}
+	return nil
+}
+
+// reportPeriodRange returns the period a schedule run at the given time
+// reports on, in UTC with an exclusive end
+func reportPeriodRange(period domain.ReportPeriod, at time.Time) (time.Time, time.Time, error) {
+	at = at.UTC()
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case domain.ReportPeriodLastWeek:
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, -7), monday, nil
+	case domain.ReportPeriodLastMonth:
+		first := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return first.AddDate(0, -1, 0), first, nil
+	case domain.ReportPeriodLastQuarter:
+		first := time.Date(at.Year(), at.Month()-(at.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+		return first.AddDate(0, -3, 0), first, nil
+	case domain.ReportPeriodLast30Days:
+		return at.AddDate(0, 0, -30), at, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w: unknown period %q", ErrInvalidReport, period)
+}
+
+// RunSchedule generates a schedule's report now, without moving its next run
+func (s *ReportService) RunSchedule(tenantID, id uuid.UUID) (*domain.Report, error) {
+	schedule, err := s.GetSchedule(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.runSchedule(schedule, time.Now())
+}
+
+// RunDueSchedules generates the reports of every schedule that is due. Each
+// run is claimed by moving next_run_at, so only one server instance generates it.
+func (s *ReportService) RunDueSchedules(ctx context.Context) error {
+	now := time.Now()
+	var due []domain.ReportSchedule
+	if err := s.db.WithContext(ctx).Where("enabled AND next_run_at <= ?", now).Find(&due).Error; err != nil {
+		return fmt.Errorf("failed to load due report schedules: %w", err)
+	}
+
+	var errs []error
+	for i := range due {
+		schedule := &due[i]
+		parsed, err := domain.ParseSchedule(schedule.Schedule)
+		if err != nil {
+			// Disable rather than retry a schedule that can never run
+			s.db.Model(schedule).Update("enabled", false)
+			errs = append(errs, fmt.Errorf("report schedule %s: %w", schedule.ID, err))
+			continue
+		}
+
+		claim := s.db.Model(&domain.ReportSchedule{}).
+			Where("id = ? AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+			Update("next_run_at", parsed.Next(now))
+		if claim.Error != nil {
+			errs = append(errs, claim.Error)
+			continue
+		}
+		if claim.RowsAffected == 0 {
+			continue // Claimed by another instance
+		}
+
+		if _, err := s.runSchedule(schedule, now); err != nil {
+			errs = append(errs, fmt.Errorf("report schedule %s: %w", schedule.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ReportService) runSchedule(schedule *domain.ReportSchedule, at time.Time) (*domain.Report, error) {
+	start, end, err := reportPeriodRange(schedule.Period, at)
+	if err != nil {
+		return nil, err
+	}
+	params := domain.ReportParameters{PeriodStart: start, PeriodEnd: end, Filter: schedule.Filter, Framework: schedule.Framework}
+	title := fmt.Sprintf("%s %s to %s", schedule.Name, start.Format("2006-01-02"), periodEndDisplay(end).Format("2006-01-02"))
+
+	report, err := newReport(schedule.TenantID, schedule.CreatedBy, title, schedule.Template, schedule.Format, params)
+	if err != nil {
+		return nil, err
+	}
+	report.ScheduleID = &schedule.ID
+	report.Recipients = schedule.Recipients
+	if report, err = s.queue(report); err != nil {
+		return nil, err
+	}
+
+	s.db.Model(schedule).Updates(map[string]interface{}{
+		"last_run_at":    at,
+		"last_report_id": report.ID,
+	})
+	return report, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReportSnapshot() *reportSnapshot {
+	asOf := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	overdue := asOf.AddDate(0, 0, -5)
+	snap := &reportSnapshot{
+		Metrics: reportMetrics{
+			AsOf:        asOf,
+			PeriodStart: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			PeriodEnd:   time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Risks: []reportRisk{
+			{ID: uuid.New(), Title: "Ransomware <on> file servers", Status: domain.StatusActive, Score: 20, Level: "CRITICAL",
+				Frameworks: []string{"ISO27001"}, CreatedAt: asOf.AddDate(0, 0, -20), UpdatedAt: asOf,
+				Mitigations: []reportMitigation{{ID: uuid.New(), Title: "Offline backups", Status: domain.MitigationPlanned, DueDate: &overdue}}},
+			{ID: uuid.New(), Title: "Phishing", Status: domain.StatusMitigated, Score: 8, Level: "MEDIUM",
+				Frameworks: []string{"ISO27001", "NIST"}, CreatedAt: asOf.AddDate(-1, 0, 0), UpdatedAt: asOf.AddDate(0, 0, -15),
+				Mitigations: []reportMitigation{{ID: uuid.New(), Title: "Awareness training", Status: domain.MitigationDone, Progress: 100}}},
+		},
+		ByStatus: map[string]int{},
+		ByLevel:  map[string]int{},
+	}
+	snap.compute()
+	return snap
+}
+
+func TestReportSnapshotCompute(t *testing.T) {
+	snap := testReportSnapshot()
+
+	assert.Equal(t, 1, snap.ByLevel["CRITICAL"], "only open risks are counted by level")
+	assert.Equal(t, 1, snap.Metrics.NewRisks)
+	assert.Equal(t, 1, snap.Metrics.ClosedRisks)
+	assert.Equal(t, reportTreatmentCounts{Active: 1, Completed: 1, Overdue: 1}, snap.Treatments)
+	require.Len(t, snap.Frameworks, 2)
+	assert.Equal(t, "ISO27001", snap.Frameworks[0].Framework, "frameworks with untreated risks come first")
+	assert.Equal(t, 1, snap.Frameworks[0].Untreated)
+	assert.Contains(t, snap.Findings[0], "Ransomware")
+}
+
+func TestReportSnapshotRoundTrip(t *testing.T) {
+	snap := testReportSnapshot()
+	audit, err := snap.auditReport(uuid.New(), uuid.New(), "Board pack", domain.ReportBoardPack)
+	require.NoError(t, err)
+
+	restored, err := snapshotFromAuditReport(audit)
+	require.NoError(t, err)
+	assert.Equal(t, snap.Summary, restored.Summary)
+	assert.Equal(t, snap.Findings, restored.Findings)
+	assert.Equal(t, snap.Treatments, restored.Treatments)
+	assert.Equal(t, snap.Frameworks, restored.Frameworks)
+	require.Len(t, restored.Risks, 2)
+	assert.True(t, restored.Risks[0].Mitigations[0].DueDate.Equal(*snap.Risks[0].Mitigations[0].DueDate))
+}
+
+func TestRenderReportFormats(t *testing.T) {
+	snap := testReportSnapshot()
+	for _, template := range domain.ValidReportTemplates {
+		doc, err := buildReportDocument(template, "Monthly <report>", snap)
+		require.NoError(t, err, template)
+
+		var pdf bytes.Buffer
+		require.NoError(t, renderReport(doc, domain.ReportFormatPDF, &pdf), template)
+		assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")), template)
+
+		var docx bytes.Buffer
+		require.NoError(t, renderReport(doc, domain.ReportFormatDOCX, &docx), template)
+		archive, err := zip.NewReader(bytes.NewReader(docx.Bytes()), int64(docx.Len()))
+		require.NoError(t, err, template)
+		parts := map[string]bool{}
+		for _, f := range archive.File {
+			parts[f.Name] = true
+		}
+		assert.True(t, parts["[Content_Types].xml"] && parts["word/document.xml"], template)
+
+		var html bytes.Buffer
+		require.NoError(t, renderReport(doc, domain.ReportFormatHTML, &html), template)
+		assert.Contains(t, html.String(), "Monthly &lt;report&gt;", template)
+		assert.False(t, strings.Contains(html.String(), "<on>"), "content is escaped")
+	}
+
+	_, err := buildReportDocument("weekly_digest", "x", snap)
+	assert.True(t, errors.Is(err, ErrInvalidReport))
+	assert.True(t, errors.Is(renderReport(&reportDocument{}, "odt", &bytes.Buffer{}), ErrInvalidReport))
+}
+
+func TestReportPeriodRange(t *testing.T) {
+	at := time.Date(2026, 5, 14, 9, 30, 0, 0, time.UTC) // Thursday
+
+	start, end, err := reportPeriodRange(domain.ReportPeriodLastWeek, at)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2026, 5, 11, 0, 0, 0, 0, time.UTC), end)
+
+	start, end, err = reportPeriodRange(domain.ReportPeriodLastMonth, at)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, end, err = reportPeriodRange(domain.ReportPeriodLastQuarter, at)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), end)
+
+	_, _, err = reportPeriodRange("yesterday", at)
+	assert.True(t, errors.Is(err, ErrInvalidReport))
+}
+
+func TestReportParameters(t *testing.T) {
+	now := time.Date(2026, 5, 14, 9, 30, 0, 0, time.UTC)
+
+	params, err := reportParameters(GenerateReportInput{PeriodStart: "2026-04-01", PeriodEnd: "2026-04-30"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), params.PeriodEnd, "a date-only end includes the whole day")
+
+	params, err = reportParameters(GenerateReportInput{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -defaultReportDays), params.PeriodStart)
+
+	_, err = reportParameters(GenerateReportInput{PeriodStart: "2026-05-01", PeriodEnd: "2026-04-01"}, now)
+	assert.True(t, errors.Is(err, ErrInvalidReport))
+
+	_, err = validateReportRequest(domain.ReportExecutiveSummary, domain.ReportFormatPDF, []string{"not an address"})
+	assert.True(t, errors.Is(err, ErrInvalidReport))
+}
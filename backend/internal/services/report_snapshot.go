@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// reportSnapshot is everything a report shows, frozen at generation time. It
+// is persisted as a RiskAuditReport so a report can be rendered again, in any
+// format, with the same figures.
+type reportSnapshot struct {
+	Metrics    reportMetrics
+	Risks      []reportRisk
+	ByStatus   map[string]int
+	ByLevel    map[string]int
+	Frameworks []reportFrameworkStatus
+	Treatments reportTreatmentCounts
+	Summary    string
+	Findings   []string
+}
+
+// reportMetrics are the parameters and headline figures of a snapshot
+type reportMetrics struct {
+	AsOf         time.Time `json:"as_of"`
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	Filter       string    `json:"filter,omitempty"`
+	Framework    string    `json:"framework,omitempty"`
+	AverageScore float64   `json:"average_score"`
+	NewRisks     int       `json:"new_risks"`    // Created during the period
+	ClosedRisks  int       `json:"closed_risks"` // Mitigated or accepted during the period
+}
+
+type reportRisk struct {
+	ID          uuid.UUID          `json:"id"`
+	Title       string             `json:"title"`
+	Status      domain.RiskStatus  `json:"status"`
+	Owner       string             `json:"owner,omitempty"`
+	Score       float64            `json:"score"`
+	Impact      int                `json:"impact"`
+	Probability int                `json:"probability"`
+	Level       string             `json:"level"`
+	Tags        []string           `json:"tags,omitempty"`
+	Frameworks  []string           `json:"frameworks,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Mitigations []reportMitigation `json:"mitigations,omitempty"`
+}
+
+type reportMitigation struct {
+	ID       uuid.UUID               `json:"id"`
+	Title    string                  `json:"title"`
+	Assignee string                  `json:"assignee,omitempty"`
+	Status   domain.MitigationStatus `json:"status"`
+	Progress int                     `json:"progress"`
+	DueDate  *time.Time              `json:"due_date,omitempty"`
+}
+
+type reportFrameworkStatus struct {
+	Framework    string  `json:"framework"`
+	Risks        int     `json:"risks"`
+	OpenRisks    int     `json:"open_risks"`
+	HighRisks    int     `json:"high_risks"` // Open risks rated HIGH or CRITICAL
+	Untreated    int     `json:"untreated"`  // Open HIGH or CRITICAL risks without any treatment in progress or done
+	AverageScore float64 `json:"average_score"`
+}
+
+type reportTreatmentCounts struct {
+	Active    int
+	Completed int
+	Overdue   int
+}
+
+// riskOpen tells whether a risk still needs attention
+func riskOpen(status domain.RiskStatus) bool {
+	return status != domain.StatusMitigated && status != domain.StatusAccepted
+}
+
+func (r reportRisk) highOrCritical() bool {
+	return r.Level == "HIGH" || r.Level == "CRITICAL"
+}
+
+// treated tells whether at least one treatment is under way or finished
+func (r reportRisk) treated() bool {
+	for _, m := range r.Mitigations {
+		if m.Status == domain.MitigationInProgress || m.Status == domain.MitigationDone {
+			return true
+		}
+	}
+	return false
+}
+
+func (m reportMitigation) overdue(asOf time.Time) bool {
+	return m.Status != domain.MitigationDone && m.DueDate != nil && m.DueDate.Before(asOf)
+}
+
+// collectReportSnapshot reads the risks selected by riskFilter, with their
+// mitigations, and computes the figures every template needs
+func collectReportSnapshot(ctx context.Context, db *gorm.DB, riskFilter *RiskFilter, params domain.ReportParameters, asOf time.Time) (*reportSnapshot, error) {
+	snap := &reportSnapshot{
+		Metrics: reportMetrics{
+			AsOf:        asOf,
+			PeriodStart: params.PeriodStart,
+			PeriodEnd:   params.PeriodEnd,
+			Filter:      params.Filter,
+			Framework:   params.Framework,
+		},
+		Risks:    []reportRisk{},
+		ByStatus: map[string]int{},
+		ByLevel:  map[string]int{},
+	}
+
+	var batch []*domain.Risk
+	result := riskFilter.Preload(riskFilter.Scope(db.WithContext(ctx).Model(&domain.Risk{})), "Mitigations").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			if err := riskFilter.AttachVersions(db.WithContext(ctx), batch); err != nil {
+				return err
+			}
+			for _, risk := range batch {
+				snap.Risks = append(snap.Risks, snapshotRisk(risk))
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load risks: %w", result.Error)
+	}
+	// Batches come back in primary key order; reports list the highest scores first
+	sort.SliceStable(snap.Risks, func(i, j int) bool { return snap.Risks[i].Score > snap.Risks[j].Score })
+
+	snap.compute()
+	return snap, nil
+}
+
+func snapshotRisk(risk *domain.Risk) reportRisk {
+	r := reportRisk{
+		ID:          risk.ID,
+		Title:       risk.Title,
+		Status:      risk.Status,
+		Owner:       risk.Owner,
+		Score:       risk.Score,
+		Impact:      risk.Impact,
+		Probability: risk.Probability,
+		Level:       riskLevelForScore(risk.Score),
+		Tags:        risk.Tags,
+		Frameworks:  risk.Frameworks,
+		CreatedAt:   risk.CreatedAt,
+		UpdatedAt:   risk.UpdatedAt,
+	}
+	for _, m := range risk.Mitigations {
+		mitigation := reportMitigation{
+			ID:       m.ID,
+			Title:    m.Title,
+			Assignee: m.Assignee,
+			Status:   m.Status,
+			Progress: m.Progress,
+		}
+		// Mitigations without a due date carry the zero time
+		if m.DueDate.After(time.Unix(0, 0)) {
+			due := m.DueDate
+			mitigation.DueDate = &due
+		}
+		r.Mitigations = append(r.Mitigations, mitigation)
+	}
+	return r
+}
+
+// compute derives counts, framework coverage and the summary from the risks
+func (s *reportSnapshot) compute() {
+	m := &s.Metrics
+	frameworks := map[string]*reportFrameworkStatus{}
+	var total float64
+
+	for _, r := range s.Risks {
+		total += r.Score
+		s.ByStatus[string(r.Status)]++
+		if riskOpen(r.Status) {
+			s.ByLevel[r.Level]++
+		}
+		if inPeriod(r.CreatedAt, m.PeriodStart, m.PeriodEnd) {
+			m.NewRisks++
+		}
+		if !riskOpen(r.Status) && inPeriod(r.UpdatedAt, m.PeriodStart, m.PeriodEnd) {
+			m.ClosedRisks++
+		}
+
+		for _, mit := range r.Mitigations {
+			switch {
+			case mit.Status == domain.MitigationDone:
+				s.Treatments.Completed++
+			case mit.overdue(m.AsOf):
+				s.Treatments.Active++
+				s.Treatments.Overdue++
+			default:
+				s.Treatments.Active++
+			}
+		}
+
+		for _, name := range r.Frameworks {
+			fw := frameworks[name]
+			if fw == nil {
+				fw = &reportFrameworkStatus{Framework: name}
+				frameworks[name] = fw
+			}
+			fw.Risks++
+			fw.AverageScore += r.Score
+			if riskOpen(r.Status) {
+				fw.OpenRisks++
+				if r.highOrCritical() {
+					fw.HighRisks++
+					if !r.treated() {
+						fw.Untreated++
+					}
+				}
+			}
+		}
+	}
+	if len(s.Risks) > 0 {
+		m.AverageScore = total / float64(len(s.Risks))
+	}
+
+	s.Frameworks = make([]reportFrameworkStatus, 0, len(frameworks))
+	for _, fw := range frameworks {
+		fw.AverageScore /= float64(fw.Risks)
+		s.Frameworks = append(s.Frameworks, *fw)
+	}
+	sort.Slice(s.Frameworks, func(i, j int) bool {
+		if s.Frameworks[i].Untreated != s.Frameworks[j].Untreated {
+			return s.Frameworks[i].Untreated > s.Frameworks[j].Untreated
+		}
+		return s.Frameworks[i].Framework < s.Frameworks[j].Framework
+	})
+
+	s.Summary, s.Findings = s.summarize()
+}
+
+func inPeriod(t, start, end time.Time) bool {
+	return !t.Before(start) && t.Before(end)
+}
+
+// summarize writes the executive summary and key findings
+func (s *reportSnapshot) summarize() (string, []string) {
+	m := s.Metrics
+	critical, high := s.ByLevel["CRITICAL"], s.ByLevel["HIGH"]
+
+	summary := fmt.Sprintf("As of %s, %d risks are in scope with an average score of %.1f. %d critical and %d high risks remain open. Between %s and %s, %d risks were identified and %d were mitigated or accepted. %d treatments are in progress, %d of them overdue.",
+		m.AsOf.UTC().Format("2 January 2006"), len(s.Risks), m.AverageScore, critical, high,
+		m.PeriodStart.UTC().Format("2 January 2006"), periodEndDisplay(m.PeriodEnd).Format("2 January 2006"),
+		m.NewRisks, m.ClosedRisks, s.Treatments.Active, s.Treatments.Overdue)
+
+	var findings []string
+	if critical > 0 {
+		for _, r := range s.Risks {
+			if riskOpen(r.Status) && r.Level == "CRITICAL" {
+				findings = append(findings, fmt.Sprintf("%d critical risks are open; the highest is %q (score %.1f).", critical, r.Title, r.Score))
+				break
+			}
+		}
+	}
+	untreated := 0
+	for _, r := range s.Risks {
+		if riskOpen(r.Status) && r.highOrCritical() && !r.treated() {
+			untreated++
+		}
+	}
+	if untreated > 0 {
+		findings = append(findings, fmt.Sprintf("%d high or critical risks have no treatment in progress.", untreated))
+	}
+	if s.Treatments.Overdue > 0 {
+		findings = append(findings, fmt.Sprintf("%d treatments are past their due date.", s.Treatments.Overdue))
+	}
+	for _, fw := range s.Frameworks {
+		if fw.Untreated == 0 {
+			break
+		}
+		findings = append(findings, fmt.Sprintf("%s: %d untreated high or critical risks out of %d.", fw.Framework, fw.Untreated, fw.Risks))
+		if len(findings) >= 6 {
+			break
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, "No open high or critical risk is without treatment and no treatment is overdue.")
+	}
+	return summary, findings
+}
+
+// periodEndDisplay is the last day of a period whose end is exclusive
+func periodEndDisplay(end time.Time) time.Time {
+	return end.Add(-time.Nanosecond).UTC()
+}
+
+// auditReport converts the snapshot into its persisted form
+func (s *reportSnapshot) auditReport(tenantID, userID uuid.UUID, title string, template domain.ReportTemplate) (*domain.RiskAuditReport, error) {
+	encode := func(v interface{}) (datatypes.JSON, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode report snapshot: %w", err)
+		}
+		return datatypes.JSON(data), nil
+	}
+
+	report := &domain.RiskAuditReport{
+		ID:                   uuid.New(),
+		TenantID:             tenantID,
+		ReportTitle:          title,
+		ReportType:           string(template),
+		ReportingPeriodStart: s.Metrics.PeriodStart,
+		ReportingPeriodEnd:   s.Metrics.PeriodEnd,
+		GeneratedBy:          userID,
+		GeneratedDate:        s.Metrics.AsOf,
+		ExecutiveSummary:     s.Summary,
+		KeyFindings:          strings.Join(s.Findings, "\n"),
+		TotalRisks:           len(s.Risks),
+		TreatmentsActive:     s.Treatments.Active,
+		TreatmentsCompleted:  s.Treatments.Completed,
+		TreatmentsOverdue:    s.Treatments.Overdue,
+		Status:               "DRAFT",
+	}
+	for _, fw := range s.Frameworks {
+		report.FrameworksAudited = append(report.FrameworksAudited, fw.Framework)
+	}
+	if report.FrameworksAudited == nil {
+		report.FrameworksAudited = pq.StringArray{}
+	}
+
+	var err error
+	if report.MetricsAndAnalytics, err = encode(s.Metrics); err != nil {
+		return nil, err
+	}
+	if report.RiskSnapshots, err = encode(s.Risks); err != nil {
+		return nil, err
+	}
+	if report.RisksByStatus, err = encode(s.ByStatus); err != nil {
+		return nil, err
+	}
+	if report.RisksBySeverity, err = encode(s.ByLevel); err != nil {
+		return nil, err
+	}
+	if report.ComplianceStatus, err = encode(s.Frameworks); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// snapshotFromAuditReport restores a snapshot persisted by auditReport
+func snapshotFromAuditReport(report *domain.RiskAuditReport) (*reportSnapshot, error) {
+	snap := &reportSnapshot{
+		Summary: report.ExecutiveSummary,
+		Treatments: reportTreatmentCounts{
+			Active:    report.TreatmentsActive,
+			Completed: report.TreatmentsCompleted,
+			Overdue:   report.TreatmentsOverdue,
+		},
+		ByStatus: map[string]int{},
+		ByLevel:  map[string]int{},
+	}
+	if report.KeyFindings != "" {
+		snap.Findings = strings.Split(report.KeyFindings, "\n")
+	}
+
+	decode := func(data datatypes.JSON, v interface{}) error {
+		if len(data) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("report snapshot %s is corrupt: %w", report.ID, err)
+		}
+		return nil
+	}
+	for _, part := range []struct {
+		data datatypes.JSON
+		v    interface{}
+	}{
+		{report.MetricsAndAnalytics, &snap.Metrics},
+		{report.RiskSnapshots, &snap.Risks},
+		{report.RisksByStatus, &snap.ByStatus},
+		{report.RisksBySeverity, &snap.ByLevel},
+		{report.ComplianceStatus, &snap.Frameworks},
+	} {
+		if err := decode(part.data, part.v); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// reportTopRisks is the number of risks listed in summaries
+const reportTopRisks = 10
+
+// reportTemplateNames are the display names of the report templates
+var reportTemplateNames = map[domain.ReportTemplate]string{
+	domain.ReportExecutiveSummary: "Executive Summary",
+	domain.ReportRiskRegister:     "Risk Register",
+	domain.ReportTreatmentStatus:  "Treatment Status",
+	domain.ReportComplianceGap:    "Compliance Gap Analysis",
+	domain.ReportBoardPack:        "Board Pack",
+}
+
+// buildReportDocument lays out a snapshot with a template
+func buildReportDocument(template domain.ReportTemplate, title string, snap *reportSnapshot) (*reportDocument, error) {
+	doc := &reportDocument{
+		Title:       title,
+		Subtitle:    reportSubtitle(snap.Metrics),
+		GeneratedAt: snap.Metrics.AsOf,
+	}
+
+	switch template {
+	case domain.ReportExecutiveSummary:
+		doc.Sections = append(doc.Sections, summarySections(snap)...)
+		doc.Sections = append(doc.Sections, topRisksSection(snap))
+	case domain.ReportRiskRegister:
+		doc.Sections = append(doc.Sections, registerSection(snap))
+	case domain.ReportTreatmentStatus:
+		doc.Sections = append(doc.Sections, treatmentSections(snap, true)...)
+	case domain.ReportComplianceGap:
+		doc.Sections = append(doc.Sections, complianceSections(snap)...)
+	case domain.ReportBoardPack:
+		doc.Sections = append(doc.Sections, summarySections(snap)...)
+		doc.Sections = append(doc.Sections, topRisksSection(snap))
+		treatment := treatmentSections(snap, false)
+		treatment[0].PageBreak = true
+		doc.Sections = append(doc.Sections, treatment...)
+		compliance := complianceSections(snap)
+		compliance[0].PageBreak = true
+		doc.Sections = append(doc.Sections, compliance...)
+	default:
+		return nil, fmt.Errorf("%w: unknown report template %q", ErrInvalidReport, template)
+	}
+	return doc, nil
+}
+
+func reportSubtitle(m reportMetrics) string {
+	parts := []string{fmt.Sprintf("Period %s to %s",
+		m.PeriodStart.UTC().Format("2 January 2006"), periodEndDisplay(m.PeriodEnd).Format("2 January 2006"))}
+	if m.Framework != "" {
+		parts = append(parts, "Framework "+m.Framework)
+	}
+	if m.Filter != "" {
+		parts = append(parts, "Filter: "+m.Filter)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func summarySections(snap *reportSnapshot) []reportSection {
+	m := snap.Metrics
+	summary := reportSection{
+		Heading:    "Summary",
+		Paragraphs: []string{snap.Summary},
+		Facts: []reportFact{
+			{"Risks in scope", fmt.Sprint(len(snap.Risks))},
+			{"Average score", fmt.Sprintf("%.1f", m.AverageScore)},
+			{"Open critical risks", fmt.Sprint(snap.ByLevel["CRITICAL"])},
+			{"Open high risks", fmt.Sprint(snap.ByLevel["HIGH"])},
+			{"Open medium risks", fmt.Sprint(snap.ByLevel["MEDIUM"])},
+			{"Open low risks", fmt.Sprint(snap.ByLevel["LOW"])},
+			{"New risks in period", fmt.Sprint(m.NewRisks)},
+			{"Risks closed in period", fmt.Sprint(m.ClosedRisks)},
+			{"Treatments in progress", fmt.Sprint(snap.Treatments.Active)},
+			{"Treatments overdue", fmt.Sprint(snap.Treatments.Overdue)},
+		},
+	}
+
+	statuses := make([]string, 0, len(snap.ByStatus))
+	for status := range snap.ByStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if snap.ByStatus[statuses[i]] != snap.ByStatus[statuses[j]] {
+			return snap.ByStatus[statuses[i]] > snap.ByStatus[statuses[j]]
+		}
+		return statuses[i] < statuses[j]
+	})
+	byStatus := &reportTable{Columns: []string{"Status", "Risks", "Share"}, Widths: []float64{3, 1, 1}, Empty: "No risks in scope."}
+	for _, status := range statuses {
+		byStatus.Rows = append(byStatus.Rows, []string{status, fmt.Sprint(snap.ByStatus[status]), percent(snap.ByStatus[status], len(snap.Risks))})
+	}
+
+	return []reportSection{
+		summary,
+		{Heading: "Key findings", Paragraphs: bulletList(snap.Findings)},
+		{Heading: "Risks by status", Table: byStatus},
+	}
+}
+
+func topRisksSection(snap *reportSnapshot) reportSection {
+	table := &reportTable{
+		Columns: []string{"Score", "Risk", "Level", "Status", "Owner"},
+		Widths:  []float64{1, 5, 1.5, 1.5, 2.5},
+		Empty:   "No open risks.",
+	}
+	for _, r := range snap.Risks {
+		if !riskOpen(r.Status) {
+			continue
+		}
+		table.Rows = append(table.Rows, []string{fmt.Sprintf("%.1f", r.Score), r.Title, r.Level, string(r.Status), r.Owner})
+		if len(table.Rows) == reportTopRisks {
+			break
+		}
+	}
+	return reportSection{Heading: "Top risks", Table: table}
+}
+
+func registerSection(snap *reportSnapshot) reportSection {
+	table := &reportTable{
+		Columns: []string{"Score", "Risk", "I x P", "Level", "Status", "Owner", "Frameworks", "Treatments"},
+		Widths:  []float64{1, 4.5, 1, 1.4, 1.6, 2.2, 2.2, 1.3},
+		Empty:   "No risks match the report filter.",
+	}
+	for _, r := range snap.Risks {
+		done := 0
+		for _, m := range r.Mitigations {
+			if m.Status == domain.MitigationDone {
+				done++
+			}
+		}
+		table.Rows = append(table.Rows, []string{
+			fmt.Sprintf("%.1f", r.Score),
+			r.Title,
+			fmt.Sprintf("%d x %d", r.Impact, r.Probability),
+			r.Level,
+			string(r.Status),
+			r.Owner,
+			strings.Join(r.Frameworks, ", "),
+			fmt.Sprintf("%d/%d", done, len(r.Mitigations)),
+		})
+	}
+	return reportSection{
+		Heading:    "Risk register",
+		Paragraphs: []string{fmt.Sprintf("%d risks, highest score first.", len(snap.Risks))},
+		Table:      table,
+	}
+}
+
+// treatmentSections reports mitigation progress; full lists every treatment
+// in addition to the overdue ones
+func treatmentSections(snap *reportSnapshot, full bool) []reportSection {
+	asOf := snap.Metrics.AsOf
+	progress, count := 0, 0
+	overdue := &reportTable{
+		Columns: []string{"Risk", "Treatment", "Assignee", "Due", "Days late", "Progress"},
+		Widths:  []float64{3, 3, 2, 1.5, 1, 1},
+		Empty:   "No overdue treatments.",
+	}
+	all := &reportTable{
+		Columns: []string{"Risk", "Treatment", "Status", "Assignee", "Due", "Progress"},
+		Widths:  []float64{3, 3, 1.5, 2, 1.5, 1},
+		Empty:   "No treatments recorded for the risks in scope.",
+	}
+	for _, r := range snap.Risks {
+		for _, m := range r.Mitigations {
+			progress += m.Progress
+			count++
+			due := ""
+			if m.DueDate != nil {
+				due = m.DueDate.UTC().Format("2006-01-02")
+			}
+			if m.overdue(asOf) {
+				overdue.Rows = append(overdue.Rows, []string{r.Title, m.Title, m.Assignee, due,
+					fmt.Sprint(int(asOf.Sub(*m.DueDate).Hours() / 24)), fmt.Sprintf("%d%%", m.Progress)})
+			}
+			all.Rows = append(all.Rows, []string{r.Title, m.Title, string(m.Status), m.Assignee, due, fmt.Sprintf("%d%%", m.Progress)})
+		}
+	}
+	average := 0
+	if count > 0 {
+		average = progress / count
+	}
+
+	sections := []reportSection{
+		{
+			Heading: "Treatment status",
+			Facts: []reportFact{
+				{"Treatments", fmt.Sprint(count)},
+				{"In progress or planned", fmt.Sprint(snap.Treatments.Active)},
+				{"Completed", fmt.Sprint(snap.Treatments.Completed)},
+				{"Overdue", fmt.Sprint(snap.Treatments.Overdue)},
+				{"Average progress", fmt.Sprintf("%d%%", average)},
+			},
+		},
+		{Heading: "Overdue treatments", Table: overdue},
+	}
+	if full {
+		sections = append(sections, reportSection{Heading: "All treatments", Table: all})
+	}
+	return sections
+}
+
+func complianceSections(snap *reportSnapshot) []reportSection {
+	coverage := &reportTable{
+		Columns: []string{"Framework", "Risks", "Open", "High/critical open", "Untreated", "Avg score"},
+		Widths:  []float64{3, 1, 1, 1.6, 1.2, 1.2},
+		Empty:   "No risk in scope is mapped to a framework.",
+	}
+	for _, fw := range snap.Frameworks {
+		coverage.Rows = append(coverage.Rows, []string{fw.Framework, fmt.Sprint(fw.Risks), fmt.Sprint(fw.OpenRisks),
+			fmt.Sprint(fw.HighRisks), fmt.Sprint(fw.Untreated), fmt.Sprintf("%.1f", fw.AverageScore)})
+	}
+
+	gaps := &reportTable{
+		Columns: []string{"Score", "Risk", "Level", "Frameworks", "Owner"},
+		Widths:  []float64{1, 5, 1.5, 3, 2.5},
+		Empty:   "Every open high or critical risk has a treatment in progress.",
+	}
+	unmapped := 0
+	for _, r := range snap.Risks {
+		if len(r.Frameworks) == 0 {
+			unmapped++
+		}
+		if riskOpen(r.Status) && r.highOrCritical() && !r.treated() {
+			gaps.Rows = append(gaps.Rows, []string{fmt.Sprintf("%.1f", r.Score), r.Title, r.Level, strings.Join(r.Frameworks, ", "), r.Owner})
+		}
+	}
+
+	coverageSection := reportSection{Heading: "Framework coverage", Table: coverage}
+	if unmapped > 0 {
+		coverageSection.Paragraphs = []string{fmt.Sprintf("%d risks in scope are not mapped to any framework.", unmapped)}
+	}
+	return []reportSection{
+		coverageSection,
+		{
+			Heading:    "Gaps",
+			Paragraphs: []string{"Open high or critical risks without a treatment in progress or completed."},
+			Table:      gaps,
+		},
+	}
+}
+
+func bulletList(items []string) []string {
+	list := make([]string, len(items))
+	for i, item := range items {
+		list[i] = "• " + item
+	}
+	return list
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", float64(n)*100/float64(total))
+}
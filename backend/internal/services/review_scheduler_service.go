@@ -0,0 +1,564 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// ErrCalendarFeedNotFound is returned for unknown or revoked calendar feed tokens
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+const (
+	reviewSourcePolicy        = "POLICY"
+	reviewSourceTreatmentPlan = "TREATMENT_PLAN"
+	reviewSourceExplicit      = "REVIEW"
+)
+
+// ReviewSchedulerService computes review due dates from the tenant cadence policy,
+// opens review tasks for risk owners and publishes them as iCalendar feeds.
+type ReviewSchedulerService struct {
+	db *gorm.DB
+}
+
+// NewReviewSchedulerService creates a new review scheduler service
+func NewReviewSchedulerService(db *gorm.DB) *ReviewSchedulerService {
+	return &ReviewSchedulerService{db: db}
+}
+
+// ReviewScheduleEntry is the computed review cadence of one register entry
+type ReviewScheduleEntry struct {
+	RiskRegisterID  uuid.UUID              `json:"risk_register_id"`
+	RiskID          uuid.UUID              `json:"risk_id"`
+	RiskTitle       string                 `json:"risk_title"`
+	OwnerID         uuid.UUID              `json:"owner_id"`
+	OwnerEmail      string                 `json:"owner_email"`
+	RiskLevel       string                 `json:"risk_level"`
+	Frequency       domain.ReviewFrequency `json:"frequency"`
+	FrequencySource string                 `json:"frequency_source"` // POLICY, TREATMENT_PLAN or REVIEW
+	LastReviewedAt  *time.Time             `json:"last_reviewed_at,omitempty"`
+	DueDate         time.Time              `json:"due_date"`
+	DaysOverdue     int                    `json:"days_overdue"`
+	Stale           bool                   `json:"stale"`
+}
+
+// GetPolicy returns the tenant's review cadence policy, or the default one
+func (s *ReviewSchedulerService) GetPolicy(tenantID uuid.UUID) domain.ReviewCadencePolicy {
+	var policy domain.ReviewCadencePolicy
+	if err := s.db.First(&policy, "tenant_id = ?", tenantID).Error; err != nil {
+		return domain.DefaultReviewCadencePolicy(tenantID)
+	}
+	return policy
+}
+
+// UpdatePolicy creates or replaces the tenant's review cadence policy
+func (s *ReviewSchedulerService) UpdatePolicy(tenantID uuid.UUID, updatedBy uuid.UUID, input domain.ReviewCadencePolicy) (*domain.ReviewCadencePolicy, error) {
+	for level, freq := range map[string]domain.ReviewFrequency{"critical": input.Critical, "high": input.High, "medium": input.Medium, "low": input.Low} {
+		if _, ok := domain.ParseReviewFrequency(string(freq)); !ok {
+			return nil, fmt.Errorf("invalid review frequency for %s: %q", level, freq)
+		}
+	}
+	if input.LeadDays < 0 || input.GraceDays < 0 {
+		return nil, fmt.Errorf("lead_days and grace_days must not be negative")
+	}
+
+	policy := s.GetPolicy(tenantID)
+	policy.Critical, _ = domain.ParseReviewFrequency(string(input.Critical))
+	policy.High, _ = domain.ParseReviewFrequency(string(input.High))
+	policy.Medium, _ = domain.ParseReviewFrequency(string(input.Medium))
+	policy.Low, _ = domain.ParseReviewFrequency(string(input.Low))
+	policy.LeadDays = input.LeadDays
+	policy.GraceDays = input.GraceDays
+	policy.UpdatedBy = updatedBy
+	if policy.ID == uuid.Nil {
+		policy.ID = uuid.New()
+	}
+
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save review cadence policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// ComputeSchedule returns the review schedule of every open register entry of a tenant, soonest first
+func (s *ReviewSchedulerService) ComputeSchedule(tenantID uuid.UUID, now time.Time) ([]ReviewScheduleEntry, error) {
+	var registers []domain.RiskRegister
+	if err := s.db.Where("tenant_id = ? AND status <> ?", tenantID, "CLOSED").Find(&registers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk register: %w", err)
+	}
+	return s.scheduleFor(tenantID, registers, now)
+}
+
+// StaleRisks returns the register entries whose review is overdue beyond the policy grace period
+func (s *ReviewSchedulerService) StaleRisks(tenantID uuid.UUID, now time.Time) ([]ReviewScheduleEntry, error) {
+	schedule, err := s.ComputeSchedule(tenantID, now)
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]ReviewScheduleEntry, 0)
+	for _, entry := range schedule {
+		if entry.Stale {
+			stale = append(stale, entry)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool { return stale[i].DaysOverdue > stale[j].DaysOverdue })
+	return stale, nil
+}
+
+// NextReviewDate returns when a register entry must next be reviewed after a review at reviewedAt
+func (s *ReviewSchedulerService) NextReviewDate(tenantID uuid.UUID, riskRegisterID uuid.UUID, riskLevel string, reviewedAt time.Time) time.Time {
+	policy := s.GetPolicy(tenantID)
+	freq, _ := resolveReviewFrequency(&policy, riskLevel, s.planFrequencies(riskRegisterID)[riskRegisterID])
+	return freq.Next(reviewedAt)
+}
+
+// GenerateTasks opens review tasks for entries due within the lead time of their tenant policy
+// and flags overdue tasks. It returns the number of tasks created.
+func (s *ReviewSchedulerService) GenerateTasks(now time.Time) (int, error) {
+	if err := s.db.Model(&domain.ReviewTask{}).
+		Where("status = ? AND due_date < ?", domain.ReviewTaskOpen, now).
+		Update("status", domain.ReviewTaskOverdue).Error; err != nil {
+		return 0, fmt.Errorf("failed to flag overdue review tasks: %w", err)
+	}
+
+	var registers []domain.RiskRegister
+	if err := s.db.Where("status <> ?", "CLOSED").Find(&registers).Error; err != nil {
+		return 0, fmt.Errorf("failed to load risk register: %w", err)
+	}
+	byTenant := make(map[uuid.UUID][]domain.RiskRegister)
+	for _, register := range registers {
+		byTenant[register.TenantID] = append(byTenant[register.TenantID], register)
+	}
+
+	var pending []uuid.UUID
+	s.db.Model(&domain.ReviewTask{}).
+		Where("status IN ?", []domain.ReviewTaskStatus{domain.ReviewTaskOpen, domain.ReviewTaskOverdue}).
+		Pluck("risk_register_id", &pending)
+	hasTask := make(map[uuid.UUID]bool, len(pending))
+	for _, id := range pending {
+		hasTask[id] = true
+	}
+
+	created := 0
+	for tenantID, tenantRegisters := range byTenant {
+		policy := s.GetPolicy(tenantID)
+		schedule, err := s.scheduleFor(tenantID, tenantRegisters, now)
+		if err != nil {
+			return created, err
+		}
+		horizon := now.AddDate(0, 0, policy.LeadDays)
+		for _, entry := range schedule {
+			if hasTask[entry.RiskRegisterID] || entry.DueDate.After(horizon) {
+				continue
+			}
+			task := domain.ReviewTask{
+				ID:             uuid.New(),
+				TenantID:       tenantID,
+				RiskRegisterID: entry.RiskRegisterID,
+				RiskID:         entry.RiskID,
+				Title:          "Review: " + entry.RiskTitle,
+				AssigneeID:     entry.OwnerID,
+				RiskLevel:      entry.RiskLevel,
+				Frequency:      entry.Frequency,
+				DueDate:        entry.DueDate,
+				Status:         domain.ReviewTaskOpen,
+			}
+			if entry.DueDate.Before(now) {
+				task.Status = domain.ReviewTaskOverdue
+			}
+			if err := s.db.Create(&task).Error; err != nil {
+				log.Printf("reviews: failed to open review task for %s: %v", entry.RiskRegisterID, err)
+				continue
+			}
+			created++
+		}
+	}
+	return created, nil
+}
+
+// CompleteReviewTasks closes the open review tasks of a register entry once a monitoring review is recorded
+func (s *ReviewSchedulerService) CompleteReviewTasks(tenantID, riskRegisterID, reviewID, reviewedBy uuid.UUID, reviewedAt time.Time) error {
+	return s.db.Model(&domain.ReviewTask{}).
+		Where("tenant_id = ? AND risk_register_id = ? AND status IN ?", tenantID, riskRegisterID,
+			[]domain.ReviewTaskStatus{domain.ReviewTaskOpen, domain.ReviewTaskOverdue}).
+		Updates(map[string]interface{}{
+			"status":       domain.ReviewTaskCompleted,
+			"review_id":    reviewID,
+			"completed_by": reviewedBy,
+			"completed_at": reviewedAt,
+		}).Error
+}
+
+// ListTasks lists review tasks of a tenant, optionally for an assignee and status
+func (s *ReviewSchedulerService) ListTasks(tenantID uuid.UUID, assigneeID *uuid.UUID, status string) ([]domain.ReviewTask, error) {
+	var tasks []domain.ReviewTask
+	query := s.db.Where("tenant_id = ?", tenantID)
+	if assigneeID != nil {
+		query = query.Where("assignee_id = ?", *assigneeID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("due_date").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list review tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// CreateCalendarFeed issues a secret feed URL token for a user's or a team's reviews.
+// The raw token is only returned once; only its hash is stored.
+func (s *ReviewSchedulerService) CreateCalendarFeed(
+	tenantID uuid.UUID,
+	ownerType domain.CalendarFeedOwner,
+	ownerID uuid.UUID,
+	createdBy uuid.UUID,
+) (*domain.ReviewCalendarFeed, string, error) {
+	switch ownerType {
+	case domain.CalendarFeedUser:
+	case domain.CalendarFeedTeam:
+		var membership int64
+		s.db.Model(&domain.TeamMember{}).Where("team_id = ? AND user_id = ?", ownerID, createdBy).Count(&membership)
+		if membership == 0 {
+			return nil, "", fmt.Errorf("only team members can subscribe to a team's reviews")
+		}
+	default:
+		return nil, "", fmt.Errorf("invalid calendar feed owner type: %s", ownerType)
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	feed := &domain.ReviewCalendarFeed{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+		TokenHash: hashFeedToken(token),
+		CreatedBy: createdBy,
+	}
+	if err := s.db.Create(feed).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create calendar feed: %w", err)
+	}
+	return feed, token, nil
+}
+
+// ListCalendarFeeds lists the calendar feeds created by a user
+func (s *ReviewSchedulerService) ListCalendarFeeds(tenantID, createdBy uuid.UUID) ([]domain.ReviewCalendarFeed, error) {
+	var feeds []domain.ReviewCalendarFeed
+	if err := s.db.Where("tenant_id = ? AND created_by = ? AND revoked_at IS NULL", tenantID, createdBy).
+		Order("created_at DESC").Find(&feeds).Error; err != nil {
+		return nil, fmt.Errorf("failed to list calendar feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+// RevokeCalendarFeed disables a feed URL
+func (s *ReviewSchedulerService) RevokeCalendarFeed(tenantID, feedID, userID uuid.UUID) error {
+	result := s.db.Model(&domain.ReviewCalendarFeed{}).
+		Where("id = ? AND tenant_id = ? AND created_by = ? AND revoked_at IS NULL", feedID, tenantID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke calendar feed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrCalendarFeedNotFound
+	}
+	return nil
+}
+
+// RenderCalendarFeed resolves a feed token and renders its reviews as an iCalendar document
+func (s *ReviewSchedulerService) RenderCalendarFeed(token string, now time.Time) (string, error) {
+	var feed domain.ReviewCalendarFeed
+	if err := s.db.First(&feed, "token_hash = ? AND revoked_at IS NULL", hashFeedToken(token)).Error; err != nil {
+		return "", ErrCalendarFeedNotFound
+	}
+
+	assignees := []uuid.UUID{feed.OwnerID}
+	name := "OpenRisk reviews"
+	if feed.OwnerType == domain.CalendarFeedTeam {
+		var team domain.Team
+		if err := s.db.First(&team, "id = ?", feed.OwnerID).Error; err != nil {
+			return "", ErrCalendarFeedNotFound
+		}
+		name = "OpenRisk reviews — " + team.Name
+		assignees = nil
+		s.db.Model(&domain.TeamMember{}).Where("team_id = ?", team.ID).Pluck("user_id", &assignees)
+	}
+
+	events, err := s.calendarEvents(feed.TenantID, assignees, now)
+	if err != nil {
+		return "", err
+	}
+	return buildReviewCalendar(name, events, now), nil
+}
+
+// calendarEvents merges open review tasks with the projected next reviews of the assignees' risks
+func (s *ReviewSchedulerService) calendarEvents(tenantID uuid.UUID, assignees []uuid.UUID, now time.Time) ([]reviewCalendarEvent, error) {
+	events := make([]reviewCalendarEvent, 0)
+	if len(assignees) == 0 {
+		return events, nil
+	}
+
+	var tasks []domain.ReviewTask
+	if err := s.db.Where("tenant_id = ? AND assignee_id IN ? AND status IN ?", tenantID, assignees,
+		[]domain.ReviewTaskStatus{domain.ReviewTaskOpen, domain.ReviewTaskOverdue}).
+		Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load review tasks: %w", err)
+	}
+	covered := make(map[uuid.UUID]bool, len(tasks))
+	for _, task := range tasks {
+		covered[task.RiskRegisterID] = true
+		events = append(events, reviewCalendarEvent{
+			UID:         task.ID.String() + "@openrisk",
+			Date:        task.DueDate,
+			Summary:     task.Title,
+			Description: fmt.Sprintf("%s review (%s risk). Status: %s.", task.Frequency, task.RiskLevel, task.Status),
+			Categories:  []string{"RISK-REVIEW", task.RiskLevel},
+		})
+	}
+
+	var registers []domain.RiskRegister
+	if err := s.db.Where("tenant_id = ? AND risk_owner IN ? AND status <> ?", tenantID, assignees, "CLOSED").
+		Find(&registers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk register: %w", err)
+	}
+	schedule, err := s.scheduleFor(tenantID, registers, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range schedule {
+		if covered[entry.RiskRegisterID] {
+			continue
+		}
+		events = append(events, reviewCalendarEvent{
+			UID:         fmt.Sprintf("%s-%s@openrisk", entry.RiskRegisterID, entry.DueDate.Format("20060102")),
+			Date:        entry.DueDate,
+			Summary:     "Review: " + entry.RiskTitle,
+			Description: fmt.Sprintf("Scheduled %s review (%s risk).", entry.Frequency, entry.RiskLevel),
+			Categories:  []string{"RISK-REVIEW", entry.RiskLevel},
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date.Before(events[j].Date) })
+	return events, nil
+}
+
+// scheduleFor computes the schedule entries of the given register entries
+func (s *ReviewSchedulerService) scheduleFor(tenantID uuid.UUID, registers []domain.RiskRegister, now time.Time) ([]ReviewScheduleEntry, error) {
+	entries := make([]ReviewScheduleEntry, 0, len(registers))
+	if len(registers) == 0 {
+		return entries, nil
+	}
+
+	policy := s.GetPolicy(tenantID)
+	registerIDs := make([]uuid.UUID, 0, len(registers))
+	riskIDs := make([]uuid.UUID, 0, len(registers))
+	for _, register := range registers {
+		registerIDs = append(registerIDs, register.ID)
+		riskIDs = append(riskIDs, register.RiskID)
+	}
+
+	var reviews []domain.RiskMonitoringReview
+	if err := s.db.Where("risk_register_id IN ?", registerIDs).Order("review_date DESC").Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to load monitoring reviews: %w", err)
+	}
+	latest := make(map[uuid.UUID]domain.RiskMonitoringReview, len(reviews))
+	for _, review := range reviews {
+		if _, ok := latest[review.RiskRegisterID]; !ok {
+			latest[review.RiskRegisterID] = review
+		}
+	}
+
+	var risks []domain.Risk
+	s.db.Select("id", "title").Where("id IN ?", riskIDs).Find(&risks)
+	titles := make(map[uuid.UUID]string, len(risks))
+	for _, risk := range risks {
+		titles[risk.ID] = risk.Title
+	}
+
+	planFrequencies := s.planFrequencies(registerIDs...)
+
+	for _, register := range registers {
+		level := register.ResidualRiskLevel
+		if level == "" {
+			level = register.InherentRiskLevel
+		}
+
+		entry := ReviewScheduleEntry{
+			RiskRegisterID: register.ID,
+			RiskID:         register.RiskID,
+			RiskTitle:      titles[register.RiskID],
+			OwnerID:        register.RiskOwner,
+			OwnerEmail:     register.RiskOwnerEmail,
+			RiskLevel:      level,
+		}
+
+		anchor := register.IdentificationDate
+		if anchor.IsZero() {
+			anchor = register.CreatedAt
+		}
+		var explicitNext time.Time
+		if review, ok := latest[register.ID]; ok {
+			reviewedAt := review.ReviewDate
+			entry.LastReviewedAt = &reviewedAt
+			anchor = reviewedAt
+			explicitNext = review.NextReviewDate
+		}
+
+		entry.Frequency, entry.FrequencySource = resolveReviewFrequency(&policy, level, planFrequencies[register.ID])
+		entry.DueDate = entry.Frequency.Next(anchor)
+		if !explicitNext.IsZero() && explicitNext.Before(entry.DueDate) {
+			entry.DueDate = explicitNext
+			entry.FrequencySource = reviewSourceExplicit
+		}
+
+		entry.DaysOverdue, entry.Stale = reviewOverdue(entry.DueDate, now, policy.GraceDays)
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].DueDate.Before(entries[j].DueDate) })
+	return entries, nil
+}
+
+// planFrequencies returns, per register entry, the review frequency of its most recent
+// treatment plan that defines one
+func (s *ReviewSchedulerService) planFrequencies(registerIDs ...uuid.UUID) map[uuid.UUID]string {
+	var plans []domain.RiskTreatmentPlan
+	s.db.Select("risk_register_id", "review_frequency", "created_at").
+		Where("risk_register_id IN ? AND review_frequency <> ''", registerIDs).
+		Order("created_at DESC").
+		Find(&plans)
+
+	frequencies := make(map[uuid.UUID]string, len(plans))
+	for _, plan := range plans {
+		if _, ok := frequencies[plan.RiskRegisterID]; !ok {
+			frequencies[plan.RiskRegisterID] = plan.ReviewFrequency
+		}
+	}
+	return frequencies
+}
+
+// resolveReviewFrequency picks the treatment plan frequency when valid, else the policy one for the level
+func resolveReviewFrequency(policy *domain.ReviewCadencePolicy, level string, planFrequency string) (domain.ReviewFrequency, string) {
+	if freq, ok := domain.ParseReviewFrequency(planFrequency); ok {
+		return freq, reviewSourceTreatmentPlan
+	}
+	return policy.FrequencyFor(level), reviewSourcePolicy
+}
+
+// reviewOverdue returns the whole days a review is overdue and whether that exceeds the grace period
+func reviewOverdue(due, now time.Time, graceDays int) (int, bool) {
+	if !now.After(due) {
+		return 0, false
+	}
+	days := int(now.Sub(due).Hours() / 24)
+	return days, now.After(due.AddDate(0, 0, graceDays))
+}
+
+func hashFeedToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// reviewCalendarEvent is an all-day calendar entry for a review
+type reviewCalendarEvent struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Categories  []string
+}
+
+// buildReviewCalendar renders events as an RFC 5545 iCalendar document
+func buildReviewCalendar(name string, events []reviewCalendarEvent, now time.Time) string {
+	var b strings.Builder
+	writeLine := func(line string) {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//OpenDefender//OpenRisk Reviews//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("METHOD:PUBLISH")
+	writeLine("X-WR-CALNAME:" + escapeICSText(name))
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		day := event.Date.UTC()
+		categories := make([]string, 0, len(event.Categories))
+		for _, category := range event.Categories {
+			if category != "" {
+				categories = append(categories, escapeICSText(category))
+			}
+		}
+
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:" + event.UID)
+		writeLine("DTSTAMP:" + stamp)
+		writeLine("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+		writeLine("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+		writeLine("SUMMARY:" + escapeICSText(event.Summary))
+		if event.Description != "" {
+			writeLine("DESCRIPTION:" + escapeICSText(event.Description))
+		}
+		if len(categories) > 0 {
+			writeLine("CATEGORIES:" + strings.Join(categories, ","))
+		}
+		writeLine("TRANSP:TRANSPARENT")
+		writeLine("END:VEVENT")
+	}
+
+	writeLine("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a TEXT value (RFC 5545 §3.3.11)
+func escapeICSText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// foldICSLine folds a content line longer than 75 octets (RFC 5545 §3.1),
+// without splitting multi-byte UTF-8 characters
+func foldICSLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	max := limit
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > max {
+			b.WriteString("\r\n ")
+			width = 0
+			max = limit - 1 // Continuation lines start with a space
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestParseReviewFrequency(t *testing.T) {
+	tests := []struct {
+		input string
+		want  domain.ReviewFrequency
+		ok    bool
+	}{
+		{"monthly", domain.ReviewMonthly, true},
+		{"Semi-Annual", domain.ReviewSemiAnnual, true},
+		{" QUARTERLY ", domain.ReviewQuarterly, true},
+		{"yearly", domain.ReviewAnnual, true},
+		{"sometimes", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := domain.ParseReviewFrequency(tt.input)
+		assert.Equal(t, tt.ok, ok, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+}
+
+func TestResolveReviewFrequency(t *testing.T) {
+	policy := domain.DefaultReviewCadencePolicy(uuid.New())
+
+	freq, source := resolveReviewFrequency(&policy, "CRITICAL", "")
+	assert.Equal(t, domain.ReviewMonthly, freq)
+	assert.Equal(t, reviewSourcePolicy, source)
+
+	freq, source = resolveReviewFrequency(&policy, "LOW", "weekly")
+	assert.Equal(t, domain.ReviewWeekly, freq)
+	assert.Equal(t, reviewSourceTreatmentPlan, source)
+
+	// Unknown plan frequencies fall back to the policy
+	freq, _ = resolveReviewFrequency(&policy, "MEDIUM", "whenever")
+	assert.Equal(t, domain.ReviewSemiAnnual, freq)
+}
+
+func TestReviewOverdue(t *testing.T) {
+	due := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	days, stale := reviewOverdue(due, due.Add(-time.Hour), 0)
+	assert.Equal(t, 0, days)
+	assert.False(t, stale)
+
+	days, stale = reviewOverdue(due, due.AddDate(0, 0, 3), 7)
+	assert.Equal(t, 3, days)
+	assert.False(t, stale)
+
+	days, stale = reviewOverdue(due, due.AddDate(0, 0, 10), 7)
+	assert.Equal(t, 10, days)
+	assert.True(t, stale)
+}
+
+func TestBuildReviewCalendar(t *testing.T) {
+	now := time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC)
+	events := []reviewCalendarEvent{{
+		UID:         "task-1@openrisk",
+		Date:        time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC),
+		Summary:     "Review: Ransomware; backups, offsite",
+		Description: strings.Repeat("Quarterly review of the ransomware exposure. ", 3),
+		Categories:  []string{"RISK-REVIEW", "HIGH"},
+	}}
+
+	ics := buildReviewCalendar("Team reviews", events, now)
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "DTSTAMP:20260501T083000Z\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20260520\r\n")
+	assert.Contains(t, ics, "DTEND;VALUE=DATE:20260521\r\n")
+	assert.Contains(t, ics, `SUMMARY:Review: Ransomware\; backups\, offsite`)
+	assert.Contains(t, ics, "CATEGORIES:RISK-REVIEW,HIGH\r\n")
+
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "line must be folded: %q", line)
+	}
+}
+
+func TestFoldICSLine_KeepsMultiByteRunes(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("é", 80)
+	folded := foldICSLine(line)
+
+	assert.Equal(t, line, strings.ReplaceAll(folded, "\r\n ", ""))
+	for _, part := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(part), 75)
+		assert.True(t, strings.ToValidUTF8(part, "?") == part)
+	}
+}
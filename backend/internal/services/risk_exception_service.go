@@ -0,0 +1,607 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+const (
+	exceptionDecisionAcceptance      = "RISK_ACCEPTANCE"
+	exceptionDecisionRecertification = "RECERTIFICATION"
+)
+
+// RiskExceptionService manages risk acceptances as time-boxed exceptions:
+// approval, expiry, owner reminders and re-certification.
+type RiskExceptionService struct {
+	db        *gorm.DB
+	approvals *ApprovalWorkflowService
+}
+
+// NewRiskExceptionService creates a new risk exception service
+func NewRiskExceptionService(db *gorm.DB) *RiskExceptionService {
+	return &RiskExceptionService{
+		db:        db,
+		approvals: NewApprovalWorkflowService(db),
+	}
+}
+
+// ExceptionRegisterEntry is one line of the exceptions register report
+type ExceptionRegisterEntry struct {
+	ExceptionID          uuid.UUID                    `json:"exception_id"`
+	Title                string                       `json:"title"`
+	RiskID               uuid.UUID                    `json:"risk_id"`
+	RiskTitle            string                       `json:"risk_title"`
+	RiskScore            float64                      `json:"risk_score"`
+	Owner                string                       `json:"owner"`
+	Status               domain.RiskExceptionStatus   `json:"status"`
+	ExpiresAt            time.Time                    `json:"expires_at"`
+	DaysRemaining        int                          `json:"days_remaining"`
+	Expiring             bool                         `json:"expiring"`
+	RecertificationCount int                          `json:"recertification_count"`
+	PendingRecertify     bool                         `json:"pending_recertification"`
+	CompensatingControls []domain.CompensatingControl `json:"compensating_controls"`
+	Assets               []string                     `json:"assets"`
+}
+
+// ExceptionRegister lists active exceptions, flagging those expiring within the window
+type ExceptionRegister struct {
+	GeneratedAt   time.Time                `json:"generated_at"`
+	ExpiringDays  int                      `json:"expiring_within_days"`
+	TotalActive   int                      `json:"total_active"`
+	TotalExpiring int                      `json:"total_expiring"`
+	Entries       []ExceptionRegisterEntry `json:"entries"`
+}
+
+// CreateException requests an exception for a risk. The exception starts in PENDING_APPROVAL
+// and is routed through the tenant's RISK_EXCEPTION approval chain when one is configured.
+func (s *RiskExceptionService) CreateException(
+	tenantID uuid.UUID,
+	requestedBy uuid.UUID,
+	exception *domain.RiskException,
+	controls []domain.CompensatingControl,
+	assetIDs []uuid.UUID,
+) (*domain.RiskException, error) {
+	now := time.Now()
+	if exception.ValidFrom.IsZero() {
+		exception.ValidFrom = now
+	}
+	if !exception.ExpiresAt.After(exception.ValidFrom) || !exception.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("expires_at must be in the future and after valid_from")
+	}
+
+	var risk domain.Risk
+	if err := s.db.First(&risk, "id = ?", exception.RiskID).Error; err != nil {
+		return nil, fmt.Errorf("risk not found: %w", err)
+	}
+
+	data, err := json.Marshal(controls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal compensating controls: %w", err)
+	}
+
+	exception.ID = uuid.New()
+	exception.TenantID = tenantID
+	exception.RequestedBy = requestedBy
+	exception.CompensatingControls = data
+	exception.Status = domain.ExceptionPendingApproval
+	if exception.Owner == "" {
+		exception.Owner = risk.Owner
+	}
+	if len(exception.ReminderDays) == 0 {
+		exception.ReminderDays = domain.DefaultExceptionReminderDays
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(exception).Error; err != nil {
+			return fmt.Errorf("failed to create exception: %w", err)
+		}
+		if len(assetIDs) > 0 {
+			var assets []*domain.Asset
+			if err := tx.Where("id IN ?", assetIDs).Find(&assets).Error; err != nil {
+				return fmt.Errorf("failed to load scoped assets: %w", err)
+			}
+			if len(assets) != len(assetIDs) {
+				return fmt.Errorf("one or more scoped assets do not exist")
+			}
+			if err := tx.Model(exception).Association("Assets").Replace(assets); err != nil {
+				return fmt.Errorf("failed to scope exception to assets: %w", err)
+			}
+			exception.Assets = assets
+		}
+		// Started in the same transaction so an exception never exists without its approval chain
+		if _, err := s.startApproval(tx, exception, &risk, exceptionDecisionAcceptance); err != nil {
+			return fmt.Errorf("failed to start exception approval: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return exception, nil
+}
+
+// GetException retrieves an exception with its risk and scoped assets
+func (s *RiskExceptionService) GetException(tenantID, exceptionID uuid.UUID) (*domain.RiskException, error) {
+	var exception domain.RiskException
+	if err := s.db.Preload("Risk").Preload("Assets").
+		First(&exception, "id = ? AND tenant_id = ?", exceptionID, tenantID).Error; err != nil {
+		return nil, fmt.Errorf("exception not found: %w", err)
+	}
+	return &exception, nil
+}
+
+// ListExceptions lists the exceptions of a tenant, optionally filtered by status and risk
+func (s *RiskExceptionService) ListExceptions(tenantID uuid.UUID, status string, riskID *uuid.UUID) ([]domain.RiskException, error) {
+	var exceptions []domain.RiskException
+	query := s.db.Where("tenant_id = ?", tenantID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if riskID != nil {
+		query = query.Where("risk_id = ?", *riskID)
+	}
+	if err := query.Preload("Assets").Order("expires_at").Find(&exceptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+// ApproveException approves a pending exception or re-certification in a single step.
+// Exceptions governed by an approval workflow must be approved through their approval tasks.
+func (s *RiskExceptionService) ApproveException(tenantID, exceptionID, approvedBy uuid.UUID) (*domain.RiskException, error) {
+	if err := s.ensureNoPendingApproval(exceptionID); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var exception domain.RiskException
+		if err := tx.First(&exception, "id = ? AND tenant_id = ?", exceptionID, tenantID).Error; err != nil {
+			return fmt.Errorf("exception not found: %w", err)
+		}
+		return approveException(tx, &exception, approvedBy, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetException(tenantID, exceptionID)
+}
+
+// RejectException rejects a pending exception or re-certification in a single step
+func (s *RiskExceptionService) RejectException(tenantID, exceptionID uuid.UUID, reason string) (*domain.RiskException, error) {
+	if err := s.ensureNoPendingApproval(exceptionID); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var exception domain.RiskException
+		if err := tx.First(&exception, "id = ? AND tenant_id = ?", exceptionID, tenantID).Error; err != nil {
+			return fmt.Errorf("exception not found: %w", err)
+		}
+		exception.CloseReason = reason
+		return rejectException(tx, &exception, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetException(tenantID, exceptionID)
+}
+
+// Recertify proposes a new expiry for an active or expired exception. The extension only
+// takes effect once approved, through the approval chain or ApproveException.
+func (s *RiskExceptionService) Recertify(
+	tenantID uuid.UUID,
+	exceptionID uuid.UUID,
+	newExpiry time.Time,
+	reason string,
+	requestedBy uuid.UUID,
+) (*domain.RiskException, error) {
+	exception, err := s.GetException(tenantID, exceptionID)
+	if err != nil {
+		return nil, err
+	}
+	if exception.Status != domain.ExceptionActive && exception.Status != domain.ExceptionExpired {
+		return nil, fmt.Errorf("only active or expired exceptions can be re-certified (status: %s)", exception.Status)
+	}
+	if exception.ProposedExpiresAt != nil {
+		return nil, fmt.Errorf("a re-certification is already pending")
+	}
+	if !newExpiry.After(time.Now()) || !newExpiry.After(exception.ExpiresAt) {
+		return nil, fmt.Errorf("new expiry must be in the future and after the current expiry")
+	}
+
+	// The proposal and its approval request are stored together: an exception must not be
+	// left pending re-certification without a way to approve it
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(exception).
+			Where("status IN ? AND proposed_expires_at IS NULL", []domain.RiskExceptionStatus{domain.ExceptionActive, domain.ExceptionExpired}).
+			Updates(map[string]interface{}{
+				"proposed_expires_at":    newExpiry,
+				"recertification_reason": reason,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to request re-certification: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("a re-certification is already pending")
+		}
+		exception.ProposedExpiresAt = &newExpiry
+		exception.RecertificationReason = reason
+
+		if _, err := s.startApproval(tx, exception, exception.Risk, exceptionDecisionRecertification); err != nil {
+			return fmt.Errorf("failed to start re-certification approval: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetException(tenantID, exceptionID)
+}
+
+// RevokeException ends an active exception early and returns the risk to its previous status
+func (s *RiskExceptionService) RevokeException(tenantID, exceptionID, revokedBy uuid.UUID, reason string) (*domain.RiskException, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var exception domain.RiskException
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&exception, "id = ? AND tenant_id = ?", exceptionID, tenantID).Error; err != nil {
+			return fmt.Errorf("exception not found: %w", err)
+		}
+		if exception.Status != domain.ExceptionActive {
+			return fmt.Errorf("only active exceptions can be revoked (status: %s)", exception.Status)
+		}
+		return closeException(tx, &exception, domain.ExceptionRevoked, reason, revokedBy.String(), "EXCEPTION_REVOKED", time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetException(tenantID, exceptionID)
+}
+
+// ProcessExpirations expires active exceptions past their expiry date and returns
+// their risks to their prior status. It returns the number of exceptions expired.
+func (s *RiskExceptionService) ProcessExpirations(now time.Time) (int, error) {
+	var exceptions []domain.RiskException
+	if err := s.db.Where("status = ? AND expires_at <= ?", domain.ExceptionActive, now).
+		Find(&exceptions).Error; err != nil {
+		return 0, fmt.Errorf("failed to load expired exceptions: %w", err)
+	}
+
+	expired := 0
+	for i := range exceptions {
+		closed := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// Re-read under lock: the exception may have been revoked or re-certified since it was listed
+			var exception domain.RiskException
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&exception, "id = ? AND status = ? AND expires_at <= ?", exceptions[i].ID, domain.ExceptionActive, now).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			}
+			closed = true
+			return closeException(tx, &exception, domain.ExceptionExpired, "Exception expired", domain.SystemActor, "EXCEPTION_EXPIRED", now)
+		})
+		if err != nil {
+			log.Printf("exceptions: failed to expire %s: %v", exceptions[i].ID, err)
+			continue
+		}
+		if closed {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// ProcessReminders records a reminder for each active exception that reached one of its
+// reminder thresholds. It returns the reminders created.
+func (s *RiskExceptionService) ProcessReminders(now time.Time) ([]domain.RiskExceptionReminder, error) {
+	var exceptions []domain.RiskException
+	if err := s.db.Where("status = ? AND expires_at > ?", domain.ExceptionActive, now).
+		Find(&exceptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load active exceptions: %w", err)
+	}
+
+	var created []domain.RiskExceptionReminder
+	for _, exception := range exceptions {
+		var previous []domain.RiskExceptionReminder
+		s.db.Where("exception_id = ? AND expires_at = ?", exception.ID, exception.ExpiresAt).Find(&previous)
+		sent := make(map[int64]bool, len(previous))
+		for _, r := range previous {
+			sent[r.DaysBeforeExpiry] = true
+		}
+
+		thresholds := []int64(exception.ReminderDays)
+		if len(thresholds) == 0 {
+			thresholds = domain.DefaultExceptionReminderDays
+		}
+		days, due := domain.DueExceptionReminder(exception.ExpiresAt, now, thresholds, sent)
+		if !due {
+			continue
+		}
+
+		reminder := domain.RiskExceptionReminder{
+			ID:               uuid.New(),
+			TenantID:         exception.TenantID,
+			ExceptionID:      exception.ID,
+			Recipient:        exception.Owner,
+			DaysBeforeExpiry: days,
+			ExpiresAt:        exception.ExpiresAt,
+			SentAt:           now,
+		}
+		if err := s.db.Create(&reminder).Error; err != nil {
+			log.Printf("exceptions: failed to record reminder for %s: %v", exception.ID, err)
+			continue
+		}
+		log.Printf("exceptions: reminder to %s — exception %q expires in %d day(s)", exception.Owner, exception.Title, exception.DaysRemaining(now))
+		created = append(created, reminder)
+	}
+	return created, nil
+}
+
+// ListReminders lists the reminders sent to a recipient, newest first.
+// A user can be addressed by email or ID, hence several recipient keys.
+func (s *RiskExceptionService) ListReminders(tenantID uuid.UUID, recipients []string, unacknowledgedOnly bool) ([]domain.RiskExceptionReminder, error) {
+	var reminders []domain.RiskExceptionReminder
+	query := s.db.Where("tenant_id = ? AND recipient IN ?", tenantID, recipients)
+	if unacknowledgedOnly {
+		query = query.Where("acknowledged_at IS NULL")
+	}
+	if err := query.Order("sent_at DESC").Find(&reminders).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// AcknowledgeReminder marks a reminder as read by its recipient
+func (s *RiskExceptionService) AcknowledgeReminder(tenantID, reminderID uuid.UUID, recipients []string) error {
+	result := s.db.Model(&domain.RiskExceptionReminder{}).
+		Where("id = ? AND tenant_id = ? AND recipient IN ? AND acknowledged_at IS NULL", reminderID, tenantID, recipients).
+		Update("acknowledged_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to acknowledge reminder: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("reminder not found")
+	}
+	return nil
+}
+
+// GetExceptionRegister builds the exceptions register: every active exception, with
+// those expiring within expiringDays flagged and listed first.
+func (s *RiskExceptionService) GetExceptionRegister(tenantID uuid.UUID, expiringDays int, now time.Time) (*ExceptionRegister, error) {
+	var exceptions []domain.RiskException
+	if err := s.db.Preload("Risk").Preload("Assets").
+		Where("tenant_id = ? AND status = ?", tenantID, domain.ExceptionActive).
+		Find(&exceptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load exceptions register: %w", err)
+	}
+
+	register := &ExceptionRegister{
+		GeneratedAt:  now,
+		ExpiringDays: expiringDays,
+		Entries:      buildExceptionRegisterEntries(exceptions, expiringDays, now),
+	}
+	register.TotalActive = len(register.Entries)
+	for _, entry := range register.Entries {
+		if entry.Expiring {
+			register.TotalExpiring++
+		}
+	}
+	return register, nil
+}
+
+// startApproval starts the approval chain of an exception within tx
+func (s *RiskExceptionService) startApproval(tx *gorm.DB, exception *domain.RiskException, risk *domain.Risk, decisionType string) (*domain.ApprovalRequest, error) {
+	var registerID uuid.UUID
+	tx.Model(&domain.RiskRegister{}).Where("risk_id = ?", exception.RiskID).Limit(1).Pluck("id", &registerID)
+
+	riskLevel := ""
+	if risk != nil {
+		riskLevel = riskLevelForScore(risk.Score)
+	}
+	return NewApprovalWorkflowService(tx).StartApprovalAtLevel(exception.TenantID, domain.ApprovalEntityException, exception.ID, registerID, decisionType, riskLevel, exception.RequestedBy)
+}
+
+func (s *RiskExceptionService) ensureNoPendingApproval(exceptionID uuid.UUID) error {
+	pending, err := s.approvals.HasPendingApproval(domain.ApprovalEntityException, exceptionID)
+	if err != nil {
+		return fmt.Errorf("failed to check approval workflow: %w", err)
+	}
+	if pending {
+		return ErrApprovalPending
+	}
+	return nil
+}
+
+// applyExceptionOutcome is called by the approval engine when an exception's approval request closes
+func applyExceptionOutcome(tx *gorm.DB, exceptionID uuid.UUID, approved bool, actor uuid.UUID, now time.Time) error {
+	var exception domain.RiskException
+	if err := tx.First(&exception, "id = ?", exceptionID).Error; err != nil {
+		return err
+	}
+	if approved {
+		return approveException(tx, &exception, actor, now)
+	}
+	exception.CloseReason = "Rejected by approval workflow"
+	return rejectException(tx, &exception, now)
+}
+
+// approveException activates a pending exception or applies a pending re-certification
+func approveException(tx *gorm.DB, exception *domain.RiskException, actor uuid.UUID, now time.Time) error {
+	switch {
+	case exception.Status == domain.ExceptionPendingApproval:
+		var risk domain.Risk
+		if err := tx.First(&risk, "id = ?", exception.RiskID).Error; err != nil {
+			return fmt.Errorf("risk not found: %w", err)
+		}
+		// A risk already accepted under another exception keeps the status it had before that one
+		previous := risk.Status
+		if previous == domain.StatusAccepted {
+			var covering domain.RiskException
+			if err := tx.Where("risk_id = ? AND id <> ? AND status = ? AND previous_risk_status <> ''", exception.RiskID, exception.ID, domain.ExceptionActive).
+				First(&covering).Error; err == nil {
+				previous = covering.PreviousRiskStatus
+			}
+		}
+		if err := tx.Model(exception).Updates(map[string]interface{}{
+			"status":               domain.ExceptionActive,
+			"approved_by":          actor,
+			"approved_at":          now,
+			"previous_risk_status": previous,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to activate exception: %w", err)
+		}
+		return setRiskStatus(tx, &risk, domain.StatusAccepted, actor.String(), "EXCEPTION_APPROVED")
+
+	case exception.ProposedExpiresAt != nil:
+		reactivate := exception.Status == domain.ExceptionExpired
+		if err := tx.Model(exception).Updates(map[string]interface{}{
+			"status":                domain.ExceptionActive,
+			"expires_at":            *exception.ProposedExpiresAt,
+			"proposed_expires_at":   nil,
+			"recertification_count": gorm.Expr("recertification_count + 1"),
+			"last_recertified_at":   now,
+			"approved_by":           actor,
+			"approved_at":           now,
+			"closed_at":             nil,
+			"close_reason":          "",
+		}).Error; err != nil {
+			return fmt.Errorf("failed to re-certify exception: %w", err)
+		}
+		if !reactivate {
+			return nil
+		}
+		var risk domain.Risk
+		if err := tx.First(&risk, "id = ?", exception.RiskID).Error; err != nil {
+			return fmt.Errorf("risk not found: %w", err)
+		}
+		return setRiskStatus(tx, &risk, domain.StatusAccepted, actor.String(), "EXCEPTION_RECERTIFIED")
+	}
+
+	return fmt.Errorf("exception has nothing awaiting approval (status: %s)", exception.Status)
+}
+
+// rejectException rejects a pending exception, or drops a pending re-certification
+func rejectException(tx *gorm.DB, exception *domain.RiskException, now time.Time) error {
+	switch {
+	case exception.Status == domain.ExceptionPendingApproval:
+		return tx.Model(exception).Updates(map[string]interface{}{
+			"status":       domain.ExceptionRejected,
+			"closed_at":    now,
+			"close_reason": exception.CloseReason,
+		}).Error
+	case exception.ProposedExpiresAt != nil:
+		return tx.Model(exception).Updates(map[string]interface{}{
+			"proposed_expires_at":    nil,
+			"recertification_reason": "",
+		}).Error
+	}
+	return fmt.Errorf("exception has nothing awaiting approval (status: %s)", exception.Status)
+}
+
+// closeException expires or revokes an exception and, unless another exception still
+// covers the risk, returns the risk to the status it had before the exception (ACTIVE
+// when unknown) with a history entry.
+func closeException(
+	tx *gorm.DB,
+	exception *domain.RiskException,
+	status domain.RiskExceptionStatus,
+	reason string,
+	changedBy string,
+	changeType string,
+	now time.Time,
+) error {
+	if err := tx.Model(exception).Updates(map[string]interface{}{
+		"status":       status,
+		"closed_at":    now,
+		"close_reason": reason,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to close exception: %w", err)
+	}
+
+	var stillCovered int64
+	tx.Model(&domain.RiskException{}).
+		Where("risk_id = ? AND id <> ? AND status = ?", exception.RiskID, exception.ID, domain.ExceptionActive).
+		Count(&stillCovered)
+	if stillCovered > 0 {
+		return nil
+	}
+
+	var risk domain.Risk
+	if err := tx.First(&risk, "id = ?", exception.RiskID).Error; err != nil {
+		return fmt.Errorf("risk not found: %w", err)
+	}
+	if risk.Status != domain.StatusAccepted {
+		return nil
+	}
+	restored := exception.PreviousRiskStatus
+	if restored == "" || restored == domain.StatusAccepted {
+		restored = domain.StatusActive
+	}
+	return setRiskStatus(tx, &risk, restored, changedBy, changeType)
+}
+
+// setRiskStatus changes a risk's status and records a history entry with the given change type.
+// The update bypasses the Risk hooks so the history entry carries the real actor and reason.
+func setRiskStatus(tx *gorm.DB, risk *domain.Risk, status domain.RiskStatus, changedBy string, changeType string) error {
+	if err := tx.Model(risk).UpdateColumn("status", status).Error; err != nil {
+		return fmt.Errorf("failed to update risk status: %w", err)
+	}
+	risk.Status = status
+	return domain.RecordRiskChange(tx.WithContext(domain.WithActor(tx.Statement.Context, changedBy)), risk.ID, changeType)
+}
+
+// buildExceptionRegisterEntries turns exceptions into register lines, expiring ones first
+func buildExceptionRegisterEntries(exceptions []domain.RiskException, expiringDays int, now time.Time) []ExceptionRegisterEntry {
+	entries := make([]ExceptionRegisterEntry, 0, len(exceptions))
+	for i := range exceptions {
+		exception := &exceptions[i]
+		controls, _ := exception.Controls()
+		if controls == nil {
+			controls = []domain.CompensatingControl{}
+		}
+
+		entry := ExceptionRegisterEntry{
+			ExceptionID:          exception.ID,
+			Title:                exception.Title,
+			RiskID:               exception.RiskID,
+			Owner:                exception.Owner,
+			Status:               exception.Status,
+			ExpiresAt:            exception.ExpiresAt,
+			DaysRemaining:        exception.DaysRemaining(now),
+			RecertificationCount: exception.RecertificationCount,
+			PendingRecertify:     exception.ProposedExpiresAt != nil,
+			CompensatingControls: controls,
+			Assets:               []string{},
+		}
+		entry.Expiring = exception.ExpiresAt.Before(now.AddDate(0, 0, expiringDays))
+		if exception.Risk != nil {
+			entry.RiskTitle = exception.Risk.Title
+			entry.RiskScore = exception.Risk.Score
+		}
+		for _, asset := range exception.Assets {
+			entry.Assets = append(entry.Assets, asset.Name)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Expiring != entries[j].Expiring {
+			return entries[i].Expiring
+		}
+		return entries[i].ExpiresAt.Before(entries[j].ExpiresAt)
+	})
+	return entries
+}
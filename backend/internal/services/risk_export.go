@@ -0,0 +1,431 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+// Export formats supported by bulk exports
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatJSON = "json"
+)
+
+// customFieldColumnPrefix prefixes the columns holding risk custom fields
+const customFieldColumnPrefix = "custom."
+
+// ExportColumn describes one column of a risk export
+type ExportColumn struct {
+	Name    string `json:"name"`
+	Label   string `json:"label"`
+	Numeric bool   `json:"numeric"`
+	Default bool   `json:"default"`
+
+	value func(risk *domain.Risk) string
+}
+
+// riskExportColumns lists the built-in columns; related records are flattened
+// into delimited cells so every risk is exactly one row.
+var riskExportColumns = []ExportColumn{
+	{Name: "id", Label: "ID", Default: true, value: func(r *domain.Risk) string { return r.ID.String() }},
+	{Name: "title", Label: "Title", Default: true, value: func(r *domain.Risk) string { return r.Title }},
+	{Name: "description", Label: "Description", value: func(r *domain.Risk) string { return r.Description }},
+	{Name: "status", Label: "Status", Default: true, value: func(r *domain.Risk) string { return string(r.Status) }},
+	{Name: "impact", Label: "Impact", Numeric: true, Default: true, value: func(r *domain.Risk) string { return strconv.Itoa(r.Impact) }},
+	{Name: "probability", Label: "Probability", Numeric: true, Default: true, value: func(r *domain.Risk) string { return strconv.Itoa(r.Probability) }},
+	{Name: "score", Label: "Score", Numeric: true, Default: true, value: func(r *domain.Risk) string { return strconv.FormatFloat(r.Score, 'f', -1, 64) }},
+	{Name: "owner", Label: "Owner", Default: true, value: func(r *domain.Risk) string { return r.Owner }},
+	{Name: "tags", Label: "Tags", Default: true, value: func(r *domain.Risk) string { return strings.Join(r.Tags, "; ") }},
+	{Name: "frameworks", Label: "Frameworks", value: func(r *domain.Risk) string { return strings.Join(r.Frameworks, "; ") }},
+	{Name: "source", Label: "Source", value: func(r *domain.Risk) string { return r.Source }},
+	{Name: "external_id", Label: "External ID", value: func(r *domain.Risk) string { return r.ExternalID }},
+	{Name: "mitigation_count", Label: "Mitigations (count)", Numeric: true, Default: true, value: func(r *domain.Risk) string { return strconv.Itoa(len(r.Mitigations)) }},
+	{Name: "mitigations", Label: "Mitigations", Default: true, value: func(r *domain.Risk) string {
+		parts := make([]string, 0, len(r.Mitigations))
+		for _, m := range r.Mitigations {
+			parts = append(parts, fmt.Sprintf("%s [%s %d%%]", m.Title, m.Status, m.Progress))
+		}
+		return strings.Join(parts, "; ")
+	}},
+	{Name: "asset_count", Label: "Assets (count)", Numeric: true, value: func(r *domain.Risk) string { return strconv.Itoa(len(r.Assets)) }},
+	{Name: "assets", Label: "Assets", Default: true, value: func(r *domain.Risk) string {
+		parts := make([]string, 0, len(r.Assets))
+		for _, a := range r.Assets {
+			parts = append(parts, fmt.Sprintf("%s (%s)", a.Name, a.Criticality))
+		}
+		return strings.Join(parts, "; ")
+	}},
+	{Name: "created_at", Label: "Created At", Default: true, value: func(r *domain.Risk) string { return r.CreatedAt.UTC().Format(time.RFC3339) }},
+	{Name: "updated_at", Label: "Updated At", value: func(r *domain.Risk) string { return r.UpdatedAt.UTC().Format(time.RFC3339) }},
+}
+
+// AvailableExportColumns returns the built-in columns followed by one column
+// per risk custom field definition
+func AvailableExportColumns(customFields []domain.CustomField) []ExportColumn {
+	columns := make([]ExportColumn, 0, len(riskExportColumns)+len(customFields))
+	columns = append(columns, riskExportColumns...)
+
+	// Custom field columns share the decoded custom fields of the current row
+	values := &customFieldValues{}
+	for _, field := range customFields {
+		name := field.Name
+		label := field.DisplayName
+		if label == "" {
+			label = name
+		}
+		columns = append(columns, ExportColumn{
+			Name:    customFieldColumnPrefix + name,
+			Label:   label,
+			Numeric: field.FieldType == domain.CustomFieldTypeNumber,
+			Default: field.Visible,
+			value:   values.column(name),
+		})
+	}
+
+	return columns
+}
+
+// SelectExportColumns resolves the requested column names, in the requested
+// order; an empty selection returns the default columns
+func SelectExportColumns(available []ExportColumn, selected []string) ([]ExportColumn, error) {
+	if len(selected) == 0 {
+		columns := make([]ExportColumn, 0, len(available))
+		for _, column := range available {
+			if column.Default {
+				columns = append(columns, column)
+			}
+		}
+		return columns, nil
+	}
+
+	byName := make(map[string]ExportColumn, len(available))
+	for _, column := range available {
+		byName[column.Name] = column
+	}
+
+	columns := make([]ExportColumn, 0, len(selected))
+	seen := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		column, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown export column: %s", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			columns = append(columns, column)
+		}
+	}
+
+	return columns, nil
+}
+
+// customFieldValues decodes a risk's custom fields once per row rather than once per column
+type customFieldValues struct {
+	riskID uuid.UUID
+	values map[string]interface{}
+}
+
+func (c *customFieldValues) column(name string) func(r *domain.Risk) string {
+	return func(r *domain.Risk) string {
+		// Keyed by ID: batch loading reuses the same Risk structs for different rows
+		if c.riskID != r.ID || c.values == nil {
+			c.riskID = r.ID
+			c.values = map[string]interface{}{}
+			if len(r.CustomFields) > 0 {
+				_ = json.Unmarshal(r.CustomFields, &c.values)
+			}
+		}
+
+		switch v := c.values[name].(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		default:
+			raw, _ := json.Marshal(v)
+			return string(raw)
+		}
+	}
+}
+
+// numericCell reports whether a value can be written as a number
+func numericCell(column ExportColumn, value string) bool {
+	if !column.Numeric || value == "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+// exportWriter streams rows into an export file
+type exportWriter interface {
+	WriteRow(risk *domain.Risk) error
+	Close() error
+}
+
+// exportContentType returns the MIME type and file extension of a format
+func exportContentType(format string) (string, string, error) {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx", nil
+	case ExportFormatJSON:
+		return "application/json", "json", nil
+	}
+	return "", "", fmt.Errorf("unsupported export format: %s", format)
+}
+
+// ExportFileType returns the MIME type and file extension of an export format
+func ExportFileType(format string) (string, string) {
+	contentType, ext, err := exportContentType(format)
+	if err != nil {
+		return "application/octet-stream", "bin"
+	}
+	return contentType, ext
+}
+
+// newExportWriter writes the header for the format and returns a row writer
+func newExportWriter(format string, w io.Writer, columns []ExportColumn) (exportWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVExportWriter(w, columns)
+	case ExportFormatXLSX:
+		return newXLSXExportWriter(w, columns)
+	case ExportFormatJSON:
+		return newJSONExportWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unsupported export format: %s", format)
+}
+
+type csvExportWriter struct {
+	w       *csv.Writer
+	columns []ExportColumn
+	record  []string
+}
+
+func newCSVExportWriter(w io.Writer, columns []ExportColumn) (*csvExportWriter, error) {
+	cw := &csvExportWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		cw.record[i] = column.Label
+	}
+	return cw, cw.w.Write(cw.record)
+}
+
+func (cw *csvExportWriter) WriteRow(risk *domain.Risk) error {
+	for i, column := range cw.columns {
+		value := column.value(risk)
+		if !numericCell(column, value) {
+			value = neutralizeFormula(value)
+		}
+		cw.record[i] = value
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvExportWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// neutralizeFormula stops spreadsheet applications from evaluating text cells
+// as formulas (CSV injection)
+func neutralizeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type jsonExportWriter struct {
+	w       *bufio.Writer
+	columns []ExportColumn
+	keys    [][]byte
+	rows    int
+}
+
+func newJSONExportWriter(w io.Writer, columns []ExportColumn) (*jsonExportWriter, error) {
+	jw := &jsonExportWriter{w: bufio.NewWriter(w), columns: columns, keys: make([][]byte, len(columns))}
+	for i, column := range columns {
+		jw.keys[i], _ = json.Marshal(column.Name)
+	}
+	_, err := jw.w.WriteString("[")
+	return jw, err
+}
+
+func (jw *jsonExportWriter) WriteRow(risk *domain.Risk) error {
+	if jw.rows > 0 {
+		jw.w.WriteString(",")
+	}
+	jw.rows++
+
+	jw.w.WriteString("\n{")
+	for i, column := range jw.columns {
+		if i > 0 {
+			jw.w.WriteString(",")
+		}
+		jw.w.Write(jw.keys[i])
+		jw.w.WriteString(":")
+
+		value := column.value(risk)
+		if numericCell(column, value) {
+			jw.w.WriteString(value)
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		jw.w.Write(raw)
+	}
+	_, err := jw.w.WriteString("}")
+	return err
+}
+
+func (jw *jsonExportWriter) Close() error {
+	jw.w.WriteString("\n]\n")
+	return jw.w.Flush()
+}
+
+// xlsxExportWriter writes a minimal Office Open XML workbook. The static parts
+// are written first so the worksheet can be streamed as the last zip entry.
+type xlsxExportWriter struct {
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+	columns []ExportColumn
+	refs    []string
+	row     int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Risks" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+func newXLSXExportWriter(w io.Writer, columns []ExportColumn) (*xlsxExportWriter, error) {
+	zw := zip.NewWriter(w)
+
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &xlsxExportWriter{zw: zw, sheet: bufio.NewWriter(sheet), columns: columns, refs: make([]string, len(columns))}
+	for i := range columns {
+		xw.refs[i] = xlsxColumnName(i)
+	}
+	xw.sheet.WriteString(xlsxSheetHeader)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Label
+	}
+	return xw, xw.writeCells(header, nil)
+}
+
+func (xw *xlsxExportWriter) WriteRow(risk *domain.Risk) error {
+	values := make([]string, len(xw.columns))
+	for i, column := range xw.columns {
+		values[i] = column.value(risk)
+	}
+	return xw.writeCells(values, xw.columns)
+}
+
+// writeCells writes one row; columns marks numeric cells (nil writes text only)
+func (xw *xlsxExportWriter) writeCells(values []string, columns []ExportColumn) error {
+	xw.row++
+	row := strconv.Itoa(xw.row)
+
+	xw.sheet.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		ref := xw.refs[i] + row
+		if columns != nil && numericCell(columns[i], value) {
+			xw.sheet.WriteString(`<c r="` + ref + `"><v>` + value + `</v></c>`)
+			continue
+		}
+		xw.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(xw.sheet, []byte(value)); err != nil {
+			return err
+		}
+		xw.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := xw.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (xw *xlsxExportWriter) Close() error {
+	xw.sheet.WriteString(xlsxSheetFooter)
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zw.Close()
+}
+
+// xlsxColumnName converts a zero-based index into a spreadsheet column (A, Z, AA, ...)
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// signExportDownload signs a download link for a bulk operation's artifact
+func signExportDownload(key []byte, opID uuid.UUID, expires time.Time) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "bulk-export\n%s\n%d", opID, expires.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyExportDownload checks a download signature and its expiry
+func verifyExportDownload(key []byte, opID uuid.UUID, expiresUnix int64, signature string, now time.Time) bool {
+	if len(key) == 0 || now.Unix() > expiresUnix {
+		return false
+	}
+	expected := signExportDownload(key, opID, time.Unix(expiresUnix, 0))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func exportTestRisks() []*domain.Risk {
+	return []*domain.Risk{
+		{
+			ID:           uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+			Title:        "=HYPERLINK(\"http://evil\")",
+			Impact:       4,
+			Probability:  3,
+			Score:        12,
+			Tags:         []string{"ISO27001", "GDPR"},
+			CustomFields: datatypes.JSON(`{"department":"Finance","budget":1500}`),
+			Mitigations:  []domain.Mitigation{{Title: "MFA", Status: "IN_PROGRESS", Progress: 50}},
+			Assets:       []*domain.Asset{{Name: "ERP", Criticality: "HIGH"}},
+		},
+		{
+			ID:          uuid.MustParse("22222222-2222-2222-2222-222222222222"),
+			Title:       "Vendor <lock-in> & outage",
+			Impact:      2,
+			Probability: 2,
+			Score:       4,
+		},
+	}
+}
+
+func exportTestColumns(t *testing.T, names ...string) []ExportColumn {
+	available := AvailableExportColumns([]domain.CustomField{
+		{Name: "department", DisplayName: "Department", FieldType: domain.CustomFieldTypeText, Visible: true},
+		{Name: "budget", FieldType: domain.CustomFieldTypeNumber},
+	})
+	columns, err := SelectExportColumns(available, names)
+	require.NoError(t, err)
+	return columns
+}
+
+func writeExport(t *testing.T, format string, columns []ExportColumn) []byte {
+	var buf bytes.Buffer
+	w, err := newExportWriter(format, &buf, columns)
+	require.NoError(t, err)
+	for _, risk := range exportTestRisks() {
+		require.NoError(t, w.WriteRow(risk))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestSelectExportColumns(t *testing.T) {
+	defaults := exportTestColumns(t)
+	names := make([]string, len(defaults))
+	for i, c := range defaults {
+		names[i] = c.Name
+	}
+	assert.Contains(t, names, "custom.department")
+	assert.NotContains(t, names, "custom.budget")
+	assert.NotContains(t, names, "description")
+
+	selected := exportTestColumns(t, "score", "title", "score")
+	require.Len(t, selected, 2)
+	assert.Equal(t, "score", selected[0].Name)
+
+	_, err := SelectExportColumns(AvailableExportColumns(nil), []string{"password"})
+	assert.Error(t, err)
+}
+
+func TestCSVExport(t *testing.T) {
+	out := writeExport(t, ExportFormatCSV, exportTestColumns(t, "title", "score", "tags", "mitigations", "assets", "custom.department", "custom.budget"))
+
+	records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, []string{"Title", "Score", "Tags", "Mitigations", "Assets", "Department", "budget"}, records[0])
+	assert.Equal(t, `'=HYPERLINK("http://evil")`, records[1][0], "formulas are neutralised")
+	assert.Equal(t, "12", records[1][1])
+	assert.Equal(t, "ISO27001; GDPR", records[1][2])
+	assert.Equal(t, "MFA [IN_PROGRESS 50%]", records[1][3])
+	assert.Equal(t, "ERP (HIGH)", records[1][4])
+	assert.Equal(t, "Finance", records[1][5])
+	assert.Equal(t, "1500", records[1][6])
+	assert.Equal(t, "", records[2][5], "custom fields of the previous row do not leak")
+}
+
+func TestJSONExport(t *testing.T) {
+	out := writeExport(t, ExportFormatJSON, exportTestColumns(t, "id", "score", "custom.budget"))
+
+	var rows []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &rows))
+	require.Len(t, rows, 2)
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", rows[0]["id"])
+	assert.Equal(t, float64(12), rows[0]["score"])
+	assert.Equal(t, float64(1500), rows[0]["custom.budget"])
+	assert.Equal(t, "", rows[1]["custom.budget"])
+}
+
+func TestXLSXExport(t *testing.T) {
+	out := writeExport(t, ExportFormatXLSX, exportTestColumns(t, "title", "score"))
+
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+
+	require.Contains(t, files, "[Content_Types].xml")
+	require.Contains(t, files, "xl/workbook.xml")
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="B2"><v>12</v></c>`)
+	assert.Contains(t, sheet, "Vendor &lt;lock-in&gt; &amp; outage")
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+	assert.Equal(t, 3, strings.Count(sheet, "<row "))
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumnName(0))
+	assert.Equal(t, "Z", xlsxColumnName(25))
+	assert.Equal(t, "AA", xlsxColumnName(26))
+	assert.Equal(t, "BA", xlsxColumnName(52))
+}
+
+func TestExportDownloadSignature(t *testing.T) {
+	key := []byte("secret")
+	opID := uuid.New()
+	now := time.Now()
+	expires := now.Add(15 * time.Minute)
+	signature := signExportDownload(key, opID, expires)
+
+	assert.True(t, verifyExportDownload(key, opID, expires.Unix(), signature, now))
+	assert.False(t, verifyExportDownload(key, opID, expires.Unix(), signature, expires.Add(time.Second)), "expired")
+	assert.False(t, verifyExportDownload(key, uuid.New(), expires.Unix(), signature, now), "other operation")
+	assert.False(t, verifyExportDownload(key, opID, expires.Add(time.Hour).Unix(), signature, now), "extended expiry")
+	assert.False(t, verifyExportDownload(nil, opID, expires.Unix(), signExportDownload(nil, opID, expires), now), "no signing key")
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/database"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
+)
+
+// RiskFilterContext is what the macros of a filter are evaluated against:
+// @me resolves to the user, @now and @today to Now. With AsOf, the filter
+// reads the register as it was at that date and the macros are evaluated
+// then.
+type RiskFilterContext struct {
+	UserID uuid.UUID
+	Email  string
+	Now    time.Time
+	AsOf   *time.Time
+}
+
+// RiskFilter is a compiled risk filter expression. The same RiskFilter scopes
+// list, count, bulk and export queries, so a previewed selection is exactly
+// the one an operation processes.
+type RiskFilter struct {
+	Expression string
+	AsOf       *time.Time // Past date the register is read at; nil for the current register
+	cond       *filter.Condition
+}
+
+// Scope restricts a query on the risks table to the filter; a nil or blank
+// filter leaves the query unchanged. As of a date, the query reads the
+// versions valid then in place of the risks table.
+func (f *RiskFilter) Scope(db *gorm.DB) *gorm.DB {
+	if f == nil {
+		return db
+	}
+	if f.AsOf != nil {
+		db = db.Table("(?) AS risks", risksAsOf(db, *f.AsOf))
+	}
+	if f.cond == nil || f.cond.SQL == "" {
+		return db
+	}
+	return db.Where(f.cond.SQL, f.cond.Args...)
+}
+
+// RiskFilterService compiles filter expressions against the risk schema,
+// custom fields included
+type RiskFilterService struct {
+	db           *gorm.DB
+	customFields *CustomFieldService
+}
+
+// NewRiskFilterService creates a new risk filter service
+func NewRiskFilterService() *RiskFilterService {
+	return &RiskFilterService{
+		db:           database.DB,
+		customFields: NewCustomFieldService(),
+	}
+}
+
+// riskAssetsExists selects the assets of the risk being filtered
+const riskAssetsExists = "SELECT 1 FROM risk_assets ra JOIN assets a ON a.id = ra.asset_id AND a.deleted_at IS NULL WHERE ra.risk_id = risks.id"
+
+var riskFilterFields = []filter.Field{
+	{Name: "id", Type: filter.FieldUUID, Expr: "risks.id"},
+	{Name: "title", Type: filter.FieldString, Expr: "risks.title"},
+	{Name: "description", Type: filter.FieldString, Expr: "risks.description"},
+	{Name: "impact", Type: filter.FieldNumber, Expr: "risks.impact", Description: "1 (low) to 5 (critical)"},
+	{Name: "probability", Type: filter.FieldNumber, Expr: "risks.probability", Description: "1 (rare) to 5 (almost certain)"},
+	{Name: "score", Type: filter.FieldNumber, Expr: "risks.score"},
+	{Name: "status", Type: filter.FieldEnum, Expr: "risks.status", Values: []string{
+		string(domain.StatusDraft), string(domain.StatusActive), string(domain.StatusMitigated), string(domain.StatusAccepted),
+	}},
+	{Name: "owner", Type: filter.FieldString, Expr: "risks.owner", Description: "Owner email or user ID",
+		Macros: []string{"@me", "@team:<name>"}},
+	{Name: "source", Type: filter.FieldString, Expr: "risks.source"},
+	{Name: "external_id", Type: filter.FieldString, Expr: "risks.external_id"},
+	{Name: "tags", Type: filter.FieldArray, Expr: "risks.tags"},
+	{Name: "frameworks", Type: filter.FieldArray, Expr: "risks.frameworks"},
+	{Name: "assets", Type: filter.FieldRelation, Description: "Asset names or external IDs",
+		Relation: &filter.Relation{Exists: riskAssetsExists, Match: []string{"a.name", "a.external_id"}}},
+	{Name: "created_at", Type: filter.FieldDate, Expr: "risks.created_at", Macros: []string{"@now", "@today", "@today-30d"}},
+	{Name: "updated_at", Type: filter.FieldDate, Expr: "risks.updated_at", Macros: []string{"@now", "@today", "@today-30d"}},
+}
+
+// Guards keep casts of free-form JSON values from failing the whole query
+const (
+	customFieldValue  = "(risks.custom_fields ->> ?)"
+	customFieldNumber = `CASE WHEN (risks.custom_fields ->> ?) ~ '^-{0,1}[0-9]+(\.[0-9]+){0,1}$' THEN (risks.custom_fields ->> ?)::numeric END`
+	customFieldDate   = `CASE WHEN (risks.custom_fields ->> ?) ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}' THEN left(risks.custom_fields ->> ?, 10)::date END`
+)
+
+// CustomFilterFieldName is the filter field name of a custom field:
+// "Business Unit" is filtered as custom.business_unit
+func CustomFilterFieldName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+	}
+	return "custom." + strings.TrimSuffix(b.String(), "_")
+}
+
+// RiskFilterSchema builds the filter schema of risks with the given custom fields
+func RiskFilterSchema(customFields []*domain.CustomField) *filter.Schema {
+	schema := filter.NewSchema(riskFilterFields...)
+
+	// Sorted so that colliding names resolve the same way every time
+	sorted := append([]*domain.CustomField(nil), customFields...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for _, cf := range sorted {
+		name := CustomFilterFieldName(cf.Name)
+		if name == "custom." {
+			continue
+		}
+		if _, taken := schema.Field(name); taken {
+			continue
+		}
+
+		field := filter.Field{
+			Name:        name,
+			Type:        filter.FieldString,
+			Description: cf.DisplayName,
+			Expr:        customFieldValue,
+			ExprArgs:    []interface{}{cf.Name},
+		}
+		switch cf.FieldType {
+		case domain.CustomFieldTypeNumber:
+			field.Type = filter.FieldNumber
+			field.Expr, field.ExprArgs = customFieldNumber, []interface{}{cf.Name, cf.Name}
+		case domain.CustomFieldTypeDate:
+			field.Type = filter.FieldDate
+			field.Expr, field.ExprArgs = customFieldDate, []interface{}{cf.Name, cf.Name}
+		case domain.CustomFieldTypeCheckbox:
+			field.Type = filter.FieldBool
+		case domain.CustomFieldTypeChoice:
+			var validation domain.CustomFieldValidation
+			if len(cf.Validation) > 0 {
+				json.Unmarshal(cf.Validation, &validation)
+			}
+			if len(validation.AllowedValues) > 0 {
+				field.Type = filter.FieldEnum
+				field.Values = validation.AllowedValues
+			}
+		}
+		schema.Add(field)
+	}
+
+	return schema
+}
+
+// Schema returns the risk filter schema with the visible risk custom fields
+func (s *RiskFilterService) Schema() (*filter.Schema, error) {
+	customFields, err := s.customFields.GetCustomFieldsByScope(domain.CustomFieldScopeRisk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load custom fields: %w", err)
+	}
+	return RiskFilterSchema(customFields), nil
+}
+
+// Context returns the filter context of a user, evaluated now
+func (s *RiskFilterService) Context(userID uuid.UUID) *RiskFilterContext {
+	ctx := &RiskFilterContext{UserID: userID, Now: time.Now()}
+	if userID != uuid.Nil {
+		var user domain.User
+		if err := s.db.Select("email").First(&user, "id = ?", userID).Error; err == nil {
+			ctx.Email = user.Email
+		}
+	}
+	return ctx
+}
+
+// Compile parses and validates an expression. Syntax and validation errors are
+// returned as *filter.Error with the position of the offending token.
+func (s *RiskFilterService) Compile(expression string, fctx *RiskFilterContext) (*RiskFilter, error) {
+	var asOf *time.Time
+	if fctx != nil {
+		asOf = fctx.AsOf
+	}
+
+	node, err := filter.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return &RiskFilter{AsOf: asOf}, nil
+	}
+
+	schema, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := filter.Options{Resolve: s.resolver(fctx)}
+	if fctx != nil {
+		opts.Now = fctx.Now
+	}
+	if asOf != nil {
+		opts.Now = *asOf
+		for _, field := range asOfFilterFields {
+			schema.Add(field)
+		}
+	}
+	cond, err := filter.Compile(node, schema, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RiskFilter{Expression: strings.TrimSpace(expression), AsOf: asOf, cond: cond}, nil
+}
+
+// resolver expands @me and @team:<name> into the user IDs and emails risk
+// owners may be recorded as
+func (s *RiskFilterService) resolver(fctx *RiskFilterContext) func(string) ([]string, error) {
+	return func(macro string) ([]string, error) {
+		switch {
+		case strings.EqualFold(macro, "me"):
+			if fctx == nil || fctx.UserID == uuid.Nil {
+				return nil, fmt.Errorf("requires a signed-in user")
+			}
+			values := []string{fctx.UserID.String()}
+			if fctx.Email != "" {
+				values = append(values, fctx.Email)
+			}
+			return values, nil
+
+		case len(macro) > 5 && strings.EqualFold(macro[:5], "team:"):
+			name := macro[5:]
+			var team domain.Team
+			if err := s.db.Select("id").Where("LOWER(name) = LOWER(?)", name).First(&team).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return nil, fmt.Errorf("unknown team %q", name)
+				}
+				return nil, fmt.Errorf("failed to load team: %w", err)
+			}
+
+			var members []domain.User
+			if err := s.db.Select("users.id, users.email").
+				Joins("JOIN team_members ON team_members.user_id = users.id AND team_members.deleted_at IS NULL").
+				Where("team_members.team_id = ?", team.ID).
+				Find(&members).Error; err != nil {
+				return nil, fmt.Errorf("failed to load team members: %w", err)
+			}
+
+			values := make([]string, 0, 2*len(members))
+			for _, m := range members {
+				values = append(values, m.ID.String())
+				if m.Email != "" {
+					values = append(values, m.Email)
+				}
+			}
+			return values, nil
+		}
+
+		return nil, fmt.Errorf("unknown macro (use @me or @team:<name>)")
+	}
+}
+
+// LegacyRiskFilter translates the key/value filters the API accepted before the
+// filter language (status, min_score, max_score, tags, tag, q) into an expression
+func LegacyRiskFilter(params map[string]interface{}) string {
+	var parts []string
+
+	if status, ok := params["status"].(string); ok && status != "" {
+		parts = append(parts, "status = "+filter.Quote(status))
+	}
+	if min, ok := legacyNumber(params["min_score"]); ok {
+		parts = append(parts, "score >= "+min)
+	}
+	if max, ok := legacyNumber(params["max_score"]); ok {
+		parts = append(parts, "score <= "+max)
+	}
+
+	var tags []string
+	switch v := params["tags"].(type) {
+	case []interface{}:
+		for _, t := range v {
+			if s, ok := t.(string); ok && s != "" {
+				tags = append(tags, filter.Quote(s))
+			}
+		}
+	case []string:
+		for _, s := range v {
+			if s != "" {
+				tags = append(tags, filter.Quote(s))
+			}
+		}
+	}
+	if len(tags) > 0 {
+		parts = append(parts, "tags HAS ANY ("+strings.Join(tags, ", ")+")")
+	}
+	if tag, ok := params["tag"].(string); ok && tag != "" {
+		parts = append(parts, "tags HAS "+filter.Quote(tag))
+	}
+
+	if q, ok := params["q"].(string); ok && strings.TrimSpace(q) != "" {
+		q = filter.Quote(strings.TrimSpace(q))
+		parts = append(parts, "(title CONTAINS "+q+" OR description CONTAINS "+q+")")
+	}
+
+	return strings.Join(parts, " AND ")
+}
+
+func legacyNumber(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case float64:
+		return fmt.Sprintf("%g", n), true
+	case int:
+		return fmt.Sprintf("%d", n), true
+	case string:
+		var f float64
+		if _, err := fmt.Sscanf(n, "%g", &f); err == nil {
+			return fmt.Sprintf("%g", f), true
+		}
+	}
+	return "", false
+}
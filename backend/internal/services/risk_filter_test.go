@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestLegacyRiskFilter(t *testing.T) {
+	assert.Equal(t, "", LegacyRiskFilter(nil))
+
+	// Bulk operation filter_query as decoded from JSON
+	expr := LegacyRiskFilter(map[string]interface{}{
+		"status":    "ACTIVE",
+		"min_score": 12.0,
+		"max_score": "20",
+		"tags":      []interface{}{"GDPR", `ISO "27001"`},
+	})
+	assert.Equal(t, `status = "ACTIVE" AND score >= 12 AND score <= 20 AND tags HAS ANY ("GDPR", "ISO \"27001\"")`, expr)
+
+	cond, err := filter.CompileString(expr, RiskFilterSchema(nil), filter.Options{})
+	require.NoError(t, err)
+	assert.Len(t, cond.Args, 4)
+
+	// List query params
+	expr = LegacyRiskFilter(map[string]interface{}{"q": " leak ", "tag": "PCI", "min_score": "abc"})
+	assert.Equal(t, `tags HAS "PCI" AND (title CONTAINS "leak" OR description CONTAINS "leak")`, expr)
+}
+
+func TestRiskFilterSchemaCustomFields(t *testing.T) {
+	schema := RiskFilterSchema([]*domain.CustomField{
+		{Name: "Business Unit", FieldType: domain.CustomFieldTypeChoice,
+			Validation: datatypes.JSON(`{"allowed_values":["EU","US"]}`)},
+		{Name: "budget", FieldType: domain.CustomFieldTypeNumber},
+		{Name: "title", FieldType: domain.CustomFieldTypeText},
+		{Name: "???", FieldType: domain.CustomFieldTypeText},
+	})
+
+	cond, err := filter.CompileString(`custom.business_unit = "eu" AND custom.budget > 1000 AND custom.title CONTAINS "x"`, schema, filter.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"Business Unit", "EU", "budget", "budget", 1000.0, "title", "%x%"}, cond.Args)
+
+	_, err = filter.CompileString(`custom.business_unit = "APAC"`, schema, filter.Options{})
+	assert.Error(t, err, "choice values are validated")
+
+	_, ok := schema.Field("custom.")
+	assert.False(t, ok, "names without usable characters are skipped")
+}
+
+func TestCustomFilterFieldName(t *testing.T) {
+	assert.Equal(t, "custom.business_unit", CustomFilterFieldName(" Business Unit "))
+	assert.Equal(t, "custom.risk_id", CustomFilterFieldName("Risk-ID#"))
+}
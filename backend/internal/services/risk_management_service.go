@@ -1,23 +1,29 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/opendefender/openrisk/internal/core/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // RiskManagementService handles ISO 31000 and NIST RMF compliant risk lifecycle management
 type RiskManagementService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	approvals *ApprovalWorkflowService
+	reviews   *ReviewSchedulerService
 }
 
 // NewRiskManagementService creates a new risk management service
 func NewRiskManagementService(db *gorm.DB) *RiskManagementService {
 	return &RiskManagementService{
-		db: db,
+		db:        db,
+		approvals: NewApprovalWorkflowService(db),
+		reviews:   NewReviewSchedulerService(db),
 	}
 }
 
@@ -46,7 +52,7 @@ func (s *RiskManagementService) IdentifyRisk(
 		return nil, fmt.Errorf("failed to identify risk: %w", err)
 	}
 
-	s.logChange(tenantID, riskRegister.ID, "RISK_REGISTER", riskRegister.ID, "CREATE", "", "", identifiedBy)
+	s.logChange(tenantID, riskRegister.ID, "RISK_REGISTER", riskRegister.ID, "CREATE", "", "", "", identifiedBy)
 	return riskRegister, nil
 }
 
@@ -151,7 +157,13 @@ func (s *RiskManagementService) CreateTreatmentPlan(
 	riskRegister.Status = "TREATMENT_PLANNED"
 	s.db.Save(&riskRegister)
 
-	s.logChange(tenantID, riskRegisterID, "TREATMENT_PLAN", treatmentPlan.ID, "CREATE", "", "", createdBy)
+	s.logChange(tenantID, riskRegisterID, "TREATMENT_PLAN", treatmentPlan.ID, "CREATE", "", "", "", createdBy)
+
+	// Route the plan through the tenant's approval chain when one is configured
+	if _, err := s.approvals.StartApproval(tenantID, domain.ApprovalEntityTreatmentPlan, treatmentPlan.ID, riskRegisterID, treatmentType, createdBy); err != nil {
+		return nil, fmt.Errorf("failed to start treatment plan approval: %w", err)
+	}
+
 	return treatmentPlan, nil
 }
 
@@ -180,7 +192,7 @@ func (s *RiskManagementService) AddTreatmentAction(
 		return nil, fmt.Errorf("failed to add action: %w", err)
 	}
 
-	s.logChange(tenantID, treatmentPlanID, "TREATMENT_ACTION", action.ID, "CREATE", "", "", createdBy)
+	s.logChange(tenantID, treatmentPlanID, "TREATMENT_ACTION", action.ID, "CREATE", "", "", "", createdBy)
 	return action, nil
 }
 
@@ -201,12 +213,13 @@ func (s *RiskManagementService) CreateMonitoringReview(
 
 	currentRiskScore := float64(currentProbability * currentImpact)
 	currentRiskLevel := s.calculateRiskLevel(currentRiskScore)
+	reviewedAt := time.Now()
 
 	review := &domain.RiskMonitoringReview{
 		ID:                      uuid.New(),
 		RiskRegisterID:          riskRegisterID,
 		TenantID:                tenantID,
-		ReviewDate:              time.Now(),
+		ReviewDate:              reviewedAt,
 		ReviewType:              reviewType,
 		ReviewedBy:              reviewedBy,
 		CurrentProbabilityScore: currentProbability,
@@ -215,7 +228,7 @@ func (s *RiskManagementService) CreateMonitoringReview(
 		CurrentRiskLevel:        currentRiskLevel,
 		TreatmentEffectiveness:  treatmentEffectiveness,
 		StatusChangedFrom:       string(riskRegister.Status),
-		NextReviewDate:          time.Now().AddDate(0, 1, 0),
+		NextReviewDate:          s.reviews.NextReviewDate(tenantID, riskRegisterID, currentRiskLevel, reviewedAt),
 	}
 
 	if err := s.db.Create(review).Error; err != nil {
@@ -229,7 +242,15 @@ func (s *RiskManagementService) CreateMonitoringReview(
 	riskRegister.Status = "MONITORED"
 	s.db.Save(&riskRegister)
 
-	s.logChange(tenantID, riskRegisterID, "MONITORING_REVIEW", review.ID, "CREATE", "", "", reviewedBy)
+	// The review completes any scheduled review task and restarts the treatment plans' review cycle
+	if err := s.reviews.CompleteReviewTasks(tenantID, riskRegisterID, review.ID, reviewedBy, reviewedAt); err != nil {
+		return nil, fmt.Errorf("failed to complete review tasks: %w", err)
+	}
+	s.db.Model(&domain.RiskTreatmentPlan{}).
+		Where("risk_register_id = ? AND review_frequency <> ''", riskRegisterID).
+		Updates(map[string]interface{}{"last_review_date": reviewedAt, "next_review_date": review.NextReviewDate})
+
+	s.logChange(tenantID, riskRegisterID, "MONITORING_REVIEW", review.ID, "CREATE", "", "", "", reviewedBy)
 	return review, nil
 }
 
@@ -262,31 +283,88 @@ func (s *RiskManagementService) RecordDecision(
 		return nil, fmt.Errorf("failed to record decision: %w", err)
 	}
 
-	s.logChange(tenantID, riskRegisterID, "RISK_DECISION", decision.ID, "CREATE", "", "", decisionMaker)
+	s.logChange(tenantID, riskRegisterID, "RISK_DECISION", decision.ID, "CREATE", "", "", "", decisionMaker)
+
+	// Route the decision through the tenant's approval chain when one is configured
+	request, err := s.approvals.StartApproval(tenantID, domain.ApprovalEntityDecision, decision.ID, riskRegisterID, decisionType, decisionMaker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start decision approval: %w", err)
+	}
+	if request != nil {
+		decision.ApprovalRequired = true
+		s.db.Model(decision).Update("approval_required", true)
+	}
+
 	return decision, nil
 }
 
-// ApproveDecision approves a recorded decision
+// ApproveDecision approves a recorded decision.
+// Decisions governed by an approval workflow must be approved through their approval tasks;
+// once the workflow rejected or cancelled them they cannot be approved directly.
+// With ifMatch, the decision is approved only if it is still at that version
+// (see DecisionVersion); on ErrVersionConflict the current decision is returned.
 func (s *RiskManagementService) ApproveDecision(
 	tenantID uuid.UUID,
 	decisionID uuid.UUID,
 	approvedBy uuid.UUID,
-) error {
+	ifMatch string,
+) (*domain.RiskDecision, error) {
 	var decision domain.RiskDecision
-	if err := s.db.First(&decision, "id = ?", decisionID).Error; err != nil {
-		return fmt.Errorf("decision not found: %w", err)
+	var oldStatus string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&decision, "id = ? AND tenant_id = ?", decisionID, tenantID).Error; err != nil {
+			return fmt.Errorf("decision not found: %w", err)
+		}
+		if err := domain.CheckVersion(ifMatch, DecisionVersion(&decision)); err != nil {
+			return err
+		}
+
+		// A decision routed through a workflow is approved only by it: cancelling or
+		// getting the chain rejected must not open the way to a direct approval
+		latest, err := s.approvals.LatestApproval(domain.ApprovalEntityDecision, decisionID)
+		if err != nil {
+			return fmt.Errorf("failed to check approval workflow: %w", err)
+		}
+		if latest != nil && latest.Status == domain.ApprovalRequestPending {
+			return ErrApprovalPending
+		}
+		if (latest != nil || decision.ApprovalRequired) && (latest == nil || latest.Status != domain.ApprovalRequestApproved) {
+			return ErrApprovalNotGranted
+		}
+
+		oldStatus = decision.Status
+		decision.Status = "APPROVED"
+		decision.ApprovedBy = approvedBy
+		decision.ApprovedDate = time.Now()
+
+		if err := tx.Save(&decision).Error; err != nil {
+			return fmt.Errorf("failed to approve decision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrVersionConflict) {
+			return &decision, err
+		}
+		return nil, err
 	}
 
-	decision.Status = "APPROVED"
-	decision.ApprovedBy = approvedBy
-	decision.ApprovedDate = time.Now()
+	s.logChange(tenantID, decision.RiskRegisterID, "RISK_DECISION", decisionID, "APPROVE", "status", oldStatus, decision.Status, approvedBy)
+	return &decision, nil
+}
 
-	if err := s.db.Save(&decision).Error; err != nil {
-		return fmt.Errorf("failed to approve decision: %w", err)
+// GetDecision returns a recorded decision
+func (s *RiskManagementService) GetDecision(tenantID uuid.UUID, decisionID uuid.UUID) (*domain.RiskDecision, error) {
+	var decision domain.RiskDecision
+	if err := s.db.First(&decision, "id = ? AND tenant_id = ?", decisionID, tenantID).Error; err != nil {
+		return nil, fmt.Errorf("decision not found: %w", err)
 	}
+	return &decision, nil
+}
 
-	s.logChange(tenantID, decision.RiskRegisterID, "RISK_DECISION", decisionID, "APPROVE", decision.Status, "APPROVED", approvedBy)
-	return nil
+// DecisionVersion is the version of a decision used as its ETag
+func DecisionVersion(decision *domain.RiskDecision) int64 {
+	return int64(decision.Version)
 }
 
 // GenerateAuditReport creates audit-ready report for compliance
@@ -318,13 +396,18 @@ func (s *RiskManagementService) GenerateAuditReport(
 		return nil, fmt.Errorf("failed to generate audit report: %w", err)
 	}
 
-	s.logChange(tenantID, uuid.Nil, "AUDIT_REPORT", report.ID, "CREATE", "", "", generatedBy)
+	s.logChange(tenantID, uuid.Nil, "AUDIT_REPORT", report.ID, "CREATE", "", "", "", generatedBy)
 	return report, nil
 }
 
 // Helper functions
 
 func (s *RiskManagementService) calculateRiskLevel(score float64) string {
+	return riskLevelForScore(score)
+}
+
+// riskLevelForScore maps a probability x impact score to a risk level
+func riskLevelForScore(score float64) string {
 	if score <= 5 {
 		return "LOW"
 	} else if score <= 12 {
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -65,24 +66,27 @@ func (s *RiskTimelineService) GetRiskChangesByType(riskID uuid.UUID, changeType
 	return history, nil
 }
 
-// GetStatusChanges retrieves only status change events
+// GetStatusChanges retrieves only the events that changed the status
 func (s *RiskTimelineService) GetStatusChanges(riskID uuid.UUID) ([]*domain.RiskHistory, error) {
-	var history []*domain.RiskHistory
-	if err := s.db.Where("risk_id = ? AND change_type = ?", riskID, "STATUS_CHANGE").
-		Order("created_at DESC").
-		Find(&history).Error; err != nil {
-		return nil, err
-	}
-	return history, nil
+	return s.GetFieldChanges(riskID, "status")
 }
 
-// GetScoreChanges retrieves only score change events
+// GetScoreChanges retrieves only the events that changed the score
 func (s *RiskTimelineService) GetScoreChanges(riskID uuid.UUID) ([]*domain.RiskHistory, error) {
+	return s.GetFieldChanges(riskID, "score")
+}
+
+// GetFieldChanges retrieves the events whose diff includes a field
+func (s *RiskTimelineService) GetFieldChanges(riskID uuid.UUID, field string) ([]*domain.RiskHistory, error) {
+	match, err := json.Marshal([]map[string]string{{"field": field}})
+	if err != nil {
+		return nil, err
+	}
 	var history []*domain.RiskHistory
-	if err := s.db.Where("risk_id = ? AND change_type = ?", riskID, "SCORE_CHANGE").
+	if err := s.db.Where("risk_id = ? AND changes @> ?::jsonb", riskID, string(match)).
 		Order("created_at DESC").
 		Find(&history).Error; err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get risk changes: %w", err)
 	}
 	return history, nil
 }
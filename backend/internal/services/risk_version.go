@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/filter"
+)
+
+var (
+	ErrRiskVersionNotFound = errors.New("risk version not found")
+	ErrInvalidAsOf         = errors.New("invalid as_of date")
+)
+
+// versionValidAt selects the risk versions valid at a date, deleted risks excluded
+const versionValidAt = "rv.valid_from <= ? AND (rv.valid_to IS NULL OR rv.valid_to > ?) AND NOT rv.deleted"
+
+// risksAsOf is the risks table as it was at a date, rebuilt from the versions
+// valid then. It has the columns of risks, so filters, sorting and
+// aggregates written for the table apply unchanged, plus the version snapshot
+// for the relations.
+func risksAsOf(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Table("risk_versions AS rv").
+		Select("(jsonb_populate_record(NULL::risks, rv.snapshot)).*, rv.snapshot AS version_snapshot").
+		Where(versionValidAt, at, at)
+}
+
+// mitigationsAsOf is the mitigations table as it was at a date, read from the
+// mitigations recorded in the risk versions valid then
+func mitigationsAsOf(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Table("risk_versions AS rv, jsonb_to_recordset(COALESCE(rv.snapshot -> 'mitigations', '[]'::jsonb)) AS m("+
+			"id uuid, risk_id uuid, title text, assignee text, status text, progress int, due_date timestamptz, "+
+			"cost int, mitigation_time int, created_at timestamptz, updated_at timestamptz)").
+		Select("m.*, NULL::timestamptz AS deleted_at").
+		Where(versionValidAt, at, at)
+}
+
+// riskAssetsAsOf matches the assets recorded in the version of the risk being
+// filtered; it stands in for riskAssetsExists when filtering as of a date
+const riskAssetsAsOf = "SELECT 1 FROM jsonb_to_recordset(COALESCE(risks.version_snapshot -> 'assets', '[]'::jsonb)) AS a(name text, external_id text) WHERE true"
+
+// ParseAsOf parses the as_of parameter of a request: an RFC 3339 timestamp, or
+// a date standing for the end of that day (UTC). Empty means now and returns nil.
+func ParseAsOf(raw string, now time.Time) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	at, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		day, dayErr := time.Parse("2006-01-02", raw)
+		if dayErr != nil {
+			return nil, fmt.Errorf("%w: use YYYY-MM-DD or an RFC 3339 timestamp, got %q", ErrInvalidAsOf, raw)
+		}
+		at = day.AddDate(0, 0, 1).Add(-time.Microsecond)
+		if at.After(now) && !day.After(now) {
+			// Today, so far
+			at = now
+		}
+	}
+	if at.After(now) {
+		return nil, fmt.Errorf("%w: %s is in the future", ErrInvalidAsOf, raw)
+	}
+	at = at.UTC()
+	return &at, nil
+}
+
+// At is the date the filter reads the register at: its as-of date, or now
+func (f *RiskFilter) At() time.Time {
+	if f != nil && f.AsOf != nil {
+		return *f.AsOf
+	}
+	return time.Now()
+}
+
+// Preload preloads relations of the selected risks from the current tables.
+// As of a date it preloads nothing: AttachVersions sets the relations the
+// risks had then.
+func (f *RiskFilter) Preload(db *gorm.DB, relations ...string) *gorm.DB {
+	if f != nil && f.AsOf != nil {
+		return db
+	}
+	for _, relation := range relations {
+		db = db.Preload(relation)
+	}
+	return db
+}
+
+// AttachVersions sets the mitigations and assets of risks read as of a date
+// to those recorded in their versions; without a date it does nothing
+func (f *RiskFilter) AttachVersions(db *gorm.DB, risks []*domain.Risk) error {
+	if f == nil || f.AsOf == nil || len(risks) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(risks))
+	for i, risk := range risks {
+		ids[i] = risk.ID
+	}
+
+	var versions []domain.RiskVersion
+	if err := db.Table("risk_versions AS rv").Select("rv.risk_id, rv.snapshot").
+		Where(versionValidAt, *f.AsOf, *f.AsOf).Where("rv.risk_id IN ?", ids).
+		Find(&versions).Error; err != nil {
+		return fmt.Errorf("failed to load risk versions: %w", err)
+	}
+	byRisk := make(map[uuid.UUID]*domain.Risk, len(versions))
+	for i := range versions {
+		risk, err := versions[i].Risk()
+		if err != nil {
+			return err
+		}
+		byRisk[versions[i].RiskID] = risk
+	}
+	for _, risk := range risks {
+		if version := byRisk[risk.ID]; version != nil {
+			risk.Mitigations, risk.Assets = version.Mitigations, version.Assets
+		}
+	}
+	return nil
+}
+
+// Mitigations starts a query on the mitigations of the selected risks; as of a
+// date, on the mitigations recorded in the risk versions
+func (f *RiskFilter) Mitigations(db *gorm.DB) *gorm.DB {
+	query := db.Model(&domain.Mitigation{})
+	if f == nil {
+		return query
+	}
+	if f.AsOf != nil {
+		query = query.Table("(?) AS mitigations", mitigationsAsOf(db, *f.AsOf))
+	}
+	if f.cond != nil && f.cond.SQL != "" {
+		query = query.Where("mitigations.risk_id IN (?)", f.Scope(db.Model(&domain.Risk{})).Select("risks.id"))
+	}
+	return query
+}
+
+// RiskVersionService reads the version history of risks. Versions are
+// recorded by the Risk and Mitigation hooks.
+type RiskVersionService struct {
+	db *gorm.DB
+}
+
+// NewRiskVersionService creates a new risk version service
+func NewRiskVersionService(db *gorm.DB) *RiskVersionService {
+	return &RiskVersionService{db: db}
+}
+
+// ListVersions returns the versions of a risk, latest first
+func (s *RiskVersionService) ListVersions(riskID uuid.UUID) ([]domain.RiskVersion, error) {
+	var versions []domain.RiskVersion
+	if err := s.db.Where("risk_id = ?", riskID).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil, ErrRiskVersionNotFound
+	}
+	return versions, nil
+}
+
+// GetRiskAsOf returns a risk, with its mitigations and assets, as it was at a
+// date. Risks that did not exist yet or were deleted then are not found.
+func (s *RiskVersionService) GetRiskAsOf(riskID uuid.UUID, at time.Time) (*domain.Risk, error) {
+	var version domain.RiskVersion
+	if err := s.db.Table("risk_versions AS rv").Where(versionValidAt, at, at).
+		Where("rv.risk_id = ?", riskID).Take(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRiskVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to load risk version: %w", err)
+	}
+	return version.Risk()
+}
+
+// Backfill records a baseline version of the risks created before
+// versioning, so that as-of queries see them. It returns how many were
+// recorded.
+func (s *RiskVersionService) Backfill() (int, error) {
+	var ids []uuid.UUID
+	if err := s.db.Unscoped().Model(&domain.Risk{}).
+		Where("NOT EXISTS (SELECT 1 FROM risk_versions rv WHERE rv.risk_id = risks.id)").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to list unversioned risks: %w", err)
+	}
+	for i, id := range ids {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return domain.BaselineRiskVersion(tx, id)
+		}); err != nil {
+			return i, fmt.Errorf("failed to record baseline of risk %s: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// RegisterDiff compares the risk register at two dates
+type RegisterDiff struct {
+	From      time.Time  `json:"from"`
+	To        time.Time  `json:"to"`
+	Filter    string     `json:"filter,omitempty"`
+	Added     []RiskDiff `json:"added"`   // Selected at To only: created, or matching the filter since
+	Removed   []RiskDiff `json:"removed"` // Selected at From only: deleted, or no longer matching
+	Changed   []RiskDiff `json:"changed"`
+	Unchanged int        `json:"unchanged"`
+}
+
+// RiskDiff is a risk that differs between the two dates
+type RiskDiff struct {
+	RiskID  uuid.UUID                `json:"risk_id"`
+	Title   string                   `json:"title"`
+	Changes []domain.RiskFieldChange `json:"changes,omitempty"`
+}
+
+// Diff compares the risks selected by from and to, two compilations of the
+// same expression as of the two dates
+func (s *RiskVersionService) Diff(ctx context.Context, from, to *RiskFilter) (*RegisterDiff, error) {
+	if from == nil || from.AsOf == nil || to == nil || to.AsOf == nil {
+		return nil, fmt.Errorf("%w: both dates are required", ErrInvalidAsOf)
+	}
+	if from.AsOf.After(*to.AsOf) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidAsOf)
+	}
+	before, err := s.risksAt(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.risksAt(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RegisterDiff{From: *from.AsOf, To: *to.AsOf, Filter: to.Expression,
+		Added: []RiskDiff{}, Removed: []RiskDiff{}, Changed: []RiskDiff{}}
+	for id, risk := range after {
+		old, ok := before[id]
+		if !ok {
+			diff.Added = append(diff.Added, RiskDiff{RiskID: id, Title: risk.Title})
+			continue
+		}
+		if changes := domain.DiffRisks(old, risk); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, RiskDiff{RiskID: id, Title: risk.Title, Changes: changes})
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for id, risk := range before {
+		if _, ok := after[id]; !ok {
+			diff.Removed = append(diff.Removed, RiskDiff{RiskID: id, Title: risk.Title})
+		}
+	}
+	for _, list := range [][]RiskDiff{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Title != list[j].Title {
+				return list[i].Title < list[j].Title
+			}
+			return list[i].RiskID.String() < list[j].RiskID.String()
+		})
+	}
+	return diff, nil
+}
+
+// risksAt decodes the versions of the risks a filter selects at its date
+func (s *RiskVersionService) risksAt(ctx context.Context, f *RiskFilter) (map[uuid.UUID]*domain.Risk, error) {
+	var rows []struct {
+		ID              uuid.UUID
+		VersionSnapshot datatypes.JSON
+	}
+	if err := f.Scope(s.db.WithContext(ctx).Model(&domain.Risk{})).
+		Select("risks.id, risks.version_snapshot").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk versions: %w", err)
+	}
+	risks := make(map[uuid.UUID]*domain.Risk, len(rows))
+	for _, row := range rows {
+		version := domain.RiskVersion{Snapshot: row.VersionSnapshot}
+		risk, err := version.Risk()
+		if err != nil {
+			return nil, err
+		}
+		risks[row.ID] = risk
+	}
+	return risks, nil
+}
+
+// asOfFilterFields replace the fields whose SQL reads other tables than risks
+// when a filter is compiled as of a date
+var asOfFilterFields = []filter.Field{
+	{Name: "assets", Type: filter.FieldRelation, Description: "Asset names or external IDs",
+		Relation: &filter.Relation{Exists: riskAssetsAsOf, Match: []string{"a.name", "a.external_id"}}},
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAsOf(t *testing.T) {
+	now := time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	at, err := ParseAsOf("", now)
+	require.NoError(t, err)
+	assert.Nil(t, at, "empty means now")
+
+	at, err = ParseAsOf("2026-03-31", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 31, 23, 59, 59, 999999000, time.UTC), *at, "a date is the end of that day")
+
+	at, err = ParseAsOf("2026-06-15", now)
+	require.NoError(t, err)
+	assert.Equal(t, now, *at, "today stops at now")
+
+	at, err = ParseAsOf("2026-06-01T12:00:00+02:00", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC), *at)
+	assert.Equal(t, time.UTC, at.Location())
+
+	for _, raw := range []string{"2026-06-16", "2027-01-01T00:00:00Z", "yesterday", "31/03/2026"} {
+		_, err := ParseAsOf(raw, now)
+		assert.True(t, errors.Is(err, ErrInvalidAsOf), raw)
+	}
+}
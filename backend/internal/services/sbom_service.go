@@ -0,0 +1,713 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrVulnerabilityNotFound = errors.New("vulnerability not found")
+	ErrLicensePolicyNotFound = errors.New("license policy not found")
+	ErrInvalidLicensePolicy  = errors.New("invalid license policy")
+	ErrEmptySBOM             = errors.New("SBOM lists no component")
+)
+
+// severityScores maps a vulnerability or license policy severity to the
+// impact and probability of the risk it raises
+var severityScores = map[string][2]int{
+	"CRITICAL": {5, 4},
+	"HIGH":     {4, 3},
+	"MEDIUM":   {3, 3},
+	"LOW":      {2, 2},
+}
+
+func scoresForSeverity(severity string) (impact, probability int) {
+	s, ok := severityScores[strings.ToUpper(severity)]
+	if !ok {
+		s = severityScores["MEDIUM"]
+	}
+	return s[0], s[1]
+}
+
+// SBOMService keeps the software components of assets from their SBOMs and
+// matches them against the vulnerability feed and the license policies. A
+// vulnerability found on an asset raises a risk on it (source SBOM), a
+// forbidden license a compliance risk (source SBOM_LICENSE); the risk is
+// resolved once a later SBOM or feed no longer reports it.
+type SBOMService struct {
+	db *gorm.DB
+}
+
+// NewSBOMService creates a new SBOM service
+func NewSBOMService(db *gorm.DB) *SBOMService {
+	return &SBOMService{db: db}
+}
+
+// SBOMMatchResult sums up the matching of an asset's components
+type SBOMMatchResult struct {
+	Vulnerabilities   int `json:"vulnerabilities"`
+	LicenseViolations int `json:"license_violations"`
+	RisksCreated      int `json:"risks_created"`
+	RisksUpdated      int `json:"risks_updated"`
+	RisksResolved     int `json:"risks_resolved"`
+}
+
+func (r *SBOMMatchResult) add(other *SBOMMatchResult) {
+	r.Vulnerabilities += other.Vulnerabilities
+	r.LicenseViolations += other.LicenseViolations
+	r.RisksCreated += other.RisksCreated
+	r.RisksUpdated += other.RisksUpdated
+	r.RisksResolved += other.RisksResolved
+}
+
+// ImportSBOM records an SBOM of an asset, replaces the asset's components
+// with those it lists and matches them
+func (s *SBOMService) ImportSBOM(ctx context.Context, assetID uuid.UUID, doc domain.SBOMDocument, components []domain.SoftwareComponent) (*domain.SBOMDocument, *SBOMMatchResult, error) {
+	if len(components) == 0 {
+		return nil, nil, ErrEmptySBOM
+	}
+	var result *SBOMMatchResult
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var asset domain.Asset
+		if err := tx.Take(&asset, "id = ?", assetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAssetNotFound
+			}
+			return fmt.Errorf("failed to load asset: %w", err)
+		}
+
+		doc.ID = uuid.New()
+		doc.AssetID = asset.ID
+		doc.ComponentCount = len(components)
+		doc.UploadedBy = domain.ActorFrom(ctx)
+		if err := tx.Create(&doc).Error; err != nil {
+			return fmt.Errorf("failed to record SBOM: %w", err)
+		}
+
+		if err := tx.Where("asset_id = ?", asset.ID).Delete(&domain.ComponentVulnerability{}).Error; err != nil {
+			return fmt.Errorf("failed to clear vulnerabilities: %w", err)
+		}
+		if err := tx.Where("asset_id = ?", asset.ID).Delete(&domain.SoftwareComponent{}).Error; err != nil {
+			return fmt.Errorf("failed to clear components: %w", err)
+		}
+		for i := range components {
+			components[i].ID = uuid.New()
+			components[i].AssetID = asset.ID
+			components[i].SBOMID = doc.ID
+			components[i].Asset = nil
+		}
+		if err := tx.CreateInBatches(components, 500).Error; err != nil {
+			return fmt.Errorf("failed to store components: %w", err)
+		}
+
+		var err error
+		if result, err = matchAsset(tx, &asset); err != nil {
+			return err
+		}
+		doc.VulnerabilityCount = result.Vulnerabilities
+		doc.LicenseViolations = result.LicenseViolations
+		return tx.Model(&doc).Updates(map[string]interface{}{
+			"vulnerability_count": doc.VulnerabilityCount,
+			"license_violations":  doc.LicenseViolations,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &doc, result, nil
+}
+
+// ImportVulnerabilities loads advisories into the vulnerability feed,
+// replacing the ones already known, then matches every asset again
+func (s *SBOMService) ImportVulnerabilities(ctx context.Context, vulns []domain.Vulnerability) (*SBOMMatchResult, error) {
+	db := s.db.WithContext(ctx)
+	const chunk = 500
+	for start := 0; start < len(vulns); start += chunk {
+		batch := vulns[start:min(start+chunk, len(vulns))]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			ids := make([]string, len(batch))
+			var packages []domain.VulnerablePackage
+			for i := range batch {
+				ids[i] = batch[i].ID
+				for _, p := range batch[i].Packages {
+					p.ID = uuid.New()
+					p.VulnerabilityID = batch[i].ID
+					packages = append(packages, p)
+				}
+			}
+			if err := tx.Omit("Packages").Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"aliases", "summary", "details", "severity", "cvss_vector", "source", "published", "modified", "withdrawn", "updated_at"}),
+			}).Create(&batch).Error; err != nil {
+				return fmt.Errorf("failed to store vulnerabilities: %w", err)
+			}
+			if err := tx.Where("vulnerability_id IN ?", ids).Delete(&domain.VulnerablePackage{}).Error; err != nil {
+				return fmt.Errorf("failed to clear vulnerable packages: %w", err)
+			}
+			if len(packages) > 0 {
+				if err := tx.CreateInBatches(packages, chunk).Error; err != nil {
+					return fmt.Errorf("failed to store vulnerable packages: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.MatchAll(ctx)
+}
+
+// MatchAll matches the components of every asset that has some
+func (s *SBOMService) MatchAll(ctx context.Context) (*SBOMMatchResult, error) {
+	db := s.db.WithContext(ctx)
+	var assetIDs []uuid.UUID
+	if err := db.Model(&domain.SoftwareComponent{}).Distinct("asset_id").Pluck("asset_id", &assetIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list assets with components: %w", err)
+	}
+	total := &SBOMMatchResult{}
+	for _, id := range assetIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var asset domain.Asset
+			if err := tx.Take(&asset, "id = ?", id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			}
+			result, err := matchAsset(tx, &asset)
+			if err != nil {
+				return err
+			}
+			total.add(result)
+			return nil
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to match asset %s: %w", id, err)
+		}
+	}
+	return total, nil
+}
+
+// componentMatch is a vulnerability found on a component
+type componentMatch struct {
+	Component       *domain.SoftwareComponent
+	VulnerabilityID string
+	FixedVersion    string
+}
+
+// matchComponents finds the components in the affected versions of the
+// vulnerable packages. Packages match by ecosystem (its release suffix
+// aside: "Debian:12" is Debian) and case-insensitive name.
+func matchComponents(components []domain.SoftwareComponent, packages []domain.VulnerablePackage) []componentMatch {
+	byName := map[string][]domain.VulnerablePackage{}
+	for _, p := range packages {
+		key := strings.ToLower(p.Name)
+		byName[key] = append(byName[key], p)
+	}
+	var matches []componentMatch
+	seen := map[string]bool{}
+	for i := range components {
+		c := &components[i]
+		if c.Version == "" {
+			continue
+		}
+		for _, p := range byName[strings.ToLower(c.PackageName)] {
+			if c.Ecosystem != "" && !strings.EqualFold(baseEcosystem(p.Ecosystem), baseEcosystem(c.Ecosystem)) {
+				continue
+			}
+			fixed, affected := packageAffects(p, c.Version)
+			key := c.ID.String() + "|" + p.VulnerabilityID
+			if !affected || seen[key] {
+				continue
+			}
+			seen[key] = true
+			matches = append(matches, componentMatch{Component: c, VulnerabilityID: p.VulnerabilityID, FixedVersion: fixed})
+		}
+	}
+	return matches
+}
+
+func baseEcosystem(ecosystem string) string {
+	if i := strings.IndexByte(ecosystem, ':'); i >= 0 {
+		return ecosystem[:i]
+	}
+	return ecosystem
+}
+
+// packageAffects tells whether version is affected and, if a range holds
+// it, the version that fixes it
+func packageAffects(p domain.VulnerablePackage, version string) (fixed string, affected bool) {
+	var ranges []domain.VersionRange
+	if len(p.Ranges) > 0 {
+		_ = json.Unmarshal(p.Ranges, &ranges)
+	}
+	for _, r := range ranges {
+		if r.Contains(version) {
+			return r.Fixed, true
+		}
+	}
+	for _, v := range p.Versions {
+		if domain.CompareVersions(v, version) == 0 {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// licenseViolation is a component under a license a policy forbids
+type licenseViolation struct {
+	Policy     *domain.LicensePolicy
+	Components []*domain.SoftwareComponent
+}
+
+// findLicenseViolations groups by policy the components under a license
+// the policy forbids
+func findLicenseViolations(components []domain.SoftwareComponent, policies []domain.LicensePolicy) []licenseViolation {
+	byLicense := map[string]int{}
+	for i, p := range policies {
+		byLicense[strings.ToLower(p.License)] = i
+	}
+	found := map[int]*licenseViolation{}
+	for i := range components {
+		for _, id := range domain.LicenseIDs(components[i].Licenses) {
+			idx, ok := byLicense[strings.ToLower(id)]
+			if !ok {
+				continue
+			}
+			v := found[idx]
+			if v == nil {
+				v = &licenseViolation{Policy: &policies[idx]}
+				found[idx] = v
+			}
+			v.Components = append(v.Components, &components[i])
+		}
+	}
+	violations := make([]licenseViolation, 0, len(found))
+	for _, v := range found {
+		violations = append(violations, *v)
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Policy.License < violations[j].Policy.License })
+	return violations
+}
+
+// matchAsset matches the components of an asset, records the vulnerabilities
+// found and raises, updates or resolves the asset's SBOM risks
+func matchAsset(tx *gorm.DB, asset *domain.Asset) (*SBOMMatchResult, error) {
+	result := &SBOMMatchResult{}
+	var components []domain.SoftwareComponent
+	if err := tx.Where("asset_id = ?", asset.ID).Order("name, version").Find(&components).Error; err != nil {
+		return nil, fmt.Errorf("failed to load components: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, c := range components {
+		names[strings.ToLower(c.PackageName)] = true
+	}
+	var packages []domain.VulnerablePackage
+	if len(names) > 0 {
+		list := make([]string, 0, len(names))
+		for n := range names {
+			list = append(list, n)
+		}
+		if err := tx.Where("lower(name) IN ?", list).Find(&packages).Error; err != nil {
+			return nil, fmt.Errorf("failed to load vulnerable packages: %w", err)
+		}
+	}
+	matches := matchComponents(components, packages)
+
+	// Vulnerabilities found, and their components
+	byVuln := map[string][]componentMatch{}
+	var vulnIDs []string
+	for _, m := range matches {
+		if _, ok := byVuln[m.VulnerabilityID]; !ok {
+			vulnIDs = append(vulnIDs, m.VulnerabilityID)
+		}
+		byVuln[m.VulnerabilityID] = append(byVuln[m.VulnerabilityID], m)
+	}
+	sort.Strings(vulnIDs)
+	vulns := map[string]domain.Vulnerability{}
+	if len(vulnIDs) > 0 {
+		var list []domain.Vulnerability
+		if err := tx.Where("id IN ?", vulnIDs).Find(&list).Error; err != nil {
+			return nil, fmt.Errorf("failed to load vulnerabilities: %w", err)
+		}
+		for _, v := range list {
+			vulns[v.ID] = v
+		}
+	}
+
+	if err := tx.Where("asset_id = ?", asset.ID).Delete(&domain.ComponentVulnerability{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear vulnerabilities: %w", err)
+	}
+	raised := map[string]bool{}
+	for _, id := range vulnIDs {
+		vuln, ok := vulns[id]
+		if !ok {
+			continue
+		}
+		found := byVuln[id]
+		result.Vulnerabilities++
+		externalID := id + "@" + asset.ID.String()
+		riskID, created, err := upsertSBOMRisk(tx, asset, domain.RiskSourceSBOM, externalID, vulnerabilityRiskContent(asset, vuln, found), vuln.Severity)
+		if err != nil {
+			return nil, err
+		}
+		result.countRisk(riskID, created)
+		raised[externalID] = true
+
+		rows := make([]domain.ComponentVulnerability, 0, len(found))
+		for _, m := range found {
+			rows = append(rows, domain.ComponentVulnerability{
+				ComponentID:     m.Component.ID,
+				VulnerabilityID: id,
+				AssetID:         asset.ID,
+				FixedVersion:    m.FixedVersion,
+				RiskID:          riskID,
+			})
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to record vulnerabilities: %w", err)
+		}
+	}
+
+	var policies []domain.LicensePolicy
+	if err := tx.Order("license").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to load license policies: %w", err)
+	}
+	for _, v := range findLicenseViolations(components, policies) {
+		result.LicenseViolations++
+		if v.Policy.ReportOnly {
+			continue
+		}
+		externalID := v.Policy.License + "@" + asset.ID.String()
+		riskID, created, err := upsertSBOMRisk(tx, asset, domain.RiskSourceSBOMLicense, externalID, licenseRiskContent(asset, v), v.Policy.Severity)
+		if err != nil {
+			return nil, err
+		}
+		result.countRisk(riskID, created)
+		raised[externalID] = true
+	}
+
+	resolved, err := resolveSBOMRisks(tx, asset.ID, raised)
+	if err != nil {
+		return nil, err
+	}
+	result.RisksResolved = resolved
+	return result, nil
+}
+
+func (r *SBOMMatchResult) countRisk(riskID *uuid.UUID, created bool) {
+	switch {
+	case riskID == nil:
+	case created:
+		r.RisksCreated++
+	default:
+		r.RisksUpdated++
+	}
+}
+
+// sbomRiskContent is what an SBOM risk says
+type sbomRiskContent struct {
+	Title       string
+	Description string
+	Tags        []string
+}
+
+func vulnerabilityRiskContent(asset *domain.Asset, vuln domain.Vulnerability, found []componentMatch) sbomRiskContent {
+	ref := vuln.CVE()
+	if ref == "" {
+		ref = vuln.ID
+	}
+	names := make([]string, 0, len(found))
+	var lines []string
+	for _, m := range found {
+		names = append(names, m.Component.Name+" "+m.Component.Version)
+		line := fmt.Sprintf("- %s %s", m.Component.PackageName, m.Component.Version)
+		if m.FixedVersion != "" {
+			line += " (fixed in " + m.FixedVersion + ")"
+		}
+		lines = append(lines, line)
+	}
+	title := fmt.Sprintf("[VULN] %s in %s on %s", ref, strings.Join(names, ", "), asset.Name)
+
+	var b strings.Builder
+	if vuln.Summary != "" {
+		b.WriteString(vuln.Summary + "\n\n")
+	}
+	b.WriteString("Affected components:\n" + strings.Join(lines, "\n"))
+	fmt.Fprintf(&b, "\n\nDetected from the SBOM of %s (advisory %s).", asset.Name, vuln.ID)
+
+	tags := []string{"SBOM", "VULNERABILITY", vuln.ID}
+	for _, alias := range vuln.Aliases {
+		if alias != vuln.ID {
+			tags = append(tags, alias)
+		}
+	}
+	if vuln.Severity != "" {
+		tags = append(tags, vuln.Severity)
+	}
+	return sbomRiskContent{Title: truncate(title, 255), Description: b.String(), Tags: tags}
+}
+
+func licenseRiskContent(asset *domain.Asset, v licenseViolation) sbomRiskContent {
+	var lines []string
+	for _, c := range v.Components {
+		lines = append(lines, fmt.Sprintf("- %s %s (%s)", c.PackageName, c.Version, strings.Join(c.Licenses, ", ")))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s ships components under %s, which the license policy forbids.", asset.Name, v.Policy.License)
+	if v.Policy.Reason != "" {
+		b.WriteString("\n\n" + v.Policy.Reason)
+	}
+	b.WriteString("\n\nComponents:\n" + strings.Join(lines, "\n"))
+	return sbomRiskContent{
+		Title:       truncate(fmt.Sprintf("[LICENSE] %s in %d component(s) of %s", v.Policy.License, len(v.Components), asset.Name), 255),
+		Description: b.String(),
+		Tags:        []string{"SBOM", "LICENSE", "COMPLIANCE", v.Policy.License},
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+// upsertSBOMRisk raises the risk of a finding on an asset, or updates it.
+// A mitigated risk found again is reopened; a risk sent to the recycle bin
+// is left there and nil is returned.
+func upsertSBOMRisk(tx *gorm.DB, asset *domain.Asset, source, externalID string, content sbomRiskContent, severity string) (*uuid.UUID, bool, error) {
+	var risk domain.Risk
+	err := tx.Unscoped().Where("source = ? AND external_id = ?", source, externalID).Take(&risk).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		impact, probability := scoresForSeverity(severity)
+		risk = domain.Risk{
+			ID:          uuid.New(),
+			Title:       content.Title,
+			Description: content.Description,
+			Impact:      impact,
+			Probability: probability,
+			Status:      domain.StatusActive,
+			Source:      source,
+			ExternalID:  externalID,
+			Tags:        content.Tags,
+			Assets:      []*domain.Asset{asset},
+		}
+		if err := NewImpactService(tx).ApplyEffectiveCriticality(risk.Assets); err != nil {
+			return nil, false, err
+		}
+		risk.Score = ComputeRiskScore(risk.Impact, risk.Probability, risk.Assets)
+		if err := tx.Omit("Assets.*").Create(&risk).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to create risk: %w", err)
+		}
+		return &risk.ID, true, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to load risk: %w", err)
+	case risk.DeletedAt.Valid:
+		return nil, false, nil
+	}
+
+	updates := map[string]interface{}{}
+	if risk.Title != content.Title {
+		updates["title"] = content.Title
+	}
+	if risk.Description != content.Description {
+		updates["description"] = content.Description
+	}
+	if strings.Join(risk.Tags, "\n") != strings.Join(content.Tags, "\n") {
+		updates["tags"] = pq.StringArray(content.Tags)
+	}
+	if risk.Status == domain.StatusMitigated {
+		updates["status"] = domain.StatusActive
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(&risk).Updates(updates).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to update risk: %w", err)
+		}
+	}
+	return &risk.ID, false, nil
+}
+
+// resolveSBOMRisks marks mitigated the open SBOM risks of an asset that were
+// not raised again
+func resolveSBOMRisks(tx *gorm.DB, assetID uuid.UUID, raised map[string]bool) (int, error) {
+	var risks []domain.Risk
+	if err := tx.Where("source IN ? AND external_id LIKE ? AND status IN ?",
+		[]string{domain.RiskSourceSBOM, domain.RiskSourceSBOMLicense}, "%@"+assetID.String(),
+		[]domain.RiskStatus{domain.StatusDraft, domain.StatusActive}).Find(&risks).Error; err != nil {
+		return 0, fmt.Errorf("failed to load SBOM risks: %w", err)
+	}
+	resolved := 0
+	for i := range risks {
+		if raised[risks[i].ExternalID] {
+			continue
+		}
+		if err := tx.Model(&risks[i]).Update("status", domain.StatusMitigated).Error; err != nil {
+			return resolved, fmt.Errorf("failed to resolve risk: %w", err)
+		}
+		resolved++
+	}
+	return resolved, nil
+}
+
+// ListSBOMs returns the SBOMs uploaded for an asset, most recent first
+func (s *SBOMService) ListSBOMs(assetID uuid.UUID) ([]domain.SBOMDocument, error) {
+	var docs []domain.SBOMDocument
+	if err := s.db.Where("asset_id = ?", assetID).Order("created_at DESC").Find(&docs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list SBOMs: %w", err)
+	}
+	return docs, nil
+}
+
+// ListComponents returns the components an asset ships
+func (s *SBOMService) ListComponents(assetID uuid.UUID) ([]domain.SoftwareComponent, error) {
+	var components []domain.SoftwareComponent
+	if err := s.db.Where("asset_id = ?", assetID).Order("name, version").Find(&components).Error; err != nil {
+		return nil, fmt.Errorf("failed to list components: %w", err)
+	}
+	return components, nil
+}
+
+// ListAssetVulnerabilities returns the vulnerabilities found on the
+// components of an asset
+func (s *SBOMService) ListAssetVulnerabilities(assetID uuid.UUID) ([]domain.ComponentVulnerability, error) {
+	var found []domain.ComponentVulnerability
+	if err := s.db.Preload("Component").Preload("Vulnerability").
+		Where("asset_id = ?", assetID).Order("vulnerability_id").Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to list vulnerabilities: %w", err)
+	}
+	return found, nil
+}
+
+// GetVulnerability returns an advisory with its vulnerable packages
+func (s *SBOMService) GetVulnerability(id string) (*domain.Vulnerability, error) {
+	var vuln domain.Vulnerability
+	if err := s.db.Preload("Packages").Take(&vuln, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVulnerabilityNotFound
+		}
+		return nil, fmt.Errorf("failed to load vulnerability: %w", err)
+	}
+	return &vuln, nil
+}
+
+// ComponentFilter selects shipped components: by name (the component's or
+// its package's), ecosystem, version constraint ("<2.17") and license
+type ComponentFilter struct {
+	Name       string
+	Ecosystem  string
+	Constraint domain.VersionConstraint
+	License    string
+	Limit      int
+	Offset     int
+}
+
+// SearchComponents answers "which assets ship log4j-core < 2.17": the
+// matching components with their asset, and the number of assets
+func (s *SBOMService) SearchComponents(filter ComponentFilter) ([]domain.SoftwareComponent, int, int, error) {
+	query := s.db.Preload("Asset")
+	if filter.Name != "" {
+		query = query.Where("lower(name) = lower(?) OR lower(package_name) = lower(?) OR purl ILIKE ?",
+			filter.Name, filter.Name, "pkg:%/"+filter.Name+"@%")
+	}
+	if filter.Ecosystem != "" {
+		query = query.Where("lower(ecosystem) = lower(?)", filter.Ecosystem)
+	}
+	if filter.License != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM unnest(licenses) l WHERE l ILIKE ?)", "%"+filter.License+"%")
+	}
+	var components []domain.SoftwareComponent
+	if err := query.Order("name, version").Find(&components).Error; err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to search components: %w", err)
+	}
+
+	// Versions compare in Go, not in SQL
+	matched := components[:0]
+	assets := map[uuid.UUID]bool{}
+	for _, c := range components {
+		if filter.Constraint != nil && !filter.Constraint.Matches(c.Version) {
+			continue
+		}
+		matched = append(matched, c)
+		assets[c.AssetID] = true
+	}
+	total := len(matched)
+	if filter.Offset >= total {
+		return []domain.SoftwareComponent{}, total, len(assets), nil
+	}
+	end := total
+	if filter.Limit > 0 && filter.Offset+filter.Limit < total {
+		end = filter.Offset + filter.Limit
+	}
+	return matched[filter.Offset:end], total, len(assets), nil
+}
+
+// ListLicensePolicies returns the license policies
+func (s *SBOMService) ListLicensePolicies() ([]domain.LicensePolicy, error) {
+	var policies []domain.LicensePolicy
+	if err := s.db.Order("license").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to list license policies: %w", err)
+	}
+	return policies, nil
+}
+
+// CreateLicensePolicy forbids a license and matches the assets again
+func (s *SBOMService) CreateLicensePolicy(ctx context.Context, policy *domain.LicensePolicy) (*SBOMMatchResult, error) {
+	if err := s.createLicensePolicy(policy); err != nil {
+		return nil, err
+	}
+	return s.MatchAll(ctx)
+}
+
+func (s *SBOMService) createLicensePolicy(policy *domain.LicensePolicy) error {
+	policy.License = strings.TrimSpace(policy.License)
+	if policy.License == "" {
+		return fmt.Errorf("%w: license is required", ErrInvalidLicensePolicy)
+	}
+	policy.Severity = strings.ToUpper(strings.TrimSpace(policy.Severity))
+	if policy.Severity == "" {
+		policy.Severity = "HIGH"
+	}
+	if _, ok := severityScores[policy.Severity]; !ok {
+		return fmt.Errorf("%w: severity must be LOW, MEDIUM, HIGH or CRITICAL", ErrInvalidLicensePolicy)
+	}
+	var count int64
+	if err := s.db.Model(&domain.LicensePolicy{}).Where("lower(license) = lower(?)", policy.License).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check license policies: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: a policy already covers %s", ErrInvalidLicensePolicy, policy.License)
+	}
+	policy.ID = uuid.New()
+	if err := s.db.Create(policy).Error; err != nil {
+		return fmt.Errorf("failed to create license policy: %w", err)
+	}
+	return nil
+}
+
+// DeleteLicensePolicy allows a license again; the compliance risks it
+// raised are resolved
+func (s *SBOMService) DeleteLicensePolicy(ctx context.Context, id uuid.UUID) (*SBOMMatchResult, error) {
+	res := s.db.Delete(&domain.LicensePolicy{}, "id = ?", id)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to delete license policy: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrLicensePolicyNotFound
+	}
+	return s.MatchAll(ctx)
+}
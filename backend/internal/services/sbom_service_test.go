@@ -0,0 +1,90 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func vulnerablePackage(t *testing.T, vulnID, ecosystem, name string, ranges ...domain.VersionRange) domain.VulnerablePackage {
+	raw, err := json.Marshal(ranges)
+	require.NoError(t, err)
+	return domain.VulnerablePackage{VulnerabilityID: vulnID, Ecosystem: ecosystem, Name: name, Ranges: raw}
+}
+
+func TestMatchComponents(t *testing.T) {
+	components := []domain.SoftwareComponent{
+		{ID: uuid.New(), Name: "log4j-core", Version: "2.14.1", Ecosystem: "Maven", PackageName: "org.apache.logging.log4j:log4j-core"},
+		{ID: uuid.New(), Name: "log4j-core", Version: "2.17.1", Ecosystem: "Maven", PackageName: "org.apache.logging.log4j:log4j-core"},
+		{ID: uuid.New(), Name: "openssl", Version: "1.1.1n", Ecosystem: "Debian", PackageName: "openssl"},
+		{ID: uuid.New(), Name: "lodash", Version: "4.17.15", Ecosystem: "npm", PackageName: "lodash"},
+		{ID: uuid.New(), Name: "Django", Version: "3.2.0", Ecosystem: "PyPI", PackageName: "django"},
+		{ID: uuid.New(), Name: "unversioned", Ecosystem: "npm", PackageName: "unversioned"},
+	}
+	packages := []domain.VulnerablePackage{
+		vulnerablePackage(t, "GHSA-log4shell", "Maven", "org.apache.logging.log4j:log4j-core", domain.VersionRange{Introduced: "2.13.0", Fixed: "2.15.0"}),
+		vulnerablePackage(t, "DSA-openssl", "Debian:11", "openssl", domain.VersionRange{Introduced: "0", Fixed: "1.1.1t"}),
+		// Same name in another ecosystem does not match
+		vulnerablePackage(t, "PYSEC-lodash", "PyPI", "lodash", domain.VersionRange{Introduced: "0"}),
+		{VulnerabilityID: "GHSA-lodash", Ecosystem: "npm", Name: "lodash", Versions: pq.StringArray{"4.17.15", "4.17.16"}},
+		vulnerablePackage(t, "PYSEC-django", "PyPI", "Django", domain.VersionRange{Introduced: "3.0", LastAffected: "3.2.0"}),
+		vulnerablePackage(t, "GHSA-unversioned", "npm", "unversioned", domain.VersionRange{Introduced: "0"}),
+	}
+
+	matches := matchComponents(components, packages)
+	got := map[string]string{}
+	for _, m := range matches {
+		got[m.VulnerabilityID] = m.Component.Name + "@" + m.Component.Version + " fixed:" + m.FixedVersion
+	}
+	assert.Equal(t, map[string]string{
+		"GHSA-log4shell": "log4j-core@2.14.1 fixed:2.15.0",
+		"DSA-openssl":    "openssl@1.1.1n fixed:1.1.1t",
+		"GHSA-lodash":    "lodash@4.17.15 fixed:",
+		"PYSEC-django":   "Django@3.2.0 fixed:",
+	}, got)
+}
+
+func TestFindLicenseViolations(t *testing.T) {
+	components := []domain.SoftwareComponent{
+		{Name: "gpl-lib", Version: "1.0", Licenses: pq.StringArray{"GPL-3.0-only"}},
+		{Name: "dual", Version: "2.0", Licenses: pq.StringArray{"(MIT OR AGPL-3.0-or-later)"}},
+		{Name: "classpath", Version: "3.0", Licenses: pq.StringArray{"GPL-2.0-only WITH Classpath-exception-2.0"}},
+		{Name: "mit", Version: "1.0", Licenses: pq.StringArray{"MIT"}},
+	}
+	policies := []domain.LicensePolicy{
+		{License: "gpl-3.0-only", Severity: "HIGH"},
+		{License: "AGPL-3.0-or-later", Severity: "CRITICAL"},
+		{License: "Classpath-exception-2.0", Severity: "LOW"},
+	}
+
+	violations := findLicenseViolations(components, policies)
+	require.Len(t, violations, 2)
+	assert.Equal(t, "AGPL-3.0-or-later", violations[0].Policy.License)
+	assert.Equal(t, "dual", violations[0].Components[0].Name)
+	assert.Equal(t, "gpl-3.0-only", violations[1].Policy.License)
+	assert.Equal(t, "gpl-lib", violations[1].Components[0].Name)
+}
+
+func TestVulnerabilityRiskContent(t *testing.T) {
+	asset := &domain.Asset{ID: uuid.New(), Name: "billing"}
+	vuln := domain.Vulnerability{ID: "GHSA-jfh8-c2jp-5v3q", Aliases: pq.StringArray{"CVE-2021-44228"}, Summary: "Log4Shell", Severity: "CRITICAL"}
+	component := &domain.SoftwareComponent{Name: "log4j-core", Version: "2.14.1", PackageName: "org.apache.logging.log4j:log4j-core"}
+
+	content := vulnerabilityRiskContent(asset, vuln, []componentMatch{{Component: component, VulnerabilityID: vuln.ID, FixedVersion: "2.15.0"}})
+	assert.Equal(t, "[VULN] CVE-2021-44228 in log4j-core 2.14.1 on billing", content.Title)
+	assert.Contains(t, content.Description, "org.apache.logging.log4j:log4j-core 2.14.1 (fixed in 2.15.0)")
+	assert.Equal(t, []string{"SBOM", "VULNERABILITY", "GHSA-jfh8-c2jp-5v3q", "CVE-2021-44228", "CRITICAL"}, content.Tags)
+
+	impact, probability := scoresForSeverity(vuln.Severity)
+	assert.Equal(t, 5, impact)
+	assert.Equal(t, 4, probability)
+	impact, probability = scoresForSeverity("")
+	assert.Equal(t, 3, impact)
+	assert.Equal(t, 3, probability)
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrScheduledJobNotFound = errors.New("scheduled job not found")
+
+// ScheduledTaskFunc is the body of a periodic job
+type ScheduledTaskFunc func(ctx context.Context) error
+
+// ScheduleOptions tunes a registered periodic job
+type ScheduleOptions struct {
+	Description     string
+	MissedRunPolicy domain.MissedRunPolicy // Defaults to RUN_ONCE
+	Timeout         time.Duration          // Defaults to 10 minutes; also bounds the lease
+}
+
+type scheduledTask struct {
+	name     string
+	schedule domain.Schedule
+	fn       ScheduledTaskFunc
+	timeout  time.Duration
+}
+
+// SchedulerService runs periodic workers once per cluster instead of once per
+// replica. Job state lives in the scheduled_jobs table; a replica runs a due job
+// only after winning a compare-and-set on the job's lease, so every replica can
+// tick the scheduler safely.
+type SchedulerService struct {
+	db         *gorm.DB
+	instanceID string
+
+	mu      sync.RWMutex
+	tasks   map[string]*scheduledTask
+	running map[string]bool
+}
+
+// NewSchedulerService creates a new scheduler service
+func NewSchedulerService(db *gorm.DB) *SchedulerService {
+	host, _ := os.Hostname()
+	return &SchedulerService{
+		db:         db,
+		instanceID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		tasks:      make(map[string]*scheduledTask),
+		running:    make(map[string]bool),
+	}
+}
+
+// Register declares a periodic job. The schedule is a cron expression, a
+// descriptor such as @daily, or "@every 5m". The job row is created on first
+// registration and its schedule is updated when the code changes it.
+func (s *SchedulerService) Register(name, expr string, fn ScheduledTaskFunc, opts ScheduleOptions) error {
+	schedule, err := domain.ParseSchedule(expr)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for %s: %w", name, err)
+	}
+	if opts.MissedRunPolicy == "" {
+		opts.MissedRunPolicy = domain.MissedRunOnce
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+
+	row := domain.ScheduledJob{
+		Name:            name,
+		Description:     opts.Description,
+		Schedule:        expr,
+		MissedRunPolicy: opts.MissedRunPolicy,
+		TimeoutSeconds:  int(opts.Timeout.Seconds()),
+		NextRunAt:       schedule.Next(time.Now()),
+	}
+
+	// Replicas register concurrently on start-up; the first insert wins
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		return fmt.Errorf("failed to register scheduled job %s: %w", name, err)
+	}
+
+	var existing domain.ScheduledJob
+	if err := s.db.First(&existing, "name = ?", name).Error; err != nil {
+		return fmt.Errorf("failed to load scheduled job %s: %w", name, err)
+	}
+
+	updates := map[string]interface{}{}
+	if existing.Schedule != expr {
+		updates["schedule"] = expr
+		updates["next_run_at"] = schedule.Next(time.Now())
+	}
+	if existing.MissedRunPolicy != opts.MissedRunPolicy {
+		updates["missed_run_policy"] = opts.MissedRunPolicy
+	}
+	if existing.TimeoutSeconds != row.TimeoutSeconds {
+		updates["timeout_seconds"] = row.TimeoutSeconds
+	}
+	if existing.Description != opts.Description {
+		updates["description"] = opts.Description
+	}
+	if len(updates) > 0 {
+		if err := s.db.Model(&existing).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update scheduled job %s: %w", name, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.tasks[name] = &scheduledTask{name: name, schedule: schedule, fn: fn, timeout: opts.Timeout}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Start ticks the scheduler every tick until ctx is cancelled
+func (s *SchedulerService) Start(ctx context.Context, tick time.Duration) {
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.Tick(ctx, now)
+			}
+		}
+	}()
+}
+
+// Tick starts every registered job that is due and whose lease this replica wins
+func (s *SchedulerService) Tick(ctx context.Context, now time.Time) {
+	s.mu.RLock()
+	tasks := make([]*scheduledTask, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task)
+	}
+	s.mu.RUnlock()
+
+	for _, task := range tasks {
+		job, ok, err := s.acquire(task, now)
+		if err != nil {
+			log.Printf("scheduler: failed to acquire %s: %v", task.name, err)
+			continue
+		}
+		if ok {
+			go s.run(ctx, task, job, now)
+		}
+	}
+}
+
+// acquire takes the lease of a due job; the conditional update only succeeds on one replica
+func (s *SchedulerService) acquire(task *scheduledTask, now time.Time) (*domain.ScheduledJob, bool, error) {
+	s.mu.Lock()
+	if s.running[task.name] {
+		s.mu.Unlock()
+		return nil, false, nil
+	}
+	s.mu.Unlock()
+
+	var job domain.ScheduledJob
+	if err := s.db.First(&job, "name = ?", task.name).Error; err != nil {
+		return nil, false, err
+	}
+
+	due := job.TriggerRequested || (!job.Paused && !job.NextRunAt.After(now))
+	if !due || (job.LeaseUntil != nil && job.LeaseUntil.After(now)) {
+		return nil, false, nil
+	}
+
+	leaseUntil := now.Add(task.timeout + time.Minute)
+	res := s.db.Model(&domain.ScheduledJob{}).
+		Where("name = ? AND (lease_until IS NULL OR lease_until < ?)", task.name, now).
+		Where("trigger_requested = ? OR (paused = ? AND next_run_at <= ?)", true, false, now).
+		Updates(map[string]interface{}{
+			"locked_by":         s.instanceID,
+			"lease_until":       leaseUntil,
+			"trigger_requested": false,
+		})
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil, false, nil
+	}
+
+	s.mu.Lock()
+	s.running[task.name] = true
+	s.mu.Unlock()
+
+	return &job, true, nil
+}
+
+// run executes a job under its lease, then records the run and schedules the next one
+func (s *SchedulerService) run(ctx context.Context, task *scheduledTask, job *domain.ScheduledJob, now time.Time) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, task.name)
+		s.mu.Unlock()
+	}()
+
+	trigger := domain.ScheduledRunBySchedule
+	var triggeredBy *uuid.UUID
+	shouldRun, missed, next := true, 0, task.schedule.Next(now)
+
+	if job.TriggerRequested {
+		trigger = domain.ScheduledRunManual
+		triggeredBy = job.TriggeredBy
+		// A manual run does not consume the upcoming scheduled run
+		if job.NextRunAt.After(now) {
+			next = job.NextRunAt
+		}
+	} else {
+		shouldRun, missed, next = domain.PlanScheduledRun(task.schedule, job.NextRunAt, now, job.MissedRunPolicy)
+	}
+
+	record := domain.ScheduledJobRun{
+		ID:           uuid.New(),
+		JobName:      task.name,
+		Trigger:      trigger,
+		Status:       domain.ScheduledRunRunning,
+		Instance:     s.instanceID,
+		ScheduledFor: job.NextRunAt,
+		MissedRuns:   missed,
+		TriggeredBy:  triggeredBy,
+		StartedAt:    now,
+	}
+
+	var runErr error
+	if shouldRun {
+		if err := s.db.Create(&record).Error; err != nil {
+			log.Printf("scheduler: failed to record run of %s: %v", task.name, err)
+		}
+		runErr = s.invoke(ctx, task)
+		record.Status = domain.ScheduledRunSucceeded
+		if runErr != nil {
+			record.Status = domain.ScheduledRunFailed
+			record.Error = runErr.Error()
+			log.Printf("scheduler: %s failed: %v", task.name, runErr)
+		}
+	} else {
+		record.Status = domain.ScheduledRunSkipped
+		record.Error = fmt.Sprintf("skipped %d missed run(s)", missed)
+	}
+
+	finished := time.Now()
+	record.FinishedAt = &finished
+	record.DurationMs = finished.Sub(now).Milliseconds()
+	if err := s.db.Save(&record).Error; err != nil {
+		log.Printf("scheduler: failed to record run of %s: %v", task.name, err)
+	}
+
+	updates := map[string]interface{}{
+		"next_run_at":      next,
+		"last_status":      record.Status,
+		"last_error":       record.Error,
+		"last_duration_ms": record.DurationMs,
+		"locked_by":        "",
+		"lease_until":      nil,
+		"triggered_by":     nil,
+	}
+	if shouldRun {
+		updates["last_run_at"] = now
+	}
+	if err := s.db.Model(&domain.ScheduledJob{}).
+		Where("name = ? AND locked_by = ?", task.name, s.instanceID).
+		Updates(updates).Error; err != nil {
+		log.Printf("scheduler: failed to release %s: %v", task.name, err)
+	}
+}
+
+// invoke runs the task with its timeout, converting panics into errors
+func (s *SchedulerService) invoke(ctx context.Context, task *scheduledTask) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, task.timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("scheduled job panicked: %v", r)
+		}
+	}()
+
+	return task.fn(ctx)
+}
+
+// ListJobs lists every scheduled job with its state
+func (s *SchedulerService) ListJobs() ([]domain.ScheduledJob, error) {
+	var jobs []domain.ScheduledJob
+	if err := s.db.Order("name ASC").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list scheduled jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetJob retrieves a scheduled job by name
+func (s *SchedulerService) GetJob(name string) (*domain.ScheduledJob, error) {
+	var job domain.ScheduledJob
+	if err := s.db.First(&job, "name = ?", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduledJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get scheduled job: %w", err)
+	}
+	return &job, nil
+}
+
+// ListRuns returns the run history of a job, newest first
+func (s *SchedulerService) ListRuns(name string, limit int) ([]domain.ScheduledJobRun, error) {
+	if _, err := s.GetJob(name); err != nil {
+		return nil, err
+	}
+
+	var runs []domain.ScheduledJobRun
+	if err := s.db.Where("job_name = ?", name).Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list scheduled job runs: %w", err)
+	}
+	return runs, nil
+}
+
+// TriggerNow asks the cluster to run a job on the next tick, even when paused
+func (s *SchedulerService) TriggerNow(name string, userID uuid.UUID) (*domain.ScheduledJob, error) {
+	return s.updateJob(name, map[string]interface{}{
+		"trigger_requested": true,
+		"triggered_by":      userID,
+	})
+}
+
+// Pause stops scheduled runs of a job until it is resumed
+func (s *SchedulerService) Pause(name string, userID uuid.UUID) (*domain.ScheduledJob, error) {
+	return s.updateJob(name, map[string]interface{}{
+		"paused":    true,
+		"paused_by": userID,
+	})
+}
+
+// Resume re-enables a paused job. Runs missed while paused are not caught up.
+func (s *SchedulerService) Resume(name string) (*domain.ScheduledJob, error) {
+	job, err := s.GetJob(name)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := domain.ParseSchedule(job.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule for %s: %w", name, err)
+	}
+
+	return s.updateJob(name, map[string]interface{}{
+		"paused":      false,
+		"paused_by":   nil,
+		"next_run_at": schedule.Next(time.Now()),
+	})
+}
+
+func (s *SchedulerService) updateJob(name string, updates map[string]interface{}) (*domain.ScheduledJob, error) {
+	res := s.db.Model(&domain.ScheduledJob{}).Where("name = ?", name).Updates(updates)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to update scheduled job: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrScheduledJobNotFound
+	}
+	return s.GetJob(name)
+}
@@ -17,6 +17,7 @@ var criticalityFactor = map[domain.AssetCriticality]float64{
 // ComputeRiskScore computes a final score using impact, probability and asset criticality.
 // Formula: base = impact * probability; final = base * avg(asset_factors)
 // If there are no assets, avg factor defaults to 1.0
+// An asset's effective criticality, when set (see ImpactService), prevails over its own.
 func ComputeRiskScore(impact, probability int, assets []*domain.Asset) float64 {
 	base := float64(impact * probability)
 	if len(assets) == 0 {
@@ -25,7 +26,11 @@ func ComputeRiskScore(impact, probability int, assets []*domain.Asset) float64 {
 
 	var sum float64
 	for _, a := range assets {
-		if f, ok := criticalityFactor[a.Criticality]; ok {
+		criticality := a.Criticality
+		if a.EffectiveCriticality != "" {
+			criticality = a.EffectiveCriticality
+		}
+		if f, ok := criticalityFactor[criticality]; ok {
 			sum += f
 		} else {
 			sum += 1.0
@@ -23,3 +23,14 @@ func TestComputeRiskScore_WithAssets(t *testing.T) {
 		t.Fatalf("expected 10.25 got %v", s)
 	}
 }
+
+func TestComputeRiskScore_EffectiveCriticality(t *testing.T) {
+	// A test VM that a critical service depends on is scored as critical
+	assets := []*domain.Asset{
+		{Criticality: domain.CriticalityLow, EffectiveCriticality: domain.CriticalityCritical},
+	}
+	s := ComputeRiskScore(2, 5, assets) // base=10, factor=1.5 => 15
+	if s != 15.0 {
+		t.Fatalf("expected 15.0 got %v", s)
+	}
+}
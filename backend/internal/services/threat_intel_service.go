@@ -0,0 +1,634 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/opendefender/openrisk/internal/core/ports"
+)
+
+var (
+	ErrThreatNotFound          = errors.New("threat not found")
+	ErrThreatFeedNotFound      = errors.New("threat feed not found")
+	ErrInvalidThreatFeed       = errors.New("invalid threat feed")
+	ErrTAXIICollectionNotFound = errors.New("TAXII collection not found")
+	ErrInvalidTAXIICollection  = errors.New("invalid TAXII collection")
+)
+
+// ThreatIntelService imports threats from STIX bundles and TAXII feeds,
+// links them to the risks they realize, and selects the risks shared as STIX
+// through exports and the collections of the TAXII server
+type ThreatIntelService struct {
+	db    *gorm.DB
+	feeds ports.ThreatFeedProvider
+}
+
+// NewThreatIntelService creates a new threat intelligence service; feeds
+// polls the TAXII feeds
+func NewThreatIntelService(db *gorm.DB, feeds ports.ThreatFeedProvider) *ThreatIntelService {
+	return &ThreatIntelService{db: db, feeds: feeds}
+}
+
+// ThreatImportOptions tells where threats come from, the marking of those
+// carrying none, and risks to link every imported threat to
+type ThreatImportOptions struct {
+	Source     string
+	DefaultTLP string
+	RiskIDs    []uuid.UUID
+}
+
+// ThreatImportResult sums up an import
+type ThreatImportResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"` // Not newer than the stored version
+	Skipped   int `json:"skipped"`   // Shared risks of ours, objects without ID
+	Linked    int `json:"linked"`    // New links to risks
+}
+
+// Import stores the threats, a newer version replacing the stored one, and
+// links them to risks: those of the options, shared risks they have a
+// relationship with, risks referencing the CVE of a vulnerability and risks
+// realized by the technique of an attack pattern
+func (s *ThreatIntelService) Import(ctx context.Context, intel *ports.ThreatIntel, opts ThreatImportOptions) (*ThreatImportResult, error) {
+	defaultTLP, err := domain.NormalizeTLP(opts.DefaultTLP)
+	if err != nil {
+		return nil, err
+	}
+	result := &ThreatImportResult{}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Vulnerabilities standing for our own shared risks are not threats
+		ownRisks, err := existingRisks(tx, intel)
+		if err != nil {
+			return err
+		}
+
+		stored := map[string]domain.Threat{}
+		for _, threat := range intel.Threats {
+			if riskID, ok := domain.RiskIDFromSTIXID(threat.STIXID); ok && ownRisks[riskID] {
+				result.Skipped++
+				continue
+			}
+			if threat.STIXID == "" {
+				result.Skipped++
+				continue
+			}
+			if threat.TLP == "" {
+				threat.TLP = defaultTLP
+			}
+			threat.Source = opts.Source
+
+			var current domain.Threat
+			err := tx.Take(&current, "stix_id = ?", threat.STIXID).Error
+			switch {
+			case err == nil && !threat.ModifiedAt.After(current.ModifiedAt):
+				result.Unchanged++
+				stored[threat.STIXID] = current
+				continue
+			case err == nil:
+				threat.ID = current.ID
+				threat.CreatedAt = current.CreatedAt
+				if err := tx.Save(&threat).Error; err != nil {
+					return fmt.Errorf("failed to update threat %s: %w", threat.STIXID, err)
+				}
+				result.Updated++
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				threat.ID = uuid.New()
+				if err := tx.Create(&threat).Error; err != nil {
+					return fmt.Errorf("failed to store threat %s: %w", threat.STIXID, err)
+				}
+				result.Created++
+			default:
+				return fmt.Errorf("failed to load threat: %w", err)
+			}
+			stored[threat.STIXID] = threat
+		}
+
+		link := func(riskID, threatID uuid.UUID, relationship string) error {
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.RiskThreat{
+				RiskID: riskID, ThreatID: threatID, Relationship: relationship, Source: opts.Source,
+			})
+			if res.Error != nil {
+				return fmt.Errorf("failed to link threat to risk: %w", res.Error)
+			}
+			result.Linked += int(res.RowsAffected)
+			return nil
+		}
+
+		for _, riskID := range opts.RiskIDs {
+			for _, threat := range stored {
+				if err := link(riskID, threat.ID, "related-to"); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, r := range intel.Relationships {
+			riskID, threatRef := riskEnd(r, ownRisks)
+			if riskID == uuid.Nil {
+				continue
+			}
+			threat, ok := stored[threatRef]
+			if !ok {
+				if err := tx.Take(&threat, "stix_id = ?", threatRef).Error; err != nil {
+					continue
+				}
+			}
+			if err := link(riskID, threat.ID, r.Type); err != nil {
+				return err
+			}
+		}
+
+		for _, threat := range stored {
+			if threat.Revoked || threat.ExternalID == "" {
+				continue
+			}
+			var riskIDs []uuid.UUID
+			relationship := "related-to"
+			switch {
+			case threat.Type == domain.ThreatVulnerability:
+				err = tx.Model(&domain.Risk{}).
+					Where("upper(external_id) = ? OR id IN (?)", threat.ExternalID,
+						tx.Model(&domain.RiskEnrichment{}).Select("risk_id").Where("? = ANY(cves)", threat.ExternalID)).
+					Pluck("id", &riskIDs).Error
+			case threat.Type == domain.ThreatAttackPattern && domain.IsAttackTechniqueID(threat.ExternalID):
+				relationship = "targets"
+				err = tx.Model(&domain.Risk{}).Where("? = ANY(techniques)", threat.ExternalID).Pluck("id", &riskIDs).Error
+			default:
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to find risks of threat %s: %w", threat.ExternalID, err)
+			}
+			for _, riskID := range riskIDs {
+				if err := link(riskID, threat.ID, relationship); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// existingRisks returns which of the shared risks the intel refers to exist
+func existingRisks(tx *gorm.DB, intel *ports.ThreatIntel) (map[uuid.UUID]bool, error) {
+	var candidates []uuid.UUID
+	for _, t := range intel.Threats {
+		if id, ok := domain.RiskIDFromSTIXID(t.STIXID); ok {
+			candidates = append(candidates, id)
+		}
+	}
+	for _, r := range intel.Relationships {
+		for _, ref := range []string{r.SourceRef, r.TargetRef} {
+			if id, ok := domain.RiskIDFromSTIXID(ref); ok {
+				candidates = append(candidates, id)
+			}
+		}
+	}
+	found := map[uuid.UUID]bool{}
+	if len(candidates) == 0 {
+		return found, nil
+	}
+	var ids []uuid.UUID
+	if err := tx.Model(&domain.Risk{}).Where("id IN ?", candidates).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to check shared risks: %w", err)
+	}
+	for _, id := range ids {
+		found[id] = true
+	}
+	return found, nil
+}
+
+// riskEnd returns the risk at one end of a relationship and the object at
+// the other end
+func riskEnd(r ports.ThreatRelationship, risks map[uuid.UUID]bool) (uuid.UUID, string) {
+	if id, ok := domain.RiskIDFromSTIXID(r.TargetRef); ok && risks[id] {
+		return id, r.SourceRef
+	}
+	if id, ok := domain.RiskIDFromSTIXID(r.SourceRef); ok && risks[id] {
+		return id, r.TargetRef
+	}
+	return uuid.Nil, ""
+}
+
+// ThreatFilter selects threats
+type ThreatFilter struct {
+	Type   string
+	Query  string
+	TLP    string
+	Limit  int
+	Offset int
+}
+
+// ListThreats lists threats, most recently modified first
+func (s *ThreatIntelService) ListThreats(filter ThreatFilter) ([]domain.Threat, int64, error) {
+	db := s.db.Model(&domain.Threat{})
+	if filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+	if filter.TLP != "" {
+		db = db.Where("tlp = ?", filter.TLP)
+	}
+	if filter.Query != "" {
+		like := "%" + filter.Query + "%"
+		db = db.Where("name ILIKE ? OR external_id ILIKE ? OR stix_id = ?", like, like, filter.Query)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count threats: %w", err)
+	}
+	var threats []domain.Threat
+	if err := db.Order("modified_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&threats).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list threats: %w", err)
+	}
+	return threats, total, nil
+}
+
+// LinkedRisk is a risk a threat is linked to
+type LinkedRisk struct {
+	RiskID       uuid.UUID         `json:"risk_id"`
+	Title        string            `json:"title"`
+	Status       domain.RiskStatus `json:"status"`
+	Score        float64           `json:"score"`
+	Relationship string            `json:"relationship"`
+	Source       string            `json:"source,omitempty"`
+}
+
+// ThreatDetail is a threat with the risks it is linked to
+type ThreatDetail struct {
+	domain.Threat
+	Risks []LinkedRisk `json:"risks"`
+}
+
+// GetThreat returns a threat with its risks
+func (s *ThreatIntelService) GetThreat(id uuid.UUID) (*ThreatDetail, error) {
+	var detail ThreatDetail
+	if err := s.db.Take(&detail.Threat, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThreatNotFound
+		}
+		return nil, fmt.Errorf("failed to load threat: %w", err)
+	}
+	detail.Risks = []LinkedRisk{}
+	if err := s.db.Table("risk_threats").
+		Select("risks.id AS risk_id, risks.title, risks.status, risks.score, risk_threats.relationship, risk_threats.source").
+		Joins("JOIN risks ON risks.id = risk_threats.risk_id AND risks.deleted_at IS NULL").
+		Where("risk_threats.threat_id = ?", id).
+		Order("risks.score DESC").Scan(&detail.Risks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load threat risks: %w", err)
+	}
+	return &detail, nil
+}
+
+// LinkedThreat is a threat linked to a risk
+type LinkedThreat struct {
+	domain.Threat
+	Relationship string `json:"relationship"`
+}
+
+// RiskThreats lists the threats linked to a risk
+func (s *ThreatIntelService) RiskThreats(riskID uuid.UUID) ([]LinkedThreat, error) {
+	threats := []LinkedThreat{}
+	if err := s.db.Table("threats").
+		Select("threats.*, risk_threats.relationship").
+		Joins("JOIN risk_threats ON risk_threats.threat_id = threats.id").
+		Where("risk_threats.risk_id = ?", riskID).
+		Order("threats.modified_at DESC").Scan(&threats).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk threats: %w", err)
+	}
+	return threats, nil
+}
+
+// LinkRisk links a threat to a risk
+func (s *ThreatIntelService) LinkRisk(ctx context.Context, riskID, threatID uuid.UUID, relationship string) error {
+	db := s.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&domain.Risk{}).Where("id = ?", riskID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to load risk: %w", err)
+	}
+	if count == 0 {
+		return ErrRiskNotFound
+	}
+	if err := db.Model(&domain.Threat{}).Where("id = ?", threatID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to load threat: %w", err)
+	}
+	if count == 0 {
+		return ErrThreatNotFound
+	}
+	if relationship == "" {
+		relationship = "related-to"
+	}
+	link := domain.RiskThreat{RiskID: riskID, ThreatID: threatID, Relationship: relationship, Source: "manual"}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "risk_id"}, {Name: "threat_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"relationship"}),
+	}).Create(&link).Error; err != nil {
+		return fmt.Errorf("failed to link threat to risk: %w", err)
+	}
+	return nil
+}
+
+// UnlinkRisk removes the link between a threat and a risk
+func (s *ThreatIntelService) UnlinkRisk(ctx context.Context, riskID, threatID uuid.UUID) error {
+	res := s.db.WithContext(ctx).Where("risk_id = ? AND threat_id = ?", riskID, threatID).Delete(&domain.RiskThreat{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to unlink threat: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrThreatNotFound
+	}
+	return nil
+}
+
+// RiskExportFilter selects the risks shared as STIX: those of the tenant
+// when set, among RiskIDs and having one of Tags when set, marked at most
+// MaxTLP and updated after AddedAfter, in order of update
+type RiskExportFilter struct {
+	TenantID   uuid.UUID
+	RiskIDs    []uuid.UUID
+	Tags       []string
+	MaxTLP     string
+	AddedAfter *time.Time
+	Limit      int
+	Offset     int
+}
+
+// RiskIntel is what is shared of risks: the risks with their mitigations,
+// their threats and the ATT&CK catalog entries of their techniques. More is
+// set when the limit left risks out.
+type RiskIntel struct {
+	Risks      []domain.Risk
+	Threats    map[uuid.UUID][]domain.Threat
+	Techniques map[string]domain.AttackObject
+	More       bool
+}
+
+// ExportRisks selects the risks to share with what goes with them
+func (s *ThreatIntelService) ExportRisks(ctx context.Context, filter RiskExportFilter) (*RiskIntel, error) {
+	db := s.db.WithContext(ctx).Model(&domain.Risk{}).Preload("Mitigations").
+		Where("COALESCE(NULLIF(tlp, ''), ?) IN ?", domain.DefaultTLP, domain.TLPsAllowedBy(filter.MaxTLP))
+	if filter.TenantID != uuid.Nil {
+		db = db.Where("id IN (?)", s.db.Model(&domain.RiskRegister{}).Select("risk_id").Where("tenant_id = ?", filter.TenantID))
+	}
+	if len(filter.RiskIDs) > 0 {
+		db = db.Where("id IN ?", filter.RiskIDs)
+	}
+	if len(filter.Tags) > 0 {
+		db = db.Where("tags && ?", pq.StringArray(filter.Tags))
+	}
+	if filter.AddedAfter != nil {
+		db = db.Where("updated_at > ?", *filter.AddedAfter)
+	}
+	db = db.Order("updated_at, id").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		db = db.Limit(filter.Limit + 1)
+	}
+
+	intel := &RiskIntel{Threats: map[uuid.UUID][]domain.Threat{}, Techniques: map[string]domain.AttackObject{}}
+	if err := db.Find(&intel.Risks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risks: %w", err)
+	}
+	if filter.Limit > 0 && len(intel.Risks) > filter.Limit {
+		intel.Risks = intel.Risks[:filter.Limit]
+		intel.More = true
+	}
+	if len(intel.Risks) == 0 {
+		return intel, nil
+	}
+
+	riskIDs := make([]uuid.UUID, 0, len(intel.Risks))
+	var techniques []string
+	for _, r := range intel.Risks {
+		riskIDs = append(riskIDs, r.ID)
+		techniques = append(techniques, r.Techniques...)
+	}
+
+	var links []domain.RiskThreat
+	if err := s.db.Where("risk_id IN ?", riskIDs).Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk threats: %w", err)
+	}
+	if len(links) > 0 {
+		threatIDs := make([]uuid.UUID, 0, len(links))
+		for _, l := range links {
+			threatIDs = append(threatIDs, l.ThreatID)
+		}
+		var threats []domain.Threat
+		if err := s.db.Where("id IN ?", threatIDs).Find(&threats).Error; err != nil {
+			return nil, fmt.Errorf("failed to load threats: %w", err)
+		}
+		byID := map[uuid.UUID]domain.Threat{}
+		for _, t := range threats {
+			byID[t.ID] = t
+		}
+		for _, l := range links {
+			if t, ok := byID[l.ThreatID]; ok {
+				intel.Threats[l.RiskID] = append(intel.Threats[l.RiskID], t)
+			}
+		}
+	}
+
+	if len(techniques) > 0 {
+		var objects []domain.AttackObject
+		if err := s.db.Select("id", "stix_id", "name", "url").Where("id IN ?", techniques).Find(&objects).Error; err != nil {
+			return nil, fmt.Errorf("failed to load ATT&CK techniques: %w", err)
+		}
+		for _, o := range objects {
+			intel.Techniques[o.ID] = o
+		}
+	}
+	return intel, nil
+}
+
+// CreateCollection creates a collection of the TAXII server and returns the
+// access token of its readers, only once; only its hash is stored
+func (s *ThreatIntelService) CreateCollection(tenantID, createdBy uuid.UUID, input domain.TAXIICollection) (*domain.TAXIICollection, string, error) {
+	maxTLP, err := domain.NormalizeTLP(input.MaxTLP)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidTAXIICollection, err)
+	}
+	if strings.TrimSpace(input.Title) == "" || maxTLP == "" {
+		return nil, "", fmt.Errorf("%w: title and max_tlp are required", ErrInvalidTAXIICollection)
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate collection token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	collection := &domain.TAXIICollection{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Title:       strings.TrimSpace(input.Title),
+		Description: input.Description,
+		MaxTLP:      maxTLP,
+		Tags:        input.Tags,
+		TokenHash:   hashFeedToken(token),
+		CreatedBy:   createdBy,
+	}
+	if err := s.db.Create(collection).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create TAXII collection: %w", err)
+	}
+	return collection, token, nil
+}
+
+// ListCollections lists the tenant's TAXII collections
+func (s *ThreatIntelService) ListCollections(tenantID uuid.UUID) ([]domain.TAXIICollection, error) {
+	collections := []domain.TAXIICollection{}
+	if err := s.db.Where("tenant_id = ?", tenantID).Order("created_at").Find(&collections).Error; err != nil {
+		return nil, fmt.Errorf("failed to list TAXII collections: %w", err)
+	}
+	return collections, nil
+}
+
+// DeleteCollection deletes a TAXII collection, revoking its token
+func (s *ThreatIntelService) DeleteCollection(tenantID, id uuid.UUID) error {
+	res := s.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&domain.TAXIICollection{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete TAXII collection: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrTAXIICollectionNotFound
+	}
+	return nil
+}
+
+// CollectionForToken returns the collection an access token reads
+func (s *ThreatIntelService) CollectionForToken(token string) (*domain.TAXIICollection, error) {
+	if token == "" {
+		return nil, ErrTAXIICollectionNotFound
+	}
+	var collection domain.TAXIICollection
+	if err := s.db.Take(&collection, "token_hash = ?", hashFeedToken(token)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTAXIICollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to load TAXII collection: %w", err)
+	}
+	return &collection, nil
+}
+
+// CreateFeed registers a TAXII collection to poll for threats
+func (s *ThreatIntelService) CreateFeed(tenantID, createdBy uuid.UUID, input domain.ThreatFeed) (*domain.ThreatFeed, error) {
+	defaultTLP, err := domain.NormalizeTLP(input.DefaultTLP)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidThreatFeed, err)
+	}
+	root, err := url.Parse(input.APIRoot)
+	if err != nil || (root.Scheme != "https" && root.Scheme != "http") || root.Host == "" {
+		return nil, fmt.Errorf("%w: api_root must be an http(s) URL", ErrInvalidThreatFeed)
+	}
+	if strings.TrimSpace(input.Name) == "" || strings.TrimSpace(input.CollectionID) == "" {
+		return nil, fmt.Errorf("%w: name and collection_id are required", ErrInvalidThreatFeed)
+	}
+
+	feed := &domain.ThreatFeed{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		Name:         strings.TrimSpace(input.Name),
+		APIRoot:      input.APIRoot,
+		CollectionID: strings.TrimSpace(input.CollectionID),
+		Username:     input.Username,
+		Password:     input.Password,
+		DefaultTLP:   defaultTLP,
+		AddedAfter:   input.AddedAfter,
+		CreatedBy:    createdBy,
+	}
+	if err := s.db.Create(feed).Error; err != nil {
+		return nil, fmt.Errorf("failed to create threat feed: %w", err)
+	}
+	return feed, nil
+}
+
+// ListFeeds lists the tenant's threat feeds
+func (s *ThreatIntelService) ListFeeds(tenantID uuid.UUID) ([]domain.ThreatFeed, error) {
+	feeds := []domain.ThreatFeed{}
+	if err := s.db.Where("tenant_id = ?", tenantID).Order("created_at").Find(&feeds).Error; err != nil {
+		return nil, fmt.Errorf("failed to list threat feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+// DeleteFeed stops polling a threat feed; its threats are kept
+func (s *ThreatIntelService) DeleteFeed(tenantID, id uuid.UUID) error {
+	res := s.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&domain.ThreatFeed{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete threat feed: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrThreatFeedNotFound
+	}
+	return nil
+}
+
+// PollFeed imports the threats added to a feed since its last poll. The
+// outcome is recorded on the feed, which resumes from the last object added.
+func (s *ThreatIntelService) PollFeed(ctx context.Context, tenantID, id uuid.UUID) (*ThreatImportResult, error) {
+	var feed domain.ThreatFeed
+	if err := s.db.Take(&feed, "tenant_id = ? AND id = ?", tenantID, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThreatFeedNotFound
+		}
+		return nil, fmt.Errorf("failed to load threat feed: %w", err)
+	}
+	return s.poll(ctx, &feed)
+}
+
+// PollAll polls every enabled threat feed
+func (s *ThreatIntelService) PollAll(ctx context.Context) error {
+	var feeds []domain.ThreatFeed
+	if err := s.db.Where("disabled = ?", false).Find(&feeds).Error; err != nil {
+		return fmt.Errorf("failed to list threat feeds: %w", err)
+	}
+	var errs []error
+	for i := range feeds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.poll(ctx, &feeds[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", feeds[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ThreatIntelService) poll(ctx context.Context, feed *domain.ThreatFeed) (*ThreatImportResult, error) {
+	if s.feeds == nil {
+		return nil, errors.New("no TAXII client configured")
+	}
+	now := time.Now()
+	updates := map[string]interface{}{"last_polled_at": now}
+
+	intel, last, err := s.feeds.PollFeed(ctx, *feed)
+	var result *ThreatImportResult
+	if err == nil {
+		result, err = s.Import(ctx, intel, ThreatImportOptions{Source: feed.Name, DefaultTLP: feed.DefaultTLP})
+	}
+	if err != nil {
+		updates["last_error"] = err.Error()
+	} else {
+		updates["last_error"] = ""
+		updates["last_imported"] = result.Created + result.Updated
+		updates["added_after"] = last
+	}
+	if dbErr := s.db.Model(feed).Updates(updates).Error; dbErr != nil && err == nil {
+		err = fmt.Errorf("failed to record threat feed poll: %w", dbErr)
+	}
+	return result, err
+}
@@ -0,0 +1,481 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrViewNotFound      = errors.New("view not found")
+	ErrDashboardNotFound = errors.New("dashboard not found")
+	ErrViewForbidden     = errors.New("only the owner or an admin can change this")
+	ErrInvalidView       = errors.New("invalid view")
+)
+
+// ViewBundleVersion is the version of the portable view and dashboard format
+const ViewBundleVersion = 1
+
+// Sortable risk columns, shared with GET /risks
+var riskSortColumns = []string{"score", "title", "created_at", "updated_at", "impact", "probability", "status", "source"}
+
+// ViewActor is the user saving, sharing or reading views and dashboards
+type ViewActor struct {
+	TenantID uuid.UUID
+	UserID   uuid.UUID
+	Email    string
+	Admin    bool
+}
+
+// filterContext evaluates filter macros (@me, @today) for the actor
+func (a ViewActor) filterContext() *RiskFilterContext {
+	return &RiskFilterContext{UserID: a.UserID, Email: a.Email, Now: time.Now()}
+}
+
+// ViewService stores saved views and dashboards, enforces their visibility
+// and moves them between tenants as JSON bundles
+type ViewService struct {
+	db        *gorm.DB
+	filters   *RiskFilterService
+	analytics *AnalyticsService
+}
+
+// NewViewService creates a new view service
+func NewViewService(db *gorm.DB) *ViewService {
+	return &ViewService{
+		db:        db,
+		filters:   &RiskFilterService{db: db, customFields: &CustomFieldService{db: db}},
+		analytics: NewAnalyticsService(db),
+	}
+}
+
+// SavedViewInput is the editable part of a saved view
+type SavedViewInput struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Filter      string                `json:"filter"`
+	SortBy      string                `json:"sort_by"`
+	SortDir     string                `json:"sort_dir"`
+	Columns     []string              `json:"columns"`
+	Visibility  domain.ViewVisibility `json:"visibility"`
+	TeamID      *uuid.UUID            `json:"team_id"`
+}
+
+// visibleTo restricts a saved_views or dashboards query to what the actor may
+// see: their own items, items shared with the tenant and with their teams
+func (s *ViewService) visibleTo(actor ViewActor) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		teams := s.db.Model(&domain.TeamMember{}).Select("team_id").Where("user_id = ?", actor.UserID)
+		return db.Where("tenant_id = ?", actor.TenantID).
+			Where("owner_id = ? OR visibility = ? OR (visibility = ? AND team_id IN (?))",
+				actor.UserID, domain.VisibilityTenant, domain.VisibilityTeam, teams)
+	}
+}
+
+func canEditView(actor ViewActor, ownerID uuid.UUID) bool {
+	return actor.UserID == ownerID || actor.Admin
+}
+
+// ListViews lists the saved views visible to the actor, their own first
+func (s *ViewService) ListViews(actor ViewActor) ([]domain.SavedView, error) {
+	var views []domain.SavedView
+	if err := s.db.Scopes(s.visibleTo(actor)).
+		Order(clauseOwnFirst(actor.UserID)).
+		Order("name ASC").
+		Find(&views).Error; err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	return views, nil
+}
+
+// clauseOwnFirst orders the actor's own items before shared ones
+func clauseOwnFirst(userID uuid.UUID) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{SQL: "CASE WHEN owner_id = ? THEN 0 ELSE 1 END", Vars: []interface{}{userID}}}
+}
+
+// GetView returns a saved view visible to the actor
+func (s *ViewService) GetView(actor ViewActor, viewID uuid.UUID) (*domain.SavedView, error) {
+	var view domain.SavedView
+	if err := s.db.Scopes(s.visibleTo(actor)).First(&view, "id = ?", viewID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrViewNotFound
+		}
+		return nil, fmt.Errorf("failed to load view: %w", err)
+	}
+	return &view, nil
+}
+
+// CreateView saves a new view owned by the actor
+func (s *ViewService) CreateView(actor ViewActor, input SavedViewInput) (*domain.SavedView, error) {
+	view := &domain.SavedView{
+		ID:       uuid.New(),
+		TenantID: actor.TenantID,
+		OwnerID:  actor.UserID,
+	}
+	if err := s.applyViewInput(actor, view, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(view).Error; err != nil {
+		return nil, fmt.Errorf("failed to create view: %w", err)
+	}
+	return view, nil
+}
+
+// UpdateView replaces the definition of a view
+func (s *ViewService) UpdateView(actor ViewActor, viewID uuid.UUID, input SavedViewInput) (*domain.SavedView, error) {
+	view, err := s.GetView(actor, viewID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditView(actor, view.OwnerID) {
+		return nil, ErrViewForbidden
+	}
+	if err := s.applyViewInput(actor, view, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(view).Error; err != nil {
+		return nil, fmt.Errorf("failed to update view: %w", err)
+	}
+	return view, nil
+}
+
+// DeleteView deletes a view
+func (s *ViewService) DeleteView(actor ViewActor, viewID uuid.UUID) error {
+	view, err := s.GetView(actor, viewID)
+	if err != nil {
+		return err
+	}
+	if !canEditView(actor, view.OwnerID) {
+		return ErrViewForbidden
+	}
+	return s.db.Delete(view).Error
+}
+
+// applyViewInput validates input and copies it onto view
+func (s *ViewService) applyViewInput(actor ViewActor, view *domain.SavedView, input SavedViewInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w: name is required (at most 255 characters)", ErrInvalidView)
+	}
+
+	// Invalid expressions are returned as *filter.Error for the caller to locate
+	if _, err := s.filters.Compile(input.Filter, actor.filterContext()); err != nil {
+		return err
+	}
+
+	sortBy, sortDir, err := normalizeViewSort(input.SortBy, input.SortDir)
+	if err != nil {
+		return err
+	}
+
+	columns, err := s.validateViewColumns(input.Columns)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkVisibility(actor, input.Visibility, input.TeamID); err != nil {
+		return err
+	}
+
+	view.Name = name
+	view.Description = input.Description
+	view.Filter = strings.TrimSpace(input.Filter)
+	view.SortBy = sortBy
+	view.SortDir = sortDir
+	view.Columns = columns
+	view.Visibility = visibilityOrDefault(input.Visibility)
+	view.TeamID = nil
+	if view.Visibility == domain.VisibilityTeam {
+		view.TeamID = input.TeamID
+	}
+	return nil
+}
+
+func normalizeViewSort(sortBy, sortDir string) (string, string, error) {
+	sortBy = strings.ToLower(strings.TrimSpace(sortBy))
+	sortDir = strings.ToLower(strings.TrimSpace(sortDir))
+
+	if sortBy != "" {
+		valid := false
+		for _, column := range riskSortColumns {
+			if column == sortBy {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", "", fmt.Errorf("%w: cannot sort by %q (use one of %s)", ErrInvalidView, sortBy, strings.Join(riskSortColumns, ", "))
+		}
+	}
+
+	switch sortDir {
+	case "":
+		sortDir = "desc"
+	case "asc", "desc":
+	default:
+		return "", "", fmt.Errorf("%w: sort_dir must be asc or desc", ErrInvalidView)
+	}
+	return sortBy, sortDir, nil
+}
+
+// validateViewColumns checks columns against the risk export columns, which
+// include the risk custom fields
+func (s *ViewService) validateViewColumns(columns []string) ([]string, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	var fields []domain.CustomField
+	if err := s.db.Where("scope = ?", domain.CustomFieldScopeRisk).Find(&fields).Error; err != nil {
+		return nil, fmt.Errorf("failed to load custom fields: %w", err)
+	}
+	if _, err := SelectExportColumns(AvailableExportColumns(fields), columns); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidView, err)
+	}
+	return columns, nil
+}
+
+func visibilityOrDefault(v domain.ViewVisibility) domain.ViewVisibility {
+	if v == "" {
+		return domain.VisibilityPrivate
+	}
+	return v
+}
+
+// checkVisibility validates a sharing setting; only team members share with a team
+func (s *ViewService) checkVisibility(actor ViewActor, visibility domain.ViewVisibility, teamID *uuid.UUID) error {
+	switch visibilityOrDefault(visibility) {
+	case domain.VisibilityPrivate, domain.VisibilityTenant:
+		return nil
+	case domain.VisibilityTeam:
+		if teamID == nil || *teamID == uuid.Nil {
+			return fmt.Errorf("%w: team_id is required to share with a team", ErrInvalidView)
+		}
+		if actor.Admin {
+			var teams int64
+			s.db.Model(&domain.Team{}).Where("id = ?", *teamID).Count(&teams)
+			if teams == 0 {
+				return fmt.Errorf("%w: unknown team", ErrInvalidView)
+			}
+			return nil
+		}
+		var membership int64
+		s.db.Model(&domain.TeamMember{}).Where("team_id = ? AND user_id = ?", *teamID, actor.UserID).Count(&membership)
+		if membership == 0 {
+			return fmt.Errorf("%w: only team members can share with a team", ErrInvalidView)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: visibility must be private, team or tenant", ErrInvalidView)
+}
+
+// ViewBundle is the portable JSON form of saved views and dashboards. IDs,
+// owners and tenants are left out; teams travel by name.
+type ViewBundle struct {
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Views      []PortableView      `json:"views"`
+	Dashboards []PortableDashboard `json:"dashboards"`
+}
+
+// PortableView is a saved view in a bundle
+type PortableView struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Filter      string                `json:"filter,omitempty"`
+	SortBy      string                `json:"sort_by,omitempty"`
+	SortDir     string                `json:"sort_dir,omitempty"`
+	Columns     []string              `json:"columns,omitempty"`
+	Visibility  domain.ViewVisibility `json:"visibility"`
+	Team        string                `json:"team,omitempty"`
+}
+
+// PortableDashboard is a dashboard in a bundle
+type PortableDashboard struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Filter      string                `json:"filter,omitempty"`
+	Visibility  domain.ViewVisibility `json:"visibility"`
+	Team        string                `json:"team,omitempty"`
+	Widgets     []DashboardWidgetSpec `json:"widgets"`
+}
+
+// ViewImportResult reports what an imported bundle created
+type ViewImportResult struct {
+	Views      []domain.SavedView `json:"views"`
+	Dashboards []domain.Dashboard `json:"dashboards"`
+	Errors     []string           `json:"errors,omitempty"` // Items skipped, e.g. filters on fields this tenant lacks
+	Warnings   []string           `json:"warnings,omitempty"`
+}
+
+// Export bundles the given views and dashboards; with no IDs at all, every
+// view and dashboard the actor owns is exported
+func (s *ViewService) Export(actor ViewActor, viewIDs, dashboardIDs []uuid.UUID) (*ViewBundle, error) {
+	bundle := &ViewBundle{
+		Version:    ViewBundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Views:      []PortableView{},
+		Dashboards: []PortableDashboard{},
+	}
+
+	var views []domain.SavedView
+	var dashboards []domain.Dashboard
+	if len(viewIDs) == 0 && len(dashboardIDs) == 0 {
+		if err := s.db.Where("tenant_id = ? AND owner_id = ?", actor.TenantID, actor.UserID).Order("name ASC").Find(&views).Error; err != nil {
+			return nil, fmt.Errorf("failed to load views: %w", err)
+		}
+		if err := s.db.Preload("Widgets", orderWidgets).
+			Where("tenant_id = ? AND owner_id = ?", actor.TenantID, actor.UserID).Order("name ASC").Find(&dashboards).Error; err != nil {
+			return nil, fmt.Errorf("failed to load dashboards: %w", err)
+		}
+	} else {
+		for _, id := range viewIDs {
+			view, err := s.GetView(actor, id)
+			if err != nil {
+				return nil, err
+			}
+			views = append(views, *view)
+		}
+		for _, id := range dashboardIDs {
+			dashboard, err := s.GetDashboard(actor, id)
+			if err != nil {
+				return nil, err
+			}
+			dashboards = append(dashboards, *dashboard)
+		}
+	}
+
+	for _, v := range views {
+		bundle.Views = append(bundle.Views, PortableView{
+			Name:        v.Name,
+			Description: v.Description,
+			Filter:      v.Filter,
+			SortBy:      v.SortBy,
+			SortDir:     v.SortDir,
+			Columns:     v.Columns,
+			Visibility:  v.Visibility,
+			Team:        s.teamName(v.TeamID),
+		})
+	}
+	for _, d := range dashboards {
+		portable := PortableDashboard{
+			Name:        d.Name,
+			Description: d.Description,
+			Filter:      d.Filter,
+			Visibility:  d.Visibility,
+			Team:        s.teamName(d.TeamID),
+			Widgets:     make([]DashboardWidgetSpec, 0, len(d.Widgets)),
+		}
+		for _, w := range d.Widgets {
+			portable.Widgets = append(portable.Widgets, widgetSpec(w))
+		}
+		bundle.Dashboards = append(bundle.Dashboards, portable)
+	}
+
+	return bundle, nil
+}
+
+func (s *ViewService) teamName(teamID *uuid.UUID) string {
+	if teamID == nil {
+		return ""
+	}
+	var team domain.Team
+	if err := s.db.Select("name").First(&team, "id = ?", *teamID).Error; err != nil {
+		return ""
+	}
+	return team.Name
+}
+
+// Import creates the views and dashboards of a bundle, owned by the actor.
+// Each item is validated against this tenant's schema; invalid items are
+// reported and skipped. Team sharing falls back to private when the team
+// does not exist here or the actor is not a member.
+func (s *ViewService) Import(actor ViewActor, bundle *ViewBundle) (*ViewImportResult, error) {
+	if bundle == nil || bundle.Version < 1 || bundle.Version > ViewBundleVersion {
+		return nil, fmt.Errorf("%w: unsupported bundle version", ErrInvalidView)
+	}
+
+	result := &ViewImportResult{Views: []domain.SavedView{}, Dashboards: []domain.Dashboard{}}
+
+	for _, v := range bundle.Views {
+		visibility, teamID, warning := s.importVisibility(actor, v.Visibility, v.Team)
+		if warning != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("view %q: %s", v.Name, warning))
+		}
+		view, err := s.CreateView(actor, SavedViewInput{
+			Name:        v.Name,
+			Description: v.Description,
+			Filter:      v.Filter,
+			SortBy:      v.SortBy,
+			SortDir:     v.SortDir,
+			Columns:     v.Columns,
+			Visibility:  visibility,
+			TeamID:      teamID,
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("view %q: %v", v.Name, err))
+			continue
+		}
+		result.Views = append(result.Views, *view)
+	}
+
+	for _, d := range bundle.Dashboards {
+		visibility, teamID, warning := s.importVisibility(actor, d.Visibility, d.Team)
+		if warning != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("dashboard %q: %s", d.Name, warning))
+		}
+		dashboard, err := s.CreateDashboard(actor, DashboardInput{
+			Name:        d.Name,
+			Description: d.Description,
+			Filter:      d.Filter,
+			Visibility:  visibility,
+			TeamID:      teamID,
+			Widgets:     d.Widgets,
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("dashboard %q: %v", d.Name, err))
+			continue
+		}
+		result.Dashboards = append(result.Dashboards, *dashboard)
+	}
+
+	return result, nil
+}
+
+// importVisibility maps a bundle's sharing setting onto this tenant
+func (s *ViewService) importVisibility(actor ViewActor, visibility domain.ViewVisibility, team string) (domain.ViewVisibility, *uuid.UUID, string) {
+	if visibility != domain.VisibilityTeam {
+		return visibility, nil, ""
+	}
+
+	var found domain.Team
+	if team == "" || s.db.Select("id").Where("LOWER(name) = LOWER(?)", team).First(&found).Error != nil {
+		return domain.VisibilityPrivate, nil, fmt.Sprintf("team %q does not exist here, imported as private", team)
+	}
+	if err := s.checkVisibility(actor, domain.VisibilityTeam, &found.ID); err != nil {
+		return domain.VisibilityPrivate, nil, fmt.Sprintf("not a member of team %q, imported as private", team)
+	}
+	return domain.VisibilityTeam, &found.ID, ""
+}
+
+// ParseViewBundle decodes a bundle, rejecting unknown fields so that typos do
+// not silently drop settings
+func ParseViewBundle(data []byte) (*ViewBundle, error) {
+	var bundle ViewBundle
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidView, err)
+	}
+	return &bundle, nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeViewSort(t *testing.T) {
+	sortBy, sortDir, err := normalizeViewSort(" Score ", "")
+	require.NoError(t, err)
+	assert.Equal(t, "score", sortBy)
+	assert.Equal(t, "desc", sortDir)
+
+	_, _, err = normalizeViewSort("owner; drop table risks", "asc")
+	assert.True(t, errors.Is(err, ErrInvalidView))
+
+	_, _, err = normalizeViewSort("title", "up")
+	assert.True(t, errors.Is(err, ErrInvalidView))
+}
+
+func TestParseViewBundle(t *testing.T) {
+	bundle, err := ParseViewBundle([]byte(`{
+		"version": 1,
+		"views": [{"name": "Mine", "filter": "owner = @me", "visibility": "private"}],
+		"dashboards": [{"name": "Board", "visibility": "team", "team": "Security",
+			"widgets": [{"type": "top_risks", "options": {"limit": 5}, "x": 0, "y": 0}]}]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, bundle.Dashboards, 1)
+	assert.Equal(t, domain.WidgetTopRisks, bundle.Dashboards[0].Widgets[0].Type)
+	assert.JSONEq(t, `{"limit": 5}`, string(bundle.Dashboards[0].Widgets[0].Options))
+
+	_, err = ParseViewBundle([]byte(`{"version": 1, "views": [{"name": "x", "filtre": "score > 1"}]}`))
+	assert.True(t, errors.Is(err, ErrInvalidView), "unknown fields are rejected")
+}
+
+func TestBuildWidget(t *testing.T) {
+	s := &ViewService{filters: &RiskFilterService{}}
+	actor := ViewActor{}
+
+	widget, err := s.buildWidget(actor, DashboardWidgetSpec{Type: domain.WidgetRiskMatrix, X: 8})
+	require.NoError(t, err)
+	assert.Equal(t, 4, widget.Width)
+	assert.Equal(t, 3, widget.Height)
+
+	_, err = s.buildWidget(actor, DashboardWidgetSpec{Type: "pie"})
+	assert.True(t, errors.Is(err, ErrInvalidView))
+
+	_, err = s.buildWidget(actor, DashboardWidgetSpec{Type: domain.WidgetTrend, X: 10, Width: 4})
+	assert.True(t, errors.Is(err, ErrInvalidView), "widgets must fit the grid")
+
+	_, err = s.buildWidget(actor, DashboardWidgetSpec{Type: domain.WidgetTrend, Options: []byte(`[1]`)})
+	assert.True(t, errors.Is(err, ErrInvalidView), "options must be an object")
+}
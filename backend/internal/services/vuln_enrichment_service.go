@@ -0,0 +1,440 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+var (
+	ErrCVENotFound             = errors.New("CVE not found")
+	ErrRiskNotEnriched         = errors.New("risk has no enrichment")
+	ErrInvalidEnrichmentPolicy = errors.New("invalid enrichment policy")
+)
+
+// cveFeedColumns are the columns of cve_records each feed owns
+var cveFeedColumns = map[string][]string{
+	domain.FeedNVD:  {"description", "cvss_vector", "cvss_version", "cvss_score", "cvss_severity", "published", "last_modified", "updated_at"},
+	domain.FeedKEV:  {"kev", "kev_name", "kev_date_added", "kev_due_date", "kev_ransomware", "updated_at"},
+	domain.FeedEPSS: {"epss", "epss_percentile", "epss_date", "updated_at"},
+}
+
+// VulnEnrichmentService keeps what the offline NVD, KEV and EPSS feeds know
+// about CVEs and enriches the risks referencing them in their tags, external
+// ID or description: CVE and KEV tags, and, when the policy of the risk's
+// tenant allows it, a probability following KEV status and EPSS percentile.
+// The provenance of each enrichment is kept in RiskEnrichment.
+type VulnEnrichmentService struct {
+	db *gorm.DB
+}
+
+// NewVulnEnrichmentService creates a new vulnerability enrichment service
+func NewVulnEnrichmentService(db *gorm.DB) *VulnEnrichmentService {
+	return &VulnEnrichmentService{db: db}
+}
+
+// EnrichmentResult sums up an enrichment run
+type EnrichmentResult struct {
+	Risks               int `json:"risks"`    // Risks referencing a CVE
+	Enriched            int `json:"enriched"` // Risks whose tags or probability changed
+	ProbabilityAdjusted int `json:"probability_adjusted"`
+	UnknownCVEs         int `json:"unknown_cves"` // CVEs referenced but absent from the feeds
+}
+
+// ImportFeed stores the records of a feed, updating only the columns of its
+// source. A KEV catalog is complete: CVEs it no longer lists lose their KEV
+// status.
+func (s *VulnEnrichmentService) ImportFeed(ctx context.Context, feed domain.VulnFeedImport, records []domain.CVERecord) (*domain.VulnFeedImport, error) {
+	columns, ok := cveFeedColumns[feed.Source]
+	if !ok {
+		return nil, fmt.Errorf("unknown vulnerability feed %q", feed.Source)
+	}
+	db := s.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		const chunk = 1000
+		for start := 0; start < len(records); start += chunk {
+			batch := records[start:min(start+chunk, len(records))]
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns(columns),
+			}).Create(&batch).Error; err != nil {
+				return fmt.Errorf("failed to store %s records: %w", feed.Source, err)
+			}
+		}
+		if feed.Source == domain.FeedKEV {
+			listed := make([]string, len(records))
+			for i := range records {
+				listed[i] = records[i].ID
+			}
+			query := tx.Model(&domain.CVERecord{}).Where("kev")
+			if len(listed) > 0 {
+				query = query.Where("id NOT IN ?", listed)
+			}
+			if err := query.Updates(map[string]interface{}{"kev": false, "kev_ransomware": false}).Error; err != nil {
+				return fmt.Errorf("failed to clear KEV status: %w", err)
+			}
+		}
+
+		feed.ID = uuid.New()
+		feed.RecordCount = len(records)
+		feed.ImportedBy = domain.ActorFrom(ctx)
+		if err := tx.Create(&feed).Error; err != nil {
+			return fmt.Errorf("failed to record feed import: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// ListFeedImports returns the last feed loads, most recent first
+func (s *VulnEnrichmentService) ListFeedImports(source string, limit int) ([]domain.VulnFeedImport, error) {
+	query := s.db.Order("created_at DESC").Limit(limit)
+	if source != "" {
+		query = query.Where("source = ?", strings.ToUpper(source))
+	}
+	var imports []domain.VulnFeedImport
+	if err := query.Find(&imports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list feed imports: %w", err)
+	}
+	return imports, nil
+}
+
+// GetCVE returns what the feeds know about a CVE
+func (s *VulnEnrichmentService) GetCVE(id string) (*domain.CVERecord, error) {
+	var record domain.CVERecord
+	if err := s.db.Take(&record, "id = ?", strings.ToUpper(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCVENotFound
+		}
+		return nil, fmt.Errorf("failed to load CVE: %w", err)
+	}
+	return &record, nil
+}
+
+// GetRiskEnrichment returns the provenance of the last enrichment of a risk
+func (s *VulnEnrichmentService) GetRiskEnrichment(riskID uuid.UUID) (*domain.RiskEnrichment, error) {
+	var enrichment domain.RiskEnrichment
+	if err := s.db.Take(&enrichment, "risk_id = ?", riskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRiskNotEnriched
+		}
+		return nil, fmt.Errorf("failed to load risk enrichment: %w", err)
+	}
+	return &enrichment, nil
+}
+
+// GetPolicy returns the tenant's enrichment policy, or the default one
+func (s *VulnEnrichmentService) GetPolicy(tenantID uuid.UUID) domain.EnrichmentPolicy {
+	var policy domain.EnrichmentPolicy
+	if err := s.db.First(&policy, "tenant_id = ?", tenantID).Error; err != nil {
+		return domain.DefaultEnrichmentPolicy(tenantID)
+	}
+	return policy
+}
+
+// UpdatePolicy creates or replaces the tenant's enrichment policy
+func (s *VulnEnrichmentService) UpdatePolicy(tenantID, updatedBy uuid.UUID, input domain.EnrichmentPolicy) (*domain.EnrichmentPolicy, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnrichmentPolicy, err)
+	}
+
+	policy := s.GetPolicy(tenantID)
+	policy.AdjustProbability = input.AdjustProbability
+	policy.AllowDecrease = input.AllowDecrease
+	policy.KEVProbability = input.KEVProbability
+	if len(input.EPSSThresholds) > 0 {
+		policy.EPSSThresholds = input.EPSSThresholds
+	}
+	policy.UpdatedBy = updatedBy
+	if policy.ID == uuid.Nil {
+		policy.ID = uuid.New()
+	}
+
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save enrichment policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// EnrichAll enriches every risk referencing a CVE
+func (s *VulnEnrichmentService) EnrichAll(ctx context.Context) (*EnrichmentResult, error) {
+	db := s.db.WithContext(ctx)
+	var riskIDs []uuid.UUID
+	if err := db.Model(&domain.Risk{}).
+		Where("description ILIKE ? OR external_id ILIKE ? OR array_to_string(tags, ' ') ILIKE ?", "%CVE-%", "%CVE-%", "%CVE-%").
+		Pluck("id", &riskIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list risks referencing CVEs: %w", err)
+	}
+
+	run, err := s.newEnrichmentRun(db)
+	if err != nil {
+		return nil, err
+	}
+	result := &EnrichmentResult{}
+	for _, id := range riskIDs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if _, err := run.enrich(db, id, result); err != nil {
+			return result, fmt.Errorf("failed to enrich risk %s: %w", id, err)
+		}
+	}
+	return result, nil
+}
+
+// EnrichRisk enriches one risk and returns its enrichment
+func (s *VulnEnrichmentService) EnrichRisk(ctx context.Context, riskID uuid.UUID) (*domain.RiskEnrichment, error) {
+	db := s.db.WithContext(ctx)
+	run, err := s.newEnrichmentRun(db)
+	if err != nil {
+		return nil, err
+	}
+	enrichment, err := run.enrich(db, riskID, &EnrichmentResult{})
+	if err != nil {
+		return nil, err
+	}
+	if enrichment == nil {
+		return nil, ErrRiskNotEnriched
+	}
+	return enrichment, nil
+}
+
+// enrichmentRun holds what an enrichment run shares between risks: the
+// tenant policies and the versions of the feeds
+type enrichmentRun struct {
+	service  *VulnEnrichmentService
+	tenants  map[uuid.UUID]uuid.UUID // Risk ID to tenant of its register entry
+	policies map[uuid.UUID]*domain.EnrichmentPolicy
+	feeds    []byte
+}
+
+func (s *VulnEnrichmentService) newEnrichmentRun(db *gorm.DB) (*enrichmentRun, error) {
+	run := &enrichmentRun{
+		service:  s,
+		tenants:  map[uuid.UUID]uuid.UUID{},
+		policies: map[uuid.UUID]*domain.EnrichmentPolicy{},
+	}
+	var registers []domain.RiskRegister
+	if err := db.Select("risk_id", "tenant_id").Find(&registers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load risk register: %w", err)
+	}
+	for _, r := range registers {
+		run.tenants[r.RiskID] = r.TenantID
+	}
+
+	versions := map[string]string{}
+	for _, source := range []string{domain.FeedNVD, domain.FeedKEV, domain.FeedEPSS} {
+		var last domain.VulnFeedImport
+		err := db.Where("source = ?", source).Order("created_at DESC").Take(&last).Error
+		if err == nil {
+			versions[source] = strings.TrimSpace(last.Version + " (loaded " + last.CreatedAt.UTC().Format(time.RFC3339) + ")")
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to load feed imports: %w", err)
+		}
+	}
+	run.feeds, _ = json.Marshal(versions)
+	return run, nil
+}
+
+func (run *enrichmentRun) policy(tenantID uuid.UUID) *domain.EnrichmentPolicy {
+	if p, ok := run.policies[tenantID]; ok {
+		return p
+	}
+	p := run.service.GetPolicy(tenantID)
+	run.policies[tenantID] = &p
+	return &p
+}
+
+// enrich enriches a risk and saves its tags, probability and provenance.
+// It returns nil for a risk referencing no CVE.
+func (run *enrichmentRun) enrich(db *gorm.DB, riskID uuid.UUID, result *EnrichmentResult) (*domain.RiskEnrichment, error) {
+	var enrichment *domain.RiskEnrichment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var risk domain.Risk
+		if err := tx.Preload("Assets").Take(&risk, "id = ?", riskID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRiskNotFound
+			}
+			return fmt.Errorf("failed to load risk: %w", err)
+		}
+		cves := domain.ExtractCVEs(append([]string{risk.ExternalID, risk.Description}, risk.Tags...)...)
+		if len(cves) == 0 {
+			return nil
+		}
+		result.Risks++
+
+		var records []domain.CVERecord
+		if err := tx.Where("id IN ?", cves).Find(&records).Error; err != nil {
+			return fmt.Errorf("failed to load CVE records: %w", err)
+		}
+		byID := make(map[string]domain.CVERecord, len(records))
+		for _, r := range records {
+			byID[r.ID] = r
+		}
+		result.UnknownCVEs += len(cves) - len(byID)
+
+		var previous domain.RiskEnrichment
+		if err := tx.Take(&previous, "risk_id = ?", risk.ID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to load risk enrichment: %w", err)
+		}
+		tenantID := run.tenants[risk.ID]
+		outcome := enrichRisk(&risk, cves, byID, run.policy(tenantID), previous.KEV)
+
+		updates := map[string]interface{}{}
+		if strings.Join(risk.Tags, "\n") != strings.Join(outcome.Tags, "\n") {
+			updates["tags"] = pq.StringArray(outcome.Tags)
+		}
+		now := time.Now()
+		enrichment = &previous
+		if outcome.Probability != risk.Probability {
+			if err := NewImpactService(tx).ApplyEffectiveCriticality(risk.Assets); err != nil {
+				return err
+			}
+			updates["probability"] = outcome.Probability
+			updates["score"] = ComputeRiskScore(risk.Impact, outcome.Probability, risk.Assets)
+			enrichment.ProbabilityFrom = risk.Probability
+			enrichment.ProbabilityTo = outcome.Probability
+			enrichment.ProbabilityReason = outcome.Reason
+			enrichment.ProbabilityChangedAt = &now
+			result.ProbabilityAdjusted++
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&risk).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update risk: %w", err)
+			}
+			result.Enriched++
+		}
+
+		evidence, _ := json.Marshal(outcome.Evidence)
+		enrichment.RiskID = risk.ID
+		enrichment.TenantID = tenantID
+		enrichment.CVEs = cves
+		enrichment.Evidence = evidence
+		enrichment.KEV = outcome.KEV
+		enrichment.MaxCVSSScore = outcome.MaxCVSSScore
+		enrichment.MaxEPSSPercentile = outcome.MaxEPSSPercentile
+		enrichment.SuggestedProbability = outcome.Suggested
+		enrichment.Reason = outcome.Reason
+		enrichment.Feeds = run.feeds
+		enrichment.EnrichedAt = now
+		if err := tx.Save(enrichment).Error; err != nil {
+			return fmt.Errorf("failed to save risk enrichment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enrichment, nil
+}
+
+// riskEnrichmentOutcome is what enrichment makes of a risk
+type riskEnrichmentOutcome struct {
+	Tags              []string
+	Probability       int
+	Suggested         int
+	Reason            string
+	Evidence          []domain.CVEEvidence
+	KEV               bool
+	MaxCVSSScore      float64
+	MaxEPSSPercentile *float64
+}
+
+// enrichRisk computes the tags and probability of a risk from the records
+// of its CVEs and the policy of its tenant. The CVEs and, while one is
+// listed, the KEV tag are added; a KEV tag set by an earlier enrichment is
+// removed once no CVE is listed anymore. Only open risks have their
+// probability moved.
+func enrichRisk(risk *domain.Risk, cves []string, records map[string]domain.CVERecord, policy *domain.EnrichmentPolicy, wasKEV bool) riskEnrichmentOutcome {
+	outcome := riskEnrichmentOutcome{Probability: risk.Probability}
+	var kevCVE, epssCVE string
+	var kevSince *time.Time
+	for _, cve := range cves {
+		record, ok := records[cve]
+		if !ok {
+			outcome.Evidence = append(outcome.Evidence, domain.CVEEvidence{CVE: cve})
+			continue
+		}
+		outcome.Evidence = append(outcome.Evidence, domain.CVEEvidence{
+			CVE:            cve,
+			CVSSVector:     record.CVSSVector,
+			CVSSScore:      record.CVSSScore,
+			KEV:            record.KEV,
+			KEVDateAdded:   record.KEVDateAdded,
+			EPSS:           record.EPSS,
+			EPSSPercentile: record.EPSSPercentile,
+		})
+		if record.KEV && !outcome.KEV {
+			outcome.KEV, kevCVE, kevSince = true, cve, record.KEVDateAdded
+		}
+		if record.CVSSScore > outcome.MaxCVSSScore {
+			outcome.MaxCVSSScore = record.CVSSScore
+		}
+		if record.EPSSPercentile != nil && (outcome.MaxEPSSPercentile == nil || *record.EPSSPercentile > *outcome.MaxEPSSPercentile) {
+			pct := *record.EPSSPercentile
+			outcome.MaxEPSSPercentile, epssCVE = &pct, cve
+		}
+	}
+
+	// Tags
+	tags := append([]string(nil), risk.Tags...)
+	has := map[string]bool{}
+	for _, tag := range tags {
+		has[strings.ToUpper(tag)] = true
+	}
+	for _, cve := range cves {
+		if !has[cve] {
+			tags = append(tags, cve)
+			has[cve] = true
+		}
+	}
+	switch {
+	case outcome.KEV && !has[domain.TagKEV]:
+		tags = append(tags, domain.TagKEV)
+	case !outcome.KEV && wasKEV:
+		kept := tags[:0]
+		for _, tag := range tags {
+			if !strings.EqualFold(tag, domain.TagKEV) {
+				kept = append(kept, tag)
+			}
+		}
+		tags = kept
+	}
+	outcome.Tags = tags
+
+	// Probability
+	suggested, rule := policy.ProbabilityFor(outcome.KEV, outcome.MaxEPSSPercentile)
+	if suggested == 0 {
+		return outcome
+	}
+	outcome.Suggested = suggested
+	if outcome.KEV && policy.KEVProbability > 0 {
+		outcome.Reason = fmt.Sprintf("%s %s", kevCVE, rule)
+		if kevSince != nil {
+			outcome.Reason += " since " + kevSince.Format("2006-01-02")
+		}
+	} else {
+		outcome.Reason = fmt.Sprintf("%s: %s", epssCVE, rule)
+	}
+	outcome.Reason += fmt.Sprintf(": probability %d", suggested)
+
+	open := risk.Status == domain.StatusDraft || risk.Status == domain.StatusActive
+	if policy.AdjustProbability && open &&
+		(suggested > risk.Probability || (policy.AllowDecrease && suggested < risk.Probability)) {
+		outcome.Probability = suggested
+	}
+	return outcome
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opendefender/openrisk/internal/core/domain"
+)
+
+func TestEnrichRisk(t *testing.T) {
+	pct := func(v float64) *float64 { return &v }
+	added := time.Date(2021, 12, 10, 0, 0, 0, 0, time.UTC)
+	records := map[string]domain.CVERecord{
+		"CVE-2021-44228": {ID: "CVE-2021-44228", CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", CVSSScore: 10, KEV: true, KEVDateAdded: &added, EPSSPercentile: pct(0.99)},
+		"CVE-2023-1111":  {ID: "CVE-2023-1111", CVSSScore: 6.5, EPSSPercentile: pct(0.85)},
+	}
+	policy := domain.DefaultEnrichmentPolicy(uuid.Nil)
+
+	t.Run("tags only by default", func(t *testing.T) {
+		risk := &domain.Risk{Probability: 2, Status: domain.StatusActive, Tags: pq.StringArray{"log4j", "cve-2021-44228"}}
+		outcome := enrichRisk(risk, []string{"CVE-2021-44228", "CVE-2099-0001"}, records, &policy, false)
+		assert.Equal(t, []string{"log4j", "cve-2021-44228", "CVE-2099-0001", "KEV"}, outcome.Tags)
+		assert.Equal(t, 2, outcome.Probability, "the default policy does not adjust probability")
+		assert.Equal(t, 5, outcome.Suggested)
+		assert.Equal(t, "CVE-2021-44228 listed in the CISA KEV catalog since 2021-12-10: probability 5", outcome.Reason)
+		assert.True(t, outcome.KEV)
+		assert.Equal(t, 10.0, outcome.MaxCVSSScore)
+		assert.Len(t, outcome.Evidence, 2)
+		assert.Empty(t, outcome.Evidence[1].CVSSVector, "unknown CVEs are listed without evidence")
+	})
+
+	adjusting := policy
+	adjusting.AdjustProbability = true
+
+	t.Run("raises probability from EPSS", func(t *testing.T) {
+		risk := &domain.Risk{Probability: 2, Status: domain.StatusDraft}
+		outcome := enrichRisk(risk, []string{"CVE-2023-1111"}, records, &adjusting, false)
+		assert.Equal(t, 4, outcome.Probability)
+		assert.Equal(t, "CVE-2023-1111: EPSS percentile 0.85 >= 0.80: probability 4", outcome.Reason)
+	})
+
+	t.Run("never lowers unless allowed", func(t *testing.T) {
+		risk := &domain.Risk{Probability: 5, Status: domain.StatusActive}
+		outcome := enrichRisk(risk, []string{"CVE-2023-1111"}, records, &adjusting, false)
+		assert.Equal(t, 5, outcome.Probability)
+
+		lowering := adjusting
+		lowering.AllowDecrease = true
+		outcome = enrichRisk(risk, []string{"CVE-2023-1111"}, records, &lowering, false)
+		assert.Equal(t, 4, outcome.Probability)
+	})
+
+	t.Run("closed risks keep their probability", func(t *testing.T) {
+		risk := &domain.Risk{Probability: 1, Status: domain.StatusAccepted}
+		outcome := enrichRisk(risk, []string{"CVE-2021-44228"}, records, &adjusting, false)
+		assert.Equal(t, 1, outcome.Probability)
+		assert.Equal(t, 5, outcome.Suggested)
+	})
+
+	t.Run("KEV tag removed once delisted", func(t *testing.T) {
+		delisted := map[string]domain.CVERecord{"CVE-2021-44228": {ID: "CVE-2021-44228"}}
+		risk := &domain.Risk{Probability: 3, Status: domain.StatusActive, Tags: pq.StringArray{"CVE-2021-44228", "KEV", "web"}}
+		outcome := enrichRisk(risk, []string{"CVE-2021-44228"}, delisted, &adjusting, true)
+		assert.Equal(t, []string{"CVE-2021-44228", "web"}, outcome.Tags)
+		assert.Zero(t, outcome.Suggested)
+		assert.Equal(t, 3, outcome.Probability)
+
+		outcome = enrichRisk(risk, []string{"CVE-2021-44228"}, delisted, &adjusting, false)
+		assert.Contains(t, outcome.Tags, "KEV", "a KEV tag set by hand is kept")
+	})
+}
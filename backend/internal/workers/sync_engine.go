@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/opendefender/openrisk/internal/core/domain"
 	"github.com/opendefender/openrisk/internal/core/ports"
 	"github.com/opendefender/openrisk/internal/repositories"
@@ -30,6 +31,9 @@ type SyncMetrics struct {
 // SyncEngine coordinates synchronization of external incident sources
 type SyncEngine struct {
 	IncidentProvider ports.IncidentProvider
+	// Persists incidents with the risks they realized; incidents are only
+	// turned into risks when nil
+	IncidentRecorder ports.IncidentRecorder
 	ticker           *time.Ticker
 	stopCh           chan struct{}
 	doneCh           chan struct{}
@@ -117,8 +121,14 @@ func (e *SyncEngine) Stop() {
 	}
 }
 
+// RunOnce performs a single sync cycle (with retries). It lets a cluster-wide
+// scheduler drive the engine instead of the engine's own ticker.
+func (e *SyncEngine) RunOnce(ctx context.Context) error {
+	return e.syncWithRetry()
+}
+
 // syncWithRetry implements exponential backoff retry logic
-func (e *SyncEngine) syncWithRetry() {
+func (e *SyncEngine) syncWithRetry() error {
 	var lastErr error
 
 	for attempt := 0; attempt <= e.maxRetries; attempt++ {
@@ -139,7 +149,7 @@ func (e *SyncEngine) syncWithRetry() {
 
 		lastErr = e.syncIncidents()
 		if lastErr == nil {
-			return
+			return nil
 		}
 	}
 
@@ -154,6 +164,7 @@ func (e *SyncEngine) syncWithRetry() {
 		"max_retries": e.maxRetries,
 		"error":       lastErr.Error(),
 	})
+	return lastErr
 }
 
 // syncIncidents fetches and processes incidents from all providers
@@ -206,15 +217,16 @@ func (e *SyncEngine) syncIncidents() error {
 	return nil
 }
 
-// processIncident transforms external incident to risk and stores it
+// processIncident stores the incident and, for high-severity incidents, the
+// risk it realized, linking both
 func (e *SyncEngine) processIncident(inc *domain.Incident) error {
 	// Only create risks for high-severity incidents
 	if inc.Severity != "HIGH" && inc.Severity != "CRITICAL" {
-		e.logDebug("Skipping low-severity incident", map[string]interface{}{
+		e.logDebug("Skipping risk creation for low-severity incident", map[string]interface{}{
 			"incident_id": inc.ExternalID,
 			"severity":    inc.Severity,
 		})
-		return nil
+		return e.recordIncident(inc)
 	}
 
 	// Map incident severity to risk scores
@@ -239,6 +251,9 @@ func (e *SyncEngine) processIncident(inc *domain.Incident) error {
 	if err != nil {
 		return fmt.Errorf("failed to create/update risk: %w", err)
 	}
+	if err := e.recordIncident(inc, newRisk.ID); err != nil {
+		return err
+	}
 
 	e.logDebug("Processed incident successfully", map[string]interface{}{
 		"incident_id": inc.ExternalID,
@@ -249,6 +264,18 @@ func (e *SyncEngine) processIncident(inc *domain.Incident) error {
 	return nil
 }
 
+// recordIncident persists the incident linked to the risks it realized
+func (e *SyncEngine) recordIncident(inc *domain.Incident, riskIDs ...uuid.UUID) error {
+	if e.IncidentRecorder == nil {
+		return nil
+	}
+	ctx := domain.WithActor(context.Background(), domain.SystemActor)
+	if _, err := e.IncidentRecorder.RecordIncident(ctx, inc, riskIDs...); err != nil {
+		return fmt.Errorf("failed to record incident: %w", err)
+	}
+	return nil
+}
+
 // GetMetrics returns current synchronization metrics
 func (e *SyncEngine) GetMetrics() *SyncMetrics {
 	e.metrics.mu.RLock()
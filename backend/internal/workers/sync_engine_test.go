@@ -158,6 +158,43 @@ func TestProcessIncidentLowSeverity(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// MockIncidentRecorder implements IncidentRecorder for testing
+type MockIncidentRecorder struct {
+	recorded []*domain.Incident
+	riskIDs  [][]uuid.UUID
+	actors   []string
+}
+
+func (m *MockIncidentRecorder) RecordIncident(ctx context.Context, incident *domain.Incident, riskIDs ...uuid.UUID) (*domain.Incident, error) {
+	m.recorded = append(m.recorded, incident)
+	m.riskIDs = append(m.riskIDs, riskIDs)
+	m.actors = append(m.actors, domain.ActorFrom(ctx))
+	return incident, nil
+}
+
+// TestProcessIncidentRecordsLowSeverity verifies low-severity incidents are
+// kept without creating a risk
+func TestProcessIncidentRecordsLowSeverity(t *testing.T) {
+	recorder := &MockIncidentRecorder{}
+	engine := NewSyncEngine(&MockIncidentProvider{})
+	engine.IncidentRecorder = recorder
+
+	incident := &domain.Incident{
+		ID:         uuid.New(),
+		Title:      "Phishing report",
+		Severity:   "MEDIUM",
+		ExternalID: "ext-medium-001",
+		Source:     "THEHIVE",
+	}
+
+	err := engine.processIncident(incident)
+	require.NoError(t, err)
+	require.Len(t, recorder.recorded, 1)
+	assert.Equal(t, incident, recorder.recorded[0])
+	assert.Empty(t, recorder.riskIDs[0], "no risk is realized by a low-severity incident")
+	assert.Equal(t, domain.SystemActor, recorder.actors[0])
+}
+
 // TestProcessIncidentHighSeverity verifies HIGH severity incident processing attempt
 // Note: This test verifies the logic path only; actual DB persistence requires integration tests
 func TestProcessIncidentHighSeverity(t *testing.T) {
@@ -10,6 +10,9 @@ export interface Asset {
   owner: string;
   risks?: Risk[];
   source: string;
+  parent_id?: string;
+  description?: string;
+  tags?: string[];
 }
 
 interface AssetStore {
@@ -25,8 +28,8 @@ export const useAssetStore = create<AssetStore>((set, get) => ({
   fetchAssets: async () => {
     set({ isLoading: true });
     try {
-      const { data } = await api.get('/assets');
-      set({ assets: data });
+      const { data } = await api.get('/assets', { params: { limit: 200 } });
+      set({ assets: data.items ?? [] });
     } catch (e) { console.error(e); } 
     finally { set({ isLoading: false }); }
   },
@@ -0,0 +1,92 @@
+-- Migration: Create approval workflow tables
+-- Configurable multi-step approval chains for risk decisions and treatment plans
+
+CREATE TABLE IF NOT EXISTS approval_workflows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    entity_type VARCHAR(50) NOT NULL, -- RISK_DECISION, TREATMENT_PLAN
+    decision_type VARCHAR(100),       -- e.g. RISK_ACCEPTANCE, empty = any
+    min_risk_level VARCHAR(50),       -- LOW, MEDIUM, HIGH, CRITICAL
+    priority INTEGER DEFAULT 0,
+    steps JSONB NOT NULL DEFAULT '[]',
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS approval_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    workflow_id UUID NOT NULL REFERENCES approval_workflows(id),
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    risk_register_id UUID,
+    decision_type VARCHAR(100),
+    risk_level VARCHAR(50),
+    status VARCHAR(50) DEFAULT 'PENDING', -- PENDING, APPROVED, REJECTED, CANCELLED
+    current_stage INTEGER DEFAULT 0,
+    requested_by UUID NOT NULL,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS approval_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    request_id UUID NOT NULL REFERENCES approval_requests(id) ON DELETE CASCADE,
+    tenant_id UUID,
+    step_name VARCHAR(255) NOT NULL,
+    stage INTEGER NOT NULL,
+    approvers TEXT[],
+    required_approvals INTEGER DEFAULT 1,
+    timeout_hours INTEGER DEFAULT 0,
+    escalate_to_roles TEXT[],
+    escalate_to_users TEXT[],
+    status VARCHAR(50) DEFAULT 'WAITING', -- WAITING, PENDING, APPROVED, REJECTED, SKIPPED
+    approval_count INTEGER DEFAULT 0,
+    rejection_count INTEGER DEFAULT 0,
+    due_at TIMESTAMP,
+    escalated BOOLEAN DEFAULT FALSE,
+    escalated_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS approval_votes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL REFERENCES approval_tasks(id) ON DELETE CASCADE,
+    request_id UUID NOT NULL REFERENCES approval_requests(id) ON DELETE CASCADE,
+    approver_id UUID NOT NULL,
+    on_behalf_of UUID,
+    decision VARCHAR(20) NOT NULL, -- APPROVE, REJECT
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    delegator_id UUID NOT NULL,
+    delegate_id UUID NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_until TIMESTAMP NOT NULL,
+    reason TEXT,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_approval_workflows_tenant_entity ON approval_workflows(tenant_id, entity_type);
+CREATE INDEX IF NOT EXISTS idx_approval_workflows_deleted_at ON approval_workflows(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_approval_entity ON approval_requests(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_approval_requests_status ON approval_requests(tenant_id, status);
+CREATE INDEX IF NOT EXISTS idx_approval_tasks_request_id ON approval_tasks(request_id);
+CREATE INDEX IF NOT EXISTS idx_approval_tasks_status_due ON approval_tasks(status, due_at);
+CREATE INDEX IF NOT EXISTS idx_approval_tasks_approvers ON approval_tasks USING GIN(approvers);
+CREATE INDEX IF NOT EXISTS idx_approval_votes_task_id ON approval_votes(task_id);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate ON approval_delegations(delegate_id, valid_until);
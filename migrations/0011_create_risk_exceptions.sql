@@ -0,0 +1,55 @@
+-- Migration: Create risk exception tables
+-- Time-boxed risk acceptances with compensating controls, asset scope, reminders and re-certification
+
+CREATE TABLE IF NOT EXISTS risk_exceptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    risk_id UUID NOT NULL REFERENCES risks(id) ON DELETE CASCADE,
+    decision_id UUID,
+    title VARCHAR(255) NOT NULL,
+    justification TEXT NOT NULL,
+    compensating_controls JSONB DEFAULT '[]',
+    owner VARCHAR(255),
+    status VARCHAR(50) DEFAULT 'PENDING_APPROVAL', -- PENDING_APPROVAL, ACTIVE, EXPIRED, REVOKED, REJECTED
+    valid_from TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    reminder_days INTEGER[] DEFAULT '{30,7,1}',
+    proposed_expires_at TIMESTAMP,
+    recertification_reason TEXT,
+    recertification_count INTEGER DEFAULT 0,
+    last_recertified_at TIMESTAMP,
+    previous_risk_status VARCHAR(50),
+    requested_by UUID,
+    approved_by UUID,
+    approved_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    close_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS risk_exception_assets (
+    risk_exception_id UUID NOT NULL REFERENCES risk_exceptions(id) ON DELETE CASCADE,
+    asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    PRIMARY KEY (risk_exception_id, asset_id)
+);
+
+CREATE TABLE IF NOT EXISTS risk_exception_reminders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    exception_id UUID NOT NULL REFERENCES risk_exceptions(id) ON DELETE CASCADE,
+    recipient VARCHAR(255),
+    days_before_expiry INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP NOT NULL,
+    acknowledged_at TIMESTAMP
+);
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_risk_exceptions_risk_id ON risk_exceptions(risk_id);
+CREATE INDEX IF NOT EXISTS idx_risk_exceptions_status_expiry ON risk_exceptions(status, expires_at);
+CREATE INDEX IF NOT EXISTS idx_risk_exceptions_owner ON risk_exceptions(owner);
+CREATE INDEX IF NOT EXISTS idx_risk_exceptions_deleted_at ON risk_exceptions(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_risk_exception_reminders_exception ON risk_exception_reminders(exception_id, expires_at);
+CREATE INDEX IF NOT EXISTS idx_risk_exception_reminders_recipient ON risk_exception_reminders(recipient);
@@ -0,0 +1,52 @@
+-- Migration: Create review scheduling tables
+-- Review cadence per risk level, review tasks for risk owners and iCalendar feed tokens
+
+CREATE TABLE IF NOT EXISTS review_cadence_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID UNIQUE,
+    critical VARCHAR(20) DEFAULT 'MONTHLY',
+    high VARCHAR(20) DEFAULT 'QUARTERLY',
+    medium VARCHAR(20) DEFAULT 'SEMI_ANNUAL',
+    low VARCHAR(20) DEFAULT 'ANNUAL',
+    lead_days INTEGER DEFAULT 14,
+    grace_days INTEGER DEFAULT 0,
+    updated_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS review_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    risk_register_id UUID NOT NULL,
+    risk_id UUID,
+    title VARCHAR(255),
+    assignee_id UUID,
+    risk_level VARCHAR(50),
+    frequency VARCHAR(20),
+    due_date TIMESTAMP NOT NULL,
+    status VARCHAR(20) DEFAULT 'OPEN', -- OPEN, OVERDUE, COMPLETED, CANCELLED
+    review_id UUID,
+    completed_by UUID,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS review_calendar_feeds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    owner_type VARCHAR(10) NOT NULL, -- USER, TEAM
+    owner_id UUID NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_by UUID,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_review_tasks_register ON review_tasks(risk_register_id, status);
+CREATE INDEX IF NOT EXISTS idx_review_tasks_assignee ON review_tasks(assignee_id, status);
+CREATE INDEX IF NOT EXISTS idx_review_tasks_due_date ON review_tasks(due_date);
+CREATE INDEX IF NOT EXISTS idx_review_calendar_feeds_owner ON review_calendar_feeds(owner_id);
@@ -0,0 +1,38 @@
+-- Migration: Create durable background job queue
+-- Workers claim jobs with SELECT ... FOR UPDATE SKIP LOCKED and hold them under a lease (locked_until)
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    type VARCHAR(100) NOT NULL,
+    payload JSONB,
+    status VARCHAR(20) NOT NULL DEFAULT 'QUEUED', -- QUEUED, RUNNING, SUCCEEDED, FAILED, CANCELLED
+    priority INTEGER DEFAULT 0,
+    idempotency_key VARCHAR(255),
+    attempts INTEGER DEFAULT 0,
+    max_attempts INTEGER DEFAULT 5,
+    run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_by VARCHAR(100),
+    locked_until TIMESTAMP,
+    progress INTEGER DEFAULT 0,
+    progress_message VARCHAR(255),
+    last_error TEXT,
+    result JSONB,
+    cancel_requested BOOLEAN DEFAULT FALSE,
+    created_by UUID,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_idempotency ON jobs(tenant_id, idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant_id ON jobs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_type ON jobs(type);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+CREATE INDEX IF NOT EXISTS idx_jobs_run_at ON jobs(run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_locked_until ON jobs(locked_until);
+CREATE INDEX IF NOT EXISTS idx_jobs_created_by ON jobs(created_by);
+-- Partial index serving the worker claim query
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(priority DESC, run_at) WHERE status IN ('QUEUED', 'RUNNING');
+
@@ -0,0 +1,44 @@
+-- Migration: Create cluster scheduler tables
+-- One row per periodic worker; replicas compete for the row's lease before running it
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name VARCHAR(100) PRIMARY KEY,
+    description VARCHAR(255),
+    schedule VARCHAR(100) NOT NULL, -- cron expression, @daily-style descriptor or "@every 5m"
+    missed_run_policy VARCHAR(20) DEFAULT 'RUN_ONCE', -- RUN_ONCE, SKIP
+    timeout_seconds INTEGER DEFAULT 600,
+    paused BOOLEAN DEFAULT FALSE,
+    paused_by UUID,
+    trigger_requested BOOLEAN DEFAULT FALSE,
+    triggered_by UUID,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP,
+    last_status VARCHAR(20),
+    last_error TEXT,
+    last_duration_ms BIGINT DEFAULT 0,
+    locked_by VARCHAR(100),
+    lease_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_next_run_at ON scheduled_jobs(next_run_at);
+
+CREATE TABLE IF NOT EXISTS scheduled_job_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_name VARCHAR(100) NOT NULL REFERENCES scheduled_jobs(name) ON DELETE CASCADE,
+    trigger VARCHAR(20), -- SCHEDULE, MANUAL
+    status VARCHAR(20), -- RUNNING, SUCCEEDED, FAILED, SKIPPED
+    instance VARCHAR(100),
+    scheduled_for TIMESTAMP,
+    missed_runs INTEGER DEFAULT 0,
+    triggered_by UUID,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    duration_ms BIGINT DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_job_runs_job_name ON scheduled_job_runs(job_name);
+CREATE INDEX IF NOT EXISTS idx_scheduled_job_runs_status ON scheduled_job_runs(status);
+CREATE INDEX IF NOT EXISTS idx_scheduled_job_runs_started_at ON scheduled_job_runs(started_at);
@@ -0,0 +1,54 @@
+-- Migration: Create spreadsheet import tables
+-- A batch is one uploaded CSV/XLSX file; records journal every change it applied so it can be rolled back
+
+CREATE TABLE IF NOT EXISTS import_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    entity VARCHAR(20) NOT NULL, -- risk, asset, mitigation
+    status VARCHAR(20) DEFAULT 'UPLOADED', -- UPLOADED, VALIDATED, COMMITTING, COMMITTED, FAILED, ROLLED_BACK
+    file_name VARCHAR(255),
+    format VARCHAR(10), -- csv, xlsx
+    file_key TEXT, -- blob store key of the uploaded file
+    file_size BIGINT DEFAULT 0,
+    headers TEXT[],
+    mapping JSONB, -- header -> field name
+    match_by VARCHAR(20) DEFAULT 'auto', -- auto, external_id, title, none
+    skip_invalid BOOLEAN DEFAULT FALSE,
+    total_rows INTEGER DEFAULT 0,
+    create_count INTEGER DEFAULT 0,
+    update_count INTEGER DEFAULT 0,
+    unchanged_count INTEGER DEFAULT 0,
+    error_count INTEGER DEFAULT 0,
+    preview JSONB, -- dry-run rows, errors first
+    job_id UUID,
+    error_message TEXT,
+    created_by UUID,
+    validated_at TIMESTAMP,
+    committed_at TIMESTAMP,
+    rolled_back_at TIMESTAMP,
+    rolled_back_by UUID,
+    rollback_skipped_count INTEGER DEFAULT 0, -- records changed since the import, left as they were
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_batches_tenant_id ON import_batches(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_import_batches_status ON import_batches(status);
+CREATE INDEX IF NOT EXISTS idx_import_batches_job_id ON import_batches(job_id);
+CREATE INDEX IF NOT EXISTS idx_import_batches_created_by ON import_batches(created_by);
+
+CREATE TABLE IF NOT EXISTS import_records (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    row_number INTEGER,
+    entity VARCHAR(20),
+    entity_id UUID,
+    action VARCHAR(20), -- CREATE, UPDATE
+    before JSONB, -- column values the update overwrote
+    entity_updated_at TIMESTAMP, -- updated_at of the record right after the import changed it
+    rollback_skipped BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_records_batch_id ON import_records(batch_id);
+CREATE INDEX IF NOT EXISTS idx_import_records_entity_id ON import_records(entity_id);
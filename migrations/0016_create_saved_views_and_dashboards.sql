@@ -0,0 +1,64 @@
+-- Migration: Create saved views and dashboards
+-- A saved view is a named risk filter, sort and column set; a dashboard is a grid of widgets
+-- Both are private, shared with a team or shared with the whole tenant
+
+CREATE TABLE IF NOT EXISTS saved_views (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    owner_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    filter TEXT, -- risk filter expression
+    sort_by VARCHAR(50),
+    sort_dir VARCHAR(4), -- asc, desc
+    columns TEXT[],
+    visibility VARCHAR(10) DEFAULT 'private', -- private, team, tenant
+    team_id UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_views_tenant_id ON saved_views(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_saved_views_owner_id ON saved_views(owner_id);
+CREATE INDEX IF NOT EXISTS idx_saved_views_visibility ON saved_views(visibility);
+CREATE INDEX IF NOT EXISTS idx_saved_views_team_id ON saved_views(team_id);
+CREATE INDEX IF NOT EXISTS idx_saved_views_deleted_at ON saved_views(deleted_at);
+
+CREATE TABLE IF NOT EXISTS dashboards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    owner_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    filter TEXT, -- applies to every widget
+    visibility VARCHAR(10) DEFAULT 'private', -- private, team, tenant
+    team_id UUID,
+    is_default BOOLEAN DEFAULT FALSE, -- the owner's home dashboard
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_dashboards_tenant_id ON dashboards(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_dashboards_owner_id ON dashboards(owner_id);
+CREATE INDEX IF NOT EXISTS idx_dashboards_visibility ON dashboards(visibility);
+CREATE INDEX IF NOT EXISTS idx_dashboards_team_id ON dashboards(team_id);
+CREATE INDEX IF NOT EXISTS idx_dashboards_deleted_at ON dashboards(deleted_at);
+
+CREATE TABLE IF NOT EXISTS dashboard_widgets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    dashboard_id UUID NOT NULL REFERENCES dashboards(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL, -- risk_matrix, trend, top_risks, sla_breaches, framework_coverage
+    title VARCHAR(255),
+    filter TEXT, -- combined with the dashboard filter
+    options JSONB, -- type specific, e.g. {"limit": 10} or {"days": 90}
+    position INTEGER DEFAULT 0,
+    x INTEGER DEFAULT 0,
+    y INTEGER DEFAULT 0,
+    width INTEGER DEFAULT 4,
+    height INTEGER DEFAULT 3,
+    refresh_rate INTEGER DEFAULT 0 -- seconds, 0 for manual refresh
+);
+
+CREATE INDEX IF NOT EXISTS idx_dashboard_widgets_dashboard_id ON dashboard_widgets(dashboard_id);
@@ -0,0 +1,101 @@
+-- Migration: Create reports, report schedules and risk audit report snapshots
+-- A report is a rendered artifact (PDF, DOCX or HTML) of a template; its figures come
+-- from a risk_audit_reports snapshot so regenerating it reproduces the same content
+
+CREATE TABLE IF NOT EXISTS risk_audit_reports (
+    id UUID PRIMARY KEY,
+    tenant_id UUID,
+    report_title VARCHAR(255) NOT NULL,
+    report_type VARCHAR(100) NOT NULL,
+    reporting_period_start TIMESTAMP,
+    reporting_period_end TIMESTAMP,
+    generated_by UUID NOT NULL,
+    generated_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    frameworks_audited TEXT[],
+    compliance_status JSONB,
+    executive_summary TEXT,
+    key_findings TEXT,
+    metrics_and_analytics JSONB,
+    total_risks INTEGER DEFAULT 0,
+    risks_by_status JSONB,
+    risks_by_severity JSONB,
+    treatments_active INTEGER DEFAULT 0,
+    treatments_completed INTEGER DEFAULT 0,
+    treatments_overdue INTEGER DEFAULT 0,
+    risk_snapshots JSONB,
+    decision_history JSONB,
+    policy_changes JSONB,
+    reviewed_by UUID,
+    review_date TIMESTAMP,
+    review_comments TEXT,
+    status VARCHAR(50) DEFAULT 'DRAFT',
+    is_signed_off BOOLEAN DEFAULT FALSE,
+    signed_off_by UUID,
+    signed_off_date TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_audit_reports_tenant_id ON risk_audit_reports(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_risk_audit_reports_generated_date ON risk_audit_reports(generated_date);
+CREATE INDEX IF NOT EXISTS idx_risk_audit_reports_status ON risk_audit_reports(status);
+CREATE INDEX IF NOT EXISTS idx_risk_audit_reports_deleted_at ON risk_audit_reports(deleted_at);
+
+CREATE TABLE IF NOT EXISTS reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    title VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL, -- executive_summary, risk_register, treatment_status, compliance_gap, board_pack
+    format VARCHAR(10) NOT NULL, -- pdf, docx, html
+    status VARCHAR(20) DEFAULT 'PENDING', -- PENDING, GENERATING, COMPLETED, FAILED
+    parameters JSONB, -- period, filter, framework
+    snapshot_id UUID REFERENCES risk_audit_reports(id),
+    schedule_id UUID,
+    job_id UUID,
+    artifact_key VARCHAR(500),
+    size BIGINT DEFAULT 0, -- bytes
+    recipients TEXT[],
+    delivered_at TIMESTAMP,
+    delivery_error TEXT,
+    error_message TEXT,
+    generated_by UUID,
+    generated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_tenant_id ON reports(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_reports_type ON reports(type);
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status);
+CREATE INDEX IF NOT EXISTS idx_reports_snapshot_id ON reports(snapshot_id);
+CREATE INDEX IF NOT EXISTS idx_reports_schedule_id ON reports(schedule_id);
+CREATE INDEX IF NOT EXISTS idx_reports_generated_by ON reports(generated_by);
+CREATE INDEX IF NOT EXISTS idx_reports_deleted_at ON reports(deleted_at);
+
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    name VARCHAR(255) NOT NULL,
+    template VARCHAR(50) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    schedule VARCHAR(100) NOT NULL, -- cron expression or descriptor such as @monthly
+    period VARCHAR(20) NOT NULL, -- last_week, last_month, last_quarter, last_30_days
+    filter TEXT,
+    framework VARCHAR(100),
+    recipients TEXT[],
+    enabled BOOLEAN DEFAULT TRUE,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    last_report_id UUID,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_tenant_id ON report_schedules(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_report_schedules_enabled ON report_schedules(enabled);
+CREATE INDEX IF NOT EXISTS idx_report_schedules_next_run_at ON report_schedules(next_run_at);
+CREATE INDEX IF NOT EXISTS idx_report_schedules_deleted_at ON report_schedules(deleted_at);
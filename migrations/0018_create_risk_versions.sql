@@ -0,0 +1,25 @@
+-- Migration: Create risk versions
+-- Append-only snapshots of a risk with its mitigations and asset links; a version is
+-- valid from valid_from until valid_to, so the register as of a date is the set of
+-- versions valid then. Risks created before this migration get a baseline version
+-- at startup.
+
+CREATE TABLE IF NOT EXISTS risk_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    risk_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    change_type VARCHAR(20) NOT NULL, -- CREATE, UPDATE, DELETE, BASELINE
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ, -- NULL for the current version
+    snapshot JSONB NOT NULL,
+    checksum VARCHAR(64) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_risk_versions_risk_version ON risk_versions(risk_id, version);
+CREATE INDEX IF NOT EXISTS idx_risk_versions_valid_from ON risk_versions(valid_from);
+CREATE INDEX IF NOT EXISTS idx_risk_versions_valid_to ON risk_versions(valid_to);
+
+-- Bulk exports of the register as of a date
+ALTER TABLE bulk_operations
+  ADD COLUMN IF NOT EXISTS as_of timestamptz NULL;
@@ -0,0 +1,10 @@
+-- Migration: Field-level risk history
+-- Each history entry records the risk version it produced and the old and new value of
+-- every field that changed; changed_by is the authenticated user, or System
+
+ALTER TABLE risk_histories
+  ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS changes JSONB;
+
+-- Timeline filters such as status and score changes match on the diff
+CREATE INDEX IF NOT EXISTS idx_risk_histories_changes ON risk_histories USING GIN (changes);
@@ -0,0 +1,16 @@
+-- Migration: Recycle bin
+-- Soft-deleted risks, assets, mitigations, teams and custom fields are listed here with
+-- who deleted them, when, and what was removed along with them so a restore can put it back
+
+CREATE TABLE IF NOT EXISTS deleted_items (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  resource_type VARCHAR(30) NOT NULL,
+  resource_id UUID NOT NULL,
+  name VARCHAR(255),
+  deleted_by VARCHAR(100),
+  deleted_at TIMESTAMPTZ NOT NULL,
+  cascade JSONB
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deleted_items_resource ON deleted_items (resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_deleted_items_deleted_at ON deleted_items (deleted_at);
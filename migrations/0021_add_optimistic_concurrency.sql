@@ -0,0 +1,18 @@
+-- Migration: Optimistic concurrency control
+-- risks.version mirrors the latest risk version; mitigations.version and
+-- risk_decisions.version count saves. All are served as ETags and checked against If-Match.
+-- Bulk operations record the version of each risk their filter selected and skip the
+-- risks changed since.
+
+ALTER TABLE risks ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0;
+UPDATE risks r SET version = v.version
+  FROM (SELECT risk_id, MAX(version) AS version FROM risk_versions GROUP BY risk_id) v
+  WHERE v.risk_id = r.id;
+
+ALTER TABLE mitigations ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE IF EXISTS risk_decisions ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE bulk_operations
+  ADD COLUMN IF NOT EXISTS skipped_count INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS risk_versions JSONB;
@@ -0,0 +1,38 @@
+-- Migration: Asset inventory
+-- Asset hierarchy (datacenter → cluster → host), typed relationships between assets
+-- and business services composed of assets
+
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS tags TEXT[];
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES assets(id);
+CREATE INDEX IF NOT EXISTS idx_assets_parent_id ON assets (parent_id);
+
+CREATE TABLE IF NOT EXISTS asset_relationships (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  source_id UUID NOT NULL REFERENCES assets(id),
+  target_id UUID NOT NULL REFERENCES assets(id),
+  type VARCHAR(30) NOT NULL,
+  description TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_asset_relationships_edge ON asset_relationships (source_id, target_id, type);
+CREATE INDEX IF NOT EXISTS idx_asset_relationships_target_id ON asset_relationships (target_id);
+
+CREATE TABLE IF NOT EXISTS business_services (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  owner TEXT,
+  criticality VARCHAR(20) NOT NULL DEFAULT 'MEDIUM',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_business_services_name ON business_services (name);
+
+CREATE TABLE IF NOT EXISTS business_service_assets (
+  business_service_id UUID NOT NULL REFERENCES business_services(id),
+  asset_id UUID NOT NULL REFERENCES assets(id),
+  PRIMARY KEY (business_service_id, asset_id)
+);
@@ -0,0 +1,31 @@
+-- Migration: Asset discovery
+-- Retirement of assets reported deleted by their cloud or CMDB inventory, and
+-- the record of inventory ingestions
+
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS retired_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_assets_retired_at ON assets (retired_at);
+CREATE INDEX IF NOT EXISTS idx_assets_source_external_id ON assets (source, external_id);
+
+CREATE TABLE IF NOT EXISTS asset_discovery_runs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  source VARCHAR(20) NOT NULL,
+  format VARCHAR(30) NOT NULL,
+  file_name VARCHAR(255),
+  dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+  retire_missing BOOLEAN NOT NULL DEFAULT FALSE,
+  status VARCHAR(20) NOT NULL,
+  total_count INTEGER NOT NULL DEFAULT 0,
+  created_count INTEGER NOT NULL DEFAULT 0,
+  updated_count INTEGER NOT NULL DEFAULT 0,
+  unchanged_count INTEGER NOT NULL DEFAULT 0,
+  retired_count INTEGER NOT NULL DEFAULT 0,
+  review_count INTEGER NOT NULL DEFAULT 0,
+  changes JSONB,
+  error_message TEXT,
+  started_by VARCHAR(100),
+  started_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_discovery_runs_source ON asset_discovery_runs (source);
+CREATE INDEX IF NOT EXISTS idx_asset_discovery_runs_started_at ON asset_discovery_runs (started_at);
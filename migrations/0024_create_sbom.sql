@@ -0,0 +1,97 @@
+-- Migration: SBOM and vulnerability feed
+-- Component inventory of assets from CycloneDX and SPDX SBOMs, OSV advisories
+-- matched against it, and the licenses forbidden in shipped components
+
+CREATE TABLE IF NOT EXISTS sbom_documents (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  format VARCHAR(20) NOT NULL,
+  encoding VARCHAR(20),
+  spec_version VARCHAR(20),
+  name VARCHAR(255),
+  version VARCHAR(100),
+  file_name VARCHAR(255),
+  component_count INTEGER NOT NULL DEFAULT 0,
+  vulnerability_count INTEGER NOT NULL DEFAULT 0,
+  license_violations INTEGER NOT NULL DEFAULT 0,
+  uploaded_by VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sbom_documents_asset_id ON sbom_documents (asset_id);
+
+CREATE TABLE IF NOT EXISTS software_components (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  sbom_id UUID NOT NULL REFERENCES sbom_documents(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  "group" VARCHAR(255),
+  version VARCHAR(100),
+  purl VARCHAR(1000),
+  ecosystem VARCHAR(50),
+  package_name VARCHAR(500),
+  licenses TEXT[],
+  supplier VARCHAR(255),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_software_components_asset_id ON software_components (asset_id);
+CREATE INDEX IF NOT EXISTS idx_software_components_sbom_id ON software_components (sbom_id);
+CREATE INDEX IF NOT EXISTS idx_software_components_name ON software_components (name);
+CREATE INDEX IF NOT EXISTS idx_software_components_purl ON software_components (purl);
+CREATE INDEX IF NOT EXISTS idx_software_components_ecosystem ON software_components (ecosystem);
+CREATE INDEX IF NOT EXISTS idx_software_components_package_name ON software_components (package_name);
+CREATE INDEX IF NOT EXISTS idx_software_components_lower_package_name ON software_components (lower(package_name));
+
+CREATE TABLE IF NOT EXISTS vulnerabilities (
+  id VARCHAR(100) PRIMARY KEY,
+  aliases TEXT[],
+  summary TEXT,
+  details TEXT,
+  severity VARCHAR(20),
+  cvss_vector VARCHAR(200),
+  source VARCHAR(20),
+  published TIMESTAMPTZ,
+  modified TIMESTAMPTZ,
+  withdrawn TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vulnerabilities_aliases ON vulnerabilities USING GIN (aliases);
+
+CREATE TABLE IF NOT EXISTS vulnerable_packages (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  vulnerability_id VARCHAR(100) NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+  ecosystem VARCHAR(50) NOT NULL,
+  name VARCHAR(500) NOT NULL,
+  ranges JSONB,
+  versions TEXT[]
+);
+
+CREATE INDEX IF NOT EXISTS idx_vulnerable_packages_vulnerability_id ON vulnerable_packages (vulnerability_id);
+CREATE INDEX IF NOT EXISTS idx_vulnerable_packages_name ON vulnerable_packages (name);
+CREATE INDEX IF NOT EXISTS idx_vulnerable_packages_lower_name ON vulnerable_packages (lower(name));
+
+CREATE TABLE IF NOT EXISTS component_vulnerabilities (
+  component_id UUID NOT NULL REFERENCES software_components(id) ON DELETE CASCADE,
+  vulnerability_id VARCHAR(100) NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+  asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  fixed_version VARCHAR(100),
+  risk_id UUID REFERENCES risks(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (component_id, vulnerability_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_component_vulnerabilities_asset_id ON component_vulnerabilities (asset_id);
+CREATE INDEX IF NOT EXISTS idx_component_vulnerabilities_risk_id ON component_vulnerabilities (risk_id);
+
+CREATE TABLE IF NOT EXISTS license_policies (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  license VARCHAR(100) NOT NULL UNIQUE,
+  severity VARCHAR(20) NOT NULL DEFAULT 'HIGH',
+  reason TEXT,
+  report_only BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,72 @@
+-- Migration: Vulnerability enrichment
+-- CVE records from the offline NVD, KEV and EPSS feeds, the loads of those
+-- feeds, the tenant policy mapping exploitation evidence to probability, and
+-- the provenance of each risk enrichment
+
+CREATE TABLE IF NOT EXISTS cve_records (
+  id VARCHAR(30) PRIMARY KEY,
+  description TEXT,
+  cvss_vector VARCHAR(200),
+  cvss_version VARCHAR(10),
+  cvss_score NUMERIC(3,1),
+  cvss_severity VARCHAR(20),
+  published TIMESTAMPTZ,
+  last_modified TIMESTAMPTZ,
+  kev BOOLEAN NOT NULL DEFAULT FALSE,
+  kev_name VARCHAR(255),
+  kev_date_added TIMESTAMPTZ,
+  kev_due_date TIMESTAMPTZ,
+  kev_ransomware BOOLEAN NOT NULL DEFAULT FALSE,
+  epss DOUBLE PRECISION,
+  epss_percentile DOUBLE PRECISION,
+  epss_date TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cve_records_kev ON cve_records (kev) WHERE kev;
+
+CREATE TABLE IF NOT EXISTS vuln_feed_imports (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  source VARCHAR(10) NOT NULL,
+  version VARCHAR(100),
+  file_name VARCHAR(255),
+  record_count INTEGER NOT NULL DEFAULT 0,
+  imported_by VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vuln_feed_imports_source ON vuln_feed_imports (source);
+CREATE INDEX IF NOT EXISTS idx_vuln_feed_imports_created_at ON vuln_feed_imports (created_at);
+
+CREATE TABLE IF NOT EXISTS enrichment_policies (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID UNIQUE,
+  adjust_probability BOOLEAN NOT NULL DEFAULT FALSE,
+  allow_decrease BOOLEAN NOT NULL DEFAULT FALSE,
+  kev_probability INTEGER NOT NULL DEFAULT 5,
+  epss_thresholds JSONB,
+  updated_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS risk_enrichments (
+  risk_id UUID PRIMARY KEY REFERENCES risks(id) ON DELETE CASCADE,
+  tenant_id UUID,
+  cves TEXT[],
+  evidence JSONB,
+  kev BOOLEAN NOT NULL DEFAULT FALSE,
+  max_cvss_score NUMERIC(3,1),
+  max_epss_percentile DOUBLE PRECISION,
+  suggested_probability INTEGER,
+  reason TEXT,
+  probability_from INTEGER,
+  probability_to INTEGER,
+  probability_reason TEXT,
+  probability_changed_at TIMESTAMPTZ,
+  feeds JSONB,
+  enriched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_enrichments_tenant_id ON risk_enrichments (tenant_id);
@@ -0,0 +1,17 @@
+-- Migration: CVSS
+-- The CVSS vector of a risk, from which its impact and probability are
+-- derived, and the tenant policy mapping CVSS components to them
+
+ALTER TABLE risks ADD COLUMN IF NOT EXISTS cvss_vector VARCHAR(200);
+ALTER TABLE risks ADD COLUMN IF NOT EXISTS cvss_score NUMERIC(3,1) DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS cvss_mapping_policies (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID UNIQUE,
+  impact_thresholds JSONB,
+  probability_thresholds JSONB,
+  ignore_asset_criticality BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,49 @@
+-- Migration: MITRE ATT&CK
+-- The ATT&CK Enterprise catalog loaded from its STIX bundle, the techniques
+-- realizing risks and the techniques, ATT&CK mitigations and D3FEND
+-- countermeasures of mitigations
+
+CREATE TABLE IF NOT EXISTS attack_objects (
+  id VARCHAR(20) PRIMARY KEY,
+  stix_id VARCHAR(100),
+  type VARCHAR(20),
+  name VARCHAR(255),
+  description TEXT,
+  short_name VARCHAR(100),
+  tactics TEXT[],
+  platforms TEXT[],
+  parent_id VARCHAR(20),
+  url VARCHAR(255),
+  deprecated BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attack_objects_stix_id ON attack_objects(stix_id);
+CREATE INDEX IF NOT EXISTS idx_attack_objects_type ON attack_objects(type);
+CREATE INDEX IF NOT EXISTS idx_attack_objects_parent_id ON attack_objects(parent_id);
+
+CREATE TABLE IF NOT EXISTS attack_mitigates (
+  mitigation_id VARCHAR(20) NOT NULL,
+  technique_id VARCHAR(20) NOT NULL,
+  PRIMARY KEY (mitigation_id, technique_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attack_mitigates_technique_id ON attack_mitigates(technique_id);
+
+CREATE TABLE IF NOT EXISTS attack_bundle_imports (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  version VARCHAR(50),
+  file_name VARCHAR(255),
+  checksum VARCHAR(64),
+  techniques INTEGER NOT NULL DEFAULT 0,
+  tactics INTEGER NOT NULL DEFAULT 0,
+  mitigations INTEGER NOT NULL DEFAULT 0,
+  imported_by VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE risks ADD COLUMN IF NOT EXISTS techniques TEXT[];
+ALTER TABLE mitigations ADD COLUMN IF NOT EXISTS techniques TEXT[];
+ALTER TABLE mitigations ADD COLUMN IF NOT EXISTS countermeasures TEXT[];
+
+CREATE INDEX IF NOT EXISTS idx_risks_techniques ON risks USING GIN (techniques);
@@ -0,0 +1,83 @@
+-- Migration: Threat intelligence
+-- Threats imported from STIX 2.1 bundles and TAXII 2.1 feeds, their links to
+-- risks, the TLP marking of risks and the collections of the TAXII server
+
+CREATE TABLE IF NOT EXISTS threats (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  stix_id VARCHAR(100) NOT NULL,
+  type VARCHAR(30),
+  name VARCHAR(255),
+  description TEXT,
+  tlp VARCHAR(20),
+  external_id VARCHAR(50),
+  pattern TEXT,
+  pattern_type VARCHAR(30),
+  labels TEXT[],
+  aliases TEXT[],
+  confidence INTEGER,
+  source VARCHAR(255),
+  revoked BOOLEAN NOT NULL DEFAULT FALSE,
+  valid_from TIMESTAMPTZ,
+  valid_until TIMESTAMPTZ,
+  reported_at TIMESTAMPTZ,
+  modified_at TIMESTAMPTZ,
+  raw JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_threats_stix_id ON threats(stix_id);
+CREATE INDEX IF NOT EXISTS idx_threats_type ON threats(type);
+CREATE INDEX IF NOT EXISTS idx_threats_external_id ON threats(external_id);
+
+CREATE TABLE IF NOT EXISTS risk_threats (
+  risk_id UUID NOT NULL REFERENCES risks(id) ON DELETE CASCADE,
+  threat_id UUID NOT NULL REFERENCES threats(id) ON DELETE CASCADE,
+  relationship VARCHAR(30),
+  source VARCHAR(255),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (risk_id, threat_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_threats_threat_id ON risk_threats(threat_id);
+
+CREATE TABLE IF NOT EXISTS taxii_collections (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID,
+  title VARCHAR(255) NOT NULL,
+  description TEXT,
+  max_tlp VARCHAR(20) NOT NULL,
+  tags TEXT[],
+  token_hash VARCHAR(64) NOT NULL,
+  created_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_taxii_collections_token_hash ON taxii_collections(token_hash);
+CREATE INDEX IF NOT EXISTS idx_taxii_collections_tenant_id ON taxii_collections(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_taxii_collections_deleted_at ON taxii_collections(deleted_at);
+
+CREATE TABLE IF NOT EXISTS threat_feeds (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID,
+  name VARCHAR(255) NOT NULL,
+  api_root VARCHAR(500) NOT NULL,
+  collection_id VARCHAR(100) NOT NULL,
+  username VARCHAR(255),
+  password VARCHAR(500),
+  default_tlp VARCHAR(20),
+  disabled BOOLEAN NOT NULL DEFAULT FALSE,
+  added_after TIMESTAMPTZ,
+  last_polled_at TIMESTAMPTZ,
+  last_error TEXT,
+  last_imported INTEGER NOT NULL DEFAULT 0,
+  created_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_threat_feeds_tenant_id ON threat_feeds(tenant_id);
+
+ALTER TABLE risks ADD COLUMN IF NOT EXISTS tlp VARCHAR(20);
@@ -0,0 +1,59 @@
+-- Migration: Control catalog
+-- Controls imported from OSCAL catalogs, the profiles (baselines) selecting
+-- them, and how each tenant implements them
+
+CREATE TABLE IF NOT EXISTS control_catalogs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  kind VARCHAR(20),
+  oscal_uuid VARCHAR(36) NOT NULL,
+  title VARCHAR(255),
+  version VARCHAR(100),
+  oscal_version VARCHAR(20),
+  last_modified TIMESTAMPTZ,
+  base_catalog_id UUID REFERENCES control_catalogs(id) ON DELETE CASCADE,
+  control_ids TEXT[],
+  controls INTEGER NOT NULL DEFAULT 0,
+  file_name VARCHAR(255),
+  checksum VARCHAR(64),
+  imported_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_control_catalogs_oscal_uuid ON control_catalogs(oscal_uuid);
+CREATE INDEX IF NOT EXISTS idx_control_catalogs_kind ON control_catalogs(kind);
+CREATE INDEX IF NOT EXISTS idx_control_catalogs_base_catalog_id ON control_catalogs(base_catalog_id);
+
+CREATE TABLE IF NOT EXISTS controls (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  catalog_id UUID NOT NULL REFERENCES control_catalogs(id) ON DELETE CASCADE,
+  control_id VARCHAR(100) NOT NULL,
+  label VARCHAR(100),
+  name VARCHAR(500),
+  class VARCHAR(100),
+  framework VARCHAR(255),
+  family VARCHAR(255),
+  parent_id VARCHAR(100),
+  statement TEXT,
+  guidance TEXT,
+  position INTEGER NOT NULL DEFAULT 0,
+  withdrawn BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_controls_catalog_control ON controls(catalog_id, control_id);
+CREATE INDEX IF NOT EXISTS idx_controls_family ON controls(family);
+
+CREATE TABLE IF NOT EXISTS control_implementations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID,
+  control_id UUID NOT NULL REFERENCES controls(id) ON DELETE CASCADE,
+  status VARCHAR(20) NOT NULL,
+  description TEXT,
+  responsible_role VARCHAR(100),
+  updated_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_control_impl_tenant_control ON control_implementations(tenant_id, control_id);
@@ -0,0 +1,81 @@
+-- Migration: Incidents and loss events
+-- Incidents synchronised from TheHive or recorded by hand, the risks they
+-- realized, the losses they caused, the expected annual loss of risks, and
+-- the tenant policy raising the probability of risks realized repeatedly
+-- with the provenance of each raise
+
+ALTER TABLE risks ADD COLUMN IF NOT EXISTS expected_loss NUMERIC(14,2) DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS incidents (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  title VARCHAR(255) NOT NULL,
+  status VARCHAR(50),
+  severity VARCHAR(20),
+  description TEXT,
+  source VARCHAR(50) NOT NULL DEFAULT 'MANUAL',
+  external_id VARCHAR(255),
+  resolved_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_source_external_id ON incidents (source, external_id) WHERE external_id <> '';
+CREATE INDEX IF NOT EXISTS idx_incidents_status ON incidents (status);
+CREATE INDEX IF NOT EXISTS idx_incidents_severity ON incidents (severity);
+CREATE INDEX IF NOT EXISTS idx_incidents_created_at ON incidents (created_at);
+
+CREATE TABLE IF NOT EXISTS incident_risks (
+  incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+  risk_id UUID NOT NULL REFERENCES risks(id) ON DELETE CASCADE,
+  linked_by VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (incident_id, risk_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_risks_risk_id ON incident_risks (risk_id);
+
+CREATE TABLE IF NOT EXISTS loss_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+  description TEXT,
+  direct_loss NUMERIC(14,2) NOT NULL DEFAULT 0 CHECK (direct_loss >= 0),
+  indirect_loss NUMERIC(14,2) NOT NULL DEFAULT 0 CHECK (indirect_loss >= 0),
+  recovery_cost NUMERIC(14,2) NOT NULL DEFAULT 0 CHECK (recovery_cost >= 0),
+  occurred_at TIMESTAMPTZ NOT NULL,
+  discovered_at TIMESTAMPTZ,
+  recovered_at TIMESTAMPTZ,
+  recorded_by VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_loss_events_incident_id ON loss_events (incident_id);
+CREATE INDEX IF NOT EXISTS idx_loss_events_occurred_at ON loss_events (occurred_at);
+
+CREATE TABLE IF NOT EXISTS recalibration_policies (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID UNIQUE,
+  enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  window_days INTEGER NOT NULL DEFAULT 365,
+  thresholds JSONB,
+  updated_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS risk_recalibrations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  risk_id UUID NOT NULL REFERENCES risks(id) ON DELETE CASCADE,
+  incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL,
+  tenant_id UUID,
+  occurrences INTEGER NOT NULL DEFAULT 0,
+  window_days INTEGER NOT NULL DEFAULT 0,
+  probability_from INTEGER NOT NULL,
+  probability_to INTEGER NOT NULL,
+  reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_recalibrations_risk_id ON risk_recalibrations (risk_id);
+CREATE INDEX IF NOT EXISTS idx_risk_recalibrations_incident_id ON risk_recalibrations (incident_id);
+CREATE INDEX IF NOT EXISTS idx_risk_recalibrations_tenant_id ON risk_recalibrations (tenant_id);
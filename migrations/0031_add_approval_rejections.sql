@@ -0,0 +1,11 @@
+-- Migration: Approval rejections
+-- A rejected decision or treatment plan records who rejected it and when, instead of
+-- writing the rejecting approver into approved_by
+
+ALTER TABLE IF EXISTS risk_decisions
+  ADD COLUMN IF NOT EXISTS rejected_by UUID,
+  ADD COLUMN IF NOT EXISTS rejected_date TIMESTAMPTZ;
+
+ALTER TABLE IF EXISTS risk_treatment_plans
+  ADD COLUMN IF NOT EXISTS rejected_by UUID,
+  ADD COLUMN IF NOT EXISTS rejected_date TIMESTAMPTZ;